@@ -0,0 +1,235 @@
+// Package apiauth provides an HTTP middleware that authenticates clients using
+// API keys and applies per-key token bucket rate limits, so that services
+// exposed to the public (e.g. the frontend or the bspgraph job API) cannot be
+// trivially abused.
+package apiauth
+
+import (
+	"crypto/subtle"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultHeader = "X-API-Key"
+
+	// maxAnonymousBuckets bounds the number of per-IP buckets kept for
+	// anonymous clients. When exceeded, buckets that have been fully
+	// refilled are discarded.
+	maxAnonymousBuckets = 10000
+)
+
+// Limit describes a token bucket rate limit. Rate is the number of requests
+// per second that are allowed on average while Burst is the maximum number of
+// requests that can be served in a row. A zero Limit does not restrict
+// requests.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) unlimited() bool {
+	return l == Limit{}
+}
+
+func (l Limit) validate() error {
+	if l.unlimited() {
+		return nil
+	}
+	if l.Rate <= 0 || l.Burst <= 0 {
+		return xerrors.Errorf("invalid limit %+v: rate and burst must be positive", l)
+	}
+	return nil
+}
+
+// Config encapsulates the configuration options for the middleware.
+type Config struct {
+	// Keys maps each accepted API key to its rate limit.
+	Keys map[string]Limit
+
+	// Header is the name of the request header that carries the API key.
+	// Keys may also be supplied as bearer tokens via the Authorization
+	// header. If not specified, a default value of "X-API-Key" will be
+	// used.
+	Header string
+
+	// AllowAnonymous permits requests without an API key. Anonymous
+	// requests are rate limited per client IP address using
+	// AnonymousLimit.
+	AllowAnonymous bool
+	AnonymousLimit Limit
+
+	// Clock returns the current time. If not specified, time.Now will be
+	// used.
+	Clock func() time.Time
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if len(cfg.Keys) == 0 && !cfg.AllowAnonymous {
+		err = xerrors.New("no API keys specified")
+	}
+	for key, limit := range cfg.Keys {
+		if key == "" {
+			err = xerrors.New("API keys must not be empty")
+		} else if limitErr := limit.validate(); limitErr != nil {
+			err = limitErr
+		}
+	}
+	if limitErr := cfg.AnonymousLimit.validate(); limitErr != nil {
+		err = xerrors.Errorf("anonymous limit: %w", limitErr)
+	}
+
+	if cfg.Header == "" {
+		cfg.Header = defaultHeader
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return err
+}
+
+// Middleware authenticates and rate limits the requests to an http.Handler.
+type Middleware struct {
+	cfg Config
+
+	mu sync.Mutex
+	// keyBuckets and ipBuckets hold the token buckets of API keys and
+	// anonymous clients respectively.
+	keyBuckets map[string]*tokenBucket
+	ipBuckets  map[string]*tokenBucket
+}
+
+// New creates a new Middleware using the provided config.
+func New(cfg Config) (*Middleware, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("api auth config validation failed: %w", err)
+	}
+
+	keys := make(map[string]Limit, len(cfg.Keys))
+	for key, limit := range cfg.Keys {
+		keys[key] = limit
+	}
+	cfg.Keys = keys
+
+	return &Middleware{
+		cfg:        cfg,
+		keyBuckets: make(map[string]*tokenBucket),
+		ipBuckets:  make(map[string]*tokenBucket),
+	}, nil
+}
+
+// Wrap returns an http.Handler that only passes authenticated requests that
+// are within their rate limit on to h. Requests with a missing or unknown API
+// key are rejected with 401 while requests exceeding their rate limit are
+// rejected with 429 and a Retry-After header.
+func (m *Middleware) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := m.apiKey(r)
+
+		var (
+			limit Limit
+			found bool
+		)
+		if key != "" {
+			limit, found = m.lookupKey(key)
+		} else if m.cfg.AllowAnonymous {
+			limit, found = m.cfg.AnonymousLimit, true
+		}
+		if !found {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		if !limit.unlimited() {
+			allowed, remaining, retryAfter := m.take(key, clientIP(r), limit)
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// apiKey extracts the API key from r.
+func (m *Middleware) apiKey(r *http.Request) string {
+	if key := r.Header.Get(m.cfg.Header); key != "" {
+		return key
+	}
+	const bearer = "Bearer "
+	if auth := r.Header.Get("Authorization"); len(auth) > len(bearer) && strings.EqualFold(auth[:len(bearer)], bearer) {
+		return strings.TrimSpace(auth[len(bearer):])
+	}
+	return ""
+}
+
+// lookupKey returns the limit of key. Keys are compared in constant time so
+// that valid keys cannot be discovered through timing attacks.
+func (m *Middleware) lookupKey(key string) (Limit, bool) {
+	var (
+		limit Limit
+		found bool
+	)
+	for candidate, candidateLimit := range m.cfg.Keys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			limit, found = candidateLimit, true
+		}
+	}
+	return limit, found
+}
+
+// take consumes a token from the bucket of key or, for anonymous requests,
+// from the bucket of ip.
+func (m *Middleware) take(key, ip string, limit Limit) (allowed bool, remaining int, retryAfter time.Duration) {
+	now := m.cfg.Clock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buckets, id := m.keyBuckets, key
+	if key == "" {
+		buckets, id = m.ipBuckets, ip
+		if _, exists := buckets[id]; !exists && len(buckets) >= maxAnonymousBuckets {
+			m.pruneFullBuckets(buckets, now)
+		}
+	}
+
+	b, exists := buckets[id]
+	if !exists {
+		b = newTokenBucket(limit, now)
+		buckets[id] = b
+	}
+	allowed, retryAfter = b.take(now)
+	return allowed, b.remaining(), retryAfter
+}
+
+// pruneFullBuckets discards the buckets of clients that have not sent any
+// requests for long enough for their bucket to be refilled.
+func (m *Middleware) pruneFullBuckets(buckets map[string]*tokenBucket, now time.Time) {
+	for id, b := range buckets {
+		if b.full(now) {
+			delete(buckets, id)
+		}
+	}
+}
+
+// clientIP returns the IP address of the client that sent r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
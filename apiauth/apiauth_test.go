@@ -0,0 +1,130 @@
+package apiauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(APIAuthTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type APIAuthTestSuite struct {
+	now time.Time
+}
+
+func (s *APIAuthTestSuite) SetUpTest(c *gc.C) {
+	s.now = time.Now()
+}
+
+func (s *APIAuthTestSuite) TestAuthentication(c *gc.C) {
+	h := s.wrap(c, Config{Keys: map[string]Limit{"key-1": {}}})
+
+	c.Assert(s.serve(h, nil).Code, gc.Equals, http.StatusUnauthorized)
+	c.Assert(s.serve(h, map[string]string{"X-API-Key": "bogus"}).Code, gc.Equals, http.StatusUnauthorized)
+	c.Assert(s.serve(h, map[string]string{"X-API-Key": "key-1"}).Code, gc.Equals, http.StatusOK)
+	c.Assert(s.serve(h, map[string]string{"Authorization": "bearer key-1"}).Code, gc.Equals, http.StatusOK)
+}
+
+func (s *APIAuthTestSuite) TestPerKeyRateLimits(c *gc.C) {
+	h := s.wrap(c, Config{
+		Keys: map[string]Limit{
+			"slow": {Rate: 1, Burst: 2},
+			"fast": {Rate: 10, Burst: 5},
+		},
+		Header: "X-Key",
+	})
+
+	slow := map[string]string{"X-Key": "slow"}
+	for i := 0; i < 2; i++ {
+		c.Assert(s.serve(h, slow).Code, gc.Equals, http.StatusOK)
+	}
+	rec := s.serve(h, slow)
+	c.Assert(rec.Code, gc.Equals, http.StatusTooManyRequests)
+	c.Assert(rec.Header().Get("Retry-After"), gc.Equals, "1")
+	c.Assert(rec.Header().Get("X-RateLimit-Limit"), gc.Equals, "2")
+	c.Assert(rec.Header().Get("X-RateLimit-Remaining"), gc.Equals, "0")
+
+	// Other keys have their own bucket
+	fast := map[string]string{"X-Key": "fast"}
+	for i := 0; i < 5; i++ {
+		c.Assert(s.serve(h, fast).Code, gc.Equals, http.StatusOK)
+	}
+	c.Assert(s.serve(h, fast).Code, gc.Equals, http.StatusTooManyRequests)
+
+	// Buckets are refilled over time
+	s.now = s.now.Add(time.Second)
+	c.Assert(s.serve(h, slow).Code, gc.Equals, http.StatusOK)
+	c.Assert(s.serve(h, slow).Code, gc.Equals, http.StatusTooManyRequests)
+}
+
+func (s *APIAuthTestSuite) TestAnonymousRequestsAreLimitedPerIP(c *gc.C) {
+	h := s.wrap(c, Config{
+		AllowAnonymous: true,
+		AnonymousLimit: Limit{Rate: 0.5, Burst: 1},
+	})
+
+	req := func(remoteAddr string) int {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	c.Assert(req("10.0.0.1:1234"), gc.Equals, http.StatusOK)
+	c.Assert(req("10.0.0.1:5678"), gc.Equals, http.StatusTooManyRequests)
+	c.Assert(req("10.0.0.2:1234"), gc.Equals, http.StatusOK)
+
+	s.now = s.now.Add(2 * time.Second)
+	c.Assert(req("10.0.0.1:1234"), gc.Equals, http.StatusOK)
+}
+
+func (s *APIAuthTestSuite) TestPruneFullBuckets(c *gc.C) {
+	m, err := New(Config{AllowAnonymous: true, AnonymousLimit: Limit{Rate: 1, Burst: 1}, Clock: func() time.Time { return s.now }})
+	c.Assert(err, gc.IsNil)
+
+	m.take("", "10.0.0.1", m.cfg.AnonymousLimit)
+	m.take("", "10.0.0.2", m.cfg.AnonymousLimit)
+	s.now = s.now.Add(time.Second)
+	m.take("", "10.0.0.2", m.cfg.AnonymousLimit)
+	s.now = s.now.Add(200 * time.Millisecond)
+
+	m.pruneFullBuckets(m.ipBuckets, s.now)
+	c.Assert(m.ipBuckets, gc.HasLen, 1)
+	c.Assert(m.ipBuckets["10.0.0.2"], gc.NotNil)
+}
+
+func (s *APIAuthTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := New(Config{})
+	c.Assert(err, gc.ErrorMatches, ".*no API keys specified")
+
+	_, err = New(Config{Keys: map[string]Limit{"key": {Rate: 1}}})
+	c.Assert(err, gc.ErrorMatches, ".*rate and burst must be positive")
+
+	_, err = New(Config{Keys: map[string]Limit{"": {}}})
+	c.Assert(err, gc.ErrorMatches, ".*API keys must not be empty")
+}
+
+func (s *APIAuthTestSuite) wrap(c *gc.C, cfg Config) http.Handler {
+	cfg.Clock = func() time.Time { return s.now }
+	m, err := New(cfg)
+	c.Assert(err, gc.IsNil)
+	return m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func (s *APIAuthTestSuite) serve(h http.Handler, hdr map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,51 @@
+package apiauth
+
+import (
+	"math"
+	"time"
+)
+
+// tokenBucket implements the token bucket rate limiting algorithm. The bucket
+// holds up to burst tokens and is refilled at a constant rate; each request
+// consumes one token.
+type tokenBucket struct {
+	limit    Limit
+	tokens   float64
+	lastFill time.Time
+}
+
+func newTokenBucket(limit Limit, now time.Time) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(limit.Burst), lastFill: now}
+}
+
+// take consumes a token if one is available. Otherwise, it returns false
+// along with the time until the next token becomes available.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / b.limit.Rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// remaining returns the number of whole tokens left in the bucket.
+func (b *tokenBucket) remaining() int {
+	return int(b.tokens)
+}
+
+// full returns true if the bucket has been refilled to its capacity, i.e. it
+// behaves exactly like a newly created bucket.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.lastFill); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed.Seconds()*b.limit.Rate)
+		b.lastFill = now
+	}
+}
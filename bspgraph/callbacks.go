@@ -0,0 +1,112 @@
+package bspgraph
+
+import (
+	"context"
+	"math"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrUnknownAggregator is returned by the ready-made executor callbacks when
+// they reference an aggregator that has not been registered with the graph.
+var ErrUnknownAggregator = xerrors.New("unknown aggregator")
+
+// ComposeCallbacks combines a list of ExecutorCallbacks into a single one.
+// The PreStep and PostStep callbacks are invoked in order and the first
+// error aborts the chain. The composed PostStepKeepRunning callback only
+// keeps running if all of the composed PostStepKeepRunning callbacks agree.
+func ComposeCallbacks(callbacks ...ExecutorCallbacks) ExecutorCallbacks {
+	return ExecutorCallbacks{
+		PreStep: func(ctx context.Context, g *Graph) error {
+			for _, cb := range callbacks {
+				if cb.PreStep == nil {
+					continue
+				}
+				if err := cb.PreStep(ctx, g); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		PostStep: func(ctx context.Context, g *Graph, activeInStep int) error {
+			for _, cb := range callbacks {
+				if cb.PostStep == nil {
+					continue
+				}
+				if err := cb.PostStep(ctx, g, activeInStep); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		PostStepKeepRunning: func(ctx context.Context, g *Graph, activeInStep int) (bool, error) {
+			// All callbacks are invoked even if one of them has already
+			// voted to stop as they may be keeping track of state (e.g.
+			// aggregator deltas) between supersteps.
+			keepRunning := true
+			for _, cb := range callbacks {
+				if cb.PostStepKeepRunning == nil {
+					continue
+				}
+				cbKeepRunning, err := cb.PostStepKeepRunning(ctx, g, activeInStep)
+				if err != nil {
+					return false, err
+				}
+				keepRunning = keepRunning && cbKeepRunning
+			}
+			return keepRunning, nil
+		},
+	}
+}
+
+// MaxSupersteps returns ExecutorCallbacks that stop the executor once n
+// supersteps have been executed.
+func MaxSupersteps(n int) ExecutorCallbacks {
+	return ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, g *Graph, _ int) (bool, error) {
+			return g.Superstep()+1 < n, nil
+		},
+	}
+}
+
+// ConvergeWhenDeltaBelow returns ExecutorCallbacks that stop the executor
+// once the absolute change in the value of the aggregator with the specified
+// name during a superstep drops below eps. The aggregator must produce
+// numeric values.
+func ConvergeWhenDeltaBelow(aggName string, eps float64) ExecutorCallbacks {
+	return ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, g *Graph, _ int) (bool, error) {
+			aggr := g.Aggregator(aggName)
+			if aggr == nil {
+				return false, xerrors.Errorf("converge when delta below: %q: %w", aggName, ErrUnknownAggregator)
+			}
+
+			delta, ok := toFloat64(aggr.Delta())
+			if !ok {
+				return false, xerrors.Errorf("converge when delta below: aggregator %q does not produce numeric values", aggName)
+			}
+			return math.Abs(delta) >= eps, nil
+		},
+	}
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,77 @@
+package bspgraph
+
+import (
+	"context"
+	"math"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph/aggregator"
+	"github.com/brandonshearin/ask_brandon/bspgraph/message"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CallbacksTestSuite))
+
+type CallbacksTestSuite struct{}
+
+// newHalvingGraph returns a graph with a single vertex whose compute function
+// adds 0.5^superstep to the "delta" aggregator.
+func newHalvingGraph(c *gc.C) *Graph {
+	g, err := NewGraph(GraphConfig{
+		ComputeFn: func(g *Graph, _ *Vertex, _ message.Iterator) error {
+			g.Aggregator("delta").Aggregate(math.Pow(0.5, float64(g.Superstep())))
+			return nil
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	g.AddVertex("v", nil)
+	g.RegisterAggregator("delta", new(aggregator.Float64Accumulator))
+	return g
+}
+
+func (s *CallbacksTestSuite) TestMaxSupersteps(c *gc.C) {
+	g := newHalvingGraph(c)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	err := NewExecutor(g, MaxSupersteps(3)).RunToCompletion(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(g.Superstep(), gc.Equals, 2)
+}
+
+func (s *CallbacksTestSuite) TestConvergeWhenDeltaBelow(c *gc.C) {
+	g := newHalvingGraph(c)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	err := NewExecutor(g, ConvergeWhenDeltaBelow("delta", 0.1)).RunToCompletion(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(g.Superstep(), gc.Equals, 4)
+}
+
+func (s *CallbacksTestSuite) TestComposeCallbacks(c *gc.C) {
+	g := newHalvingGraph(c)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	var preSteps int
+	cb := ComposeCallbacks(
+		ExecutorCallbacks{
+			PreStep: func(context.Context, *Graph) error {
+				preSteps++
+				return nil
+			},
+		},
+		ConvergeWhenDeltaBelow("delta", 0.1),
+		MaxSupersteps(2),
+	)
+	err := NewExecutor(g, cb).RunToCompletion(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(g.Superstep(), gc.Equals, 1)
+	c.Assert(preSteps, gc.Equals, 2)
+}
+
+func (s *CallbacksTestSuite) TestConvergeWithUnknownAggregator(c *gc.C) {
+	g := newHalvingGraph(c)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	err := NewExecutor(g, ConvergeWhenDeltaBelow("bogus", 0.1)).RunToCompletion(context.TODO())
+	c.Assert(xerrors.Is(err, ErrUnknownAggregator), gc.Equals, true)
+}
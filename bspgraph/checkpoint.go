@@ -0,0 +1,145 @@
+package bspgraph
+
+import (
+	"github.com/brandonshearin/ask_brandon/bspgraph/message"
+	"golang.org/x/xerrors"
+)
+
+// Checkpoint captures the state of a graph at a superstep boundary so that a
+// failed computation can be rolled back and resumed. Vertex, edge and
+// aggregator values are copied by reference; compute functions should treat
+// them as immutable and replace them via SetValue instead of mutating them in
+// place.
+type Checkpoint struct {
+	// Superstep is the superstep that will be executed next when resuming
+	// from this checkpoint
+	Superstep int
+
+	Vertices    map[string]VertexCheckpoint
+	Aggregators map[string]interface{}
+}
+
+// VertexCheckpoint captures the state of a single vertex.
+type VertexCheckpoint struct {
+	Value  interface{}
+	Active bool
+	Edges  []EdgeCheckpoint
+
+	// Messages holds the contents of the two message queues of the vertex
+	Messages [2][]message.Message
+}
+
+// EdgeCheckpoint captures the state of a single edge.
+type EdgeCheckpoint struct {
+	DstID string
+	Value interface{}
+}
+
+// Checkpoint captures the current state of the graph. It must only be
+// invoked between supersteps, i.e. from a PostStep executor callback.
+func (g *Graph) Checkpoint() (*Checkpoint, error) {
+	g.topologyMu.RLock()
+	defer g.topologyMu.RUnlock()
+
+	cp := &Checkpoint{
+		Superstep:   g.superstep + 1,
+		Vertices:    make(map[string]VertexCheckpoint, len(g.vertices)),
+		Aggregators: make(map[string]interface{}, len(g.aggregators)),
+	}
+
+	for id, v := range g.vertices {
+		vcp := VertexCheckpoint{Value: v.value, Active: v.active}
+		for _, e := range v.edges {
+			vcp.Edges = append(vcp.Edges, EdgeCheckpoint{DstID: e.dstID, Value: e.value})
+		}
+		for i := 0; i < 2; i++ {
+			msgs, err := snapshotQueue(v.msgQueue[i])
+			if err != nil {
+				return nil, xerrors.Errorf("checkpoint message queue #%d for vertex %q: %w", i, id, err)
+			}
+			vcp.Messages[i] = msgs
+		}
+		cp.Vertices[id] = vcp
+	}
+
+	for name, aggr := range g.aggregators {
+		cp.Aggregators[name] = aggr.Get()
+	}
+
+	return cp, nil
+}
+
+// snapshotQueue returns the messages currently buffered in q. As iterating a
+// queue drains it, the captured messages are enqueued again afterwards. The
+// delivery order of messages is not part of the BSP contract and is therefore
+// not guaranteed to be preserved.
+func snapshotQueue(q message.Queue) ([]message.Message, error) {
+	var msgs []message.Message
+	it := q.Messages()
+	for it.Next() {
+		msgs = append(msgs, it.Message())
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	for _, msg := range msgs {
+		if err := q.Enqueue(msg); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+// RestoreCheckpoint replaces the state of the graph with the state captured
+// by cp. Aggregators with the names captured by the checkpoint must already be
+// registered with the graph. When resuming a computation with a new Executor,
+// RestoreCheckpoint must be invoked after creating the executor as executors
+// reset the superstep counter of the graph when created.
+func (g *Graph) RestoreCheckpoint(cp *Checkpoint) error {
+	g.topologyMu.Lock()
+	defer g.topologyMu.Unlock()
+
+	for name := range cp.Aggregators {
+		if g.aggregators[name] == nil {
+			return xerrors.Errorf("restore checkpoint: aggregator %q is not registered", name)
+		}
+	}
+
+	for id, v := range g.vertices {
+		for i := 0; i < 2; i++ {
+			if err := v.msgQueue[i].Close(); err != nil {
+				return xerrors.Errorf("closing message queue #%d for vertex %v: %w", i, id, err)
+			}
+		}
+	}
+
+	g.vertices = make(map[string]*Vertex, len(cp.Vertices))
+	for id, vcp := range cp.Vertices {
+		v := &Vertex{
+			id:         id,
+			value:      vcp.Value,
+			active:     vcp.Active,
+			msgQueue:   [2]message.Queue{g.queueFactory(), g.queueFactory()},
+			topologyMu: &g.topologyMu,
+		}
+		for _, ecp := range vcp.Edges {
+			v.edges = append(v.edges, &Edge{dstID: ecp.DstID, value: ecp.Value})
+		}
+		for i := 0; i < 2; i++ {
+			for _, msg := range vcp.Messages[i] {
+				if err := v.msgQueue[i].Enqueue(msg); err != nil {
+					return xerrors.Errorf("restoring message queue #%d for vertex %v: %w", i, id, err)
+				}
+			}
+		}
+		g.vertices[id] = v
+	}
+
+	for name, val := range cp.Aggregators {
+		g.aggregators[name].Set(val)
+	}
+
+	g.superstep = cp.Superstep
+	return nil
+}
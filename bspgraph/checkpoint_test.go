@@ -0,0 +1,83 @@
+package bspgraph
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph/aggregator"
+	"github.com/brandonshearin/ask_brandon/bspgraph/message"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CheckpointTestSuite))
+
+type CheckpointTestSuite struct{}
+
+type intMsg struct{ val int }
+
+func (intMsg) Type() string { return "intMsg" }
+
+// newSummingGraph returns a ring graph where each vertex adds the values it
+// receives to its own value and forwards the result to its neighbor.
+func newSummingGraph(c *gc.C) *Graph {
+	g, err := NewGraph(GraphConfig{
+		ComputeFn: func(g *Graph, v *Vertex, msgIt message.Iterator) error {
+			sum := v.Value().(int)
+			for msgIt.Next() {
+				sum += msgIt.Message().(intMsg).val
+			}
+			v.SetValue(sum)
+			g.Aggregator("steps").Aggregate(1.0)
+			return g.BroadcastToNeighbors(v, intMsg{val: sum})
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	g.RegisterAggregator("steps", new(aggregator.Float64Accumulator))
+	return g
+}
+
+func (s *CheckpointTestSuite) TestCheckpointAndRestore(c *gc.C) {
+	var cp *Checkpoint
+	g := newSummingGraph(c)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+	for i, id := range []string{"a", "b", "c"} {
+		g.AddVertex(id, i+1)
+	}
+	c.Assert(g.AddEdge("a", "b", nil), gc.IsNil)
+	c.Assert(g.AddEdge("b", "c", nil), gc.IsNil)
+	c.Assert(g.AddEdge("c", "a", nil), gc.IsNil)
+
+	err := NewExecutor(g, ExecutorCallbacks{
+		PostStep: func(_ context.Context, g *Graph, _ int) error {
+			if g.Superstep() == 1 {
+				var err error
+				cp, err = g.Checkpoint()
+				return err
+			}
+			return nil
+		},
+	}).RunSteps(context.TODO(), 4)
+	c.Assert(err, gc.IsNil)
+	c.Assert(cp, gc.NotNil)
+	c.Assert(cp.Superstep, gc.Equals, 2)
+
+	// Resume the computation from the checkpoint on a fresh graph
+	restored := newSummingGraph(c)
+	defer func() { c.Assert(restored.Close(), gc.IsNil) }()
+	ex := NewExecutor(restored, ExecutorCallbacks{})
+	c.Assert(restored.RestoreCheckpoint(cp), gc.IsNil)
+	c.Assert(ex.RunSteps(context.TODO(), 2), gc.IsNil)
+
+	c.Assert(restored.Superstep(), gc.Equals, g.Superstep())
+	c.Assert(restored.Aggregator("steps").Get(), gc.Equals, g.Aggregator("steps").Get())
+	for id, v := range g.vertices {
+		c.Assert(restored.vertices[id].Value(), gc.Equals, v.Value(), gc.Commentf("vertex %s", id))
+	}
+}
+
+func (s *CheckpointTestSuite) TestRestoreWithUnknownAggregator(c *gc.C) {
+	g := newSummingGraph(c)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	err := g.RestoreCheckpoint(&Checkpoint{Aggregators: map[string]interface{}{"missing": 1.0}})
+	c.Assert(err, gc.ErrorMatches, `.*aggregator "missing" is not registered`)
+}
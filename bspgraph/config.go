@@ -1,6 +1,8 @@
 package bspgraph
 
 import (
+	"time"
+
 	"github.com/brandonshearin/ask_brandon/bspgraph/message"
 	"github.com/hashicorp/go-multierror"
 	"golang.org/x/xerrors"
@@ -10,7 +12,9 @@ import (
 type GraphConfig struct {
 	// QueueFactory is used by the graph to create message queue instances
 	// for each vertex that is added to the graph. If not specified, the
-	// default in-memory queue will be used instead.
+	// default in-memory queue will be used instead. To guard against
+	// message floods, a factory returned by message.NewBoundedQueueFactory
+	// can be used for capping the size of each queue.
 	QueueFactory message.QueueFactory
 
 	// ComputeFn is the compute function that will be invoked for each graph
@@ -22,6 +26,19 @@ type GraphConfig struct {
 	// the registered ComputeFunc when executing each superstep. If not
 	// specified, a single worker will be used.
 	ComputeWorkers int
+
+	// ComputeTimeout, if specified, limits the time that the ComputeFn may
+	// spend on a single vertex. Vertices exceeding the timeout are marked
+	// as failed (see Graph.FailedVertices), excluded from subsequent
+	// supersteps and the superstep reports an ErrComputeTimeout error.
+	// Note that as goroutines cannot be interrupted, the stuck ComputeFn
+	// invocation keeps running in the background.
+	ComputeTimeout time.Duration
+
+	// SlowVertexThreshold, if specified, enables a watchdog that logs
+	// the vertices whose ComputeFn invocation takes longer than the
+	// threshold.
+	SlowVertexThreshold time.Duration
 }
 
 // validate checks whether a graph configuration is valid and sets the default
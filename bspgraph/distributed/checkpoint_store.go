@@ -0,0 +1,64 @@
+package distributed
+
+import (
+	"sync"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph"
+	"golang.org/x/xerrors"
+)
+
+// ErrNoCheckpoint is returned by checkpoint stores when no checkpoint exists
+// for the requested partition and superstep.
+var ErrNoCheckpoint = xerrors.New("checkpoint not found")
+
+// CheckpointStore is implemented by types that can persist partition
+// checkpoints. Checkpoints are keyed by partition rather than by worker so
+// that a replacement worker can restore the partitions of a failed one.
+type CheckpointStore interface {
+	// Save persists the checkpoint of a job partition.
+	Save(jobID string, partition int, cp *bspgraph.Checkpoint) error
+
+	// Load retrieves the checkpoint of a job partition that was captured
+	// at the specified superstep.
+	Load(jobID string, partition, superstep int) (*bspgraph.Checkpoint, error)
+}
+
+type checkpointKey struct {
+	jobID     string
+	partition int
+	superstep int
+}
+
+// InMemoryCheckpointStore implements CheckpointStore by keeping checkpoints
+// in memory. It is meant for tests and single-process deployments.
+type InMemoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[checkpointKey]*bspgraph.Checkpoint
+}
+
+// NewInMemoryCheckpointStore creates a new in-memory checkpoint store.
+func NewInMemoryCheckpointStore() *InMemoryCheckpointStore {
+	return &InMemoryCheckpointStore{
+		checkpoints: make(map[checkpointKey]*bspgraph.Checkpoint),
+	}
+}
+
+// Save implements CheckpointStore.
+func (s *InMemoryCheckpointStore) Save(jobID string, partition int, cp *bspgraph.Checkpoint) error {
+	s.mu.Lock()
+	s.checkpoints[checkpointKey{jobID: jobID, partition: partition, superstep: cp.Superstep}] = cp
+	s.mu.Unlock()
+	return nil
+}
+
+// Load implements CheckpointStore.
+func (s *InMemoryCheckpointStore) Load(jobID string, partition, superstep int) (*bspgraph.Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cp := s.checkpoints[checkpointKey{jobID: jobID, partition: partition, superstep: superstep}]
+	if cp == nil {
+		return nil, xerrors.Errorf("load checkpoint for job %q partition %d superstep %d: %w", jobID, partition, superstep, ErrNoCheckpoint)
+	}
+	return cp, nil
+}
@@ -0,0 +1,113 @@
+package distributed
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// jobSpecRequest is the payload accepted by the job submission endpoint.
+type jobSpecRequest struct {
+	GraphSource string            `json:"graph_source"`
+	Algorithm   string            `json:"algorithm"`
+	Params      map[string]string `json:"params,omitempty"`
+}
+
+// jobStatusResponse is the JSON representation of a JobStatus.
+type jobStatusResponse struct {
+	ID             string            `json:"id"`
+	GraphSource    string            `json:"graph_source"`
+	Algorithm      string            `json:"algorithm"`
+	Params         map[string]string `json:"params,omitempty"`
+	State          string            `json:"state"`
+	LastCheckpoint int               `json:"last_checkpoint"`
+	Assignment     []string          `json:"assignment,omitempty"`
+	Restarts       int               `json:"restarts"`
+}
+
+// NewHTTPHandler returns an http.Handler that exposes the job API of the
+// master so that jobs can be launched and monitored remotely:
+//
+//	POST /jobs       submits a job; the body is a JSON-encoded job spec
+//	GET  /jobs       lists the status of all jobs
+//	GET  /jobs/{id}  returns the status of a single job
+func NewHTTPHandler(m *Master) http.Handler {
+	h := &httpHandler{m: m}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", h.jobs)
+	mux.HandleFunc("/jobs/", h.job)
+	return mux
+}
+
+type httpHandler struct {
+	m *Master
+}
+
+func (h *httpHandler) jobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		statuses := h.m.Jobs()
+		res := make([]jobStatusResponse, 0, len(statuses))
+		for _, st := range statuses {
+			res = append(res, toJobStatusResponse(st))
+		}
+		writeJSON(w, http.StatusOK, res)
+	case http.MethodPost:
+		var req jobSpecRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "malformed job spec", http.StatusBadRequest)
+			return
+		}
+
+		id, err := h.m.SubmitJob(JobSpec{
+			GraphSource: req.GraphSource,
+			Algorithm:   req.Algorithm,
+			Params:      req.Params,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"id": id})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *httpHandler) job(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	st, err := h.m.JobStatus(strings.TrimPrefix(r.URL.Path, "/jobs/"))
+	if xerrors.Is(err, ErrUnknownJob) {
+		http.Error(w, "unknown job", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "unable to retrieve job status", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, toJobStatusResponse(st))
+}
+
+func toJobStatusResponse(st JobStatus) jobStatusResponse {
+	return jobStatusResponse{
+		ID:             st.ID,
+		GraphSource:    st.Spec.GraphSource,
+		Algorithm:      st.Spec.Algorithm,
+		Params:         st.Spec.Params,
+		State:          st.State.String(),
+		LastCheckpoint: st.LastCheckpoint,
+		Assignment:     st.Assignment,
+		Restarts:       st.Restarts,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,69 @@
+package distributed
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(HTTPHandlerTestSuite))
+
+type HTTPHandlerTestSuite struct{}
+
+func (s *HTTPHandlerTestSuite) TestSubmitAndQueryJob(c *gc.C) {
+	m, err := NewMaster(2)
+	c.Assert(err, gc.IsNil)
+	m.RegisterWorker("w1")
+	h := NewHTTPHandler(m)
+
+	rec := httptest.NewRecorder()
+	body := `{"graph_source":"memory://links","algorithm":"pagerank","params":{"damping":"0.85"}}`
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+	c.Assert(rec.Code, gc.Equals, http.StatusCreated)
+
+	var created map[string]string
+	c.Assert(json.NewDecoder(rec.Body).Decode(&created), gc.IsNil)
+	c.Assert(created["id"], gc.Not(gc.Equals), "")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+created["id"], nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+
+	var st jobStatusResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&st), gc.IsNil)
+	c.Assert(st.ID, gc.Equals, created["id"])
+	c.Assert(st.Algorithm, gc.Equals, "pagerank")
+	c.Assert(st.Params, gc.DeepEquals, map[string]string{"damping": "0.85"})
+	c.Assert(st.State, gc.Equals, "running")
+	c.Assert(st.Assignment, gc.DeepEquals, []string{"w1", "w1"})
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	var list []jobStatusResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&list), gc.IsNil)
+	c.Assert(list, gc.HasLen, 1)
+}
+
+func (s *HTTPHandlerTestSuite) TestErrors(c *gc.C) {
+	m, err := NewMaster(1)
+	c.Assert(err, gc.IsNil)
+	h := NewHTTPHandler(m)
+
+	specs := []struct {
+		method, path, body string
+		exp                int
+	}{
+		{http.MethodPost, "/jobs", `{`, http.StatusBadRequest},
+		{http.MethodPost, "/jobs", `{"graph_source":"memory://links"}`, http.StatusBadRequest},
+		{http.MethodDelete, "/jobs", ``, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/jobs/missing", ``, http.StatusNotFound},
+	}
+	for i, spec := range specs {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(spec.method, spec.path, strings.NewReader(spec.body)))
+		c.Assert(rec.Code, gc.Equals, spec.exp, gc.Commentf("spec %d", i))
+	}
+}
@@ -0,0 +1,488 @@
+package distributed
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrUnknownJob is returned when referencing a job that has not been
+	// started by the master.
+	ErrUnknownJob = xerrors.New("unknown job")
+
+	// ErrUnknownWorker is returned when referencing a worker that has not
+	// registered with the master.
+	ErrUnknownWorker = xerrors.New("unknown worker")
+
+	// ErrInvalidJobSpec is returned when submitting a job whose spec is
+	// missing required fields.
+	ErrInvalidJobSpec = xerrors.New("invalid job spec")
+
+	// ErrInvalidJobState is returned when a worker reports progress that is
+	// not valid for the current state of a job.
+	ErrInvalidJobState = xerrors.New("invalid job state")
+)
+
+// JobState describes the lifecycle state of a job.
+type JobState uint8
+
+const (
+	// JobStatePending indicates that the job is queued until a worker
+	// becomes available.
+	JobStatePending JobState = iota
+
+	// JobStateRunning indicates that the workers are executing supersteps.
+	JobStateRunning
+
+	// JobStateRecovering indicates that a worker failed and the job is
+	// being rolled back to its last complete checkpoint.
+	JobStateRecovering
+
+	// JobStateCompleted indicates that the job ran to completion.
+	JobStateCompleted
+
+	// JobStateFailed indicates that the job could not be recovered.
+	JobStateFailed
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobStatePending:
+		return "pending"
+	case JobStateRunning:
+		return "running"
+	case JobStateRecovering:
+		return "recovering"
+	case JobStateCompleted:
+		return "completed"
+	case JobStateFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// JobSpec describes a job to be executed by the workers.
+type JobSpec struct {
+	// GraphSource identifies the input graph, e.g. the address of a link
+	// graph store.
+	GraphSource string
+
+	// Algorithm is the name of the algorithm to run (e.g. "pagerank").
+	// Workers resolve it to a compute function they were built with.
+	Algorithm string
+
+	// Params holds algorithm-specific parameters.
+	Params map[string]string
+}
+
+func (spec JobSpec) validate() error {
+	if spec.GraphSource == "" {
+		return xerrors.Errorf("graph source not specified: %w", ErrInvalidJobSpec)
+	}
+	if spec.Algorithm == "" {
+		return xerrors.Errorf("algorithm not specified: %w", ErrInvalidJobSpec)
+	}
+	return nil
+}
+
+// Assignment describes the partitions of a job that a worker is responsible
+// for.
+type Assignment struct {
+	JobID      string
+	Spec       JobSpec
+	State      JobState
+	Partitions []int
+
+	// LastCheckpoint is the superstep that the worker must restore its
+	// partitions from while the job is recovering.
+	LastCheckpoint int
+}
+
+// JobStatus is a snapshot of the state of a job as tracked by the master.
+type JobStatus struct {
+	ID    string
+	Spec  JobSpec
+	State JobState
+
+	// LastCheckpoint is the latest superstep for which all workers have
+	// persisted a checkpoint or -1 if no checkpoint is available yet. When
+	// the job is recovering, workers must restore their partitions from
+	// this checkpoint (or from the job input if it is -1).
+	LastCheckpoint int
+
+	// Assignment maps each partition to the ID of the worker processing it.
+	// It is empty while the job is pending.
+	Assignment []string
+
+	// Restarts counts the number of times the job has been rolled back.
+	Restarts int
+}
+
+type job struct {
+	id             string
+	spec           JobSpec
+	state          JobState
+	lastCheckpoint int
+	assignment     []string
+	restarts       int
+
+	// checkpointAcks tracks, for each superstep, the set of workers that
+	// have persisted a checkpoint for their partitions.
+	checkpointAcks map[int]map[string]bool
+
+	// recoveryAcks tracks the workers that have restored their partitions
+	// while the job is recovering.
+	recoveryAcks map[string]bool
+}
+
+// Master coordinates the execution of jobs across a set of workers. It
+// partitions the graph, tracks checkpoint progress and, when a worker fails,
+// reassigns its partitions and rolls back the job to the last checkpoint that
+// was completed by all workers.
+//
+// Master is safe for concurrent use.
+type Master struct {
+	numPartitions int
+
+	mu      sync.Mutex
+	workers map[string]bool
+	jobs    map[string]*job
+
+	// jobOrder lists job IDs in submission order.
+	jobOrder []string
+}
+
+// NewMaster creates a master that splits each job into numPartitions
+// partitions.
+func NewMaster(numPartitions int) (*Master, error) {
+	if numPartitions <= 0 {
+		return nil, xerrors.Errorf("invalid number of partitions %d", numPartitions)
+	}
+
+	return &Master{
+		numPartitions: numPartitions,
+		workers:       make(map[string]bool),
+		jobs:          make(map[string]*job),
+	}, nil
+}
+
+// RegisterWorker adds a live worker to the pool and starts any pending jobs.
+// Registered workers that are not assigned any partitions serve as spares
+// when a worker fails.
+func (m *Master) RegisterWorker(workerID string) {
+	m.mu.Lock()
+	m.workers[workerID] = true
+	m.startPendingJobs()
+	m.mu.Unlock()
+}
+
+// SubmitJob queues a job for execution and returns its ID. The job starts as
+// soon as at least one live worker is available; its partitions are then
+// assigned to the live workers in a round-robin fashion.
+func (m *Master) SubmitJob(spec JobSpec) (string, error) {
+	if err := spec.validate(); err != nil {
+		return "", xerrors.Errorf("submit job: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j := &job{
+		id:             uuid.New().String(),
+		spec:           spec,
+		state:          JobStatePending,
+		lastCheckpoint: -1,
+		checkpointAcks: make(map[int]map[string]bool),
+	}
+	m.jobs[j.id] = j
+	m.jobOrder = append(m.jobOrder, j.id)
+	m.startPendingJobs()
+	return j.id, nil
+}
+
+// startPendingJobs assigns partitions to all pending jobs if there are live
+// workers available.
+func (m *Master) startPendingJobs() {
+	live := m.liveWorkers()
+	if len(live) == 0 {
+		return
+	}
+
+	for _, id := range m.jobOrder {
+		j := m.jobs[id]
+		if j.state != JobStatePending {
+			continue
+		}
+
+		j.assignment = make([]string, m.numPartitions)
+		for p := range j.assignment {
+			j.assignment[p] = live[p%len(live)]
+		}
+		j.state = JobStateRunning
+	}
+}
+
+// CheckpointCompleted is invoked by a worker once it has persisted a
+// checkpoint for all of its partitions at the specified superstep (as recorded
+// in bspgraph.Checkpoint.Superstep). When every
+// worker assigned to the job has reported the same superstep, it becomes the
+// job's rollback point.
+func (m *Master) CheckpointCompleted(jobID, workerID string, superstep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.job(jobID)
+	if err != nil {
+		return err
+	} else if j.state != JobStateRunning {
+		return xerrors.Errorf("checkpoint for job %q while %s: %w", jobID, j.state, ErrInvalidJobState)
+	} else if !j.isAssigned(workerID) {
+		return xerrors.Errorf("checkpoint for job %q from worker %q: %w", jobID, workerID, ErrUnknownWorker)
+	}
+
+	acks := j.checkpointAcks[superstep]
+	if acks == nil {
+		acks = make(map[string]bool)
+		j.checkpointAcks[superstep] = acks
+	}
+	acks[workerID] = true
+
+	for _, w := range j.assignedWorkers() {
+		if !acks[w] {
+			return nil
+		}
+	}
+
+	if superstep > j.lastCheckpoint {
+		j.lastCheckpoint = superstep
+	}
+	for step := range j.checkpointAcks {
+		if step <= j.lastCheckpoint {
+			delete(j.checkpointAcks, step)
+		}
+	}
+	return nil
+}
+
+// WorkerFailed removes a worker from the pool and rolls back every job it
+// was participating in. The partitions of the failed worker are reassigned to
+// a spare worker if one is available or to the least loaded surviving worker
+// otherwise. Jobs for which no live worker remains are marked as failed.
+func (m *Master) WorkerFailed(workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.workers[workerID]; !exists {
+		return xerrors.Errorf("worker %q failed: %w", workerID, ErrUnknownWorker)
+	}
+	m.workers[workerID] = false
+
+	for _, j := range m.jobs {
+		if (j.state != JobStateRunning && j.state != JobStateRecovering) || !j.isAssigned(workerID) {
+			continue
+		}
+
+		replacement := m.replacementWorker(j)
+		if replacement == "" {
+			j.state = JobStateFailed
+			continue
+		}
+
+		for p, w := range j.assignment {
+			if w == workerID {
+				j.assignment[p] = replacement
+			}
+		}
+
+		// Any checkpoints that were in progress are now incomplete.
+		j.checkpointAcks = make(map[int]map[string]bool)
+		j.recoveryAcks = make(map[string]bool)
+		j.state = JobStateRecovering
+		j.restarts++
+	}
+
+	return nil
+}
+
+// RecoveryCompleted is invoked by a worker once it has restored its
+// partitions from the job's last checkpoint. When all assigned workers have
+// restored their state, the job resumes.
+func (m *Master) RecoveryCompleted(jobID, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.job(jobID)
+	if err != nil {
+		return err
+	} else if j.state != JobStateRecovering {
+		return xerrors.Errorf("recovery for job %q while %s: %w", jobID, j.state, ErrInvalidJobState)
+	} else if !j.isAssigned(workerID) {
+		return xerrors.Errorf("recovery for job %q from worker %q: %w", jobID, workerID, ErrUnknownWorker)
+	}
+
+	j.recoveryAcks[workerID] = true
+	for _, w := range j.assignedWorkers() {
+		if !j.recoveryAcks[w] {
+			return nil
+		}
+	}
+
+	j.recoveryAcks = nil
+	j.state = JobStateRunning
+	return nil
+}
+
+// CompleteJob marks a running job as completed.
+func (m *Master) CompleteJob(jobID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.job(jobID)
+	if err != nil {
+		return err
+	} else if j.state != JobStateRunning {
+		return xerrors.Errorf("complete job %q while %s: %w", jobID, j.state, ErrInvalidJobState)
+	}
+
+	j.state = JobStateCompleted
+	return nil
+}
+
+// JobStatus returns a snapshot of the state of the specified job.
+func (m *Master) JobStatus(jobID string) (JobStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.job(jobID)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	return j.status(), nil
+}
+
+// Jobs returns a snapshot of the state of all jobs in submission order.
+func (m *Master) Jobs() []JobStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]JobStatus, 0, len(m.jobOrder))
+	for _, id := range m.jobOrder {
+		out = append(out, m.jobs[id].status())
+	}
+	return out
+}
+
+// Assignments returns the partitions of running or recovering jobs that are
+// assigned to the specified worker. Workers poll it to discover new work and
+// to detect that they need to roll back to a checkpoint.
+func (m *Master) Assignments(workerID string) ([]Assignment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.workers[workerID]; !exists {
+		return nil, xerrors.Errorf("assignments for worker %q: %w", workerID, ErrUnknownWorker)
+	}
+
+	var out []Assignment
+	for _, id := range m.jobOrder {
+		j := m.jobs[id]
+		if j.state != JobStateRunning && j.state != JobStateRecovering {
+			continue
+		}
+
+		var partitions []int
+		for p, w := range j.assignment {
+			if w == workerID {
+				partitions = append(partitions, p)
+			}
+		}
+		if len(partitions) == 0 {
+			continue
+		}
+
+		out = append(out, Assignment{
+			JobID:          j.id,
+			Spec:           j.spec,
+			State:          j.state,
+			Partitions:     partitions,
+			LastCheckpoint: j.lastCheckpoint,
+		})
+	}
+	return out, nil
+}
+
+func (m *Master) job(jobID string) (*job, error) {
+	j := m.jobs[jobID]
+	if j == nil {
+		return nil, xerrors.Errorf("job %q: %w", jobID, ErrUnknownJob)
+	}
+	return j, nil
+}
+
+// liveWorkers returns the sorted list of live worker IDs.
+func (m *Master) liveWorkers() []string {
+	var live []string
+	for id, alive := range m.workers {
+		if alive {
+			live = append(live, id)
+		}
+	}
+	sort.Strings(live)
+	return live
+}
+
+// replacementWorker selects the worker that should take over the partitions
+// of a failed worker of job j. Spare workers are preferred over workers that
+// are already processing partitions of the job.
+func (m *Master) replacementWorker(j *job) string {
+	load := make(map[string]int)
+	for _, w := range j.assignment {
+		load[w]++
+	}
+
+	var best string
+	for _, w := range m.liveWorkers() {
+		if best == "" || load[w] < load[best] {
+			best = w
+		}
+	}
+	return best
+}
+
+func (j *job) status() JobStatus {
+	return JobStatus{
+		ID:             j.id,
+		Spec:           j.spec,
+		State:          j.state,
+		LastCheckpoint: j.lastCheckpoint,
+		Assignment:     append([]string(nil), j.assignment...),
+		Restarts:       j.restarts,
+	}
+}
+
+func (j *job) isAssigned(workerID string) bool {
+	for _, w := range j.assignment {
+		if w == workerID {
+			return true
+		}
+	}
+	return false
+}
+
+// assignedWorkers returns the distinct workers that are assigned at least one
+// partition of the job.
+func (j *job) assignedWorkers() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, w := range j.assignment {
+		if !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package distributed
+
+import (
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(MasterTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type MasterTestSuite struct{}
+
+func (s *MasterTestSuite) newMaster(c *gc.C, numPartitions int, workers ...string) *Master {
+	m, err := NewMaster(numPartitions)
+	c.Assert(err, gc.IsNil)
+	for _, w := range workers {
+		m.RegisterWorker(w)
+	}
+	return m
+}
+
+func (s *MasterTestSuite) submitJob(c *gc.C, m *Master) string {
+	id, err := m.SubmitJob(JobSpec{GraphSource: "memory://links", Algorithm: "pagerank"})
+	c.Assert(err, gc.IsNil)
+	return id
+}
+
+func (s *MasterTestSuite) TestSubmitJob(c *gc.C) {
+	m := s.newMaster(c, 4)
+	_, err := m.SubmitJob(JobSpec{GraphSource: "memory://links"})
+	c.Assert(xerrors.Is(err, ErrInvalidJobSpec), gc.Equals, true)
+
+	// Jobs stay pending until a worker becomes available
+	spec := JobSpec{
+		GraphSource: "memory://links",
+		Algorithm:   "shortestpath",
+		Params:      map[string]string{"src": "a"},
+	}
+	job, err := m.SubmitJob(spec)
+	c.Assert(err, gc.IsNil)
+	st, err := m.JobStatus(job)
+	c.Assert(err, gc.IsNil)
+	c.Assert(st.State, gc.Equals, JobStatePending)
+	c.Assert(st.Spec, gc.DeepEquals, spec)
+	c.Assert(st.Assignment, gc.HasLen, 0)
+
+	m.RegisterWorker("w1")
+	m.RegisterWorker("w2")
+	st, _ = m.JobStatus(job)
+	c.Assert(st.State, gc.Equals, JobStateRunning)
+	c.Assert(st.LastCheckpoint, gc.Equals, -1)
+	c.Assert(st.Assignment, gc.DeepEquals, []string{"w1", "w1", "w1", "w1"})
+
+	// New jobs are spread across all live workers
+	other := s.submitJob(c, m)
+	st, _ = m.JobStatus(other)
+	c.Assert(st.Assignment, gc.DeepEquals, []string{"w1", "w2", "w1", "w2"})
+
+	jobs := m.Jobs()
+	c.Assert(jobs, gc.HasLen, 2)
+	c.Assert(jobs[0].ID, gc.Equals, job)
+	c.Assert(jobs[1].ID, gc.Equals, other)
+
+	assignments, err := m.Assignments("w2")
+	c.Assert(err, gc.IsNil)
+	c.Assert(assignments, gc.HasLen, 1)
+	c.Assert(assignments[0].JobID, gc.Equals, other)
+	c.Assert(assignments[0].Partitions, gc.DeepEquals, []int{1, 3})
+
+	_, err = m.JobStatus("other")
+	c.Assert(xerrors.Is(err, ErrUnknownJob), gc.Equals, true)
+	_, err = m.Assignments("ghost")
+	c.Assert(xerrors.Is(err, ErrUnknownWorker), gc.Equals, true)
+}
+
+func (s *MasterTestSuite) TestCheckpointCoordination(c *gc.C) {
+	m := s.newMaster(c, 3, "w1", "w2", "w3")
+	job := s.submitJob(c, m)
+
+	c.Assert(m.CheckpointCompleted(job, "w1", 2), gc.IsNil)
+	c.Assert(m.CheckpointCompleted(job, "w2", 2), gc.IsNil)
+	st, _ := m.JobStatus(job)
+	c.Assert(st.LastCheckpoint, gc.Equals, -1, gc.Commentf("checkpoint must not complete until all workers report"))
+
+	c.Assert(m.CheckpointCompleted(job, "w3", 2), gc.IsNil)
+	st, _ = m.JobStatus(job)
+	c.Assert(st.LastCheckpoint, gc.Equals, 2)
+
+	err := m.CheckpointCompleted(job, "stranger", 4)
+	c.Assert(xerrors.Is(err, ErrUnknownWorker), gc.Equals, true)
+}
+
+func (s *MasterTestSuite) TestWorkerFailureRollsBackToLastCheckpoint(c *gc.C) {
+	m := s.newMaster(c, 4, "w1", "w2", "w3")
+	job := s.submitJob(c, m)
+	for _, w := range []string{"w1", "w2", "w3"} {
+		c.Assert(m.CheckpointCompleted(job, w, 2), gc.IsNil)
+	}
+	// w2 dies before the checkpoint for superstep 4 completes
+	c.Assert(m.CheckpointCompleted(job, "w1", 4), gc.IsNil)
+
+	m.RegisterWorker("spare")
+	c.Assert(m.WorkerFailed("w2"), gc.IsNil)
+
+	st, _ := m.JobStatus(job)
+	c.Assert(st.State, gc.Equals, JobStateRecovering)
+	c.Assert(st.LastCheckpoint, gc.Equals, 2)
+	c.Assert(st.Restarts, gc.Equals, 1)
+	c.Assert(st.Assignment, gc.DeepEquals, []string{"w1", "spare", "w3", "w1"})
+
+	err := m.CheckpointCompleted(job, "w1", 4)
+	c.Assert(xerrors.Is(err, ErrInvalidJobState), gc.Equals, true)
+
+	for _, w := range []string{"w1", "spare", "w3"} {
+		st, _ = m.JobStatus(job)
+		c.Assert(st.State, gc.Equals, JobStateRecovering)
+		c.Assert(m.RecoveryCompleted(job, w), gc.IsNil)
+	}
+	st, _ = m.JobStatus(job)
+	c.Assert(st.State, gc.Equals, JobStateRunning)
+
+	// Without spares, partitions move to the least loaded survivor
+	c.Assert(m.WorkerFailed("w1"), gc.IsNil)
+	st, _ = m.JobStatus(job)
+	c.Assert(st.Assignment, gc.DeepEquals, []string{"spare", "spare", "w3", "spare"})
+}
+
+func (s *MasterTestSuite) TestJobFailsWhenNoWorkersRemain(c *gc.C) {
+	m := s.newMaster(c, 2, "w1")
+	job := s.submitJob(c, m)
+	c.Assert(m.WorkerFailed("w1"), gc.IsNil)
+
+	st, _ := m.JobStatus(job)
+	c.Assert(st.State, gc.Equals, JobStateFailed)
+
+	err := m.WorkerFailed("ghost")
+	c.Assert(xerrors.Is(err, ErrUnknownWorker), gc.Equals, true)
+}
+
+func (s *MasterTestSuite) TestInMemoryCheckpointStore(c *gc.C) {
+	store := NewInMemoryCheckpointStore()
+	_, err := store.Load("job", 0, 2)
+	c.Assert(xerrors.Is(err, ErrNoCheckpoint), gc.Equals, true)
+
+	cp := &bspgraph.Checkpoint{Superstep: 2}
+	c.Assert(store.Save("job", 0, cp), gc.IsNil)
+	got, err := store.Load("job", 0, 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, cp)
+}
@@ -0,0 +1,253 @@
+package distributed
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph"
+	"github.com/brandonshearin/ask_brandon/bspgraph/message"
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// RelayedMessage is a message addressed to a vertex managed by a remote
+// worker.
+type RelayedMessage struct {
+	DstID   string
+	Message message.Message
+}
+
+// Conn is implemented by connections to remote workers.
+type Conn interface {
+	// Send delivers a batch of messages to the remote worker.
+	Send(batch []RelayedMessage) error
+
+	// Close terminates the connection.
+	Close() error
+}
+
+// Dialer is implemented by types that can establish connections to remote
+// workers.
+type Dialer interface {
+	Dial(workerID string) (Conn, error)
+}
+
+// RelayerConfig encapsulates the settings for a BatchingRelayer.
+type RelayerConfig struct {
+	// LocalWorkerID is the ID of the worker that owns the relayer.
+	LocalWorkerID string
+
+	// Locate returns the ID of the worker that manages the vertex with
+	// the specified ID.
+	Locate func(vertexID string) string
+
+	// Dialer is used for connecting to remote workers.
+	Dialer Dialer
+
+	// MaxBatchSize is the number of pending messages for a destination
+	// worker that triggers a flush. Defaults to 128.
+	MaxBatchSize int
+
+	// FlushInterval is the maximum amount of time that messages may stay
+	// buffered before being flushed. Defaults to 100ms.
+	FlushInterval time.Duration
+
+	// MaxRetries is the number of times a failed delivery is retried,
+	// reconnecting to the remote worker before each attempt. Defaults to 3;
+	// a negative value disables retries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry; it doubles with
+	// every subsequent attempt. Defaults to 50ms.
+	RetryBackoff time.Duration
+}
+
+func (cfg *RelayerConfig) validate() error {
+	var err error
+	if cfg.LocalWorkerID == "" {
+		err = multierror.Append(err, xerrors.New("local worker ID not specified"))
+	}
+	if cfg.Locate == nil {
+		err = multierror.Append(err, xerrors.New("vertex locator not specified"))
+	}
+	if cfg.Dialer == nil {
+		err = multierror.Append(err, xerrors.New("dialer not specified"))
+	}
+	if cfg.MaxBatchSize <= 0 {
+		cfg.MaxBatchSize = 128
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 100 * time.Millisecond
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 3
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 50 * time.Millisecond
+	}
+	return err
+}
+
+// BatchingRelayer implements bspgraph.Relayer by buffering messages per
+// destination worker and delivering them in batches once either the batch
+// size or the flush interval threshold is reached.
+//
+// Errors encountered while flushing in the background are reported by the
+// next call to Relay or Flush. Workers should call Flush at the end of each
+// superstep to ensure that all messages have been delivered before entering
+// the barrier.
+type BatchingRelayer struct {
+	cfg RelayerConfig
+
+	mu      sync.Mutex
+	pending map[string][]RelayedMessage
+	err     error
+
+	// sendMu serializes deliveries and guards conns.
+	sendMu sync.Mutex
+	conns  map[string]Conn
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewBatchingRelayer creates a relayer with the specified config and starts
+// its background flusher.
+func NewBatchingRelayer(cfg RelayerConfig) (*BatchingRelayer, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("relayer config validation failed: %w", err)
+	}
+
+	r := &BatchingRelayer{
+		cfg:     cfg,
+		pending: make(map[string][]RelayedMessage),
+		conns:   make(map[string]Conn),
+		closeCh: make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.flushPeriodically()
+	return r, nil
+}
+
+// Relay implements bspgraph.Relayer.
+func (r *BatchingRelayer) Relay(dst string, msg message.Message) error {
+	workerID := r.cfg.Locate(dst)
+	if workerID == "" || workerID == r.cfg.LocalWorkerID {
+		return bspgraph.ErrDestinationIsLocal
+	}
+
+	r.mu.Lock()
+	if err := r.err; err != nil {
+		r.err = nil
+		r.mu.Unlock()
+		return err
+	}
+	batch := append(r.pending[workerID], RelayedMessage{DstID: dst, Message: msg})
+	if len(batch) < r.cfg.MaxBatchSize {
+		r.pending[workerID] = batch
+		r.mu.Unlock()
+		return nil
+	}
+	delete(r.pending, workerID)
+	r.mu.Unlock()
+
+	return r.deliver(workerID, batch)
+}
+
+// Flush synchronously delivers all buffered messages.
+func (r *BatchingRelayer) Flush() error {
+	r.mu.Lock()
+	err := r.err
+	r.err = nil
+	pending := r.pending
+	r.pending = make(map[string][]RelayedMessage)
+	r.mu.Unlock()
+
+	workerIDs := make([]string, 0, len(pending))
+	for workerID := range pending {
+		workerIDs = append(workerIDs, workerID)
+	}
+	sort.Strings(workerIDs)
+
+	for _, workerID := range workerIDs {
+		if dErr := r.deliver(workerID, pending[workerID]); dErr != nil {
+			err = multierror.Append(err, dErr)
+		}
+	}
+	return err
+}
+
+// Close stops the background flusher, delivers any buffered messages and
+// closes all connections to remote workers.
+func (r *BatchingRelayer) Close() error {
+	r.closeOnce.Do(func() { close(r.closeCh) })
+	r.wg.Wait()
+
+	err := r.Flush()
+
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+	for workerID, conn := range r.conns {
+		if cErr := conn.Close(); cErr != nil {
+			err = multierror.Append(err, xerrors.Errorf("closing connection to worker %q: %w", workerID, cErr))
+		}
+		delete(r.conns, workerID)
+	}
+	return err
+}
+
+func (r *BatchingRelayer) flushPeriodically() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				r.mu.Lock()
+				r.err = multierror.Append(r.err, err)
+				r.mu.Unlock()
+			}
+		case <-r.closeCh:
+			return
+		}
+	}
+}
+
+// deliver sends a batch to the specified worker. Failed attempts drop the
+// connection to the worker so that the next attempt reconnects.
+func (r *BatchingRelayer) deliver(workerID string, batch []RelayedMessage) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	var err error
+	backoff := r.cfg.RetryBackoff
+	for attempt := 0; attempt <= r.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		conn := r.conns[workerID]
+		if conn == nil {
+			if conn, err = r.cfg.Dialer.Dial(workerID); err != nil {
+				continue
+			}
+			r.conns[workerID] = conn
+		}
+
+		if err = conn.Send(batch); err == nil {
+			return nil
+		}
+		_ = conn.Close()
+		delete(r.conns, workerID)
+	}
+
+	return xerrors.Errorf("relay %d messages to worker %q: %w", len(batch), workerID, err)
+}
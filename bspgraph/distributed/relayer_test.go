@@ -0,0 +1,130 @@
+package distributed
+
+import (
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(BatchingRelayerTestSuite))
+
+type BatchingRelayerTestSuite struct{}
+
+type testMsg struct{ id int }
+
+func (testMsg) Type() string { return "testMsg" }
+
+// fakeDialer records the batches sent to each worker. The first failSends
+// calls to Send fail.
+type fakeDialer struct {
+	mu        sync.Mutex
+	dials     int
+	failSends int
+	batches   map[string][][]RelayedMessage
+}
+
+func (d *fakeDialer) Dial(workerID string) (Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dials++
+	return &fakeConn{d: d, workerID: workerID}, nil
+}
+
+func (d *fakeDialer) sent(workerID string) [][]RelayedMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.batches[workerID]
+}
+
+type fakeConn struct {
+	d        *fakeDialer
+	workerID string
+}
+
+func (c *fakeConn) Send(batch []RelayedMessage) error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	if c.d.failSends > 0 {
+		c.d.failSends--
+		return xerrors.New("connection reset")
+	}
+	if c.d.batches == nil {
+		c.d.batches = make(map[string][][]RelayedMessage)
+	}
+	c.d.batches[c.workerID] = append(c.d.batches[c.workerID], batch)
+	return nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (s *BatchingRelayerTestSuite) newRelayer(c *gc.C, d *fakeDialer, cfg RelayerConfig) *BatchingRelayer {
+	cfg.LocalWorkerID = "l"
+	cfg.Locate = func(id string) string { return id[:1] }
+	cfg.Dialer = d
+	cfg.RetryBackoff = time.Millisecond
+	r, err := NewBatchingRelayer(cfg)
+	c.Assert(err, gc.IsNil)
+	return r
+}
+
+func (s *BatchingRelayerTestSuite) TestBatchesBySize(c *gc.C) {
+	d := new(fakeDialer)
+	r := s.newRelayer(c, d, RelayerConfig{MaxBatchSize: 2, FlushInterval: time.Hour})
+
+	c.Assert(r.Relay("a1", testMsg{1}), gc.IsNil)
+	c.Assert(r.Relay("b1", testMsg{2}), gc.IsNil)
+	c.Assert(d.sent("a"), gc.HasLen, 0)
+
+	c.Assert(r.Relay("a2", testMsg{3}), gc.IsNil)
+	c.Assert(d.sent("a"), gc.DeepEquals, [][]RelayedMessage{
+		{{DstID: "a1", Message: testMsg{1}}, {DstID: "a2", Message: testMsg{3}}},
+	})
+
+	c.Assert(r.Close(), gc.IsNil)
+	c.Assert(d.sent("b"), gc.DeepEquals, [][]RelayedMessage{
+		{{DstID: "b1", Message: testMsg{2}}},
+	})
+}
+
+func (s *BatchingRelayerTestSuite) TestFlushesOnInterval(c *gc.C) {
+	d := new(fakeDialer)
+	r := s.newRelayer(c, d, RelayerConfig{FlushInterval: 10 * time.Millisecond})
+	defer func() { c.Assert(r.Close(), gc.IsNil) }()
+
+	c.Assert(r.Relay("a1", testMsg{1}), gc.IsNil)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(d.sent("a")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	c.Assert(d.sent("a"), gc.HasLen, 1)
+}
+
+func (s *BatchingRelayerTestSuite) TestLocalDestination(c *gc.C) {
+	d := new(fakeDialer)
+	r := s.newRelayer(c, d, RelayerConfig{FlushInterval: time.Hour})
+	defer func() { c.Assert(r.Close(), gc.IsNil) }()
+
+	err := r.Relay("local-vertex", testMsg{1})
+	c.Assert(xerrors.Is(err, bspgraph.ErrDestinationIsLocal), gc.Equals, true)
+}
+
+func (s *BatchingRelayerTestSuite) TestRetryAndReconnect(c *gc.C) {
+	d := &fakeDialer{failSends: 2}
+	r := s.newRelayer(c, d, RelayerConfig{FlushInterval: time.Hour})
+
+	c.Assert(r.Relay("a1", testMsg{1}), gc.IsNil)
+	c.Assert(r.Flush(), gc.IsNil)
+	c.Assert(d.sent("a"), gc.HasLen, 1)
+	c.Assert(d.dials, gc.Equals, 3)
+
+	// Exhausting all retries surfaces the error
+	d.failSends = 10
+	c.Assert(r.Relay("a2", testMsg{2}), gc.IsNil)
+	c.Assert(r.Flush(), gc.ErrorMatches, `(?s).*relay 1 messages to worker "a": connection reset.*`)
+
+	d.failSends = 0
+	c.Assert(r.Close(), gc.IsNil)
+}
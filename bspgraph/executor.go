@@ -35,10 +35,13 @@ type ExecutorCallbacks struct {
 type ExecutorFactory func(*Graph, ExecutorCallbacks) *Executor
 
 // NewExecutor returns an Executor instance for graph g that invokes the
-// provided list of callbacks inside each execution loop.
+// provided list of callbacks inside each execution loop.  The graph superstep
+// is reset and all vertices are reactivated so that a graph can be reused for
+// running multiple computations.
 func NewExecutor(g *Graph, cb ExecutorCallbacks) *Executor {
 	patchEmptyCallbacks(&cb)
 	g.superstep = 0
+	g.activateVertices()
 	return &Executor{
 		g:  g,
 		cb: cb,
@@ -1,8 +1,11 @@
 package bspgraph
 
 import (
+	"log"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/brandonshearin/ask_brandon/bspgraph/message"
 	"golang.org/x/xerrors"
@@ -17,6 +20,10 @@ var (
 	// that a message destination is actually owned by the local graph.
 	ErrDestinationIsLocal = xerrors.New("message destination is assigned to the local graph")
 
+	// ErrComputeTimeout is returned when the compute function for a vertex
+	// does not complete within the configured ComputeTimeout.
+	ErrComputeTimeout = xerrors.New("compute function timed out")
+
 	// ErrInvalidMessageDestination is returned by calls to SendMessage and
 	// BroadcastToNeighbors when the destination cannot be resolved to any
 	// (local or remote) vertex.
@@ -27,8 +34,13 @@ type Vertex struct {
 	id       string
 	value    interface{}
 	active   bool
+	failed   bool
 	msgQueue [2]message.Queue
 	edges    []*Edge
+
+	// topologyMu points to the topology lock of the graph that owns the
+	// vertex; it guards edges against concurrent AddEdge calls
+	topologyMu *sync.RWMutex
 }
 
 func (v *Vertex) ID() string { return v.id }
@@ -39,7 +51,21 @@ func (v *Vertex) SetValue(val interface{}) { v.value = val }
 
 func (v *Vertex) Freeze() { v.active = false }
 
-func (v *Vertex) Edges() []*Edge { return v.edges }
+// Edges returns the outgoing edges of the vertex.  It is safe to call while
+// edges are concurrently added to the vertex.
+func (v *Vertex) Edges() []*Edge { return v.edgeList() }
+
+// edgeList returns a snapshot of the outgoing edges of the vertex.  As
+// AddEdge only ever appends to the edge list, the returned slice is not
+// affected by edges added afterwards.
+func (v *Vertex) edgeList() []*Edge {
+	if v.topologyMu == nil {
+		return v.edges
+	}
+	v.topologyMu.RLock()
+	defer v.topologyMu.RUnlock()
+	return v.edges
+}
 
 type Edge struct {
 	value interface{}
@@ -54,15 +80,27 @@ func (e *Edge) SetValue(val interface{}) { e.value = val }
 
 // Graph implements a parallel graph processor based on the concepts described
 // in the Pregel paper.
+//
+// AddVertex and AddEdge are safe for concurrent use, both while loading the
+// graph and from within compute functions (e.g. when creating vertices upon
+// receiving relayed messages).  Vertices added while a superstep is running
+// will participate in the next superstep.
 type Graph struct {
 	superstep int
 
 	aggregators map[string]Aggregator
-	vertices    map[string]*Vertex
 	computeFn   ComputeFunc
 
+	computeTimeout      time.Duration
+	slowVertexThreshold time.Duration
+
+	// topologyMu guards the vertices map and the edge lists of vertices
+	topologyMu sync.RWMutex
+	vertices   map[string]*Vertex
+
 	queueFactory message.QueueFactory
 	relayer      Relayer
+	queueStats   QueueStats
 
 	wg              sync.WaitGroup
 	vertexCh        chan *Vertex
@@ -82,6 +120,9 @@ func NewGraph(cfg GraphConfig) (*Graph, error) {
 		queueFactory: cfg.QueueFactory,
 		aggregators:  make(map[string]Aggregator),
 		vertices:     make(map[string]*Vertex),
+
+		computeTimeout:      cfg.ComputeTimeout,
+		slowVertexThreshold: cfg.SlowVertexThreshold,
 	}
 
 	g.startWorkers(cfg.ComputeWorkers)
@@ -90,6 +131,9 @@ func NewGraph(cfg GraphConfig) (*Graph, error) {
 }
 
 func (g *Graph) Reset() error {
+	g.topologyMu.Lock()
+	defer g.topologyMu.Unlock()
+
 	g.superstep = 0
 	for _, v := range g.vertices {
 		for i := 0; i < 2; i++ {
@@ -115,6 +159,9 @@ func (g *Graph) Close() error {
 // into the graph.  If the vertex already exists, AddVertex will just overwrite
 // its value with the provided initValue
 func (g *Graph) AddVertex(id string, initValue interface{}) {
+	g.topologyMu.Lock()
+	defer g.topologyMu.Unlock()
+
 	v := g.vertices[id]
 	// if vertex not in graph, create & add
 	if v == nil {
@@ -124,7 +171,8 @@ func (g *Graph) AddVertex(id string, initValue interface{}) {
 				g.queueFactory(),
 				g.queueFactory(),
 			},
-			active: true,
+			active:     true,
+			topologyMu: &g.topologyMu,
 		}
 		g.vertices[id] = v
 	}
@@ -136,6 +184,9 @@ func (g *Graph) AddVertex(id string, initValue interface{}) {
 // By design, edges are owned by the source vertices (destinations can be either local or remote)
 // and therefore srcID must resolve to a local vertex.  Otherwise, AddEdge returns an error
 func (g *Graph) AddEdge(srcID, dstID string, initValue interface{}) error {
+	g.topologyMu.Lock()
+	defer g.topologyMu.Unlock()
+
 	srcVert := g.vertices[srcID]
 	if srcVert == nil {
 		return xerrors.Errorf("create edge from %q to %q: %w", srcID, dstID, ErrUnknownEdgeSource)
@@ -149,6 +200,18 @@ func (g *Graph) AddEdge(srcID, dstID string, initValue interface{}) error {
 	return nil
 }
 
+// Vertices returns a snapshot of the graph vertices keyed by their ID.
+func (g *Graph) Vertices() map[string]*Vertex {
+	g.topologyMu.RLock()
+	defer g.topologyMu.RUnlock()
+
+	vertices := make(map[string]*Vertex, len(g.vertices))
+	for id, v := range g.vertices {
+		vertices[id] = v
+	}
+	return vertices
+}
+
 // Superstep returns the current superstep value.
 func (g *Graph) Superstep() int { return g.superstep }
 
@@ -170,7 +233,7 @@ func (g *Graph) Aggregators() map[string]Aggregator { return g.aggregators }
 // to each neighbor of a particular vertex.  Messages are queued for delivery
 // and will be processed by recipients in the next superstep
 func (g *Graph) BroadcastToNeighbors(v *Vertex, msg message.Message) error {
-	for _, e := range v.edges {
+	for _, e := range v.edgeList() {
 		if err := g.SendMessage(e.dstID, msg); err != nil {
 			return err
 		}
@@ -180,7 +243,10 @@ func (g *Graph) BroadcastToNeighbors(v *Vertex, msg message.Message) error {
 }
 
 func (g *Graph) SendMessage(dstID string, msg message.Message) error {
+	g.topologyMu.RLock()
 	dstVert := g.vertices[dstID]
+	g.topologyMu.RUnlock()
+
 	if dstVert != nil {
 		queueIndex := (g.superstep + 1) % 2
 		return dstVert.msgQueue[queueIndex].Enqueue(msg)
@@ -211,12 +277,22 @@ func (g *Graph) startWorkers(numWorkers int) {
 // Step executes the next superstep and returns back the number of vertices that
 // we processed either bcause they were still active or because they receieved a message
 func (g *Graph) step() (activeInStep int, err error) {
-	g.activeInStep, g.pendingInStep = 0, int64(len(g.vertices))
+	// Take a snapshot of the vertex set so compute functions can add new
+	// vertices without having to wait for the superstep to complete
+	g.topologyMu.RLock()
+	vertices := make([]*Vertex, 0, len(g.vertices))
+	for _, v := range g.vertices {
+		vertices = append(vertices, v)
+	}
+	g.topologyMu.RUnlock()
+
+	g.queueStats = g.collectQueueStats(vertices)
+	g.activeInStep, g.pendingInStep = 0, int64(len(vertices))
 	if g.pendingInStep == 0 {
 		return 0, nil //no work required
 	}
 
-	for _, v := range g.vertices {
+	for _, v := range vertices {
 		g.vertexCh <- v
 	}
 
@@ -238,10 +314,10 @@ func (g *Graph) step() (activeInStep int, err error) {
 func (g *Graph) stepWorker() {
 	for v := range g.vertexCh {
 		buffer := g.superstep % 2
-		if v.active || v.msgQueue[buffer].PendingMessages() {
+		if !v.failed && (v.active || v.msgQueue[buffer].PendingMessages()) {
 			_ = atomic.AddInt64(&g.activeInStep, 1)
 			v.active = true
-			if err := g.computeFn(g, v, v.msgQueue[buffer].Messages()); err != nil {
+			if err := g.compute(v, v.msgQueue[buffer].Messages()); err != nil {
 				tryEmitError(g.errCh, xerrors.Errorf("running compute function for vertex %q failed: %w", v.ID(), err))
 			} else if err := v.msgQueue[buffer].DiscardMessages(); err != nil {
 				tryEmitError(g.errCh, xerrors.Errorf("discarding unprocessed messages for vertex %q failed: %w", v.ID(), err))
@@ -255,6 +331,74 @@ func (g *Graph) stepWorker() {
 	g.wg.Done()
 }
 
+// compute invokes the ComputeFunc for v while enforcing the configured compute
+// timeout and reporting slow vertices.
+func (g *Graph) compute(v *Vertex, msgIt message.Iterator) error {
+	if g.computeTimeout <= 0 && g.slowVertexThreshold <= 0 {
+		return g.computeFn(g, v, msgIt)
+	}
+
+	var (
+		start     = time.Now()
+		doneCh    = make(chan error, 1)
+		slowCh    <-chan time.Time
+		timeoutCh <-chan time.Time
+	)
+	go func() { doneCh <- g.computeFn(g, v, msgIt) }()
+
+	if g.slowVertexThreshold > 0 {
+		slowTimer := time.NewTimer(g.slowVertexThreshold)
+		defer slowTimer.Stop()
+		slowCh = slowTimer.C
+	}
+	if g.computeTimeout > 0 {
+		timeoutTimer := time.NewTimer(g.computeTimeout)
+		defer timeoutTimer.Stop()
+		timeoutCh = timeoutTimer.C
+	}
+
+	for {
+		select {
+		case err := <-doneCh:
+			return err
+		case <-slowCh:
+			log.Printf("bspgraph: compute function for vertex %q has been running for %s in superstep %d", v.ID(), time.Since(start), g.superstep)
+			slowCh = nil
+		case <-timeoutCh:
+			v.failed = true
+			v.active = false
+			return xerrors.Errorf("after %s: %w", g.computeTimeout, ErrComputeTimeout)
+		}
+	}
+}
+
+// activateVertices marks all vertices, except the ones that have failed, as
+// active.
+func (g *Graph) activateVertices() {
+	g.topologyMu.RLock()
+	defer g.topologyMu.RUnlock()
+
+	for _, v := range g.vertices {
+		v.active = !v.failed
+	}
+}
+
+// FailedVertices returns the IDs of the vertices whose compute function
+// exceeded the configured compute timeout.
+func (g *Graph) FailedVertices() []string {
+	g.topologyMu.RLock()
+	defer g.topologyMu.RUnlock()
+
+	var failed []string
+	for id, v := range g.vertices {
+		if v.failed {
+			failed = append(failed, id)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
 func tryEmitError(errCh chan<- error, err error) {
 	select {
 	case errCh <- err: // queued error
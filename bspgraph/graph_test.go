@@ -0,0 +1,147 @@
+package bspgraph
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph/message"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(GraphTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type GraphTestSuite struct{}
+
+func (s *GraphTestSuite) TestConcurrentTopologyUpdates(c *gc.C) {
+	g, err := NewGraph(GraphConfig{
+		ComputeFn: func(*Graph, *Vertex, message.Iterator) error { return nil },
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	var (
+		numLoaders  = 8
+		numVertices = 100
+		wg          sync.WaitGroup
+	)
+	wg.Add(numLoaders)
+	for loader := 0; loader < numLoaders; loader++ {
+		go func(loader int) {
+			defer wg.Done()
+			for i := 0; i < numVertices; i++ {
+				id := fmt.Sprintf("%d-%d", loader, i)
+				g.AddVertex(id, i)
+				c.Check(g.AddEdge(id, "0-0", nil), gc.IsNil)
+			}
+		}(loader)
+	}
+	wg.Wait()
+
+	c.Assert(g.vertices, gc.HasLen, numLoaders*numVertices)
+	for id, v := range g.vertices {
+		c.Assert(v.Edges(), gc.HasLen, 1, gc.Commentf("vertex %s", id))
+	}
+}
+
+func (s *GraphTestSuite) TestReadEdgesWhileAddingEdges(c *gc.C) {
+	g, err := NewGraph(GraphConfig{
+		ComputeFn: func(*Graph, *Vertex, message.Iterator) error { return nil },
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	g.AddVertex("src", nil)
+	v := g.Vertices()["src"]
+
+	numEdges := 100
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for i := 0; i < numEdges; i++ {
+			c.Check(g.AddEdge("src", fmt.Sprint(i), i), gc.IsNil)
+		}
+	}()
+
+	// Run with -race to detect unguarded accesses to the edge list
+	for done := false; !done; {
+		select {
+		case <-doneCh:
+			done = true
+		default:
+		}
+		_ = v.Edges()
+		_ = v.EdgesWhere(func(*Edge) bool { return true })
+		_ = v.RandomNeighbors(3)
+		_ = v.WeightedRandomEdge(func(*Edge) float64 { return 1 })
+	}
+	c.Assert(v.Edges(), gc.HasLen, numEdges)
+}
+
+func (s *GraphTestSuite) TestComputeTimeout(c *gc.C) {
+	unblockCh := make(chan struct{})
+	defer close(unblockCh)
+
+	g, err := NewGraph(GraphConfig{
+		ComputeFn: func(_ *Graph, v *Vertex, _ message.Iterator) error {
+			if v.ID() == "stuck" {
+				<-unblockCh
+			}
+			return nil
+		},
+		ComputeTimeout:      100 * time.Millisecond,
+		SlowVertexThreshold: 20 * time.Millisecond,
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	g.AddVertex("ok", nil)
+	g.AddVertex("stuck", nil)
+
+	err = NewExecutor(g, ExecutorCallbacks{}).RunSteps(context.TODO(), 1)
+	c.Assert(xerrors.Is(err, ErrComputeTimeout), gc.Equals, true)
+	c.Assert(g.FailedVertices(), gc.DeepEquals, []string{"stuck"})
+
+	// failed vertices are excluded from subsequent supersteps
+	activeInStep, err := g.step()
+	c.Assert(err, gc.IsNil)
+	c.Assert(activeInStep, gc.Equals, 1)
+}
+
+func (s *GraphTestSuite) TestQueueStats(c *gc.C) {
+	g, err := NewGraph(GraphConfig{
+		ComputeFn: func(g *Graph, v *Vertex, _ message.Iterator) error {
+			if g.Superstep() == 0 {
+				return g.BroadcastToNeighbors(v, intMsg{val: 1})
+			}
+			return nil
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(g.Close(), gc.IsNil) }()
+
+	for _, id := range []string{"a", "b", "hub"} {
+		g.AddVertex(id, nil)
+	}
+	c.Assert(g.AddEdge("a", "hub", nil), gc.IsNil)
+	c.Assert(g.AddEdge("b", "hub", nil), gc.IsNil)
+	c.Assert(g.AddEdge("hub", "a", nil), gc.IsNil)
+
+	ex := NewExecutor(g, ExecutorCallbacks{})
+	c.Assert(ex.RunSteps(context.TODO(), 1), gc.IsNil)
+	c.Assert(g.VertexQueueLen("hub"), gc.Equals, 2)
+	c.Assert(g.VertexQueueLen("missing"), gc.Equals, -1)
+
+	c.Assert(ex.RunSteps(context.TODO(), 1), gc.IsNil)
+	c.Assert(g.QueueStats(), gc.DeepEquals, QueueStats{
+		Superstep:         1,
+		Messages:          3,
+		MaxVertexID:       "hub",
+		MaxVertexMessages: 2,
+	})
+}
@@ -0,0 +1,251 @@
+package message
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrQueueFull is returned by bounded queues when a message cannot be
+// enqueued because the queue is at capacity.
+var ErrQueueFull = xerrors.New("message queue is full")
+
+// OverflowPolicy specifies how a bounded queue handles messages that arrive
+// while it is at capacity.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock makes Enqueue wait for messages to be dequeued. If no
+	// room becomes available within the configured block timeout,
+	// Enqueue fails with ErrQueueFull. Note that in a BSP computation the
+	// messages enqueued during a superstep are only consumed in the next
+	// one, so blocking mostly serves to throttle senders while queues are
+	// being drained concurrently.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowSpill diverts messages to a secondary (e.g. disk-backed)
+	// queue once the primary queue is at capacity.
+	OverflowSpill
+)
+
+// BoundedQueueConfig encapsulates the settings for bounded queues.
+type BoundedQueueConfig struct {
+	// MaxMessages is the maximum number of messages held by the primary
+	// queue.
+	MaxMessages int
+
+	// Policy specifies how messages are handled when the primary queue is
+	// full.
+	Policy OverflowPolicy
+
+	// BlockTimeout is the maximum time Enqueue waits for room to become
+	// available when using OverflowBlock. Defaults to 1s.
+	BlockTimeout time.Duration
+
+	// QueueFactory creates the primary queue. If not specified, in-memory
+	// queues will be used.
+	QueueFactory QueueFactory
+
+	// SpillQueueFactory creates the queue that receives the overflowing
+	// messages when using OverflowSpill.
+	SpillQueueFactory QueueFactory
+}
+
+func (cfg *BoundedQueueConfig) validate() error {
+	if cfg.MaxMessages <= 0 {
+		return xerrors.New("max messages must be positive")
+	}
+	if cfg.QueueFactory == nil {
+		cfg.QueueFactory = NewInMemoryQueue
+	}
+	switch cfg.Policy {
+	case OverflowBlock:
+		if cfg.BlockTimeout <= 0 {
+			cfg.BlockTimeout = time.Second
+		}
+	case OverflowSpill:
+		if cfg.SpillQueueFactory == nil {
+			return xerrors.New("spill queue factory not specified")
+		}
+	default:
+		return xerrors.Errorf("unsupported overflow policy %d", cfg.Policy)
+	}
+	return nil
+}
+
+// NewBoundedQueueFactory returns a QueueFactory for queues that hold at most
+// cfg.MaxMessages messages in their primary queue and apply backpressure
+// according to cfg.Policy once that limit is reached. The returned queues
+// implement Sizer.
+func NewBoundedQueueFactory(cfg BoundedQueueConfig) (QueueFactory, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("bounded queue config validation failed: %w", err)
+	}
+
+	return func() Queue {
+		return &boundedQueue{
+			cfg:     cfg,
+			primary: cfg.QueueFactory(),
+			spaceCh: make(chan struct{}),
+		}
+	}, nil
+}
+
+type boundedQueue struct {
+	cfg BoundedQueueConfig
+
+	mu         sync.Mutex
+	primary    Queue
+	primaryLen int
+	spill      Queue
+	spillLen   int
+
+	// spaceCh is closed (and replaced) whenever messages are removed from
+	// the primary queue to wake up blocked producers.
+	spaceCh chan struct{}
+
+	primaryIt  Iterator
+	spillIt    Iterator
+	latchedMsg Message
+}
+
+func (q *boundedQueue) Enqueue(msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.primaryLen < q.cfg.MaxMessages {
+		return q.enqueuePrimary(msg)
+	}
+
+	if q.cfg.Policy == OverflowSpill {
+		if q.spill == nil {
+			q.spill = q.cfg.SpillQueueFactory()
+		}
+		if err := q.spill.Enqueue(msg); err != nil {
+			return err
+		}
+		q.spillLen++
+		return nil
+	}
+
+	timeout := time.NewTimer(q.cfg.BlockTimeout)
+	defer timeout.Stop()
+	for q.primaryLen >= q.cfg.MaxMessages {
+		spaceCh := q.spaceCh
+		q.mu.Unlock()
+		select {
+		case <-spaceCh:
+			q.mu.Lock()
+		case <-timeout.C:
+			q.mu.Lock()
+			return xerrors.Errorf("blocked for %s: %w", q.cfg.BlockTimeout, ErrQueueFull)
+		}
+	}
+	return q.enqueuePrimary(msg)
+}
+
+func (q *boundedQueue) enqueuePrimary(msg Message) error {
+	if err := q.primary.Enqueue(msg); err != nil {
+		return err
+	}
+	q.primaryLen++
+	return nil
+}
+
+// Len implements Sizer.
+func (q *boundedQueue) Len() int {
+	q.mu.Lock()
+	n := q.primaryLen + q.spillLen
+	q.mu.Unlock()
+	return n
+}
+
+func (q *boundedQueue) PendingMessages() bool { return q.Len() != 0 }
+
+func (q *boundedQueue) DiscardMessages() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := q.primary.DiscardMessages(); err != nil {
+		return err
+	}
+	if q.spill != nil {
+		if err := q.spill.DiscardMessages(); err != nil {
+			return err
+		}
+	}
+	q.primaryLen, q.spillLen = 0, 0
+	q.notifySpace()
+	return nil
+}
+
+func (q *boundedQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.spill != nil {
+		if err := q.spill.Close(); err != nil {
+			return err
+		}
+	}
+	return q.primary.Close()
+}
+
+func (q *boundedQueue) Messages() Iterator {
+	q.mu.Lock()
+	q.primaryIt, q.spillIt = q.primary.Messages(), nil
+	q.mu.Unlock()
+	return q
+}
+
+// Next drains the primary queue before the spilled messages.
+func (q *boundedQueue) Next() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.primaryIt != nil && q.primaryIt.Next() {
+		q.latchedMsg = q.primaryIt.Message()
+		q.primaryLen--
+		q.notifySpace()
+		return true
+	}
+	if q.spillIt == nil && q.spill != nil {
+		q.spillIt = q.spill.Messages()
+	}
+	if q.spillIt != nil && q.spillIt.Next() {
+		q.latchedMsg = q.spillIt.Message()
+		q.spillLen--
+		return true
+	}
+	return false
+}
+
+func (q *boundedQueue) Message() Message {
+	q.mu.Lock()
+	msg := q.latchedMsg
+	q.mu.Unlock()
+	return msg
+}
+
+func (q *boundedQueue) Error() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.primaryIt != nil {
+		if err := q.primaryIt.Error(); err != nil {
+			return err
+		}
+	}
+	if q.spillIt != nil {
+		return q.spillIt.Error()
+	}
+	return nil
+}
+
+// notifySpace wakes up any producers blocked on a full queue. It must be
+// called while holding q.mu.
+func (q *boundedQueue) notifySpace() {
+	close(q.spaceCh)
+	q.spaceCh = make(chan struct{})
+}
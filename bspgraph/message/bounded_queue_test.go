@@ -0,0 +1,89 @@
+package message
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(BoundedQueueTest))
+
+type BoundedQueueTest struct{}
+
+func (s *BoundedQueueTest) TestSpill(c *gc.C) {
+	var spilled Queue
+	factory, err := NewBoundedQueueFactory(BoundedQueueConfig{
+		MaxMessages: 2,
+		Policy:      OverflowSpill,
+		SpillQueueFactory: func() Queue {
+			spilled = NewInMemoryQueue()
+			return spilled
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	q := factory()
+	defer func() { c.Assert(q.Close(), gc.IsNil) }()
+
+	for i := 0; i < 5; i++ {
+		c.Assert(q.Enqueue(msg{payload: fmt.Sprint(i)}), gc.IsNil)
+	}
+	c.Assert(q.(Sizer).Len(), gc.Equals, 5)
+	c.Assert(spilled.(Sizer).Len(), gc.Equals, 3)
+
+	var got []string
+	for it := q.Messages(); it.Next(); {
+		got = append(got, it.Message().(msg).payload)
+	}
+	c.Assert(got, gc.HasLen, 5)
+	c.Assert(q.PendingMessages(), gc.Equals, false)
+}
+
+func (s *BoundedQueueTest) TestBlockTimesOut(c *gc.C) {
+	factory, err := NewBoundedQueueFactory(BoundedQueueConfig{
+		MaxMessages:  1,
+		Policy:       OverflowBlock,
+		BlockTimeout: 10 * time.Millisecond,
+	})
+	c.Assert(err, gc.IsNil)
+	q := factory()
+
+	c.Assert(q.Enqueue(msg{payload: "0"}), gc.IsNil)
+	err = q.Enqueue(msg{payload: "1"})
+	c.Assert(xerrors.Is(err, ErrQueueFull), gc.Equals, true)
+	c.Assert(q.(Sizer).Len(), gc.Equals, 1)
+}
+
+func (s *BoundedQueueTest) TestBlockResumesWhenDrained(c *gc.C) {
+	factory, err := NewBoundedQueueFactory(BoundedQueueConfig{
+		MaxMessages:  1,
+		Policy:       OverflowBlock,
+		BlockTimeout: 5 * time.Second,
+	})
+	c.Assert(err, gc.IsNil)
+	q := factory()
+	c.Assert(q.Enqueue(msg{payload: "0"}), gc.IsNil)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- q.Enqueue(msg{payload: "1"}) }()
+
+	select {
+	case err = <-errCh:
+		c.Fatalf("expected Enqueue to block; got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	it := q.Messages()
+	c.Assert(it.Next(), gc.Equals, true)
+	c.Assert(<-errCh, gc.IsNil)
+	c.Assert(q.(Sizer).Len(), gc.Equals, 1)
+}
+
+func (s *BoundedQueueTest) TestInvalidConfig(c *gc.C) {
+	_, err := NewBoundedQueueFactory(BoundedQueueConfig{})
+	c.Assert(err, gc.NotNil)
+
+	_, err = NewBoundedQueueFactory(BoundedQueueConfig{MaxMessages: 1, Policy: OverflowSpill})
+	c.Assert(err, gc.ErrorMatches, ".*spill queue factory not specified")
+}
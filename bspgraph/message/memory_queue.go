@@ -33,6 +33,14 @@ func (q *inMemoryQueue) PendingMessages() bool {
 	return pending
 }
 
+func (q *inMemoryQueue) Len() int {
+	q.mu.Lock()
+	n := len(q.msgs)
+	q.mu.Unlock()
+
+	return n
+}
+
 func (q *inMemoryQueue) DiscardMessages() error {
 	q.mu.Lock()
 	q.msgs = q.msgs[:0]
@@ -24,6 +24,13 @@ type Queue interface {
 	Messages() Iterator
 }
 
+// Sizer is implemented by queues that can report the number of messages they
+// currently hold.
+type Sizer interface {
+	// Len returns the number of queued messages
+	Len() int
+}
+
 // Iterator provides an API for iterating a list of messages
 type Iterator interface {
 	// Next advances the iterator so taht the next message can be
@@ -0,0 +1,75 @@
+package bspgraph
+
+import "github.com/brandonshearin/ask_brandon/bspgraph/message"
+
+// QueueStats summarizes the message queues of the graph vertices at the start
+// of a superstep. Only queues that implement message.Sizer are accounted for.
+type QueueStats struct {
+	// Superstep is the superstep the stats were collected for.
+	Superstep int
+
+	// Messages is the total number of messages delivered to vertices.
+	Messages int
+
+	// MaxVertexID is the vertex with the most incoming messages and
+	// MaxVertexMessages the number of messages it received.
+	MaxVertexID       string
+	MaxVertexMessages int
+}
+
+// QueueStats returns the queue stats collected at the start of the last
+// executed superstep. It should only be invoked between supersteps, e.g.
+// from an executor callback.
+func (g *Graph) QueueStats() QueueStats { return g.queueStats }
+
+// VertexQueueLen returns the number of messages queued for delivery to the
+// vertex with the specified ID. It should only be invoked between supersteps
+// and returns -1 if the vertex does not exist or its queues do not implement
+// message.Sizer.
+func (g *Graph) VertexQueueLen(id string) int {
+	g.topologyMu.RLock()
+	v := g.vertices[id]
+	g.topologyMu.RUnlock()
+
+	if v == nil {
+		return -1
+	}
+
+	// Messages consumed by the last superstep have already been discarded
+	// so the pending messages reside in either one of the two queues.
+	var total int
+	for i := 0; i < 2; i++ {
+		n := queueLen(v.msgQueue[i])
+		if n < 0 {
+			return -1
+		}
+		total += n
+	}
+	return total
+}
+
+// collectQueueStats computes the stats for the queues that will be consumed
+// by the current superstep.
+func (g *Graph) collectQueueStats(vertices []*Vertex) QueueStats {
+	stats := QueueStats{Superstep: g.superstep}
+	buffer := g.superstep % 2
+	for _, v := range vertices {
+		n := queueLen(v.msgQueue[buffer])
+		if n <= 0 {
+			continue
+		}
+
+		stats.Messages += n
+		if n > stats.MaxVertexMessages {
+			stats.MaxVertexID, stats.MaxVertexMessages = v.id, n
+		}
+	}
+	return stats
+}
+
+func queueLen(q message.Queue) int {
+	if sizer, ok := q.(message.Sizer); ok {
+		return sizer.Len()
+	}
+	return -1
+}
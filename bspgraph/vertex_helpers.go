@@ -0,0 +1,60 @@
+package bspgraph
+
+import "math/rand"
+
+// EdgesWhere returns the outgoing edges of the vertex that satisfy pred.
+func (v *Vertex) EdgesWhere(pred func(*Edge) bool) []*Edge {
+	var matched []*Edge
+	for _, e := range v.edgeList() {
+		if pred(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// RandomNeighbors returns up to k distinct outgoing edges of the vertex
+// selected uniformly at random. If the vertex has k or fewer edges, all of
+// them are returned in random order.
+func (v *Vertex) RandomNeighbors(k int) []*Edge {
+	vertexEdges := v.edgeList()
+	if k > len(vertexEdges) {
+		k = len(vertexEdges)
+	}
+	if k <= 0 {
+		return nil
+	}
+
+	// Run a partial Fisher-Yates shuffle on a copy of the edge list so the
+	// vertex edges are not reordered
+	edges := append([]*Edge(nil), vertexEdges...)
+	for i := 0; i < k; i++ {
+		j := i + rand.Intn(len(edges)-i)
+		edges[i], edges[j] = edges[j], edges[i]
+	}
+	return edges[:k]
+}
+
+// WeightedRandomEdge selects an outgoing edge of the vertex at random with a
+// probability proportional to the value returned by weight. Edges with a
+// non-positive weight are never selected. It returns nil if the vertex has
+// no edges with a positive weight.
+func (v *Vertex) WeightedRandomEdge(weight func(*Edge) float64) *Edge {
+	var (
+		total    float64
+		selected *Edge
+	)
+	// Use weighted reservoir sampling so edge weights only need to be
+	// evaluated once
+	for _, e := range v.edgeList() {
+		w := weight(e)
+		if w <= 0 {
+			continue
+		}
+		total += w
+		if rand.Float64()*total < w {
+			selected = e
+		}
+	}
+	return selected
+}
@@ -0,0 +1,63 @@
+package bspgraph
+
+import (
+	"fmt"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(VertexHelpersTestSuite))
+
+type VertexHelpersTestSuite struct {
+	v *Vertex
+}
+
+func (s *VertexHelpersTestSuite) SetUpTest(c *gc.C) {
+	s.v = &Vertex{id: "src"}
+	for i := 0; i < 10; i++ {
+		s.v.edges = append(s.v.edges, &Edge{dstID: fmt.Sprint(i), value: i})
+	}
+}
+
+func (s *VertexHelpersTestSuite) TestEdgesWhere(c *gc.C) {
+	even := s.v.EdgesWhere(func(e *Edge) bool { return e.Value().(int)%2 == 0 })
+	c.Assert(even, gc.HasLen, 5)
+	for _, e := range even {
+		c.Assert(e.Value().(int)%2, gc.Equals, 0)
+	}
+}
+
+func (s *VertexHelpersTestSuite) TestRandomNeighbors(c *gc.C) {
+	picked := s.v.RandomNeighbors(4)
+	c.Assert(picked, gc.HasLen, 4)
+
+	seen := make(map[string]bool)
+	for _, e := range picked {
+		c.Assert(seen[e.DstID()], gc.Equals, false, gc.Commentf("duplicate neighbor %s", e.DstID()))
+		seen[e.DstID()] = true
+	}
+
+	c.Assert(s.v.RandomNeighbors(100), gc.HasLen, 10)
+	c.Assert(s.v.RandomNeighbors(0), gc.HasLen, 0)
+
+	// the edge list of the vertex must not be reordered
+	for i, e := range s.v.Edges() {
+		c.Assert(e.DstID(), gc.Equals, fmt.Sprint(i))
+	}
+}
+
+func (s *VertexHelpersTestSuite) TestWeightedRandomEdge(c *gc.C) {
+	// only a single edge has a positive weight
+	for i := 0; i < 10; i++ {
+		e := s.v.WeightedRandomEdge(func(e *Edge) float64 {
+			if e.DstID() == "7" {
+				return 2
+			}
+			return 0
+		})
+		c.Assert(e, gc.NotNil)
+		c.Assert(e.DstID(), gc.Equals, "7")
+	}
+
+	c.Assert(s.v.WeightedRandomEdge(func(*Edge) float64 { return 0 }), gc.IsNil)
+}
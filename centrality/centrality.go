@@ -0,0 +1,284 @@
+package centrality
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph"
+	"github.com/brandonshearin/ask_brandon/bspgraph/message"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// Config encapsulates the settings for a centrality Calculator.
+type Config struct {
+	// ComputeWorkers is the number of workers used for executing each
+	// superstep. If not specified, a single worker will be used.
+	ComputeWorkers int
+
+	// Samples is the number of source vertices used for approximating the
+	// centrality scores. If zero or larger than the number of vertices,
+	// all vertices are used as sources and the scores are exact.
+	Samples int
+
+	// Seed initializes the random number generator used for sampling the
+	// source vertices.
+	Seed int64
+}
+
+// Scores holds the centrality scores of a single vertex.
+type Scores struct {
+	// Betweenness is the (estimated) number of shortest paths between
+	// other vertex pairs that pass through the vertex.
+	Betweenness float64
+
+	// Closeness measures how easily the vertex is reached from the rest of
+	// the graph. It is computed over incoming shortest paths using the
+	// Wasserman-Faust formula so that it remains meaningful for graphs
+	// that are not strongly connected.
+	Closeness float64
+}
+
+// Calculator computes approximate betweenness and closeness centrality
+// scores for the vertices of an unweighted directed graph.
+//
+// For each sampled source vertex, a breadth-first search is executed on a
+// bspgraph instance to calculate the distance and the number of shortest
+// paths from the source to every vertex. The dependency accumulation phase of
+// Brandes' algorithm is then performed on the collected search results.
+type Calculator struct {
+	cfg             Config
+	g               *bspgraph.Graph
+	srcID           string
+	executorFactory bspgraph.ExecutorFactory
+}
+
+// NewCalculator returns a new centrality calculator instance.
+func NewCalculator(cfg Config) (*Calculator, error) {
+	c := &Calculator{
+		cfg:             cfg,
+		executorFactory: bspgraph.NewExecutor,
+	}
+
+	var err error
+	if c.g, err = bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn:      c.bfsStep,
+		ComputeWorkers: cfg.ComputeWorkers,
+	}); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Close cleans up any allocated graph resources.
+func (c *Calculator) Close() error {
+	return c.g.Close()
+}
+
+// SetExecutorFactory configures the calculator to use a custom executor
+// factory when Calculate is invoked.
+func (c *Calculator) SetExecutorFactory(factory bspgraph.ExecutorFactory) {
+	c.executorFactory = factory
+}
+
+// AddVertex inserts a new vertex with the specified ID into the graph.
+func (c *Calculator) AddVertex(id string) {
+	c.g.AddVertex(id, nil)
+}
+
+// AddEdge creates a directed edge from srcID to dstID.
+func (c *Calculator) AddEdge(srcID, dstID string) error {
+	return c.g.AddEdge(srcID, dstID, nil)
+}
+
+// Calculate computes the centrality scores for all graph vertices.
+func (c *Calculator) Calculate(ctx context.Context) (map[string]Scores, error) {
+	vertices := c.g.Vertices()
+	ids := make([]string, 0, len(vertices))
+	for id := range vertices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	sources := ids
+	if c.cfg.Samples > 0 && c.cfg.Samples < len(ids) {
+		rng := rand.New(rand.NewSource(c.cfg.Seed))
+		sources = make([]string, c.cfg.Samples)
+		for i, j := range rng.Perm(len(ids))[:c.cfg.Samples] {
+			sources[i] = ids[j]
+		}
+	}
+
+	var (
+		betweenness = make(map[string]float64, len(ids))
+		distSum     = make(map[string]int, len(ids))
+		reachedBy   = make(map[string]int, len(ids))
+	)
+	for _, srcID := range sources {
+		if err := c.search(ctx, srcID); err != nil {
+			return nil, xerrors.Errorf("centrality: search from %q: %w", srcID, err)
+		}
+
+		visited := c.accumulate(srcID, vertices, betweenness)
+		for _, v := range visited {
+			if st := v.Value().(*bfsState); v.ID() != srcID {
+				distSum[v.ID()] += st.dist
+				reachedBy[v.ID()]++
+			}
+		}
+	}
+
+	// Scale the sampled betweenness estimates so they approximate the
+	// scores that would be obtained by using all vertices as sources.
+	scale := float64(len(ids)) / float64(len(sources))
+	scores := make(map[string]Scores, len(ids))
+	for _, id := range ids {
+		s := Scores{Betweenness: betweenness[id] * scale}
+
+		// Sources other than the vertex itself that could have reached it
+		candidates := len(sources)
+		for _, srcID := range sources {
+			if srcID == id {
+				candidates--
+				break
+			}
+		}
+		if r := reachedBy[id]; r > 0 && distSum[id] > 0 {
+			s.Closeness = (float64(r) / float64(candidates)) * (float64(r) / float64(distSum[id]))
+		}
+		scores[id] = s
+	}
+
+	return scores, nil
+}
+
+// search runs a breadth-first search from srcID.
+func (c *Calculator) search(ctx context.Context, srcID string) error {
+	c.srcID = srcID
+	exec := c.executorFactory(c.g, bspgraph.ExecutorCallbacks{
+		PostStepKeepRunning: func(_ context.Context, _ *bspgraph.Graph, activeInStep int) (bool, error) {
+			return activeInStep != 0, nil
+		},
+	})
+	return exec.RunToCompletion(ctx)
+}
+
+// accumulate performs the dependency accumulation step of Brandes' algorithm
+// for the vertices visited by the last search, adds the dependencies to
+// betweenness and returns the visited vertices.
+func (c *Calculator) accumulate(srcID string, vertices map[string]*bspgraph.Vertex, betweenness map[string]float64) []*bspgraph.Vertex {
+	var visited []*bspgraph.Vertex
+	for _, v := range vertices {
+		if v.Value().(*bfsState).dist >= 0 {
+			visited = append(visited, v)
+		}
+	}
+
+	// Process vertices in order of non-increasing distance from the source
+	sort.Slice(visited, func(i, j int) bool {
+		return visited[i].Value().(*bfsState).dist > visited[j].Value().(*bfsState).dist
+	})
+
+	delta := make(map[string]float64, len(visited))
+	for _, w := range visited {
+		st := w.Value().(*bfsState)
+		for _, predID := range st.preds {
+			pred := vertices[predID].Value().(*bfsState)
+			delta[predID] += pred.sigma / st.sigma * (1 + delta[w.ID()])
+		}
+		if w.ID() != srcID {
+			betweenness[w.ID()] += delta[w.ID()]
+		}
+	}
+
+	return visited
+}
+
+// PathCountMessage announces the number of shortest paths from the source
+// that reach a vertex.
+type PathCountMessage struct {
+	FromID string
+	Sigma  float64
+}
+
+// Type implements message.Message.
+func (PathCountMessage) Type() string { return "path_count" }
+
+// bfsState is stored as the vertex value during a search.
+type bfsState struct {
+	// dist is the distance from the source or -1 if the vertex has not
+	// been reached yet.
+	dist int
+
+	// sigma is the number of shortest paths from the source to the vertex.
+	sigma float64
+
+	// preds are the IDs of the vertices preceding this vertex in shortest
+	// paths from the source.
+	preds []string
+}
+
+func (c *Calculator) bfsStep(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+	if g.Superstep() == 0 {
+		st := &bfsState{dist: -1}
+		if v.ID() == c.srcID {
+			st.dist, st.sigma = 0, 1
+		}
+		v.SetValue(st)
+	}
+
+	st := v.Value().(*bfsState)
+	if g.Superstep() > 0 && st.dist == -1 {
+		// All messages received in the superstep where a vertex is first
+		// reached originate from shortest paths.
+		for msgIt.Next() {
+			m := msgIt.Message().(PathCountMessage)
+			st.sigma += m.Sigma
+			st.preds = append(st.preds, m.FromID)
+		}
+		if len(st.preds) != 0 {
+			st.dist = g.Superstep()
+		}
+	}
+
+	if st.dist == g.Superstep() {
+		if err := g.BroadcastToNeighbors(v, PathCountMessage{FromID: v.ID(), Sigma: st.sigma}); err != nil {
+			return err
+		}
+	}
+
+	v.Freeze()
+	return nil
+}
+
+// Updater is implemented by types that can store centrality scores, such as
+// an index.Indexer.
+type Updater interface {
+	UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error
+}
+
+// WriteScores stores the scores of each vertex via updater. Vertex IDs must be
+// link IDs. Documents that cannot be updated are reported in the returned
+// error while the remaining updates are still applied.
+func WriteScores(updater Updater, scores map[string]Scores) error {
+	var err error
+	for id, s := range scores {
+		linkID, pErr := uuid.Parse(id)
+		if pErr != nil {
+			err = multierror.Append(err, xerrors.Errorf("vertex %q is not a link ID: %w", id, pErr))
+			continue
+		}
+
+		if uErr := updater.UpdateFields(linkID, map[string]interface{}{
+			index.FieldBetweenness: s.Betweenness,
+			index.FieldCloseness:   s.Closeness,
+		}); uErr != nil {
+			err = multierror.Append(err, xerrors.Errorf("update scores for %q: %w", id, uErr))
+		}
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package centrality
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CentralityTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type CentralityTestSuite struct{}
+
+func (s *CentralityTestSuite) calculate(c *gc.C, cfg Config, vertices []string, edges [][2]string) map[string]Scores {
+	calc, err := NewCalculator(cfg)
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(calc.Close(), gc.IsNil) }()
+
+	for _, id := range vertices {
+		calc.AddVertex(id)
+	}
+	for _, e := range edges {
+		c.Assert(calc.AddEdge(e[0], e[1]), gc.IsNil)
+	}
+
+	scores, err := calc.Calculate(context.TODO())
+	c.Assert(err, gc.IsNil)
+	return scores
+}
+
+func (s *CentralityTestSuite) TestExactScores(c *gc.C) {
+	// a -> {b, c} -> d -> e
+	scores := s.calculate(c, Config{ComputeWorkers: 2},
+		[]string{"a", "b", "c", "d", "e"},
+		[][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}, {"d", "e"}},
+	)
+
+	c.Assert(scores["a"].Betweenness, gc.Equals, 0.0)
+	c.Assert(scores["b"].Betweenness, gc.Equals, 1.0) // half of a->d and a->e
+	c.Assert(scores["c"].Betweenness, gc.Equals, 1.0)
+	c.Assert(scores["d"].Betweenness, gc.Equals, 3.0) // a->e, b->e, c->e
+	c.Assert(scores["e"].Betweenness, gc.Equals, 0.0)
+
+	// e is reached by all 4 other vertices with a total distance of 8
+	c.Assert(scores["e"].Closeness, gc.Equals, 0.5)
+	// a is not reachable from any vertex
+	c.Assert(scores["a"].Closeness, gc.Equals, 0.0)
+}
+
+func (s *CentralityTestSuite) TestSampledScoresAreDeterministic(c *gc.C) {
+	vertices := []string{"a", "b", "c", "d", "e", "f"}
+	var edges [][2]string
+	for i := range vertices {
+		edges = append(edges, [2]string{vertices[i], vertices[(i+1)%len(vertices)]})
+	}
+
+	cfg := Config{Samples: 3, Seed: 42}
+	first := s.calculate(c, cfg, vertices, edges)
+	second := s.calculate(c, cfg, vertices, edges)
+	c.Assert(first, gc.DeepEquals, second)
+	c.Assert(first, gc.HasLen, len(vertices))
+}
+
+type fakeUpdater map[uuid.UUID]map[string]interface{}
+
+func (u fakeUpdater) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	u[linkID] = fields
+	return nil
+}
+
+func (s *CentralityTestSuite) TestWriteScores(c *gc.C) {
+	linkID := uuid.New()
+	updater := make(fakeUpdater)
+	err := WriteScores(updater, map[string]Scores{
+		linkID.String(): {Betweenness: 2, Closeness: 0.5},
+		"not-a-uuid":    {},
+	})
+	c.Assert(err, gc.ErrorMatches, `(?s).*vertex "not-a-uuid" is not a link ID.*`)
+	c.Assert(updater[linkID], gc.DeepEquals, map[string]interface{}{
+		"Betweenness": 2.0,
+		"Closeness":   0.5,
+	})
+}
@@ -0,0 +1,99 @@
+// Command linkviz exports the link neighborhood of a seed link, i.e. the
+// subgraph induced by the links within a number of hops from it, as JSON that
+// can be fed to the D3 or vis.js graph visualization libraries. The
+// neighborhood is retrieved from the admin area of a frontend instance.
+//
+// Usage:
+//
+//	linkviz [-addr http://localhost:8080] [-user admin] [-radius 2] [-format d3|vis] [-o file] seed-link-id
+//
+// The admin password is read from the LINKVIZ_PASSWORD environment variable.
+// Nodes carry the URL of each link as their label and are sized by PageRank.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Getenv); err != nil {
+		fmt.Fprintf(os.Stderr, "linkviz: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer, getenv func(string) string) error {
+	fs := flag.NewFlagSet("linkviz", flag.ContinueOnError)
+	addr := fs.String("addr", "http://localhost:8080", "base URL of the frontend")
+	user := fs.String("user", "admin", "admin username")
+	radius := fs.Int("radius", 2, "number of hops around the seed link to include")
+	format := fs.String("format", "d3", "output format: d3 or vis")
+	outFile := fs.String("o", "", "write the output to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return xerrors.New("expected a seed link ID")
+	}
+	if _, err := uuid.Parse(fs.Arg(0)); err != nil {
+		return xerrors.Errorf("invalid seed link ID %q", fs.Arg(0))
+	}
+	if *format != "d3" && *format != "vis" {
+		return xerrors.Errorf("invalid format %q", *format)
+	}
+
+	params := url.Values{
+		"seed":   {fs.Arg(0)},
+		"radius": {strconv.Itoa(*radius)},
+		"format": {*format},
+	}
+	body, err := fetchNeighborhood(*addr, *user, getenv("LINKVIZ_PASSWORD"), params)
+	if err != nil {
+		return err
+	}
+
+	if *outFile != "" {
+		return ioutil.WriteFile(*outFile, body, 0644)
+	}
+	_, err = out.Write(body)
+	return err
+}
+
+func fetchNeighborhood(addr, user, password string, params url.Values) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/admin/neighborhood?"+params.Encode(), nil)
+	if err != nil {
+		return nil, xerrors.Errorf("fetch neighborhood: %w", err)
+	}
+	req.SetBasicAuth(user, password)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("fetch neighborhood: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, xerrors.Errorf("fetch neighborhood: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("fetch neighborhood: %w", err)
+	}
+	if res.Header.Get("X-Neighborhood-Truncated") == "true" {
+		fmt.Fprintln(os.Stderr, "linkviz: warning: the neighborhood was truncated; try a smaller radius")
+	}
+	return body, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(LinkVizTestSuite))
+
+type LinkVizTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+const seed = "5d6e8e0a-9a4f-4d1c-8f7e-2a1b3c4d5e6f"
+
+func (s *LinkVizTestSuite) TestRun(c *gc.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "admin" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		q := r.URL.Query()
+		if r.URL.Path != "/admin/neighborhood" || q.Get("seed") != seed {
+			http.Error(w, "unknown seed link", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"format":"` + q.Get("format") + `","radius":` + q.Get("radius") + `}`))
+	}))
+	defer srv.Close()
+	getenv := func(string) string { return "secret" }
+
+	var out bytes.Buffer
+	c.Assert(run([]string{"-addr", srv.URL, seed}, &out, getenv), gc.IsNil)
+	c.Assert(out.String(), gc.Equals, `{"format":"d3","radius":2}`)
+
+	outFile := filepath.Join(c.MkDir(), "graph.json")
+	c.Assert(run([]string{"-addr", srv.URL, "-format", "vis", "-radius", "1", "-o", outFile, seed}, new(bytes.Buffer), getenv), gc.IsNil)
+	written, err := ioutil.ReadFile(outFile)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(written), gc.Equals, `{"format":"vis","radius":1}`)
+
+	err = run([]string{"-addr", srv.URL, "2d6e8e0a-9a4f-4d1c-8f7e-2a1b3c4d5e6f"}, new(bytes.Buffer), getenv)
+	c.Assert(err, gc.ErrorMatches, "fetch neighborhood: 404 Not Found: unknown seed link")
+
+	err = run([]string{"-addr", srv.URL, seed}, new(bytes.Buffer), func(string) string { return "" })
+	c.Assert(err, gc.ErrorMatches, "fetch neighborhood: 401 Unauthorized: unauthorized")
+
+	err = run([]string{"http://a.com/"}, new(bytes.Buffer), getenv)
+	c.Assert(err, gc.ErrorMatches, `invalid seed link ID "http://a.com/"`)
+
+	err = run([]string{"-format", "dot", seed}, new(bytes.Buffer), getenv)
+	c.Assert(err, gc.ErrorMatches, `invalid format "dot"`)
+}
@@ -0,0 +1,134 @@
+// Command passdiff prints what changed between two crawl passes. The diff is
+// retrieved from the admin area of a frontend instance whose link graph keeps
+// the history of crawl passes.
+//
+// Usage:
+//
+//	passdiff [-addr http://localhost:8080] [-user admin] [-exit-code] passA passB
+//
+// The admin password is read from the PASSDIFF_PASSWORD environment variable.
+// Each line of the output describes a single change:
+//
+//	+ link <url>        link retrieved by passB but not by passA
+//	- link <url>        link retrieved by passA but not by passB
+//	+ edge <src> -> <dst>
+//	- edge <src> -> <dst>
+//	~ doc  <url>        page whose content changed
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// errChanged is returned by run when the passes differ and -exit-code is set.
+var errChanged = xerrors.New("crawl passes differ")
+
+// passDiff mirrors the JSON response of the /admin/passdiff endpoint.
+type passDiff struct {
+	PassA            uint64   `json:"pass_a"`
+	PassB            uint64   `json:"pass_b"`
+	NewLinks         []string `json:"new_links"`
+	RemovedLinks     []string `json:"removed_links"`
+	NewEdges         []string `json:"new_edges"`
+	RemovedEdges     []string `json:"removed_edges"`
+	ChangedDocuments []string `json:"changed_documents"`
+}
+
+func main() {
+	switch err := run(os.Args[1:], os.Stdout, os.Getenv); {
+	case err == errChanged:
+		os.Exit(1)
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "passdiff: %v\n", err)
+		os.Exit(2)
+	}
+}
+
+func run(args []string, out io.Writer, getenv func(string) string) error {
+	fs := flag.NewFlagSet("passdiff", flag.ContinueOnError)
+	addr := fs.String("addr", "http://localhost:8080", "base URL of the frontend")
+	user := fs.String("user", "admin", "admin username")
+	exitCode := fs.Bool("exit-code", false, "exit with status 1 if the passes differ")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return xerrors.New("expected two crawl pass IDs")
+	}
+	for _, arg := range fs.Args() {
+		if _, err := strconv.ParseUint(arg, 10, 64); err != nil {
+			return xerrors.Errorf("invalid crawl pass ID %q", arg)
+		}
+	}
+
+	diff, err := fetchDiff(*addr, *user, getenv("PASSDIFF_PASSWORD"), fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if err = writeDiff(out, diff); err != nil {
+		return err
+	}
+
+	changes := len(diff.NewLinks) + len(diff.RemovedLinks) + len(diff.NewEdges) + len(diff.RemovedEdges) + len(diff.ChangedDocuments)
+	if *exitCode && changes != 0 {
+		return errChanged
+	}
+	return nil
+}
+
+func fetchDiff(addr, user, password, passA, passB string) (*passDiff, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/admin/passdiff?"+url.Values{"a": {passA}, "b": {passB}}.Encode(), nil)
+	if err != nil {
+		return nil, xerrors.Errorf("fetch diff: %w", err)
+	}
+	req.SetBasicAuth(user, password)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("fetch diff: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, xerrors.Errorf("fetch diff: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	diff := new(passDiff)
+	if err = json.NewDecoder(res.Body).Decode(diff); err != nil {
+		return nil, xerrors.Errorf("fetch diff: decode response: %w", err)
+	}
+	return diff, nil
+}
+
+func writeDiff(w io.Writer, diff *passDiff) error {
+	sections := []struct {
+		prefix string
+		items  []string
+	}{
+		{"+ link ", diff.NewLinks},
+		{"- link ", diff.RemovedLinks},
+		{"+ edge ", diff.NewEdges},
+		{"- edge ", diff.RemovedEdges},
+		{"~ doc  ", diff.ChangedDocuments},
+	}
+	for _, section := range sections {
+		for _, item := range section.items {
+			if _, err := fmt.Fprintln(w, section.prefix+item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(PassDiffTestSuite))
+
+type PassDiffTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *PassDiffTestSuite) TestRun(c *gc.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "admin" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/admin/passdiff" || r.URL.Query().Get("a") != "1" || r.URL.Query().Get("b") != "2" {
+			http.Error(w, "unknown crawl pass", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"pass_a":1,"pass_b":2,"new_links":["http://a.com/new"],"removed_edges":["http://a.com/ -> http://b.com/"],"changed_documents":["http://a.com/"]}`))
+	}))
+	defer srv.Close()
+	getenv := func(string) string { return "secret" }
+
+	var out bytes.Buffer
+	c.Assert(run([]string{"-addr", srv.URL, "1", "2"}, &out, getenv), gc.IsNil)
+	c.Assert(out.String(), gc.Equals, "+ link http://a.com/new\n- edge http://a.com/ -> http://b.com/\n~ doc  http://a.com/\n")
+
+	err := run([]string{"-addr", srv.URL, "-exit-code", "1", "2"}, new(bytes.Buffer), getenv)
+	c.Assert(err, gc.Equals, errChanged)
+
+	err = run([]string{"-addr", srv.URL, "1", "3"}, new(bytes.Buffer), getenv)
+	c.Assert(err, gc.ErrorMatches, "fetch diff: 404 Not Found: unknown crawl pass")
+
+	err = run([]string{"-addr", srv.URL, "1", "2"}, new(bytes.Buffer), func(string) string { return "" })
+	c.Assert(err, gc.ErrorMatches, "fetch diff: 401 Unauthorized: unauthorized")
+
+	err = run([]string{"1", "latest"}, new(bytes.Buffer), getenv)
+	c.Assert(err, gc.ErrorMatches, `invalid crawl pass ID "latest"`)
+}
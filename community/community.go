@@ -0,0 +1,192 @@
+package community
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/bspgraph"
+	"github.com/brandonshearin/ask_brandon/bspgraph/message"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// Config encapsulates the settings for a community Detector.
+type Config struct {
+	// ComputeWorkers is the number of workers used for executing each
+	// superstep. If not specified, a single worker will be used.
+	ComputeWorkers int
+
+	// MaxIterations bounds the number of label propagation rounds in case
+	// the labels oscillate instead of converging. Defaults to 50.
+	MaxIterations int
+}
+
+// Detector clusters a graph into communities using label propagation. Each
+// vertex starts with its own ID as its label and repeatedly adopts the label
+// that is most common among itself and its neighbors until no label changes.
+// Vertices sharing a label form a community.
+//
+// Edges are treated as undirected: a link from one page to another makes both
+// pages neighbors.
+type Detector struct {
+	cfg             Config
+	g               *bspgraph.Graph
+	executorFactory bspgraph.ExecutorFactory
+}
+
+// NewDetector returns a new community detector instance.
+func NewDetector(cfg Config) (*Detector, error) {
+	if cfg.MaxIterations <= 0 {
+		cfg.MaxIterations = 50
+	}
+
+	d := &Detector{
+		cfg:             cfg,
+		executorFactory: bspgraph.NewExecutor,
+	}
+
+	var err error
+	if d.g, err = bspgraph.NewGraph(bspgraph.GraphConfig{
+		ComputeFn:      d.propagateLabels,
+		ComputeWorkers: cfg.ComputeWorkers,
+	}); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Close cleans up any allocated graph resources.
+func (d *Detector) Close() error {
+	return d.g.Close()
+}
+
+// SetExecutorFactory configures the detector to use a custom executor factory
+// when Detect is invoked.
+func (d *Detector) SetExecutorFactory(factory bspgraph.ExecutorFactory) {
+	d.executorFactory = factory
+}
+
+// AddVertex inserts a new vertex with the specified ID into the graph.
+func (d *Detector) AddVertex(id string) {
+	d.g.AddVertex(id, nil)
+}
+
+// AddEdge connects the vertices with the specified IDs.
+func (d *Detector) AddEdge(srcID, dstID string) error {
+	if err := d.g.AddEdge(srcID, dstID, nil); err != nil {
+		return err
+	}
+	return d.g.AddEdge(dstID, srcID, nil)
+}
+
+// Detect runs label propagation and returns the community ID of each vertex.
+func (d *Detector) Detect(ctx context.Context) (map[string]string, error) {
+	exec := d.executorFactory(d.g, bspgraph.ComposeCallbacks(
+		bspgraph.MaxSupersteps(d.cfg.MaxIterations+1),
+		bspgraph.ExecutorCallbacks{
+			PostStepKeepRunning: func(_ context.Context, _ *bspgraph.Graph, activeInStep int) (bool, error) {
+				return activeInStep != 0, nil
+			},
+		},
+	))
+	if err := exec.RunToCompletion(ctx); err != nil {
+		return nil, xerrors.Errorf("community detection: %w", err)
+	}
+
+	vertices := d.g.Vertices()
+	communities := make(map[string]string, len(vertices))
+	for id, v := range vertices {
+		communities[id] = v.Value().(*labelState).label
+	}
+	return communities, nil
+}
+
+// LabelMessage announces the label of a vertex to its neighbors.
+type LabelMessage struct {
+	FromID string
+	Label  string
+}
+
+// Type implements message.Message.
+func (LabelMessage) Type() string { return "label" }
+
+// labelState is stored as the vertex value.
+type labelState struct {
+	label string
+
+	// neighborLabels tracks the last label announced by each neighbor.
+	neighborLabels map[string]string
+}
+
+func (d *Detector) propagateLabels(g *bspgraph.Graph, v *bspgraph.Vertex, msgIt message.Iterator) error {
+	if g.Superstep() == 0 {
+		v.SetValue(&labelState{
+			label:          v.ID(),
+			neighborLabels: make(map[string]string),
+		})
+		v.Freeze()
+		return g.BroadcastToNeighbors(v, LabelMessage{FromID: v.ID(), Label: v.ID()})
+	}
+
+	st := v.Value().(*labelState)
+	for msgIt.Next() {
+		m := msgIt.Message().(LabelMessage)
+		st.neighborLabels[m.FromID] = m.Label
+	}
+
+	// Only vertices whose label changes need to notify their neighbors
+	v.Freeze()
+	if best := st.mostFrequentLabel(); best != st.label {
+		st.label = best
+		return g.BroadcastToNeighbors(v, LabelMessage{FromID: v.ID(), Label: best})
+	}
+	return nil
+}
+
+// mostFrequentLabel returns the most common label among the vertex and its
+// neighbors. Ties are broken in favor of the lexicographically smallest label
+// so that the outcome is deterministic and labels do not oscillate between
+// equally popular alternatives.
+func (st *labelState) mostFrequentLabel() string {
+	counts := map[string]int{st.label: 1}
+	for _, label := range st.neighborLabels {
+		counts[label]++
+	}
+
+	var best string
+	for label, count := range counts {
+		if best == "" || count > counts[best] || (count == counts[best] && label < best) {
+			best = label
+		}
+	}
+	return best
+}
+
+// Updater is implemented by types that can store community IDs, such as an
+// index.Indexer.
+type Updater interface {
+	UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error
+}
+
+// WriteCommunities stores the community ID of each vertex via updater. Vertex
+// IDs must be link IDs. Documents that cannot be updated are reported in the
+// returned error while the remaining updates are still applied.
+func WriteCommunities(updater Updater, communities map[string]string) error {
+	var err error
+	for id, communityID := range communities {
+		linkID, pErr := uuid.Parse(id)
+		if pErr != nil {
+			err = multierror.Append(err, xerrors.Errorf("vertex %q is not a link ID: %w", id, pErr))
+			continue
+		}
+
+		if uErr := updater.UpdateFields(linkID, map[string]interface{}{
+			index.FieldCommunityID: communityID,
+		}); uErr != nil {
+			err = multierror.Append(err, xerrors.Errorf("update community for %q: %w", id, uErr))
+		}
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package community
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CommunityTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type CommunityTestSuite struct{}
+
+func (s *CommunityTestSuite) TestDetectCommunities(c *gc.C) {
+	d, err := NewDetector(Config{ComputeWorkers: 2})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(d.Close(), gc.IsNil) }()
+
+	// Two cliques joined by a single bridge (c-x) and an isolated vertex
+	for _, id := range []string{"a", "b", "c", "d", "w", "x", "y", "z", "island"} {
+		d.AddVertex(id)
+	}
+	edges := [][2]string{
+		{"a", "b"}, {"a", "c"}, {"a", "d"}, {"b", "c"}, {"b", "d"}, {"c", "d"},
+		{"w", "x"}, {"w", "y"}, {"w", "z"}, {"x", "y"}, {"x", "z"}, {"y", "z"},
+		{"c", "x"},
+	}
+	for _, e := range edges {
+		c.Assert(d.AddEdge(e[0], e[1]), gc.IsNil)
+	}
+
+	communities, err := d.Detect(context.TODO())
+	c.Assert(err, gc.IsNil)
+
+	for _, id := range []string{"b", "c", "d"} {
+		c.Assert(communities[id], gc.Equals, communities["a"], gc.Commentf("vertex %s", id))
+	}
+	for _, id := range []string{"x", "y", "z"} {
+		c.Assert(communities[id], gc.Equals, communities["w"], gc.Commentf("vertex %s", id))
+	}
+	c.Assert(communities["a"], gc.Not(gc.Equals), communities["w"])
+	c.Assert(communities["island"], gc.Equals, "island")
+}
+
+type fakeUpdater map[uuid.UUID]map[string]interface{}
+
+func (u fakeUpdater) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	u[linkID] = fields
+	return nil
+}
+
+func (s *CommunityTestSuite) TestWriteCommunities(c *gc.C) {
+	linkID := uuid.New()
+	updater := make(fakeUpdater)
+	c.Assert(WriteCommunities(updater, map[string]string{linkID.String(): "cluster"}), gc.IsNil)
+	c.Assert(updater[linkID], gc.DeepEquals, map[string]interface{}{"CommunityID": "cluster"})
+}
@@ -0,0 +1,212 @@
+// Package alert detects anomalies in crawl passes. The statistics of each
+// pass are compared to the ones of the previous pass and an alert is sent to
+// the configured notifiers (e.g. a webhook or a Slack channel) when the error
+// rate, the ratio of 4xx/5xx responses or the number of fetched pages
+// deviate beyond the configured thresholds.
+package alert
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultMaxErrorRateIncrease   = 0.1
+	defaultMaxStatusRatioIncrease = 0.1
+	defaultMaxFetchedDeviation    = 0.5
+)
+
+// PassStats summarizes the outcome of the fetch requests of a crawl pass.
+type PassStats struct {
+	PassID uint64
+
+	// Fetched is the number of requests that yielded a response while
+	// Errors is the number of requests that failed without one (e.g.
+	// connection errors or timeouts).
+	Fetched int
+	Errors  int
+
+	// ClientErrors and ServerErrors are the number of responses with a
+	// 4xx and 5xx status code respectively.
+	ClientErrors int
+	ServerErrors int
+}
+
+// ErrorRate returns the fraction of requests that failed without a response.
+func (s PassStats) ErrorRate() float64 {
+	return ratio(s.Errors, s.Fetched+s.Errors)
+}
+
+// ClientErrorRatio returns the fraction of responses with a 4xx status code.
+func (s PassStats) ClientErrorRatio() float64 {
+	return ratio(s.ClientErrors, s.Fetched)
+}
+
+// ServerErrorRatio returns the fraction of responses with a 5xx status code.
+func (s PassStats) ServerErrorRatio() float64 {
+	return ratio(s.ServerErrors, s.Fetched)
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// Alert describes a metric of a crawl pass that deviates from the previous
+// pass beyond the configured threshold.
+type Alert struct {
+	PassID         uint64  `json:"pass_id"`
+	PreviousPassID uint64  `json:"previous_pass_id"`
+	Metric         string  `json:"metric"`
+	Previous       float64 `json:"previous"`
+	Current        float64 `json:"current"`
+	Threshold      float64 `json:"threshold"`
+}
+
+// String returns a human-readable description of the alert.
+func (a Alert) String() string {
+	if a.Metric == MetricFetched {
+		return fmt.Sprintf("crawl pass %d: %s changed from %.0f to %.0f compared to pass %d (threshold ±%.1f%%)",
+			a.PassID, a.Metric, a.Previous, a.Current, a.PreviousPassID, 100*a.Threshold)
+	}
+	return fmt.Sprintf("crawl pass %d: %s increased from %.1f%% to %.1f%% compared to pass %d (threshold +%.1f%%)",
+		a.PassID, a.Metric, 100*a.Previous, 100*a.Current, a.PreviousPassID, 100*a.Threshold)
+}
+
+// The metrics that are monitored for anomalies.
+const (
+	MetricErrorRate        = "error rate"
+	MetricClientErrorRatio = "4xx ratio"
+	MetricServerErrorRatio = "5xx ratio"
+	MetricFetched          = "fetched pages"
+)
+
+// Notifier is implemented by objects that can deliver alerts.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Config encapsulates the configuration options for a Monitor.
+type Config struct {
+	// Notifiers receive the alerts raised by the monitor.
+	Notifiers []Notifier
+
+	// MaxErrorRateIncrease and MaxStatusRatioIncrease are the maximum
+	// increase, in absolute terms, of the error rate and of the 4xx/5xx
+	// response ratios compared to the previous pass. If not specified, a
+	// default value of 0.1 (10 percentage points) will be used.
+	MaxErrorRateIncrease   float64
+	MaxStatusRatioIncrease float64
+
+	// MaxFetchedDeviation is the maximum relative change of the number of
+	// fetched pages compared to the previous pass. If not specified, a
+	// default value of 0.5 (±50%) will be used.
+	MaxFetchedDeviation float64
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if len(cfg.Notifiers) == 0 {
+		err = xerrors.New("no notifiers specified")
+	}
+	if cfg.MaxErrorRateIncrease <= 0 {
+		cfg.MaxErrorRateIncrease = defaultMaxErrorRateIncrease
+	}
+	if cfg.MaxStatusRatioIncrease <= 0 {
+		cfg.MaxStatusRatioIncrease = defaultMaxStatusRatioIncrease
+	}
+	if cfg.MaxFetchedDeviation <= 0 {
+		cfg.MaxFetchedDeviation = defaultMaxFetchedDeviation
+	}
+	return err
+}
+
+// Monitor compares the statistics of consecutive crawl passes. It is safe
+// for concurrent use.
+type Monitor struct {
+	cfg Config
+
+	mu   sync.Mutex
+	prev *PassStats
+}
+
+// NewMonitor creates a new Monitor using the provided config.
+func NewMonitor(cfg Config) (*Monitor, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("alert monitor config validation failed: %w", err)
+	}
+	return &Monitor{cfg: cfg}, nil
+}
+
+// Observe compares stats to the statistics of the previously observed pass
+// and sends an alert to all notifiers for each metric that deviates beyond
+// its threshold. The first observed pass never raises alerts. Observe
+// returns the raised alerts along with any errors reported by the notifiers.
+func (m *Monitor) Observe(ctx context.Context, stats PassStats) ([]Alert, error) {
+	m.mu.Lock()
+	prev := m.prev
+	m.prev = &stats
+	m.mu.Unlock()
+	if prev == nil {
+		return nil, nil
+	}
+
+	alerts := m.detect(*prev, stats)
+	var err error
+	for _, alert := range alerts {
+		for _, n := range m.cfg.Notifiers {
+			if nErr := n.Notify(ctx, alert); nErr != nil {
+				err = multierror.Append(err, nErr)
+			}
+		}
+	}
+	if err != nil {
+		return alerts, xerrors.Errorf("notify: %w", err)
+	}
+	return alerts, nil
+}
+
+func (m *Monitor) detect(prev, cur PassStats) []Alert {
+	var alerts []Alert
+	raise := func(metric string, prevVal, curVal, threshold float64) {
+		alerts = append(alerts, Alert{
+			PassID:         cur.PassID,
+			PreviousPassID: prev.PassID,
+			Metric:         metric,
+			Previous:       prevVal,
+			Current:        curVal,
+			Threshold:      threshold,
+		})
+	}
+
+	rates := []struct {
+		metric    string
+		prev, cur float64
+		threshold float64
+	}{
+		{MetricErrorRate, prev.ErrorRate(), cur.ErrorRate(), m.cfg.MaxErrorRateIncrease},
+		{MetricClientErrorRatio, prev.ClientErrorRatio(), cur.ClientErrorRatio(), m.cfg.MaxStatusRatioIncrease},
+		{MetricServerErrorRatio, prev.ServerErrorRatio(), cur.ServerErrorRatio(), m.cfg.MaxStatusRatioIncrease},
+	}
+	for _, r := range rates {
+		if r.cur-r.prev > r.threshold {
+			raise(r.metric, r.prev, r.cur, r.threshold)
+		}
+	}
+
+	// A pass that fetched pages after one that fetched none always
+	// deviates.
+	if prev.Fetched != cur.Fetched {
+		if prev.Fetched == 0 || math.Abs(float64(cur.Fetched-prev.Fetched))/float64(prev.Fetched) > m.cfg.MaxFetchedDeviation {
+			raise(MetricFetched, float64(prev.Fetched), float64(cur.Fetched), m.cfg.MaxFetchedDeviation)
+		}
+	}
+	return alerts
+}
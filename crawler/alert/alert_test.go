@@ -0,0 +1,102 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(AlertTestSuite))
+
+type AlertTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *AlertTestSuite) TestObserve(c *gc.C) {
+	n := new(recordingNotifier)
+	m, err := NewMonitor(Config{Notifiers: []Notifier{n}})
+	c.Assert(err, gc.IsNil)
+
+	// The first pass only establishes a baseline.
+	alerts, err := m.Observe(context.TODO(), PassStats{PassID: 1, Fetched: 100, Errors: 5, ClientErrors: 10, ServerErrors: 2})
+	c.Assert(err, gc.IsNil)
+	c.Assert(alerts, gc.HasLen, 0)
+
+	// Small deviations are tolerated.
+	alerts, err = m.Observe(context.TODO(), PassStats{PassID: 2, Fetched: 120, Errors: 10, ClientErrors: 15, ServerErrors: 5})
+	c.Assert(err, gc.IsNil)
+	c.Assert(alerts, gc.HasLen, 0)
+
+	alerts, err = m.Observe(context.TODO(), PassStats{PassID: 3, Fetched: 40, Errors: 40, ClientErrors: 4, ServerErrors: 20})
+	c.Assert(err, gc.IsNil)
+	var metrics []string
+	for _, alert := range alerts {
+		c.Assert(alert.PassID, gc.Equals, uint64(3))
+		c.Assert(alert.PreviousPassID, gc.Equals, uint64(2))
+		metrics = append(metrics, alert.Metric)
+	}
+	c.Assert(metrics, gc.DeepEquals, []string{MetricErrorRate, MetricServerErrorRatio, MetricFetched})
+	c.Assert(n.alerts, gc.DeepEquals, alerts)
+	c.Assert(alerts[0].String(), gc.Equals, "crawl pass 3: error rate increased from 7.7% to 50.0% compared to pass 2 (threshold +10.0%)")
+	c.Assert(alerts[2].String(), gc.Equals, "crawl pass 3: fetched pages changed from 120 to 40 compared to pass 2 (threshold ±50.0%)")
+}
+
+func (s *AlertTestSuite) TestNotifierErrors(c *gc.C) {
+	failing := &recordingNotifier{err: xerrors.New("connection refused")}
+	ok := new(recordingNotifier)
+	m, err := NewMonitor(Config{Notifiers: []Notifier{failing, ok}, MaxFetchedDeviation: 0.1})
+	c.Assert(err, gc.IsNil)
+
+	_, err = m.Observe(context.TODO(), PassStats{PassID: 1, Fetched: 100})
+	c.Assert(err, gc.IsNil)
+	alerts, err := m.Observe(context.TODO(), PassStats{PassID: 2, Fetched: 80})
+	c.Assert(err, gc.ErrorMatches, "(?s)notify: .*connection refused.*")
+	c.Assert(alerts, gc.HasLen, 1)
+	c.Assert(ok.alerts, gc.HasLen, 1)
+}
+
+func (s *AlertTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewMonitor(Config{})
+	c.Assert(err, gc.ErrorMatches, ".*no notifiers specified")
+}
+
+func (s *AlertTestSuite) TestWebhookAndSlackNotifiers(c *gc.C) {
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, gc.Equals, http.MethodPost)
+		c.Check(r.Header.Get("Content-Type"), gc.Equals, "application/json")
+		var body map[string]interface{}
+		c.Check(json.NewDecoder(r.Body).Decode(&body), gc.IsNil)
+		bodies = append(bodies, body)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	alert := Alert{PassID: 2, PreviousPassID: 1, Metric: MetricServerErrorRatio, Previous: 0.01, Current: 0.5, Threshold: 0.1}
+	c.Assert(NewWebhookNotifier(srv.URL+"/hook", nil).Notify(context.TODO(), alert), gc.IsNil)
+	c.Assert(NewSlackNotifier(srv.URL+"/slack", srv.Client()).Notify(context.TODO(), alert), gc.IsNil)
+	err := NewWebhookNotifier(srv.URL+"/broken", nil).Notify(context.TODO(), alert)
+	c.Assert(err, gc.ErrorMatches, "webhook notifier: unexpected response status: 500 Internal Server Error")
+
+	c.Assert(bodies, gc.HasLen, 3)
+	c.Assert(bodies[0]["metric"], gc.Equals, MetricServerErrorRatio)
+	c.Assert(bodies[0]["pass_id"], gc.Equals, float64(2))
+	c.Assert(bodies[1]["text"], gc.Equals, ":warning: crawl pass 2: 5xx ratio increased from 1.0% to 50.0% compared to pass 1 (threshold +10.0%)")
+}
+
+type recordingNotifier struct {
+	alerts []Alert
+	err    error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, alert Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return n.err
+}
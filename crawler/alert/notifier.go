@@ -0,0 +1,83 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	_ Notifier = (*WebhookNotifier)(nil)
+	_ Notifier = (*SlackNotifier)(nil)
+)
+
+// WebhookNotifier delivers alerts by POSTing them as JSON to a URL.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier returns a notifier that POSTs alerts to url. If client
+// is nil, http.DefaultClient will be used.
+func NewWebhookNotifier(url string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: client}
+}
+
+// Notify implements Notifier.
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	if err := postJSON(ctx, n.client, n.url, alert); err != nil {
+		return xerrors.Errorf("webhook notifier: %w", err)
+	}
+	return nil
+}
+
+// SlackNotifier delivers alerts to a Slack channel via an incoming webhook.
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier returns a notifier that posts alerts to the Slack incoming
+// webhook at webhookURL. If client is nil, http.DefaultClient will be used.
+func NewSlackNotifier(webhookURL string, client *http.Client) *SlackNotifier {
+	return &SlackNotifier{webhookURL: webhookURL, client: client}
+}
+
+// Notify implements Notifier.
+func (n *SlackNotifier) Notify(ctx context.Context, alert Alert) error {
+	msg := struct {
+		Text string `json:"text"`
+	}{Text: ":warning: " + alert.String()}
+	if err := postJSON(ctx, n.client, n.webhookURL, msg); err != nil {
+		return xerrors.Errorf("slack notifier: %w", err)
+	}
+	return nil
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return xerrors.Errorf("unexpected response status: %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+)
+
+// ObjectStore is implemented by object storage services such as S3 or GCS
+// that can be used for archiving the output of the crawler.
+type ObjectStore interface {
+	// PutObject stores data under the specified key, replacing any
+	// existing object. The data is gzip-compressed JSON.
+	PutObject(ctx context.Context, key string, data []byte) error
+}
+
+// archiveRecord is the JSON representation of an archived payload.
+type archiveRecord struct {
+	LinkID      uuid.UUID `json:"link_id"`
+	URL         string    `json:"url"`
+	FinalURL    string    `json:"final_url,omitempty"`
+	CrawlPassID uint64    `json:"crawl_pass_id"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	HTTPStatus  int       `json:"http_status"`
+	ContentType string    `json:"content_type,omitempty"`
+	ContentHash string    `json:"content_hash,omitempty"`
+
+	Title         string         `json:"title,omitempty"`
+	Language      string         `json:"language,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	Keywords      []string       `json:"keywords,omitempty"`
+	OGTitle       string         `json:"og_title,omitempty"`
+	OGDescription string         `json:"og_description,omitempty"`
+	OGImage       string         `json:"og_image,omitempty"`
+	Entities      []index.Entity `json:"entities,omitempty"`
+
+	Text     string `json:"text,omitempty"`
+	MainText string `json:"main_text,omitempty"`
+
+	Links         []string `json:"links,omitempty"`
+	NoFollowLinks []string `json:"nofollow_links,omitempty"`
+	FeedLinks     []string `json:"feed_links,omitempty"`
+}
+
+// archiveSink writes each payload it consumes as a gzip-compressed JSON
+// object. Objects are partitioned by the date the page was fetched using
+// Hive-style keys so that they can be queried directly by tools such as
+// Athena or BigQuery:
+//
+//   <prefix>dt=2006-01-02/<crawl pass ID>/<link ID>.json.gz
+//
+// Re-crawling a link in the same pass overwrites its object.
+type archiveSink struct {
+	store  ObjectStore
+	prefix string
+	now    func() time.Time
+}
+
+func newArchiveSink(store ObjectStore, prefix string) *archiveSink {
+	return &archiveSink{store: store, prefix: prefix, now: time.Now}
+}
+
+// Consume implements pipeline.Sink.
+func (s *archiveSink) Consume(ctx context.Context, p pipeline.Payload) error {
+	payload := p.(*Payload)
+	fetchedAt := payload.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = s.now()
+	}
+
+	data, err := encodeArchiveRecord(&archiveRecord{
+		LinkID:        payload.LinkID,
+		URL:           payload.URL,
+		FinalURL:      payload.FinalURL,
+		CrawlPassID:   payload.CrawlPassID,
+		FetchedAt:     fetchedAt.UTC(),
+		HTTPStatus:    payload.HTTPStatus,
+		ContentType:   payload.ContentType,
+		ContentHash:   payload.ContentHash,
+		Title:         payload.Title,
+		Language:      payload.Language,
+		Description:   payload.Description,
+		Keywords:      payload.Keywords,
+		OGTitle:       payload.OGTitle,
+		OGDescription: payload.OGDescription,
+		OGImage:       payload.OGImage,
+		Entities:      payload.Entities,
+		Text:          payload.TextContent,
+		MainText:      payload.MainText,
+		Links:         payload.Links,
+		NoFollowLinks: payload.NoFollowLinks,
+		FeedLinks:     payload.FeedLinks,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.store.PutObject(ctx, s.objectKey(payload, fetchedAt), data)
+}
+
+// Process allows the sink to be used as a branch of the crawler pipeline. The
+// payload is archived and then discarded so that it does not affect the
+// counts reported by Crawl.
+func (s *archiveSink) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	if err := s.Consume(ctx, p); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *archiveSink) objectKey(payload *Payload, fetchedAt time.Time) string {
+	passID := "unknown"
+	if payload.CrawlPassID != 0 {
+		passID = strconv.FormatUint(payload.CrawlPassID, 10)
+	}
+	return s.prefix + path.Join(
+		"dt="+fetchedAt.UTC().Format("2006-01-02"),
+		passID,
+		payload.LinkID.String()+".json.gz",
+	)
+}
+
+func encodeArchiveRecord(rec *archiveRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(rec); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,82 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ArchiveSinkTestSuite))
+
+type ArchiveSinkTestSuite struct{}
+
+func (s *ArchiveSinkTestSuite) TestArchivePayload(c *gc.C) {
+	store := make(memObjectStore)
+	sink := newArchiveSink(store, "crawls/")
+
+	linkID := uuid.New()
+	fetchedAt := time.Date(2020, 3, 14, 23, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	p := &Payload{
+		LinkID:      linkID,
+		URL:         "http://example.com",
+		CrawlPassID: 1,
+		FetchedAt:   fetchedAt,
+		HTTPStatus:  200,
+		ContentType: "text/html",
+		Title:       "Example",
+		TextContent: "Hello world",
+		Links:       []string{"http://example.com/about"},
+	}
+	_, err := p.RawContent.WriteString("<html>Hello world</html>")
+	c.Assert(err, gc.IsNil)
+
+	out, err := sink.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.IsNil, gc.Commentf("expected archived payloads to be discarded"))
+
+	// Objects are partitioned by the UTC fetch date
+	key := "crawls/dt=2020-03-15/1/" + linkID.String() + ".json.gz"
+	data, found := store[key]
+	c.Assert(found, gc.Equals, true, gc.Commentf("expected object with key %q; got %v", key, store))
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	c.Assert(err, gc.IsNil)
+	var rec archiveRecord
+	c.Assert(json.NewDecoder(zr).Decode(&rec), gc.IsNil)
+	c.Assert(rec, gc.DeepEquals, archiveRecord{
+		LinkID:      linkID,
+		URL:         "http://example.com",
+		CrawlPassID: 1,
+		FetchedAt:   fetchedAt.UTC(),
+		HTTPStatus:  200,
+		ContentType: "text/html",
+		Title:       "Example",
+		Text:        "Hello world",
+		Links:       []string{"http://example.com/about"},
+	})
+}
+
+func (s *ArchiveSinkTestSuite) TestArchiveError(c *gc.C) {
+	sink := newArchiveSink(failingObjectStore{}, "")
+	_, err := sink.Process(context.TODO(), &Payload{URL: "http://example.com"})
+	c.Assert(err, gc.ErrorMatches, "access denied")
+}
+
+type memObjectStore map[string][]byte
+
+func (s memObjectStore) PutObject(_ context.Context, key string, data []byte) error {
+	s[key] = append([]byte(nil), data...)
+	return nil
+}
+
+type failingObjectStore struct{}
+
+func (failingObjectStore) PutObject(context.Context, string, []byte) error {
+	return xerrors.New("access denied")
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+const (
+	//minBlockWords is the minimum number of words a block of text must
+	//contain to be considered part of the main content of a page
+	minBlockWords = 8
+
+	//maxLinkDensity is the maximum fraction of the text of a block that can
+	//appear inside links before the block is considered navigation
+	maxLinkDensity = 0.33
+)
+
+var (
+	//boilerplateElemRegexes match elements that never contain the main
+	//content of a page.  Go regexps do not support backreferences so we
+	//need one per element
+	boilerplateElemRegexes = compileElemRegexes("script", "style", "noscript", "nav", "header", "footer", "aside", "form")
+
+	//blockBoundaryRegex matches the opening or closing tags of block-level
+	//elements which are used to split the page into blocks of text
+	blockBoundaryRegex = regexp.MustCompile(`(?i)</?(?:p|div|li|ul|ol|td|th|tr|table|section|article|main|h[1-6]|blockquote|pre|br)\b[^>]*>`)
+
+	anchorRegex = regexp.MustCompile(`(?is)<a\b[^>]*>(.*?)</a\s*>`)
+)
+
+func compileElemRegexes(tags ...string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, len(tags))
+	for i, tag := range tags {
+		out[i] = regexp.MustCompile(`(?is)<` + tag + `\b[^>]*>.*?</` + tag + `\s*>`)
+	}
+	return out
+}
+
+/*
+extractMainText implements a text-density based boilerplate removal heuristic
+similar to the one used by readability.  Elements such as navigation menus and
+footers are dropped, the remaining markup is split into blocks at block-level
+element boundaries and only the blocks with enough words and a low link density
+are kept.  It returns an empty string if no block qualifies as main content
+*/
+func extractMainText(content string, policy *bluemonday.Policy) string {
+	for _, re := range boilerplateElemRegexes {
+		content = re.ReplaceAllString(content, " ")
+	}
+
+	var mainBlocks []string
+	for _, block := range blockBoundaryRegex.Split(content, -1) {
+		text := blockText(block, policy)
+		if len(strings.Fields(text)) < minBlockWords {
+			continue
+		}
+
+		var linkTextLen int
+		for _, match := range anchorRegex.FindAllStringSubmatch(block, -1) {
+			linkTextLen += utf8.RuneCountInString(blockText(match[1], policy))
+		}
+		if float64(linkTextLen)/float64(utf8.RuneCountInString(text)) > maxLinkDensity {
+			continue
+		}
+
+		mainBlocks = append(mainBlocks, text)
+	}
+
+	return strings.Join(mainBlocks, " ")
+}
+
+//blockText strips all markup from an HTML fragment and normalizes its whitespace
+func blockText(fragment string, policy *bluemonday.Policy) string {
+	return strings.TrimSpace(html.UnescapeString(repeatedSpaceRegex.ReplaceAllString(
+		policy.Sanitize(fragment), " ",
+	)))
+}
@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// LinkRange is a partition of the link graph that holds the links whose IDs
+// are in [FromID, ToID).
+type LinkRange struct {
+	FromID uuid.UUID
+	ToID   uuid.UUID
+}
+
+// linkLister is implemented by graphs that can iterate the links of a
+// partition.
+type linkLister interface {
+	Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+}
+
+// CrawlRanges crawls the links in ranges that were last retrieved before
+// retrievedBefore as a single crawl pass with one partition per range (see
+// CrawlPartitions).  If a checkpointer is available, the progress of each
+// partition is saved while crawling.  When the last saved pass over the same
+// ranges was interrupted, CrawlRanges resumes it with its original pass ID and
+// cutoff, skipping the partitions that were completed and the links that were
+// already sent through the pipeline, instead of starting a new pass.  Links
+// that were read ahead but not crawled before the interruption are picked up
+// by the next pass.  The returned count only includes the links crawled by
+// this call
+func (c *Crawler) CrawlRanges(ctx context.Context, ranges []LinkRange, retrievedBefore time.Time) (int, error) {
+	s := c.currentSettings()
+	lister, ok := s.graph.(linkLister)
+	if !ok {
+		return 0, xerrors.New("crawl ranges: graph does not support listing links")
+	}
+
+	progress, err := s.interruptedPass(ranges)
+	if err != nil {
+		return 0, err
+	} else if progress == nil {
+		passID, err := c.nextCrawlPass(s.graph)
+		if err != nil {
+			return 0, err
+		}
+		progress = &graph.PassProgress{PassID: passID, RetrievedBefore: retrievedBefore}
+		for _, r := range ranges {
+			progress.Partitions = append(progress.Partitions, graph.PartitionProgress{FromID: r.FromID, ToID: r.ToID})
+		}
+	}
+
+	cp := &passCheckpoint{store: s.checkpointer, interval: s.checkpointInterval, progress: progress}
+	var linkIts []graph.LinkIterator
+	defer func() {
+		for _, linkIt := range linkIts {
+			_ = linkIt.Close()
+		}
+	}()
+	for i, part := range progress.Partitions {
+		if part.Done {
+			continue
+		}
+		fromID := part.FromID
+		if part.Crawled != 0 {
+			fromID = nextUUID(part.LastLinkID)
+		}
+		linkIt, err := lister.Links(fromID, part.ToID, progress.RetrievedBefore)
+		if err != nil {
+			return 0, xerrors.Errorf("crawl ranges: %w", err)
+		}
+		linkIts = append(linkIts, &checkpointingLinkIterator{LinkIterator: linkIt, cp: cp, partition: i})
+	}
+
+	count, err := s.crawlPass(ctx, progress.PassID, linkIts)
+	if cpErr := cp.save(); cpErr != nil && err == nil {
+		err = cpErr
+	}
+	return count, err
+}
+
+// interruptedPass returns the saved progress of the last crawl pass if the
+// pass covered ranges and was not completed or nil otherwise.
+func (s *crawlSettings) interruptedPass(ranges []LinkRange) (*graph.PassProgress, error) {
+	if s.checkpointer == nil {
+		return nil, nil
+	}
+
+	progress, err := s.checkpointer.CrawlPassProgress()
+	if xerrors.Is(err, graph.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, xerrors.Errorf("load crawl pass progress: %w", err)
+	}
+
+	if progress.Done() || len(progress.Partitions) != len(ranges) {
+		return nil, nil
+	}
+	for i, part := range progress.Partitions {
+		if part.FromID != ranges[i].FromID || part.ToID != ranges[i].ToID {
+			return nil, nil
+		}
+	}
+	return progress, nil
+}
+
+// passCheckpoint tracks the progress of the partitions of a crawl pass and
+// periodically saves it. It is safe for concurrent use.
+type passCheckpoint struct {
+	store    graph.CrawlPassCheckpointer
+	interval int
+
+	mu       sync.Mutex
+	progress *graph.PassProgress
+	unsaved  int
+}
+
+// advance records that linkID was read from the specified partition. Errors
+// saving intermediate checkpoints are not fatal; if the pass is interrupted
+// it is resumed from an earlier checkpoint.
+func (cp *passCheckpoint) advance(partition int, linkID uuid.UUID) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	part := &cp.progress.Partitions[partition]
+	part.LastLinkID = linkID
+	part.Crawled++
+	if cp.unsaved++; cp.unsaved >= cp.interval {
+		_ = cp.saveLocked()
+	}
+}
+
+// finish records that all links of the specified partition have been read.
+func (cp *passCheckpoint) finish(partition int) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.progress.Partitions[partition].Done = true
+	_ = cp.saveLocked()
+}
+
+// save persists the current progress of the pass.
+func (cp *passCheckpoint) save() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.saveLocked()
+}
+
+// saveLocked persists the current progress. Callers must hold the lock.
+func (cp *passCheckpoint) saveLocked() error {
+	if cp.store == nil {
+		return nil
+	}
+
+	cp.unsaved = 0
+	if err := cp.store.SaveCrawlPassProgress(cp.progress); err != nil {
+		return xerrors.Errorf("save crawl pass %d progress: %w", cp.progress.PassID, err)
+	}
+	return nil
+}
+
+// checkpointingLinkIterator is a graph.LinkIterator that records the links
+// read from a partition in a passCheckpoint.
+type checkpointingLinkIterator struct {
+	graph.LinkIterator
+	cp        *passCheckpoint
+	partition int
+}
+
+// Next implements graph.LinkIterator.
+func (it *checkpointingLinkIterator) Next() bool {
+	if !it.LinkIterator.Next() {
+		if it.LinkIterator.Error() == nil {
+			it.cp.finish(it.partition)
+		}
+		return false
+	}
+	it.cp.advance(it.partition, it.Link().ID)
+	return true
+}
+
+// nextUUID returns the UUID that follows id in byte order.
+func nextUUID(id uuid.UUID) uuid.UUID {
+	for i := len(id) - 1; i >= 0; i-- {
+		if id[i]++; id[i] != 0 {
+			break
+		}
+	}
+	return id
+}
@@ -0,0 +1,95 @@
+package crawler
+
+import (
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	memgraph "github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CheckpointTestSuite))
+
+type CheckpointTestSuite struct{}
+
+func (s *CheckpointTestSuite) TestCheckpointingLinkIterator(c *gc.C) {
+	it := newSliceLinkIterator("http://a.com/1", "http://a.com/2", "http://a.com/3")
+	for _, link := range it.links {
+		link.ID = uuid.New()
+	}
+
+	store := new(recordingCheckpointer)
+	cp := &passCheckpoint{
+		store:    store,
+		interval: 2,
+		progress: &graph.PassProgress{PassID: 1, Partitions: make([]graph.PartitionProgress, 2)},
+	}
+	cpIt := &checkpointingLinkIterator{LinkIterator: it, cp: cp, partition: 1}
+	for cpIt.Next() {
+	}
+
+	// One checkpoint after two links and one once the partition is done
+	c.Assert(store.saved, gc.HasLen, 2)
+	c.Assert(store.saved[0].Partitions[1], gc.DeepEquals, graph.PartitionProgress{
+		LastLinkID: it.links[1].ID,
+		Crawled:    2,
+	})
+	c.Assert(store.saved[1].Partitions[1], gc.DeepEquals, graph.PartitionProgress{
+		LastLinkID: it.links[2].ID,
+		Crawled:    3,
+		Done:       true,
+	})
+	c.Assert(store.saved[1].Partitions[0], gc.DeepEquals, graph.PartitionProgress{})
+}
+
+func (s *CheckpointTestSuite) TestDecoratedGraph(c *gc.C) {
+	mem := memgraph.NewInMemoryGraph()
+	g := graph.WithRetry(
+		graph.WithCache(graph.DualWriter(mem, memgraph.NewInMemoryGraph()), graph.CacheConfig{}),
+		graph.RetryPolicy{},
+	)
+
+	// The checkpointer and the pass tracker of the decorated graph must be
+	// used instead of falling back to timestamp-based pass IDs
+	c.Assert(Config{Graph: g}.passCheckpointer(), gc.Equals, mem)
+
+	passID, err := new(Crawler).nextCrawlPass(g)
+	c.Assert(err, gc.IsNil)
+	next, err := mem.NextCrawlPass()
+	c.Assert(err, gc.IsNil)
+	c.Assert(next, gc.Equals, passID+1)
+}
+
+func (s *CheckpointTestSuite) TestNextUUID(c *gc.C) {
+	specs := []struct {
+		id  string
+		exp string
+	}{
+		{id: "00000000-0000-0000-0000-000000000000", exp: "00000000-0000-0000-0000-000000000001"},
+		{id: "00000000-0000-0000-0000-0000000000ff", exp: "00000000-0000-0000-0000-000000000100"},
+		{id: "7fffffff-ffff-ffff-ffff-ffffffffffff", exp: "80000000-0000-0000-0000-000000000000"},
+	}
+	for _, spec := range specs {
+		c.Assert(nextUUID(uuid.MustParse(spec.id)).String(), gc.Equals, spec.exp)
+	}
+}
+
+// recordingCheckpointer is a graph.CrawlPassCheckpointer that keeps a copy of
+// every checkpoint it is asked to save.
+type recordingCheckpointer struct {
+	saved []graph.PassProgress
+}
+
+func (r *recordingCheckpointer) SaveCrawlPassProgress(progress *graph.PassProgress) error {
+	pCopy := *progress
+	pCopy.Partitions = append([]graph.PartitionProgress(nil), progress.Partitions...)
+	r.saved = append(r.saved, pCopy)
+	return nil
+}
+
+func (r *recordingCheckpointer) CrawlPassProgress() (*graph.PassProgress, error) {
+	if len(r.saved) == 0 {
+		return nil, graph.ErrNotFound
+	}
+	pCopy := r.saved[len(r.saved)-1]
+	return &pCopy, nil
+}
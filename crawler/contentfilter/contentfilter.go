@@ -0,0 +1,165 @@
+// Package contentfilter provides a keyword and domain blocklist based content
+// classifier that the crawler uses to flag adult content, spam and other pages
+// that should be kept out of the default search results.
+package contentfilter
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/xerrors"
+)
+
+// defaultMinKeywordHits is used when Config.MinKeywordHits is not specified.
+const defaultMinKeywordHits = 3
+
+// Config encapsulates the configuration options for a Classifier.
+type Config struct {
+	// Keywords maps a category (e.g. "adult" or "spam") to the words and
+	// phrases that indicate that a page belongs to it. Keywords are
+	// matched case-insensitively against whole words.
+	Keywords map[string][]string
+
+	// MinKeywordHits is the number of keyword occurrences in the title and
+	// text of a page that are required to flag it. If not specified, a
+	// default value of 3 will be used.
+	MinKeywordHits int
+
+	// Domains maps a category to the domains whose pages are always
+	// flagged. Domains match their subdomains as well.
+	Domains map[string][]string
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if len(cfg.Keywords) == 0 && len(cfg.Domains) == 0 {
+		err = xerrors.New("no keywords or domains specified")
+	}
+	if cfg.MinKeywordHits <= 0 {
+		cfg.MinKeywordHits = defaultMinKeywordHits
+	}
+	return err
+}
+
+// category holds the keywords of a category, each one split into words.
+type category struct {
+	name     string
+	keywords [][]string
+}
+
+// Classifier flags pages that are hosted on a blocklisted domain or whose
+// title and text contain too many of the keywords of a category. It is safe
+// for concurrent use.
+type Classifier struct {
+	categories []category
+	domains    map[string]string
+	minHits    int
+}
+
+// New creates a new Classifier using the provided config.
+func New(cfg Config) (*Classifier, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("content filter config validation failed: %w", err)
+	}
+
+	c := &Classifier{domains: make(map[string]string), minHits: cfg.MinKeywordHits}
+	for name, keywords := range cfg.Keywords {
+		cat := category{name: name}
+		for _, keyword := range keywords {
+			words := splitWords(keyword)
+			if len(words) == 0 {
+				return nil, xerrors.Errorf("content filter config validation failed: category %q: empty keyword", name)
+			}
+			cat.keywords = append(cat.keywords, words)
+		}
+		c.categories = append(c.categories, cat)
+	}
+	// Categories are checked in a deterministic order
+	sort.Slice(c.categories, func(i, j int) bool { return c.categories[i].name < c.categories[j].name })
+
+	for name, domains := range cfg.Domains {
+		for _, domain := range domains {
+			domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+			if domain == "" {
+				return nil, xerrors.Errorf("content filter config validation failed: category %q: empty domain", name)
+			}
+			c.domains[domain] = name
+		}
+	}
+	return c, nil
+}
+
+// Classify implements crawler.ContentClassifier. It returns the category of
+// the page or an empty string if the page is acceptable. Blocklisted domains
+// take precedence over keywords.
+func (c *Classifier) Classify(rawURL, title, text string) string {
+	if name := c.domainCategory(rawURL); name != "" {
+		return name
+	}
+
+	words := splitWords(title + " " + text)
+	for _, cat := range c.categories {
+		var hits int
+		for _, keyword := range cat.keywords {
+			hits += countOccurrences(words, keyword)
+		}
+		if hits >= c.minHits {
+			return cat.name
+		}
+	}
+	return ""
+}
+
+// domainCategory returns the category of the most specific blocklisted
+// domain that matches the host of rawURL.
+func (c *Classifier) domainCategory(rawURL string) string {
+	if len(c.domains) == 0 {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	for host != "" {
+		if name, found := c.domains[host]; found {
+			return name
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot == -1 {
+			break
+		}
+		host = host[dot+1:]
+	}
+	return ""
+}
+
+// countOccurrences returns the number of times the sequence of words in
+// keyword appears in words.
+func countOccurrences(words, keyword []string) int {
+	var count int
+	for i := 0; i+len(keyword) <= len(words); i++ {
+		match := true
+		for j, word := range keyword {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			count++
+		}
+	}
+	return count
+}
+
+// splitWords lower-cases text and splits it into words at any character that
+// is neither a letter nor a digit.
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
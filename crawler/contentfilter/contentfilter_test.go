@@ -0,0 +1,56 @@
+package contentfilter
+
+import (
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ContentFilterTestSuite))
+
+type ContentFilterTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *ContentFilterTestSuite) TestClassify(c *gc.C) {
+	classifier, err := New(Config{
+		Keywords: map[string][]string{
+			"spam":     {"casino", "free spins", "Viagra"},
+			"gambling": {"casino"},
+		},
+		MinKeywordHits: 2,
+		Domains: map[string][]string{
+			"adult": {"example.xxx", "Adult.example.com."},
+		},
+	})
+	c.Assert(err, gc.IsNil)
+
+	specs := []struct {
+		url, title, text string
+		exp              string
+	}{
+		{url: "http://example.com/", title: "Gophers", text: "Gophers are small burrowing rodents.", exp: ""},
+		{url: "http://example.com/", title: "Casino!", text: "Claim your FREE spins today", exp: "spam"},
+		// Categories are checked in alphabetical order
+		{url: "http://example.com/", title: "Casino", text: "The casino opened in 1923.", exp: "gambling"},
+		// Keywords only match whole words
+		{url: "http://example.com/", title: "Spinach", text: "casinos free spinsters", exp: ""},
+		{url: "http://www.example.xxx/gophers", title: "Gophers", exp: "adult"},
+		{url: "http://adult.example.com/", exp: "adult"},
+		{url: "http://example.com.xxx/", exp: ""},
+	}
+	for i, spec := range specs {
+		c.Assert(classifier.Classify(spec.url, spec.title, spec.text), gc.Equals, spec.exp, gc.Commentf("spec %d", i))
+	}
+}
+
+func (s *ContentFilterTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := New(Config{})
+	c.Assert(err, gc.ErrorMatches, ".*no keywords or domains specified")
+
+	_, err = New(Config{Keywords: map[string][]string{"spam": {" !! "}}})
+	c.Assert(err, gc.ErrorMatches, `.*category "spam": empty keyword`)
+
+	_, err = New(Config{Domains: map[string][]string{"adult": {""}}})
+	c.Assert(err, gc.ErrorMatches, `.*category "adult": empty domain`)
+}
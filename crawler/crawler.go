@@ -1,26 +1,100 @@
 package crawler
 
 import (
+	"container/heap"
 	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
 
+	"github.com/brandonshearin/ask_brandon/crawler/alert"
+	"github.com/brandonshearin/ask_brandon/crawler/rules"
+	"github.com/brandonshearin/ask_brandon/crawler/warc"
 	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
 	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
 )
 
-//decorate the link iterator from graph package to implment the source interface for our pipeline
+//go:generate mockgen -package mocks -destination mocks/mocks.go github.com/brandonshearin/ask_brandon/crawler URLGetter,RenderingURLGetter,PrivateNetworkDetector,Graph,Indexer
+
+// decorate the link iterator from graph package to implment the source interface for our pipeline
 type linkSource struct {
 	linkIt graph.LinkIterator
+	passID uint64
+	stats  *passCounters
+	quota  *domainQuota
+	traps  *spiderTrapDetector
+
+	//window is the number of links that are read ahead from linkIt so that
+	//the ones with the highest priority are crawled first
+	window int
+	//authority, if set, orders links with the same priority by the
+	//authority of their host
+	authority DomainAuthority
+	queue     linkQueue
+	seq       int
+	exhausted bool
+
+	latchedLink *graph.Link
+}
+
+/*Error() method is a proxy to underlying iterator obj.*/
+func (ls *linkSource) Error() error { return ls.linkIt.Error() }
+
+// Next advances the underlying iterator, skipping links that have been parked
+// until a later time because their server asked us to back off and links whose
+// domain has exhausted its quota for this pass.  Within the read-ahead window,
+// links are emitted in priority order
+func (ls *linkSource) Next(context.Context) bool {
+	now := time.Now()
+	for {
+		for !ls.exhausted && (ls.queue.Len() < ls.window || ls.queue.Len() == 0) {
+			if !ls.linkIt.Next() {
+				ls.exhausted = true
+				break
+			}
+			if link := ls.linkIt.Link(); !link.RetryNotBefore.After(now) {
+				heap.Push(&ls.queue, queuedLink{link: link, authority: ls.domainAuthority(link.URL), seq: ls.seq})
+				ls.seq++
+			}
+		}
+		if ls.queue.Len() == 0 {
+			return false
+		}
+
+		//quotas are checked last so that they are consumed by the links
+		//with the highest priority
+		if link := heap.Pop(&ls.queue).(queuedLink).link; ls.quota.allow(link.URL) {
+			ls.latchedLink = link
+			return true
+		}
+	}
+}
+
+// domainAuthority returns the authority of the host of rawURL or zero if no
+// domain authority scores are available
+func (ls *linkSource) domainAuthority(rawURL string) float64 {
+	if ls.authority == nil {
+		return 0
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	return ls.authority.Score(normalizeDomain(u.Hostname()))
 }
 
-/*Error() and Next() methods are proxies to underlying iterator obj.*/
-func (ls *linkSource) Error() error              { return ls.linkIt.Error() }
-func (ls *linkSource) Next(context.Context) bool { return ls.linkIt.Next() }
 func (ls *linkSource) Payload() pipeline.Payload {
-	link := ls.linkIt.Link()
-	p := payloadPool.Get().(*crawlerPayload)
+	link := ls.latchedLink
+	p := payloadPool.Get().(*Payload)
 	p.LinkID = link.ID
 	p.URL = link.URL
 	p.RetrievedAt = link.RetrievedAt
+	p.CrawlPassID = ls.passID
+	p.passStats = ls.stats
+	p.spiderTraps = ls.traps
 
 	return p
 }
@@ -40,30 +114,142 @@ func (s *countingSink) getCount() int {
 	return s.count / 2
 }
 
-/*Sink needs to function as a blackhole.  Once payload goes through link updater
-and text indexer stages, we have no further use for it*/
+/*
+Sink needs to function as a blackhole.  Once payload goes through link updater
+and text indexer stages, we have no further use for it
+*/
 type nopSink struct{}
 
-//Consume ignores payloads and returns nil error.  Once the call to consume returns,
-//pipeline worker automatically invokes the MarkAsProcessed method on the payload
+// Consume ignores payloads and returns nil error.  Once the call to consume returns,
+// pipeline worker automatically invokes the MarkAsProcessed method on the payload
 func (nopSink) Consume(context.Context, pipeline.Payload) error { return nil }
 
 // Crawler implements a web-page crawling pipeline consisting of the following stages:
 //
-// - Given a URL, retrieve the web-page contents from the remote server.
-// - Extract and resolve absolute and relative links from teh retrieved page
-// - Extract page title and text content from the retrieved page
-// - Update the link graph: add new links and create edges between the crawled
-//   page and the links within it
-// - Index crawled page title and text content
+//   - Given a URL, retrieve the web-page contents from the remote server.
+//   - Extract and resolve absolute and relative links from teh retrieved page
+//   - Extract structured (JSON-LD) entities embedded in the retrieved page
+//   - Extract page title and text content from the retrieved page
+//   - Update the link graph: add new links and create edges between the crawled
+//     page and the links within it
+//   - Index crawled page title and text content
 type Crawler struct {
-	p *pipeline.Pipeline
+	// The politeness state is shared by all pipelines so that hosts are
+	// not hit harder when several partitions are crawled concurrently.  It
+	// is kept across reconfigurations so that rate limit changes apply to
+	// the requests of the passes in progress too
+	limiter *hostLimiter
+	delay   *adaptiveDelay
+
+	settingsMu sync.RWMutex
+	settings   *crawlSettings
+
+	passMu   sync.Mutex
+	lastPass uint64
+}
+
+// crawlSettings holds the pipelines and the per-pass options of the crawler
+// that are derived from a Config.  Each crawl pass uses the settings that were
+// in effect when it started
+type crawlSettings struct {
+	//pipelines holds the idle crawler pipelines; a partition is crawled
+	//by taking a pipeline out of the pool and returning it once done
+	pipelines      chan *pipeline.Pipeline
+	crawlPipelines []*pipeline.Pipeline
+	ingestP        *pipeline.Pipeline
+	graph          Graph
+	monitor        *alert.Monitor
+
+	maxPagesPerDomain int
+	domainQuotas      map[string]int
+	quotaReporter     QuotaReporter
+	window            int
+	authority         DomainAuthority
+	stealBatchSize    int
+
+	checkpointer       graph.CrawlPassCheckpointer
+	checkpointInterval int
+
+	trapLimits   spiderTrapLimits
+	trapReporter SpiderTrapReporter
 }
 
 // NewCrawler returns a new crawler instance
 func NewCrawler(cfg Config) *Crawler {
-	return &Crawler{
-		p: assembleCrawlerPipeline(cfg),
+	minDelay, maxDelay := cfg.crawlDelay()
+	c := &Crawler{
+		limiter: newHostLimiter(cfg.maxConnsPerHost()),
+		delay:   newAdaptiveDelay(minDelay, maxDelay, cfg.SlowResponseThreshold),
+	}
+	c.delay.setSiteDelays(cfg.SiteCrawlDelays)
+	c.settings = c.newSettings(cfg)
+	return c
+}
+
+// Reconfigure applies cfg to a running crawler, e.g. after its configuration
+// file has been reloaded.  The per-host connection limit and the crawl delays
+// take effect immediately.  All other options, such as the number of fetch
+// workers, the crawl rules, the content classifier, the render domains or the
+// domain quotas, are applied by the next crawl pass while the passes in
+// progress complete with the previous configuration
+func (c *Crawler) Reconfigure(cfg Config) error {
+	if cfg.FetchWorkers <= 0 {
+		return xerrors.New("reconfigure crawler: fetch workers must be greater than zero")
+	} else if cfg.Graph == nil {
+		return xerrors.New("reconfigure crawler: graph has not been provided")
+	}
+
+	settings := c.newSettings(cfg)
+	c.limiter.setMaxConns(cfg.maxConnsPerHost())
+	minDelay, maxDelay := cfg.crawlDelay()
+	c.delay.setBounds(minDelay, maxDelay, cfg.SlowResponseThreshold)
+	c.delay.setSiteDelays(cfg.SiteCrawlDelays)
+
+	c.settingsMu.Lock()
+	c.settings = settings
+	c.settingsMu.Unlock()
+	return nil
+}
+
+// currentSettings returns the settings that new crawl passes should use
+func (c *Crawler) currentSettings() *crawlSettings {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+	return c.settings
+}
+
+// newSettings assembles the pipelines and collects the per-pass options of cfg
+func (c *Crawler) newSettings(cfg Config) *crawlSettings {
+	crawlPipelines := make([]*pipeline.Pipeline, cfg.concurrentPartitions())
+	pipelines := make(chan *pipeline.Pipeline, len(crawlPipelines))
+	for i := range crawlPipelines {
+		crawlPipelines[i] = assembleCrawlerPipeline(cfg, c.limiter, c.delay)
+		pipelines <- crawlPipelines[i]
+	}
+
+	return &crawlSettings{
+		pipelines:      pipelines,
+		crawlPipelines: crawlPipelines,
+		ingestP:        assembleIngestPipeline(cfg),
+		graph:          cfg.Graph,
+		monitor:        cfg.AlertMonitor,
+
+		maxPagesPerDomain: cfg.MaxPagesPerDomain,
+		domainQuotas:      cfg.DomainQuotas,
+		quotaReporter:     cfg.QuotaReporter,
+		window:            cfg.PrioritizationWindow,
+		authority:         cfg.DomainAuthority,
+		stealBatchSize:    cfg.StealBatchSize,
+
+		checkpointer:       cfg.passCheckpointer(),
+		checkpointInterval: cfg.checkpointInterval(),
+
+		trapLimits: spiderTrapLimits{
+			maxPathDepth:        cfg.MaxPathDepth,
+			maxRepeatedSegments: cfg.MaxRepeatedPathSegments,
+			maxURLsPerPattern:   cfg.MaxURLsPerPattern,
+		},
+		trapReporter: cfg.SpiderTrapReporter,
 	}
 }
 
@@ -74,32 +260,496 @@ type Config struct {
 	Graph                  Graph
 	Indexer                Indexer
 
+	// RenderingURLGetter, if specified, is used for fetching links whose
+	// host matches one of the RenderDomains. This allows SPA-heavy sites
+	// to be fetched through a headless browser so they produce indexable
+	// content instead of empty bodies.
+	RenderingURLGetter RenderingURLGetter
+	RenderDomains      []string
+
+	// LanguageDetector, if specified, is used by the text extractor to
+	// tag each document with the language of its content.
+	LanguageDetector LanguageDetector
+
+	// ContentClassifier, if specified, is invoked by the text extractor
+	// to flag pages (e.g. adult content or spam) that are indexed but
+	// excluded from the default search results. Flagged pages are still
+	// added to the link graph. See the contentfilter package for a
+	// keyword and blocklist based implementation.
+	ContentClassifier ContentClassifier
+
+	// Enrichers, if specified, are invoked in order by the text indexer
+	// to augment each document (e.g. with extracted keywords, entity tags
+	// or a summary) before it is indexed. Enrichment errors fail the crawl
+	// pass like indexing errors do, so enrichers that depend on flaky
+	// services should handle transient failures themselves. See the
+	// textindexer/summarizer and textindexer/keyphrase packages for
+	// enrichers that compute document summaries and keyphrases.
+	Enrichers []index.Enricher
+
+	// Passages, if specified, makes the text indexer split the content of
+	// each page into overlapping passages that are indexed as child
+	// documents of the page (see index.SplitPassages) so they can be
+	// retrieved by queries that set index.Query.Passages. If the indexer
+	// also implements Delete, passages left over from previous, longer,
+	// versions of a page are removed.
+	Passages *index.PassageConfig
+
+	// RemoveBoilerplate enables a text-density based heuristic in the text
+	// extractor that strips navigation menus, footers and other
+	// boilerplate from the page text. When the main text of a page can be
+	// identified, it is indexed instead of the full page text.
+	RemoveBoilerplate bool
+
+	// MediaStore, if specified, enables an extra pipeline branch that
+	// catalogs the images and videos referenced by each crawled page.
+	MediaStore MediaStore
+
+	// MinCrawlDelay and MaxCrawlDelay bound the adaptive delay between
+	// two requests to the same host. The delay grows when a host responds
+	// slower than SlowResponseThreshold or with 429/503 status codes and
+	// shrinks while the host stays healthy. Setting MaxCrawlDelay to
+	// zero disables the adaptive politeness controller.
+	MinCrawlDelay         time.Duration
+	MaxCrawlDelay         time.Duration
+	SlowResponseThreshold time.Duration
+
+	// SiteCrawlDelays, if specified, provides per-site crawl rate
+	// preferences. The delay between two requests to a site never drops
+	// below its preference, even if the adaptive politeness controller is
+	// disabled.
+	SiteCrawlDelays SiteCrawlDelays
+
+	// MaxConnsPerHost caps the number of concurrent connections that the
+	// fetch workers may open to a single host. If not specified, a
+	// default value of 2 will be used.
+	MaxConnsPerHost int
+
+	// UseHeadPrecheck enables issuing a HEAD request before downloading
+	// each link so that non-HTML resources and resources larger than
+	// MaxContentLength are skipped without fetching their body. The
+	// URLGetter must implement HeadURLGetter (http.Client does);
+	// otherwise the precheck is silently skipped.
+	UseHeadPrecheck bool
+
+	// MaxContentLength is the maximum size, in bytes, of the resources
+	// that are downloaded when UseHeadPrecheck is enabled. If not
+	// specified, a default value of 10MiB will be used.
+	MaxContentLength int64
+
+	// SpoolThreshold, if specified, is the size in bytes above which
+	// fetched response bodies are spooled to temporary files in SpoolDir
+	// (or the default temporary directory) instead of being buffered in
+	// memory. This bounds the memory used by payloads that are waiting to
+	// be processed by the pipeline. Temporary files are removed once the
+	// payload has been processed.
+	SpoolThreshold int
+	SpoolDir       string
+
+	// ArchiveStore, if specified, enables an extra pipeline branch that
+	// writes each processed page (URL, fetch metadata and extracted text)
+	// as compressed JSON to object storage for offline analytics.
+	// ArchivePrefix is prepended to the keys of the archived objects.
+	ArchiveStore  ObjectStore
+	ArchivePrefix string
+
+	// WARCWriter, if specified, enables an extra pipeline branch that
+	// writes the fetched pages as WARC request/response records so that
+	// crawls can be archived and replayed by standard web-archive tools.
+	WARCWriter *warc.Writer
+
+	// AlertMonitor, if specified, receives the fetch statistics of each
+	// completed crawl pass and raises alerts when the error rate, the
+	// ratio of 4xx/5xx responses or the number of fetched pages deviate
+	// from the previous pass.
+	AlertMonitor *alert.Monitor
+
+	// MaxPagesPerDomain, if specified, caps the number of links per host
+	// that are crawled in a single pass so that a huge site cannot
+	// consume the entire crawl budget. DomainQuotas overrides the cap for
+	// specific domains; all subdomains of an overridden domain share its
+	// quota and a non-positive quota lifts the cap. Links over quota are
+	// left untouched in the graph and are picked up by a subsequent pass.
+	// QuotaReporter, if specified, receives the per-domain usage at the
+	// end of each pass.
+	MaxPagesPerDomain int
+	DomainQuotas      map[string]int
+	QuotaReporter     QuotaReporter
+
+	// CrawlRules, if specified, are applied by the link extractor to each
+	// extracted link. Rules can deny links, strip query parameters or
+	// adjust the priority of links; priorities are stored in the graph.
+	CrawlRules *rules.Engine
+
+	// PrioritizationWindow is the number of links that the crawler reads
+	// ahead from the link iterator so that links with a higher priority
+	// are crawled first. If not specified, links are crawled in the order
+	// they are returned by the iterator.
+	PrioritizationWindow int
+
+	// DomainAuthority, if specified, breaks ties between the links with
+	// the same priority within the PrioritizationWindow: links whose host
+	// has a higher authority score are crawled first. See the
+	// linkgraph/hostgraph package for scores computed by running PageRank
+	// over the host graph.
+	DomainAuthority DomainAuthority
+
+	// MaxPathDepth, MaxRepeatedPathSegments and MaxURLsPerPattern enable
+	// heuristics in the link extractor that stop the crawler from getting
+	// lost in spider traps such as calendars or faceted navigation.
+	// Extracted links are suppressed if their path has more than
+	// MaxPathDepth segments, if any path segment occurs more than
+	// MaxRepeatedPathSegments times or if more than MaxURLsPerPattern
+	// distinct links with the same pattern (the same host and path once
+	// numbers are ignored and the same query parameter names) were
+	// extracted in the current pass. Each heuristic is disabled if its
+	// threshold is not specified. SpiderTrapReporter, if specified,
+	// receives the number of suppressed links at the end of each pass.
+	MaxPathDepth            int
+	MaxRepeatedPathSegments int
+	MaxURLsPerPattern       int
+	SpiderTrapReporter      SpiderTrapReporter
+
+	// DetectSoft404s enables a stage that keeps "page not found" pages
+	// that are served with a 2xx status out of the index. Pages are
+	// classified by looking for not-found phrases in their title and
+	// text and, when crawling, by comparing their text to the page that
+	// each host returns for a random URL that does not exist. Each host
+	// is probed at most once a day.
+	DetectSoft404s bool
+
+	// PreFetch, PostExtract and PreIndex are hooks for injecting custom
+	// processors (e.g. for deduplication, enrichment or filtering) into the
+	// pipelines. The processors receive *Payload values and each hook runs
+	// its processors in order:
+	//  - PreFetch processors run before a link is fetched and only have
+	//    access to the fields populated by the link source. Discarded
+	//    payloads are neither fetched nor updated in the graph. The hook is
+	//    not used by the ingest pipeline whose pages have already been
+	//    fetched.
+	//  - PostExtract processors run after the built-in extraction stages.
+	//    Discarded payloads are not sent to the graph, the indexers or the
+	//    archive.
+	//  - PreIndex processors run right before a page is added to the text
+	//    index. Discarded payloads are only kept out of the text index.
+	PreFetch    []pipeline.Processor
+	PostExtract []pipeline.Processor
+	PreIndex    []pipeline.Processor
+
+	// ExtraStages are custom stages (e.g. screenshotting or ML enrichment)
+	// that are inserted into both the crawler and the ingest pipelines after
+	// the PostExtract hooks and before the payloads are sent to the graph,
+	// the indexers and the archive. The stages process *Payload
+	// values and may enrich or discard them. The same stage runners are used
+	// by all pipelines of the crawler so they must support concurrent Run
+	// calls, as the runners of the pipeline package do.
+	ExtraStages []pipeline.StageRunner
+
+	// ConcurrentPartitions is the number of partitions that CrawlPartitions
+	// crawls concurrently. Each partition is processed by its own pipeline
+	// with FetchWorkers fetch workers while the per-host connection limit
+	// and crawl delay are enforced across all of them. If not specified,
+	// partitions are crawled one at a time.
+	ConcurrentPartitions int
+
+	// StealBatchSize enables work stealing between the partitions that are
+	// crawled by CrawlPartitions. Once a pipeline runs out of links in its
+	// own partition, it takes batches of StealBatchSize links from the
+	// partition that lags the most instead of sitting idle until the pass
+	// completes. If not specified, each partition is crawled by a single
+	// pipeline.
+	StealBatchSize int
+
+	// PassCheckpointer, if specified, persists the progress of the passes
+	// started by CrawlRanges so that a restarted crawler resumes an
+	// interrupted pass. If not specified and the graph, or a graph it
+	// decorates (see graph.Wrapper), implements
+	// graph.CrawlPassCheckpointer, the progress is saved in the graph.
+	// CheckpointInterval is the number of links that are crawled between
+	// checkpoints. If not specified, a default value of 100 will be used.
+	PassCheckpointer   graph.CrawlPassCheckpointer
+	CheckpointInterval int
+
 	FetchWorkers int
 }
 
+// defaultMaxConnsPerHost is used when Config.MaxConnsPerHost is not specified.
+const defaultMaxConnsPerHost = 2
+
+// defaultCheckpointInterval is used when Config.CheckpointInterval is not
+// specified.
+const defaultCheckpointInterval = 100
+
+// defaultMaxContentLength is used when Config.MaxContentLength is not specified.
+const defaultMaxContentLength = 10 << 20
+
+func (cfg Config) maxConnsPerHost() int {
+	if cfg.MaxConnsPerHost <= 0 {
+		return defaultMaxConnsPerHost
+	}
+	return cfg.MaxConnsPerHost
+}
+
+// crawlDelay returns the bounds of the per-host crawl delay which are both
+// zero if the politeness controller is disabled
+func (cfg Config) crawlDelay() (time.Duration, time.Duration) {
+	if cfg.MaxCrawlDelay <= 0 {
+		return 0, 0
+	}
+	return cfg.MinCrawlDelay, cfg.MaxCrawlDelay
+}
+
+func (cfg Config) concurrentPartitions() int {
+	if cfg.ConcurrentPartitions <= 0 {
+		return 1
+	}
+	return cfg.ConcurrentPartitions
+}
+
+func (cfg Config) passCheckpointer() graph.CrawlPassCheckpointer {
+	if cfg.PassCheckpointer != nil {
+		return cfg.PassCheckpointer
+	}
+	checkpointer, _ := graph.AsCrawlPassCheckpointer(cfg.Graph)
+	return checkpointer
+}
+
+func (cfg Config) checkpointInterval() int {
+	if cfg.CheckpointInterval <= 0 {
+		return defaultCheckpointInterval
+	}
+	return cfg.CheckpointInterval
+}
+
+func (cfg Config) maxContentLength() int64 {
+	if cfg.MaxContentLength <= 0 {
+		return defaultMaxContentLength
+	}
+	return cfg.MaxContentLength
+}
+
 // assembleCrawlerPipeline creates the various stages of a crawler pipeline
-// using the options in cfg and assembles them into a pipeline instance
-func assembleCrawlerPipeline(cfg Config) *pipeline.Pipeline {
-	return pipeline.New(
-		pipeline.FixedWorkerPool(
-			newLinkFetcher(cfg.URLGetter, cfg.PrivateNetworkDetector),
-			cfg.FetchWorkers,
-		),
-		pipeline.FIFO(newLinkExtractor(cfg.PrivateNetworkDetector)),
-		pipeline.FIFO(newTextExtractor()),
-		pipeline.Broadcast(
-			newGraphUpdater(cfg.Graph),
-			newTextIndexer(cfg.Indexer),
-		),
-	)
+// using the options in cfg and assembles them into a pipeline instance.  The
+// link fetcher enforces the per-host politeness using limiter and delay
+func assembleCrawlerPipeline(cfg Config, limiter *hostLimiter, delay *adaptiveDelay) *pipeline.Pipeline {
+	fetcher := newLinkFetcher(cfg.URLGetter, cfg.PrivateNetworkDetector)
+	fetcher.renderer = cfg.RenderingURLGetter
+	fetcher.renderDomains = cfg.RenderDomains
+	fetcher.hostLimiter = limiter
+	fetcher.linkParker = cfg.Graph
+	fetcher.headPrecheck = cfg.UseHeadPrecheck
+	fetcher.maxContentLength = cfg.maxContentLength()
+	fetcher.spoolThreshold = cfg.SpoolThreshold
+	fetcher.spoolDir = cfg.SpoolDir
+	fetcher.politeness = delay
+
+	stages := append(hookStages(cfg.PreFetch), pipeline.FixedWorkerPool(fetcher, cfg.FetchWorkers))
+	return pipeline.New(append(stages, processingStages(cfg, true)...)...)
+}
+
+// assembleIngestPipeline creates a pipeline for processing pages read from
+// WARC files. It consists of the same stages as the crawler pipeline except
+// for the link fetcher and the WARC exporter.
+func assembleIngestPipeline(cfg Config) *pipeline.Pipeline {
+	return pipeline.New(processingStages(cfg, false)...)
+}
+
+// processingStages creates the stages that process the fetched pages. If live
+// is false, the pages are not fetched by the crawler and the stages must not
+// send any requests or export the pages as WARC records.
+func processingStages(cfg Config, live bool) []pipeline.StageRunner {
+	extractor := newTextExtractor()
+	extractor.langDetector = cfg.LanguageDetector
+	extractor.removeBoilerplate = cfg.RemoveBoilerplate
+	extractor.classifier = cfg.ContentClassifier
+
+	linkExtractor := newLinkExtractor(cfg.PrivateNetworkDetector)
+	linkExtractor.rules = cfg.CrawlRules
+
+	stages := []pipeline.StageRunner{
+		pipeline.FIFO(linkExtractor),
+		pipeline.FIFO(newStructuredDataExtractor()),
+	}
+	var textIndexer pipeline.Processor = newTextIndexer(cfg.Indexer, cfg.Enrichers, cfg.Passages)
+	if len(cfg.PreIndex) != 0 {
+		textIndexer = &indexHooks{hooks: cfg.PreIndex, indexer: textIndexer}
+	}
+	branches := []pipeline.Processor{
+		newGraphUpdater(cfg.Graph),
+		textIndexer,
+	}
+
+	// Media must be extracted before the text extractor consumes the raw
+	// page content. The media indexer branch discards its payloads so it
+	// does not affect the counts reported by Crawl.
+	if cfg.MediaStore != nil {
+		stages = append(stages, pipeline.FIFO(newMediaExtractor()))
+		branches = append(branches, newMediaIndexer(cfg.MediaStore))
+	}
+	if cfg.ArchiveStore != nil {
+		branches = append(branches, newArchiveSink(cfg.ArchiveStore, cfg.ArchivePrefix))
+	}
+	if cfg.WARCWriter != nil && live {
+		branches = append(branches, newWARCExporter(cfg.WARCWriter))
+	}
+
+	stages = append(stages, pipeline.FIFO(extractor))
+	if cfg.DetectSoft404s {
+		var prober URLGetter
+		if live {
+			prober = cfg.URLGetter
+		}
+		stages = append(stages, pipeline.FIFO(newSoft404Detector(prober)))
+	}
+
+	stages = append(stages, hookStages(cfg.PostExtract)...)
+	stages = append(stages, cfg.ExtraStages...)
+	return append(stages, pipeline.Broadcast(branches...))
 }
 
 // Crawl iterates linkIt and sends each link through the crawler pipeline
 // returning the total count of links that went through the pipeline.  Calls
 // to Crawl block until the link iterator is exhausted, an error occurs or
-// the context is cancelled
+// the context is cancelled.  Each call to Crawl is assigned a new crawl pass
+// ID which is recorded on all links, edges and documents updated during the pass
 func (c *Crawler) Crawl(ctx context.Context, linkIt graph.LinkIterator) (int, error) {
-	sink := new(countingSink)
-	err := c.p.Process(ctx, &linkSource{linkIt: linkIt}, sink)
-	return sink.getCount(), err
+	return c.CrawlPartitions(ctx, []graph.LinkIterator{linkIt})
+}
+
+// CrawlPartitions works like Crawl but crawls the links of several iterators
+// (e.g. one per graph partition) as a single crawl pass.  Up to
+// Config.ConcurrentPartitions iterators are processed concurrently, each one
+// by its own pipeline; the remaining ones wait for a pipeline to become idle.
+// Per-host politeness and per-domain quotas are enforced across all
+// partitions.  If Config.StealBatchSize is set, pipelines that finish their
+// partition early steal links from the partitions still being crawled.  If
+// crawling a partition fails, the other partitions are cancelled and the
+// first error is returned
+func (c *Crawler) CrawlPartitions(ctx context.Context, linkIts []graph.LinkIterator) (int, error) {
+	s := c.currentSettings()
+	passID, err := c.nextCrawlPass(s.graph)
+	if err != nil {
+		return 0, err
+	}
+	return s.crawlPass(ctx, passID, linkIts)
+}
+
+// crawlPass crawls the links of linkIts as part of the specified crawl pass
+func (s *crawlSettings) crawlPass(ctx context.Context, passID uint64, linkIts []graph.LinkIterator) (int, error) {
+	var (
+		stats = new(passCounters)
+		quota = newDomainQuota(s.maxPagesPerDomain, s.domainQuotas)
+		traps = newSpiderTrapDetector(s.trapLimits)
+
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		count int
+		err   error
+	)
+	if s.stealBatchSize > 0 && len(linkIts) > 1 {
+		sc := newStealCoordinator(linkIts, s.stealBatchSize)
+		stealingIts := make([]graph.LinkIterator, len(linkIts))
+		for i := range linkIts {
+			stealingIts[i] = sc.iterator(i)
+		}
+		linkIts = stealingIts
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for _, linkIt := range linkIts {
+		wg.Add(1)
+		go func(linkIt graph.LinkIterator) {
+			defer wg.Done()
+
+			var p *pipeline.Pipeline
+			select {
+			case p = <-s.pipelines:
+			case <-runCtx.Done():
+				return
+			}
+
+			sink := new(countingSink)
+			src := &linkSource{
+				linkIt:    linkIt,
+				passID:    passID,
+				stats:     stats,
+				quota:     quota,
+				traps:     traps,
+				window:    s.window,
+				authority: s.authority,
+			}
+			pErr := p.Process(runCtx, src, sink)
+			s.pipelines <- p
+
+			mu.Lock()
+			count += sink.getCount()
+			if pErr != nil && err == nil {
+				err = pErr
+				cancel()
+			}
+			mu.Unlock()
+		}(linkIt)
+	}
+	wg.Wait()
+
+	if quota != nil && s.quotaReporter != nil {
+		s.quotaReporter.ReportQuotaUsage(passID, quota.report())
+	}
+	s.reportSpiderTraps(passID, traps)
+	if err != nil {
+		return count, err
+	}
+
+	// Only completed passes are compared as an interrupted pass would
+	// always be reported as an anomaly
+	if s.monitor != nil {
+		if _, err = s.monitor.Observe(ctx, stats.stats(passID)); err != nil {
+			return count, xerrors.Errorf("crawl pass %d: %w", passID, err)
+		}
+	}
+	return count, nil
+}
+
+// DescribePipelines returns a description of the crawler pipelines and the
+// live statistics of their stages keyed by pipeline name.  The pipelines that
+// crawl partitions are named "crawl-0", "crawl-1" etc. while the pipeline used
+// by Ingest is named "ingest"
+func (c *Crawler) DescribePipelines() map[string]*pipeline.Description {
+	s := c.currentSettings()
+	descriptions := map[string]*pipeline.Description{"ingest": s.ingestP.Describe()}
+	for i, p := range s.crawlPipelines {
+		descriptions[fmt.Sprintf("crawl-%d", i)] = p.Describe()
+	}
+	return descriptions
+}
+
+// reportSpiderTraps sends the statistics collected by traps to the
+// configured SpiderTrapReporter.
+func (s *crawlSettings) reportSpiderTraps(passID uint64, traps *spiderTrapDetector) {
+	if traps != nil && s.trapReporter != nil {
+		s.trapReporter.ReportSpiderTraps(passID, traps.report())
+	}
+}
+
+// nextCrawlPass returns a new crawl pass ID.  Pass IDs are allocated by g if
+// it, or a graph it decorates, implements graph.CrawlPassTracker.  Otherwise they are derived from the
+// current time so that they keep increasing across restarts
+func (c *Crawler) nextCrawlPass(g Graph) (uint64, error) {
+	if tracker, ok := graph.AsCrawlPassTracker(g); ok {
+		passID, err := tracker.NextCrawlPass()
+		if err != nil {
+			return 0, xerrors.Errorf("allocate crawl pass: %w", err)
+		}
+		return passID, nil
+	}
+
+	c.passMu.Lock()
+	defer c.passMu.Unlock()
+	passID := uint64(time.Now().UnixNano())
+	if passID <= c.lastPass {
+		passID = c.lastPass + 1
+	}
+	c.lastPass = passID
+	return passID, nil
 }
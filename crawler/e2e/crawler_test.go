@@ -0,0 +1,382 @@
+package e2e_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/crawler"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	memgraph "github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	memindex "github.com/brandonshearin/ask_brandon/textindexer/store/memory"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var (
+	_ = gc.Suite(new(CrawlerE2ETestSuite))
+
+	minUUID = uuid.Nil
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+// maxCrawlPasses bounds the number of crawl passes so a regression in the
+// crawler cannot make the test loop forever.
+const maxCrawlPasses = 5
+
+type CrawlerE2ETestSuite struct {
+	site        *httptest.Server
+	private     *httptest.Server
+	privateHits int32
+
+	graph   *memgraph.InMemoryGraph
+	indexer *memindex.InMemoryBleveIndexer
+}
+
+func (s *CrawlerE2ETestSuite) SetUpTest(c *gc.C) {
+	atomic.StoreInt32(&s.privateHits, 0)
+	s.private = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&s.privateHits, 1)
+		serveHTML(w, "Secret", "internal only")
+	}))
+
+	// The private server is linked through the "localhost" host name which
+	// the test network detector flags as private.
+	privURL, err := url.Parse(s.private.URL)
+	c.Assert(err, gc.IsNil)
+	privateLink := fmt.Sprintf("http://localhost:%s/secret", privURL.Port())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		serveHTML(w, "Home", `welcome home
+			<a href="/about">About</a>
+			<a href="/old">Moved</a>
+			<a href="/login" rel="nofollow">Login</a>
+			<a href="/logo.png">Logo</a>
+			<a href="/report.pdf">Report</a>
+			<a href="/missing">Missing</a>
+			<a href="`+privateLink+`">Intranet</a>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, _ *http.Request) {
+		serveHTML(w, "About", `about us <a href="/">Home</a>`)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, _ *http.Request) {
+		serveHTML(w, "New", "the page has moved here")
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, _ *http.Request) {
+		serveHTML(w, "Login", "please sign in")
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/report.pdf", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	})
+	s.site = httptest.NewServer(mux)
+
+	s.graph = memgraph.NewInMemoryGraph()
+	s.indexer, err = memindex.NewInMemoryBleveIndexer()
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *CrawlerE2ETestSuite) TearDownTest(c *gc.C) {
+	s.site.Close()
+	s.private.Close()
+	c.Assert(s.indexer.Close(), gc.IsNil)
+}
+
+func (s *CrawlerE2ETestSuite) TestCrawlSite(c *gc.C) {
+	c.Assert(s.graph.UpsertLink(&graph.Link{URL: s.site.URL + "/"}), gc.IsNil)
+	s.crawl(c, 1)
+
+	// The PNG link is dropped by the link extractor and the private link is
+	// never added to the graph.
+	c.Assert(s.linkURLs(c), gc.DeepEquals, s.siteURLs(
+		"/", "/about", "/login", "/missing", "/old", "/report.pdf",
+	))
+
+	// Links marked as nofollow are added to the graph but no edge is
+	// created for them.
+	c.Assert(s.edgeURLs(c), gc.DeepEquals, []string{
+		s.site.URL + "/ -> " + s.site.URL + "/about",
+		s.site.URL + "/ -> " + s.site.URL + "/missing",
+		s.site.URL + "/ -> " + s.site.URL + "/old",
+		s.site.URL + "/ -> " + s.site.URL + "/report.pdf",
+		s.site.URL + "/about -> " + s.site.URL + "/",
+	})
+
+	// Redirects are followed and the target content is indexed under the
+	// original link; non-HTML and missing resources are not indexed.
+	expDocs := map[string]string{
+		"/":           "Home",
+		"/about":      "About",
+		"/login":      "Login",
+		"/old":        "New",
+		"/missing":    "",
+		"/report.pdf": "",
+	}
+	linkIDs := s.linkIDs(c)
+	for path, expTitle := range expDocs {
+		doc, err := s.indexer.FindByID(linkIDs[s.site.URL+path])
+		if expTitle == "" {
+			c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true, gc.Commentf("path %q", path))
+			continue
+		}
+		c.Assert(err, gc.IsNil, gc.Commentf("path %q", path))
+		c.Assert(doc.URL, gc.Equals, s.site.URL+path)
+		c.Assert(doc.Title, gc.Equals, expTitle)
+		c.Assert(doc.HTTPStatus, gc.Equals, http.StatusOK)
+		c.Assert(doc.ContentHash, gc.Not(gc.Equals), "")
+	}
+
+	c.Assert(atomic.LoadInt32(&s.privateHits), gc.Equals, int32(0))
+}
+
+func (s *CrawlerE2ETestSuite) TestCrawlSitePartitions(c *gc.C) {
+	c.Assert(s.graph.UpsertLink(&graph.Link{URL: s.site.URL + "/"}), gc.IsNil)
+	s.crawl(c, 2)
+
+	c.Assert(s.linkURLs(c), gc.DeepEquals, s.siteURLs(
+		"/", "/about", "/login", "/missing", "/old", "/report.pdf",
+	))
+	c.Assert(s.edgeURLs(c), gc.HasLen, 5)
+
+	linkIDs := s.linkIDs(c)
+	for _, path := range []string{"/", "/about", "/login", "/old"} {
+		_, err := s.indexer.FindByID(linkIDs[s.site.URL+path])
+		c.Assert(err, gc.IsNil, gc.Commentf("path %q", path))
+	}
+	c.Assert(atomic.LoadInt32(&s.privateHits), gc.Equals, int32(0))
+}
+
+func (s *CrawlerE2ETestSuite) TestResumeInterruptedPass(c *gc.C) {
+	home := &graph.Link{URL: s.site.URL + "/"}
+	c.Assert(s.graph.UpsertLink(home), gc.IsNil)
+	about := &graph.Link{URL: s.site.URL + "/about"}
+	c.Assert(s.graph.UpsertLink(about), gc.IsNil)
+	first, second := home, about
+	if bytes.Compare(second.ID[:], first.ID[:]) < 0 {
+		first, second = second, first
+	}
+
+	// Simulate a crawler that was restarted after the first link of the
+	// pass was sent through the pipeline.
+	ranges := []crawler.LinkRange{{FromID: minUUID, ToID: maxUUID}}
+	c.Assert(s.graph.SaveCrawlPassProgress(&graph.PassProgress{
+		PassID:          7,
+		RetrievedBefore: time.Now(),
+		Partitions: []graph.PartitionProgress{
+			{FromID: minUUID, ToID: maxUUID, LastLinkID: first.ID, Crawled: 1},
+		},
+	}), gc.IsNil)
+
+	cr := crawler.NewCrawler(crawler.Config{
+		PrivateNetworkDetector: privateHosts{"localhost": true},
+		URLGetter:              &http.Client{Timeout: 5 * time.Second},
+		Graph:                  s.graph,
+		Indexer:                s.indexer,
+		FetchWorkers:           2,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	count, err := cr.CrawlRanges(ctx, ranges, time.Now())
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, 1)
+
+	link, err := s.graph.FindLink(second.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(link.CrawlPassID, gc.Equals, uint64(7))
+	_, err = s.indexer.FindByID(first.ID)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+
+	progress, err := s.graph.CrawlPassProgress()
+	c.Assert(err, gc.IsNil)
+	c.Assert(progress.PassID, gc.Equals, uint64(7))
+	c.Assert(progress.Done(), gc.Equals, true)
+	c.Assert(progress.Partitions[0].Crawled, gc.Equals, 2)
+
+	// Once the pass is complete, a new pass is started.
+	_, err = cr.CrawlRanges(ctx, ranges, time.Now())
+	c.Assert(err, gc.IsNil)
+	link, err = s.graph.FindLink(first.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(link.CrawlPassID > 7, gc.Equals, true)
+}
+
+func (s *CrawlerE2ETestSuite) TestExtraStages(c *gc.C) {
+	c.Assert(s.graph.UpsertLink(&graph.Link{URL: s.site.URL + "/"}), gc.IsNil)
+
+	// The custom stage sees the fields populated by the built-in stages
+	// and the pages it discards are not indexed.
+	var titles []string
+	enricher := pipeline.ProcessorFunc(func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+		payload := p.(*crawler.Payload)
+		titles = append(titles, payload.Title)
+		return nil, nil
+	})
+	cr := crawler.NewCrawler(crawler.Config{
+		PrivateNetworkDetector: privateHosts{"localhost": true},
+		URLGetter:              &http.Client{Timeout: 5 * time.Second},
+		Graph:                  s.graph,
+		Indexer:                s.indexer,
+		FetchWorkers:           1,
+		ExtraStages:            []pipeline.StageRunner{pipeline.FIFO(enricher)},
+	})
+
+	linkIt, err := s.graph.Links(minUUID, maxUUID, time.Now())
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(linkIt.Close(), gc.IsNil) }()
+	count, err := cr.Crawl(context.TODO(), linkIt)
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, 0)
+	c.Assert(titles, gc.DeepEquals, []string{"Home"})
+
+	_, err = s.indexer.FindByID(s.linkIDs(c)[s.site.URL+"/"])
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+}
+
+// crawl runs crawl passes over the links that have not been retrieved yet
+// until a pass no longer produces any output. The link ID space is split into
+// the specified number of partitions which are crawled concurrently with
+// work stealing enabled.
+func (s *CrawlerE2ETestSuite) crawl(c *gc.C, partitions int) {
+	cr := crawler.NewCrawler(crawler.Config{
+		PrivateNetworkDetector: privateHosts{"localhost": true},
+		URLGetter:              &http.Client{Timeout: 5 * time.Second},
+		Graph:                  s.graph,
+		Indexer:                s.indexer,
+		ConcurrentPartitions:   partitions,
+		StealBatchSize:         2,
+		FetchWorkers:           2,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Links crawled by the test get a retrieval time after crawlStart so
+	// they are not picked up again by subsequent passes.
+	crawlStart := time.Now()
+	for pass := 0; pass < maxCrawlPasses; pass++ {
+		var linkIts []graph.LinkIterator
+		for _, r := range partitionRanges(partitions) {
+			linkIt, err := s.graph.Links(r[0], r[1], crawlStart)
+			c.Assert(err, gc.IsNil)
+			linkIts = append(linkIts, linkIt)
+		}
+
+		count, err := cr.CrawlPartitions(ctx, linkIts)
+		c.Assert(err, gc.IsNil)
+		for _, linkIt := range linkIts {
+			c.Assert(linkIt.Close(), gc.IsNil)
+		}
+		if count == 0 {
+			return
+		}
+	}
+	c.Fatalf("crawl did not complete after %d passes", maxCrawlPasses)
+}
+
+// partitionRanges splits the link ID space into n ranges of roughly equal
+// size using the first byte of the IDs.
+func partitionRanges(n int) [][2]uuid.UUID {
+	ranges := make([][2]uuid.UUID, n)
+	for i := range ranges {
+		ranges[i][0] = minUUID
+		ranges[i][1] = maxUUID
+		if i > 0 {
+			ranges[i][0] = ranges[i-1][1]
+		}
+		if i < n-1 {
+			var to uuid.UUID
+			to[0] = byte((i + 1) * 256 / n)
+			ranges[i][1] = to
+		}
+	}
+	return ranges
+}
+
+func (s *CrawlerE2ETestSuite) siteURLs(paths ...string) []string {
+	urls := make([]string, len(paths))
+	for i, path := range paths {
+		urls[i] = s.site.URL + path
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func (s *CrawlerE2ETestSuite) linkIDs(c *gc.C) map[string]uuid.UUID {
+	linkIt, err := s.graph.Links(minUUID, maxUUID, time.Now().Add(time.Hour))
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(linkIt.Close(), gc.IsNil) }()
+
+	ids := make(map[string]uuid.UUID)
+	for linkIt.Next() {
+		link := linkIt.Link()
+		ids[link.URL] = link.ID
+	}
+	c.Assert(linkIt.Error(), gc.IsNil)
+	return ids
+}
+
+func (s *CrawlerE2ETestSuite) linkURLs(c *gc.C) []string {
+	var urls []string
+	for linkURL := range s.linkIDs(c) {
+		urls = append(urls, linkURL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func (s *CrawlerE2ETestSuite) edgeURLs(c *gc.C) []string {
+	urlByID := make(map[uuid.UUID]string)
+	for linkURL, id := range s.linkIDs(c) {
+		urlByID[id] = linkURL
+	}
+
+	edgeIt, err := s.graph.Edges(minUUID, maxUUID, time.Now().Add(time.Hour))
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(edgeIt.Close(), gc.IsNil) }()
+
+	var edges []string
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		edges = append(edges, urlByID[edge.Src]+" -> "+urlByID[edge.Dst])
+	}
+	c.Assert(edgeIt.Error(), gc.IsNil)
+	sort.Strings(edges)
+	return edges
+}
+
+// privateHosts is a crawler.PrivateNetworkDetector that flags a fixed set of
+// host names as private. It allows the test server, which listens on a
+// loopback address, to be crawled.
+type privateHosts map[string]bool
+
+func (h privateHosts) IsPrivate(host string) (bool, error) { return h[host], nil }
+
+func serveHTML(w http.ResponseWriter, title, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = fmt.Fprintf(w, "<html><head><title>%s</title></head><body>%s</body></html>", title, body)
+}
@@ -0,0 +1,4 @@
+// Package e2e contains end-to-end tests that run the crawler pipeline against
+// an in-process web server and verify the contents of the resulting link
+// graph and text index. The tests do not require network access.
+package e2e
@@ -0,0 +1,104 @@
+package feed
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(FeedTestSuite))
+
+type FeedTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+const (
+	rssDoc = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0"><channel>
+	<title>Gopher news</title>
+	<link>https://example.com/</link>
+	<item><title>One</title><link>https://example.com/posts/1</link></item>
+	<item><title>Two</title><link>/posts/2</link></item>
+</channel></rss>`
+
+	atomDoc = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Gopher news</title>
+	<entry>
+		<link rel="self" href="https://example.com/entries/1.atom"/>
+		<link href="https://example.com/entries/1"/>
+	</entry>
+	<entry><link rel="alternate" href="entries/2"/></entry>
+</feed>`
+)
+
+func (s *FeedTestSuite) TestParseRSS(c *gc.C) {
+	links, err := ParseItemLinks("https://example.com/feed.xml", strings.NewReader(rssDoc))
+	c.Assert(err, gc.IsNil)
+	c.Assert(links, gc.DeepEquals, []string{
+		"https://example.com/posts/1",
+		"https://example.com/posts/2",
+	})
+}
+
+func (s *FeedTestSuite) TestParseAtom(c *gc.C) {
+	links, err := ParseItemLinks("https://example.com/feed.atom", strings.NewReader(atomDoc))
+	c.Assert(err, gc.IsNil)
+	c.Assert(links, gc.DeepEquals, []string{
+		"https://example.com/entries/1",
+		"https://example.com/entries/2",
+	})
+}
+
+func (s *FeedTestSuite) TestParseUnsupported(c *gc.C) {
+	_, err := ParseItemLinks("https://example.com/", strings.NewReader("<html></html>"))
+	c.Assert(err, gc.ErrorMatches, ".*unsupported feed format.*")
+}
+
+func (s *FeedTestSuite) TestPoll(c *gc.C) {
+	g := memory.NewInMemoryGraph()
+	c.Assert(g.UpsertLink(&graph.Link{URL: "https://example.com/feed.xml", Feed: true}), gc.IsNil)
+	c.Assert(g.UpsertLink(&graph.Link{URL: "https://example.com/broken.xml", Feed: true}), gc.IsNil)
+	c.Assert(g.UpsertLink(&graph.Link{URL: "https://example.com/"}), gc.IsNil)
+
+	getter := stubGetter{
+		"https://example.com/feed.xml": rssDoc,
+	}
+	n, err := NewPoller(g, getter).Poll(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(n, gc.Equals, 2)
+
+	it, err := g.Links(minUUID, maxUUID, time.Now())
+	c.Assert(err, gc.IsNil)
+	var urls []string
+	for it.Next() {
+		urls = append(urls, it.Link().URL)
+	}
+	c.Assert(it.Close(), gc.IsNil)
+	sort.Strings(urls)
+	c.Assert(urls, gc.DeepEquals, []string{
+		"https://example.com/",
+		"https://example.com/broken.xml",
+		"https://example.com/feed.xml",
+		"https://example.com/posts/1",
+		"https://example.com/posts/2",
+	})
+}
+
+type stubGetter map[string]string
+
+func (g stubGetter) Get(url string) (*http.Response, error) {
+	body, found := g[url]
+	if !found {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+}
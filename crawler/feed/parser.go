@@ -0,0 +1,108 @@
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrUnsupportedFormat is returned by ParseItemLinks when the document is
+// neither an RSS nor an Atom feed.
+var ErrUnsupportedFormat = xerrors.New("unsupported feed format")
+
+type rssItem struct {
+	Link string `xml:"link"`
+}
+
+// rssFeed matches both RSS 2.0 documents (items nested inside the channel)
+// and RSS 1.0/RDF documents (items are siblings of the channel).
+type rssFeed struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+type atomFeed struct {
+	Entries []struct {
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// ParseItemLinks parses an RSS or Atom feed from r and returns the absolute
+// URLs of the items it contains. Relative item links are resolved against
+// feedURL.
+func ParseItemLinks(feedURL string, r io.Reader) ([]string, error) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, xerrors.Errorf("parse feed URL: %w", err)
+	}
+
+	dec := xml.NewDecoder(r)
+	// Feeds in the wild are frequently served with non UTF-8 charsets;
+	// we only care about URLs so the raw bytes are good enough.
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	root, err := rootElement(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawLinks []string
+	switch strings.ToLower(root.Name.Local) {
+	case "rss", "rdf":
+		var doc rssFeed
+		if err = dec.DecodeElement(&doc, &root); err != nil {
+			return nil, xerrors.Errorf("decode rss feed: %w", err)
+		}
+		for _, item := range append(doc.Channel.Items, doc.Items...) {
+			rawLinks = append(rawLinks, item.Link)
+		}
+	case "feed":
+		var doc atomFeed
+		if err = dec.DecodeElement(&doc, &root); err != nil {
+			return nil, xerrors.Errorf("decode atom feed: %w", err)
+		}
+		for _, entry := range doc.Entries {
+			for _, link := range entry.Links {
+				if link.Rel == "" || link.Rel == "alternate" {
+					rawLinks = append(rawLinks, link.Href)
+					break
+				}
+			}
+		}
+	default:
+		return nil, xerrors.Errorf("root element %q: %w", root.Name.Local, ErrUnsupportedFormat)
+	}
+
+	var links []string
+	for _, raw := range rawLinks {
+		ref, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || raw == "" {
+			continue
+		}
+		if link := base.ResolveReference(ref); link.Scheme == "http" || link.Scheme == "https" {
+			links = append(links, link.String())
+		}
+	}
+	return links, nil
+}
+
+// rootElement advances dec to the first start element of the document.
+func rootElement(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, xerrors.Errorf("locate feed root element: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	minUUID = uuid.Nil
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+// Graph is implemented by objects that can list the links in a link graph
+// and insert new ones.
+type Graph interface {
+	UpsertLink(link *graph.Link) error
+	Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+}
+
+// URLGetter is implemented by objects that can perform HTTP GET requests.
+type URLGetter interface {
+	Get(url string) (*http.Response, error)
+}
+
+// Poller periodically fetches the RSS/Atom feeds that the crawler has
+// discovered and inserts the links to their items into the link graph so
+// that new content gets picked up without waiting for a full crawl pass.
+type Poller struct {
+	graph     Graph
+	urlGetter URLGetter
+}
+
+// NewPoller returns a new Poller instance that discovers feeds and stores
+// item links via g and fetches feeds via urlGetter.
+func NewPoller(g Graph, urlGetter URLGetter) *Poller {
+	return &Poller{
+		graph:     g,
+		urlGetter: urlGetter,
+	}
+}
+
+// Run polls all known feeds every interval until ctx expires or an error
+// occurs.
+func (p *Poller) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		if _, err := p.Poll(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Poll performs a single pass over all the feeds in the graph and returns
+// the number of item links that were upserted. Feeds that cannot be
+// fetched or parsed are skipped; only graph errors are returned.
+func (p *Poller) Poll(ctx context.Context) (int, error) {
+	feeds, err := p.feedURLs()
+	if err != nil {
+		return 0, err
+	}
+
+	var ingested int
+	for _, feedURL := range feeds {
+		if ctx.Err() != nil {
+			break
+		}
+
+		links, err := p.fetchItemLinks(feedURL)
+		if err != nil {
+			continue
+		}
+
+		for _, link := range links {
+			if err := p.graph.UpsertLink(&graph.Link{URL: link}); err != nil {
+				return ingested, xerrors.Errorf("upsert feed item link: %w", err)
+			}
+			ingested++
+		}
+	}
+
+	return ingested, nil
+}
+
+// feedURLs returns the URLs of all links in the graph that have been
+// flagged as feeds.
+func (p *Poller) feedURLs() ([]string, error) {
+	it, err := p.graph.Links(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return nil, xerrors.Errorf("list feeds: %w", err)
+	}
+
+	var feeds []string
+	for it.Next() {
+		if link := it.Link(); link.Feed {
+			feeds = append(feeds, link.URL)
+		}
+	}
+	if err = it.Error(); err != nil {
+		_ = it.Close()
+		return nil, xerrors.Errorf("list feeds: %w", err)
+	}
+	if err = it.Close(); err != nil {
+		return nil, xerrors.Errorf("list feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+func (p *Poller) fetchItemLinks(feedURL string) ([]string, error) {
+	res, err := p.urlGetter.Get(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, xerrors.Errorf("fetch feed %q: unexpected status code %d", feedURL, res.StatusCode)
+	}
+	return ParseItemLinks(feedURL, res.Body)
+}
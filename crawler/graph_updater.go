@@ -28,42 +28,81 @@ type Graph interface {
 }
 
 func (u *graphUpdater) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	payload := p.(*Payload)
 
-	payload := p.(*crawlerPayload)
+	//if the graph supports transactions, apply all updates for the page
+	//atomically so a failure cannot leave a partial edge set behind
+	txGraph, ok := u.updater.(graph.Transactor)
+	if !ok {
+		if err := updateGraph(u.updater, payload); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+
+	tx, err := txGraph.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if err = updateGraph(tx, payload); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
 
+//updateGraph upserts the crawled link, the links it points to and the edges
+//between them
+func updateGraph(updater Graph, payload *Payload) error {
 	src := &graph.Link{
 		ID:          payload.LinkID,
 		URL:         payload.URL,
 		RetrievedAt: time.Now(),
+		CrawlPassID: payload.CrawlPassID,
+		ContentHash: payload.ContentHash,
 	}
 
-	if err := u.updater.UpsertLink(src); err != nil {
-		return nil, err
+	if err := updater.UpsertLink(src); err != nil {
+		return err
+	}
+
+	for _, feedLink := range payload.FeedLinks {
+		feed := &graph.Link{URL: feedLink, Feed: true}
+		if err := updater.UpsertLink(feed); err != nil {
+			return err
+		}
 	}
 
 	for _, dstLink := range payload.NoFollowLinks {
-		dst := &graph.Link{URL: dstLink}
-		if err := u.updater.UpsertLink(dst); err != nil {
-			return nil, err
+		dst := &graph.Link{URL: dstLink, Priority: payload.LinkPriorities[dstLink]}
+		if err := updater.UpsertLink(dst); err != nil {
+			return err
 		}
 	}
 
+	//edges that are not refreshed by the upserts below point to links that
+	//are no longer present in the page
 	removeEdgesOlderThan := time.Now()
 	for _, dstLink := range payload.Links {
-		dst := &graph.Link{URL: dstLink}
+		dst := &graph.Link{URL: dstLink, Priority: payload.LinkPriorities[dstLink]}
 
-		if err := u.updater.UpsertLink(dst); err != nil {
-			return nil, err
+		if err := updater.UpsertLink(dst); err != nil {
+			return err
 		}
 
-		if err := u.updater.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}); err != nil {
-			return nil, err
+		if err := updater.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID, CrawlPassID: payload.CrawlPassID}); err != nil {
+			return err
 		}
+	}
 
-		if err := u.updater.RemoveStaleEdges(src.ID, removeEdgesOlderThan); err != nil {
-			return nil, err
-		}
+	//prune stale edges once all edges have been upserted; this also clears
+	//the edges of pages that no longer contain any links
+	if err := updater.RemoveStaleEdges(src.ID, removeEdgesOlderThan); err != nil {
+		return err
 	}
 
-	return p, nil
+	return nil
 }
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/crawler/mocks"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(GraphUpdaterTestSuite))
+
+type GraphUpdaterTestSuite struct{}
+
+func (s *GraphUpdaterTestSuite) TestGraphUpdater(c *gc.C) {
+	g := mocks.NewFakeGraph()
+	src := &graph.Link{URL: "http://example.com/"}
+	c.Assert(g.UpsertLink(src), gc.IsNil)
+
+	p := &Payload{
+		LinkID:        src.ID,
+		URL:           src.URL,
+		Links:         []string{"http://example.com/a", "http://example.com/b"},
+		NoFollowLinks: []string{"http://example.com/login"},
+		FeedLinks:     []string{"http://example.com/feed.xml"},
+	}
+	_, err := newGraphUpdater(g).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(g.LinkURLs(), gc.DeepEquals, []string{
+		"http://example.com/",
+		"http://example.com/a",
+		"http://example.com/b",
+		"http://example.com/feed.xml",
+		"http://example.com/login",
+	})
+	c.Assert(g.EdgeURLs(), gc.DeepEquals, []string{
+		"http://example.com/ -> http://example.com/a",
+		"http://example.com/ -> http://example.com/b",
+	})
+	c.Assert(g.HasEdge("http://example.com/", "http://example.com/login"), gc.Equals, false)
+
+	feed, found := g.Link("http://example.com/feed.xml")
+	c.Assert(found, gc.Equals, true)
+	c.Assert(feed.Feed, gc.Equals, true)
+	c.Assert(g.StaleEdgesCalls(), gc.HasLen, 1)
+}
+
+func (s *GraphUpdaterTestSuite) TestGraphUpdaterRemovesStaleEdges(c *gc.C) {
+	g := mocks.NewFakeGraph()
+	src := &graph.Link{URL: "http://example.com/"}
+	c.Assert(g.UpsertLink(src), gc.IsNil)
+
+	p := &Payload{
+		LinkID: src.ID,
+		URL:    src.URL,
+		Links:  []string{"http://example.com/a", "http://example.com/b"},
+	}
+	_, err := newGraphUpdater(g).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	time.Sleep(time.Millisecond)
+
+	// The link to /b has been removed from the page.
+	p.Links = []string{"http://example.com/a"}
+	_, err = newGraphUpdater(g).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(g.EdgeURLs(), gc.DeepEquals, []string{
+		"http://example.com/ -> http://example.com/a",
+	})
+	time.Sleep(time.Millisecond)
+
+	// All links have been removed from the page.
+	p.Links = nil
+	_, err = newGraphUpdater(g).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(g.EdgeURLs(), gc.HasLen, 0)
+}
+
+func (s *GraphUpdaterTestSuite) TestGraphUpdaterRecordsCrawlPass(c *gc.C) {
+	g := memory.NewInMemoryGraph()
+	passID, err := g.NextCrawlPass()
+	c.Assert(err, gc.IsNil)
+	src := &graph.Link{URL: "http://example.com/"}
+	c.Assert(g.UpsertLink(src), gc.IsNil)
+
+	p := &Payload{
+		LinkID:      src.ID,
+		URL:         src.URL,
+		CrawlPassID: passID,
+		Links:       []string{"http://example.com/a"},
+	}
+	_, err = newGraphUpdater(g).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+
+	// Only the crawled link is tagged; discovered links are retrieved by a
+	// later pass.
+	linkIt, err := g.LinksInCrawlPass(passID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(linkIt.Next(), gc.Equals, true)
+	c.Assert(linkIt.Link().ID, gc.Equals, src.ID)
+	c.Assert(linkIt.Next(), gc.Equals, false)
+
+	edgeIt, err := g.EdgesInCrawlPass(passID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(edgeIt.Next(), gc.Equals, true)
+	c.Assert(edgeIt.Edge().Src, gc.Equals, src.ID)
+	c.Assert(edgeIt.Next(), gc.Equals, false)
+}
+
+func (s *GraphUpdaterTestSuite) TestNextCrawlPass(c *gc.C) {
+	// Pass IDs are allocated by graphs that track crawl passes.
+	g := memory.NewInMemoryGraph()
+	crawler := new(Crawler)
+	for exp := uint64(1); exp <= 2; exp++ {
+		passID, err := crawler.nextCrawlPass(g)
+		c.Assert(err, gc.IsNil)
+		c.Assert(passID, gc.Equals, exp)
+	}
+
+	// Otherwise they are derived from the clock.
+	crawler = new(Crawler)
+	fakeGraph := mocks.NewFakeGraph()
+	var last uint64
+	for i := 0; i < 100; i++ {
+		passID, err := crawler.nextCrawlPass(fakeGraph)
+		c.Assert(err, gc.IsNil)
+		c.Assert(passID > last, gc.Equals, true)
+		last = passID
+	}
+}
+
+func (s *GraphUpdaterTestSuite) TestGraphUpdaterError(c *gc.C) {
+	g := mocks.NewFakeGraph()
+	g.Err = xerrors.New("graph unavailable")
+
+	_, err := newGraphUpdater(g).Process(context.TODO(), &Payload{URL: "http://example.com/"})
+	c.Assert(err, gc.ErrorMatches, "graph unavailable")
+}
@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+)
+
+// hookStages wraps each of the hook processors in a FIFO stage so they run in
+// the order they were specified.
+func hookStages(hooks []pipeline.Processor) []pipeline.StageRunner {
+	stages := make([]pipeline.StageRunner, len(hooks))
+	for i, hook := range hooks {
+		stages[i] = pipeline.FIFO(hook)
+	}
+	return stages
+}
+
+// indexHooks runs the PreIndex hooks in front of the text indexer. A hook that
+// discards a payload only keeps it out of the text index; the payload is still
+// emitted so that the counts reported by the crawler are not affected.
+type indexHooks struct {
+	hooks   []pipeline.Processor
+	indexer pipeline.Processor
+}
+
+// Process implements pipeline.Processor.
+func (h *indexHooks) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	for _, hook := range h.hooks {
+		out, err := hook.Process(ctx, p)
+		if err != nil {
+			return nil, err
+		} else if out == nil {
+			return p, nil
+		}
+		p = out
+	}
+	return h.indexer.Process(ctx, p)
+}
@@ -0,0 +1,84 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(HooksTestSuite))
+
+type HooksTestSuite struct{}
+
+func (s *HooksTestSuite) TestIndexHooks(c *gc.C) {
+	var indexed []string
+	indexer := pipeline.ProcessorFunc(func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+		indexed = append(indexed, p.(*Payload).URL)
+		return p, nil
+	})
+	hooks := &indexHooks{
+		hooks: []pipeline.Processor{
+			pipeline.ProcessorFunc(func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+				p.(*Payload).Title = "enriched"
+				return p, nil
+			}),
+			pipeline.ProcessorFunc(func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+				switch p.(*Payload).URL {
+				case "http://example.com/skip":
+					return nil, nil
+				case "http://example.com/fail":
+					return nil, xerrors.New("boom")
+				}
+				return p, nil
+			}),
+		},
+		indexer: indexer,
+	}
+
+	p := &Payload{URL: "http://example.com/"}
+	out, err := hooks.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Equals, p)
+	c.Assert(p.Title, gc.Equals, "enriched")
+
+	// Payloads discarded by a hook are not indexed but are still emitted
+	p = &Payload{URL: "http://example.com/skip"}
+	out, err = hooks.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.Equals, p)
+
+	_, err = hooks.Process(context.TODO(), &Payload{URL: "http://example.com/fail"})
+	c.Assert(err, gc.ErrorMatches, "boom")
+
+	c.Assert(indexed, gc.DeepEquals, []string{"http://example.com/"})
+}
+
+func (s *HooksTestSuite) TestHookPlacement(c *gc.C) {
+	hook := pipeline.ProcessorFunc(func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+		return p, nil
+	})
+	d := assembleCrawlerPipeline(Config{
+		FetchWorkers: 1,
+		PreFetch:     []pipeline.Processor{hook},
+		PostExtract:  []pipeline.Processor{hook, hook},
+		PreIndex:     []pipeline.Processor{hook},
+	}, newHostLimiter(1), newAdaptiveDelay(0, 0, 0)).Describe()
+
+	var processors []string
+	for _, stage := range d.Stages {
+		processors = append(processors, stage.Processors...)
+	}
+	c.Assert(processors, gc.DeepEquals, []string{
+		"pipeline.ProcessorFunc",
+		"*crawler.linkFetcher",
+		"*crawler.linkExtractor",
+		"*crawler.structuredDataExtractor",
+		"*crawler.textExtractor",
+		"pipeline.ProcessorFunc",
+		"pipeline.ProcessorFunc",
+		"*crawler.graphUpdater",
+		"*crawler.indexHooks",
+	})
+}
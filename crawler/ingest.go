@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/brandonshearin/ask_brandon/crawler/warc"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+)
+
+// warcSource emits a payload for each HTML page stored in a WARC file.
+// Payloads are populated as if the page had been fetched by the link fetcher.
+type warcSource struct {
+	r      *warc.Reader
+	graph  Graph
+	passID uint64
+	traps  *spiderTrapDetector
+
+	payload *Payload
+	err     error
+}
+
+// Next skips over records that do not contain a successful response with an
+// HTML document; these would be discarded by the link fetcher in a live crawl.
+func (s *warcSource) Next(ctx context.Context) bool {
+	for ctx.Err() == nil {
+		rec, err := s.r.Next()
+		if err == io.EOF {
+			return false
+		} else if err != nil {
+			s.err = err
+			return false
+		}
+
+		if rec.Type != warc.TypeResponse || !strings.HasPrefix(rec.TargetURI, "http") {
+			continue
+		}
+		if payload, ok := s.payloadFromRecord(rec); ok {
+			// Resolve the link ID so that the indexed documents match
+			// the links in the graph
+			link := &graph.Link{URL: payload.URL}
+			if err = s.graph.UpsertLink(link); err != nil {
+				s.err = xerrors.Errorf("upsert link %q: %w", payload.URL, err)
+				payload.MarkAsProcessed()
+				return false
+			}
+			payload.LinkID = link.ID
+			s.payload = payload
+			return true
+		}
+	}
+	return false
+}
+
+func (s *warcSource) Payload() pipeline.Payload { return s.payload }
+
+func (s *warcSource) Error() error { return s.err }
+
+func (s *warcSource) payloadFromRecord(rec *warc.Record) (*Payload, bool) {
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Block)), nil)
+	if err != nil || res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, false
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	contentType := res.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "html") {
+		return nil, false
+	}
+
+	// Archives such as Common Crawl store bodies exactly as they were
+	// received from the server
+	body := io.Reader(res.Body)
+	if strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(res.Body)
+		if err != nil {
+			return nil, false
+		}
+		body = zr
+	}
+
+	p := payloadPool.Get().(*Payload)
+	hasher := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(&p.RawContent, hasher), body); err != nil {
+		p.MarkAsProcessed()
+		return nil, false
+	}
+
+	p.URL = rec.TargetURI
+	p.FinalURL = rec.TargetURI
+	p.CrawlPassID = s.passID
+	p.spiderTraps = s.traps
+	p.FetchedAt = rec.Date
+	p.RetrievedAt = rec.Date
+	p.HTTPStatus = res.StatusCode
+	p.ContentType = contentType
+	p.Header = res.Header
+	p.ContentHash = hex.EncodeToString(hasher.Sum(nil))
+	return p, true
+}
+
+// Ingest reads the pages stored in a WARC file, such as a Common Crawl
+// segment, and sends them through the crawler pipeline as if they had been
+// fetched live. This populates the link graph and the index without any
+// network traffic. It returns the number of pages that went through the
+// pipeline. Like Crawl, each call to Ingest is assigned a new crawl pass ID.
+func (c *Crawler) Ingest(ctx context.Context, r *warc.Reader) (int, error) {
+	s := c.currentSettings()
+	passID, err := c.nextCrawlPass(s.graph)
+	if err != nil {
+		return 0, err
+	}
+
+	sink := new(countingSink)
+	src := &warcSource{r: r, graph: s.graph, passID: passID, traps: newSpiderTrapDetector(s.trapLimits)}
+	err = s.ingestP.Process(ctx, src, sink)
+	s.reportSpiderTraps(passID, src.traps)
+	return sink.getCount(), err
+}
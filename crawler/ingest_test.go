@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/crawler/warc"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(WARCSourceTestSuite))
+
+type WARCSourceTestSuite struct{}
+
+func (s *WARCSourceTestSuite) TestEmitHTMLResponses(c *gc.C) {
+	var gzBody bytes.Buffer
+	zw := gzip.NewWriter(&gzBody)
+	_, _ = zw.Write([]byte("<html>compressed</html>"))
+	c.Assert(zw.Close(), gc.IsNil)
+
+	var buf bytes.Buffer
+	w := warc.NewWriter(&buf, true)
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []*warc.Record{
+		{Type: warc.TypeWarcinfo, Block: []byte("software: test\r\n")},
+		{Type: warc.TypeRequest, TargetURI: "http://example.com/", Block: []byte("GET / HTTP/1.1\r\n\r\n")},
+		response("http://example.com/", date, "200 OK", "Content-Type: text/html\r\n", []byte("<html>hello</html>")),
+		response("http://example.com/logo.png", date, "200 OK", "Content-Type: image/png\r\n", []byte("PNG")),
+		response("http://example.com/missing", date, "404 Not Found", "Content-Type: text/html\r\n", nil),
+		response("http://example.com/gz", date, "200 OK", "Content-Type: text/html\r\nContent-Encoding: gzip\r\n", gzBody.Bytes()),
+		response("dns:example.com", date, "200 OK", "", nil),
+	}
+	for _, rec := range records {
+		_, err := w.WriteRecord(rec)
+		c.Assert(err, gc.IsNil)
+	}
+
+	r, err := warc.NewReader(&buf)
+	c.Assert(err, gc.IsNil)
+	g := make(fakeLinkGraph)
+	src := &warcSource{r: r, graph: g, passID: 1}
+
+	var got []*Payload
+	for src.Next(context.TODO()) {
+		got = append(got, src.Payload().(*Payload))
+	}
+	c.Assert(src.Error(), gc.IsNil)
+	c.Assert(got, gc.HasLen, 2)
+
+	c.Assert(got[0].URL, gc.Equals, "http://example.com/")
+	c.Assert(got[0].LinkID, gc.Equals, g["http://example.com/"])
+	c.Assert(got[0].CrawlPassID, gc.Equals, uint64(1))
+	c.Assert(got[0].FetchedAt.Equal(date), gc.Equals, true)
+	c.Assert(got[0].HTTPStatus, gc.Equals, 200)
+	c.Assert(got[0].ContentType, gc.Equals, "text/html")
+	c.Assert(got[0].ContentHash, gc.HasLen, 64)
+	c.Assert(got[0].RawContent.String(), gc.Equals, "<html>hello</html>")
+
+	c.Assert(got[1].URL, gc.Equals, "http://example.com/gz")
+	c.Assert(got[1].RawContent.String(), gc.Equals, "<html>compressed</html>")
+}
+
+func response(uri string, date time.Time, status, header string, body []byte) *warc.Record {
+	block := append([]byte("HTTP/1.1 "+status+"\r\n"+header+"\r\n"), body...)
+	return &warc.Record{
+		Type:        warc.TypeResponse,
+		TargetURI:   uri,
+		Date:        date,
+		ContentType: warc.ContentTypeHTTPResponse,
+		Block:       block,
+	}
+}
+
+// fakeLinkGraph assigns IDs to upserted links by URL.
+type fakeLinkGraph map[string]uuid.UUID
+
+func (g fakeLinkGraph) UpsertLink(link *graph.Link) error {
+	id, found := g[link.URL]
+	if !found {
+		id = uuid.New()
+		g[link.URL] = id
+	}
+	link.ID = id
+	return nil
+}
+
+func (g fakeLinkGraph) UpsertEdge(*graph.Edge) error                { return nil }
+func (g fakeLinkGraph) RemoveStaleEdges(uuid.UUID, time.Time) error { return nil }
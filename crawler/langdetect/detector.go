@@ -0,0 +1,143 @@
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+var (
+	// defaultStopwords contains a short list of very frequent words for each
+	// supported Latin-script language, keyed by ISO 639-1 code.
+	defaultStopwords = map[string][]string{
+		"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "this", "are", "was", "on", "be"},
+		"es": {"el", "la", "de", "que", "y", "en", "los", "las", "del", "se", "por", "un", "una", "con", "para"},
+		"fr": {"le", "la", "les", "de", "et", "des", "est", "un", "une", "du", "en", "que", "pour", "dans", "pas"},
+		"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "von", "sich", "auf", "für"},
+		"it": {"il", "di", "che", "e", "la", "per", "un", "una", "non", "sono", "della", "gli", "del", "con", "le"},
+		"pt": {"o", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "os", "no", "na"},
+		"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "voor", "met", "ook", "maar"},
+	}
+
+	// scriptLanguages maps non-Latin scripts to the language that is most
+	// likely to use them.
+	scriptLanguages = []struct {
+		lang  string
+		table *unicode.RangeTable
+	}{
+		{"ru", unicode.Cyrillic},
+		{"el", unicode.Greek},
+		{"ar", unicode.Arabic},
+		{"he", unicode.Hebrew},
+		{"ja", unicode.Hiragana},
+		{"ja", unicode.Katakana},
+		{"ko", unicode.Hangul},
+		{"zh", unicode.Han},
+		{"hi", unicode.Devanagari},
+		{"th", unicode.Thai},
+	}
+)
+
+// Detector guesses the language of a block of text using a combination of
+// script detection (for non-Latin alphabets) and stop-word frequencies (for
+// Latin-based languages).
+type Detector struct {
+	stopwords map[string]map[string]struct{}
+
+	// The minimum number of stop-word hits required before the detector
+	// commits to a Latin-script language.
+	minHits int
+}
+
+// NewDetector returns a new Detector instance which recognizes English,
+// Spanish, French, German, Italian, Portuguese and Dutch text as well as
+// text written in a number of non-Latin scripts.
+func NewDetector() *Detector {
+	stopwords := make(map[string]map[string]struct{}, len(defaultStopwords))
+	for lang, words := range defaultStopwords {
+		set := make(map[string]struct{}, len(words))
+		for _, w := range words {
+			set[w] = struct{}{}
+		}
+		stopwords[lang] = set
+	}
+
+	return &Detector{stopwords: stopwords, minHits: 3}
+}
+
+// Detect returns the ISO 639-1 code for the language of text or an empty
+// string if the language cannot be determined.
+func (d *Detector) Detect(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isWordSeparator) {
+		for lang, set := range d.stopwords {
+			if _, found := set[word]; found {
+				hits[lang]++
+			}
+		}
+	}
+
+	var best string
+	var bestHits int
+	for lang, count := range hits {
+		// Break ties using the language code so results are deterministic.
+		if count > bestHits || (count == bestHits && lang < best) {
+			best, bestHits = lang, count
+		}
+	}
+
+	if bestHits < d.minHits {
+		return ""
+	}
+	return best
+}
+
+// detectScript returns the language associated with the dominant non-Latin
+// script in text or an empty string if most letters are Latin.
+func detectScript(text string) string {
+	var latin int
+	counts := make([]int, len(scriptLanguages))
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		} else if unicode.Is(unicode.Latin, r) {
+			latin++
+			continue
+		}
+
+		for i, script := range scriptLanguages {
+			if unicode.Is(script.table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	// Japanese text mixes kana with Han characters; any amount of kana
+	// is a strong signal that the text is not Chinese.
+	var kana int
+	for i, script := range scriptLanguages {
+		if script.lang == "ja" {
+			kana += counts[i]
+		}
+	}
+
+	var best, bestCount = "", latin
+	for i, count := range counts {
+		lang := scriptLanguages[i].lang
+		if lang == "zh" && kana > 0 {
+			lang = "ja"
+		}
+		if count > bestCount {
+			best, bestCount = lang, count
+		}
+	}
+	return best
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && r != '\''
+}
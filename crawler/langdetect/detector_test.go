@@ -0,0 +1,58 @@
+package langdetect
+
+import (
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(DetectorTestSuite))
+
+type DetectorTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *DetectorTestSuite) TestDetect(c *gc.C) {
+	specs := []struct {
+		descr string
+		input string
+		exp   string
+	}{
+		{
+			descr: "english",
+			input: "The quick brown fox jumps over the lazy dog and this is the end of it.",
+			exp:   "en",
+		},
+		{
+			descr: "spanish",
+			input: "El perro de la casa es muy grande y los niños juegan con el para siempre.",
+			exp:   "es",
+		},
+		{
+			descr: "german",
+			input: "Der Hund ist nicht groß, aber die Katze und das Pferd sind mit ihm auf dem Hof.",
+			exp:   "de",
+		},
+		{
+			descr: "russian",
+			input: "Быстрая коричневая лиса прыгает через ленивую собаку.",
+			exp:   "ru",
+		},
+		{
+			descr: "japanese",
+			input: "日本語のテキストです。",
+			exp:   "ja",
+		},
+		{
+			descr: "not enough signal",
+			input: "foo bar baz",
+			exp:   "",
+		},
+	}
+
+	det := NewDetector()
+	for specIndex, spec := range specs {
+		c.Logf("[spec %d] %s", specIndex, spec.descr)
+		c.Assert(det.Detect(spec.input), gc.Equals, spec.exp)
+	}
+}
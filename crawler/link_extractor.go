@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/url"
 	"regexp"
+	"strings"
 
+	"github.com/brandonshearin/ask_brandon/crawler/rules"
 	"github.com/brandonshearin/ask_brandon/pipeline"
 )
 
@@ -14,11 +16,18 @@ var (
 	- locate the <base href="XXX"> tag and capture the value
 	- extract links from the HTML contents
 	- identify links that should not be considered when calculating pagerank score
+	- locate <link rel="alternate"> tags that advertise RSS/Atom feeds
 	*/
 	exclusionRegex = regexp.MustCompile(`(?i)\.(?:jpg|jpeg|png|gif|ico|css|js)$`)
 	baseHrefRegex  = regexp.MustCompile(`(?i)<base.*?href\s*?=\s*?"(.*?)\s*?"`)
 	findLinkRegex  = regexp.MustCompile(`(?i)<a.*?href\s*?=\s*?"\s*?(.*?)\s*?".*?>`)
 	nofollowRegex  = regexp.MustCompile(`(?i)rel\s*?=\s*?"?nofollow"?`)
+	linkTagRegex   = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+
+	feedContentTypes = map[string]bool{
+		"application/rss+xml":  true,
+		"application/atom+xml": true,
+	}
 )
 
 func resolveURL(relTo *url.URL, target string) *url.URL {
@@ -39,6 +48,9 @@ func resolveURL(relTo *url.URL, target string) *url.URL {
 
 type linkExtractor struct {
 	netDetector PrivateNetworkDetector
+
+	//rules, if set, are applied to each extracted link
+	rules *rules.Engine
 }
 
 func newLinkExtractor(netDetector PrivateNetworkDetector) *linkExtractor {
@@ -49,7 +61,7 @@ func newLinkExtractor(netDetector PrivateNetworkDetector) *linkExtractor {
 
 //Process encapsulates the business logic of the link extractor
 func (le *linkExtractor) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
-	payload := p.(*crawlerPayload)
+	payload := p.(*Payload)
 	//in order to qualify any relative link we encounter,
 	//we need a fully qualified link to use as a base
 	relTo, err := url.Parse(payload.URL)
@@ -73,12 +85,33 @@ func (le *linkExtractor) Process(ctx context.Context, p pipeline.Payload) (pipel
 		}
 
 		link.Fragment = ""
-		linkStr := link.String()
+		linkStr, priority := link.String(), 0
+		if le.rules != nil {
+			res := le.rules.Apply(link)
+			if res.Denied {
+				continue
+			}
+			linkStr, priority = res.URL, res.Priority
+		}
 		if _, seen := seenMap[linkStr]; seen || exclusionRegex.MatchString(linkStr) {
 			continue //skip already seen links and links that do not contain HTML
 		}
+		if linkStr != link.String() {
+			if link, err = url.Parse(linkStr); err != nil {
+				continue
+			}
+		}
+		if !payload.spiderTraps.allow(link) {
+			continue //skip links that look like they belong to a spider trap
+		}
 
 		seenMap[linkStr] = struct{}{}
+		if priority != 0 {
+			if payload.LinkPriorities == nil {
+				payload.LinkPriorities = make(map[string]int)
+			}
+			payload.LinkPriorities[linkStr] = priority
+		}
 		if nofollowRegex.MatchString(match[0]) {
 			payload.NoFollowLinks = append(payload.NoFollowLinks, linkStr)
 		} else {
@@ -86,9 +119,31 @@ func (le *linkExtractor) Process(ctx context.Context, p pipeline.Payload) (pipel
 		}
 	}
 
+	payload.FeedLinks = extractFeedLinks(relTo, content)
 	return payload, nil
 }
 
+//extractFeedLinks returns the absolute URLs of the RSS/Atom feeds that are
+//advertised via <link rel="alternate"> tags in content
+func extractFeedLinks(relTo *url.URL, content string) []string {
+	var feeds []string
+	for _, tag := range linkTagRegex.FindAllString(content, -1) {
+		attrs := make(map[string]string)
+		for _, attr := range htmlAttrRegex.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(attr[1])] = strings.TrimSpace(attr[2] + attr[3])
+		}
+
+		if !strings.Contains(strings.ToLower(attrs["rel"]), "alternate") || !feedContentTypes[strings.ToLower(attrs["type"])] {
+			continue
+		}
+
+		if feed := resolveURL(relTo, attrs["href"]); feed != nil && (feed.Scheme == "http" || feed.Scheme == "https") {
+			feeds = append(feeds, feed.String())
+		}
+	}
+	return feeds
+}
+
 func ensureHasTrailingSlash(s string) string {
 	if s[len(s)-1] != '/' {
 		return s + "/"
@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/crawler/rules"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(LinkExtractorTestSuite))
+
+type LinkExtractorTestSuite struct{}
+
+func (s *LinkExtractorTestSuite) TestCrawlRules(c *gc.C) {
+	engine, err := rules.New([]rules.Rule{
+		{URL: "*", StripQuery: []string{"utm_*"}},
+		{URL: "*/tag/*", Priority: -10},
+		{URL: "*/admin/*", Deny: true},
+	})
+	c.Assert(err, gc.IsNil)
+
+	le := newLinkExtractor(nil)
+	le.rules = engine
+
+	p := &Payload{URL: "http://example.com/"}
+	_, err = p.RawContent.WriteString(`<html><body>
+<a href="/post?utm_source=feed">post</a>
+<a href="/post?utm_medium=email">same post</a>
+<a href="/tag/go" rel="nofollow">go</a>
+<a href="/admin/users">admin</a>
+</body></html>`)
+	c.Assert(err, gc.IsNil)
+
+	_, err = le.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p.Links, gc.DeepEquals, []string{"http://example.com/post"})
+	c.Assert(p.NoFollowLinks, gc.DeepEquals, []string{"http://example.com/tag/go"})
+	c.Assert(p.LinkPriorities, gc.DeepEquals, map[string]int{"http://example.com/tag/go": -10})
+}
@@ -2,24 +2,73 @@ package crawler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
 	"github.com/brandonshearin/ask_brandon/pipeline"
 )
 
 type linkFetcher struct {
 	urlGetter   URLGetter
 	netDetector PrivateNetworkDetector
+
+	//renderer, if set, is used instead of urlGetter for links whose host
+	//matches one of the renderDomains
+	renderer      RenderingURLGetter
+	renderDomains []string
+
+	//politeness, if set, spaces out requests to the same host
+	politeness *adaptiveDelay
+
+	//hostLimiter, if set, caps the number of concurrent connections per host
+	hostLimiter *hostLimiter
+
+	//linkParker, if set, is used to persist the retry time for links whose
+	//server responds with a 429 or 503 status and a Retry-After header
+	linkParker Graph
+
+	//headPrecheck enables issuing a HEAD request before downloading a link
+	//so that non-HTML resources and resources larger than maxContentLength
+	//can be skipped without fetching their body.  It requires the URL
+	//getter to implement HeadURLGetter
+	headPrecheck     bool
+	maxContentLength int64
+
+	//response bodies larger than spoolThreshold bytes are spooled to
+	//temporary files in spoolDir instead of being kept in memory
+	spoolThreshold int
+	spoolDir       string
 }
 
+//maxRetryAfter caps the time a link can be parked for so misbehaving servers
+//cannot keep a link out of the crawl indefinitely
+const maxRetryAfter = 24 * time.Hour
+
 //URLGetter is implmented by objects that can perform HTTP GET requests
 type URLGetter interface {
 	Get(url string) (*http.Response, error)
 }
 
+//HeadURLGetter is implemented by URL getters that can also perform HTTP HEAD
+//requests (e.g. http.Client)
+type HeadURLGetter interface {
+	Head(url string) (*http.Response, error)
+}
+
+//RenderingURLGetter is implemented by objects that can fetch a URL through a
+//headless browser so that the returned body contains the page contents after
+//any client-side JavaScript has been executed
+type RenderingURLGetter interface {
+	GetRendered(url string) (*http.Response, error)
+}
+
 //PrivateNetworkDetector is implemented by objects that can detect whether a host
 //resolves to a private network address
 type PrivateNetworkDetector interface {
@@ -38,7 +87,7 @@ func (lf *linkFetcher) Process(
 	p pipeline.Payload,
 ) (pipeline.Payload, error) {
 
-	payload := p.(*crawlerPayload)
+	payload := p.(*Payload)
 
 	//check the URL against a case-insensitive regex designed to
 	//match file extensions that are known to contain binary data
@@ -47,24 +96,73 @@ func (lf *linkFetcher) Process(
 		return nil, nil
 	}
 
+	//only web pages are crawled; the graph may also store links with other
+	//schemes, e.g. the vertices of a host graph (see the hostgraph package)
+	u, err := url.Parse(payload.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, nil
+	}
+	host := strings.ToLower(u.Hostname())
+
 	//second pre-check: ensures crawler ignores URLs that resolve to private network addresses
 	if isPrivate, err := lf.isPrivate(payload.URL); err != nil || isPrivate {
 		return nil, nil //don't crawl links in private networks
 	}
 
-	res, err := lf.urlGetter.Get(payload.URL)
+	//the connection slot must be held until the response body has been
+	//read and closed
+	if lf.hostLimiter != nil {
+		if err = lf.hostLimiter.Acquire(ctx, host); err != nil {
+			return nil, nil
+		}
+		defer lf.hostLimiter.Release(host)
+	}
+
+	if lf.headPrecheck && lf.skipAfterHead(ctx, host, payload.URL) {
+		return nil, nil
+	}
+
+	res, err := lf.politeFetch(ctx, host, payload.URL)
 	if err != nil {
+		payload.passStats.recordError()
 		return nil, nil
 	}
 
-	//for GET requests that complete w/o error, copy the response
-	//body into the payload's raw content field, then close
-	//body to avoid memory leaks
-	_, err = io.Copy(&payload.RawContent, res.Body)
+	//for GET requests that complete w/o error, stream the response
+	//body into the payload's raw content field while hashing it, then
+	//close body to avoid memory leaks
+	payload.RawContent.spoolThreshold = lf.spoolThreshold
+	payload.RawContent.spoolDir = lf.spoolDir
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(&payload.RawContent, hasher), res.Body)
 	_ = res.Body.Close()
 	if err != nil {
+		payload.passStats.recordError()
 		return nil, err
 	}
+	payload.passStats.recordResponse(res.StatusCode)
+
+	//record provenance information so it can be attached to the indexed document
+	payload.FetchedAt = time.Now()
+	payload.HTTPStatus = res.StatusCode
+	payload.ContentType = res.Header.Get("Content-Type")
+	payload.Header = res.Header
+	payload.FinalURL = payload.URL
+	if res.Request != nil && res.Request.URL != nil {
+		//the request attached to the response is the last one in the
+		//redirect chain
+		payload.FinalURL = res.Request.URL.String()
+	}
+	payload.ContentHash = hex.EncodeToString(hasher.Sum(nil))
+
+	//servers that are rate-limiting us may tell us when to come back; park the
+	//link until then instead of simply dropping it
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		if err = lf.parkLink(payload, res.Header.Get("Retry-After")); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
 
 	//Sanity check #1- if status code not in 2xx range, discard the payload
 	//rather than returning an error, as the latter would cause the pipeline to
@@ -75,10 +173,129 @@ func (lf *linkFetcher) Process(
 
 	//Sanity check #2- content type header should indicate an html document, otherwise
 	//there is no point in further processing
-	if contentType := res.Header.Get("Content-Type"); !strings.Contains(contentType, "html") {
+	if !strings.Contains(payload.ContentType, "html") {
 		return nil, nil
 	}
-	return nil, nil
+	return payload, nil
+}
+
+//parkLink records the time indicated by a Retry-After header value on the link
+//so that subsequent crawl passes skip it until then
+func (lf *linkFetcher) parkLink(payload *Payload, retryAfter string) error {
+	now := time.Now()
+	retryAt, ok := parseRetryAfter(retryAfter, now)
+	if !ok || lf.linkParker == nil {
+		return nil
+	}
+	if maxRetryAt := now.Add(maxRetryAfter); retryAt.After(maxRetryAt) {
+		retryAt = maxRetryAt
+	}
+
+	return lf.linkParker.UpsertLink(&graph.Link{
+		ID:             payload.LinkID,
+		URL:            payload.URL,
+		RetrievedAt:    payload.RetrievedAt,
+		RetryNotBefore: retryAt,
+	})
+}
+
+//parseRetryAfter parses a Retry-After header value which can either be a
+//number of seconds or an HTTP date
+func parseRetryAfter(v string, now time.Time) (time.Time, bool) {
+	if v = strings.TrimSpace(v); v == "" {
+		return time.Time{}, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return time.Time{}, false
+		}
+		return now.Add(time.Duration(secs) * time.Second), true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+//skipAfterHead issues a HEAD request for URL and returns true if the headers
+//show that the resource is not an HTML document or that it exceeds the
+//maximum content length.  If the HEAD request fails or is not supported by
+//the server, the link is not skipped and will be fetched normally
+func (lf *linkFetcher) skipAfterHead(ctx context.Context, host, URL string) bool {
+	headGetter, ok := lf.urlGetter.(HeadURLGetter)
+	if !ok || (lf.renderer != nil && lf.shouldRender(URL)) {
+		return false
+	}
+
+	res, err := lf.polite(ctx, host, func() (*http.Response, error) { return headGetter.Head(URL) })
+	if err != nil {
+		return false
+	}
+	_ = res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return false
+	}
+
+	if contentType := res.Header.Get("Content-Type"); contentType != "" && !strings.Contains(contentType, "html") {
+		return true
+	}
+	return lf.maxContentLength > 0 && res.ContentLength > lf.maxContentLength
+}
+
+//politeFetch waits until the politeness controller allows a request to the
+//host of URL, fetches it and reports the outcome back to the controller
+func (lf *linkFetcher) politeFetch(ctx context.Context, host, URL string) (*http.Response, error) {
+	return lf.polite(ctx, host, func() (*http.Response, error) { return lf.fetch(URL) })
+}
+
+//polite performs a request to host via doFn while respecting the politeness
+//controller, if one is configured
+func (lf *linkFetcher) polite(ctx context.Context, host string, doFn func() (*http.Response, error)) (*http.Response, error) {
+	if lf.politeness == nil {
+		return doFn()
+	}
+
+	if err := lf.politeness.Wait(ctx, host); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := doFn()
+	var statusCode int
+	if err == nil {
+		statusCode = res.StatusCode
+	}
+	lf.politeness.Observe(host, time.Since(start), statusCode)
+	return res, err
+}
+
+//fetch retrieves URL using the rendering getter if its host was configured
+//for rendering, or the plain URL getter otherwise
+func (lf *linkFetcher) fetch(URL string) (*http.Response, error) {
+	if lf.renderer != nil && lf.shouldRender(URL) {
+		return lf.renderer.GetRendered(URL)
+	}
+	return lf.urlGetter.Get(URL)
+}
+
+//shouldRender returns true if the host of URL matches, or is a subdomain of,
+//one of the configured render domains
+func (lf *linkFetcher) shouldRender(URL string) bool {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range lf.renderDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
 }
 
 func (lf *linkFetcher) isPrivate(URL string) (bool, error) {
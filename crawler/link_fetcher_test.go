@@ -2,14 +2,23 @@ package crawler
 
 import (
 	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
 
 	"github.com/brandonshearin/ask_brandon/crawler/mocks"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
 	"github.com/golang/mock/gomock"
 	gc "gopkg.in/check.v1"
 )
 
 var _ = gc.Suite(new(LinkFetcherTestSuite))
 
+func Test(t *testing.T) { gc.TestingT(t) }
+
 type LinkFetcherTestSuite struct {
 	urlGetter       *mocks.MockURLGetter
 	privNetDetector *mocks.MockPrivateNetworkDetector
@@ -28,8 +37,171 @@ func (s *LinkFetcherTestSuite) TestLinkFetcherWithExcludedExtension(c *gc.C) {
 	c.Assert(p, gc.IsNil)
 }
 
-func (s *LinkFetcherTestSuite) fetchLink(c *gc.C, url string) *crawlerPayload {
-	p := &crawlerPayload{
+func (s *LinkFetcherTestSuite) TestLinkFetcherWithNonWebScheme(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	s.urlGetter = mocks.NewMockURLGetter(ctrl)
+	s.privNetDetector = mocks.NewMockPrivateNetworkDetector(ctrl)
+
+	p := s.fetchLink(c, "hostgraph://example.com/")
+	c.Assert(p, gc.IsNil)
+}
+
+func (s *LinkFetcherTestSuite) TestLinkFetcherWithHTMLContent(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	s.urlGetter = mocks.NewMockURLGetter(ctrl)
+	s.privNetDetector = mocks.NewMockPrivateNetworkDetector(ctrl)
+
+	res := makeResponse(200, "<html>hello</html>", "text/html; charset=utf-8")
+	res.Request = httptest.NewRequest("GET", "http://example.com/final", nil)
+	s.privNetDetector.EXPECT().IsPrivate("example.com").Return(false, nil)
+	s.urlGetter.EXPECT().Get("http://example.com/start").Return(res, nil)
+
+	p := s.fetchLink(c, "http://example.com/start")
+	c.Assert(p, gc.NotNil)
+	c.Assert(p.RawContent.String(), gc.Equals, "<html>hello</html>")
+	c.Assert(p.HTTPStatus, gc.Equals, 200)
+	c.Assert(p.ContentType, gc.Equals, "text/html; charset=utf-8")
+	c.Assert(p.FinalURL, gc.Equals, "http://example.com/final")
+}
+
+func (s *LinkFetcherTestSuite) TestLinkFetcherWithNonHTMLContent(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	s.urlGetter = mocks.NewMockURLGetter(ctrl)
+	s.privNetDetector = mocks.NewMockPrivateNetworkDetector(ctrl)
+
+	s.privNetDetector.EXPECT().IsPrivate("example.com").Return(false, nil)
+	s.urlGetter.EXPECT().Get("http://example.com/data").Return(
+		makeResponse(200, "{}", "application/json"),
+		nil,
+	)
+
+	p := s.fetchLink(c, "http://example.com/data")
+	c.Assert(p, gc.IsNil)
+}
+
+func (s *LinkFetcherTestSuite) TestLinkFetcherWithRenderedDomain(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	s.urlGetter = mocks.NewMockURLGetter(ctrl)
+	s.privNetDetector = mocks.NewMockPrivateNetworkDetector(ctrl)
+	renderer := mocks.NewMockRenderingURLGetter(ctrl)
+
+	s.privNetDetector.EXPECT().IsPrivate("app.example.com").Return(false, nil)
+	renderer.EXPECT().GetRendered("http://app.example.com/").Return(
+		makeResponse(200, "<html>rendered</html>", "text/html"),
+		nil,
+	)
+
+	lf := newLinkFetcher(s.urlGetter, s.privNetDetector)
+	lf.renderer = renderer
+	lf.renderDomains = []string{"example.com"}
+	_, err := lf.Process(context.TODO(), &Payload{URL: "http://app.example.com/"})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *LinkFetcherTestSuite) TestLinkFetcherParksRateLimitedLink(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	s.urlGetter = mocks.NewMockURLGetter(ctrl)
+	s.privNetDetector = mocks.NewMockPrivateNetworkDetector(ctrl)
+	mockGraph := mocks.NewMockGraph(ctrl)
+
+	res := makeResponse(http.StatusTooManyRequests, "", "text/html")
+	res.Header.Set("Retry-After", "120")
+	s.privNetDetector.EXPECT().IsPrivate("example.com").Return(false, nil)
+	s.urlGetter.EXPECT().Get("http://example.com/").Return(res, nil)
+
+	var parked *graph.Link
+	mockGraph.EXPECT().UpsertLink(gomock.Any()).DoAndReturn(func(link *graph.Link) error {
+		parked = link
+		return nil
+	})
+
+	lf := newLinkFetcher(s.urlGetter, s.privNetDetector)
+	lf.linkParker = mockGraph
+	out, err := lf.Process(context.TODO(), &Payload{URL: "http://example.com/"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.IsNil)
+	c.Assert(parked, gc.NotNil)
+	c.Assert(parked.URL, gc.Equals, "http://example.com/")
+	c.Assert(parked.RetryNotBefore.After(time.Now().Add(time.Minute)), gc.Equals, true)
+}
+
+func (s *LinkFetcherTestSuite) TestParseRetryAfter(c *gc.C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got, ok := parseRetryAfter("30", now)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(got, gc.Equals, now.Add(30*time.Second))
+
+	got, ok = parseRetryAfter("Wed, 01 Jan 2020 01:00:00 GMT", now)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(got.Equal(now.Add(time.Hour)), gc.Equals, true)
+
+	for _, v := range []string{"", "-5", "soon"} {
+		_, ok = parseRetryAfter(v, now)
+		c.Assert(ok, gc.Equals, false, gc.Commentf("value %q", v))
+	}
+}
+
+//headURLGetter is a URLGetter that also supports HEAD requests and records
+//the requests it serves
+type headURLGetter struct {
+	headRes  *http.Response
+	requests []string
+}
+
+func (g *headURLGetter) Head(url string) (*http.Response, error) {
+	g.requests = append(g.requests, "HEAD "+url)
+	return g.headRes, nil
+}
+
+func (g *headURLGetter) Get(url string) (*http.Response, error) {
+	g.requests = append(g.requests, "GET "+url)
+	return makeResponse(200, "<html></html>", "text/html"), nil
+}
+
+func (s *LinkFetcherTestSuite) TestLinkFetcherHeadPrecheck(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	s.privNetDetector = mocks.NewMockPrivateNetworkDetector(ctrl)
+	s.privNetDetector.EXPECT().IsPrivate("example.com").Return(false, nil).AnyTimes()
+
+	oversized := makeResponse(200, "", "text/html")
+	oversized.ContentLength = 1 << 20
+	specs := []struct {
+		descr   string
+		headRes *http.Response
+		expGet  bool
+	}{
+		{"html", makeResponse(200, "", "text/html; charset=utf-8"), true},
+		{"non-html", makeResponse(200, "", "application/pdf"), false},
+		{"oversized", oversized, false},
+		{"head not allowed", makeResponse(http.StatusMethodNotAllowed, "", ""), true},
+	}
+
+	for _, spec := range specs {
+		getter := &headURLGetter{headRes: spec.headRes}
+		lf := newLinkFetcher(getter, s.privNetDetector)
+		lf.headPrecheck = true
+		lf.maxContentLength = 1024
+
+		_, err := lf.Process(context.TODO(), &Payload{URL: "http://example.com/"})
+		c.Assert(err, gc.IsNil)
+
+		expRequests := []string{"HEAD http://example.com/"}
+		if spec.expGet {
+			expRequests = append(expRequests, "GET http://example.com/")
+		}
+		c.Assert(getter.requests, gc.DeepEquals, expRequests, gc.Commentf(spec.descr))
+	}
+}
+
+func (s *LinkFetcherTestSuite) fetchLink(c *gc.C, url string) *Payload {
+	p := &Payload{
 		URL: url,
 	}
 
@@ -37,8 +209,20 @@ func (s *LinkFetcherTestSuite) fetchLink(c *gc.C, url string) *crawlerPayload {
 	c.Assert(err, gc.IsNil)
 	if out != nil {
 		c.Assert(out, gc.FitsTypeOf, p)
-		return out.(*crawlerPayload)
+		return out.(*Payload)
 	}
 
 	return nil
 }
+
+func makeResponse(status int, body, contentType string) *http.Response {
+	res := &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+	if contentType != "" {
+		res.Header.Set("Content-Type", contentType)
+	}
+	return res
+}
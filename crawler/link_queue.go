@@ -0,0 +1,40 @@
+package crawler
+
+import "github.com/brandonshearin/ask_brandon/linkgraph/graph"
+
+// DomainAuthority is implemented by objects that score hosts by their
+// authority, such as the domain authority scores of the hostgraph package.
+type DomainAuthority interface {
+	Score(host string) float64
+}
+
+// queuedLink is a link buffered by the link source.
+type queuedLink struct {
+	link      *graph.Link
+	authority float64
+	seq       int
+}
+
+// linkQueue implements heap.Interface. Links with a higher priority are
+// popped first; links with the same priority are popped in order of
+// decreasing domain authority and then in the order they were pushed.
+type linkQueue []queuedLink
+
+func (q linkQueue) Len() int { return len(q) }
+func (q linkQueue) Less(i, j int) bool {
+	if q[i].link.Priority != q[j].link.Priority {
+		return q[i].link.Priority > q[j].link.Priority
+	}
+	if q[i].authority != q[j].authority {
+		return q[i].authority > q[j].authority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q linkQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *linkQueue) Push(x interface{}) { *q = append(*q, x.(queuedLink)) }
+func (q *linkQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"context"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/mediaindex/media"
+	"github.com/brandonshearin/ask_brandon/pipeline"
+)
+
+var (
+	mediaTagRegex   = regexp.MustCompile(`(?is)<(img|video|audio|source)\s[^>]*>`)
+	videoExtRegex   = regexp.MustCompile(`(?i)\.(?:mp4|webm|ogv|mov|m3u8)$`)
+	audioExtRegex   = regexp.MustCompile(`(?i)\.(?:mp3|ogg|oga|wav|m4a|flac)$`)
+	mediaTagToTypes = map[string]media.Type{
+		"img":   media.TypeImage,
+		"video": media.TypeVideo,
+		"audio": media.TypeAudio,
+	}
+)
+
+// MediaStore is implemented by objects that can catalog the images and
+// videos referenced by crawled pages.
+type MediaStore interface {
+	UpsertItem(item *media.Item) error
+}
+
+// mediaExtractor collects the media resources that are embedded in a page.
+// These are skipped by the link extractor because they do not contain HTML.
+type mediaExtractor struct{}
+
+func newMediaExtractor() *mediaExtractor {
+	return &mediaExtractor{}
+}
+
+func (me *mediaExtractor) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	payload := p.(*Payload)
+	relTo, err := url.Parse(payload.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	seenMap := make(map[string]struct{})
+	for _, match := range mediaTagRegex.FindAllStringSubmatch(payload.RawContent.String(), -1) {
+		attrs := make(map[string]string)
+		for _, attr := range htmlAttrRegex.FindAllStringSubmatch(match[0], -1) {
+			attrs[strings.ToLower(attr[1])] = attr[2] + attr[3]
+		}
+
+		link := resolveURL(relTo, strings.TrimSpace(html.UnescapeString(attrs["src"])))
+		if link == nil || (link.Scheme != "http" && link.Scheme != "https") {
+			continue // skip data: URIs and unresolvable links
+		}
+
+		link.Fragment = ""
+		linkStr := link.String()
+		if _, seen := seenMap[linkStr]; seen {
+			continue
+		}
+		seenMap[linkStr] = struct{}{}
+
+		payload.Media = append(payload.Media, media.Item{
+			URL:     linkStr,
+			Type:    mediaType(strings.ToLower(match[1]), link.Path),
+			AltText: strings.TrimSpace(html.UnescapeString(attrs["alt"])),
+		})
+	}
+
+	return payload, nil
+}
+
+// mediaType infers the type of a media resource from the tag that embeds it
+// or, for <source> tags, from the resource's file extension.
+func mediaType(tag, path string) media.Type {
+	if t, found := mediaTagToTypes[tag]; found {
+		return t
+	}
+
+	if audioExtRegex.MatchString(path) {
+		return media.TypeAudio
+	} else if videoExtRegex.MatchString(path) {
+		return media.TypeVideo
+	}
+	return media.TypeImage
+}
+
+// mediaIndexer records the media extracted from a page into a MediaStore.
+type mediaIndexer struct {
+	store MediaStore
+}
+
+func newMediaIndexer(store MediaStore) *mediaIndexer {
+	return &mediaIndexer{
+		store: store,
+	}
+}
+
+// Process stores the media items of the payload and then discards it; this
+// stage runs as an extra branch of the broadcast stage and its output should
+// not be counted by the pipeline sink.
+func (mi *mediaIndexer) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	payload := p.(*Payload)
+
+	now := time.Now()
+	for _, item := range payload.Media {
+		item.PageLinkID = payload.LinkID
+		item.PageURL = payload.URL
+		item.DiscoveredAt = now
+		if err := mi.store.UpsertItem(&item); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,34 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/mediaindex/media"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(MediaExtractorTestSuite))
+
+type MediaExtractorTestSuite struct{}
+
+func (s *MediaExtractorTestSuite) TestExtractMedia(c *gc.C) {
+	content := `<html><body>
+<img src="/img/gopher.png" alt="A &quot;gopher&quot;">
+<img alt="dup" src="http://example.com/img/gopher.png#top">
+<img src="data:image/png;base64,AAAA">
+<video poster="/poster.jpg" src="//cdn.example.com/intro.webm"></video>
+<audio><source src="theme.mp3" type="audio/mpeg"></audio>
+</body></html>`
+
+	p := &Payload{URL: "http://example.com/blog/"}
+	_, err := p.RawContent.WriteString(content)
+	c.Assert(err, gc.IsNil)
+
+	out, err := newMediaExtractor().Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out.(*Payload).Media, gc.DeepEquals, []media.Item{
+		{URL: "http://example.com/img/gopher.png", Type: media.TypeImage, AltText: `A "gopher"`},
+		{URL: "http://cdn.example.com/intro.webm", Type: media.TypeVideo},
+		{URL: "http://example.com/blog/theme.mp3", Type: media.TypeAudio},
+	})
+}
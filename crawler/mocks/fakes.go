@@ -0,0 +1,218 @@
+package mocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+)
+
+// StaleEdgesCall records the arguments of a RemoveStaleEdges call.
+type StaleEdgesCall struct {
+	FromID        uuid.UUID
+	UpdatedBefore time.Time
+}
+
+// FakeGraph is an in-memory implementation of the crawler Graph interface
+// that records the calls it receives. Unlike MockGraph, it does not require
+// expectations to be set up front which makes it convenient for tests that
+// only care about the final state of the graph. FakeGraph is safe for
+// concurrent use.
+type FakeGraph struct {
+	mu sync.Mutex
+
+	// Err, if set, is returned by all methods of the fake.
+	Err error
+
+	links      map[string]*graph.Link
+	linkURLs   map[uuid.UUID]string
+	edges      map[[2]uuid.UUID]*graph.Edge
+	staleCalls []StaleEdgesCall
+}
+
+// NewFakeGraph returns a new, empty FakeGraph.
+func NewFakeGraph() *FakeGraph {
+	return &FakeGraph{
+		links:    make(map[string]*graph.Link),
+		linkURLs: make(map[uuid.UUID]string),
+		edges:    make(map[[2]uuid.UUID]*graph.Edge),
+	}
+}
+
+// UpsertLink creates a new link or updates the existing link with the same
+// URL. Like the real graph implementations, it assigns an ID to new links
+// and populates link.ID.
+func (g *FakeGraph) UpsertLink(link *graph.Link) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Err != nil {
+		return g.Err
+	}
+
+	if existing := g.links[link.URL]; existing != nil {
+		link.ID = existing.ID
+	} else if link.ID == uuid.Nil {
+		link.ID = uuid.New()
+	}
+
+	lCopy := new(graph.Link)
+	*lCopy = *link
+	g.links[link.URL] = lCopy
+	g.linkURLs[link.ID] = link.URL
+	return nil
+}
+
+// UpsertEdge creates a new edge or refreshes the update time of an existing
+// edge between the same links.
+func (g *FakeGraph) UpsertEdge(edge *graph.Edge) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Err != nil {
+		return g.Err
+	}
+
+	key := [2]uuid.UUID{edge.Src, edge.Dst}
+	edge.ID = graph.EdgeID(edge.Src, edge.Dst)
+	edge.UpdatedAt = time.Now()
+
+	eCopy := new(graph.Edge)
+	*eCopy = *edge
+	g.edges[key] = eCopy
+	return nil
+}
+
+// RemoveStaleEdges removes the edges originating from fromID that were last
+// updated before updatedBefore and records the call.
+func (g *FakeGraph) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.Err != nil {
+		return g.Err
+	}
+
+	g.staleCalls = append(g.staleCalls, StaleEdgesCall{FromID: fromID, UpdatedBefore: updatedBefore})
+	for key, edge := range g.edges {
+		if edge.Src == fromID && edge.UpdatedAt.Before(updatedBefore) {
+			delete(g.edges, key)
+		}
+	}
+	return nil
+}
+
+// Link returns a copy of the link with the specified URL.
+func (g *FakeGraph) Link(url string) (*graph.Link, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	link := g.links[url]
+	if link == nil {
+		return nil, false
+	}
+	lCopy := new(graph.Link)
+	*lCopy = *link
+	return lCopy, true
+}
+
+// LinkURLs returns the sorted list of link URLs in the graph.
+func (g *FakeGraph) LinkURLs() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	urls := make([]string, 0, len(g.links))
+	for url := range g.links {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// EdgeURLs returns the sorted list of edges in the graph formatted as
+// "src-URL -> dst-URL".
+func (g *FakeGraph) EdgeURLs() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	edges := make([]string, 0, len(g.edges))
+	for _, edge := range g.edges {
+		edges = append(edges, g.linkURLs[edge.Src]+" -> "+g.linkURLs[edge.Dst])
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+// HasEdge returns true if the graph contains an edge between the links with
+// the specified URLs.
+func (g *FakeGraph) HasEdge(srcURL, dstURL string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	src, dst := g.links[srcURL], g.links[dstURL]
+	if src == nil || dst == nil {
+		return false
+	}
+	_, found := g.edges[[2]uuid.UUID{src.ID, dst.ID}]
+	return found
+}
+
+// StaleEdgesCalls returns the recorded RemoveStaleEdges calls in the order
+// they were received.
+func (g *FakeGraph) StaleEdgesCalls() []StaleEdgesCall {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]StaleEdgesCall(nil), g.staleCalls...)
+}
+
+// FakeIndexer is an in-memory implementation of the crawler Indexer
+// interface that records the documents it receives. FakeIndexer is safe for
+// concurrent use.
+type FakeIndexer struct {
+	mu sync.Mutex
+
+	// Err, if set, is returned by Index.
+	Err error
+
+	docs []*index.Document
+}
+
+// NewFakeIndexer returns a new FakeIndexer with no recorded documents.
+func NewFakeIndexer() *FakeIndexer {
+	return new(FakeIndexer)
+}
+
+// Index records a copy of doc.
+func (i *FakeIndexer) Index(doc *index.Document) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.Err != nil {
+		return i.Err
+	}
+
+	dCopy := new(index.Document)
+	*dCopy = *doc
+	i.docs = append(i.docs, dCopy)
+	return nil
+}
+
+// Documents returns the recorded documents in the order they were indexed.
+func (i *FakeIndexer) Documents() []*index.Document {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]*index.Document(nil), i.docs...)
+}
+
+// Document returns the most recently indexed document with the specified
+// URL.
+func (i *FakeIndexer) Document(url string) (*index.Document, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for j := len(i.docs) - 1; j >= 0; j-- {
+		if i.docs[j].URL == url {
+			return i.docs[j], true
+		}
+	}
+	return nil, false
+}
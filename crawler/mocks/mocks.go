@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/PacktPublishing/Hands-On-Software-Engineering-with-Golang/Chapter07/crawler (interfaces: URLGetter,PrivateNetworkDetector,Graph,Indexer)
+// Source: github.com/PacktPublishing/Hands-On-Software-Engineering-with-Golang/Chapter07/crawler (interfaces: URLGetter,RenderingURLGetter,PrivateNetworkDetector,Graph,Indexer)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -52,6 +52,44 @@ func (mr *MockURLGetterMockRecorder) Get(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockURLGetter)(nil).Get), arg0)
 }
 
+// MockRenderingURLGetter is a mock of RenderingURLGetter interface
+type MockRenderingURLGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRenderingURLGetterMockRecorder
+}
+
+// MockRenderingURLGetterMockRecorder is the mock recorder for MockRenderingURLGetter
+type MockRenderingURLGetterMockRecorder struct {
+	mock *MockRenderingURLGetter
+}
+
+// NewMockRenderingURLGetter creates a new mock instance
+func NewMockRenderingURLGetter(ctrl *gomock.Controller) *MockRenderingURLGetter {
+	mock := &MockRenderingURLGetter{ctrl: ctrl}
+	mock.recorder = &MockRenderingURLGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRenderingURLGetter) EXPECT() *MockRenderingURLGetterMockRecorder {
+	return m.recorder
+}
+
+// GetRendered mocks base method
+func (m *MockRenderingURLGetter) GetRendered(arg0 string) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRendered", arg0)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRendered indicates an expected call of GetRendered
+func (mr *MockRenderingURLGetterMockRecorder) GetRendered(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRendered", reflect.TypeOf((*MockRenderingURLGetter)(nil).GetRendered), arg0)
+}
+
 // MockPrivateNetworkDetector is a mock of PrivateNetworkDetector interface
 type MockPrivateNetworkDetector struct {
 	ctrl     *gomock.Controller
@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"sync/atomic"
+
+	"github.com/brandonshearin/ask_brandon/crawler/alert"
+)
+
+// passCounters collects the fetch statistics of a crawl pass. The counters
+// are shared by all payloads of the pass and are updated atomically. All
+// methods can be invoked on a nil receiver.
+type passCounters struct {
+	fetched      int64
+	errors       int64
+	clientErrors int64
+	serverErrors int64
+}
+
+// recordResponse counts a response with the specified status code.
+func (pc *passCounters) recordResponse(statusCode int) {
+	if pc == nil {
+		return
+	}
+	atomic.AddInt64(&pc.fetched, 1)
+	switch {
+	case statusCode >= 400 && statusCode <= 499:
+		atomic.AddInt64(&pc.clientErrors, 1)
+	case statusCode >= 500 && statusCode <= 599:
+		atomic.AddInt64(&pc.serverErrors, 1)
+	}
+}
+
+// recordError counts a request that failed without a response.
+func (pc *passCounters) recordError() {
+	if pc == nil {
+		return
+	}
+	atomic.AddInt64(&pc.errors, 1)
+}
+
+// stats returns a snapshot of the counters.
+func (pc *passCounters) stats(passID uint64) alert.PassStats {
+	stats := alert.PassStats{PassID: passID}
+	if pc != nil {
+		stats.Fetched = int(atomic.LoadInt64(&pc.fetched))
+		stats.Errors = int(atomic.LoadInt64(&pc.errors))
+		stats.ClientErrors = int(atomic.LoadInt64(&pc.clientErrors))
+		stats.ServerErrors = int(atomic.LoadInt64(&pc.serverErrors))
+	}
+	return stats
+}
@@ -0,0 +1,32 @@
+package crawler
+
+import (
+	"github.com/brandonshearin/ask_brandon/crawler/alert"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(PassStatsTestSuite))
+
+type PassStatsTestSuite struct{}
+
+func (s *PassStatsTestSuite) TestPassCounters(c *gc.C) {
+	pc := new(passCounters)
+	for _, status := range []int{200, 200, 301, 404, 410, 503} {
+		pc.recordResponse(status)
+	}
+	pc.recordError()
+
+	c.Assert(pc.stats(7), gc.Equals, alert.PassStats{
+		PassID:       7,
+		Fetched:      6,
+		Errors:       1,
+		ClientErrors: 2,
+		ServerErrors: 1,
+	})
+
+	// Payloads that do not belong to a crawl pass are not counted.
+	var nilCounters *passCounters
+	nilCounters.recordResponse(200)
+	nilCounters.recordError()
+	c.Assert(nilCounters.stats(1), gc.Equals, alert.PassStats{PassID: 1})
+}
@@ -1,65 +1,160 @@
 package crawler
 
 import (
-	"bytes"
-	"fmt"
 	"io"
+	"net/http"
 	"sync"
 	"time"
 
+	"github.com/brandonshearin/ask_brandon/mediaindex/media"
 	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
 	"github.com/google/uuid"
+	"golang.org/x/xerrors"
 )
 
 var (
-	// _ pipeline.Payload = (*crawlerPayload)(nil)
+	// _ pipeline.Payload = (*Payload)(nil)
 
 	payloadPool = sync.Pool{
-		New: func() interface{} { return new(crawlerPayload) },
+		New: func() interface{} { return new(Payload) },
 	}
 )
 
-type crawlerPayload struct {
+// Payload is the pipeline.Payload that flows through the crawler pipeline.
+// Each field is populated by the stage noted next to it and can be read (or
+// further enriched) by the stages that follow, including the custom hooks and
+// stages provided via Config.PreFetch, Config.PostExtract, Config.PreIndex and
+// Config.ExtraStages.  RawContent holds the page body as fetched and can be
+// read via its Reader or String methods.
+//
+// Payloads are pooled and reused once processed, so stages must not retain
+// references to a payload or its fields after returning it.  New fields may be
+// added in future versions; custom stages should not construct payloads or
+// rely on the set of fields being fixed.
+type Payload struct {
 	LinkID      uuid.UUID
 	URL         string
 	RetrievedAt time.Time
+	CrawlPassID uint64              //populated by the link source
+	passStats   *passCounters       //^^ fetch statistics of the crawl pass
+	spiderTraps *spiderTrapDetector //^^ spider-trap state of the crawl pass
 
-	RawContent bytes.Buffer //populated by link fetcher stage
+	RawContent  spoolBuffer //populated by link fetcher stage
+	FetchedAt   time.Time   //^^
+	HTTPStatus  int         //^^
+	ContentHash string      //^^ hex-encoded SHA-256 of RawContent
+	ContentType string      //^^
+	FinalURL    string      //^^ URL after following any redirects
+	Header      http.Header //^^ response headers
 
 	// NoFollowLinks are still added to the graph but no outgoing edges
 	// will be created from this link to them.
 	NoFollowLinks []string //populated by link extractor stage
 	Links         []string //^^
+	FeedLinks     []string //^^ RSS/Atom feeds advertised by the page
 
-	Title       string //populated by text extractor stage
-	TextContent string //^^
+	// LinkPriorities holds the non-zero crawl priorities assigned to
+	// Links and NoFollowLinks by the crawl rules.
+	LinkPriorities map[string]int //populated by link extractor stage
+
+	Title        string //populated by text extractor stage
+	TextContent  string //^^
+	MainText     string //^^ TextContent without boilerplate (if enabled)
+	Language     string //^^
+	FilterReason string //^^ set if the content classifier flagged the page
+	Soft404      bool   //populated by soft-404 detector stage (if enabled)
+
+	Description   string   //populated by text extractor stage from <meta> tags
+	Keywords      []string //^^
+	OGTitle       string   //^^
+	OGDescription string   //^^
+	OGImage       string   //^^
+
+	Entities []index.Entity //populated by structured data extractor stage
+
+	Media []media.Item //populated by media extractor stage (if enabled)
 }
 
-//Clone implements pipeline.Payload
-func (p *crawlerPayload) Clone() pipeline.Payload {
-	newP := payloadPool.Get().(*crawlerPayload)
+//Clone implements pipeline.Payload.  It fails if the raw content cannot be
+//copied, e.g. because it cannot be spooled to disk
+func (p *Payload) Clone() (pipeline.Payload, error) {
+	newP := payloadPool.Get().(*Payload)
 	newP.LinkID = p.LinkID
 	newP.URL = p.URL
 	newP.RetrievedAt = p.RetrievedAt
+	newP.CrawlPassID = p.CrawlPassID
+	newP.passStats = p.passStats
+	newP.spiderTraps = p.spiderTraps
+	newP.FetchedAt = p.FetchedAt
+	newP.HTTPStatus = p.HTTPStatus
+	newP.ContentHash = p.ContentHash
+	newP.ContentType = p.ContentType
+	newP.FinalURL = p.FinalURL
+	newP.Header = p.Header.Clone()
 	newP.NoFollowLinks = append([]string(nil), p.NoFollowLinks...)
 	newP.Links = append([]string(nil), p.Links...)
+	newP.FeedLinks = append([]string(nil), p.FeedLinks...)
+	if len(p.LinkPriorities) != 0 {
+		newP.LinkPriorities = make(map[string]int, len(p.LinkPriorities))
+		for link, priority := range p.LinkPriorities {
+			newP.LinkPriorities[link] = priority
+		}
+	}
 	newP.Title = p.Title
 	newP.TextContent = p.TextContent
+	newP.MainText = p.MainText
+	newP.Language = p.Language
+	newP.Soft404 = p.Soft404
+	newP.FilterReason = p.FilterReason
+	newP.Description = p.Description
+	newP.Keywords = append([]string(nil), p.Keywords...)
+	newP.OGTitle = p.OGTitle
+	newP.OGDescription = p.OGDescription
+	newP.OGImage = p.OGImage
+	newP.Entities = append([]index.Entity(nil), p.Entities...)
+	newP.Media = append([]media.Item(nil), p.Media...)
 
-	_, err := io.Copy(&newP.RawContent, &p.RawContent)
-	if err != nil {
-		panic(fmt.Sprintf("[BUG] error cloning payload raw content: %v", err))
+	newP.RawContent.spoolThreshold = p.RawContent.spoolThreshold
+	newP.RawContent.spoolDir = p.RawContent.spoolDir
+	if _, err := io.Copy(&newP.RawContent, p.RawContent.Reader()); err != nil {
+		newP.MarkAsProcessed()
+		return nil, xerrors.Errorf("clone raw content: %w", err)
 	}
-	return newP
+	return newP, nil
 }
 
-//MarkAsProcessed implements pipeline.Payload
-func (p *crawlerPayload) MarkAsProcessed() {
+//MarkAsProcessed implements pipeline.Payload.  Any temporary file used for
+//spooling the raw content is removed
+func (p *Payload) MarkAsProcessed() {
 	p.URL = p.URL[:0]
+	p.CrawlPassID = 0
+	p.passStats = nil
+	p.spiderTraps = nil
 	p.RawContent.Reset()
+	p.HTTPStatus = 0
+	p.ContentHash = p.ContentHash[:0]
+	p.ContentType = p.ContentType[:0]
+	p.FinalURL = p.FinalURL[:0]
+	p.Header = nil
 	p.NoFollowLinks = p.NoFollowLinks[:0]
 	p.Links = p.Links[:0]
+	p.FeedLinks = p.FeedLinks[:0]
+	for link := range p.LinkPriorities {
+		delete(p.LinkPriorities, link)
+	}
 	p.Title = p.Title[:0]
 	p.TextContent = p.TextContent[:0]
+	p.MainText = p.MainText[:0]
+	p.Language = p.Language[:0]
+	p.Soft404 = false
+	p.FilterReason = p.FilterReason[:0]
+	p.Description = p.Description[:0]
+	p.Keywords = p.Keywords[:0]
+	p.OGTitle = p.OGTitle[:0]
+	p.OGDescription = p.OGDescription[:0]
+	p.OGImage = p.OGImage[:0]
+	p.Entities = p.Entities[:0]
+	p.Media = p.Media[:0]
 	payloadPool.Put(p)
 }
@@ -0,0 +1,302 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// delayBackoffFactor is applied to the per-host delay when a server
+	// signals that it is overloaded (429/503 responses or fetch errors).
+	delayBackoffFactor = 2.0
+
+	// delaySlowFactor is applied to the per-host delay when a server
+	// responds slower than the configured threshold.
+	delaySlowFactor = 1.5
+
+	// delayRecoveryFactor is applied to the per-host delay when a server
+	// responds promptly and successfully.
+	delayRecoveryFactor = 0.9
+)
+
+// adaptiveDelay is a politeness controller that spaces out requests to the
+// same host. The delay between two requests to a host grows when the host
+// responds slowly or asks us to back off and shrinks again while the host
+// remains healthy. The controller is disabled while the maximum delay is zero.
+type adaptiveDelay struct {
+	minDelay     time.Duration
+	maxDelay     time.Duration
+	slowResponse time.Duration
+
+	// siteDelays, if set, provides per-site minimum delays that are
+	// enforced even while the controller is disabled.
+	siteDelays SiteCrawlDelays
+
+	mu    sync.Mutex
+	hosts map[string]*hostDelay
+}
+
+// SiteCrawlDelays is implemented by objects that provide per-site crawl rate
+// preferences, e.g. the ones set by verified site owners (see the webmaster
+// package).
+type SiteCrawlDelays interface {
+	// CrawlDelay returns the minimum delay between two requests to host or
+	// zero if no preference has been set for host.
+	CrawlDelay(host string) time.Duration
+}
+
+type hostDelay struct {
+	delay       time.Duration
+	nextFetchAt time.Time
+}
+
+func newAdaptiveDelay(minDelay, maxDelay, slowResponse time.Duration) *adaptiveDelay {
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	return &adaptiveDelay{
+		minDelay:     minDelay,
+		maxDelay:     maxDelay,
+		slowResponse: slowResponse,
+		hosts:        make(map[string]*hostDelay),
+	}
+}
+
+// Wait blocks until the next request to host is allowed to proceed or ctx
+// expires. Each call reserves a slot, so concurrent callers for the same host
+// are spaced out by the current delay. The delay never drops below the
+// preference configured for the site, if any.
+func (d *adaptiveDelay) Wait(ctx context.Context, host string) error {
+	d.mu.Lock()
+	siteDelays := d.siteDelays
+	d.mu.Unlock()
+
+	// Preferences are looked up without holding the lock as they may be
+	// served by a slow external store.
+	var siteDelay time.Duration
+	if siteDelays != nil {
+		siteDelay = siteDelays.CrawlDelay(host)
+	}
+
+	d.mu.Lock()
+	if d.maxDelay == 0 && siteDelay <= 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	hd := d.hostDelay(host)
+	delay := hd.delay
+	if delay < siteDelay {
+		delay = siteDelay
+	}
+	now := time.Now()
+	fetchAt := hd.nextFetchAt
+	if fetchAt.Before(now) {
+		fetchAt = now
+	}
+	hd.nextFetchAt = fetchAt.Add(delay)
+	d.mu.Unlock()
+
+	if wait := fetchAt.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Observe adjusts the delay for host based on the outcome of a request. A
+// zero statusCode indicates that the request failed without a response.
+func (d *adaptiveDelay) Observe(host string, latency time.Duration, statusCode int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.maxDelay == 0 {
+		return
+	}
+
+	hd := d.hostDelay(host)
+	factor := delayRecoveryFactor
+	switch {
+	case statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		factor = delayBackoffFactor
+	case d.slowResponse > 0 && latency > d.slowResponse:
+		factor = delaySlowFactor
+	}
+
+	// Make sure that we can back off even if the min delay is zero.
+	delay := hd.delay
+	if delay == 0 && factor > 1 {
+		delay = d.slowResponse
+		if delay == 0 {
+			delay = time.Second
+		}
+	}
+
+	hd.delay = d.clamp(time.Duration(float64(delay) * factor))
+}
+
+// clamp limits delay to the configured range. Callers must hold the lock.
+func (d *adaptiveDelay) clamp(delay time.Duration) time.Duration {
+	if delay < d.minDelay {
+		return d.minDelay
+	} else if delay > d.maxDelay {
+		return d.maxDelay
+	}
+	return delay
+}
+
+// setBounds changes the range of the per-host delays and the slow response
+// threshold. The current delays are clamped to the new range. A zero
+// maxDelay disables the politeness controller.
+func (d *adaptiveDelay) setBounds(minDelay, maxDelay, slowResponse time.Duration) {
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.minDelay, d.maxDelay, d.slowResponse = minDelay, maxDelay, slowResponse
+	for _, hd := range d.hosts {
+		hd.delay = d.clamp(hd.delay)
+	}
+}
+
+// setSiteDelays changes the provider of the per-site delay preferences. A nil
+// provider removes all preferences.
+func (d *adaptiveDelay) setSiteDelays(siteDelays SiteCrawlDelays) {
+	d.mu.Lock()
+	d.siteDelays = siteDelays
+	d.mu.Unlock()
+}
+
+// Delay returns the current delay between requests to host.
+func (d *adaptiveDelay) Delay(host string) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.hostDelay(host).delay
+}
+
+// hostDelay returns the delay state for host, creating it if required.
+// Callers must hold the lock.
+func (d *adaptiveDelay) hostDelay(host string) *hostDelay {
+	hd := d.hosts[host]
+	if hd == nil {
+		hd = &hostDelay{delay: d.minDelay}
+		d.hosts[host] = hd
+	}
+	return hd
+}
+
+// hostLimiter caps the number of concurrent connections to each host,
+// independently of the number of fetch workers. The limit can be changed
+// while requests are in flight.
+type hostLimiter struct {
+	mu       sync.Mutex
+	maxConns int
+	hosts    map[string]*hostSemaphore
+}
+
+type hostSemaphore struct {
+	// active counts the callers that are holding a connection slot while
+	// waiters holds the channels of the callers that are queued for one in
+	// FIFO order. A waiter is granted a slot by closing its channel. The
+	// semaphore is dropped once the host becomes idle.
+	active  int
+	waiters []chan struct{}
+}
+
+func newHostLimiter(maxConns int) *hostLimiter {
+	return &hostLimiter{
+		maxConns: maxConns,
+		hosts:    make(map[string]*hostSemaphore),
+	}
+}
+
+// Acquire blocks until a connection slot for host becomes available or ctx
+// expires. Callers must invoke Release once they are done with the slot.
+func (l *hostLimiter) Acquire(ctx context.Context, host string) error {
+	l.mu.Lock()
+	sem := l.hosts[host]
+	if sem == nil {
+		sem = new(hostSemaphore)
+		l.hosts[host] = sem
+	}
+	if sem.active < l.maxConns && len(sem.waiters) == 0 {
+		sem.active++
+		l.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	sem.waiters = append(sem.waiters, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, waiter := range sem.waiters {
+		if waiter == ready {
+			sem.waiters = append(sem.waiters[:i], sem.waiters[i+1:]...)
+			l.dropIfIdle(host, sem)
+			return ctx.Err()
+		}
+	}
+
+	// The slot was granted just as ctx expired; pass it on
+	l.release(host, sem)
+	return ctx.Err()
+}
+
+// Release returns a connection slot for host that was obtained via Acquire.
+func (l *hostLimiter) Release(host string) {
+	l.mu.Lock()
+	l.release(host, l.hosts[host])
+	l.mu.Unlock()
+}
+
+// setMaxConns changes the maximum number of concurrent connections per host.
+// Raising the limit immediately admits queued callers while lowering it lets
+// the requests in flight complete.
+func (l *hostLimiter) setMaxConns(maxConns int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxConns = maxConns
+	for _, sem := range l.hosts {
+		l.grant(sem)
+	}
+}
+
+// release frees a slot of sem. Callers must hold the lock.
+func (l *hostLimiter) release(host string, sem *hostSemaphore) {
+	sem.active--
+	l.grant(sem)
+	l.dropIfIdle(host, sem)
+}
+
+// grant hands the free slots of sem to its waiters. Callers must hold the
+// lock.
+func (l *hostLimiter) grant(sem *hostSemaphore) {
+	for len(sem.waiters) != 0 && sem.active < l.maxConns {
+		close(sem.waiters[0])
+		sem.waiters = sem.waiters[1:]
+		sem.active++
+	}
+}
+
+// dropIfIdle removes the semaphore of host once it is no longer used.
+// Callers must hold the lock.
+func (l *hostLimiter) dropIfIdle(host string, sem *hostSemaphore) {
+	if sem.active == 0 && len(sem.waiters) == 0 {
+		delete(l.hosts, host)
+	}
+}
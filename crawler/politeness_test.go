@@ -0,0 +1,164 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(AdaptiveDelayTestSuite))
+
+type AdaptiveDelayTestSuite struct{}
+
+func (s *AdaptiveDelayTestSuite) TestDelayAdjustments(c *gc.C) {
+	d := newAdaptiveDelay(100*time.Millisecond, time.Second, 500*time.Millisecond)
+	c.Assert(d.Delay("example.com"), gc.Equals, 100*time.Millisecond)
+
+	// Back off when asked to
+	d.Observe("example.com", 10*time.Millisecond, http.StatusTooManyRequests)
+	c.Assert(d.Delay("example.com"), gc.Equals, 200*time.Millisecond)
+	d.Observe("example.com", 10*time.Millisecond, http.StatusServiceUnavailable)
+	c.Assert(d.Delay("example.com"), gc.Equals, 400*time.Millisecond)
+
+	// Slow responses increase the delay at a lower rate
+	d.Observe("example.com", time.Second, http.StatusOK)
+	c.Assert(d.Delay("example.com"), gc.Equals, 600*time.Millisecond)
+
+	// Delay is capped
+	d.Observe("example.com", 0, 0)
+	c.Assert(d.Delay("example.com"), gc.Equals, time.Second)
+
+	// Healthy responses gradually lower the delay down to the minimum
+	for i := 0; i < 50; i++ {
+		d.Observe("example.com", 10*time.Millisecond, http.StatusOK)
+	}
+	c.Assert(d.Delay("example.com"), gc.Equals, 100*time.Millisecond)
+
+	// Other hosts are not affected
+	c.Assert(d.Delay("other.com"), gc.Equals, 100*time.Millisecond)
+}
+
+func (s *AdaptiveDelayTestSuite) TestWaitSpacesOutRequests(c *gc.C) {
+	d := newAdaptiveDelay(50*time.Millisecond, time.Second, 0)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c.Assert(d.Wait(context.TODO(), "example.com"), gc.IsNil)
+	}
+	c.Assert(time.Since(start) >= 100*time.Millisecond, gc.Equals, true)
+
+	// Waiting on a reserved slot returns early when the context expires
+	ctx, cancelFn := context.WithCancel(context.TODO())
+	cancelFn()
+	c.Assert(d.Wait(ctx, "example.com"), gc.Equals, context.Canceled)
+}
+
+func (s *AdaptiveDelayTestSuite) TestHostLimiter(c *gc.C) {
+	l := newHostLimiter(2)
+
+	c.Assert(l.Acquire(context.TODO(), "example.com"), gc.IsNil)
+	c.Assert(l.Acquire(context.TODO(), "example.com"), gc.IsNil)
+
+	// Other hosts have their own slots
+	c.Assert(l.Acquire(context.TODO(), "other.com"), gc.IsNil)
+	l.Release("other.com")
+
+	// All slots for example.com are taken
+	ctx, cancelFn := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancelFn()
+	c.Assert(l.Acquire(ctx, "example.com"), gc.Equals, context.DeadlineExceeded)
+
+	// Releasing a slot unblocks a waiting caller
+	acquiredCh := make(chan error, 1)
+	go func() { acquiredCh <- l.Acquire(context.TODO(), "example.com") }()
+	l.Release("example.com")
+	select {
+	case err := <-acquiredCh:
+		c.Assert(err, gc.IsNil)
+	case <-time.After(time.Second):
+		c.Fatal("timed out waiting for connection slot")
+	}
+
+	l.Release("example.com")
+	l.Release("example.com")
+	c.Assert(l.hosts, gc.HasLen, 0, gc.Commentf("expected idle host semaphores to be dropped"))
+}
+
+func (s *AdaptiveDelayTestSuite) TestHostLimiterSetMaxConns(c *gc.C) {
+	l := newHostLimiter(1)
+	c.Assert(l.Acquire(context.TODO(), "example.com"), gc.IsNil)
+
+	// Raising the limit admits a queued caller without waiting for a release
+	acquiredCh := make(chan error, 1)
+	go func() { acquiredCh <- l.Acquire(context.TODO(), "example.com") }()
+	for {
+		l.mu.Lock()
+		queued := len(l.hosts["example.com"].waiters)
+		l.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	l.setMaxConns(2)
+	select {
+	case err := <-acquiredCh:
+		c.Assert(err, gc.IsNil)
+	case <-time.After(time.Second):
+		c.Fatal("timed out waiting for connection slot")
+	}
+
+	// Lowering the limit keeps the slots in use but blocks new callers
+	l.setMaxConns(1)
+	l.Release("example.com")
+	ctx, cancelFn := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancelFn()
+	c.Assert(l.Acquire(ctx, "example.com"), gc.Equals, context.DeadlineExceeded)
+
+	l.Release("example.com")
+	c.Assert(l.hosts, gc.HasLen, 0, gc.Commentf("expected idle host semaphores to be dropped"))
+}
+
+func (s *AdaptiveDelayTestSuite) TestSetDelayBounds(c *gc.C) {
+	d := newAdaptiveDelay(100*time.Millisecond, time.Second, 0)
+	d.Observe("example.com", 0, http.StatusTooManyRequests)
+	c.Assert(d.Delay("example.com"), gc.Equals, 200*time.Millisecond)
+
+	// Existing delays are clamped to the new bounds
+	d.setBounds(10*time.Millisecond, 50*time.Millisecond, 0)
+	c.Assert(d.Delay("example.com"), gc.Equals, 50*time.Millisecond)
+	c.Assert(d.Delay("other.com"), gc.Equals, 10*time.Millisecond)
+
+	// A zero max delay disables the controller
+	d.setBounds(0, 0, 0)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c.Assert(d.Wait(context.TODO(), "example.com"), gc.IsNil)
+	}
+	c.Assert(time.Since(start) < 50*time.Millisecond, gc.Equals, true)
+}
+
+func (s *AdaptiveDelayTestSuite) TestSiteCrawlDelays(c *gc.C) {
+	d := newAdaptiveDelay(0, 0, 0)
+	d.setSiteDelays(fixedSiteDelays{"slow.com": 50 * time.Millisecond})
+
+	// Site preferences apply even while the controller is disabled
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		c.Assert(d.Wait(context.TODO(), "slow.com"), gc.IsNil)
+	}
+	c.Assert(time.Since(start) >= 100*time.Millisecond, gc.Equals, true)
+
+	// Other sites are not affected
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		c.Assert(d.Wait(context.TODO(), "fast.com"), gc.IsNil)
+	}
+	c.Assert(time.Since(start) < 50*time.Millisecond, gc.Equals, true)
+}
+
+type fixedSiteDelays map[string]time.Duration
+
+func (d fixedSiteDelays) CrawlDelay(host string) time.Duration { return d[host] }
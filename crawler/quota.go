@@ -0,0 +1,127 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DomainUsage describes how much of its quota a domain used in a crawl pass.
+type DomainUsage struct {
+	Domain string
+
+	// Quota is the maximum number of links of the domain that may be
+	// crawled per pass or zero if the domain is not capped.
+	Quota int
+
+	// Crawled is the number of links that were sent through the pipeline
+	// while Deferred is the number of links that were skipped because the
+	// quota was exhausted. Deferred links are crawled by a subsequent pass.
+	Crawled  int
+	Deferred int
+}
+
+// QuotaReporter is implemented by objects that receive the per-domain quota
+// usage at the end of each crawl pass.
+type QuotaReporter interface {
+	ReportQuotaUsage(passID uint64, usage []DomainUsage)
+}
+
+// domainQuota caps the number of links per domain that are crawled in a
+// single pass. It is shared by the link sources of all the partitions that
+// are crawled by the pass and is safe for concurrent use.
+type domainQuota struct {
+	defaultQuota int
+	overrides    map[string]int
+
+	mu    sync.Mutex
+	usage map[string]*DomainUsage
+}
+
+// newDomainQuota returns a domainQuota that allows up to defaultQuota links
+// per host. Domains listed in overrides (which also match their subdomains)
+// share a single quota across all of their hosts; a non-positive quota
+// disables the cap. If no quotas are configured, newDomainQuota returns nil.
+func newDomainQuota(defaultQuota int, overrides map[string]int) *domainQuota {
+	if defaultQuota <= 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	q := &domainQuota{
+		defaultQuota: defaultQuota,
+		overrides:    make(map[string]int, len(overrides)),
+		usage:        make(map[string]*DomainUsage),
+	}
+	for domain, quota := range overrides {
+		q.overrides[normalizeDomain(domain)] = quota
+	}
+	return q
+}
+
+// allow returns true if the link can be crawled in this pass and records it
+// as either crawled or deferred. A nil domainQuota allows all links.
+func (q *domainQuota) allow(rawURL string) bool {
+	if q == nil {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		// Let the link fetcher discard it.
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	usage := q.usageFor(normalizeDomain(u.Hostname()))
+	if usage.Quota > 0 && usage.Crawled >= usage.Quota {
+		usage.Deferred++
+		return false
+	}
+	usage.Crawled++
+	return true
+}
+
+// usageFor returns the usage entry of the domain that host is accounted to.
+// Callers must hold the lock.
+func (q *domainQuota) usageFor(host string) *DomainUsage {
+	domain, quota := host, q.defaultQuota
+	for candidate := host; ; {
+		if override, found := q.overrides[candidate]; found {
+			domain, quota = candidate, override
+			break
+		}
+		dot := strings.IndexByte(candidate, '.')
+		if dot == -1 {
+			break
+		}
+		candidate = candidate[dot+1:]
+	}
+
+	usage := q.usage[domain]
+	if usage == nil {
+		usage = &DomainUsage{Domain: domain, Quota: quota}
+		if usage.Quota < 0 {
+			usage.Quota = 0
+		}
+		q.usage[domain] = usage
+	}
+	return usage
+}
+
+// report returns the usage of each domain sorted by domain name.
+func (q *domainQuota) report() []DomainUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]DomainUsage, 0, len(q.usage))
+	for _, usage := range q.usage {
+		list = append(list, *usage)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Domain < list[j].Domain })
+	return list
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
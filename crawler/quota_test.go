@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(QuotaTestSuite))
+
+type QuotaTestSuite struct{}
+
+func (s *QuotaTestSuite) TestLinkSourceDefersLinksOverQuota(c *gc.C) {
+	urls := []string{
+		"http://en.wikipedia.org/1",
+		"http://en.wikipedia.org/2",
+		"http://de.wikipedia.org/1",
+		"http://example.com/1",
+		"http://Example.com/2",
+		"http://example.com/3",
+		"http://blog.example.com/1",
+		"http://unlimited.org/1",
+		"http://unlimited.org/2",
+		"http://unlimited.org/3",
+	}
+	src := &linkSource{
+		linkIt: newSliceLinkIterator(urls...),
+		quota: newDomainQuota(2, map[string]int{
+			"Wikipedia.org": 2,
+			"unlimited.org": 0,
+		}),
+	}
+
+	var crawled []string
+	for src.Next(context.TODO()) {
+		crawled = append(crawled, src.latchedLink.URL)
+	}
+	c.Assert(crawled, gc.DeepEquals, []string{
+		"http://en.wikipedia.org/1",
+		"http://en.wikipedia.org/2",
+		"http://example.com/1",
+		"http://Example.com/2",
+		"http://blog.example.com/1",
+		"http://unlimited.org/1",
+		"http://unlimited.org/2",
+		"http://unlimited.org/3",
+	})
+	c.Assert(src.quota.report(), gc.DeepEquals, []DomainUsage{
+		{Domain: "blog.example.com", Quota: 2, Crawled: 1},
+		{Domain: "example.com", Quota: 2, Crawled: 2, Deferred: 1},
+		{Domain: "unlimited.org", Crawled: 3},
+		{Domain: "wikipedia.org", Quota: 2, Crawled: 2, Deferred: 1},
+	})
+}
+
+func (s *QuotaTestSuite) TestQuotasDisabled(c *gc.C) {
+	q := newDomainQuota(0, nil)
+	c.Assert(q, gc.IsNil)
+	c.Assert(q.allow("http://example.com/"), gc.Equals, true)
+}
+
+// sliceLinkIterator is a graph.LinkIterator over a fixed list of links.
+type sliceLinkIterator struct {
+	links []*graph.Link
+	cur   int
+}
+
+func newSliceLinkIterator(urls ...string) *sliceLinkIterator {
+	it := new(sliceLinkIterator)
+	for _, u := range urls {
+		it.links = append(it.links, &graph.Link{URL: u})
+	}
+	return it
+}
+
+func (it *sliceLinkIterator) Next() bool {
+	if it.cur >= len(it.links) {
+		return false
+	}
+	it.cur++
+	return true
+}
+
+func (it *sliceLinkIterator) Link() *graph.Link { return it.links[it.cur-1] }
+func (it *sliceLinkIterator) Error() error      { return nil }
+func (it *sliceLinkIterator) Close() error      { return nil }
+
+func (s *QuotaTestSuite) TestLinkSourcePrioritization(c *gc.C) {
+	it := newSliceLinkIterator(
+		"http://a.com/tag/1",
+		"http://a.com/1",
+		"http://a.com/tag/2",
+		"http://a.com/2",
+		"http://a.com/important",
+	)
+	for _, link := range it.links {
+		switch {
+		case strings.Contains(link.URL, "/tag/"):
+			link.Priority = -1
+		case strings.HasSuffix(link.URL, "important"):
+			link.Priority = 1
+		}
+	}
+	// The quota is consumed by the links with the highest priority.
+	src := &linkSource{linkIt: it, window: 3, quota: newDomainQuota(4, nil)}
+
+	var crawled []string
+	for src.Next(context.TODO()) {
+		crawled = append(crawled, src.latchedLink.URL)
+	}
+	c.Assert(crawled, gc.DeepEquals, []string{
+		"http://a.com/1",
+		"http://a.com/2",
+		"http://a.com/important",
+		"http://a.com/tag/1",
+	})
+}
+
+func (s *QuotaTestSuite) TestLinkSourceDomainAuthority(c *gc.C) {
+	it := newSliceLinkIterator(
+		"http://a.com/1",
+		"http://b.com/1",
+		"http://c.com/1",
+		"http://a.com/important",
+	)
+	it.links[3].Priority = 1
+	// Authority only breaks ties between links with the same priority.
+	src := &linkSource{
+		linkIt:    it,
+		window:    4,
+		authority: fakeAuthority{"b.com": 0.9, "c.com": 0.5},
+		quota:     newDomainQuota(0, nil),
+	}
+
+	var crawled []string
+	for src.Next(context.TODO()) {
+		crawled = append(crawled, src.latchedLink.URL)
+	}
+	c.Assert(crawled, gc.DeepEquals, []string{
+		"http://a.com/important",
+		"http://b.com/1",
+		"http://c.com/1",
+		"http://a.com/1",
+	})
+}
+
+type fakeAuthority map[string]float64
+
+func (a fakeAuthority) Score(host string) float64 { return a[host] }
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Renderer fetches web-pages through a remote headless-Chrome rendering
+// service (e.g. rendertron) that exposes a `GET <endpoint>/render/<url>`
+// API. The body of the returned response contains the serialized DOM after
+// all client-side scripts have been executed.
+type Renderer struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewRenderer returns a new Renderer instance that talks to the rendering
+// service at endpoint. Requests that take longer than timeout to complete
+// will be aborted; a zero timeout disables the request deadline.
+func NewRenderer(endpoint string, timeout time.Duration) (*Renderer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid rendering service endpoint: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, xerrors.Errorf("invalid rendering service endpoint: unsupported scheme %q", u.Scheme)
+	}
+
+	return &Renderer{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// GetRendered asks the rendering service to load target in a headless
+// browser and returns back its response.
+func (r *Renderer) GetRendered(target string) (*http.Response, error) {
+	res, err := r.client.Get(r.endpoint + "/render/" + target)
+	if err != nil {
+		return nil, xerrors.Errorf("render %q: %w", target, err)
+	}
+	return res, nil
+}
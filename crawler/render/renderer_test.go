@@ -0,0 +1,41 @@
+package render
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RendererTestSuite))
+
+type RendererTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *RendererTestSuite) TestGetRendered(c *gc.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html>%s</html>", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	r, err := NewRenderer(srv.URL+"/", 0)
+	c.Assert(err, gc.IsNil)
+
+	res, err := r.GetRendered("http://example.com/app")
+	c.Assert(err, gc.IsNil)
+	body, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(body), gc.Equals, "<html>/render/http://example.com/app</html>")
+	c.Assert(res.Header.Get("Content-Type"), gc.Equals, "text/html")
+}
+
+func (s *RendererTestSuite) TestInvalidEndpoint(c *gc.C) {
+	_, err := NewRenderer("ftp://render.local", 0)
+	c.Assert(err, gc.ErrorMatches, ".*unsupported scheme.*")
+}
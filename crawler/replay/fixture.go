@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrNoFixture is returned by the Replayer when no fixture has been recorded
+// for the requested URL.
+var ErrNoFixture = xerrors.New("no fixture recorded for URL")
+
+// fixture is the on-disk representation of a recorded response.
+type fixture struct {
+	URL        string              `json:"url"`
+	FinalURL   string              `json:"final_url,omitempty"`
+	StatusCode int                 `json:"status_code,omitempty"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       []byte              `json:"body,omitempty"`
+
+	// Error is set instead of the response fields when the request failed.
+	Error string `json:"error,omitempty"`
+}
+
+// fixturePath returns the path of the fixture file for rawURL. File names are
+// derived from a hash of the URL so that any URL maps to a valid file name.
+func fixturePath(dir, rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func writeFixture(dir string, f *fixture) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("encode fixture for %q: %w", f.URL, err)
+	}
+
+	// Write to a temporary file first so a concurrent replay never
+	// observes a partially written fixture.
+	tmp, err := ioutil.TempFile(dir, ".fixture-")
+	if err != nil {
+		return xerrors.Errorf("write fixture for %q: %w", f.URL, err)
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Close()
+	} else {
+		_ = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fixturePath(dir, f.URL))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return xerrors.Errorf("write fixture for %q: %w", f.URL, err)
+	}
+	return nil
+}
+
+func readFixture(dir, rawURL string) (*fixture, error) {
+	data, err := ioutil.ReadFile(fixturePath(dir, rawURL))
+	if os.IsNotExist(err) {
+		return nil, xerrors.Errorf("replay %q: %w", rawURL, ErrNoFixture)
+	} else if err != nil {
+		return nil, xerrors.Errorf("replay %q: %w", rawURL, err)
+	}
+
+	f := new(fixture)
+	if err = json.Unmarshal(data, f); err != nil {
+		return nil, xerrors.Errorf("replay %q: decode fixture: %w", rawURL, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,122 @@
+package replay
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"golang.org/x/xerrors"
+)
+
+// URLGetter is implemented by objects that can perform HTTP GET requests
+// (e.g. http.Client).
+type URLGetter interface {
+	Get(url string) (*http.Response, error)
+}
+
+// defaultRedactedHeaders lists the response headers that are never written
+// to fixtures as they may contain credentials or session state.
+var defaultRedactedHeaders = []string{
+	"Set-Cookie",
+	"Authorization",
+	"Proxy-Authenticate",
+	"WWW-Authenticate",
+}
+
+// RecorderConfig encapsulates the configuration options for a Recorder.
+type RecorderConfig struct {
+	// Getter performs the actual requests.
+	Getter URLGetter
+
+	// Dir is the directory where fixtures are stored. It is created if it
+	// does not exist.
+	Dir string
+
+	// RedactHeaders lists additional response headers to be stripped from
+	// fixtures. The Set-Cookie and authentication headers are always
+	// stripped.
+	RedactHeaders []string
+
+	// SanitizeBody, if specified, is applied to each response body before
+	// it is written to disk. It can be used to scrub e-mail addresses,
+	// tokens or other sensitive content from the recorded pages.
+	SanitizeBody func(url string, body []byte) []byte
+}
+
+func (cfg *RecorderConfig) validate() error {
+	var err error
+	if cfg.Getter == nil {
+		err = xerrors.New("URL getter not specified")
+	} else if cfg.Dir == "" {
+		err = xerrors.New("fixture directory not specified")
+	}
+	return err
+}
+
+// Recorder is a URLGetter that performs requests through another URLGetter and
+// captures the sanitized responses to disk so they can be played back by a
+// Replayer.
+type Recorder struct {
+	cfg      RecorderConfig
+	redacted map[string]bool
+}
+
+// NewRecorder returns a new Recorder instance using the provided config.
+func NewRecorder(cfg RecorderConfig) (*Recorder, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("recorder config validation failed: %w", err)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, xerrors.Errorf("create fixture directory: %w", err)
+	}
+
+	redacted := make(map[string]bool)
+	for _, h := range append(defaultRedactedHeaders, cfg.RedactHeaders...) {
+		redacted[http.CanonicalHeaderKey(h)] = true
+	}
+	return &Recorder{cfg: cfg, redacted: redacted}, nil
+}
+
+// Get performs a GET request for url and records its outcome. Failed requests
+// are recorded as well so replays reproduce them. The returned response
+// carries the unsanitized body.
+func (r *Recorder) Get(url string) (*http.Response, error) {
+	res, reqErr := r.cfg.Getter.Get(url)
+	if reqErr != nil {
+		if err := writeFixture(r.cfg.Dir, &fixture{URL: url, Error: reqErr.Error()}); err != nil {
+			return nil, err
+		}
+		return nil, reqErr
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, xerrors.Errorf("record %q: read body: %w", url, err)
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	f := &fixture{
+		URL:        url,
+		StatusCode: res.StatusCode,
+		Header:     make(map[string][]string),
+		Body:       body,
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		f.FinalURL = res.Request.URL.String()
+	}
+	for name, values := range res.Header {
+		if !r.redacted[http.CanonicalHeaderKey(name)] {
+			f.Header[name] = append([]string(nil), values...)
+		}
+	}
+	if r.cfg.SanitizeBody != nil {
+		f.Body = r.cfg.SanitizeBody(url, append([]byte(nil), body...))
+	}
+
+	if err = writeFixture(r.cfg.Dir, f); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ReplayTestSuite))
+
+type ReplayTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *ReplayTestSuite) TestRecordAndReplay(c *gc.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Api-Key", "secret")
+		fmt.Fprintf(w, "<html>%s contact: admin@example.com</html>", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	dir := c.MkDir()
+	rec, err := NewRecorder(RecorderConfig{
+		Getter:        srv.Client(),
+		Dir:           dir,
+		RedactHeaders: []string{"x-api-key"},
+		SanitizeBody: func(_ string, body []byte) []byte {
+			return bytes.Replace(body, []byte("admin@example.com"), []byte("REDACTED"), -1)
+		},
+	})
+	c.Assert(err, gc.IsNil)
+
+	// The recorder returns the original response.
+	res, err := rec.Get(srv.URL + "/old")
+	c.Assert(err, gc.IsNil)
+	c.Assert(readBody(c, res), gc.Equals, "<html>/new contact: admin@example.com</html>")
+
+	// Shut down the server to ensure responses are served from disk.
+	srv.Close()
+	res, err = NewReplayer(dir).Get(srv.URL + "/old")
+	c.Assert(err, gc.IsNil)
+	c.Assert(res.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(res.Request.URL.String(), gc.Equals, srv.URL+"/new")
+	c.Assert(res.Header.Get("Content-Type"), gc.Equals, "text/html")
+	c.Assert(res.Header.Get("Set-Cookie"), gc.Equals, "")
+	c.Assert(res.Header.Get("X-Api-Key"), gc.Equals, "")
+	c.Assert(readBody(c, res), gc.Equals, "<html>/new contact: REDACTED</html>")
+}
+
+func (s *ReplayTestSuite) TestRecordAndReplayError(c *gc.C) {
+	dir := c.MkDir()
+	rec, err := NewRecorder(RecorderConfig{
+		Getter: failingGetter{},
+		Dir:    dir,
+	})
+	c.Assert(err, gc.IsNil)
+
+	_, err = rec.Get("http://example.com/")
+	c.Assert(err, gc.ErrorMatches, "connection refused")
+
+	_, err = NewReplayer(dir).Get("http://example.com/")
+	c.Assert(err, gc.ErrorMatches, "connection refused")
+}
+
+func (s *ReplayTestSuite) TestReplayMissingFixture(c *gc.C) {
+	_, err := NewReplayer(c.MkDir()).Get("http://example.com/")
+	c.Assert(xerrors.Is(err, ErrNoFixture), gc.Equals, true)
+}
+
+func (s *ReplayTestSuite) TestRecorderConfigValidation(c *gc.C) {
+	_, err := NewRecorder(RecorderConfig{Dir: c.MkDir()})
+	c.Assert(err, gc.ErrorMatches, ".*URL getter not specified")
+
+	_, err = NewRecorder(RecorderConfig{Getter: failingGetter{}})
+	c.Assert(err, gc.ErrorMatches, ".*fixture directory not specified")
+}
+
+type failingGetter struct{}
+
+func (failingGetter) Get(string) (*http.Response, error) {
+	return nil, xerrors.New("connection refused")
+}
+
+func readBody(c *gc.C, res *http.Response) string {
+	body, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	c.Assert(err, gc.IsNil)
+	return string(body)
+}
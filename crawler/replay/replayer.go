@@ -0,0 +1,64 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"golang.org/x/xerrors"
+)
+
+// Replayer is a URLGetter that serves responses previously captured by a
+// Recorder without touching the network. It allows crawler behavior to be
+// validated against realistic corpora deterministically.
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer returns a new Replayer instance that serves the fixtures stored
+// in dir.
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+// Get returns the response recorded for url. If the original request failed,
+// an error with the same message is returned instead. ErrNoFixture is
+// returned for URLs that were never recorded.
+func (r *Replayer) Get(rawURL string) (*http.Response, error) {
+	f, err := readFixture(r.dir, rawURL)
+	if err != nil {
+		return nil, err
+	} else if f.Error != "" {
+		return nil, errors.New(f.Error)
+	}
+
+	// Attach a request for the final URL so that consumers can tell where
+	// any redirects led.
+	reqURL := f.FinalURL
+	if reqURL == "" {
+		reqURL = rawURL
+	}
+	u, err := url.Parse(reqURL)
+	if err != nil {
+		return nil, xerrors.Errorf("replay %q: %w", rawURL, err)
+	}
+
+	res := &http.Response{
+		Status:        strconv.Itoa(f.StatusCode) + " " + http.StatusText(f.StatusCode),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       &http.Request{Method: http.MethodGet, URL: u, Header: make(http.Header)},
+	}
+	for name, values := range f.Header {
+		res.Header[name] = values
+	}
+	return res, nil
+}
@@ -0,0 +1,173 @@
+// Package rules implements a URL rules engine for the crawler. Rules are
+// matched against the URLs of the links extracted from crawled pages and can
+// deny links, strip query parameters (e.g. tracking parameters such as
+// utm_source) or adjust the crawl priority of links. Rules are typically
+// loaded from a JSON file so that they can be configured per deployment.
+package rules
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+// Rule describes a set of actions that are applied to the URLs matched by
+// the rule. Exactly one of URL and Regex must be specified.
+type Rule struct {
+	// URL is a glob pattern that must match the entire URL. A '*'
+	// matches any sequence of characters (including '/') and a '?'
+	// matches a single character.
+	URL string `json:"url,omitempty"`
+
+	// Regex is a regular expression that must match part of the URL.
+	Regex string `json:"regex,omitempty"`
+
+	// Deny drops the matched URLs; no further rules are evaluated.
+	Deny bool `json:"deny,omitempty"`
+
+	// StripQuery lists glob patterns for the names of the query
+	// parameters that are removed from the matched URLs.
+	StripQuery []string `json:"strip_query,omitempty"`
+
+	// Priority is added to the crawl priority of the matched URLs.
+	// Negative values cause links to be crawled after other links.
+	Priority int `json:"priority,omitempty"`
+}
+
+// Result is the outcome of evaluating the rules against a URL.
+type Result struct {
+	// URL is the URL after all transformations have been applied.
+	URL string
+
+	Denied   bool
+	Priority int
+}
+
+// Engine evaluates a list of rules. It is safe for concurrent use.
+type Engine struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	match      *regexp.Regexp
+	stripQuery []*regexp.Regexp
+}
+
+// New compiles rules into an Engine.
+func New(rules []Rule) (*Engine, error) {
+	e := &Engine{rules: make([]compiledRule, len(rules))}
+	for i, rule := range rules {
+		cr, err := compile(rule)
+		if err != nil {
+			return nil, xerrors.Errorf("rule %d: %w", i, err)
+		}
+		e.rules[i] = cr
+	}
+	return e, nil
+}
+
+// Load reads a JSON array of rules from r and compiles them into an Engine.
+func Load(r io.Reader) (*Engine, error) {
+	var list []Rule
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, xerrors.Errorf("load rules: %w", err)
+	}
+	return New(list)
+}
+
+func compile(rule Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: rule}
+	switch {
+	case rule.URL != "" && rule.Regex != "":
+		return cr, xerrors.New("only one of url and regex may be specified")
+	case rule.URL != "":
+		cr.match = globRegexp(rule.URL)
+	case rule.Regex != "":
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return cr, xerrors.Errorf("invalid regex: %w", err)
+		}
+		cr.match = re
+	default:
+		return cr, xerrors.New("url or regex must be specified")
+	}
+
+	for _, name := range rule.StripQuery {
+		cr.stripQuery = append(cr.stripQuery, globRegexp(name))
+	}
+	return cr, nil
+}
+
+// globRegexp converts a glob pattern to an anchored regular expression.
+func globRegexp(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// Apply evaluates the rules in order against u. Transformations applied by a
+// rule are visible to the rules that follow it. The provided URL is not
+// modified.
+func (e *Engine) Apply(u *url.URL) Result {
+	cur := *u
+	res := Result{URL: cur.String()}
+	for _, rule := range e.rules {
+		if !rule.match.MatchString(res.URL) {
+			continue
+		}
+		if rule.Deny {
+			res.Denied = true
+			return res
+		}
+		if len(rule.stripQuery) != 0 && cur.RawQuery != "" {
+			cur.RawQuery = stripQuery(cur.RawQuery, rule.stripQuery)
+			res.URL = cur.String()
+		}
+		res.Priority += rule.Priority
+	}
+	return res
+}
+
+// stripQuery removes the parameters whose name matches any of the patterns
+// from rawQuery while preserving the order of the remaining parameters.
+func stripQuery(rawQuery string, patterns []*regexp.Regexp) string {
+	var kept []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		name := param
+		if eq := strings.IndexByte(param, '='); eq != -1 {
+			name = param[:eq]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !matchesAny(name, patterns) {
+			kept = append(kept, param)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package rules
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RulesTestSuite))
+
+type RulesTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *RulesTestSuite) TestApply(c *gc.C) {
+	e, err := Load(strings.NewReader(`[
+		{"url": "*", "strip_query": ["utm_*", "fbclid"]},
+		{"url": "*/tag/*", "priority": -10},
+		{"regex": "^https?://(www\\.)?example\\.com/admin", "deny": true},
+		{"url": "https://docs.example.com/*", "priority": 5},
+		{"url": "*?print=1", "deny": true}
+	]`))
+	c.Assert(err, gc.IsNil)
+
+	specs := []struct {
+		in  string
+		exp Result
+	}{
+		{
+			in:  "https://example.com/post?utm_source=x&id=1&utm_medium=y&fbclid=z",
+			exp: Result{URL: "https://example.com/post?id=1"},
+		},
+		{
+			in:  "https://example.com/tag/go?utm_campaign=x",
+			exp: Result{URL: "https://example.com/tag/go", Priority: -10},
+		},
+		{
+			in:  "https://docs.example.com/tag/go",
+			exp: Result{URL: "https://docs.example.com/tag/go", Priority: -5},
+		},
+		{
+			in:  "http://www.example.com/admin/users",
+			exp: Result{URL: "http://www.example.com/admin/users", Denied: true},
+		},
+		{
+			// Denial rules see the URL after the tracking parameters
+			// have been stripped.
+			in:  "https://example.com/post?print=1&utm_source=x",
+			exp: Result{URL: "https://example.com/post?print=1", Denied: true},
+		},
+	}
+	for i, spec := range specs {
+		u, err := url.Parse(spec.in)
+		c.Assert(err, gc.IsNil)
+		c.Assert(e.Apply(u), gc.DeepEquals, spec.exp, gc.Commentf("spec %d", i))
+		c.Assert(u.String(), gc.Equals, spec.in, gc.Commentf("input URL must not be modified"))
+	}
+}
+
+func (s *RulesTestSuite) TestInvalidRules(c *gc.C) {
+	_, err := New([]Rule{{Deny: true}})
+	c.Assert(err, gc.ErrorMatches, "rule 0: url or regex must be specified")
+
+	_, err = New([]Rule{{URL: "*"}, {URL: "*", Regex: ".*"}})
+	c.Assert(err, gc.ErrorMatches, "rule 1: only one of url and regex may be specified")
+
+	_, err = New([]Rule{{Regex: "("}})
+	c.Assert(err, gc.ErrorMatches, "rule 0: invalid regex: .*")
+
+	_, err = Load(strings.NewReader(`{"url": "*"}`))
+	c.Assert(err, gc.ErrorMatches, "load rules: .*")
+}
@@ -0,0 +1,106 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// window is a recurring daily time window during which no crawling should
+// take place.
+type window struct {
+	spec string
+
+	// weekdays is a bit set of the days on which the window starts.
+	weekdays uint64
+
+	// start and end are offsets from midnight. Windows whose end is not
+	// after their start wrap around midnight.
+	start, end time.Duration
+}
+
+// parseWindow parses a blackout window of the form "[days] HH:MM-HH:MM"
+// where days is an optional list or range of weekdays such as "Mon-Fri" or
+// "Sat,Sun". Windows may wrap around midnight (e.g. "22:00-06:00"), in which
+// case they belong to the day on which they start.
+func parseWindow(spec string) (*window, error) {
+	parts := strings.Fields(spec)
+	if len(parts) == 0 || len(parts) > 2 {
+		return nil, xerrors.Errorf("blackout window %q: expected \"[days] HH:MM-HH:MM\"", spec)
+	}
+
+	w := &window{spec: spec, weekdays: 0x7f}
+	if len(parts) == 2 {
+		weekdays, err := parseCronField(parts[0], cronFields[4])
+		if err != nil {
+			return nil, xerrors.Errorf("blackout window %q: %w", spec, err)
+		}
+		if weekdays&(1<<7) != 0 {
+			weekdays |= 1
+		}
+		w.weekdays = weekdays & 0x7f
+	}
+
+	times := strings.SplitN(parts[len(parts)-1], "-", 2)
+	if len(times) != 2 {
+		return nil, xerrors.Errorf("blackout window %q: expected a time range", spec)
+	}
+	var err error
+	if w.start, err = parseTimeOfDay(times[0]); err != nil {
+		return nil, xerrors.Errorf("blackout window %q: %w", spec, err)
+	}
+	if w.end, err = parseTimeOfDay(times[1]); err != nil {
+		return nil, xerrors.Errorf("blackout window %q: %w", spec, err)
+	}
+	if w.end <= w.start {
+		w.end += 24 * time.Hour
+	}
+	return w, nil
+}
+
+func parseTimeOfDay(spec string) (time.Duration, error) {
+	hm := strings.SplitN(spec, ":", 2)
+	if len(hm) != 2 {
+		return 0, xerrors.Errorf("invalid time %q", spec)
+	}
+	h, errH := strconv.Atoi(hm[0])
+	m, errM := strconv.Atoi(hm[1])
+	if errH != nil || errM != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, xerrors.Errorf("invalid time %q", spec)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// occurrence returns the start and end of the window's occurrence that starts
+// on day, if there is one.
+func (w *window) occurrence(day time.Time) (time.Time, time.Time, bool) {
+	if !has(w.weekdays, int(day.Weekday())) {
+		return time.Time{}, time.Time{}, false
+	}
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return midnight.Add(w.start), midnight.Add(w.end), true
+}
+
+// contains returns true and the end of the blackout if t falls within the
+// window.
+func (w *window) contains(t time.Time) (time.Time, bool) {
+	// Occurrences that started on the previous day may still be active
+	for _, day := range []time.Time{t.AddDate(0, 0, -1), t} {
+		if start, end, ok := w.occurrence(day); ok && !t.Before(start) && t.Before(end) {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextStart returns the start of the first occurrence of the window after t.
+func (w *window) nextStart(t time.Time) time.Time {
+	for i := 0; i <= 7; i++ {
+		if start, _, ok := w.occurrence(t.AddDate(0, 0, i)); ok && start.After(t) {
+			return start
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// maxSearchYears bounds the search for the next activation of a cron
+// expression so that expressions that can never fire (e.g. "0 0 30 2 *")
+// do not loop forever.
+const maxSearchYears = 5
+
+var (
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// cronField describes the range of values accepted by a cron field.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	// 7 is accepted as an alias for Sunday
+	{name: "day of week", min: 0, max: 7, names: dayNames},
+}
+
+// Cron is a parsed cron expression in the standard five-field format
+// (minute, hour, day of month, month and day of week).
+type Cron struct {
+	expr string
+
+	minutes, hours, days, months, weekdays uint64
+
+	// Following cron(8), if both the day of month and the day of week are
+	// restricted, a day matches if either of them matches.
+	daysRestricted, weekdaysRestricted bool
+}
+
+// ParseCron parses a cron expression. Fields support lists, ranges, steps
+// and month/day names (e.g. "*/15 9-17 * * MON-FRI"). The @yearly, @monthly,
+// @weekly, @daily and @hourly macros are also supported.
+func ParseCron(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, found := cronMacros[strings.ToLower(spec)]; found {
+		spec = macro
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, xerrors.Errorf("cron expression %q: expected %d fields; got %d", expr, len(cronFields), len(parts))
+	}
+
+	var (
+		sets [5]uint64
+		err  error
+	)
+	for i, part := range parts {
+		if sets[i], err = parseCronField(part, cronFields[i]); err != nil {
+			return nil, xerrors.Errorf("cron expression %q: %w", expr, err)
+		}
+	}
+
+	// Fold Sunday=7 into Sunday=0
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &Cron{
+		expr:               expr,
+		minutes:            sets[0],
+		hours:              sets[1],
+		days:               sets[2],
+		months:             sets[3],
+		weekdays:           sets[4],
+		daysRestricted:     parts[2] != "*",
+		weekdaysRestricted: parts[4] != "*",
+	}, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (c *Cron) String() string { return c.expr }
+
+// Next returns the first activation time strictly after t, in the location of
+// t. It returns the zero time if the expression never fires.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !has(c.months, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(c.hours, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(c.minutes, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dayMatch, weekdayMatch := has(c.days, t.Day()), has(c.weekdays, int(t.Weekday()))
+	if c.daysRestricted && c.weekdaysRestricted {
+		return dayMatch || weekdayMatch
+	}
+	return dayMatch && weekdayMatch
+}
+
+func has(set uint64, v int) bool { return set&(1<<uint(v)) != 0 }
+
+// parseCronField parses a comma-separated list of values, ranges and steps
+// into a bit set.
+func parseCronField(spec string, field cronField) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(spec, ",") {
+		rangeSpec, step := item, 1
+		if idx := strings.IndexByte(item, '/'); idx >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[idx+1:]); err != nil || step <= 0 {
+				return 0, xerrors.Errorf("invalid step in %s field %q", field.name, item)
+			}
+			rangeSpec = item[:idx]
+		}
+
+		lo, hi := field.min, field.max
+		if rangeSpec != "*" {
+			bounds := strings.SplitN(rangeSpec, "-", 2)
+			var err error
+			if lo, err = parseCronValue(bounds[0], field); err != nil {
+				return 0, err
+			}
+			switch {
+			case len(bounds) == 2:
+				if hi, err = parseCronValue(bounds[1], field); err != nil {
+					return 0, err
+				}
+			case step == 1:
+				hi = lo
+			}
+			// a value with a step (e.g. 5/15) ranges up to the maximum
+			if hi < lo {
+				return 0, xerrors.Errorf("invalid range in %s field %q", field.name, item)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(spec string, field cronField) (int, error) {
+	for i, name := range field.names {
+		if strings.EqualFold(spec, name) {
+			return i + field.min, nil
+		}
+	}
+
+	v, err := strconv.Atoi(spec)
+	if err != nil || v < field.min || v > field.max {
+		return 0, xerrors.Errorf("invalid value %q for %s field", spec, field.name)
+	}
+	return v, nil
+}
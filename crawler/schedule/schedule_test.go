@@ -0,0 +1,192 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ScheduleTestSuite))
+
+type ScheduleTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+// 2020-01-06 is a Monday.
+var monday = time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+
+func at(day, hour, min int) time.Time {
+	return monday.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute)
+}
+
+func (s *ScheduleTestSuite) TestCronNext(c *gc.C) {
+	specs := []struct {
+		expr string
+		from time.Time
+		exp  time.Time
+	}{
+		{"* * * * *", at(0, 10, 30).Add(15 * time.Second), at(0, 10, 31)},
+		{"*/15 * * * *", at(0, 10, 30), at(0, 10, 45)},
+		{"0 9-17/4 * * *", at(0, 13, 0), at(0, 17, 0)},
+		{"30 2 * * SAT,sun", at(0, 0, 0), at(5, 2, 30)},
+		{"0 0 * * 7", at(0, 0, 0), at(6, 0, 0)},
+		{"@daily", at(0, 0, 0), at(1, 0, 0)},
+		{"0 0 1 FEB *", at(0, 0, 0), time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// Day of month and day of week are OR-ed when both are restricted
+		{"0 0 13 * FRI", at(0, 0, 0), at(4, 0, 0)},
+		{"0 0 30 2 *", at(0, 0, 0), time.Time{}},
+	}
+
+	for i, spec := range specs {
+		cron, err := ParseCron(spec.expr)
+		c.Assert(err, gc.IsNil, gc.Commentf("spec %d", i))
+		c.Assert(cron.Next(spec.from), gc.DeepEquals, spec.exp, gc.Commentf("spec %d: %s", i, spec.expr))
+	}
+}
+
+func (s *ScheduleTestSuite) TestParseCronErrors(c *gc.C) {
+	specs := []struct {
+		expr   string
+		expErr string
+	}{
+		{"* * * *", `.*expected 5 fields; got 4`},
+		{"60 * * * *", `.*invalid value "60" for minute field`},
+		{"*/0 * * * *", `.*invalid step in minute field "\*/0"`},
+		{"* 5-2 * * *", `.*invalid range in hour field "5-2"`},
+		{"* * * FOO *", `.*invalid value "FOO" for month field`},
+	}
+	for i, spec := range specs {
+		_, err := ParseCron(spec.expr)
+		c.Assert(err, gc.ErrorMatches, spec.expErr, gc.Commentf("spec %d", i))
+	}
+}
+
+func (s *ScheduleTestSuite) TestBlackouts(c *gc.C) {
+	sched, err := New(Config{
+		Schedule:  "0 * * * *",
+		Blackouts: []string{"Mon-Fri 09:00-17:00", "22:00-06:00"},
+		Location:  time.UTC,
+	})
+	c.Assert(err, gc.IsNil)
+
+	// Hourly passes are skipped during business hours and at night
+	c.Assert(sched.Next(at(0, 6, 30)).At, gc.DeepEquals, at(0, 7, 0))
+	c.Assert(sched.Next(at(0, 8, 0)).At, gc.DeepEquals, at(0, 17, 0))
+	c.Assert(sched.Next(at(0, 21, 0)).At, gc.DeepEquals, at(1, 6, 0))
+
+	// Business hour blackouts do not apply on weekends
+	c.Assert(sched.Next(at(5, 9, 30)).At, gc.DeepEquals, at(5, 10, 0))
+
+	c.Assert(sched.nextBlackout(at(0, 7, 0)), gc.DeepEquals, at(0, 9, 0))
+	c.Assert(sched.nextBlackout(at(4, 18, 0)), gc.DeepEquals, at(4, 22, 0))
+}
+
+func (s *ScheduleTestSuite) TestDomainOverrides(c *gc.C) {
+	sched, err := New(Config{
+		Schedule: "0 0 * * *",
+		DomainSchedules: map[string]string{
+			"news.example.com": "0 */6 * * *",
+			"Example.org.":     "0 0 * * MON",
+		},
+		Location: time.UTC,
+	})
+	c.Assert(err, gc.IsNil)
+
+	// Only the override fires
+	pass := sched.Next(at(0, 1, 0))
+	c.Assert(pass.At, gc.DeepEquals, at(0, 6, 0))
+	c.Assert(pass.Domains, gc.DeepEquals, []string{"news.example.com"})
+	c.Assert(pass.Includes("news.example.com"), gc.Equals, true)
+	c.Assert(pass.Includes("sub.news.example.com"), gc.Equals, true)
+	c.Assert(pass.Includes("example.com"), gc.Equals, false)
+
+	// The default schedule and one override fire together; the other
+	// override is excluded
+	pass = sched.Next(at(1, 23, 0))
+	c.Assert(pass.At, gc.DeepEquals, at(2, 0, 0))
+	c.Assert(pass.Domains, gc.HasLen, 0)
+	c.Assert(pass.Excluded, gc.DeepEquals, []string{"example.org"})
+	c.Assert(pass.Includes("www.example.org"), gc.Equals, false)
+	c.Assert(pass.Includes("news.example.com"), gc.Equals, true)
+	c.Assert(pass.Includes("example.com"), gc.Equals, true)
+}
+
+func (s *ScheduleTestSuite) TestFilterLinks(c *gc.C) {
+	pass := Pass{Excluded: []string{"example.org"}}
+	it := pass.FilterLinks(&sliceLinkIterator{links: []*graph.Link{
+		{URL: "http://example.com/a"},
+		{URL: "https://www.example.org:8080/b"},
+		{URL: "http://example.net/c"},
+	}})
+
+	var urls []string
+	for it.Next() {
+		urls = append(urls, it.Link().URL)
+	}
+	c.Assert(urls, gc.DeepEquals, []string{"http://example.com/a", "http://example.net/c"})
+}
+
+func (s *ScheduleTestSuite) TestRun(c *gc.C) {
+	now := at(0, 8, 59)
+	sched, err := New(Config{
+		Schedule:  "*/30 * * * *",
+		Blackouts: []string{"10:00-11:00"},
+		Location:  time.UTC,
+		Clock:     func() time.Time { return now },
+	})
+	c.Assert(err, gc.IsNil)
+
+	// Advance the fake clock instead of sleeping
+	sched.after = func(d time.Duration) <-chan time.Time {
+		now = now.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- now
+		return ch
+	}
+
+	var passes []time.Time
+	err = sched.Run(context.TODO(), func(ctx context.Context, pass Pass) error {
+		passes = append(passes, pass.At)
+
+		// Passes are stopped when the next blackout starts
+		_, hasDeadline := ctx.Deadline()
+		c.Assert(hasDeadline, gc.Equals, true)
+		c.Assert(ctx.Err(), gc.IsNil)
+		if len(passes) == 3 {
+			return xerrors.New("crawl failed")
+		}
+		return nil
+	})
+	c.Assert(err, gc.ErrorMatches, "crawl failed")
+	c.Assert(passes, gc.DeepEquals, []time.Time{at(0, 9, 0), at(0, 9, 30), at(0, 11, 0)})
+}
+
+func (s *ScheduleTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := New(Config{})
+	c.Assert(err, gc.ErrorMatches, ".*schedule not specified")
+	_, err = New(Config{Schedule: "@daily", Blackouts: []string{"Mon-Fri 9-17"}})
+	c.Assert(err, gc.ErrorMatches, `.*blackout window "Mon-Fri 9-17": invalid time "9"`)
+	_, err = New(Config{Schedule: "@daily", DomainSchedules: map[string]string{"example.com": "@never"}})
+	c.Assert(err, gc.ErrorMatches, `.*domain "example.com": cron expression "@never": expected 5 fields; got 1`)
+}
+
+type sliceLinkIterator struct {
+	links []*graph.Link
+	cur   int
+}
+
+func (it *sliceLinkIterator) Next() bool {
+	if it.cur >= len(it.links) {
+		return false
+	}
+	it.cur++
+	return true
+}
+
+func (it *sliceLinkIterator) Link() *graph.Link { return it.links[it.cur-1] }
+func (it *sliceLinkIterator) Error() error      { return nil }
+func (it *sliceLinkIterator) Close() error      { return nil }
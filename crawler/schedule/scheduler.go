@@ -0,0 +1,286 @@
+// Package schedule decides when the crawler runs. Crawl passes are triggered
+// by cron expressions, can be suppressed during blackout windows (e.g. to
+// avoid crawling during business hours) and individual domains can be
+// crawled on their own schedule.
+package schedule
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"golang.org/x/xerrors"
+)
+
+// Config encapsulates the configuration options for a Scheduler.
+type Config struct {
+	// Schedule is the cron expression that triggers crawl passes over
+	// all domains that do not have a schedule override.
+	Schedule string
+
+	// Blackouts lists the daily windows during which no passes are
+	// started, in the form "[days] HH:MM-HH:MM" (e.g. "Mon-Fri
+	// 09:00-17:00" or "22:00-06:00"). Passes that are still running when
+	// a blackout starts are stopped.
+	Blackouts []string
+
+	// DomainSchedules overrides the schedule for specific domains. Keys
+	// are domain names and match subdomains as well; values are cron
+	// expressions.
+	DomainSchedules map[string]string
+
+	// Location is the time zone in which the schedules and blackout
+	// windows are evaluated. If not specified, the local time zone will
+	// be used.
+	Location *time.Location
+
+	// Clock returns the current time. If not specified, time.Now will be
+	// used.
+	Clock func() time.Time
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.Schedule == "" {
+		err = xerrors.New("schedule not specified")
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return err
+}
+
+// Pass describes a crawl pass triggered by the scheduler.
+type Pass struct {
+	// At is the time the pass was scheduled for.
+	At time.Time
+
+	// Domains, if not empty, restricts the pass to the listed domains
+	// because only their schedule overrides fired.
+	Domains []string
+
+	// Excluded lists the domains that must be skipped because they are
+	// crawled on their own schedule.
+	Excluded []string
+}
+
+// Includes returns true if links with the specified host should be crawled
+// in this pass.
+func (p Pass) Includes(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if len(p.Domains) != 0 {
+		return matchesAny(host, p.Domains)
+	}
+	return !matchesAny(host, p.Excluded)
+}
+
+// FilterLinks wraps it so that it only yields the links included in the
+// pass.
+func (p Pass) FilterLinks(it graph.LinkIterator) graph.LinkIterator {
+	if len(p.Domains) == 0 && len(p.Excluded) == 0 {
+		return it
+	}
+	return &passLinkIterator{LinkIterator: it, pass: p}
+}
+
+func matchesAny(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+type domainSchedule struct {
+	domain string
+	cron   *Cron
+}
+
+// Scheduler triggers crawl passes according to its configuration.
+type Scheduler struct {
+	cfg       Config
+	schedule  *Cron
+	overrides []domainSchedule
+	blackouts []*window
+
+	// after is used for waiting until the next pass and can be
+	// overridden by tests.
+	after func(time.Duration) <-chan time.Time
+}
+
+// New creates a new Scheduler using the provided config.
+func New(cfg Config) (*Scheduler, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("scheduler config validation failed: %w", err)
+	}
+
+	sched, err := ParseCron(cfg.Schedule)
+	if err != nil {
+		return nil, xerrors.Errorf("scheduler config validation failed: %w", err)
+	}
+	s := &Scheduler{cfg: cfg, schedule: sched, after: time.After}
+
+	for domain, expr := range cfg.DomainSchedules {
+		domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if domain == "" {
+			return nil, xerrors.New("scheduler config validation failed: empty domain in schedule overrides")
+		}
+		if sched, err = ParseCron(expr); err != nil {
+			return nil, xerrors.Errorf("scheduler config validation failed: domain %q: %w", domain, err)
+		}
+		s.overrides = append(s.overrides, domainSchedule{domain: domain, cron: sched})
+	}
+	sort.Slice(s.overrides, func(i, j int) bool { return s.overrides[i].domain < s.overrides[j].domain })
+
+	for _, spec := range cfg.Blackouts {
+		w, err := parseWindow(spec)
+		if err != nil {
+			return nil, xerrors.Errorf("scheduler config validation failed: %w", err)
+		}
+		s.blackouts = append(s.blackouts, w)
+	}
+	return s, nil
+}
+
+// Next returns the first pass scheduled after t. If the default schedule and
+// some overrides fire at the same time, they are merged into a single pass.
+// The returned pass has a zero At field if no schedule ever fires.
+func (s *Scheduler) Next(t time.Time) Pass {
+	t = t.In(s.cfg.Location)
+	next := s.nextActivation(s.schedule, t)
+	overrideNext := make([]time.Time, len(s.overrides))
+	for i, o := range s.overrides {
+		overrideNext[i] = s.nextActivation(o.cron, t)
+		if next.IsZero() || (!overrideNext[i].IsZero() && overrideNext[i].Before(next)) {
+			next = overrideNext[i]
+		}
+	}
+
+	pass := Pass{At: next}
+	if next.IsZero() {
+		return pass
+	}
+
+	defaultFires := s.nextActivation(s.schedule, t).Equal(next)
+	for i, o := range s.overrides {
+		fires := overrideNext[i].Equal(next)
+		switch {
+		case defaultFires && !fires:
+			pass.Excluded = append(pass.Excluded, o.domain)
+		case !defaultFires && fires:
+			pass.Domains = append(pass.Domains, o.domain)
+		}
+	}
+	return pass
+}
+
+// nextActivation returns the first activation of c after t that does not fall
+// within a blackout window.
+func (s *Scheduler) nextActivation(c *Cron, t time.Time) time.Time {
+	next := c.Next(t)
+	for !next.IsZero() {
+		end, blackedOut := s.blackoutEnd(next)
+		if !blackedOut {
+			break
+		}
+		next = c.Next(end.Add(-time.Nanosecond))
+	}
+	return next
+}
+
+// blackoutEnd returns true and the time at which the blackout ends if t falls
+// within a blackout window. Overlapping windows are merged.
+func (s *Scheduler) blackoutEnd(t time.Time) (time.Time, bool) {
+	var (
+		end   time.Time
+		found bool
+	)
+	for changed := true; changed; {
+		changed = false
+		probe := t
+		if found {
+			probe = end
+		}
+		for _, w := range s.blackouts {
+			if wEnd, ok := w.contains(probe); ok && wEnd.After(end) {
+				end, found, changed = wEnd, true, true
+			}
+		}
+	}
+	return end, found
+}
+
+// nextBlackout returns the start of the first blackout window after t or the
+// zero time if no blackout windows are configured.
+func (s *Scheduler) nextBlackout(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range s.blackouts {
+		if start := w.nextStart(t); !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return next
+}
+
+// Run waits for each scheduled pass and invokes crawlFn for it until ctx
+// expires or crawlFn returns an error. The context passed to crawlFn expires
+// when the next blackout window starts. Passes that are missed because a
+// previous pass was still running are skipped.
+func (s *Scheduler) Run(ctx context.Context, crawlFn func(context.Context, Pass) error) error {
+	for {
+		now := s.cfg.Clock()
+		pass := s.Next(now)
+		if pass.At.IsZero() {
+			return xerrors.New("schedule never fires")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.after(pass.At.Sub(now)):
+		}
+
+		passCtx, cancel := ctx, context.CancelFunc(func() {})
+		if blackout := s.nextBlackout(pass.At); !blackout.IsZero() {
+			passCtx, cancel = context.WithTimeout(ctx, blackout.Sub(s.cfg.Clock()))
+		}
+		err := crawlFn(passCtx, pass)
+		// Errors caused by the pass being stopped are not reported
+		stopped := passCtx.Err() != nil
+		cancel()
+		if err != nil && !stopped {
+			return err
+		}
+	}
+}
+
+// passLinkIterator filters the links yielded by a graph.LinkIterator
+// according to a Pass.
+type passLinkIterator struct {
+	graph.LinkIterator
+	pass Pass
+}
+
+func (it *passLinkIterator) Next() bool {
+	for it.LinkIterator.Next() {
+		if it.pass.Includes(hostOf(it.LinkIterator.Link().URL)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,190 @@
+package crawler
+
+import (
+	"context"
+	"hash/fnv"
+	"html"
+	"io"
+	"io/ioutil"
+	"math/bits"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+//soft404Phrases are matched against the title and the text of pages that are
+//served with a 2xx status but tell the visitor that the page does not exist
+var soft404Phrases = []string{
+	"page not found",
+	"404 not found",
+	"error 404",
+	"404 error",
+	"page does not exist",
+	"page doesn't exist",
+	"page could not be found",
+	"page cannot be found",
+	"page you requested could not be found",
+	"no longer available",
+}
+
+const (
+	//maxSoft404TextLength is the length of the text above which a page is
+	//considered to have real content even if it mentions one of the
+	//soft404Phrases; titles are always checked
+	maxSoft404TextLength = 2000
+
+	//maxSoft404Distance is the maximum number of bits by which the
+	//fingerprints of a page and of the probe of its host may differ for the
+	//page to be classified as a soft-404
+	maxSoft404Distance = 3
+
+	//maxProbeBodySize caps the number of bytes read from a probe response
+	maxProbeBodySize = 1 << 20
+
+	//soft404ProbeTTL is the time after which a host is probed again
+	soft404ProbeTTL = 24 * time.Hour
+)
+
+//hostProbe records how a host responded to a request for a page that does
+//not exist
+type hostProbe struct {
+	probedAt time.Time
+
+	//soft is true if the host responded with a 2xx status and an HTML page
+	soft bool
+
+	//fingerprint is the simhash of the text of the probe response and
+	//target is the URL the probe was redirected to, if any
+	fingerprint uint64
+	target      string
+}
+
+//soft404Detector flags pages that are served with a 2xx status even though
+//they are "page not found" pages so they are not indexed.  Pages are flagged if
+//their title or (short) text contains one of the soft404Phrases or, if a URL
+//getter is set, if their text is nearly identical to the page the host serves
+//for a random URL that does not exist.  The detector runs as a FIFO stage and
+//is not safe for concurrent use
+type soft404Detector struct {
+	urlGetter URLGetter
+	policy    *bluemonday.Policy
+	probes    map[string]hostProbe
+	now       func() time.Time
+}
+
+func newSoft404Detector(urlGetter URLGetter) *soft404Detector {
+	return &soft404Detector{
+		urlGetter: urlGetter,
+		policy:    bluemonday.StrictPolicy(),
+		probes:    make(map[string]hostProbe),
+		now:       time.Now,
+	}
+}
+
+func (d *soft404Detector) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	payload := p.(*Payload)
+	payload.Soft404 = hasSoft404Phrase(payload.Title, payload.TextContent) || d.matchesProbe(payload)
+	return payload, nil
+}
+
+func hasSoft404Phrase(title, text string) bool {
+	title = strings.ToLower(title)
+	text = strings.ToLower(text)
+	for _, phrase := range soft404Phrases {
+		if strings.Contains(title, phrase) || (len(text) <= maxSoft404TextLength && strings.Contains(text, phrase)) {
+			return true
+		}
+	}
+	return false
+}
+
+//matchesProbe returns true if the text of the page is a near-duplicate of the
+//page that its host serves for URLs that do not exist
+func (d *soft404Detector) matchesProbe(payload *Payload) bool {
+	if d.urlGetter == nil {
+		return false
+	}
+
+	pageURL := payload.FinalURL
+	if pageURL == "" {
+		pageURL = payload.URL
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	probe := d.probe(u.Scheme, u.Host)
+	//hosts that redirect unknown URLs to an existing page (e.g. the home
+	//page) would otherwise get that page classified as a soft-404
+	if !probe.soft || (probe.target != "" && probe.target == pageURL) {
+		return false
+	}
+	return bits.OnesCount64(simhash(payload.TextContent)^probe.fingerprint) <= maxSoft404Distance
+}
+
+//probe returns the cached probe for the host, requesting a random URL from
+//the host if it has not been probed yet or the probe has expired
+func (d *soft404Detector) probe(scheme, host string) hostProbe {
+	key := scheme + "://" + strings.ToLower(host)
+	now := d.now()
+	if probe, found := d.probes[key]; found && now.Sub(probe.probedAt) < soft404ProbeTTL {
+		return probe
+	}
+
+	probe := hostProbe{probedAt: now}
+	probeURL := key + "/" + uuid.New().String()
+	if res, err := d.urlGetter.Get(probeURL); err == nil {
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxProbeBodySize))
+		_ = res.Body.Close()
+
+		if err == nil && res.StatusCode >= 200 && res.StatusCode <= 299 && strings.Contains(res.Header.Get("Content-Type"), "html") {
+			probe.soft = true
+			probe.fingerprint = simhash(html.UnescapeString(d.policy.Sanitize(string(body))))
+			if res.Request != nil && res.Request.URL != nil && res.Request.URL.String() != probeURL {
+				probe.target = res.Request.URL.String()
+			}
+		}
+	}
+
+	d.probes[key] = probe
+	return probe
+}
+
+//simhash returns a 64-bit fingerprint of text computed over overlapping
+//three-word shingles.  The fingerprints of similar texts differ in few bits
+func simhash(text string) uint64 {
+	const shingleSize = 3
+
+	words := strings.Fields(strings.ToLower(text))
+	var weights [64]int
+	for i := 0; i == 0 || i+shingleSize <= len(words); i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(strings.Join(words[i:end], " ")))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint
+}
@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/crawler/mocks"
+	"github.com/golang/mock/gomock"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(Soft404TestSuite))
+
+type Soft404TestSuite struct{}
+
+const soft404Body = `<html><body><h1>Oops!</h1>
+<p>We looked everywhere but the page you are
+looking for has gone missing. Try the search box above or head back to the home
+page to find what you need.</p></body></html>`
+
+const soft404Text = "Oops! We looked everywhere but the page you are looking for has gone missing. " +
+	"Try the search box above or head back to the home page to find what you need."
+
+func (s *Soft404TestSuite) TestPhrases(c *gc.C) {
+	d := newSoft404Detector(nil)
+	specs := []struct {
+		title, text string
+		exp         bool
+	}{
+		{title: "Page Not Found | Example", exp: true},
+		{title: "Example", text: "Sorry, this page does not exist.", exp: true},
+		{title: "Gophers", text: "Gophers are small burrowing rodents.", exp: false},
+		// Long pages that mention a phrase have real content
+		{title: "Changelog", text: "The v1 API is no longer available. " + strings.Repeat("lorem ipsum ", 200), exp: false},
+	}
+
+	for i, spec := range specs {
+		p := &Payload{URL: "http://example.com/", Title: spec.title, TextContent: spec.text}
+		_, err := d.Process(context.TODO(), p)
+		c.Assert(err, gc.IsNil)
+		c.Assert(p.Soft404, gc.Equals, spec.exp, gc.Commentf("spec %d", i))
+	}
+}
+
+func (s *Soft404TestSuite) TestProbeSimilarity(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	urlGetter := mocks.NewMockURLGetter(ctrl)
+
+	// The probe is only sent once per host until it expires
+	urlGetter.EXPECT().Get(gomock.Any()).DoAndReturn(func(string) (*http.Response, error) {
+		return makeResponse(200, soft404Body, "text/html"), nil
+	}).Times(2)
+
+	now := time.Now()
+	d := newSoft404Detector(urlGetter)
+	d.now = func() time.Time { return now }
+
+	missing := &Payload{
+		URL:         "http://example.com/old-post",
+		TextContent: soft404Text,
+	}
+	_, err := d.Process(context.TODO(), missing)
+	c.Assert(err, gc.IsNil)
+	c.Assert(missing.Soft404, gc.Equals, true)
+
+	found := &Payload{
+		URL:         "http://example.com/post",
+		TextContent: "Gophers are small burrowing rodents that are endemic to North and Central America.",
+	}
+	_, err = d.Process(context.TODO(), found)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.Soft404, gc.Equals, false)
+
+	now = now.Add(soft404ProbeTTL)
+	_, err = d.Process(context.TODO(), found)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *Soft404TestSuite) TestProbeIgnoresRedirectTarget(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	urlGetter := mocks.NewMockURLGetter(ctrl)
+
+	res := makeResponse(200, soft404Body, "text/html")
+	res.Request = httptest.NewRequest("GET", "http://example.com/", nil)
+	urlGetter.EXPECT().Get(gomock.Any()).Return(res, nil)
+
+	home := &Payload{URL: "http://example.com/", FinalURL: "http://example.com/", TextContent: soft404Text}
+	_, err := newSoft404Detector(urlGetter).Process(context.TODO(), home)
+	c.Assert(err, gc.IsNil)
+	c.Assert(home.Soft404, gc.Equals, false)
+}
+
+func (s *Soft404TestSuite) TestHostWithHard404s(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	urlGetter := mocks.NewMockURLGetter(ctrl)
+	urlGetter.EXPECT().Get(gomock.Any()).Return(makeResponse(404, soft404Body, "text/html"), nil)
+
+	p := &Payload{URL: "http://example.com/oops", TextContent: soft404Text}
+	_, err := newSoft404Detector(urlGetter).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p.Soft404, gc.Equals, false)
+}
+
+func (s *Soft404TestSuite) TestTextIndexerSkipsSoft404s(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	indexer := mocks.NewMockIndexer(ctrl)
+
+	p := &Payload{URL: "http://example.com/oops", Title: "Page not found", Soft404: true}
+	got, err := newTextIndexer(indexer, nil, nil).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got, gc.Equals, p)
+}
@@ -0,0 +1,177 @@
+package crawler
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var digitsRegex = regexp.MustCompile(`[0-9]+`)
+
+// SpiderTrapStats describes the links that were suppressed by the spider-trap
+// heuristics of the link extractor during a crawl pass.
+type SpiderTrapStats struct {
+	// PathTooDeep, RepeatedSegments and PatternCapped are the number of
+	// links that were suppressed by each heuristic.
+	PathTooDeep      int
+	RepeatedSegments int
+	PatternCapped    int
+
+	// CappedPatterns maps each URL pattern that reached its cap to the
+	// number of links of the pattern that were suppressed.
+	CappedPatterns map[string]int
+}
+
+// Suppressed returns the total number of suppressed links.
+func (s SpiderTrapStats) Suppressed() int {
+	return s.PathTooDeep + s.RepeatedSegments + s.PatternCapped
+}
+
+// SpiderTrapReporter is implemented by objects that receive the spider-trap
+// statistics at the end of each crawl pass.
+type SpiderTrapReporter interface {
+	ReportSpiderTraps(passID uint64, stats SpiderTrapStats)
+}
+
+// spiderTrapLimits holds the thresholds used for detecting spider traps. A
+// non-positive threshold disables the corresponding heuristic.
+type spiderTrapLimits struct {
+	maxPathDepth        int
+	maxRepeatedSegments int
+	maxURLsPerPattern   int
+}
+
+func (l spiderTrapLimits) enabled() bool {
+	return l.maxPathDepth > 0 || l.maxRepeatedSegments > 0 || l.maxURLsPerPattern > 0
+}
+
+// spiderTrapDetector suppresses links that look like they belong to an
+// unbounded URL space such as calendars or faceted navigation. A detector
+// keeps track of the links of a single crawl pass. All methods can be invoked
+// on a nil receiver, in which case no links are suppressed.
+type spiderTrapDetector struct {
+	limits spiderTrapLimits
+
+	mu       sync.Mutex
+	patterns map[string]map[string]struct{}
+	stats    SpiderTrapStats
+}
+
+// newSpiderTrapDetector returns a detector that applies limits or nil if all
+// heuristics are disabled.
+func newSpiderTrapDetector(limits spiderTrapLimits) *spiderTrapDetector {
+	if !limits.enabled() {
+		return nil
+	}
+	return &spiderTrapDetector{
+		limits:   limits,
+		patterns: make(map[string]map[string]struct{}),
+		stats:    SpiderTrapStats{CappedPatterns: make(map[string]int)},
+	}
+}
+
+// allow returns false if link should be suppressed. Links are checked in
+// order against the path depth, repeated path segment and per-pattern caps.
+// Only distinct links count towards the cap of a pattern.
+func (d *spiderTrapDetector) allow(link *url.URL) bool {
+	if d == nil {
+		return true
+	}
+
+	segments := pathSegments(link.EscapedPath())
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.limits.maxPathDepth > 0 && len(segments) > d.limits.maxPathDepth {
+		d.stats.PathTooDeep++
+		return false
+	}
+
+	if d.limits.maxRepeatedSegments > 0 && maxSegmentRepeats(segments) > d.limits.maxRepeatedSegments {
+		d.stats.RepeatedSegments++
+		return false
+	}
+
+	if d.limits.maxURLsPerPattern > 0 {
+		pattern, linkStr := urlPattern(link, segments), link.String()
+		seen := d.patterns[pattern]
+		if seen == nil {
+			seen = make(map[string]struct{})
+			d.patterns[pattern] = seen
+		}
+		if _, found := seen[linkStr]; !found {
+			if len(seen) >= d.limits.maxURLsPerPattern {
+				d.stats.PatternCapped++
+				d.stats.CappedPatterns[pattern]++
+				return false
+			}
+			seen[linkStr] = struct{}{}
+		}
+	}
+	return true
+}
+
+// report returns a snapshot of the detector statistics.
+func (d *spiderTrapDetector) report() SpiderTrapStats {
+	if d == nil {
+		return SpiderTrapStats{}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := d.stats
+	stats.CappedPatterns = make(map[string]int, len(d.stats.CappedPatterns))
+	for pattern, count := range d.stats.CappedPatterns {
+		stats.CappedPatterns[pattern] = count
+	}
+	return stats
+}
+
+func pathSegments(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+func maxSegmentRepeats(segments []string) int {
+	var (
+		max    int
+		counts = make(map[string]int, len(segments))
+	)
+	for _, segment := range segments {
+		if counts[segment]++; counts[segment] > max {
+			max = counts[segment]
+		}
+	}
+	return max
+}
+
+// urlPattern groups links that only differ by the numbers in their path (e.g.
+// calendar dates or page numbers) or by the values of their query parameters
+// (e.g. facet combinations). For example, both "/events/2020/01?view=day" and
+// "/events/2021/12?view=week" map to "host/events/N/N?view".
+func urlPattern(link *url.URL, segments []string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(link.Host))
+	for _, segment := range segments {
+		b.WriteByte('/')
+		b.WriteString(digitsRegex.ReplaceAllString(segment, "N"))
+	}
+
+	if query := link.Query(); len(query) != 0 {
+		keys := make([]string, 0, len(query))
+		for key := range query {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b.WriteByte('?')
+		b.WriteString(strings.Join(keys, "&"))
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(SpiderTrapTestSuite))
+
+type SpiderTrapTestSuite struct{}
+
+func (s *SpiderTrapTestSuite) TestPathDepth(c *gc.C) {
+	d := newSpiderTrapDetector(spiderTrapLimits{maxPathDepth: 3})
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/a/b/c")), gc.Equals, true)
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/a/b/c/d")), gc.Equals, false)
+	c.Assert(d.report().PathTooDeep, gc.Equals, 1)
+}
+
+func (s *SpiderTrapTestSuite) TestRepeatedSegments(c *gc.C) {
+	d := newSpiderTrapDetector(spiderTrapLimits{maxRepeatedSegments: 2})
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/a/b/a/b")), gc.Equals, true)
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/a/b/a/b/a")), gc.Equals, false)
+	c.Assert(d.report().RepeatedSegments, gc.Equals, 1)
+}
+
+func (s *SpiderTrapTestSuite) TestPatternCap(c *gc.C) {
+	d := newSpiderTrapDetector(spiderTrapLimits{maxURLsPerPattern: 2})
+	for day := 1; day <= 5; day++ {
+		link := mustParseURL(c, fmt.Sprintf("http://example.com/calendar/2020/01/%02d", day))
+		c.Assert(d.allow(link), gc.Equals, day <= 2, gc.Commentf("day %d", day))
+	}
+
+	// Links that were already allowed are not counted twice
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/calendar/2020/01/01")), gc.Equals, true)
+
+	// Facet combinations differ only in their query parameter values
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/shop?color=red&size=m")), gc.Equals, true)
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/shop?size=s&color=blue")), gc.Equals, true)
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/shop?color=green&size=l")), gc.Equals, false)
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/shop?color=green")), gc.Equals, true)
+
+	stats := d.report()
+	c.Assert(stats.PatternCapped, gc.Equals, 4)
+	c.Assert(stats.Suppressed(), gc.Equals, 4)
+	c.Assert(stats.CappedPatterns, gc.DeepEquals, map[string]int{
+		"example.com/calendar/N/N/N":  3,
+		"example.com/shop?color&size": 1,
+	})
+}
+
+func (s *SpiderTrapTestSuite) TestDisabled(c *gc.C) {
+	d := newSpiderTrapDetector(spiderTrapLimits{})
+	c.Assert(d, gc.IsNil)
+	c.Assert(d.allow(mustParseURL(c, "http://example.com/a/a/a/a/a/a")), gc.Equals, true)
+	c.Assert(d.report().Suppressed(), gc.Equals, 0)
+}
+
+func (s *SpiderTrapTestSuite) TestLinkExtractorSuppressesTraps(c *gc.C) {
+	p := &Payload{
+		URL:         "http://example.com/",
+		spiderTraps: newSpiderTrapDetector(spiderTrapLimits{maxPathDepth: 2, maxURLsPerPattern: 1}),
+	}
+	_, err := p.RawContent.WriteString(`<html><body>
+<a href="/about">about</a>
+<a href="/a/b/c">deep</a>
+<a href="/page/1" rel="nofollow">first</a>
+<a href="/page/2" rel="nofollow">second</a>
+</body></html>`)
+	c.Assert(err, gc.IsNil)
+
+	_, err = newLinkExtractor(nil).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(p.Links, gc.DeepEquals, []string{"http://example.com/about"})
+	c.Assert(p.NoFollowLinks, gc.DeepEquals, []string{"http://example.com/page/1"})
+	c.Assert(p.spiderTraps.report().Suppressed(), gc.Equals, 2)
+}
+
+func mustParseURL(c *gc.C, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	c.Assert(err, gc.IsNil)
+	return u
+}
@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+//spoolBuffer accumulates the raw content of a payload.  Content is kept in
+//memory until it grows beyond spoolThreshold bytes, at which point it is
+//moved to a temporary file in spoolDir so that payloads waiting in the
+//pipeline do not hold large bodies in memory.  A zero threshold disables
+//spooling.
+//
+//Unlike bytes.Buffer, reading the content via Reader or String does not
+//consume it.
+type spoolBuffer struct {
+	spoolThreshold int
+	spoolDir       string
+
+	mem  bytes.Buffer
+	file *os.File
+	size int64
+}
+
+//Write implements io.Writer
+func (b *spoolBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && b.spoolThreshold > 0 && b.mem.Len()+len(p) > b.spoolThreshold {
+		if err := b.spool(); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		n   int
+		err error
+	)
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.mem.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+//WriteString appends s to the buffer
+func (b *spoolBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+//spool moves the in-memory content to a temporary file
+func (b *spoolBuffer) spool() error {
+	f, err := ioutil.TempFile(b.spoolDir, "crawler-payload-")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b.mem.Bytes()); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	b.file = f
+	b.mem.Reset()
+	return nil
+}
+
+//Len returns the size of the buffered content in bytes
+func (b *spoolBuffer) Len() int64 { return b.size }
+
+//Spooled returns true if the content has been moved to a temporary file
+func (b *spoolBuffer) Spooled() bool { return b.file != nil }
+
+//Reader returns a reader for streaming the buffered content from the start
+func (b *spoolBuffer) Reader() io.Reader {
+	if b.file != nil {
+		return io.NewSectionReader(b.file, 0, b.size)
+	}
+	return bytes.NewReader(b.mem.Bytes())
+}
+
+//String returns the buffered content.  Content that cannot be read back from
+//its temporary file is treated as empty
+func (b *spoolBuffer) String() string {
+	if b.file == nil {
+		return b.mem.String()
+	}
+
+	var sb strings.Builder
+	sb.Grow(int(b.size))
+	if _, err := io.Copy(&sb, b.Reader()); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+//Reset discards the buffered content and removes the temporary file, if any
+func (b *spoolBuffer) Reset() {
+	if b.file != nil {
+		_ = b.file.Close()
+		_ = os.Remove(b.file.Name())
+		b.file = nil
+	}
+	b.mem.Reset()
+	b.size = 0
+}
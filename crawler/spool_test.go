@@ -0,0 +1,97 @@
+package crawler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(SpoolBufferTestSuite))
+
+type SpoolBufferTestSuite struct{}
+
+func (s *SpoolBufferTestSuite) TestInMemory(c *gc.C) {
+	var b spoolBuffer
+	_, err := b.WriteString("hello world")
+	c.Assert(err, gc.IsNil)
+	c.Assert(b.Spooled(), gc.Equals, false)
+	c.Assert(b.String(), gc.Equals, "hello world")
+
+	// reading does not consume the content
+	data, err := ioutil.ReadAll(b.Reader())
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(data), gc.Equals, "hello world")
+	c.Assert(b.String(), gc.Equals, "hello world")
+}
+
+func (s *SpoolBufferTestSuite) TestSpoolToFile(c *gc.C) {
+	dir := c.MkDir()
+	b := spoolBuffer{spoolThreshold: 16, spoolDir: dir}
+
+	_, err := b.WriteString("0123456789")
+	c.Assert(err, gc.IsNil)
+	c.Assert(b.Spooled(), gc.Equals, false)
+
+	_, err = b.WriteString("abcdefghij")
+	c.Assert(err, gc.IsNil)
+	c.Assert(b.Spooled(), gc.Equals, true)
+	c.Assert(b.Len(), gc.Equals, int64(20))
+	c.Assert(b.String(), gc.Equals, "0123456789abcdefghij")
+
+	data, err := ioutil.ReadAll(b.Reader())
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(data), gc.Equals, "0123456789abcdefghij")
+
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, gc.IsNil)
+	c.Assert(files, gc.HasLen, 1)
+
+	b.Reset()
+	c.Assert(b.Len(), gc.Equals, int64(0))
+	files, err = ioutil.ReadDir(dir)
+	c.Assert(err, gc.IsNil)
+	c.Assert(files, gc.HasLen, 0)
+}
+
+func (s *SpoolBufferTestSuite) TestPayloadCleanup(c *gc.C) {
+	dir := c.MkDir()
+	p := payloadPool.Get().(*Payload)
+	p.RawContent.spoolThreshold = 4
+	p.RawContent.spoolDir = dir
+	_, err := p.RawContent.WriteString(strings.Repeat("x", 32))
+	c.Assert(err, gc.IsNil)
+
+	cloned, err := p.Clone()
+	c.Assert(err, gc.IsNil)
+	clone := cloned.(*Payload)
+	c.Assert(clone.RawContent.String(), gc.Equals, p.RawContent.String())
+
+	p.MarkAsProcessed()
+	clone.MarkAsProcessed()
+
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, gc.IsNil)
+	c.Assert(files, gc.HasLen, 0)
+}
+
+func (s *SpoolBufferTestSuite) TestPayloadCloneError(c *gc.C) {
+	dir := c.MkDir()
+	p := payloadPool.Get().(*Payload)
+	p.RawContent.spoolThreshold = 4
+	p.RawContent.spoolDir = dir
+	_, err := p.RawContent.WriteString(strings.Repeat("x", 32))
+	c.Assert(err, gc.IsNil)
+
+	// The clone cannot spool its copy of the content
+	p.RawContent.spoolDir = filepath.Join(dir, "missing")
+	clone, err := p.Clone()
+	c.Assert(err, gc.ErrorMatches, "clone raw content: .*")
+	c.Assert(clone, gc.IsNil)
+
+	p.MarkAsProcessed()
+	files, err := ioutil.ReadDir(dir)
+	c.Assert(err, gc.IsNil)
+	c.Assert(files, gc.HasLen, 0)
+}
@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"context"
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+)
+
+var (
+	jsonLDRegex = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+
+	//date formats commonly used for schema.org datePublished values
+	jsonLDDateLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02",
+	}
+)
+
+type structuredDataExtractor struct{}
+
+func newStructuredDataExtractor() *structuredDataExtractor {
+	return &structuredDataExtractor{}
+}
+
+//Process parses any JSON-LD blocks embedded in the page and appends the
+//schema.org entities they describe to the payload.  Malformed blocks are
+//ignored as they are quite common in the wild
+func (se *structuredDataExtractor) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	payload := p.(*Payload)
+
+	for _, match := range jsonLDRegex.FindAllStringSubmatch(payload.RawContent.String(), -1) {
+		var block interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[1])), &block); err != nil {
+			continue
+		}
+		payload.Entities = appendEntities(payload.Entities, block)
+	}
+
+	return payload, nil
+}
+
+//appendEntities walks a decoded JSON-LD value which may be a single entity,
+//a list of entities or an object with an @graph list
+func appendEntities(entities []index.Entity, v interface{}) []index.Entity {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			entities = appendEntities(entities, item)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return appendEntities(entities, graph)
+		}
+
+		entityType := jsonLDString(v["@type"])
+		if entityType == "" {
+			return entities
+		}
+
+		entity := index.Entity{
+			Type:   entityType,
+			Name:   jsonLDString(v["name"]),
+			Author: jsonLDString(v["author"]),
+		}
+		if entity.Name == "" {
+			entity.Name = jsonLDString(v["headline"])
+		}
+		entity.DatePublished = parseJSONLDDate(jsonLDString(v["datePublished"]))
+		entities = append(entities, entity)
+	}
+
+	return entities
+}
+
+//jsonLDString converts a JSON-LD property into a string.  Properties may be
+//plain strings, lists (the first entry is used) or nested entities (their
+//name is used)
+func jsonLDString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(html.UnescapeString(v))
+	case []interface{}:
+		if len(v) != 0 {
+			return jsonLDString(v[0])
+		}
+	case map[string]interface{}:
+		return jsonLDString(v["name"])
+	}
+	return ""
+}
+
+func parseJSONLDDate(v string) time.Time {
+	for _, layout := range jsonLDDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
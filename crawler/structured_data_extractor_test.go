@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"context"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(StructuredDataExtractorTestSuite))
+
+type StructuredDataExtractorTestSuite struct{}
+
+func (s *StructuredDataExtractorTestSuite) TestExtractJSONLD(c *gc.C) {
+	content := `<html><head>
+<script type="application/ld+json">
+{
+	"@context": "https://schema.org",
+	"@type": "NewsArticle",
+	"headline": "Gophers take over",
+	"author": [{"@type": "Person", "name": "Jane Doe"}],
+	"datePublished": "2020-02-05T08:00:00+00:00"
+}
+</script>
+<script type='application/ld+json'>
+{"@graph": [{"@type": "Organization", "name": "Gopher Times"}, {"name": "no type"}]}
+</script>
+<script type="application/ld+json">{ not valid json </script>
+</head></html>`
+
+	p := new(Payload)
+	_, err := p.RawContent.WriteString(content)
+	c.Assert(err, gc.IsNil)
+
+	out, err := newStructuredDataExtractor().Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out.(*Payload).Entities, gc.DeepEquals, []index.Entity{
+		{
+			Type:          "NewsArticle",
+			Name:          "Gophers take over",
+			Author:        "Jane Doe",
+			DatePublished: time.Date(2020, 2, 5, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			Type: "Organization",
+			Name: "Gopher Times",
+		},
+	})
+}
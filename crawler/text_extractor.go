@@ -14,10 +14,35 @@ import (
 var (
 	titleRegex         = regexp.MustCompile(`(?i)<title.*?>(.*?)</title>`)
 	repeatedSpaceRegex = regexp.MustCompile(`\s+`)
+	metaTagRegex       = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	htmlAttrRegex      = regexp.MustCompile(`(?is)([a-z][a-z0-9:_-]*)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
 )
 
+//LanguageDetector is implemented by objects that can guess the language of a
+//block of text, returning its ISO 639-1 code or an empty string if unknown
+type LanguageDetector interface {
+	Detect(text string) string
+}
+
+//ContentClassifier is implemented by objects that can flag pages whose content
+//should be kept out of the default search results (e.g. adult content or
+//spam).  Classify returns the reason the page was flagged or an empty string
+//if the page is acceptable
+type ContentClassifier interface {
+	Classify(url, title, text string) string
+}
+
 type textExtractor struct {
 	policyPool sync.Pool
+
+	//langDetector, if set, is used to populate the payload language
+	langDetector LanguageDetector
+
+	//removeBoilerplate enables the extraction of the main text of the page
+	removeBoilerplate bool
+
+	//classifier, if set, is invoked once the text has been extracted
+	classifier ContentClassifier
 }
 
 func newTextExtractor() *textExtractor {
@@ -31,19 +56,72 @@ func newTextExtractor() *textExtractor {
 }
 
 func (te *textExtractor) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
-	payload := p.(*crawlerPayload)
+	payload := p.(*Payload)
 	policy := te.policyPool.Get().(*bluemonday.Policy)
 
-	if titleMatch := titleRegex.FindStringSubmatch(payload.RawContent.String()); len(titleMatch) == 2 {
+	content := payload.RawContent.String()
+	if titleMatch := titleRegex.FindStringSubmatch(content); len(titleMatch) == 2 {
 		payload.Title = strings.TrimSpace(html.UnescapeString(repeatedSpaceRegex.ReplaceAllString(
 			policy.Sanitize(titleMatch[1]), " ",
 		)))
 	}
 
+	extractMetadata(payload, content)
+
+	if te.removeBoilerplate {
+		payload.MainText = extractMainText(content, policy)
+	}
+
 	payload.TextContent = strings.TrimSpace(html.UnescapeString(repeatedSpaceRegex.ReplaceAllString(
-		policy.SanitizeReader(&payload.RawContent).String(), " ",
+		policy.SanitizeReader(payload.RawContent.Reader()).String(), " ",
 	)))
 
+	if te.langDetector != nil {
+		payload.Language = te.langDetector.Detect(payload.TextContent)
+	}
+
+	if te.classifier != nil {
+		payload.FilterReason = te.classifier.Classify(payload.URL, payload.Title, payload.TextContent)
+	}
+
 	te.policyPool.Put(policy)
 	return payload, nil
 }
+
+//extractMetadata populates the description, keywords and OpenGraph fields of
+//the payload from the <meta> tags of the page
+func extractMetadata(payload *Payload, content string) {
+	for _, tag := range metaTagRegex.FindAllString(content, -1) {
+		attrs := make(map[string]string)
+		for _, match := range htmlAttrRegex.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(match[1])] = match[2] + match[3]
+		}
+
+		//OpenGraph tags use the property attribute instead of name
+		name := attrs["name"]
+		if name == "" {
+			name = attrs["property"]
+		}
+		value := strings.TrimSpace(html.UnescapeString(repeatedSpaceRegex.ReplaceAllString(attrs["content"], " ")))
+		if value == "" {
+			continue
+		}
+
+		switch strings.ToLower(name) {
+		case "description":
+			payload.Description = value
+		case "keywords":
+			for _, keyword := range strings.Split(value, ",") {
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					payload.Keywords = append(payload.Keywords, keyword)
+				}
+			}
+		case "og:title":
+			payload.OGTitle = value
+		case "og:description":
+			payload.OGDescription = value
+		case "og:image":
+			payload.OGImage = value
+		}
+	}
+}
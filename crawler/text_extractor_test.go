@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"context"
+	"strings"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(TextExtractorTestSuite))
+
+type TextExtractorTestSuite struct{}
+
+func (s *TextExtractorTestSuite) TestMetadataExtraction(c *gc.C) {
+	content := `<html>
+<head>
+	<title>Gopher news</title>
+	<meta name="description" content="All the  news about gophers">
+	<meta content="go, gophers ,, golang" name="Keywords"/>
+	<meta property="og:title" content='Gopher news &amp; views'>
+	<meta property="og:image" content="https://example.com/gopher.png">
+</head>
+<body>Hello world</body>
+</html>`
+
+	p := new(Payload)
+	_, err := p.RawContent.WriteString(content)
+	c.Assert(err, gc.IsNil)
+
+	out, err := newTextExtractor().Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.FitsTypeOf, p)
+
+	got := out.(*Payload)
+	c.Assert(got.Title, gc.Equals, "Gopher news")
+	c.Assert(got.Description, gc.Equals, "All the news about gophers")
+	c.Assert(got.Keywords, gc.DeepEquals, []string{"go", "gophers", "golang"})
+	c.Assert(got.OGTitle, gc.Equals, "Gopher news & views")
+	c.Assert(got.OGDescription, gc.Equals, "")
+	c.Assert(got.OGImage, gc.Equals, "https://example.com/gopher.png")
+}
+
+func (s *TextExtractorTestSuite) TestBoilerplateRemoval(c *gc.C) {
+	content := `<html>
+<head><title>Gophers</title><style>body { color: red; }</style></head>
+<body>
+	<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+	<div class="sidebar"><a href="/a">Some related article</a> <a href="/b">Another related article</a> <a href="/c">Yet another one</a></div>
+	<article>
+		<h1>All about gophers</h1>
+		<p>Gophers are small burrowing rodents that are found throughout North and Central America.</p>
+		<p>They are well known for their extensive tunneling activities, see <a href="/tunnels">tunnels</a> for details.</p>
+	</article>
+	<footer>Copyright 2020 - all rights reserved by the gopher appreciation society</footer>
+</body>
+</html>`
+
+	p := new(Payload)
+	_, err := p.RawContent.WriteString(content)
+	c.Assert(err, gc.IsNil)
+
+	te := newTextExtractor()
+	te.removeBoilerplate = true
+	out, err := te.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+
+	got := out.(*Payload)
+	c.Assert(got.MainText, gc.Equals, "Gophers are small burrowing rodents that are found throughout North and Central America. "+
+		"They are well known for their extensive tunneling activities, see tunnels for details.")
+	c.Assert(strings.Contains(got.TextContent, "Copyright"), gc.Equals, true, gc.Commentf("full text should be retained"))
+}
+
+func (s *TextExtractorTestSuite) TestContentClassifier(c *gc.C) {
+	p := &Payload{URL: "http://example.com/deals"}
+	_, err := p.RawContent.WriteString(`<html><head><title>Cheap pills</title></head><body>Buy cheap pills now</body></html>`)
+	c.Assert(err, gc.IsNil)
+
+	te := newTextExtractor()
+	te.classifier = classifierFunc(func(url, title, text string) string {
+		if strings.Contains(text, "cheap pills") {
+			return "spam"
+		}
+		return ""
+	})
+	out, err := te.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out.(*Payload).FilterReason, gc.Equals, "spam")
+}
+
+type classifierFunc func(url, title, text string) string
+
+func (f classifierFunc) Classify(url, title, text string) string { return f(url, title, text) }
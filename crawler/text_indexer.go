@@ -6,6 +6,8 @@ import (
 
 	"github.com/brandonshearin/ask_brandon/pipeline"
 	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
 )
 
 // Indexer is implemented by objects that can index the contents of webpages retrieved by the crawler pipeline
@@ -13,31 +15,116 @@ type Indexer interface {
 	Index(doc *index.Document) error
 }
 
+//passageDeleter is implemented by indexers that can remove documents; it is
+//used for removing the passages of a page that no longer exist after its
+//content has shrunk
+type passageDeleter interface {
+	Delete(linkID uuid.UUID) error
+}
+
 type textIndexer struct {
 	indexer Indexer
+
+	//enricher, if set, augments documents before they are indexed
+	enricher index.Enricher
+
+	//passages, if set, enables splitting the content of each document
+	//into passages that are indexed as child documents
+	passages *index.PassageConfig
 }
 
-func newTextIndexer(indexer Indexer) *textIndexer {
-	return &textIndexer{
-		indexer: indexer,
+func newTextIndexer(indexer Indexer, enrichers []index.Enricher, passages *index.PassageConfig) *textIndexer {
+	i := &textIndexer{
+		indexer:  indexer,
+		passages: passages,
+	}
+	if len(enrichers) != 0 {
+		i.enricher = index.ChainEnrichers(enrichers...)
 	}
+	return i
 }
 
 func (i *textIndexer) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
 
-	payload := p.(*crawlerPayload)
+	payload := p.(*Payload)
+
+	//"page not found" pages served with a 2xx status must not end up in
+	//the index
+	if payload.Soft404 {
+		return p, nil
+	}
 
 	doc := &index.Document{
 		LinkID:    payload.LinkID,
 		URL:       payload.URL,
 		Title:     payload.Title,
 		Content:   payload.TextContent,
+		RawText:   payload.TextContent,
+		MainText:  payload.MainText,
+		Language:  payload.Language,
 		IndexedAt: time.Now(),
+
+		FetchedAt:   payload.FetchedAt,
+		CrawlPassID: payload.CrawlPassID,
+		HTTPStatus:  payload.HTTPStatus,
+		ContentHash: payload.ContentHash,
+
+		Description:   payload.Description,
+		Keywords:      append([]string(nil), payload.Keywords...),
+		OGTitle:       payload.OGTitle,
+		OGDescription: payload.OGDescription,
+		OGImage:       payload.OGImage,
+		Entities:      append([]index.Entity(nil), payload.Entities...),
+
+		Filtered:     payload.FilterReason != "",
+		FilterReason: payload.FilterReason,
+	}
+
+	//index the main text of the page if boilerplate removal managed to
+	//identify it; the full text is retained either way
+	if payload.MainText != "" {
+		doc.Content = payload.MainText
+	}
+
+	if i.enricher != nil {
+		if err := i.enricher.Enrich(ctx, doc); err != nil {
+			return nil, xerrors.Errorf("enrich document %s: %w", payload.URL, err)
+		}
 	}
 
 	if err := i.indexer.Index(doc); err != nil {
 		return nil, err
 	}
 
+	if i.passages != nil {
+		if err := i.indexPassages(doc); err != nil {
+			return nil, xerrors.Errorf("index passages of %s: %w", payload.URL, err)
+		}
+	}
+
 	return p, nil
 }
+
+//indexPassages indexes the passages of doc and, if supported by the indexer,
+//deletes any passages left over from a previous, longer, version of the page
+func (i *textIndexer) indexPassages(doc *index.Document) error {
+	passages := index.SplitPassages(doc, *i.passages)
+	for _, passage := range passages {
+		if err := i.indexer.Index(passage); err != nil {
+			return err
+		}
+	}
+
+	deleter, ok := i.indexer.(passageDeleter)
+	if !ok {
+		return nil
+	}
+	for n := len(passages); ; n++ {
+		err := deleter.Delete(index.PassageID(doc.LinkID, n))
+		if xerrors.Is(err, index.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
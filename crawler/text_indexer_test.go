@@ -0,0 +1,109 @@
+package crawler
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/crawler/mocks"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(TextIndexerTestSuite))
+
+type TextIndexerTestSuite struct{}
+
+func (s *TextIndexerTestSuite) TestEnrichers(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	indexer := mocks.NewMockIndexer(ctrl)
+
+	var indexed *index.Document
+	indexer.EXPECT().Index(gomock.Any()).DoAndReturn(func(doc *index.Document) error {
+		indexed = doc
+		return nil
+	})
+
+	enrichers := []index.Enricher{
+		index.EnricherFunc(func(_ context.Context, doc *index.Document) error {
+			doc.Keywords = append(doc.Keywords, "gophers")
+			return nil
+		}),
+		index.EnricherFunc(func(_ context.Context, doc *index.Document) error {
+			doc.Description = "A page about " + doc.Keywords[len(doc.Keywords)-1]
+			return nil
+		}),
+	}
+	p := &Payload{URL: "http://example.com/", Title: "Gophers", Keywords: []string{"go"}}
+	_, err := newTextIndexer(indexer, enrichers, nil).Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(indexed.Keywords, gc.DeepEquals, []string{"go", "gophers"})
+	c.Assert(indexed.Description, gc.Equals, "A page about gophers")
+}
+
+func (s *TextIndexerTestSuite) TestEnricherError(c *gc.C) {
+	ctrl := gomock.NewController(c)
+	defer ctrl.Finish()
+	indexer := mocks.NewMockIndexer(ctrl)
+
+	failing := index.EnricherFunc(func(context.Context, *index.Document) error {
+		return xerrors.New("model unavailable")
+	})
+	p := &Payload{URL: "http://example.com/"}
+	_, err := newTextIndexer(indexer, []index.Enricher{failing}, nil).Process(context.TODO(), p)
+	c.Assert(err, gc.ErrorMatches, "enrich document http://example.com/: model unavailable")
+}
+
+func (s *TextIndexerTestSuite) TestPassages(c *gc.C) {
+	indexer := &passageIndexer{docs: make(map[uuid.UUID]*index.Document)}
+	ti := newTextIndexer(indexer, nil, &index.PassageConfig{Words: 4, Overlap: 1})
+
+	p := &Payload{
+		LinkID:      uuid.New(),
+		URL:         "http://example.com/",
+		Title:       "Gophers",
+		TextContent: "gophers are burrowing rodents that dig tunnels in gardens at night",
+	}
+	_, err := ti.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(indexer.docs, gc.HasLen, 5)
+	for n, exp := range []string{
+		"gophers are burrowing rodents",
+		"rodents that dig tunnels",
+		"tunnels in gardens at",
+		"at night",
+	} {
+		passage := indexer.docs[index.PassageID(p.LinkID, n)]
+		c.Assert(passage, gc.NotNil)
+		c.Assert(passage.Content, gc.Equals, exp)
+		c.Assert(passage.ParentLinkID, gc.Equals, p.LinkID)
+		c.Assert(passage.URL, gc.Equals, p.URL)
+	}
+
+	// Passages that no longer exist after the page shrinks are deleted
+	p.TextContent = "gophers dig tunnels"
+	_, err = ti.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(indexer.docs, gc.HasLen, 2)
+	c.Assert(indexer.docs[index.PassageID(p.LinkID, 0)].Content, gc.Equals, "gophers dig tunnels")
+}
+
+//passageIndexer is a map-backed indexer that supports deleting documents
+type passageIndexer struct {
+	docs map[uuid.UUID]*index.Document
+}
+
+func (i *passageIndexer) Index(doc *index.Document) error {
+	i.docs[doc.LinkID] = doc
+	return nil
+}
+
+func (i *passageIndexer) Delete(linkID uuid.UUID) error {
+	if i.docs[linkID] == nil {
+		return index.ErrNotFound
+	}
+	delete(i.docs, linkID)
+	return nil
+}
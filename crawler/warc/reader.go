@@ -0,0 +1,146 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// maxHeaderLineLength bounds the length of WARC header lines so that
+// corrupted files cannot exhaust memory.
+const maxHeaderLineLength = 64 << 10
+
+// Reader reads WARC records from an underlying stream. Both plain and
+// gzip-compressed (.warc.gz) files, such as the segments published by Common
+// Crawl, are supported.
+type Reader struct {
+	br *bufio.Reader
+}
+
+// NewReader returns a Reader for the records in r. Compressed input is
+// detected automatically.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, xerrors.Errorf("warc: %w", err)
+	}
+
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, xerrors.Errorf("warc: %w", err)
+		}
+		br = bufio.NewReader(zr)
+	}
+	return &Reader{br: br}, nil
+}
+
+// Next returns the next record. It returns io.EOF once all records have been
+// read. Digest and length fields are consumed by the reader so they are not
+// included in the returned record's Fields.
+func (r *Reader) Next() (*Record, error) {
+	version, err := r.readLine()
+	for err == nil && version == "" {
+		// Skip any blank lines left over from the previous record
+		version, err = r.readLine()
+	}
+	if err == io.EOF && version == "" {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, xerrors.Errorf("warc: %w", err)
+	} else if !strings.HasPrefix(version, "WARC/1.") {
+		return nil, xerrors.Errorf("warc: unsupported record version %q", version)
+	}
+
+	var (
+		rec           Record
+		contentLength = -1
+	)
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, xerrors.Errorf("warc: read header: %w", unexpectedEOF(err))
+		} else if line == "" {
+			break
+		}
+
+		sep := strings.IndexByte(line, ':')
+		if sep < 0 {
+			return nil, xerrors.Errorf("warc: malformed header line %q", line)
+		}
+		name, value := line[:sep], strings.TrimSpace(line[sep+1:])
+		switch strings.ToLower(name) {
+		case "warc-type":
+			rec.Type = value
+		case "warc-record-id":
+			rec.ID = value
+		case "warc-date":
+			if rec.Date, err = time.Parse(time.RFC3339Nano, value); err != nil {
+				return nil, xerrors.Errorf("warc: malformed date %q", value)
+			}
+		case "warc-target-uri":
+			// WARC 1.0 files written by some tools wrap the URI
+			// in angle brackets
+			rec.TargetURI = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+		case "content-type":
+			rec.ContentType = value
+		case "content-length":
+			if contentLength, err = strconv.Atoi(value); err != nil || contentLength < 0 {
+				return nil, xerrors.Errorf("warc: malformed content length %q", value)
+			}
+		case "warc-block-digest", "warc-payload-digest":
+		default:
+			rec.Fields = append(rec.Fields, Field{Name: name, Value: value})
+		}
+	}
+	if contentLength < 0 {
+		return nil, xerrors.New("warc: record without content length")
+	}
+
+	rec.Block = make([]byte, contentLength)
+	if _, err = io.ReadFull(r.br, rec.Block); err != nil {
+		return nil, xerrors.Errorf("warc: read block: %w", unexpectedEOF(err))
+	}
+	return &rec, nil
+}
+
+// Field returns the value of the named header field or an empty string if the
+// record does not have such a field.
+func (rec *Record) Field(name string) string {
+	for _, f := range rec.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func (r *Reader) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.br.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxHeaderLineLength {
+			return "", xerrors.New("header line too long")
+		} else if !isPrefix {
+			return string(bytes.TrimRight(line, "\r")), nil
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+// Package warc implements a writer for the WARC 1.1 file format (ISO 28500)
+// used by web archives such as the Internet Archive. Files produced by the
+// writer can be replayed with standard tooling such as pywb or OpenWayback.
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/base32"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// The WARC record types emitted by the crawler.
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+	TypeMetadata = "metadata"
+)
+
+// The content types of WARC record blocks.
+const (
+	ContentTypeHTTPRequest  = "application/http;msgtype=request"
+	ContentTypeHTTPResponse = "application/http;msgtype=response"
+	ContentTypeFields       = "application/warc-fields"
+)
+
+// Field is a named field of a WARC record header. Fields are written in the
+// order they are specified.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Record describes a WARC record.
+type Record struct {
+	// ID is the WARC-Record-ID of the record. If not specified, the
+	// writer generates a new ID.
+	ID string
+
+	// Type is the WARC-Type of the record.
+	Type string
+
+	// TargetURI is the URI the record refers to.
+	TargetURI string
+
+	// Date is the time the record content was captured. If not
+	// specified, the current time will be used.
+	Date time.Time
+
+	// ContentType is the MIME type of Block.
+	ContentType string
+
+	// Fields are additional header fields such as WARC-Concurrent-To.
+	Fields []Field
+
+	// Block is the content of the record.
+	Block []byte
+
+	// PayloadOffset, if positive, is the offset within Block where the
+	// payload (e.g. the body of an HTTP response) starts. It is used for
+	// calculating the WARC-Payload-Digest of the record.
+	PayloadOffset int
+}
+
+// Writer writes WARC records to an underlying stream. It is safe for
+// concurrent use.
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	compress bool
+}
+
+// NewWriter returns a Writer that writes records to w. If compress is true,
+// each record is written as a separate gzip member, which is the layout
+// expected for .warc.gz files.
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{w: w, compress: compress}
+}
+
+// WriteRecord writes rec and returns the WARC-Record-ID assigned to it.
+func (w *Writer) WriteRecord(rec *Record) (string, error) {
+	id := rec.ID
+	if id == "" {
+		id = "<urn:uuid:" + uuid.New().String() + ">"
+	}
+	date := rec.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("WARC/1.1\r\n")
+	writeField(&buf, "WARC-Type", rec.Type)
+	writeField(&buf, "WARC-Record-ID", id)
+	writeField(&buf, "WARC-Date", date.UTC().Format(time.RFC3339))
+	if rec.TargetURI != "" {
+		writeField(&buf, "WARC-Target-URI", rec.TargetURI)
+	}
+	for _, f := range rec.Fields {
+		writeField(&buf, f.Name, f.Value)
+	}
+	if rec.ContentType != "" {
+		writeField(&buf, "Content-Type", rec.ContentType)
+	}
+	writeField(&buf, "WARC-Block-Digest", digest(rec.Block))
+	if rec.PayloadOffset > 0 && rec.PayloadOffset <= len(rec.Block) {
+		writeField(&buf, "WARC-Payload-Digest", digest(rec.Block[rec.PayloadOffset:]))
+	}
+	writeField(&buf, "Content-Length", strconv.Itoa(len(rec.Block)))
+	buf.WriteString("\r\n")
+	buf.Write(rec.Block)
+	buf.WriteString("\r\n\r\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.write(buf.Bytes()); err != nil {
+		return "", xerrors.Errorf("warc: write %s record: %w", rec.Type, err)
+	}
+	return id, nil
+}
+
+// WriteInfo writes a warcinfo record describing the records that follow it.
+func (w *Writer) WriteInfo(fields []Field) (string, error) {
+	return w.WriteRecord(&Record{
+		Type:        TypeWarcinfo,
+		ContentType: ContentTypeFields,
+		Block:       FormatFields(fields),
+	})
+}
+
+func (w *Writer) write(data []byte) error {
+	if !w.compress {
+		_, err := w.w.Write(data)
+		return err
+	}
+
+	zw := gzip.NewWriter(w.w)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// FormatFields encodes fields in the application/warc-fields format used by
+// warcinfo and metadata records.
+func FormatFields(fields []Field) []byte {
+	var buf bytes.Buffer
+	for _, f := range fields {
+		writeField(&buf, f.Name, f.Value)
+	}
+	return buf.Bytes()
+}
+
+func writeField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+// digest returns the base32-encoded SHA-1 digest of data, which is the
+// format used by most web-archive tooling.
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,153 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(WARCTestSuite))
+
+type WARCTestSuite struct{}
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+func (s *WARCTestSuite) TestWriteRecord(c *gc.C) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, false)
+
+	block := []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello")
+	id, err := w.WriteRecord(&Record{
+		Type:          TypeResponse,
+		TargetURI:     "http://example.com/",
+		Date:          time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		ContentType:   ContentTypeHTTPResponse,
+		Fields:        []Field{{Name: "WARC-IP-Address", Value: "10.0.0.1"}},
+		Block:         block,
+		PayloadOffset: len(block) - 5,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(id, gc.Matches, `<urn:uuid:[0-9a-f-]{36}>`)
+
+	exp := "WARC/1.1\r\n" +
+		"WARC-Type: response\r\n" +
+		"WARC-Record-ID: " + id + "\r\n" +
+		"WARC-Date: 2020-01-02T03:04:05Z\r\n" +
+		"WARC-Target-URI: http://example.com/\r\n" +
+		"WARC-IP-Address: 10.0.0.1\r\n" +
+		"Content-Type: application/http;msgtype=response\r\n" +
+		"WARC-Block-Digest: " + digest(block) + "\r\n" +
+		// SHA-1 of "hello"
+		"WARC-Payload-Digest: sha1:VL2MMHO4YXUKFWV63YHTWSBM3GXKSQ2N\r\n" +
+		"Content-Length: 43\r\n" +
+		"\r\n" + string(block) + "\r\n\r\n"
+	c.Assert(buf.String(), gc.Equals, exp)
+
+	// The record can be parsed by the standard HTTP reader
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	c.Assert(err, gc.IsNil)
+	body, err := ioutil.ReadAll(res.Body)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(body), gc.Equals, "hello")
+}
+
+func (s *WARCTestSuite) TestCompressedRecordsAreSeparateMembers(c *gc.C) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, true)
+	_, err := w.WriteInfo([]Field{{Name: "software", Value: "ask_brandon"}})
+	c.Assert(err, gc.IsNil)
+	_, err = w.WriteRecord(&Record{Type: TypeMetadata, TargetURI: "http://example.com/", Block: []byte("via: http://example.com\r\n")})
+	c.Assert(err, gc.IsNil)
+
+	zr, err := gzip.NewReader(&buf)
+	c.Assert(err, gc.IsNil)
+	zr.Multistream(false)
+
+	var records []string
+	for {
+		data, err := ioutil.ReadAll(zr)
+		c.Assert(err, gc.IsNil)
+		records = append(records, string(data))
+		if err = zr.Reset(&buf); err == io.EOF {
+			break
+		}
+		c.Assert(err, gc.IsNil)
+		zr.Multistream(false)
+	}
+
+	c.Assert(records, gc.HasLen, 2)
+	c.Assert(strings.HasPrefix(records[0], "WARC/1.1\r\nWARC-Type: warcinfo\r\n"), gc.Equals, true)
+	c.Assert(records[0], gc.Matches, `(?s).*\r\n\r\nsoftware: ask_brandon\r\n\r\n\r\n$`)
+	c.Assert(records[1], gc.Matches, `(?s)WARC/1.1\r\nWARC-Type: metadata\r\n.*`)
+}
+
+func (s *WARCTestSuite) TestRoundTrip(c *gc.C) {
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, compress)
+		_, err := w.WriteInfo([]Field{{Name: "software", Value: "ask_brandon"}})
+		c.Assert(err, gc.IsNil)
+
+		date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		id, err := w.WriteRecord(&Record{
+			Type:        TypeResponse,
+			TargetURI:   "http://example.com/",
+			Date:        date,
+			ContentType: ContentTypeHTTPResponse,
+			Fields:      []Field{{Name: "WARC-IP-Address", Value: "10.0.0.1"}},
+			Block:       []byte("HTTP/1.1 200 OK\r\n\r\n"),
+		})
+		c.Assert(err, gc.IsNil)
+
+		r, err := NewReader(&buf)
+		c.Assert(err, gc.IsNil)
+
+		info, err := r.Next()
+		c.Assert(err, gc.IsNil)
+		c.Assert(info.Type, gc.Equals, TypeWarcinfo)
+		c.Assert(string(info.Block), gc.Equals, "software: ask_brandon\r\n")
+
+		rec, err := r.Next()
+		c.Assert(err, gc.IsNil)
+		c.Assert(rec, gc.DeepEquals, &Record{
+			ID:          id,
+			Type:        TypeResponse,
+			TargetURI:   "http://example.com/",
+			Date:        date,
+			ContentType: ContentTypeHTTPResponse,
+			Fields:      []Field{{Name: "WARC-IP-Address", Value: "10.0.0.1"}},
+			Block:       []byte("HTTP/1.1 200 OK\r\n\r\n"),
+		})
+		c.Assert(rec.Field("warc-ip-address"), gc.Equals, "10.0.0.1")
+
+		_, err = r.Next()
+		c.Assert(err, gc.Equals, io.EOF)
+	}
+}
+
+func (s *WARCTestSuite) TestReadMalformedRecord(c *gc.C) {
+	specs := []struct {
+		input  string
+		expErr string
+	}{
+		{"HTTP/1.1 200 OK\r\n", `warc: unsupported record version "HTTP/1.1 200 OK"`},
+		{"WARC/1.0\r\nWARC-Type: response\r\n\r\n", "warc: record without content length"},
+		{"WARC/1.0\r\nContent-Length: 10\r\n\r\nabc", "warc: read block: unexpected EOF"},
+		{"WARC/1.0\r\nWARC-Type response\r\n", `warc: malformed header line "WARC-Type response"`},
+	}
+
+	for i, spec := range specs {
+		r, err := NewReader(strings.NewReader(spec.input))
+		c.Assert(err, gc.IsNil)
+		_, err = r.Next()
+		c.Assert(err, gc.ErrorMatches, spec.expErr, gc.Commentf("spec %d", i))
+	}
+}
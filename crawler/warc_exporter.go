@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/brandonshearin/ask_brandon/crawler/warc"
+	"github.com/brandonshearin/ask_brandon/pipeline"
+)
+
+// warcExporter writes the fetched pages to a WARC file. Each page is stored
+// as a request/response record pair followed by a metadata record with the
+// crawl pass ID and the extracted outlinks.
+type warcExporter struct {
+	w *warc.Writer
+}
+
+func newWARCExporter(w *warc.Writer) *warcExporter {
+	return &warcExporter{w: w}
+}
+
+// Process writes the payload to the WARC file and discards it so that it does
+// not affect the counts reported by Crawl.
+func (e *warcExporter) Process(ctx context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+	payload := p.(*Payload)
+	targetURI := payload.FinalURL
+	if targetURI == "" {
+		targetURI = payload.URL
+	}
+
+	resBlock, bodyOffset, err := httpResponseBlock(payload)
+	if err != nil {
+		return nil, err
+	}
+	resID, err := e.w.WriteRecord(&warc.Record{
+		Type:          warc.TypeResponse,
+		TargetURI:     targetURI,
+		Date:          payload.FetchedAt,
+		ContentType:   warc.ContentTypeHTTPResponse,
+		Block:         resBlock,
+		PayloadOffset: bodyOffset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	related := []warc.Field{{Name: "WARC-Concurrent-To", Value: resID}}
+	if _, err = e.w.WriteRecord(&warc.Record{
+		Type:        warc.TypeRequest,
+		TargetURI:   targetURI,
+		Date:        payload.FetchedAt,
+		ContentType: warc.ContentTypeHTTPRequest,
+		Fields:      related,
+		Block:       httpRequestBlock(targetURI),
+	}); err != nil {
+		return nil, err
+	}
+
+	if _, err = e.w.WriteRecord(&warc.Record{
+		Type:        warc.TypeMetadata,
+		TargetURI:   targetURI,
+		Date:        payload.FetchedAt,
+		ContentType: warc.ContentTypeFields,
+		Fields:      related,
+		Block:       warc.FormatFields(warcMetadataFields(payload)),
+	}); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// httpResponseBlock reconstructs the HTTP response for payload and returns it
+// along with the offset of the response body. The body has already been
+// decoded by the HTTP client, so the headers describing its transfer encoding
+// are dropped and the Content-Length is adjusted to match.
+func httpResponseBlock(payload *Payload) ([]byte, int, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %d %s\r\n", payload.HTTPStatus, http.StatusText(payload.HTTPStatus))
+
+	header := payload.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+		if payload.ContentType != "" {
+			header.Set("Content-Type", payload.ContentType)
+		}
+	}
+	header.Del("Content-Encoding")
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.FormatInt(payload.RawContent.Len(), 10))
+	if err := header.Write(&buf); err != nil {
+		return nil, 0, err
+	}
+	buf.WriteString("\r\n")
+
+	bodyOffset := buf.Len()
+	if _, err := io.Copy(&buf, payload.RawContent.Reader()); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), bodyOffset, nil
+}
+
+// httpRequestBlock reconstructs the GET request that was sent for targetURI.
+func httpRequestBlock(targetURI string) []byte {
+	u, err := url.Parse(targetURI)
+	if err != nil {
+		return nil
+	}
+	return []byte("GET " + u.RequestURI() + " HTTP/1.1\r\nHost: " + u.Host + "\r\n\r\n")
+}
+
+func warcMetadataFields(payload *Payload) []warc.Field {
+	fields := []warc.Field{{Name: "crawl-pass-id", Value: strconv.FormatUint(payload.CrawlPassID, 10)}}
+	if payload.FinalURL != "" && payload.FinalURL != payload.URL {
+		fields = append(fields, warc.Field{Name: "via", Value: payload.URL})
+	}
+	for _, link := range payload.Links {
+		fields = append(fields, warc.Field{Name: "outlink", Value: link})
+	}
+	for _, link := range payload.NoFollowLinks {
+		fields = append(fields, warc.Field{Name: "outlink", Value: link})
+	}
+	return fields
+}
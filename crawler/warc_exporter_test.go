@@ -0,0 +1,60 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/crawler/warc"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(WARCExporterTestSuite))
+
+type WARCExporterTestSuite struct{}
+
+func (s *WARCExporterTestSuite) TestExportPayload(c *gc.C) {
+	var buf bytes.Buffer
+	exporter := newWARCExporter(warc.NewWriter(&buf, false))
+
+	p := &Payload{
+		URL:         "http://example.com/old",
+		FinalURL:    "http://example.com/new?q=1",
+		CrawlPassID: 1,
+		FetchedAt:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		HTTPStatus:  200,
+		Header: http.Header{
+			"Content-Type":     {"text/html"},
+			"Content-Encoding": {"gzip"},
+			"Content-Length":   {"12"},
+		},
+		Links: []string{"http://example.com/about"},
+	}
+	_, err := p.RawContent.WriteString("<html></html>")
+	c.Assert(err, gc.IsNil)
+
+	out, err := exporter.Process(context.TODO(), p)
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.IsNil, gc.Commentf("expected exported payloads to be discarded"))
+
+	records := strings.Split(buf.String(), "WARC/1.1\r\n")[1:]
+	c.Assert(records, gc.HasLen, 3)
+	c.Assert(records[0], gc.Matches, `(?s)WARC-Type: response\r\n.*WARC-Target-URI: http://example.com/new\?q=1\r\n.*`)
+	c.Assert(records[1], gc.Matches, `(?s)WARC-Type: request\r\n.*WARC-Concurrent-To: <urn:uuid:.*GET /new\?q=1 HTTP/1.1\r\nHost: example.com\r\n.*`)
+	c.Assert(records[2], gc.Matches, `(?s)WARC-Type: metadata\r\n.*crawl-pass-id: 1\r\nvia: http://example.com/old\r\noutlink: http://example.com/about\r\n.*`)
+
+	// The response block must be a valid HTTP response describing the
+	// decoded body
+	block := records[0][strings.Index(records[0], "\r\n\r\n")+4:]
+	res, err := http.ReadResponse(bufio.NewReader(strings.NewReader(block)), nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(res.StatusCode, gc.Equals, 200)
+	c.Assert(res.Header.Get("Content-Encoding"), gc.Equals, "")
+	body, err := ioutil.ReadAll(res.Body)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(body), gc.Equals, "<html></html>")
+}
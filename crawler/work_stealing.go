@@ -0,0 +1,160 @@
+package crawler
+
+import (
+	"sync"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+)
+
+// stealCoordinator hands out the links of the partitions that are crawled by
+// a pass in batches. Each partition is primarily read by its own pipeline but
+// once a pipeline runs out of links it steals batches from the partition that
+// lags the most so that small partitions do not leave their pipelines idle
+// while others are still crawling. It is safe for concurrent use.
+type stealCoordinator struct {
+	batchSize  int
+	partitions []*stealPartition
+
+	mu  sync.Mutex
+	err error
+}
+
+// stealPartition tracks how far a partition has been read.
+type stealPartition struct {
+	mu        sync.Mutex
+	linkIt    graph.LinkIterator
+	handedOut int
+	exhausted bool
+}
+
+// newStealCoordinator returns a coordinator that reads the links of linkIts
+// in batches of batchSize links.
+func newStealCoordinator(linkIts []graph.LinkIterator, batchSize int) *stealCoordinator {
+	sc := &stealCoordinator{batchSize: batchSize}
+	for _, linkIt := range linkIts {
+		sc.partitions = append(sc.partitions, &stealPartition{linkIt: linkIt})
+	}
+	return sc
+}
+
+// iterator returns a graph.LinkIterator that yields the links of the
+// partition with the specified index followed by the links that are stolen
+// from the other partitions once it has been exhausted. Closing the iterator
+// does not close the underlying partition iterators.
+func (sc *stealCoordinator) iterator(home int) graph.LinkIterator {
+	return &stealingLinkIterator{sc: sc, home: home}
+}
+
+// nextBatch returns the next batch of links for the pipeline that crawls the
+// home partition or nil if all partitions have been exhausted.
+func (sc *stealCoordinator) nextBatch(home int) []*graph.Link {
+	if batch := sc.read(sc.partitions[home]); len(batch) != 0 {
+		return batch
+	}
+
+	// The victim may be exhausted by another pipeline before we get to
+	// read from it; keep looking until all partitions are exhausted
+	for {
+		victim := sc.victim()
+		if victim == nil {
+			return nil
+		}
+		if batch := sc.read(victim); len(batch) != 0 {
+			return batch
+		}
+	}
+}
+
+// victim returns the partition that has handed out the fewest links so far
+// and is not yet exhausted or nil if there is no such partition.
+func (sc *stealCoordinator) victim() *stealPartition {
+	var (
+		victim    *stealPartition
+		handedOut int
+	)
+	for _, p := range sc.partitions {
+		p.mu.Lock()
+		if !p.exhausted && (victim == nil || p.handedOut < handedOut) {
+			victim, handedOut = p, p.handedOut
+		}
+		p.mu.Unlock()
+	}
+	return victim
+}
+
+// read returns up to batchSize links from p. Once the partition iterator is
+// exhausted, p is marked as such and any iterator error is recorded.
+func (sc *stealCoordinator) read(p *stealPartition) []*graph.Link {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.exhausted {
+		return nil
+	}
+
+	batch := make([]*graph.Link, 0, sc.batchSize)
+	for len(batch) < sc.batchSize {
+		if !p.linkIt.Next() {
+			p.exhausted = true
+			if err := p.linkIt.Error(); err != nil {
+				sc.setError(err)
+			}
+			break
+		}
+		// Iterators may reuse the returned link between calls to Next
+		link := *p.linkIt.Link()
+		batch = append(batch, &link)
+	}
+	p.handedOut += len(batch)
+	return batch
+}
+
+func (sc *stealCoordinator) setError(err error) {
+	sc.mu.Lock()
+	if sc.err == nil {
+		sc.err = err
+	}
+	sc.mu.Unlock()
+}
+
+func (sc *stealCoordinator) error() error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.err
+}
+
+// stealingLinkIterator is a graph.LinkIterator over the batches handed out
+// by a stealCoordinator.
+type stealingLinkIterator struct {
+	sc   *stealCoordinator
+	home int
+
+	batch []*graph.Link
+	cur   int
+}
+
+// Next implements graph.LinkIterator.
+func (it *stealingLinkIterator) Next() bool {
+	if it.cur < len(it.batch) {
+		it.cur++
+		return true
+	}
+	if it.sc.error() != nil {
+		return false
+	}
+
+	if it.batch, it.cur = it.sc.nextBatch(it.home), 0; len(it.batch) == 0 {
+		return false
+	}
+	it.cur++
+	return true
+}
+
+// Link implements graph.LinkIterator.
+func (it *stealingLinkIterator) Link() *graph.Link { return it.batch[it.cur-1] }
+
+// Error implements graph.LinkIterator. An error reading any of the partitions
+// is reported by all iterators of the coordinator.
+func (it *stealingLinkIterator) Error() error { return it.sc.error() }
+
+// Close implements graph.LinkIterator.
+func (it *stealingLinkIterator) Close() error { return nil }
@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(WorkStealingTestSuite))
+
+type WorkStealingTestSuite struct{}
+
+func (s *WorkStealingTestSuite) TestIdleIteratorStealsFromLaggingPartition(c *gc.C) {
+	sc := newStealCoordinator([]graph.LinkIterator{
+		newSliceLinkIterator("http://small.com/1"),
+		newSliceLinkIterator(partitionURLs("a.com", 6)...),
+		newSliceLinkIterator(partitionURLs("b.com", 6)...),
+	}, 2)
+
+	// The b.com partition is ahead of the a.com one
+	c.Assert(drain(sc.iterator(2), 4), gc.HasLen, 4)
+
+	// Once its own partition is exhausted, the iterator steals from the
+	// partition that handed out the fewest links
+	c.Assert(drain(sc.iterator(0), 4), gc.DeepEquals, []string{
+		"http://small.com/1",
+		"http://a.com/0",
+		"http://a.com/1",
+		"http://a.com/2",
+	})
+}
+
+func (s *WorkStealingTestSuite) TestEachLinkIsHandedOutOnce(c *gc.C) {
+	var (
+		linkIts []graph.LinkIterator
+		expURLs []string
+	)
+	for i, size := range []int{1, 50, 7, 200} {
+		urls := partitionURLs(fmt.Sprintf("%d.com", i), size)
+		linkIts = append(linkIts, newSliceLinkIterator(urls...))
+		expURLs = append(expURLs, urls...)
+	}
+	sc := newStealCoordinator(linkIts, 3)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		urls []string
+	)
+	for i := range linkIts {
+		wg.Add(1)
+		go func(home int) {
+			defer wg.Done()
+			crawled := drain(sc.iterator(home), -1)
+			mu.Lock()
+			urls = append(urls, crawled...)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Strings(urls)
+	sort.Strings(expURLs)
+	c.Assert(urls, gc.DeepEquals, expURLs)
+}
+
+// drain returns the URLs of up to limit links read from it or of all its
+// links if limit is negative.
+func drain(it graph.LinkIterator, limit int) []string {
+	var urls []string
+	for len(urls) != limit && it.Next() {
+		urls = append(urls, it.Link().URL)
+	}
+	return urls
+}
+
+func partitionURLs(host string, count int) []string {
+	urls := make([]string, count)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("http://%s/%d", host, i)
+	}
+	return urls
+}
@@ -0,0 +1,529 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/report"
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	//adminListLimit is the number of entries shown in each list of the
+	//admin dashboard
+	adminListLimit = 10
+
+	//adminJobTimeout bounds the time spent waiting for a service to
+	//acknowledge a job trigger
+	adminJobTimeout = 10 * time.Second
+
+	//defaultNeighborhoodRadius and maxNeighborhoodRadius control the
+	//number of hops around the seed link included in link neighborhoods
+	defaultNeighborhoodRadius = 2
+	maxNeighborhoodRadius     = 4
+)
+
+//AdminConfig encapsulates the settings for the admin area of the frontend.  The
+//admin area is only enabled if both a username and a password are specified;
+//all other fields are optional and the corresponding dashboard sections are
+//omitted if they are not provided
+type AdminConfig struct {
+	//Username and Password are the HTTP basic auth credentials required
+	//for accessing the admin area
+	Username string
+	Password string
+
+	//Graph provides the link graph stats and top domains.  If it (or the
+	//graph it decorates, see graph.Wrapper) implements
+	//graph.CrawlPassDiffer, the differences between two crawl passes can be
+	//retrieved from the /admin/passdiff endpoint.  If it implements
+	//graph.MemoryReporter, its memory footprint is reported too.  If it
+	//can look up links (see report.NeighborhoodGraph), the neighborhood of
+	//a link can be retrieved from the /admin/neighborhood endpoint
+	Graph AdminGraph
+
+	//Index provides the text index stats.  If it implements
+	//IndexStorageStats, the storage footprint of the index is reported too.
+	//If it implements report.ScoreStore, the nodes of link neighborhoods
+	//are sized by PageRank
+	Index IndexStats
+
+	//CrawlPasses provides the history of crawl passes
+	CrawlPasses CrawlPassHistory
+
+	//Errors provides the most recent errors reported by the services
+	Errors ErrorLog
+
+	//Jobs is used for triggering crawl and PageRank passes on demand
+	Jobs JobTrigger
+
+	//Pipelines provides the topology and live per-stage statistics of the
+	//deployed processing pipelines which are served by the /admin/pipelines
+	//endpoint as JSON or, with format=dot, as a Graphviz graph
+	Pipelines PipelineDescriber
+
+	//Webmaster lets verified site owners claim and verify their sites,
+	//request recrawls, set crawl rate preferences and remove URLs from the
+	//index via the /admin/webmaster endpoints
+	Webmaster WebmasterTools
+}
+
+func (cfg AdminConfig) enabled() bool {
+	return cfg.Username != "" && cfg.Password != ""
+}
+
+//AdminGraph is implemented by link graphs that can report their size and list
+//their links
+type AdminGraph interface {
+	report.Graph
+	Stats() (*graph.Stats, error)
+}
+
+//IndexStats is implemented by text indexers that can report their size
+type IndexStats interface {
+	DocumentCount() (uint64, error)
+}
+
+//IndexStorageStats is implemented by text indexers that can estimate their
+//storage footprint (see index.Indexer)
+type IndexStorageStats interface {
+	StorageStats() (*index.StorageStats, error)
+}
+
+//PipelineDescriber is implemented by services that can describe their
+//processing pipelines (see crawler.Crawler)
+type PipelineDescriber interface {
+	//DescribePipelines returns the pipeline descriptions keyed by name
+	DescribePipelines() map[string]*pipeline.Description
+}
+
+//CrawlPass summarizes a single crawl pass
+type CrawlPass struct {
+	ID             string    `json:"id"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at,omitempty"`
+	LinksProcessed int       `json:"links_processed"`
+	Errors         int       `json:"errors"`
+}
+
+//CrawlPassHistory is implemented by objects that keep track of crawl passes
+type CrawlPassHistory interface {
+	//RecentCrawlPasses returns up to limit crawl passes, most recent first
+	RecentCrawlPasses(limit int) ([]CrawlPass, error)
+}
+
+//ErrorEntry describes an error reported by one of the services
+type ErrorEntry struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	URL     string    `json:"url,omitempty"`
+	Message string    `json:"message"`
+}
+
+//ErrorLog is implemented by objects that keep track of service errors
+type ErrorLog interface {
+	//RecentErrors returns up to limit errors, most recent first
+	RecentErrors(limit int) ([]ErrorEntry, error)
+}
+
+//JobTrigger is implemented by clients of the services that run crawl and
+//PageRank passes
+type JobTrigger interface {
+	TriggerCrawlPass(ctx context.Context) error
+	TriggerPageRankPass(ctx context.Context) error
+}
+
+//graphStats is the JSON representation of graph.Stats
+type graphStats struct {
+	Links  uint64       `json:"links"`
+	Edges  uint64       `json:"edges"`
+	Memory *graphMemory `json:"memory,omitempty"`
+}
+
+//graphMemory is the JSON representation of graph.MemoryStats
+type graphMemory struct {
+	LinkBytes         uint64 `json:"link_bytes"`
+	EdgeBytes         uint64 `json:"edge_bytes"`
+	IndexBytes        uint64 `json:"index_bytes"`
+	CrawlPassLogBytes uint64 `json:"crawl_pass_log_bytes"`
+	TotalBytes        uint64 `json:"total_bytes"`
+}
+
+//indexStats describes the text index
+type indexStats struct {
+	Documents uint64        `json:"documents"`
+	Storage   *indexStorage `json:"storage,omitempty"`
+}
+
+//indexStorage is the JSON representation of index.StorageStats
+type indexStorage struct {
+	MemoryBytes uint64 `json:"memory_bytes"`
+	DiskBytes   uint64 `json:"disk_bytes"`
+	Segments    int    `json:"segments"`
+}
+
+//passDiff is the JSON representation of graph.PassDiff
+type passDiff struct {
+	PassA            uint64   `json:"pass_a"`
+	PassB            uint64   `json:"pass_b"`
+	NewLinks         []string `json:"new_links"`
+	RemovedLinks     []string `json:"removed_links"`
+	NewEdges         []string `json:"new_edges"`
+	RemovedEdges     []string `json:"removed_edges"`
+	ChangedDocuments []string `json:"changed_documents"`
+}
+
+//adminDashboard is returned by the admin dashboard endpoint.  Sections whose
+//data could not be retrieved are listed in Unavailable
+type adminDashboard struct {
+	Graph        *graphStats          `json:"graph,omitempty"`
+	Index        *indexStats          `json:"index,omitempty"`
+	TopDomains   []report.DomainCount `json:"top_domains,omitempty"`
+	CrawlPasses  []CrawlPass          `json:"crawl_passes,omitempty"`
+	RecentErrors []ErrorEntry         `json:"recent_errors,omitempty"`
+	Unavailable  []string             `json:"unavailable,omitempty"`
+	CanTrigger   bool                 `json:"can_trigger"`
+}
+
+//registerAdminHandlers adds the admin endpoints to the service mux
+func (svc *Service) registerAdminHandlers() {
+	svc.mux.HandleFunc("/admin", svc.requireAdmin(svc.renderAdminDashboard))
+	svc.mux.HandleFunc("/admin/crawl", svc.requireAdmin(svc.triggerJob("crawl", JobTrigger.TriggerCrawlPass)))
+	svc.mux.HandleFunc("/admin/pagerank", svc.requireAdmin(svc.triggerJob("pagerank", JobTrigger.TriggerPageRankPass)))
+	svc.mux.HandleFunc("/admin/passdiff", svc.requireAdmin(svc.renderPassDiff))
+	svc.mux.HandleFunc("/admin/neighborhood", svc.requireAdmin(svc.renderNeighborhood))
+	svc.mux.HandleFunc("/admin/pipelines", svc.requireAdmin(svc.renderPipelines))
+	svc.registerWebmasterHandlers()
+	svc.registerRemovalHandlers()
+}
+
+//requireAdmin wraps h so that it can only be invoked with the admin credentials
+func (svc *Service) requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		userOK := subtle.ConstantTimeCompare([]byte(user), []byte(svc.cfg.Admin.Username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(svc.cfg.Admin.Password)) == 1
+		if !ok || !userOK || !passOK {
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (svc *Service) renderAdminDashboard(w http.ResponseWriter, r *http.Request) {
+	var (
+		cfg  = svc.cfg.Admin
+		dash = adminDashboard{CanTrigger: cfg.Jobs != nil}
+	)
+	unavailable := func(section string, err error) bool {
+		if err != nil {
+			dash.Unavailable = append(dash.Unavailable, section)
+			return true
+		}
+		return false
+	}
+
+	if cfg.Graph != nil {
+		if stats, err := cfg.Graph.Stats(); !unavailable("graph", err) {
+			dash.Graph = &graphStats{Links: stats.Links, Edges: stats.Edges}
+			if reporter, ok := graph.AsMemoryReporter(cfg.Graph); ok {
+				if mem, err := reporter.MemoryStats(); !unavailable("graph_memory", err) {
+					dash.Graph.Memory = &graphMemory{
+						LinkBytes:         mem.Links,
+						EdgeBytes:         mem.Edges,
+						IndexBytes:        mem.Indices,
+						CrawlPassLogBytes: mem.CrawlPassLogs,
+						TotalBytes:        mem.Total(),
+					}
+				}
+			}
+		}
+		if domains, err := report.TopDomains(cfg.Graph, adminListLimit); !unavailable("top_domains", err) {
+			dash.TopDomains = domains
+		}
+	}
+	if cfg.Index != nil {
+		if count, err := cfg.Index.DocumentCount(); !unavailable("index", err) {
+			dash.Index = &indexStats{Documents: count}
+			if storage, ok := cfg.Index.(IndexStorageStats); ok {
+				if stats, err := storage.StorageStats(); !unavailable("index_storage", err) {
+					dash.Index.Storage = &indexStorage{
+						MemoryBytes: stats.MemoryBytes,
+						DiskBytes:   stats.DiskBytes,
+						Segments:    stats.Segments,
+					}
+				}
+			}
+		}
+	}
+	if cfg.CrawlPasses != nil {
+		if passes, err := cfg.CrawlPasses.RecentCrawlPasses(adminListLimit); !unavailable("crawl_passes", err) {
+			dash.CrawlPasses = passes
+		}
+	}
+	if cfg.Errors != nil {
+		if errs, err := cfg.Errors.RecentErrors(adminListLimit); !unavailable("recent_errors", err) {
+			dash.RecentErrors = errs
+		}
+	}
+
+	if !wantsHTML(r) {
+		writeJSON(w, dash)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := adminTemplate.Execute(&buf, struct {
+		adminDashboard
+		SiteName string
+		Message  string
+	}{dash, svc.cfg.SiteName, r.URL.Query().Get("msg")}); err != nil {
+		http.Error(w, "unable to render admin dashboard", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+//renderPassDiff reports the differences between the crawl passes specified by
+//the a and b query parameters
+func (svc *Service) renderPassDiff(w http.ResponseWriter, r *http.Request) {
+	differ, ok := graph.AsCrawlPassDiffer(svc.cfg.Admin.Graph)
+	if !ok {
+		http.Error(w, "crawl pass diffs are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	passA, errA := strconv.ParseUint(r.URL.Query().Get("a"), 10, 64)
+	passB, errB := strconv.ParseUint(r.URL.Query().Get("b"), 10, 64)
+	if errA != nil || errB != nil {
+		http.Error(w, "crawl passes must be specified as a and b", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := differ.Diff(passA, passB)
+	if xerrors.Is(err, graph.ErrNotFound) {
+		http.Error(w, "unknown crawl pass", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "unable to diff crawl passes", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, passDiff{
+		PassA:            diff.PassA,
+		PassB:            diff.PassB,
+		NewLinks:         diff.NewLinks,
+		RemovedLinks:     diff.RemovedLinks,
+		NewEdges:         diff.NewEdges,
+		RemovedEdges:     diff.RemovedEdges,
+		ChangedDocuments: diff.ChangedDocuments,
+	})
+}
+
+//renderNeighborhood exports the subgraph around the link specified by the seed
+//query parameter for visualization.  The radius parameter sets the number of
+//hops to follow and the format parameter selects between the D3 (default) and
+//vis.js JSON formats
+func (svc *Service) renderNeighborhood(w http.ResponseWriter, r *http.Request) {
+	g, ok := svc.cfg.Admin.Graph.(report.NeighborhoodGraph)
+	if !ok {
+		http.Error(w, "link neighborhoods are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	params := r.URL.Query()
+	seed, err := uuid.Parse(params.Get("seed"))
+	if err != nil {
+		http.Error(w, "a valid seed link ID must be specified", http.StatusBadRequest)
+		return
+	}
+	radius := defaultNeighborhoodRadius
+	if v := params.Get("radius"); v != "" {
+		if radius, err = strconv.Atoi(v); err != nil || radius < 0 || radius > maxNeighborhoodRadius {
+			http.Error(w, "radius must be between 0 and "+strconv.Itoa(maxNeighborhoodRadius), http.StatusBadRequest)
+			return
+		}
+	}
+	format := params.Get("format")
+	if format != "" && format != "d3" && format != "vis" {
+		http.Error(w, "format must be d3 or vis", http.StatusBadRequest)
+		return
+	}
+
+	scores, _ := svc.cfg.Admin.Index.(report.ScoreStore)
+	nh, err := report.BuildNeighborhood(g, scores, seed, radius, 0)
+	if xerrors.Is(err, graph.ErrNotFound) {
+		http.Error(w, "unknown seed link", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "unable to build link neighborhood", http.StatusInternalServerError)
+		return
+	}
+
+	if nh.Truncated {
+		w.Header().Set("X-Neighborhood-Truncated", "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if format == "vis" {
+		_ = nh.WriteVis(w)
+		return
+	}
+	_ = nh.WriteD3(w)
+}
+
+//renderPipelines describes the deployed pipelines as JSON or, if the format
+//query parameter is set to "dot", as Graphviz graphs
+func (svc *Service) renderPipelines(w http.ResponseWriter, r *http.Request) {
+	if svc.cfg.Admin.Pipelines == nil {
+		http.Error(w, "pipeline descriptions are not configured", http.StatusNotImplemented)
+		return
+	}
+
+	descriptions := svc.cfg.Admin.Pipelines.DescribePipelines()
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, descriptions)
+	case "dot":
+		names := make([]string, 0, len(descriptions))
+		for name := range descriptions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		for _, name := range names {
+			_, _ = io.WriteString(w, descriptions[name].DOT(name))
+		}
+	default:
+		http.Error(w, "format must be json or dot", http.StatusBadRequest)
+	}
+}
+
+//triggerJob returns a handler that starts a job via trigger.  Forms submitted
+//from the dashboard are redirected back to it
+func (svc *Service) triggerJob(name string, trigger func(JobTrigger, context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		if svc.cfg.Admin.Jobs == nil {
+			http.Error(w, "job triggers are not configured", http.StatusNotImplemented)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), adminJobTimeout)
+		defer cancel()
+		if err := trigger(svc.cfg.Admin.Jobs, ctx); err != nil {
+			http.Error(w, "unable to trigger "+name+" pass", http.StatusBadGateway)
+			return
+		}
+
+		if wantsHTML(r) {
+			http.Redirect(w, r, "/admin?msg="+url.QueryEscape(name+" pass triggered"), http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		writeJSON(w, map[string]string{"status": name + " pass triggered"})
+	}
+}
+
+//sameOrigin returns false if r was sent by a page served from a different
+//origin, e.g. a malicious site that tries to trigger jobs using the cached
+//credentials of an admin
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+var adminTemplate = template.Must(template.New("admin").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Admin - {{.SiteName}}</title>
+<style>
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+.unavailable { color: #a94442; }
+</style>
+</head>
+<body>
+<h1>Admin</h1>
+{{- if .Message}}
+<p class="message">{{.Message}}</p>
+{{- end}}
+{{- if .Unavailable}}
+<p class="unavailable">Unavailable: {{range $i, $s := .Unavailable}}{{if $i}}, {{end}}{{$s}}{{end}}</p>
+{{- end}}
+{{- if .CanTrigger}}
+<form action="/admin/crawl" method="post"><button type="submit">Run crawl pass</button></form>
+<form action="/admin/pagerank" method="post"><button type="submit">Run PageRank pass</button></form>
+{{- end}}
+{{- with .Graph}}
+<h2>Link graph</h2>
+<p>{{.Links}} links, {{.Edges}} edges</p>
+{{- with .Memory}}
+<p>~{{.TotalBytes}} bytes in memory</p>
+{{- end}}
+{{- end}}
+{{- with .Index}}
+<h2>Text index</h2>
+<p>{{.Documents}} documents</p>
+{{- with .Storage}}
+<p>~{{.MemoryBytes}} bytes in memory, {{.DiskBytes}} bytes on disk, {{.Segments}} segments</p>
+{{- end}}
+{{- end}}
+{{- if .TopDomains}}
+<h2>Top domains</h2>
+<table>
+<tr><th>Domain</th><th>Links</th></tr>
+{{- range .TopDomains}}
+<tr><td>{{.Domain}}</td><td>{{.Links}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .CrawlPasses}}
+<h2>Crawl passes</h2>
+<table>
+<tr><th>ID</th><th>Started</th><th>Finished</th><th>Links</th><th>Errors</th></tr>
+{{- range .CrawlPasses}}
+<tr><td>{{.ID}}</td><td>{{.StartedAt.Format "2006-01-02 15:04:05"}}</td><td>{{if not .FinishedAt.IsZero}}{{.FinishedAt.Format "2006-01-02 15:04:05"}}{{else}}running{{end}}</td><td>{{.LinksProcessed}}</td><td>{{.Errors}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .RecentErrors}}
+<h2>Recent errors</h2>
+<table>
+<tr><th>Time</th><th>Source</th><th>URL</th><th>Message</th></tr>
+{{- range .RecentErrors}}
+<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.Source}}</td><td>{{.URL}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
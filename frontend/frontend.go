@@ -0,0 +1,484 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/apiauth"
+	"github.com/brandonshearin/ask_brandon/querylog/query"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/brandonshearin/ask_brandon/textindexer/searchutil"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultTrendingWindow  = 24 * time.Hour
+	defaultTrendingLimit   = 10
+	defaultSiteName        = "ask_brandon"
+	defaultShutdownTimeout = 10 * time.Second
+	maxResultsPerPage      = 10
+
+	//snippetLength is the maximum length of the snippets shown for each
+	//search result
+	snippetLength = 200
+)
+
+// Indexer is implemented by objects that can search the indexed documents
+type Indexer interface {
+	Search(query index.Query) (index.Iterator, error)
+}
+
+// Suggester is optionally implemented by Indexer instances that can suggest
+// corrections for search expressions.  It powers the suggestions endpoint
+type Suggester interface {
+	Suggestions(query index.Query) ([]string, error)
+}
+
+// Config encapsulates the settings for configuring the frontend service
+type Config struct {
+	//ListenAddress is the address the frontend HTTP server listens on
+	ListenAddress string
+
+	//Indexer is used for executing search queries
+	Indexer Indexer
+
+	//QueryLog, if specified, is used for recording the submitted queries
+	//and the clicked results.  It also powers the trending searches
+	//endpoint
+	QueryLog query.Store
+
+	//TrendingWindow controls how far back the trending searches endpoint
+	//looks.  If not specified, a default value of 24h will be used
+	TrendingWindow time.Duration
+
+	//SiteName is the name under which browsers list the search engine.
+	//If not specified, a default value of "ask_brandon" will be used
+	SiteName string
+
+	//BaseURL is the public URL of the frontend (e.g. https://example.com)
+	//used for the links in the OpenSearch description.  If not specified,
+	//it is derived from the incoming request
+	BaseURL string
+
+	//Admin configures the authenticated admin area.  It is disabled
+	//unless admin credentials are specified
+	Admin AdminConfig
+
+	//APIAuth, if specified, requires clients to authenticate with an API
+	//key and rate limits their requests.  Set AllowAnonymous to keep the
+	//frontend usable from browsers
+	APIAuth *apiauth.Config
+
+	//Related, if specified, powers the related pages endpoint
+	//(/api/v1/related/{id}) that lists the pages similar to a search
+	//result.  Search results then link to the endpoint
+	Related RelatedFinder
+
+	//Sitelinks, if specified, provides the important pages of the sites
+	//of search results.  They are listed under the top result of the first
+	//page of results
+	Sitelinks SitelinkProvider
+
+	//URLStatus, if specified, powers the URL status endpoint
+	//(/api/v1/urlstatus) that reports whether a batch of URLs is known
+	//to the crawler and, if the indexer can look up documents by URL,
+	//whether their pages have been indexed
+	URLStatus LinkFinder
+
+	//Removals, if specified, powers the removal endpoint (/api/v1/removals)
+	//where anyone can ask for a URL to be removed from the index.  The
+	//requests are moderated via the /admin/removals endpoints
+	Removals RemovalQueue
+
+	//Collapse, if specified, enables collapsing near-duplicate results and
+	//results from sites that have too many results on the same page (see
+	//the searchutil package).  Users can still request all results by
+	//setting the collapse parameter to "off"
+	Collapse *searchutil.CollapseConfig
+
+	//ShutdownTimeout is the time given to in-flight requests to complete
+	//once the context passed to Run expires.  If not specified, a default
+	//value of 10s will be used
+	ShutdownTimeout time.Duration
+}
+
+func (cfg *Config) validate() error {
+	if cfg.ListenAddress == "" {
+		return xerrors.New("listen address has not been specified")
+	}
+	if cfg.Indexer == nil {
+		return xerrors.New("indexer has not been provided")
+	}
+	if cfg.TrendingWindow <= 0 {
+		cfg.TrendingWindow = defaultTrendingWindow
+	}
+	if cfg.SiteName == "" {
+		cfg.SiteName = defaultSiteName
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if cfg.BaseURL != "" {
+		u, err := url.Parse(cfg.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return xerrors.Errorf("invalid base URL %q", cfg.BaseURL)
+		}
+		cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	return nil
+}
+
+// Service implements the search engine frontend
+type Service struct {
+	cfg Config
+	mux *http.ServeMux
+
+	//handler serves the requests to the mux, possibly via middleware
+	handler http.Handler
+}
+
+// NewService creates a new frontend service instance with the specified config
+func NewService(cfg Config) (*Service, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("frontend service: config validation failed: %w", err)
+	}
+
+	svc := &Service{cfg: cfg, mux: http.NewServeMux()}
+	svc.mux.HandleFunc("/search", svc.renderSearchResults)
+	svc.mux.HandleFunc("/click", svc.recordClick)
+	svc.mux.HandleFunc("/trending", svc.renderTrendingSearches)
+	svc.mux.HandleFunc("/suggest", svc.renderSuggestions)
+	svc.mux.HandleFunc("/opensearch.xml", svc.renderOpenSearchDescription)
+	if cfg.Related != nil {
+		svc.mux.HandleFunc(relatedPath, svc.renderRelatedPages)
+	}
+	if cfg.URLStatus != nil {
+		svc.mux.HandleFunc(urlStatusPath, svc.renderURLStatus)
+	}
+	if cfg.Removals != nil {
+		svc.mux.HandleFunc(removalsPath, svc.submitRemoval)
+	}
+	if cfg.Admin.enabled() {
+		svc.registerAdminHandlers()
+	}
+
+	svc.handler = svc.mux
+	if cfg.APIAuth != nil {
+		auth, err := apiauth.New(*cfg.APIAuth)
+		if err != nil {
+			return nil, xerrors.Errorf("frontend service: %w", err)
+		}
+		svc.handler = auth.Wrap(svc.mux)
+	}
+	return svc, nil
+}
+
+// Run serves the frontend until the context expires.  Once it does, the
+// server stops accepting new connections and Run blocks until the in-flight
+// requests complete or the shutdown timeout elapses
+func (svc *Service) Run(ctx context.Context) error {
+	l, err := net.Listen("tcp", svc.cfg.ListenAddress)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Close() }()
+
+	srv := &http.Server{Addr: svc.cfg.ListenAddress, Handler: svc}
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), svc.cfg.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			_ = srv.Close()
+		}
+	}()
+
+	if err = srv.Serve(l); err == http.ErrServerClosed {
+		//Serve returns as soon as Shutdown is called; wait for the
+		//in-flight requests to drain
+		<-shutdownDone
+		err = nil
+	}
+	return err
+}
+
+// ServeHTTP implements http.Handler
+func (svc *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	//advertise the OpenSearch description so browsers can offer to add
+	//the frontend as a search provider
+	w.Header().Add("Link", svc.openSearchLink())
+	svc.handler.ServeHTTP(w, r)
+}
+
+// searchResult describes a single matched document
+type searchResult struct {
+	LinkID      uuid.UUID  `json:"link_id"`
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Snippet     string     `json:"snippet"`
+	PageRank    float64    `json:"pagerank"`
+	ClickURL    string     `json:"click_url"`
+	CommunityID string     `json:"community_id,omitempty"`
+	RelatedURL  string     `json:"related_url,omitempty"`
+	Sitelinks   []sitelink `json:"sitelinks,omitempty"`
+
+	//Collapsed lists the lower ranked results of the page that were
+	//collapsed under this one.  If some of them are from the same site,
+	//ExpandURL points to the search for all results from that site
+	Collapsed []searchResult `json:"collapsed,omitempty"`
+	ExpandURL string         `json:"expand_url,omitempty"`
+}
+
+// resultGroup lists the positions of the results that belong to the same
+// community of closely linked sites
+type resultGroup struct {
+	CommunityID string `json:"community_id"`
+	Positions   []int  `json:"positions"`
+}
+
+// searchResponse is returned by the search endpoint
+type searchResponse struct {
+	QueryID    uuid.UUID      `json:"query_id,omitempty"`
+	Expression string         `json:"expression"`
+	Offset     int            `json:"offset"`
+	Total      uint64         `json:"total"`
+	TookMillis float64        `json:"took_ms"`
+	Results    []searchResult `json:"results"`
+	Groups     []resultGroup  `json:"groups,omitempty"`
+
+	//hits is the number of results of the page including the collapsed
+	//ones
+	hits int
+}
+
+func (svc *Service) renderSearchResults(w http.ResponseWriter, r *http.Request) {
+	asHTML := wantsHTML(r)
+	expr := r.URL.Query().Get("q")
+	if expr == "" {
+		if asHTML {
+			//render an empty search form
+			svc.renderHTML(w, http.StatusOK, resultsPage{})
+			return
+		}
+		http.Error(w, "missing search expression", http.StatusBadRequest)
+		return
+	}
+	q, err := parseQuery(expr)
+	if err != nil {
+		if asHTML {
+			svc.renderHTML(w, http.StatusBadRequest, resultsPage{Expression: expr, Err: err.Error()})
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset")); q.Offset < 0 {
+		q.Offset = 0
+	}
+	//safe-search is enabled unless the user explicitly turns it off
+	q.ExcludeFiltered = r.URL.Query().Get("safe") != "off"
+
+	//results are collapsed unless the user explicitly turns it off
+	res, err := svc.search(expr, q, r.URL.Query().Get("collapse") != "off")
+	if err != nil {
+		status := http.StatusInternalServerError
+		if xerrors.Is(err, index.ErrUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		if asHTML {
+			svc.renderHTML(w, status, resultsPage{Expression: expr, Err: searchErrorMessage(status)})
+			return
+		}
+		http.Error(w, "search failed", status)
+		return
+	}
+	if r.URL.Query().Get("group") == "community" {
+		res.Groups = groupByCommunity(res.Results)
+	}
+
+	if asHTML {
+		svc.renderHTML(w, http.StatusOK, newResultsPage(r.URL, expr, q, res))
+		return
+	}
+	writeJSON(w, res)
+}
+
+// search executes q, which was parsed from the search expression expr, and
+// returns the page of results starting at q.Offset.  If collapse is set and
+// the service is configured to collapse results, near-duplicate results and
+// results from sites with too many results are nested under a higher ranked
+// result of the page
+func (svc *Service) search(expr string, q index.Query, collapse bool) (*searchResponse, error) {
+	start := time.Now()
+	it, err := svc.cfg.Indexer.Search(q)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = it.Close() }()
+
+	res := &searchResponse{Expression: expr, Offset: q.Offset, Total: it.TotalCount()}
+	if svc.cfg.QueryLog != nil {
+		rec := &query.Record{Expression: expr, ResultCount: res.Total}
+		//failing to log a query should not prevent users from searching
+		if err = svc.cfg.QueryLog.RecordQuery(rec); err == nil {
+			res.QueryID = rec.ID
+		}
+	}
+
+	var docs []*index.Document
+	for len(docs) < maxResultsPerPage && it.Next() {
+		docs = append(docs, it.Document())
+	}
+	if err = it.Error(); err != nil {
+		return nil, err
+	}
+	res.hits = len(docs)
+
+	if svc.cfg.Collapse == nil || !collapse {
+		for i, doc := range docs {
+			res.Results = append(res.Results, svc.newSearchResult(res.QueryID, q, doc, q.Offset+i))
+		}
+	} else {
+		positions := make(map[uuid.UUID]int, len(docs))
+		for i, doc := range docs {
+			positions[doc.LinkID] = q.Offset + i
+		}
+		for _, cluster := range searchutil.Collapse(docs, *svc.cfg.Collapse) {
+			r := svc.newSearchResult(res.QueryID, q, cluster.Doc, positions[cluster.Doc.LinkID])
+			for _, doc := range cluster.Collapsed {
+				r.Collapsed = append(r.Collapsed, svc.newSearchResult(res.QueryID, q, doc, positions[doc.LinkID]))
+			}
+			//results are already restricted to a single site if the
+			//expression uses the site: operator
+			if q.Site == "" && cluster.CollapsedFromSite() {
+				r.ExpandURL = expandSiteURL(expr, cluster.Site)
+			}
+			res.Results = append(res.Results, r)
+		}
+	}
+	if svc.cfg.Sitelinks != nil && q.Offset == 0 && len(res.Results) != 0 {
+		res.Results[0].Sitelinks = svc.sitelinksFor(res.QueryID, res.Results[0], 0)
+	}
+
+	res.TookMillis = float64(time.Since(start)) / float64(time.Millisecond)
+	return res, nil
+}
+
+// newSearchResult returns the search result for doc which is at the specified
+// position of the result set
+func (svc *Service) newSearchResult(queryID uuid.UUID, q index.Query, doc *index.Document, position int) searchResult {
+	r := searchResult{
+		LinkID:      doc.LinkID,
+		URL:         doc.URL,
+		Title:       doc.Title,
+		Snippet:     index.Snippet(doc, q, snippetLength),
+		PageRank:    doc.PageRank,
+		ClickURL:    clickURL(queryID, doc, position),
+		CommunityID: doc.CommunityID,
+	}
+	if svc.cfg.Related != nil {
+		r.RelatedURL = relatedURL(doc.LinkID)
+	}
+	return r
+}
+
+// expandSiteURL returns the URL of the search for expr restricted to site
+func expandSiteURL(expr, site string) string {
+	params := url.Values{}
+	params.Set("q", expr+" site:"+site)
+	return "/search?" + params.Encode()
+}
+
+// groupByCommunity groups the positions of results by community ID in the order
+// in which each community first appears.  Results without a community ID are
+// not grouped
+func groupByCommunity(results []searchResult) []resultGroup {
+	var (
+		groups  []resultGroup
+		indices = make(map[string]int)
+	)
+	for pos, r := range results {
+		if r.CommunityID == "" {
+			continue
+		}
+		i, exists := indices[r.CommunityID]
+		if !exists {
+			i = len(groups)
+			indices[r.CommunityID] = i
+			groups = append(groups, resultGroup{CommunityID: r.CommunityID})
+		}
+		groups[i].Positions = append(groups[i].Positions, pos)
+	}
+	return groups
+}
+
+// clickURL returns the URL of the click endpoint that records a click on doc and
+// redirects users to it
+func clickURL(queryID uuid.UUID, doc *index.Document, position int) string {
+	params := url.Values{}
+	if queryID != uuid.Nil {
+		params.Set("qid", queryID.String())
+	}
+	params.Set("lid", doc.LinkID.String())
+	params.Set("pos", strconv.Itoa(position))
+	params.Set("url", doc.URL)
+	return "/click?" + params.Encode()
+}
+
+func (svc *Service) recordClick(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	target, err := url.Parse(params.Get("url"))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		//only redirect to web pages to avoid being used as an open
+		//redirector for other schemes
+		http.Error(w, "invalid target URL", http.StatusBadRequest)
+		return
+	}
+
+	if svc.cfg.QueryLog != nil {
+		click := &query.Click{URL: target.String()}
+		click.Position, _ = strconv.Atoi(params.Get("pos"))
+		click.LinkID, _ = uuid.Parse(params.Get("lid"))
+		click.QueryID, _ = uuid.Parse(params.Get("qid"))
+
+		//users should always be sent to their destination even if the
+		//click cannot be recorded
+		_ = svc.cfg.QueryLog.RecordClick(click)
+	}
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (svc *Service) renderTrendingSearches(w http.ResponseWriter, r *http.Request) {
+	if svc.cfg.QueryLog == nil {
+		writeJSON(w, []query.PopularQuery{})
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultTrendingLimit
+	}
+
+	popular, err := svc.cfg.QueryLog.PopularQueries(time.Now().Add(-svc.cfg.TrendingWindow), limit)
+	if err != nil {
+		http.Error(w, "unable to retrieve trending searches", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, popular)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,1139 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/apiauth"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/hostgraph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/related"
+	"github.com/brandonshearin/ask_brandon/linkgraph/report"
+	graphmemory "github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/querylog/query"
+	qlmemory "github.com/brandonshearin/ask_brandon/querylog/store/memory"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/brandonshearin/ask_brandon/textindexer/searchutil"
+	"github.com/brandonshearin/ask_brandon/textindexer/store/memory"
+	"github.com/brandonshearin/ask_brandon/webmaster"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(FrontendTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type FrontendTestSuite struct {
+	idx      *memory.InMemoryBleveIndexer
+	queryLog *qlmemory.InMemoryQueryLog
+	svc      *Service
+}
+
+func (s *FrontendTestSuite) SetUpTest(c *gc.C) {
+	var err error
+	s.idx, err = memory.NewInMemoryBleveIndexer()
+	c.Assert(err, gc.IsNil)
+	s.queryLog = qlmemory.NewInMemoryQueryLog()
+
+	s.svc, err = NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		QueryLog:      s.queryLog,
+	})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *FrontendTestSuite) TearDownTest(c *gc.C) {
+	c.Assert(s.idx.Close(), gc.IsNil)
+}
+
+func (s *FrontendTestSuite) TestSearchAndClick(c *gc.C) {
+	doc := &index.Document{
+		LinkID:  uuid.New(),
+		URL:     "http://example.com/gophers",
+		Title:   "Gophers",
+		Content: "all about gophers",
+	}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=gophers", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+
+	var res searchResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.QueryID, gc.Not(gc.Equals), uuid.Nil)
+	c.Assert(res.Total, gc.Equals, uint64(1))
+	c.Assert(res.Results, gc.HasLen, 1)
+	c.Assert(res.Results[0].LinkID, gc.Equals, doc.LinkID)
+
+	rec = httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", res.Results[0].ClickURL, nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusFound)
+	c.Assert(rec.Header().Get("Location"), gc.Equals, doc.URL)
+
+	rec = httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/click?url=javascript:alert(1)", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusBadRequest)
+}
+
+func (s *FrontendTestSuite) TestTrendingSearches(c *gc.C) {
+	for _, expr := range []string{"gophers", "golang", "Gophers"} {
+		rec := httptest.NewRecorder()
+		s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q="+expr, nil))
+		c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	}
+
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/trending?limit=1", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+
+	var popular []query.PopularQuery
+	c.Assert(json.NewDecoder(rec.Body).Decode(&popular), gc.IsNil)
+	c.Assert(popular, gc.DeepEquals, []query.PopularQuery{{Expression: "gophers", Count: 2}})
+}
+
+func (s *FrontendTestSuite) TestGroupByCommunity(c *gc.C) {
+	communities := []string{"go", "rust", "go", ""}
+	for i, community := range communities {
+		doc := &index.Document{
+			LinkID:  uuid.New(),
+			URL:     fmt.Sprintf("http://example.com/%d", i),
+			Content: "programming languages",
+		}
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		if community != "" {
+			c.Assert(s.idx.UpdateFields(doc.LinkID, map[string]interface{}{
+				index.FieldCommunityID: community,
+			}), gc.IsNil)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=programming&group=community", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+
+	var res searchResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Results, gc.HasLen, len(communities))
+
+	grouped := make(map[string]int)
+	for _, g := range res.Groups {
+		for _, pos := range g.Positions {
+			c.Assert(res.Results[pos].CommunityID, gc.Equals, g.CommunityID)
+			grouped[g.CommunityID]++
+		}
+	}
+	c.Assert(grouped, gc.DeepEquals, map[string]int{"go": 2, "rust": 1})
+}
+
+func (s *FrontendTestSuite) TestResultsPage(c *gc.C) {
+	for i := 0; i < 25; i++ {
+		doc := &index.Document{
+			LinkID:  uuid.New(),
+			URL:     fmt.Sprintf("http://example.com/%d", i),
+			Title:   fmt.Sprintf("Gopher facts #%d", i),
+			Content: "everything you ever wanted to know about <gophers>",
+		}
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(25-i)), gc.IsNil)
+	}
+
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=gophers&offset=10&format=html", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	c.Assert(rec.Header().Get("Content-Type"), gc.Equals, "text/html; charset=utf-8")
+
+	body := rec.Body.String()
+	c.Assert(body, gc.Matches, `(?s).*25 results in [0-9.]+ ms.*`)
+	c.Assert(body, gc.Matches, `(?s).*Gopher facts #10.*Gopher facts #19.*`)
+	c.Assert(strings.Contains(body, "Gopher facts #9<"), gc.Equals, false)
+	c.Assert(strings.Contains(body, "&lt;<mark>gophers</mark>&gt;"), gc.Equals, true)
+	c.Assert(strings.Contains(body, `<a href="/search?format=html&amp;offset=0&amp;q=gophers">Previous</a>`), gc.Equals, true)
+	c.Assert(strings.Contains(body, `<a href="/search?format=html&amp;offset=20&amp;q=gophers">Next</a>`), gc.Equals, true)
+	c.Assert(strings.Contains(body, `<span>2</span>`), gc.Equals, true)
+}
+
+func (s *FrontendTestSuite) TestSearchResponseIncludesSnippetAndTiming(c *gc.C) {
+	doc := &index.Document{
+		LinkID:  uuid.New(),
+		URL:     "http://example.com/gophers",
+		Title:   "Gophers",
+		Content: "gophers dig tunnels",
+	}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=tunnels", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+
+	var res searchResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Results, gc.HasLen, 1)
+	c.Assert(res.Results[0].Snippet, gc.Equals, "gophers dig tunnels")
+	c.Assert(res.TookMillis >= 0, gc.Equals, true)
+}
+
+func (s *FrontendTestSuite) TestResultsPageIndexerError(c *gc.C) {
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       failingIndexer{err: index.Unavailable(xerrors.New("connection refused"))},
+	})
+	c.Assert(err, gc.IsNil)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/search?q=gophers", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	svc.ServeHTTP(rec, req)
+	c.Assert(rec.Code, gc.Equals, http.StatusServiceUnavailable)
+	c.Assert(rec.Body.String(), gc.Matches, `(?s).*Search is temporarily unavailable.*`)
+
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=gophers", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusServiceUnavailable)
+}
+
+func (s *FrontendTestSuite) TestHighlightTerms(c *gc.C) {
+	q := index.Query{Expression: "Gophers tunnel"}
+	c.Assert(
+		highlightTerms("...the gophers' <tunnel>, dug by GOPHERS.", q),
+		gc.Equals,
+		template.HTML(`...the <mark>gophers</mark>&#39; &lt;<mark>tunnel</mark>&gt;, dug by <mark>GOPHERS</mark>.`),
+	)
+}
+
+//failingIndexer fails all searches with err
+type failingIndexer struct {
+	err error
+}
+
+func (f failingIndexer) Search(index.Query) (index.Iterator, error) {
+	return nil, f.err
+}
+
+func (s *FrontendTestSuite) TestParseQuery(c *gc.C) {
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	specs := []struct {
+		expr   string
+		exp    index.Query
+		expErr string
+	}{
+		{
+			expr: "gophers   tunnels",
+			exp:  index.Query{Type: index.QueryTypeMatch, Expression: "gophers tunnels"},
+		},
+		{
+			expr: `"gophers  dig tunnels"`,
+			exp:  index.Query{Type: index.QueryTypePhrase, Expression: "gophers dig tunnels"},
+		},
+		{
+			expr: `"gophers dig" tunnels`,
+			exp:  index.Query{Type: index.QueryTypeMatch, Expression: "gophers dig tunnels"},
+		},
+		{
+			expr: `"unterminated phrase`,
+			exp:  index.Query{Type: index.QueryTypePhrase, Expression: "unterminated phrase"},
+		},
+		{
+			expr: "gophers site:Blog.Example.com after:2024-01-01",
+			exp:  index.Query{Type: index.QueryTypeMatch, Expression: "gophers", Site: "blog.example.com", FetchedAfter: after},
+		},
+		{
+			expr: `site:https://example.com/about "gophers"`,
+			exp:  index.Query{Type: index.QueryTypePhrase, Expression: "gophers", Site: "example.com"},
+		},
+		{
+			expr: "gophers -site:example.com -site:http://example.org/",
+			exp:  index.Query{Type: index.QueryTypeMatch, Expression: "gophers", ExcludeDomains: []string{"example.com", "example.org"}},
+		},
+		{
+			expr: "golang: a tutorial",
+			exp:  index.Query{Type: index.QueryTypeMatch, Expression: "golang: a tutorial"},
+		},
+		{
+			expr:   "gophers after:yesterday",
+			expErr: `invalid date "yesterday".*`,
+		},
+		{
+			expr:   `site:example.com ""`,
+			expErr: "missing search expression",
+		},
+	}
+
+	for _, spec := range specs {
+		comment := gc.Commentf("expr %q", spec.expr)
+		q, err := parseQuery(spec.expr)
+		if spec.expErr != "" {
+			c.Assert(err, gc.ErrorMatches, spec.expErr, comment)
+			continue
+		}
+		c.Assert(err, gc.IsNil, comment)
+		c.Assert(q, gc.DeepEquals, spec.exp, comment)
+	}
+}
+
+func (s *FrontendTestSuite) TestAdvancedSearch(c *gc.C) {
+	now := time.Now()
+	docs := []*index.Document{
+		{LinkID: uuid.New(), URL: "http://blog.example.com/a", Content: "gophers dig tunnels", FetchedAt: now},
+		{LinkID: uuid.New(), URL: "http://example.com/b", Content: "tunnels dug by gophers", FetchedAt: now},
+		{LinkID: uuid.New(), URL: "http://example.org/c", Content: "gophers dig tunnels", FetchedAt: now.Add(-30 * 24 * time.Hour)},
+	}
+	for i, doc := range docs {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(len(docs)-i)), gc.IsNil)
+	}
+
+	specs := []struct {
+		expr string
+		exp  []uuid.UUID
+	}{
+		{expr: "gophers tunnels", exp: []uuid.UUID{docs[0].LinkID, docs[1].LinkID, docs[2].LinkID}},
+		{expr: `"gophers dig tunnels"`, exp: []uuid.UUID{docs[0].LinkID, docs[2].LinkID}},
+		{expr: "gophers site:example.com", exp: []uuid.UUID{docs[0].LinkID, docs[1].LinkID}},
+		{expr: "gophers -site:blog.example.com", exp: []uuid.UUID{docs[1].LinkID, docs[2].LinkID}},
+		{expr: "gophers after:" + now.AddDate(0, 0, -7).Format("2006-01-02"), exp: []uuid.UUID{docs[0].LinkID, docs[1].LinkID}},
+	}
+	for _, spec := range specs {
+		comment := gc.Commentf("expr %q", spec.expr)
+		rec := httptest.NewRecorder()
+		s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q="+url.QueryEscape(spec.expr), nil))
+		c.Assert(rec.Code, gc.Equals, http.StatusOK, comment)
+
+		var res searchResponse
+		c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil, comment)
+		c.Assert(res.Expression, gc.Equals, spec.expr, comment)
+
+		var got []uuid.UUID
+		for _, r := range res.Results {
+			got = append(got, r.LinkID)
+		}
+		c.Assert(got, gc.DeepEquals, spec.exp, comment)
+	}
+
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q="+url.QueryEscape("gophers after:soon"), nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusBadRequest)
+}
+
+func (s *FrontendTestSuite) TestSafeSearch(c *gc.C) {
+	docs := []*index.Document{
+		{LinkID: uuid.New(), URL: "http://example.com/a", Content: "gophers"},
+		{LinkID: uuid.New(), URL: "http://example.com/b", Content: "gophers", Filtered: true, FilterReason: "adult"},
+	}
+	for i, doc := range docs {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(len(docs)-i)), gc.IsNil)
+	}
+
+	specs := []struct {
+		params string
+		exp    []uuid.UUID
+	}{
+		{params: "q=gophers", exp: []uuid.UUID{docs[0].LinkID}},
+		{params: "q=gophers&safe=on", exp: []uuid.UUID{docs[0].LinkID}},
+		{params: "q=gophers&safe=off", exp: []uuid.UUID{docs[0].LinkID, docs[1].LinkID}},
+	}
+	for _, spec := range specs {
+		comment := gc.Commentf("params %q", spec.params)
+		rec := httptest.NewRecorder()
+		s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?"+spec.params, nil))
+		c.Assert(rec.Code, gc.Equals, http.StatusOK, comment)
+
+		var res searchResponse
+		c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil, comment)
+		var got []uuid.UUID
+		for _, r := range res.Results {
+			got = append(got, r.LinkID)
+		}
+		c.Assert(got, gc.DeepEquals, spec.exp, comment)
+	}
+}
+
+func (s *FrontendTestSuite) TestOpenSearchDescription(c *gc.C) {
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "http://search.example.com/opensearch.xml", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	c.Assert(rec.Header().Get("Content-Type"), gc.Equals, "application/opensearchdescription+xml; charset=utf-8")
+
+	body := rec.Body.String()
+	c.Assert(strings.Contains(body, "<ShortName>ask_brandon</ShortName>"), gc.Equals, true)
+	c.Assert(strings.Contains(body, `template="http://search.example.com/search?q={searchTerms}&amp;format=html"`), gc.Equals, true)
+	c.Assert(strings.Contains(body, `template="http://search.example.com/suggest?q={searchTerms}"`), gc.Equals, true)
+
+	svc, err := NewService(Config{ListenAddress: ":0", Indexer: s.idx, SiteName: "Gopher Search", BaseURL: "https://gophers.example.com/"})
+	c.Assert(err, gc.IsNil)
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/opensearch.xml", nil))
+	c.Assert(strings.Contains(rec.Body.String(), `template="https://gophers.example.com/opensearch.xml"`), gc.Equals, true)
+
+	_, err = NewService(Config{ListenAddress: ":0", Indexer: s.idx, BaseURL: "gophers.example.com"})
+	c.Assert(err, gc.ErrorMatches, ".*invalid base URL.*")
+}
+
+func (s *FrontendTestSuite) TestOpenSearchLinks(c *gc.C) {
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?format=html", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	c.Assert(rec.Header().Get("Link"), gc.Equals, `</opensearch.xml>; rel="search"; type="application/opensearchdescription+xml"; title="ask_brandon"`)
+	c.Assert(strings.Contains(rec.Body.String(), `<link rel="search" type="application/opensearchdescription+xml" href="/opensearch.xml" title="ask_brandon">`), gc.Equals, true)
+}
+
+func (s *FrontendTestSuite) TestSuggestions(c *gc.C) {
+	for _, content := range []string{"gophers live in a tunnel", "a tunnel dug by gophers"} {
+		c.Assert(s.idx.Index(&index.Document{LinkID: uuid.New(), Content: content}), gc.IsNil)
+	}
+
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/suggest?q="+url.QueryEscape("gopehrs tunel"), nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	c.Assert(rec.Header().Get("Content-Type"), gc.Equals, "application/x-suggestions+json")
+
+	var res []interface{}
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res, gc.HasLen, 2)
+	c.Assert(res[0], gc.Equals, "gopehrs tunel")
+	suggestions, ok := res[1].([]interface{})
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(len(suggestions) > 0, gc.Equals, true)
+	c.Assert(suggestions[0], gc.Equals, "gophers tunnel")
+
+	// Indexers that do not support suggestions yield an empty list
+	svc, err := NewService(Config{ListenAddress: ":0", Indexer: failingIndexer{}})
+	c.Assert(err, gc.IsNil)
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/suggest?q=gophers", nil))
+	c.Assert(rec.Body.String(), gc.Equals, "[\"gophers\",[]]\n")
+}
+
+func (s *FrontendTestSuite) TestRelatedPages(c *gc.C) {
+	g := graphmemory.NewInMemoryGraph()
+	links := make(map[string]*graph.Link)
+	for _, name := range []string{"hub", "gophers", "tunnels", "burrows"} {
+		link := &graph.Link{URL: "http://" + name + ".com/"}
+		c.Assert(g.UpsertLink(link), gc.IsNil)
+		links[name] = link
+	}
+	for _, dst := range []string{"gophers", "tunnels", "burrows"} {
+		c.Assert(g.UpsertEdge(&graph.Edge{Src: links["hub"].ID, Dst: links[dst].ID}), gc.IsNil)
+	}
+	for _, name := range []string{"gophers", "tunnels"} {
+		c.Assert(s.idx.Index(&index.Document{LinkID: links[name].ID, URL: links[name].URL, Title: "All about " + name, Content: "gophers"}), gc.IsNil)
+	}
+
+	finder, err := related.NewFinder(related.Config{Graph: g})
+	c.Assert(err, gc.IsNil)
+	svc, err := NewService(Config{ListenAddress: ":0", Indexer: s.idx, Related: finder})
+	c.Assert(err, gc.IsNil)
+
+	// Search results link to their related pages
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=gophers", nil))
+	var searchRes searchResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&searchRes), gc.IsNil)
+	c.Assert(searchRes.Results, gc.HasLen, 2)
+	c.Assert(searchRes.Results[0].RelatedURL, gc.Equals, "/api/v1/related/"+searchRes.Results[0].LinkID.String())
+
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/related/"+links["gophers"].ID.String(), nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var res relatedResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.LinkID, gc.Equals, links["gophers"].ID)
+	c.Assert(res.Results, gc.HasLen, 2)
+	for _, r := range res.Results {
+		c.Assert(r.Score, gc.Equals, 1.0)
+		c.Assert(r.Source, gc.Equals, related.SourceCoCitation)
+		if r.LinkID == links["tunnels"].ID {
+			c.Assert(r.Title, gc.Equals, "All about tunnels")
+		} else {
+			// Pages that have not been indexed are listed by their URL
+			c.Assert(r.URL, gc.Equals, "http://burrows.com/")
+			c.Assert(r.Title, gc.Equals, "")
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/related/"+links["gophers"].ID.String()+"?limit=1", nil))
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Results, gc.HasLen, 1)
+
+	for path, status := range map[string]int{
+		"/api/v1/related/not-a-uuid":             http.StatusBadRequest,
+		"/api/v1/related/" + uuid.New().String(): http.StatusNotFound,
+	} {
+		rec = httptest.NewRecorder()
+		svc.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		c.Assert(rec.Code, gc.Equals, status, gc.Commentf("path %s", path))
+	}
+
+	// The endpoint is disabled unless a finder is configured
+	rec = httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/related/"+links["gophers"].ID.String(), nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusNotFound)
+}
+
+func (s *FrontendTestSuite) TestURLStatus(c *gc.C) {
+	var (
+		g         = graphmemory.NewInMemoryGraph()
+		retrieved = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		indexed   = &graph.Link{URL: "http://example.com/gophers", RetrievedAt: retrieved}
+		crawled   = &graph.Link{URL: "http://example.com/moles", RetrievedAt: retrieved}
+	)
+	for _, link := range []*graph.Link{indexed, crawled} {
+		c.Assert(g.UpsertLink(link), gc.IsNil)
+	}
+	c.Assert(s.idx.Index(&index.Document{LinkID: indexed.ID, URL: indexed.URL, Content: "gophers"}), gc.IsNil)
+	c.Assert(s.idx.UpdateScore(indexed.ID, 0.5), gc.IsNil)
+
+	svc, err := NewService(Config{ListenAddress: ":0", Indexer: s.idx, URLStatus: g})
+	c.Assert(err, gc.IsNil)
+
+	body := `{"urls": ["HTTP://Example.com/gophers#top", "http://example.com/moles", "http://example.com/unknown", "not a url"]}`
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/urlstatus", strings.NewReader(body)))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var res urlStatusResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Results, gc.HasLen, 4)
+
+	gophers := res.Results[0]
+	c.Assert(gophers.URL, gc.Equals, "HTTP://Example.com/gophers#top")
+	c.Assert(gophers.Known, gc.Equals, true)
+	c.Assert(gophers.LinkID, gc.Equals, indexed.ID.String())
+	c.Assert(gophers.LastRetrieved.Equal(retrieved), gc.Equals, true)
+	c.Assert(gophers.Indexed, gc.Equals, true)
+	c.Assert(gophers.IndexedAt, gc.NotNil)
+	c.Assert(gophers.PageRank, gc.Equals, 0.5)
+
+	// Links that have been crawled but not indexed yet
+	c.Assert(res.Results[1].Known, gc.Equals, true)
+	c.Assert(res.Results[1].Indexed, gc.Equals, false)
+
+	c.Assert(res.Results[2], gc.DeepEquals, urlStatus{URL: "http://example.com/unknown"})
+	c.Assert(res.Results[3], gc.DeepEquals, urlStatus{URL: "not a url", Error: "invalid URL"})
+
+	tooMany := urlStatusRequest{URLs: make([]string, maxURLStatusURLs+1)}
+	for i := range tooMany.URLs {
+		tooMany.URLs[i] = fmt.Sprintf("http://example.com/%d", i)
+	}
+	tooManyBody, err := json.Marshal(tooMany)
+	c.Assert(err, gc.IsNil)
+	for _, req := range []struct {
+		method, body string
+		status       int
+	}{
+		{"GET", "", http.StatusMethodNotAllowed},
+		{"POST", "{", http.StatusBadRequest},
+		{"POST", `{"urls": []}`, http.StatusBadRequest},
+		{"POST", string(tooManyBody), http.StatusRequestEntityTooLarge},
+	} {
+		rec = httptest.NewRecorder()
+		svc.ServeHTTP(rec, httptest.NewRequest(req.method, "/api/v1/urlstatus", strings.NewReader(req.body)))
+		c.Assert(rec.Code, gc.Equals, req.status, gc.Commentf("%s %q", req.method, req.body))
+	}
+
+	// The endpoint is disabled unless a link graph is configured
+	rec = httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/urlstatus", strings.NewReader(body)))
+	c.Assert(rec.Code, gc.Equals, http.StatusNotFound)
+}
+
+func (s *FrontendTestSuite) TestURLStatusBatchLookups(c *gc.C) {
+	var (
+		g       = graphmemory.NewInMemoryGraph()
+		links   = &countingLinkFinder{LinkFinder: g}
+		indexer = &countingDocFinder{InMemoryBleveIndexer: s.idx}
+		req     = urlStatusRequest{URLs: make([]string, maxURLStatusURLs)}
+	)
+	for i := range req.URLs {
+		req.URLs[i] = fmt.Sprintf("http://example.com/%d", i)
+		if i%2 == 0 {
+			c.Assert(g.UpsertLink(&graph.Link{URL: req.URLs[i]}), gc.IsNil)
+		}
+	}
+	body, err := json.Marshal(req)
+	c.Assert(err, gc.IsNil)
+
+	svc, err := NewService(Config{ListenAddress: ":0", Indexer: indexer, URLStatus: links})
+	c.Assert(err, gc.IsNil)
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/urlstatus", strings.NewReader(string(body))))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+
+	var res urlStatusResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Results, gc.HasLen, maxURLStatusURLs)
+	for i, status := range res.Results {
+		c.Assert(status.URL, gc.Equals, req.URLs[i])
+		c.Assert(status.Known, gc.Equals, i%2 == 0, gc.Commentf("url %s", status.URL))
+	}
+
+	// Each store is queried with a single batch lookup
+	c.Assert(links.calls, gc.Equals, 1)
+	c.Assert(indexer.calls, gc.Equals, 1)
+}
+
+// countingLinkFinder counts the batch link lookups it receives
+type countingLinkFinder struct {
+	LinkFinder
+	calls int
+}
+
+func (f *countingLinkFinder) FindLinksByURL(rawURLs []string) ([]*graph.Link, error) {
+	f.calls++
+	return f.LinkFinder.FindLinksByURL(rawURLs)
+}
+
+// countingDocFinder counts the batch document lookups it receives
+type countingDocFinder struct {
+	*memory.InMemoryBleveIndexer
+	calls int
+}
+
+func (f *countingDocFinder) FindByURLs(rawURLs []string) ([]*index.Document, error) {
+	f.calls++
+	return f.InMemoryBleveIndexer.FindByURLs(rawURLs)
+}
+
+func (s *FrontendTestSuite) TestSitelinks(c *gc.C) {
+	var (
+		home = &index.Document{LinkID: uuid.New(), URL: "http://golang.org/", Title: "Go", Content: "the go programming language"}
+		docs = &index.Document{LinkID: uuid.New(), URL: "http://golang.org/doc", Title: "Documentation", Content: "go docs"}
+		blog = &index.Document{LinkID: uuid.New(), URL: "http://golang.org/blog"}
+	)
+	for _, doc := range []*index.Document{home, docs} {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+	}
+	sitelinks := fakeSitelinks{"http://golang.org/": {
+		{LinkID: home.LinkID, URL: home.URL},
+		{LinkID: docs.LinkID, URL: docs.URL},
+		{LinkID: blog.LinkID, URL: blog.URL},
+	}}
+	svc, err := NewService(Config{ListenAddress: ":0", Indexer: s.idx, Sitelinks: sitelinks})
+	c.Assert(err, gc.IsNil)
+
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=programming", nil))
+	var res searchResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Results, gc.HasLen, 1)
+
+	// The result itself is not repeated and pages that have not been
+	// indexed are listed by their URL
+	links := res.Results[0].Sitelinks
+	c.Assert(links, gc.HasLen, 2)
+	c.Assert(links[0].Title, gc.Equals, "Documentation")
+	c.Assert(links[1].Title, gc.Equals, "")
+	c.Assert(links[1].URL, gc.Equals, blog.URL)
+
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", links[0].ClickURL, nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusFound)
+	c.Assert(rec.Header().Get("Location"), gc.Equals, docs.URL)
+
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=programming&format=html", nil))
+	c.Assert(rec.Body.String(), gc.Matches, `(?s).*<div class="sitelinks"><a href="[^"]+">Documentation</a><a href="[^"]+">http://golang.org/blog</a>.*`)
+
+	// Sitelinks are only shown on the first page of results
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=go&offset=1", nil))
+	var nextPage searchResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&nextPage), gc.IsNil)
+	c.Assert(nextPage.Results, gc.HasLen, 1)
+	for _, r := range nextPage.Results {
+		c.Assert(r.Sitelinks, gc.HasLen, 0)
+	}
+}
+
+//fakeSitelinks maps URLs to their sitelinks
+type fakeSitelinks map[string][]hostgraph.Sitelink
+
+func (s fakeSitelinks) ForURL(rawURL string) []hostgraph.Sitelink { return s[rawURL] }
+
+func (s *FrontendTestSuite) TestCollapseResults(c *gc.C) {
+	for _, doc := range []*index.Document{
+		{LinkID: uuid.New(), URL: "http://a.com/gophers", Title: "All about gophers", Content: "gophers"},
+		{LinkID: uuid.New(), URL: "http://mirror.org/gophers", Title: "All About Gophers", Content: "gophers"},
+		{LinkID: uuid.New(), URL: "http://b.com/moles", Title: "Moles", Content: "gophers"},
+	} {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+	}
+	svc, err := NewService(Config{ListenAddress: ":0", Indexer: s.idx, Collapse: &searchutil.CollapseConfig{}})
+	c.Assert(err, gc.IsNil)
+
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=gophers", nil))
+	var res searchResponse
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Total, gc.Equals, uint64(3))
+	c.Assert(res.Results, gc.HasLen, 2)
+	var collapsed int
+	for _, r := range res.Results {
+		collapsed += len(r.Collapsed)
+		c.Assert(r.ExpandURL, gc.Equals, "")
+	}
+	c.Assert(collapsed, gc.Equals, 1)
+
+	// Users can opt out of collapsing
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=gophers&collapse=off", nil))
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res.Results, gc.HasLen, 3)
+}
+
+func (s *FrontendTestSuite) TestExpandSiteURL(c *gc.C) {
+	c.Assert(expandSiteURL("go gophers", "golang.org"), gc.Equals, "/search?q=go+gophers+site%3Agolang.org")
+}
+
+func (s *FrontendTestSuite) TestAdminDashboard(c *gc.C) {
+	g := graphmemory.NewInMemoryGraph()
+	for _, u := range []string{"http://a.com/1", "http://a.com/2", "http://b.com/"} {
+		c.Assert(g.UpsertLink(&graph.Link{URL: u}), gc.IsNil)
+	}
+	c.Assert(s.idx.Index(&index.Document{LinkID: uuid.New(), Content: "gophers"}), gc.IsNil)
+
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	jobs := new(fakeJobs)
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		Admin: AdminConfig{
+			Username:    "admin",
+			Password:    "secret",
+			Graph:       g,
+			Index:       s.idx,
+			CrawlPasses: fakeCrawlPasses{{ID: "pass-1", StartedAt: started, LinksProcessed: 3}},
+			Errors:      fakeErrorLog{err: xerrors.New("error log unavailable")},
+			Jobs:        jobs,
+		},
+	})
+	c.Assert(err, gc.IsNil)
+
+	// Credentials are required
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/admin", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusUnauthorized)
+	c.Assert(rec.Header().Get("WWW-Authenticate"), gc.Equals, `Basic realm="admin"`)
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.SetBasicAuth("admin", "wrong")
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, req)
+	c.Assert(rec.Code, gc.Equals, http.StatusUnauthorized)
+
+	req = httptest.NewRequest("GET", "/admin", nil)
+	req.SetBasicAuth("admin", "secret")
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, req)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+
+	var dash adminDashboard
+	c.Assert(json.NewDecoder(rec.Body).Decode(&dash), gc.IsNil)
+	c.Assert(dash.Graph.Links, gc.Equals, uint64(3))
+	c.Assert(dash.Graph.Edges, gc.Equals, uint64(0))
+	c.Assert(dash.Graph.Memory, gc.NotNil)
+	c.Assert(dash.Graph.Memory.LinkBytes > 0, gc.Equals, true)
+	c.Assert(dash.Graph.Memory.TotalBytes, gc.Equals, dash.Graph.Memory.LinkBytes+dash.Graph.Memory.EdgeBytes+dash.Graph.Memory.IndexBytes+dash.Graph.Memory.CrawlPassLogBytes)
+	c.Assert(dash.Index.Documents, gc.Equals, uint64(1))
+	c.Assert(dash.Index.Storage, gc.NotNil)
+	c.Assert(dash.Index.Storage.MemoryBytes > 0, gc.Equals, true)
+	c.Assert(dash.Index.Storage.Segments, gc.Equals, 1)
+	c.Assert(dash.TopDomains, gc.DeepEquals, []report.DomainCount{{Domain: "a.com", Links: 2}, {Domain: "b.com", Links: 1}})
+	c.Assert(dash.CrawlPasses, gc.DeepEquals, []CrawlPass{{ID: "pass-1", StartedAt: started, LinksProcessed: 3}})
+	c.Assert(dash.Unavailable, gc.DeepEquals, []string{"recent_errors"})
+	c.Assert(dash.CanTrigger, gc.Equals, true)
+
+	req = httptest.NewRequest("GET", "/admin?format=html", nil)
+	req.SetBasicAuth("admin", "secret")
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, req)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	c.Assert(rec.Body.String(), gc.Matches, `(?s).*bytes in memory, 0 bytes on disk, 1 segments.*<td>a.com</td><td>2</td>.*<td>pass-1</td><td>2024-01-01 12:00:00</td><td>running</td>.*`)
+}
+
+func (s *FrontendTestSuite) TestAdminJobTriggers(c *gc.C) {
+	jobs := new(fakeJobs)
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		Admin:         AdminConfig{Username: "admin", Password: "secret", Jobs: jobs},
+	})
+	c.Assert(err, gc.IsNil)
+
+	send := func(method, path string, hdr map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.SetBasicAuth("admin", "secret")
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		return rec
+	}
+
+	c.Assert(send("GET", "/admin/crawl", nil).Code, gc.Equals, http.StatusMethodNotAllowed)
+	c.Assert(send("POST", "/admin/crawl", map[string]string{"Origin": "http://evil.com"}).Code, gc.Equals, http.StatusForbidden)
+	c.Assert(jobs.crawls, gc.Equals, 0)
+
+	c.Assert(send("POST", "/admin/crawl", nil).Code, gc.Equals, http.StatusAccepted)
+	rec := send("POST", "/admin/pagerank", map[string]string{"Accept": "text/html", "Origin": "http://example.com"})
+	c.Assert(rec.Code, gc.Equals, http.StatusSeeOther)
+	c.Assert(rec.Header().Get("Location"), gc.Equals, "/admin?msg=pagerank+pass+triggered")
+	c.Assert(jobs.crawls, gc.Equals, 1)
+	c.Assert(jobs.pageRanks, gc.Equals, 1)
+
+	jobs.err = xerrors.New("connection refused")
+	c.Assert(send("POST", "/admin/crawl", nil).Code, gc.Equals, http.StatusBadGateway)
+}
+
+func (s *FrontendTestSuite) TestAdminPassDiff(c *gc.C) {
+	g := graphmemory.NewInMemoryGraph()
+	var passes []uint64
+	for _, hash := range []string{"v1", "v2"} {
+		pass, err := g.NextCrawlPass()
+		c.Assert(err, gc.IsNil)
+		passes = append(passes, pass)
+		c.Assert(g.UpsertLink(&graph.Link{URL: "http://a.com/", CrawlPassID: pass, ContentHash: hash}), gc.IsNil)
+	}
+	c.Assert(g.UpsertLink(&graph.Link{URL: "http://a.com/new", CrawlPassID: passes[1]}), gc.IsNil)
+
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		Admin:         AdminConfig{Username: "admin", Password: "secret", Graph: g},
+	})
+	c.Assert(err, gc.IsNil)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetBasicAuth("admin", "secret")
+		rec := httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(fmt.Sprintf("/admin/passdiff?a=%d&b=%d", passes[0], passes[1]))
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var diff passDiff
+	c.Assert(json.NewDecoder(rec.Body).Decode(&diff), gc.IsNil)
+	c.Assert(diff, gc.DeepEquals, passDiff{
+		PassA:            passes[0],
+		PassB:            passes[1],
+		NewLinks:         []string{"http://a.com/new"},
+		ChangedDocuments: []string{"http://a.com/"},
+	})
+
+	c.Assert(send("/admin/passdiff?a=1").Code, gc.Equals, http.StatusBadRequest)
+	c.Assert(send("/admin/passdiff?a=1&b=42").Code, gc.Equals, http.StatusNotFound)
+}
+
+func (s *FrontendTestSuite) TestAdminNeighborhood(c *gc.C) {
+	g := graphmemory.NewInMemoryGraph()
+	var links []*graph.Link
+	for _, u := range []string{"http://a.com/", "http://b.com/", "http://c.com/"} {
+		link := &graph.Link{URL: u}
+		c.Assert(g.UpsertLink(link), gc.IsNil)
+		links = append(links, link)
+	}
+	c.Assert(g.UpsertEdge(&graph.Edge{Src: links[0].ID, Dst: links[1].ID}), gc.IsNil)
+	c.Assert(g.UpsertEdge(&graph.Edge{Src: links[1].ID, Dst: links[2].ID}), gc.IsNil)
+
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		Admin:         AdminConfig{Username: "admin", Password: "secret", Graph: g},
+	})
+	c.Assert(err, gc.IsNil)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetBasicAuth("admin", "secret")
+		rec := httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("/admin/neighborhood?radius=1&seed=" + links[0].ID.String())
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var d3 struct {
+		Nodes []report.NeighborhoodNode `json:"nodes"`
+		Links []map[string]string       `json:"links"`
+	}
+	c.Assert(json.NewDecoder(rec.Body).Decode(&d3), gc.IsNil)
+	c.Assert(d3.Nodes, gc.HasLen, 2)
+	c.Assert(d3.Links, gc.DeepEquals, []map[string]string{{"source": links[0].ID.String(), "target": links[1].ID.String()}})
+
+	rec = send("/admin/neighborhood?format=vis&seed=" + links[0].ID.String())
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var vis struct {
+		Nodes []report.NeighborhoodNode `json:"nodes"`
+		Edges []map[string]string       `json:"edges"`
+	}
+	c.Assert(json.NewDecoder(rec.Body).Decode(&vis), gc.IsNil)
+	c.Assert(vis.Nodes, gc.HasLen, 3)
+	c.Assert(vis.Edges, gc.HasLen, 2)
+
+	c.Assert(send("/admin/neighborhood").Code, gc.Equals, http.StatusBadRequest)
+	c.Assert(send("/admin/neighborhood?radius=9&seed="+links[0].ID.String()).Code, gc.Equals, http.StatusBadRequest)
+	c.Assert(send("/admin/neighborhood?format=dot&seed="+links[0].ID.String()).Code, gc.Equals, http.StatusBadRequest)
+	c.Assert(send("/admin/neighborhood?seed="+uuid.New().String()).Code, gc.Equals, http.StatusNotFound)
+}
+
+func (s *FrontendTestSuite) TestAdminPipelines(c *gc.C) {
+	p := pipeline.New(pipeline.FixedWorkerPool(pipeline.ProcessorFunc(
+		func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) { return p, nil },
+	), 4))
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		Admin: AdminConfig{
+			Username:  "admin",
+			Password:  "secret",
+			Pipelines: fakePipelines{"crawl-0": p.Describe()},
+		},
+	})
+	c.Assert(err, gc.IsNil)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetBasicAuth("admin", "secret")
+		rec := httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("/admin/pipelines")
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var descriptions map[string]*pipeline.Description
+	c.Assert(json.NewDecoder(rec.Body).Decode(&descriptions), gc.IsNil)
+	c.Assert(descriptions, gc.DeepEquals, map[string]*pipeline.Description{"crawl-0": p.Describe()})
+
+	rec = send("/admin/pipelines?format=dot")
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	c.Assert(rec.Body.String(), gc.Equals, p.Describe().DOT("crawl-0"))
+
+	c.Assert(send("/admin/pipelines?format=xml").Code, gc.Equals, http.StatusBadRequest)
+}
+
+func (s *FrontendTestSuite) TestAdminWebmaster(c *gc.C) {
+	g := graphmemory.NewInMemoryGraph()
+	records := webmasterRecords{}
+	tools, err := webmaster.NewManager(webmaster.Config{Graph: g, Index: s.idx, Resolver: records})
+	c.Assert(err, gc.IsNil)
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		Admin:         AdminConfig{Username: "admin", Password: "secret", Webmaster: tools},
+	})
+	c.Assert(err, gc.IsNil)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.SetBasicAuth("admin", "secret")
+		rec := httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("POST", "/admin/webmaster/claim", `{"owner": "alice", "site": "https://example.com"}`)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var site webmasterSite
+	c.Assert(json.NewDecoder(rec.Body).Decode(&site), gc.IsNil)
+	c.Assert(site.Host, gc.Equals, "example.com")
+	c.Assert(site.Verified, gc.Equals, false)
+	c.Assert(site.MetaTag, gc.Matches, `<meta name="ask-brandon-site-verification" content="`+site.Token+`">`)
+
+	// Sites cannot be managed before they are verified
+	c.Assert(send("POST", "/admin/webmaster/crawlrate", `{"owner": "alice", "host": "example.com", "crawl_delay_ms": 2000}`).Code, gc.Equals, http.StatusForbidden)
+	c.Assert(send("POST", "/admin/webmaster/verify", `{"owner": "alice", "host": "example.com", "method": "dns"}`).Code, gc.Equals, http.StatusUnprocessableEntity)
+	c.Assert(send("POST", "/admin/webmaster/verify", `{"owner": "bob", "host": "example.com", "method": "dns"}`).Code, gc.Equals, http.StatusNotFound)
+
+	records["example.com"] = []string{site.TXTRecord}
+	c.Assert(send("POST", "/admin/webmaster/verify", `{"owner": "alice", "host": "example.com", "method": "dns"}`).Code, gc.Equals, http.StatusOK)
+	c.Assert(send("POST", "/admin/webmaster/crawlrate", `{"owner": "alice", "host": "example.com", "crawl_delay_ms": 2000}`).Code, gc.Equals, http.StatusNoContent)
+	c.Assert(tools.CrawlDelay("example.com"), gc.Equals, 2*time.Second)
+
+	doc := &index.Document{LinkID: uuid.New(), URL: "https://example.com/private", Content: "private"}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+	rec = send("POST", "/admin/webmaster/remove", `{"owner": "alice", "urls": ["https://example.com/private", "https://example.com/missing", "https://other.com/"]}`)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var res map[string][]webmasterURLResult
+	c.Assert(json.NewDecoder(rec.Body).Decode(&res), gc.IsNil)
+	c.Assert(res["results"], gc.DeepEquals, []webmasterURLResult{
+		{URL: "https://example.com/private"},
+		{URL: "https://example.com/missing", Error: "URL not indexed"},
+		{URL: "https://other.com/", Error: "site not verified"},
+	})
+	_, err = s.idx.FindByID(doc.LinkID)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+
+	rec = send("POST", "/admin/webmaster/recrawl", `{"owner": "alice", "urls": ["https://example.com/private"]}`)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	c.Assert(tools.PendingRecrawls(), gc.Equals, 1)
+
+	rec = send("GET", "/admin/webmaster/sites?owner=alice", "")
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var sites []webmasterSite
+	c.Assert(json.NewDecoder(rec.Body).Decode(&sites), gc.IsNil)
+	c.Assert(sites, gc.HasLen, 1)
+	c.Assert(sites[0].Verified, gc.Equals, true)
+	c.Assert(sites[0].Method, gc.Equals, "dns")
+	c.Assert(sites[0].CrawlDelayMS, gc.Equals, int64(2000))
+
+	c.Assert(send("GET", "/admin/webmaster/claim", "").Code, gc.Equals, http.StatusMethodNotAllowed)
+	c.Assert(send("POST", "/admin/webmaster/claim", `{"site": "https://example.com"}`).Code, gc.Equals, http.StatusBadRequest)
+	c.Assert(send("POST", "/admin/webmaster/recrawl", `{"owner": "alice"}`).Code, gc.Equals, http.StatusBadRequest)
+}
+
+func (s *FrontendTestSuite) TestRemovalQueue(c *gc.C) {
+	g := graphmemory.NewInMemoryGraph()
+	queue, err := webmaster.NewManager(webmaster.Config{Graph: g, Index: s.idx})
+	c.Assert(err, gc.IsNil)
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		Removals:      queue,
+		Admin:         AdminConfig{Username: "admin", Password: "secret"},
+	})
+	c.Assert(err, gc.IsNil)
+
+	doc := &index.Document{LinkID: uuid.New(), URL: "http://example.com/leak", Content: "leak"}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+
+	send := func(method, path, body string, admin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if admin {
+			req.SetBasicAuth("admin", "secret")
+		}
+		rec := httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("POST", "/api/v1/removals", `{"url": "http://example.com/leak", "requester": "someone@example.com", "reason": "personal data"}`, false)
+	c.Assert(rec.Code, gc.Equals, http.StatusAccepted)
+	var submitted removalRequest
+	c.Assert(json.NewDecoder(rec.Body).Decode(&submitted), gc.IsNil)
+	c.Assert(submitted.Status, gc.Equals, "pending")
+	c.Assert(submitted.Requester, gc.Equals, "")
+	c.Assert(send("POST", "/api/v1/removals", `{"url": "not a url"}`, false).Code, gc.Equals, http.StatusBadRequest)
+	c.Assert(send("GET", "/api/v1/removals", "", false).Code, gc.Equals, http.StatusMethodNotAllowed)
+
+	// Moderation requires the admin credentials
+	c.Assert(send("GET", "/admin/removals", "", false).Code, gc.Equals, http.StatusUnauthorized)
+	rec = send("GET", "/admin/removals", "", true)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var pending []removalRequest
+	c.Assert(json.NewDecoder(rec.Body).Decode(&pending), gc.IsNil)
+	c.Assert(pending, gc.HasLen, 1)
+	c.Assert(pending[0].Reason, gc.Equals, "personal data")
+
+	// The document stays indexed until the request is approved
+	_, err = s.idx.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(send("POST", "/admin/removals/approve?id=bogus", "", true).Code, gc.Equals, http.StatusBadRequest)
+	c.Assert(send("POST", "/admin/removals/approve?id="+uuid.New().String(), "", true).Code, gc.Equals, http.StatusNotFound)
+	rec = send("POST", "/admin/removals/approve?id="+submitted.ID, "", true)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var approved removalRequest
+	c.Assert(json.NewDecoder(rec.Body).Decode(&approved), gc.IsNil)
+	c.Assert(approved.Status, gc.Equals, "approved")
+	c.Assert(approved.BlockedUntil, gc.NotNil)
+	c.Assert(send("POST", "/admin/removals/reject?id="+submitted.ID, "", true).Code, gc.Equals, http.StatusConflict)
+
+	_, err = s.idx.FindByID(doc.LinkID)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+	c.Assert(queue.Blocklisted(doc.URL), gc.Equals, true)
+
+	rec = send("GET", "/admin/removals?status=all", "", true)
+	c.Assert(rec.Code, gc.Equals, http.StatusOK)
+	var all []removalRequest
+	c.Assert(json.NewDecoder(rec.Body).Decode(&all), gc.IsNil)
+	c.Assert(all, gc.HasLen, 1)
+	c.Assert(send("GET", "/admin/removals?status=bogus", "", true).Code, gc.Equals, http.StatusBadRequest)
+
+	// The submission endpoint is disabled unless a removal queue is configured
+	rec = httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/removals", strings.NewReader(`{"url": "http://example.com/"}`)))
+	c.Assert(rec.Code, gc.Equals, http.StatusNotFound)
+}
+
+type webmasterRecords map[string][]string
+
+func (r webmasterRecords) LookupTXT(_ context.Context, name string) ([]string, error) {
+	return r[name], nil
+}
+
+func (s *FrontendTestSuite) TestAdminDisabledWithoutCredentials(c *gc.C) {
+	rec := httptest.NewRecorder()
+	s.svc.ServeHTTP(rec, httptest.NewRequest("GET", "/admin", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusNotFound)
+}
+
+type fakeCrawlPasses []CrawlPass
+
+func (f fakeCrawlPasses) RecentCrawlPasses(int) ([]CrawlPass, error) { return f, nil }
+
+type fakePipelines map[string]*pipeline.Description
+
+func (f fakePipelines) DescribePipelines() map[string]*pipeline.Description { return f }
+
+type fakeErrorLog struct {
+	err error
+}
+
+func (f fakeErrorLog) RecentErrors(int) ([]ErrorEntry, error) { return nil, f.err }
+
+type fakeJobs struct {
+	err       error
+	crawls    int
+	pageRanks int
+}
+
+func (f *fakeJobs) TriggerCrawlPass(context.Context) error {
+	f.crawls++
+	return f.err
+}
+
+func (f *fakeJobs) TriggerPageRankPass(context.Context) error {
+	f.pageRanks++
+	return f.err
+}
+
+func (s *FrontendTestSuite) TestAPIAuth(c *gc.C) {
+	svc, err := NewService(Config{
+		ListenAddress: ":0",
+		Indexer:       s.idx,
+		APIAuth:       &apiauth.Config{Keys: map[string]apiauth.Limit{"key": {Rate: 1, Burst: 1}}},
+	})
+	c.Assert(err, gc.IsNil)
+
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest("GET", "/search?q=gophers", nil))
+	c.Assert(rec.Code, gc.Equals, http.StatusUnauthorized)
+
+	for _, expCode := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/search?q=gophers", nil)
+		req.Header.Set("X-API-Key", "key")
+		rec = httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		c.Assert(rec.Code, gc.Equals, expCode)
+	}
+
+	_, err = NewService(Config{ListenAddress: ":0", Indexer: s.idx, APIAuth: &apiauth.Config{}})
+	c.Assert(err, gc.ErrorMatches, ".*no API keys specified")
+}
@@ -0,0 +1,92 @@
+package frontend
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+)
+
+const (
+	openSearchPath        = "/opensearch.xml"
+	openSearchContentType = "application/opensearchdescription+xml"
+	suggestionsType       = "application/x-suggestions+json"
+)
+
+//openSearchDescription is the OpenSearch 1.1 document that allows browsers to
+//register the frontend as a keyword search provider
+type openSearchDescription struct {
+	XMLName       xml.Name        `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	URLs          []openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Method   string `xml:"method,attr,omitempty"`
+	Rel      string `xml:"rel,attr,omitempty"`
+	Template string `xml:"template,attr"`
+}
+
+func (svc *Service) renderOpenSearchDescription(w http.ResponseWriter, r *http.Request) {
+	base := svc.baseURL(r)
+	desc := openSearchDescription{
+		ShortName:     svc.cfg.SiteName,
+		Description:   fmt.Sprintf("Search %s", svc.cfg.SiteName),
+		InputEncoding: "UTF-8",
+		URLs: []openSearchURL{
+			{Type: "text/html", Method: "get", Template: base + "/search?q={searchTerms}&format=html"},
+			{Type: suggestionsType, Method: "get", Template: base + "/suggest?q={searchTerms}"},
+			{Type: openSearchContentType, Rel: "self", Template: base + openSearchPath},
+		},
+	}
+
+	w.Header().Set("Content-Type", openSearchContentType+"; charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(desc)
+}
+
+/*
+renderSuggestions returns spelling suggestions for the expression in the q
+parameter using the OpenSearch suggestions format, i.e. a JSON array whose
+first element is the expression and the second one the list of suggestions.
+An empty list is returned if the indexer does not support suggestions or the
+suggestions cannot be retrieved, as browsers call this endpoint while users
+are typing
+*/
+func (svc *Service) renderSuggestions(w http.ResponseWriter, r *http.Request) {
+	expr := r.URL.Query().Get("q")
+	suggestions := []string{}
+	if s, ok := svc.cfg.Indexer.(Suggester); ok && expr != "" {
+		if list, err := s.Suggestions(index.Query{Type: index.QueryTypeMatch, Expression: expr}); err == nil && len(list) != 0 {
+			suggestions = list
+		}
+	}
+
+	w.Header().Set("Content-Type", suggestionsType)
+	_ = json.NewEncoder(w).Encode([]interface{}{expr, suggestions})
+}
+
+//openSearchLink returns the value of the Link header that points browsers to
+//the OpenSearch description
+func (svc *Service) openSearchLink() string {
+	return fmt.Sprintf("<%s>; rel=\"search\"; type=%q; title=%q", openSearchPath, openSearchContentType, svc.cfg.SiteName)
+}
+
+//baseURL returns the public URL of the frontend, falling back to the scheme
+//and host of r if no base URL has been configured
+func (svc *Service) baseURL(r *http.Request) string {
+	if svc.cfg.BaseURL != "" {
+		return svc.cfg.BaseURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
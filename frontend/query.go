@@ -0,0 +1,154 @@
+package frontend
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+//afterDateLayout is the date format expected by the after: operator
+const afterDateLayout = "2006-01-02"
+
+//errEmptyQuery is returned by parseQuery for expressions without any search terms
+var errEmptyQuery = xerrors.New("missing search expression")
+
+/*
+parseQuery translates a search expression entered by a user into an
+index.Query.  Besides plain keywords, expressions may contain:
+
+	"exact phrase"    a phrase that must appear verbatim
+	site:example.com  restricts results to a domain and its subdomains
+	-site:example.com omits results from a domain and its subdomains
+	after:2024-01-01  restricts results to pages fetched after a date
+
+An expression that consists of a single quoted phrase yields a phrase query.
+If phrases are mixed with other keywords, a keyword query for all of the
+words is performed instead.  Operators with an unknown name are treated as
+keywords
+*/
+func parseQuery(expr string) (index.Query, error) {
+	var (
+		q       = index.Query{Type: index.QueryTypeMatch}
+		words   []string
+		phrases int
+	)
+
+	for _, tok := range tokenizeQuery(expr) {
+		if tok.phrase {
+			if tok.text != "" {
+				words = append(words, tok.text)
+				phrases++
+			}
+			continue
+		}
+
+		op, arg, isOp := splitOperator(tok.text)
+		switch {
+		case isOp && op == "site":
+			site, err := parseSite(arg)
+			if err != nil {
+				return index.Query{}, err
+			}
+			q.Site = site
+		case isOp && op == "-site":
+			site, err := parseSite(arg)
+			if err != nil {
+				return index.Query{}, err
+			}
+			q.ExcludeDomains = append(q.ExcludeDomains, site)
+		case isOp && op == "after":
+			after, err := time.Parse(afterDateLayout, arg)
+			if err != nil {
+				return index.Query{}, xerrors.Errorf("invalid date %q: expected a date formatted as YYYY-MM-DD", arg)
+			}
+			q.FetchedAfter = after
+		default:
+			words = append(words, tok.text)
+		}
+	}
+
+	if len(words) == 0 {
+		return index.Query{}, errEmptyQuery
+	}
+	if phrases == 1 && len(words) == 1 {
+		q.Type = index.QueryTypePhrase
+	}
+	q.Expression = strings.Join(words, " ")
+	return q, nil
+}
+
+//queryToken is a whitespace-delimited word or a quoted phrase of a search expression
+type queryToken struct {
+	text   string
+	phrase bool
+}
+
+//tokenizeQuery splits expr into words and quoted phrases.  An unterminated
+//quote extends to the end of the expression
+func tokenizeQuery(expr string) []queryToken {
+	var tokens []queryToken
+	for {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			return tokens
+		}
+
+		if expr[0] == '"' {
+			end := strings.IndexByte(expr[1:], '"')
+			if end == -1 {
+				end = len(expr) - 1
+			}
+			tokens = append(tokens, queryToken{text: strings.Join(strings.Fields(expr[1:end+1]), " "), phrase: true})
+			expr = expr[min(end+2, len(expr)):]
+			continue
+		}
+
+		end := strings.IndexAny(expr, " \t\n\"")
+		if end == -1 {
+			end = len(expr)
+		}
+		tokens = append(tokens, queryToken{text: expr[:end]})
+		expr = expr[end:]
+	}
+}
+
+//splitOperator splits a token of the form name:argument.  It returns false if
+//tok is not an operator
+func splitOperator(tok string) (name, arg string, ok bool) {
+	colon := strings.IndexByte(tok, ':')
+	if colon <= 0 || colon == len(tok)-1 {
+		return "", "", false
+	}
+	return strings.ToLower(tok[:colon]), tok[colon+1:], true
+}
+
+//parseSite extracts the host from the argument of a site: operator which
+//may also be specified as a URL
+func parseSite(arg string) (string, error) {
+	host := arg
+	if strings.Contains(arg, "://") {
+		u, err := url.Parse(arg)
+		if err != nil {
+			return "", xerrors.Errorf("invalid site %q: %w", arg, err)
+		}
+		host = u.Hostname()
+	} else if slash := strings.IndexByte(host, '/'); slash != -1 {
+		host = host[:slash]
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", xerrors.Errorf("invalid site %q", arg)
+	}
+	return host, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,95 @@
+package frontend
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/related"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	relatedPath         = "/api/v1/related/"
+	defaultRelatedLimit = 10
+	maxRelatedLimit     = 50
+)
+
+// RelatedFinder is implemented by objects that can find the pages related to
+// a link, such as a related.Finder.  It powers the related pages endpoint
+type RelatedFinder interface {
+	Related(linkID uuid.UUID, n int) ([]related.Result, error)
+}
+
+// documentFinder is optionally implemented by Indexer instances that can look
+// up documents by their link ID
+type documentFinder interface {
+	FindByID(linkID uuid.UUID) (*index.Document, error)
+}
+
+// relatedResult describes a single related page
+type relatedResult struct {
+	LinkID uuid.UUID `json:"link_id"`
+	URL    string    `json:"url"`
+	Title  string    `json:"title,omitempty"`
+	Score  float64   `json:"score"`
+	Source string    `json:"source"`
+}
+
+// relatedResponse is returned by the related pages endpoint
+type relatedResponse struct {
+	LinkID  uuid.UUID       `json:"link_id"`
+	Results []relatedResult `json:"results"`
+}
+
+// relatedURL returns the URL of the related pages endpoint for linkID
+func relatedURL(linkID uuid.UUID) string {
+	return relatedPath + linkID.String()
+}
+
+/*
+renderRelatedPages returns the pages related to the link whose ID follows the
+endpoint path, most related first.  The number of pages is controlled by the
+limit parameter.  Pages are titled with the title of their document if the
+indexer can look up documents
+*/
+func (svc *Service) renderRelatedPages(w http.ResponseWriter, r *http.Request) {
+	linkID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, relatedPath))
+	if err != nil {
+		http.Error(w, "invalid link ID", http.StatusBadRequest)
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultRelatedLimit
+	} else if limit > maxRelatedLimit {
+		limit = maxRelatedLimit
+	}
+
+	pages, err := svc.cfg.Related.Related(linkID, limit)
+	if xerrors.Is(err, graph.ErrNotFound) {
+		http.Error(w, "link not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "unable to retrieve related pages", http.StatusInternalServerError)
+		return
+	}
+
+	finder, _ := svc.cfg.Indexer.(documentFinder)
+	res := relatedResponse{LinkID: linkID, Results: make([]relatedResult, 0, len(pages))}
+	for _, page := range pages {
+		result := relatedResult{LinkID: page.LinkID, URL: page.URL, Score: page.Score, Source: page.Source}
+		//titles are cosmetic; pages that have not been indexed yet are
+		//listed by their URL
+		if finder != nil {
+			if doc, err := finder.FindByID(page.LinkID); err == nil {
+				result.Title = doc.Title
+			}
+		}
+		res.Results = append(res.Results, result)
+	}
+	writeJSON(w, res)
+}
@@ -0,0 +1,194 @@
+package frontend
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/webmaster"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	removalsPath = "/api/v1/removals"
+
+	//maxRemovalBodyBytes bounds the size of removal requests
+	maxRemovalBodyBytes = 16 << 10
+
+	//maxRemovalReasonLength caps the length of the reasons given by the
+	//submitters of removal requests
+	maxRemovalReasonLength = 2000
+
+	//defaultRemovalListLimit is the number of removal requests listed by
+	//the admin endpoint unless a limit is specified
+	defaultRemovalListLimit = 100
+)
+
+//RemovalQueue is implemented by objects that moderate the requests for
+//removing URLs from the index (see webmaster.Manager)
+type RemovalQueue interface {
+	SubmitRemoval(requester, rawURL, reason string) (*webmaster.RemovalRequest, error)
+	RemovalRequests(status webmaster.RemovalStatus, limit int) []*webmaster.RemovalRequest
+	ApproveRemoval(id uuid.UUID) (*webmaster.RemovalRequest, error)
+	RejectRemoval(id uuid.UUID) (*webmaster.RemovalRequest, error)
+}
+
+//removalSubmission is the body of requests to the removal endpoint
+type removalSubmission struct {
+	URL       string `json:"url"`
+	Requester string `json:"requester,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+//removalRequest is the JSON representation of webmaster.RemovalRequest
+type removalRequest struct {
+	ID           string     `json:"id"`
+	URL          string     `json:"url"`
+	Requester    string     `json:"requester,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	Status       string     `json:"status"`
+	SubmittedAt  time.Time  `json:"submitted_at"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+//registerRemovalHandlers adds the moderation endpoints of the removal queue
+//to the admin area
+func (svc *Service) registerRemovalHandlers() {
+	svc.mux.HandleFunc("/admin/removals", svc.requireAdmin(svc.renderRemovalRequests))
+	svc.mux.HandleFunc("/admin/removals/approve", svc.requireAdmin(svc.reviewRemoval(RemovalQueue.ApproveRemoval)))
+	svc.mux.HandleFunc("/admin/removals/reject", svc.requireAdmin(svc.reviewRemoval(RemovalQueue.RejectRemoval)))
+}
+
+//submitRemoval queues the removal request in the JSON body of a POST request
+//for review by a moderator
+func (svc *Service) submitRemoval(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var sub removalSubmission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRemovalBodyBytes)).Decode(&sub); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(sub.Reason) > maxRemovalReasonLength {
+		http.Error(w, "reason too long", http.StatusBadRequest)
+		return
+	}
+
+	req, err := svc.cfg.Removals.SubmitRemoval(sub.Requester, sub.URL, sub.Reason)
+	if xerrors.Is(err, webmaster.ErrInvalidArgument) {
+		http.Error(w, "invalid URL", http.StatusBadRequest)
+		return
+	} else if xerrors.Is(err, webmaster.ErrRemovalQueueFull) {
+		http.Error(w, "too many pending removal requests", http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, "unable to submit removal request", http.StatusInternalServerError)
+		return
+	}
+
+	//The submitter details of other requests for the same URL are not
+	//disclosed
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(w, removalRequest{ID: req.ID.String(), URL: req.URL, Status: string(req.Status), SubmittedAt: req.SubmittedAt})
+}
+
+//renderRemovalRequests lists the removal requests with the status specified
+//by the status query parameter (pending by default or "all"), oldest first
+func (svc *Service) renderRemovalRequests(w http.ResponseWriter, r *http.Request) {
+	if svc.cfg.Removals == nil {
+		http.Error(w, "the removal queue is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	params := r.URL.Query()
+	status := webmaster.RemovalPending
+	switch v := params.Get("status"); v {
+	case "":
+	case "all":
+		status = ""
+	case string(webmaster.RemovalPending), string(webmaster.RemovalApproved), string(webmaster.RemovalRejected):
+		status = webmaster.RemovalStatus(v)
+	default:
+		http.Error(w, "status must be pending, approved, rejected or all", http.StatusBadRequest)
+		return
+	}
+	limit := defaultRemovalListLimit
+	if v := params.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	reqs := make([]removalRequest, 0)
+	for _, req := range svc.cfg.Removals.RemovalRequests(status, limit) {
+		reqs = append(reqs, newRemovalRequest(req))
+	}
+	writeJSON(w, reqs)
+}
+
+//reviewRemoval returns a handler that approves or rejects the removal request
+//specified by the id query parameter via review
+func (svc *Service) reviewRemoval(review func(RemovalQueue, uuid.UUID) (*webmaster.RemovalRequest, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		if svc.cfg.Removals == nil {
+			http.Error(w, "the removal queue is not configured", http.StatusNotImplemented)
+			return
+		}
+		id, err := uuid.Parse(r.URL.Query().Get("id"))
+		if err != nil {
+			http.Error(w, "a valid removal request ID must be specified", http.StatusBadRequest)
+			return
+		}
+
+		req, err := review(svc.cfg.Removals, id)
+		if xerrors.Is(err, webmaster.ErrUnknownRemoval) {
+			http.Error(w, "unknown removal request", http.StatusNotFound)
+			return
+		} else if xerrors.Is(err, webmaster.ErrAlreadyReviewed) {
+			http.Error(w, "removal request already reviewed", http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, "unable to review removal request", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, newRemovalRequest(req))
+	}
+}
+
+func newRemovalRequest(req *webmaster.RemovalRequest) removalRequest {
+	res := removalRequest{
+		ID:          req.ID.String(),
+		URL:         req.URL,
+		Requester:   req.Requester,
+		Reason:      req.Reason,
+		Status:      string(req.Status),
+		SubmittedAt: req.SubmittedAt,
+	}
+	if !req.ReviewedAt.IsZero() {
+		ts := req.ReviewedAt
+		res.ReviewedAt = &ts
+	}
+	if !req.BlockedUntil.IsZero() {
+		ts := req.BlockedUntil
+		res.BlockedUntil = &ts
+	}
+	return res
+}
@@ -0,0 +1,53 @@
+package frontend
+
+import (
+	"github.com/brandonshearin/ask_brandon/linkgraph/hostgraph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+)
+
+// maxSitelinks is the maximum number of sitelinks shown under a search result
+const maxSitelinks = 4
+
+// SitelinkProvider is implemented by objects that can list the most important
+// pages of the host of a URL, such as hostgraph.Sitelinks.  It powers the
+// sitelinks shown under the top search result
+type SitelinkProvider interface {
+	ForURL(rawURL string) []hostgraph.Sitelink
+}
+
+// sitelink is an important page of the site of a search result
+type sitelink struct {
+	URL      string `json:"url"`
+	Title    string `json:"title,omitempty"`
+	ClickURL string `json:"click_url"`
+}
+
+// sitelinksFor returns the sitelinks of the site of the search result at
+// position, excluding the result itself.  Clicks on sitelinks are recorded as
+// clicks on the position of the result.  Sitelinks are titled with the title
+// of their document if the indexer can look up documents
+func (svc *Service) sitelinksFor(queryID uuid.UUID, r searchResult, position int) []sitelink {
+	finder, _ := svc.cfg.Indexer.(documentFinder)
+
+	var links []sitelink
+	for _, candidate := range svc.cfg.Sitelinks.ForURL(r.URL) {
+		if len(links) == maxSitelinks {
+			break
+		} else if candidate.LinkID == r.LinkID || candidate.URL == r.URL {
+			continue
+		}
+
+		link := sitelink{
+			URL:      candidate.URL,
+			ClickURL: clickURL(queryID, &index.Document{LinkID: candidate.LinkID, URL: candidate.URL}, position),
+		}
+		if finder != nil {
+			if doc, err := finder.FindByID(candidate.LinkID); err == nil {
+				link.Title = doc.Title
+			}
+		}
+		links = append(links, link)
+	}
+	return links
+}
@@ -0,0 +1,222 @@
+package frontend
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+)
+
+//maxPageLinks is the maximum number of page links shown by the pagination
+//controls of the results page
+const maxPageLinks = 9
+
+var resultsTemplate = template.Must(template.New("results").Funcs(template.FuncMap{
+	"highlight": highlightTerms,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Expression}}{{.Expression}} - {{end}}{{.SiteName}}</title>
+<link rel="search" type="application/opensearchdescription+xml" href="/opensearch.xml" title="{{.SiteName}}">
+<style>
+mark { background: none; font-weight: bold; }
+.result { margin-bottom: 1.5em; }
+.result .url { color: #006621; font-size: small; }
+.result .sitelinks a { margin-right: 1em; font-size: small; }
+.pagination a, .pagination span { margin-right: 0.5em; }
+.error { color: #a94442; }
+</style>
+</head>
+<body>
+<form action="/search" method="get">
+<input type="text" name="q" value="{{.Expression}}" autofocus>
+<input type="hidden" name="format" value="html">
+<button type="submit">Search</button>
+</form>
+{{- if .Err}}
+<p class="error">{{.Err}}</p>
+{{- else if .Expression}}
+<p class="stats">{{.Total}} result{{if .Plural}}s{{end}} in {{printf "%.1f" .TookMillis}} ms</p>
+{{- range $result := .Results}}
+<div class="result">
+<a href="{{.ClickURL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a>
+<div class="url">{{.URL}}</div>
+<div class="snippet">{{highlight .Snippet $.Query}}</div>
+{{- with .Collapsed}}
+<div class="collapsed">{{len .}} similar result{{if gt (len .) 1}}s{{end}} omitted
+{{- if $result.ExpandURL}} - <a href="{{$result.ExpandURL}}">show all results from this site</a>{{end}}</div>
+{{- end}}
+{{- with .Sitelinks}}
+<div class="sitelinks">
+{{- range .}}<a href="{{.ClickURL}}">{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</a>{{end}}
+</div>
+{{- end}}
+</div>
+{{- end}}
+{{- if .Pages}}
+<div class="pagination">
+{{- if .PrevURL}}<a href="{{.PrevURL}}">Previous</a>{{end}}
+{{- range .Pages}}
+{{- if .Current}}<span>{{.Number}}</span>{{else}}<a href="{{.URL}}">{{.Number}}</a>{{end}}
+{{- end}}
+{{- if .NextURL}}<a href="{{.NextURL}}">Next</a>{{end}}
+</div>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+//resultsPage holds the data rendered by resultsTemplate
+type resultsPage struct {
+	*searchResponse
+
+	SiteName   string
+	Query      index.Query
+	Expression string
+	Err        string
+
+	PrevURL string
+	NextURL string
+	Pages   []pageLink
+}
+
+//Plural returns true unless exactly one result was found
+func (p resultsPage) Plural() bool {
+	return p.Total != 1
+}
+
+//pageLink is a link to a page of search results
+type pageLink struct {
+	Number  int
+	URL     string
+	Current bool
+}
+
+//newResultsPage populates a resultsPage for res including the pagination
+//links which are derived from the URL of the current request
+func newResultsPage(reqURL *url.URL, expr string, q index.Query, res *searchResponse) resultsPage {
+	page := resultsPage{searchResponse: res, Query: q, Expression: expr}
+	if res.Total <= maxResultsPerPage && q.Offset == 0 {
+		return page
+	}
+
+	pageURL := func(offset int) string {
+		params := reqURL.Query()
+		params.Set("offset", strconv.Itoa(offset))
+		params.Set("format", "html")
+		return "/search?" + params.Encode()
+	}
+
+	var (
+		current  = q.Offset/maxResultsPerPage + 1
+		numPages = int((res.Total + maxResultsPerPage - 1) / maxResultsPerPage)
+		first    = current - maxPageLinks/2
+	)
+	if first+maxPageLinks-1 > numPages {
+		first = numPages - maxPageLinks + 1
+	}
+	if first < 1 {
+		first = 1
+	}
+	for number := first; number <= numPages && number < first+maxPageLinks; number++ {
+		page.Pages = append(page.Pages, pageLink{
+			Number:  number,
+			URL:     pageURL((number - 1) * maxResultsPerPage),
+			Current: number == current,
+		})
+	}
+
+	if q.Offset > 0 {
+		prev := q.Offset - maxResultsPerPage
+		if prev < 0 {
+			prev = 0
+		}
+		page.PrevURL = pageURL(prev)
+	}
+	if next := q.Offset + res.hits; uint64(next) < res.Total {
+		page.NextURL = pageURL(next)
+	}
+	return page
+}
+
+//highlightTerms escapes snippet and wraps the words that match any of the
+//terms of the query in <mark> tags
+func highlightTerms(snippet string, q index.Query) template.HTML {
+	terms := make(map[string]bool)
+	for _, field := range strings.Fields(q.Expression) {
+		if term := normalizeTerm(field); term != "" {
+			terms[term] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	for i, word := range strings.Fields(snippet) {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		if !terms[normalizeTerm(word)] {
+			buf.WriteString(template.HTMLEscapeString(word))
+			continue
+		}
+
+		//keep any surrounding punctuation outside of the highlighted term
+		notTerm := func(r rune) bool { return !isTermRune(r) }
+		start := len(word) - len(strings.TrimLeftFunc(word, notTerm))
+		end := len(strings.TrimRightFunc(word, notTerm))
+		buf.WriteString(template.HTMLEscapeString(word[:start]))
+		buf.WriteString("<mark>")
+		buf.WriteString(template.HTMLEscapeString(word[start:end]))
+		buf.WriteString("</mark>")
+		buf.WriteString(template.HTMLEscapeString(word[end:]))
+	}
+	return template.HTML(buf.String())
+}
+
+//normalizeTerm lowercases term and strips any leading or trailing punctuation
+func normalizeTerm(term string) string {
+	return strings.ToLower(strings.TrimFunc(term, func(r rune) bool { return !isTermRune(r) }))
+}
+
+func isTermRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+//wantsHTML returns true if the search results should be rendered as an HTML
+//page instead of JSON
+func wantsHTML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "html"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+func (svc *Service) renderHTML(w http.ResponseWriter, status int, page resultsPage) {
+	if page.searchResponse == nil {
+		page.searchResponse = new(searchResponse)
+	}
+	page.SiteName = svc.cfg.SiteName
+
+	var buf bytes.Buffer
+	if err := resultsTemplate.Execute(&buf, page); err != nil {
+		http.Error(w, "unable to render search results", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
+}
+
+//searchErrorMessage returns a user-friendly message for a failed search
+func searchErrorMessage(status int) string {
+	if status == http.StatusServiceUnavailable {
+		return "Search is temporarily unavailable. Please try again in a few moments."
+	}
+	return "Something went wrong while searching. Please try again."
+}
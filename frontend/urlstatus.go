@@ -0,0 +1,178 @@
+package frontend
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+const (
+	urlStatusPath = "/api/v1/urlstatus"
+
+	//maxURLStatusURLs is the maximum number of URLs that can be looked up
+	//by a single request to the URL status endpoint
+	maxURLStatusURLs = 100
+
+	//maxURLStatusBodyBytes bounds the size of URL status requests
+	maxURLStatusBodyBytes = 1 << 20
+)
+
+// LinkFinder is implemented by link graphs that can look up links by their
+// URL (see graph.ReadOnlyGraph).  It powers the URL status endpoint
+type LinkFinder interface {
+	FindLinksByURL(rawURLs []string) ([]*graph.Link, error)
+}
+
+// urlDocumentFinder is optionally implemented by Indexer instances that can
+// look up documents by their URL
+type urlDocumentFinder interface {
+	FindByURLs(rawURLs []string) ([]*index.Document, error)
+}
+
+// urlStatusRequest is the body of requests to the URL status endpoint
+type urlStatusRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// urlStatus describes whether a URL is known to the crawler and whether its
+// page has been indexed.  Error is set for URLs that are not valid
+type urlStatus struct {
+	URL           string     `json:"url"`
+	Known         bool       `json:"known"`
+	LinkID        string     `json:"link_id,omitempty"`
+	LastRetrieved *time.Time `json:"last_retrieved,omitempty"`
+	Indexed       bool       `json:"indexed"`
+	IndexedAt     *time.Time `json:"indexed_at,omitempty"`
+	PageRank      float64    `json:"pagerank"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// urlStatusResponse is returned by the URL status endpoint
+type urlStatusResponse struct {
+	Results []urlStatus `json:"results"`
+}
+
+/*
+renderURLStatus reports the crawl and index status of the URLs listed in the
+JSON body of a POST request, in the order in which they are listed, so that
+site owners can audit the coverage of their site.  Up to maxURLStatusURLs can
+be looked up at once.  Pages are only reported as indexed if the indexer can
+look up documents by URL
+*/
+func (svc *Service) renderURLStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req urlStatusRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxURLStatusBodyBytes)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.URLs) == 0 {
+		http.Error(w, "no URLs specified", http.StatusBadRequest)
+		return
+	} else if len(req.URLs) > maxURLStatusURLs {
+		http.Error(w, "too many URLs", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	statuses, err := svc.lookupURLStatus(req.URLs)
+	if err != nil {
+		http.Error(w, "unable to look up URLs", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, urlStatusResponse{Results: statuses})
+}
+
+// lookupURLStatus looks up urls in the link graph and the index.  URLs that
+// normalize to the same URL are only looked up once and each store is queried
+// with a single batch lookup.  Invalid URLs are reported in their status while
+// lookup failures abort the whole request
+func (svc *Service) lookupURLStatus(urls []string) ([]urlStatus, error) {
+	var (
+		normURLs = make([]string, len(urls))
+		batch    = make([]string, 0, len(urls))
+		batchIdx = make(map[string]int, len(urls))
+	)
+	for i, rawURL := range urls {
+		normURL, err := graph.NormalizeURL(rawURL)
+		if err != nil {
+			continue
+		}
+		normURLs[i] = normURL
+		if _, found := batchIdx[normURL]; !found {
+			batchIdx[normURL] = len(batch)
+			batch = append(batch, normURL)
+		}
+	}
+
+	batchStatuses, err := svc.urlStatusFor(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]urlStatus, len(urls))
+	for i, rawURL := range urls {
+		if normURLs[i] == "" {
+			statuses[i] = urlStatus{URL: rawURL, Error: "invalid URL"}
+			continue
+		}
+		statuses[i] = batchStatuses[batchIdx[normURLs[i]]]
+		statuses[i].URL = rawURL
+	}
+	return statuses, nil
+}
+
+// urlStatusFor looks up the status of each of the normalized URLs in normURLs
+func (svc *Service) urlStatusFor(normURLs []string) ([]urlStatus, error) {
+	statuses := make([]urlStatus, len(normURLs))
+	if len(normURLs) == 0 {
+		return statuses, nil
+	}
+
+	links, err := svc.cfg.URLStatus.FindLinksByURL(normURLs)
+	if err != nil {
+		return nil, err
+	} else if len(links) != len(normURLs) {
+		return nil, xerrors.Errorf("link lookup returned %d results for %d URLs", len(links), len(normURLs))
+	}
+	for i, link := range links {
+		if link == nil {
+			continue
+		}
+		statuses[i].Known, statuses[i].LinkID = true, link.ID.String()
+		if !link.RetrievedAt.IsZero() {
+			ts := link.RetrievedAt
+			statuses[i].LastRetrieved = &ts
+		}
+	}
+
+	finder, ok := svc.cfg.Indexer.(urlDocumentFinder)
+	if !ok {
+		return statuses, nil
+	}
+	docs, err := finder.FindByURLs(normURLs)
+	if err != nil {
+		return nil, err
+	} else if len(docs) != len(normURLs) {
+		return nil, xerrors.Errorf("document lookup returned %d results for %d URLs", len(docs), len(normURLs))
+	}
+	for i, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		statuses[i].Indexed, statuses[i].PageRank = true, doc.PageRank
+		if !doc.IndexedAt.IsZero() {
+			ts := doc.IndexedAt
+			statuses[i].IndexedAt = &ts
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,256 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/brandonshearin/ask_brandon/webmaster"
+	"golang.org/x/xerrors"
+)
+
+const (
+	//maxWebmasterURLs is the maximum number of URLs that can be recrawled
+	//or removed by a single request
+	maxWebmasterURLs = 100
+
+	//maxWebmasterBodyBytes bounds the size of webmaster requests
+	maxWebmasterBodyBytes = 1 << 20
+
+	//webmasterVerifyTimeout bounds the time spent looking up the
+	//verification token of a site
+	webmasterVerifyTimeout = 30 * time.Second
+)
+
+//WebmasterTools is implemented by objects that manage the sites of verified
+//owners (see webmaster.Manager)
+type WebmasterTools interface {
+	Claim(owner, siteURL string) (*webmaster.Site, error)
+	Verify(ctx context.Context, owner, host string, method webmaster.Method) (*webmaster.Site, error)
+	Sites(owner string) []*webmaster.Site
+	RequestRecrawl(owner, rawURL string) (*graph.Link, error)
+	SetCrawlDelay(owner, host string, delay time.Duration) error
+	RemoveURL(owner, rawURL string) error
+}
+
+//webmasterRequest is the body of requests to the webmaster endpoints.  Each
+//endpoint only uses a subset of the fields
+type webmasterRequest struct {
+	Owner        string   `json:"owner"`
+	Site         string   `json:"site,omitempty"`
+	Host         string   `json:"host,omitempty"`
+	Method       string   `json:"method,omitempty"`
+	URLs         []string `json:"urls,omitempty"`
+	CrawlDelayMS int64    `json:"crawl_delay_ms,omitempty"`
+}
+
+//webmasterSite is the JSON representation of webmaster.Site
+type webmasterSite struct {
+	Owner        string     `json:"owner"`
+	Host         string     `json:"host"`
+	Origin       string     `json:"origin"`
+	Token        string     `json:"token"`
+	MetaTag      string     `json:"meta_tag"`
+	TXTRecord    string     `json:"txt_record"`
+	Verified     bool       `json:"verified"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+	Method       string     `json:"method,omitempty"`
+	CrawlDelayMS int64      `json:"crawl_delay_ms,omitempty"`
+}
+
+//webmasterURLResult reports the outcome of recrawling or removing a URL
+type webmasterURLResult struct {
+	URL    string `json:"url"`
+	LinkID string `json:"link_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+//registerWebmasterHandlers adds the webmaster endpoints to the admin area
+func (svc *Service) registerWebmasterHandlers() {
+	svc.mux.HandleFunc("/admin/webmaster/sites", svc.requireAdmin(svc.renderWebmasterSites))
+	svc.mux.HandleFunc("/admin/webmaster/claim", svc.requireAdmin(svc.webmasterAction(svc.claimSite)))
+	svc.mux.HandleFunc("/admin/webmaster/verify", svc.requireAdmin(svc.webmasterAction(svc.verifySite)))
+	svc.mux.HandleFunc("/admin/webmaster/crawlrate", svc.requireAdmin(svc.webmasterAction(svc.setCrawlRate)))
+	svc.mux.HandleFunc("/admin/webmaster/recrawl", svc.requireAdmin(svc.webmasterAction(svc.recrawlURLs)))
+	svc.mux.HandleFunc("/admin/webmaster/remove", svc.requireAdmin(svc.webmasterAction(svc.removeURLs)))
+}
+
+//renderWebmasterSites lists the sites claimed by the owner specified by the
+//owner query parameter
+func (svc *Service) renderWebmasterSites(w http.ResponseWriter, r *http.Request) {
+	tools := svc.cfg.Admin.Webmaster
+	if tools == nil {
+		http.Error(w, "webmaster tools are not configured", http.StatusNotImplemented)
+		return
+	}
+	owner := r.URL.Query().Get("owner")
+	if owner == "" {
+		http.Error(w, "owner must be specified", http.StatusBadRequest)
+		return
+	}
+
+	sites := make([]webmasterSite, 0)
+	for _, site := range tools.Sites(owner) {
+		sites = append(sites, newWebmasterSite(site))
+	}
+	writeJSON(w, sites)
+}
+
+//webmasterAction returns a handler that decodes the JSON body of a POST
+//request and passes it to action
+func (svc *Service) webmasterAction(action func(http.ResponseWriter, *http.Request, WebmasterTools, webmasterRequest)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "cross-origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+		tools := svc.cfg.Admin.Webmaster
+		if tools == nil {
+			http.Error(w, "webmaster tools are not configured", http.StatusNotImplemented)
+			return
+		}
+
+		var req webmasterRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebmasterBodyBytes)).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Owner == "" {
+			http.Error(w, "owner must be specified", http.StatusBadRequest)
+			return
+		}
+		action(w, r, tools, req)
+	}
+}
+
+func (svc *Service) claimSite(w http.ResponseWriter, _ *http.Request, tools WebmasterTools, req webmasterRequest) {
+	site, err := tools.Claim(req.Owner, req.Site)
+	if err != nil {
+		writeWebmasterError(w, err)
+		return
+	}
+	writeJSON(w, newWebmasterSite(site))
+}
+
+func (svc *Service) verifySite(w http.ResponseWriter, r *http.Request, tools WebmasterTools, req webmasterRequest) {
+	ctx, cancel := context.WithTimeout(r.Context(), webmasterVerifyTimeout)
+	defer cancel()
+
+	site, err := tools.Verify(ctx, req.Owner, req.Host, webmaster.Method(req.Method))
+	if err != nil {
+		writeWebmasterError(w, err)
+		return
+	}
+	writeJSON(w, newWebmasterSite(site))
+}
+
+func (svc *Service) setCrawlRate(w http.ResponseWriter, _ *http.Request, tools WebmasterTools, req webmasterRequest) {
+	if err := tools.SetCrawlDelay(req.Owner, req.Host, time.Duration(req.CrawlDelayMS)*time.Millisecond); err != nil {
+		writeWebmasterError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+//recrawlURLs schedules a recrawl of each of the listed URLs.  Failures are
+//reported per URL
+func (svc *Service) recrawlURLs(w http.ResponseWriter, _ *http.Request, tools WebmasterTools, req webmasterRequest) {
+	svc.eachWebmasterURL(w, req, func(rawURL string) (webmasterURLResult, error) {
+		link, err := tools.RequestRecrawl(req.Owner, rawURL)
+		if err != nil {
+			return webmasterURLResult{}, err
+		}
+		return webmasterURLResult{LinkID: link.ID.String()}, nil
+	})
+}
+
+//removeURLs removes each of the listed URLs from the index.  Failures are
+//reported per URL
+func (svc *Service) removeURLs(w http.ResponseWriter, _ *http.Request, tools WebmasterTools, req webmasterRequest) {
+	svc.eachWebmasterURL(w, req, func(rawURL string) (webmasterURLResult, error) {
+		return webmasterURLResult{}, tools.RemoveURL(req.Owner, rawURL)
+	})
+}
+
+//eachWebmasterURL invokes fn for each URL of req and reports the results
+func (svc *Service) eachWebmasterURL(w http.ResponseWriter, req webmasterRequest, fn func(string) (webmasterURLResult, error)) {
+	if len(req.URLs) == 0 {
+		http.Error(w, "no URLs specified", http.StatusBadRequest)
+		return
+	} else if len(req.URLs) > maxWebmasterURLs {
+		http.Error(w, "too many URLs", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]webmasterURLResult, 0, len(req.URLs))
+	for _, rawURL := range req.URLs {
+		res, err := fn(rawURL)
+		if err != nil {
+			res.Error = webmasterErrorMessage(err)
+		}
+		res.URL = rawURL
+		results = append(results, res)
+	}
+	writeJSON(w, map[string][]webmasterURLResult{"results": results})
+}
+
+//writeWebmasterError maps err to an HTTP status code
+func writeWebmasterError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case xerrors.Is(err, webmaster.ErrInvalidArgument):
+		status = http.StatusBadRequest
+	case xerrors.Is(err, webmaster.ErrNotVerified):
+		status = http.StatusForbidden
+	case xerrors.Is(err, webmaster.ErrUnknownSite):
+		status = http.StatusNotFound
+	case xerrors.Is(err, webmaster.ErrVerificationFailed):
+		status = http.StatusUnprocessableEntity
+	}
+	http.Error(w, webmasterErrorMessage(err), status)
+}
+
+//webmasterErrorMessage returns a message describing err that does not leak
+//internal details
+func webmasterErrorMessage(err error) string {
+	switch {
+	case xerrors.Is(err, webmaster.ErrInvalidArgument):
+		return "invalid argument"
+	case xerrors.Is(err, webmaster.ErrNotVerified):
+		return "site not verified"
+	case xerrors.Is(err, webmaster.ErrUnknownSite):
+		return "site not claimed"
+	case xerrors.Is(err, webmaster.ErrVerificationFailed):
+		return "verification token not found"
+	case xerrors.Is(err, index.ErrNotFound):
+		return "URL not indexed"
+	}
+	return "internal error"
+}
+
+func newWebmasterSite(site *webmaster.Site) webmasterSite {
+	res := webmasterSite{
+		Owner:        site.Owner,
+		Host:         site.Host,
+		Origin:       site.Origin,
+		Token:        site.Token,
+		MetaTag:      site.MetaTag(),
+		TXTRecord:    site.TXTRecord(),
+		Verified:     site.Verified(),
+		Method:       string(site.Method),
+		CrawlDelayMS: int64(site.CrawlDelay / time.Millisecond),
+	}
+	if res.Verified {
+		ts := site.VerifiedAt
+		res.VerifiedAt = &ts
+	}
+	return res
+}
@@ -0,0 +1,213 @@
+package graph
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultCacheSize = 10000
+	defaultCacheTTL  = 5 * time.Minute
+)
+
+/*CacheConfig controls the size of the LRU cache used by WithCache and how long
+cached links remain valid.  Zero values are replaced by defaults of 10000 links
+and 5 minutes respectively*/
+type CacheConfig struct {
+	Size int
+	TTL  time.Duration
+}
+
+func (cfg CacheConfig) size() int {
+	if cfg.Size <= 0 {
+		return defaultCacheSize
+	}
+	return cfg.Size
+}
+
+func (cfg CacheConfig) ttl() time.Duration {
+	if cfg.TTL <= 0 {
+		return defaultCacheTTL
+	}
+	return cfg.TTL
+}
+
+/*WithCache decorates g with an LRU cache for FindLink lookups.  Cached links are
+invalidated when they are upserted through the returned graph (or through a
+transaction started by it); changes applied to g by other clients become
+visible once the cached entries expire.  If g implements Transactor, so does
+the returned graph.  The other optional interfaces of g can be reached via the
+As* helpers (see Wrapper)*/
+func WithCache(g Graph, cfg CacheConfig) Graph {
+	cg := &cachingGraph{Graph: g, cache: newLinkCache(cfg.size(), cfg.ttl())}
+	if txg, ok := g.(Transactor); ok {
+		return &cachingTxGraph{cachingGraph: cg, txg: txg}
+	}
+	return cg
+}
+
+type cachingGraph struct {
+	Graph
+	cache *linkCache
+}
+
+func (c *cachingGraph) UpsertLink(link *Link) error {
+	err := c.Graph.UpsertLink(link)
+	c.cache.invalidate(link.ID)
+	return err
+}
+
+func (c *cachingGraph) FindLink(id uuid.UUID) (*Link, error) {
+	if link := c.cache.get(id); link != nil {
+		return link, nil
+	}
+
+	gen := c.cache.generation()
+	link, err := c.Graph.FindLink(id)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(gen, link)
+	return copyLink(link), nil
+}
+
+//Unwrap implements Wrapper
+func (c *cachingGraph) Unwrap() Graph { return c.Graph }
+
+type cachingTxGraph struct {
+	*cachingGraph
+	txg Transactor
+}
+
+func (c *cachingTxGraph) Begin() (Tx, error) {
+	tx, err := c.txg.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &cachingTx{Tx: tx, cache: c.cache}, nil
+}
+
+//cachingTx invalidates the cached copies of the links upserted by a
+//transaction once it has been committed
+type cachingTx struct {
+	Tx
+	cache *linkCache
+	links []*Link
+}
+
+func (tx *cachingTx) UpsertLink(link *Link) error {
+	if err := tx.Tx.UpsertLink(link); err != nil {
+		return err
+	}
+	tx.links = append(tx.links, link)
+	return nil
+}
+
+func (tx *cachingTx) Commit() error {
+	err := tx.Tx.Commit()
+	for _, link := range tx.links {
+		tx.cache.invalidate(link.ID)
+	}
+	tx.links = nil
+	return err
+}
+
+//linkCache is an LRU cache of links with a per-entry expiry time
+type linkCache struct {
+	mu      sync.Mutex
+	maxSize int
+	ttl     time.Duration
+	now     func() time.Time
+	lru     *list.List
+	entries map[uuid.UUID]*list.Element
+
+	//gen is incremented on every invalidation so that values fetched
+	//from the backend before an upsert completed are not cached
+	gen uint64
+}
+
+type linkCacheEntry struct {
+	link      *Link
+	expiresAt time.Time
+}
+
+func newLinkCache(maxSize int, ttl time.Duration) *linkCache {
+	return &linkCache{
+		maxSize: maxSize,
+		ttl:     ttl,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+//get returns a copy of the cached link with the specified ID or nil if the
+//link is not cached or its entry has expired
+func (c *linkCache) get(id uuid.UUID) *Link {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[id]
+	if !found {
+		return nil
+	}
+	entry := elem.Value.(*linkCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return copyLink(entry.link)
+}
+
+func (c *linkCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+//put caches a copy of link unless the cache was invalidated after gen was
+//obtained
+func (c *linkCache) put(gen uint64, link *Link) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	entry := &linkCacheEntry{link: copyLink(link), expiresAt: c.now().Add(c.ttl)}
+	if elem, found := c.entries[link.ID]; found {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[link.ID] = c.lru.PushFront(entry)
+	if c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *linkCache) invalidate(id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	if elem, found := c.entries[id]; found {
+		c.remove(elem)
+	}
+}
+
+func (c *linkCache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*linkCacheEntry).link.ID)
+	c.lru.Remove(elem)
+}
+
+func copyLink(link *Link) *Link {
+	lCopy := new(Link)
+	*lCopy = *link
+	return lCopy
+}
@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CacheTestSuite))
+
+type CacheTestSuite struct{}
+
+func (s *CacheTestSuite) TestFindLinkIsCached(c *gc.C) {
+	g := newCountingGraph()
+	cg := WithCache(g, CacheConfig{})
+
+	id := uuid.New()
+	g.links[id] = &Link{ID: id, URL: "https://example.com"}
+
+	for i := 0; i < 3; i++ {
+		link, err := cg.FindLink(id)
+		c.Assert(err, gc.IsNil)
+		c.Assert(link.URL, gc.Equals, "https://example.com")
+
+		// Mutating the returned link must not affect the cached copy
+		link.URL = "mutated"
+	}
+	c.Assert(g.findCalls, gc.Equals, 1)
+}
+
+func (s *CacheTestSuite) TestUpsertInvalidatesCachedLink(c *gc.C) {
+	g := newCountingGraph()
+	cg := WithCache(g, CacheConfig{})
+
+	link := &Link{URL: "https://example.com"}
+	c.Assert(cg.UpsertLink(link), gc.IsNil)
+	_, err := cg.FindLink(link.ID)
+	c.Assert(err, gc.IsNil)
+
+	link.Feed = true
+	c.Assert(cg.UpsertLink(link), gc.IsNil)
+	found, err := cg.FindLink(link.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.Feed, gc.Equals, true)
+	c.Assert(g.findCalls, gc.Equals, 2)
+}
+
+func (s *CacheTestSuite) TestEviction(c *gc.C) {
+	g := newCountingGraph()
+	cg := WithCache(g, CacheConfig{Size: 2}).(*cachingGraph)
+
+	now := time.Now()
+	cg.cache.now = func() time.Time { return now }
+
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		ids[i] = uuid.New()
+		g.links[ids[i]] = &Link{ID: ids[i]}
+		_, err := cg.FindLink(ids[i])
+		c.Assert(err, gc.IsNil)
+	}
+
+	// The least recently used link has been evicted
+	c.Assert(cg.cache.get(ids[0]), gc.IsNil)
+	c.Assert(cg.cache.get(ids[1]), gc.NotNil)
+	c.Assert(cg.cache.get(ids[2]), gc.NotNil)
+
+	// Entries expire after the TTL
+	now = now.Add(defaultCacheTTL)
+	c.Assert(cg.cache.get(ids[2]), gc.IsNil)
+}
+
+func (s *CacheTestSuite) TestNotFoundIsNotCached(c *gc.C) {
+	g := newCountingGraph()
+	cg := WithCache(g, CacheConfig{})
+
+	id := uuid.New()
+	_, err := cg.FindLink(id)
+	c.Assert(err, gc.Equals, ErrNotFound)
+
+	g.links[id] = &Link{ID: id}
+	_, err = cg.FindLink(id)
+	c.Assert(err, gc.IsNil)
+}
+
+// countingGraph is a map-backed Graph that counts FindLink calls.
+type countingGraph struct {
+	Graph
+
+	links     map[uuid.UUID]*Link
+	findCalls int
+}
+
+func newCountingGraph() *countingGraph {
+	return &countingGraph{links: make(map[uuid.UUID]*Link)}
+}
+
+func (g *countingGraph) UpsertLink(link *Link) error {
+	if link.ID == uuid.Nil {
+		link.ID = uuid.New()
+	}
+	g.links[link.ID] = copyLink(link)
+	return nil
+}
+
+func (g *countingGraph) FindLink(id uuid.UUID) (*Link, error) {
+	g.findCalls++
+	link, found := g.links[id]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return copyLink(link), nil
+}
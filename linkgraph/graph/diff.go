@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	minUUID = uuid.Nil
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+/*DiffReport describes the differences between two graphs.  Since link IDs are
+assigned independently by each graph, links are identified by their URL and
+edges are formatted as "src-URL -> dst-URL"*/
+type DiffReport struct {
+	// Links and edges that only exist in the first graph.
+	MissingLinks []string
+	MissingEdges []string
+
+	// Links and edges that only exist in the second graph.
+	ExtraLinks []string
+	ExtraEdges []string
+}
+
+/*Consistent returns true if no differences were found*/
+func (r *DiffReport) Consistent() bool {
+	return len(r.MissingLinks)+len(r.MissingEdges)+len(r.ExtraLinks)+len(r.ExtraEdges) == 0
+}
+
+/*PassDiff describes the differences between two crawl passes (see
+CrawlPassDiffer).  Links are identified by their URL and edges are formatted
+as "src-URL -> dst-URL"*/
+type PassDiff struct {
+	PassA, PassB uint64
+
+	// Links retrieved by PassB but not by PassA and vice versa.
+	NewLinks     []string
+	RemovedLinks []string
+
+	// Edges upserted by PassB but not by PassA, and edges upserted by
+	// PassA whose source link was re-crawled by PassB without them.
+	NewEdges     []string
+	RemovedEdges []string
+
+	// Links retrieved by both passes whose content hash differs.
+	ChangedDocuments []string
+}
+
+/*Empty returns true if no differences were found*/
+func (d *PassDiff) Empty() bool {
+	return len(d.NewLinks)+len(d.RemovedLinks)+len(d.NewEdges)+len(d.RemovedEdges)+len(d.ChangedDocuments) == 0
+}
+
+/*Diff compares the links and edges of two graphs, e.g. the primary and
+secondary graph of a DualWriteGraph.  Diff holds the contents of both graphs in
+memory so it is meant for offline consistency checks*/
+func Diff(a, b ReadOnlyGraph) (*DiffReport, error) {
+	now := time.Now()
+	aLinks, aEdges, err := graphContents(a, now)
+	if err != nil {
+		return nil, xerrors.Errorf("diff: %w", err)
+	}
+	bLinks, bEdges, err := graphContents(b, now)
+	if err != nil {
+		return nil, xerrors.Errorf("diff: %w", err)
+	}
+
+	return &DiffReport{
+		MissingLinks: setDifference(aLinks, bLinks),
+		MissingEdges: setDifference(aEdges, bEdges),
+		ExtraLinks:   setDifference(bLinks, aLinks),
+		ExtraEdges:   setDifference(bEdges, aEdges),
+	}, nil
+}
+
+//graphContents returns the set of link URLs and formatted edges in g
+func graphContents(g ReadOnlyGraph, now time.Time) (map[string]bool, map[string]bool, error) {
+	linkIt, err := g.Links(minUUID, maxUUID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	urls := make(map[uuid.UUID]string)
+	for linkIt.Next() {
+		link := linkIt.Link()
+		urls[link.ID] = link.URL
+	}
+	if err = linkIt.Error(); err != nil {
+		_ = linkIt.Close()
+		return nil, nil, err
+	}
+	if err = linkIt.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	edgeIt, err := g.Edges(minUUID, maxUUID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	edges := make(map[string]bool)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		edges[urls[edge.Src]+" -> "+urls[edge.Dst]] = true
+	}
+	if err = edgeIt.Error(); err != nil {
+		_ = edgeIt.Close()
+		return nil, nil, err
+	}
+	if err = edgeIt.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	links := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		links[url] = true
+	}
+	return links, edges, nil
+}
+
+//setDifference returns the sorted list of keys in a that are not in b
+func setDifference(a, b map[string]bool) []string {
+	var out []string
+	for key := range a {
+		if !b[key] {
+			out = append(out, key)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
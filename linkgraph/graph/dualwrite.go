@@ -0,0 +1,194 @@
+package graph
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+/*DualWriteGraph mirrors all writes to a secondary graph while serving reads from
+the primary one.  It is meant to be used while migrating between graph
+backends: once the secondary graph has caught up (see Diff), clients can be
+switched over to it.
+
+Since each backend assigns its own link IDs, DualWriteGraph maintains a mapping
+from primary to secondary link IDs which is populated as links are upserted.
+Links that were created before the dual writer was put in place are looked up
+in the primary graph and copied over on demand.
+
+Writes to the secondary graph never fail the caller; the number of failed
+secondary writes and the last error are available via SecondaryErrors*/
+type DualWriteGraph struct {
+	primary   Graph
+	secondary Graph
+
+	mu          sync.RWMutex
+	secondaryID map[uuid.UUID]uuid.UUID
+	errCount    uint64
+	lastErr     error
+}
+
+/*DualWriter returns a graph that writes to both primary and secondary and reads
+from primary*/
+func DualWriter(primary, secondary Graph) *DualWriteGraph {
+	return &DualWriteGraph{
+		primary:     primary,
+		secondary:   secondary,
+		secondaryID: make(map[uuid.UUID]uuid.UUID),
+	}
+}
+
+/*SecondaryErrors returns the number of writes that failed to be mirrored to the
+secondary graph and the last such error*/
+func (d *DualWriteGraph) SecondaryErrors() (uint64, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.errCount, d.lastErr
+}
+
+/*Unwrap implements Wrapper.  It returns the primary graph so that crawl passes
+are allocated and tracked by the graph that serves the reads*/
+func (d *DualWriteGraph) Unwrap() Graph { return d.primary }
+
+func (d *DualWriteGraph) UpsertLink(link *Link) error {
+	if err := d.primary.UpsertLink(link); err != nil {
+		return err
+	}
+	d.recordSecondaryErr(d.mirrorLink(link))
+	return nil
+}
+
+func (d *DualWriteGraph) UpsertEdge(edge *Edge) error {
+	if err := d.primary.UpsertEdge(edge); err != nil {
+		return err
+	}
+
+	src, err := d.mapLinkID(edge.Src)
+	if err != nil {
+		d.recordSecondaryErr(err)
+		return nil
+	}
+	dst, err := d.mapLinkID(edge.Dst)
+	if err != nil {
+		d.recordSecondaryErr(err)
+		return nil
+	}
+	d.recordSecondaryErr(d.secondary.UpsertEdge(&Edge{Src: src, Dst: dst}))
+	return nil
+}
+
+func (d *DualWriteGraph) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error {
+	if err := d.primary.RemoveStaleEdges(fromID, updatedBefore); err != nil {
+		return err
+	}
+
+	secondaryFromID, err := d.mapLinkID(fromID)
+	if err == nil {
+		err = d.secondary.RemoveStaleEdges(secondaryFromID, updatedBefore)
+	}
+	d.recordSecondaryErr(err)
+	return nil
+}
+
+/*RemoveStaleEdgesInRange prunes the partition in the primary graph.  As link IDs
+differ between the two graphs, the links in the partition are enumerated from
+the primary graph and their stale edges are removed one by one from the
+secondary graph*/
+func (d *DualWriteGraph) RemoveStaleEdgesInRange(fromID, toID uuid.UUID, updatedBefore time.Time) error {
+	if err := d.primary.RemoveStaleEdgesInRange(fromID, toID, updatedBefore); err != nil {
+		return err
+	}
+
+	linkIt, err := d.primary.Links(fromID, toID, time.Now().Add(time.Hour))
+	if err != nil {
+		d.recordSecondaryErr(err)
+		return nil
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	for linkIt.Next() {
+		secondaryFromID, err := d.mapLinkID(linkIt.Link().ID)
+		if err == nil {
+			err = d.secondary.RemoveStaleEdges(secondaryFromID, updatedBefore)
+		}
+		d.recordSecondaryErr(err)
+	}
+	d.recordSecondaryErr(linkIt.Error())
+	return nil
+}
+
+func (d *DualWriteGraph) FindLink(id uuid.UUID) (*Link, error) {
+	return d.primary.FindLink(id)
+}
+
+func (d *DualWriteGraph) FindLinkByURL(rawURL string) (*Link, error) {
+	return d.primary.FindLinkByURL(rawURL)
+}
+
+func (d *DualWriteGraph) FindLinksByURL(rawURLs []string) ([]*Link, error) {
+	return d.primary.FindLinksByURL(rawURLs)
+}
+
+func (d *DualWriteGraph) FindEdge(src, dst uuid.UUID) (*Edge, error) {
+	return d.primary.FindEdge(src, dst)
+}
+
+func (d *DualWriteGraph) Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (LinkIterator, error) {
+	return d.primary.Links(fromID, toID, retrievedBefore)
+}
+
+func (d *DualWriteGraph) Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (EdgeIterator, error) {
+	return d.primary.Edges(fromID, toID, updatedBefore)
+}
+
+func (d *DualWriteGraph) Stats() (*Stats, error) {
+	return d.primary.Stats()
+}
+
+//mirrorLink upserts a copy of a primary graph link into the secondary graph and
+//records the ID assigned to it
+func (d *DualWriteGraph) mirrorLink(link *Link) error {
+	mirrored := *link
+	mirrored.ID = uuid.Nil
+	if err := d.secondary.UpsertLink(&mirrored); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.secondaryID[link.ID] = mirrored.ID
+	d.mu.Unlock()
+	return nil
+}
+
+//mapLinkID returns the secondary graph ID of the link with the specified
+//primary graph ID, copying the link over if needed
+func (d *DualWriteGraph) mapLinkID(primaryID uuid.UUID) (uuid.UUID, error) {
+	d.mu.RLock()
+	id, found := d.secondaryID[primaryID]
+	d.mu.RUnlock()
+	if found {
+		return id, nil
+	}
+
+	link, err := d.primary.FindLink(primaryID)
+	if err != nil {
+		return uuid.Nil, xerrors.Errorf("mirror link %s: %w", primaryID, err)
+	}
+	if err = d.mirrorLink(link); err != nil {
+		return uuid.Nil, err
+	}
+	return d.mapLinkID(primaryID)
+}
+
+func (d *DualWriteGraph) recordSecondaryErr(err error) {
+	if err == nil {
+		return
+	}
+
+	d.mu.Lock()
+	d.errCount++
+	d.lastErr = err
+	d.mu.Unlock()
+}
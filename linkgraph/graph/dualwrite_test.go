@@ -0,0 +1,91 @@
+package graph_test
+
+import (
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(DualWriteTestSuite))
+
+type DualWriteTestSuite struct{}
+
+func (s *DualWriteTestSuite) TestMirroredWrites(c *gc.C) {
+	primary, secondary := memory.NewInMemoryGraph(), memory.NewInMemoryGraph()
+	dw := graph.DualWriter(primary, secondary)
+
+	src := &graph.Link{URL: "https://example.com"}
+	dst := &graph.Link{URL: "https://example.com/about"}
+	c.Assert(dw.UpsertLink(src), gc.IsNil)
+	c.Assert(dw.UpsertLink(dst), gc.IsNil)
+	c.Assert(dw.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+
+	report, err := graph.Diff(primary, secondary)
+	c.Assert(err, gc.IsNil)
+	c.Assert(report.Consistent(), gc.Equals, true, gc.Commentf("%+v", report))
+
+	count, lastErr := dw.SecondaryErrors()
+	c.Assert(count, gc.Equals, uint64(0))
+	c.Assert(lastErr, gc.IsNil)
+}
+
+func (s *DualWriteTestSuite) TestEdgeBetweenPreexistingLinks(c *gc.C) {
+	primary, secondary := memory.NewInMemoryGraph(), memory.NewInMemoryGraph()
+
+	// Links created before the dual writer was put in place.
+	src := &graph.Link{URL: "https://example.com"}
+	dst := &graph.Link{URL: "https://example.com/about"}
+	c.Assert(primary.UpsertLink(src), gc.IsNil)
+	c.Assert(primary.UpsertLink(dst), gc.IsNil)
+
+	dw := graph.DualWriter(primary, secondary)
+	c.Assert(dw.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+
+	report, err := graph.Diff(primary, secondary)
+	c.Assert(err, gc.IsNil)
+	c.Assert(report.Consistent(), gc.Equals, true, gc.Commentf("%+v", report))
+}
+
+func (s *DualWriteTestSuite) TestRemoveStaleEdges(c *gc.C) {
+	primary, secondary := memory.NewInMemoryGraph(), memory.NewInMemoryGraph()
+	dw := graph.DualWriter(primary, secondary)
+
+	src := &graph.Link{URL: "https://example.com"}
+	dst := &graph.Link{URL: "https://example.com/about"}
+	c.Assert(dw.UpsertLink(src), gc.IsNil)
+	c.Assert(dw.UpsertLink(dst), gc.IsNil)
+	c.Assert(dw.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+
+	c.Assert(dw.RemoveStaleEdges(src.ID, time.Now().Add(time.Minute)), gc.IsNil)
+
+	for _, g := range []graph.Graph{primary, secondary} {
+		stats, err := g.Stats()
+		c.Assert(err, gc.IsNil)
+		c.Assert(stats.Edges, gc.Equals, uint64(0))
+	}
+}
+
+func (s *DualWriteTestSuite) TestDiff(c *gc.C) {
+	a, b := memory.NewInMemoryGraph(), memory.NewInMemoryGraph()
+
+	shared := &graph.Link{URL: "https://example.com"}
+	onlyA := &graph.Link{URL: "https://example.com/a"}
+	c.Assert(a.UpsertLink(shared), gc.IsNil)
+	c.Assert(a.UpsertLink(onlyA), gc.IsNil)
+	c.Assert(a.UpsertEdge(&graph.Edge{Src: shared.ID, Dst: onlyA.ID}), gc.IsNil)
+
+	sharedB := &graph.Link{URL: "https://example.com"}
+	onlyB := &graph.Link{URL: "https://example.com/b"}
+	c.Assert(b.UpsertLink(sharedB), gc.IsNil)
+	c.Assert(b.UpsertLink(onlyB), gc.IsNil)
+
+	report, err := graph.Diff(a, b)
+	c.Assert(err, gc.IsNil)
+	c.Assert(report.Consistent(), gc.Equals, false)
+	c.Assert(report.MissingLinks, gc.DeepEquals, []string{"https://example.com/a"})
+	c.Assert(report.MissingEdges, gc.DeepEquals, []string{"https://example.com -> https://example.com/a"})
+	c.Assert(report.ExtraLinks, gc.DeepEquals, []string{"https://example.com/b"})
+	c.Assert(report.ExtraEdges, gc.IsNil)
+}
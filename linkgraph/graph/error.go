@@ -2,11 +2,63 @@ package graph
 
 import "golang.org/x/xerrors"
 
+var (
+	//ErrConflict is the class of errors caused by a conflicting concurrent update
+	ErrConflict = xerrors.New("conflict")
+
+	//ErrUnavailable is the class of transient errors caused by a backend that
+	//cannot be reached; operations failing with it can be retried
+	ErrUnavailable = xerrors.New("unavailable")
+
+	//ErrInvalidArgument is the class of errors caused by invalid input; retrying
+	//such operations will not succeed
+	ErrInvalidArgument = xerrors.New("invalid argument")
+)
+
 var (
 	//ErrNotFound is returned when a link or edge lookup fails
 	ErrNotFound = xerrors.New("not found")
 
 	//ErrUnknownEdgeLinks is returned when attempting to create an edge with
 	//an invalid source/destination ID
-	ErrUnknownEdgeLinks = xerrors.New("unknown source and/or destination for edge")
+	ErrUnknownEdgeLinks = InvalidArgument(xerrors.New("unknown source and/or destination for edge"))
+
+	//ErrTxDone is returned when using a transaction that has already been
+	//committed or rolled back
+	ErrTxDone = xerrors.New("transaction has already been committed or rolled back")
 )
+
+//classifiedError attaches an error class to an error without altering its
+//message.  It matches both the wrapped error and its class
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+func (e *classifiedError) Unwrap() error { return e.err }
+func (e *classifiedError) Is(target error) bool {
+	return target == e.class
+}
+
+//Conflict marks err as an ErrConflict error
+func Conflict(err error) error { return classify(ErrConflict, err) }
+
+//Unavailable marks err as an ErrUnavailable error
+func Unavailable(err error) error { return classify(ErrUnavailable, err) }
+
+//InvalidArgument marks err as an ErrInvalidArgument error
+func InvalidArgument(err error) error { return classify(ErrInvalidArgument, err) }
+
+func classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+//IsRetryable returns true if err belongs to an error class that indicates a
+//transient failure
+func IsRetryable(err error) bool {
+	return xerrors.Is(err, ErrUnavailable)
+}
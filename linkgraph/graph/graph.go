@@ -8,17 +8,158 @@ import (
 
 /*Graph will be used by objects to perform crawler operations*/
 type Graph interface {
-	UpsertLink(link *Link) error
-	FindLink(id uuid.UUID) (*Link, error)
+	ReadOnlyGraph
 
+	UpsertLink(link *Link) error
 	UpsertEdge(edge *Edge) error
 	RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error
+	/*RemoveStaleEdgesInRange removes the edges updated before updatedBefore whose Src
+	link has a UUID within the [fromID, toID) range.  It allows a cleanup pass to prune
+	an entire partition at once*/
+	RemoveStaleEdgesInRange(fromID, toID uuid.UUID, updatedBefore time.Time) error
+}
+
+/*ReadOnlyGraph is the subset of the Graph methods that do not mutate the graph.
+Services that only consume the graph (e.g. PageRank calculation or the
+frontend) should depend on this interface so that any attempt to mutate the
+store is caught at compile time*/
+type ReadOnlyGraph interface {
+	FindLink(id uuid.UUID) (*Link, error)
+	/*FindLinkByURL looks up a link by its URL.  Both rawURL and the URLs of
+	the stored links are compared in their normalized form (see
+	NormalizeURL)*/
+	FindLinkByURL(rawURL string) (*Link, error)
+	/*FindLinksByURL looks up the links for a batch of URLs.  The returned
+	slice has an entry for each URL in rawURLs; the entries for URLs that are
+	not known to the graph are nil*/
+	FindLinksByURL(rawURLs []string) ([]*Link, error)
+	/*FindEdge looks up the edge from src to dst*/
+	FindEdge(src, dst uuid.UUID) (*Edge, error)
 
 	/*Returns a set of links whose ID is within the (fromID, toID) range. Eventually
 	we want to partition links and edges into non-overlapping regions to be processed in parallel */
 	Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (LinkIterator, error)
 	/*Returns a set of edges that have a Src Link with a UUID within the (fromID, toID) range*/
 	Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (EdgeIterator, error)
+
+	/*Stats returns the number of links and edges in the graph*/
+	Stats() (*Stats, error)
+}
+
+/*Stats summarizes the size of a graph*/
+type Stats struct {
+	Links uint64
+	Edges uint64
+}
+
+/*MemoryStats estimates the number of bytes used by a graph store that keeps its
+data in memory, broken down by the links, the edges, the indices used for
+looking them up (e.g. by URL or source link) and the crawl pass history*/
+type MemoryStats struct {
+	Links         uint64
+	Edges         uint64
+	Indices       uint64
+	CrawlPassLogs uint64
+}
+
+/*Total returns the total number of bytes*/
+func (s MemoryStats) Total() uint64 {
+	return s.Links + s.Edges + s.Indices + s.CrawlPassLogs
+}
+
+/*MemoryReporter is implemented by graphs that keep their data in memory and can
+estimate their footprint so operators can provision capacity*/
+type MemoryReporter interface {
+	MemoryStats() (*MemoryStats, error)
+}
+
+/*Transactor is implemented by graphs that can group multiple mutations into a
+transaction.  Persistent backends map transactions to their native
+transaction support while the in-memory store emulates them*/
+type Transactor interface {
+	Begin() (Tx, error)
+}
+
+/*CrawlPassTracker is implemented by graphs that keep an audit trail of crawl
+passes.  NextCrawlPass allocates a crawl pass ID that is greater than any ID
+previously allocated by or upserted into the graph.  Links and edges record the
+ID of the last pass that touched them so operators can list what a particular
+pass retrieved or updated*/
+type CrawlPassTracker interface {
+	NextCrawlPass() (uint64, error)
+	/*LinksInCrawlPass returns the links that were last retrieved by the specified pass*/
+	LinksInCrawlPass(pass uint64) (LinkIterator, error)
+	/*EdgesInCrawlPass returns the edges that were last updated by the specified pass*/
+	EdgesInCrawlPass(pass uint64) (EdgeIterator, error)
+}
+
+/*CrawlPassDiffer is implemented by graphs that keep a history of the links and
+edges touched by each crawl pass.  Diff reports what changed between two
+passes, e.g. for monitoring how a site evolves over time.  It returns
+ErrNotFound if either pass is unknown*/
+type CrawlPassDiffer interface {
+	Diff(passA, passB uint64) (*PassDiff, error)
+}
+
+/*CrawlPassCheckpointer is implemented by graphs that can persist the progress of
+the current crawl pass so that a crawler that is restarted resumes the pass
+where it left off instead of crawling its partitions from the start.
+CrawlPassProgress returns ErrNotFound if no progress has been saved*/
+type CrawlPassCheckpointer interface {
+	SaveCrawlPassProgress(progress *PassProgress) error
+	CrawlPassProgress() (*PassProgress, error)
+}
+
+/*PassProgress is a checkpoint of a crawl pass over a set of link partitions*/
+type PassProgress struct {
+	PassID uint64
+
+	/*RetrievedBefore is the cutoff used for selecting the links to crawl*/
+	RetrievedBefore time.Time
+
+	Partitions []PartitionProgress
+}
+
+/*Done returns true if all partitions of the pass have been crawled*/
+func (p *PassProgress) Done() bool {
+	for _, part := range p.Partitions {
+		if !part.Done {
+			return false
+		}
+	}
+	return true
+}
+
+/*PartitionProgress records how far the links in [FromID, ToID) have been crawled*/
+type PartitionProgress struct {
+	FromID uuid.UUID
+	ToID   uuid.UUID
+
+	/*LastLinkID is the ID of the last link of the partition that was sent
+	through the crawler or the zero UUID if no link has been sent yet*/
+	LastLinkID uuid.UUID
+
+	/*Crawled is the number of links of the partition that were sent through
+	the crawler*/
+	Crawled int
+
+	/*Done is set once all links of the partition have been sent through the
+	crawler*/
+	Done bool
+}
+
+/*Tx is a set of graph mutations that are applied atomically.  Links and edges
+upserted within a transaction are assigned their IDs right away so that they
+can be referenced by subsequent operations in the same transaction, but none of
+the mutations are visible to other graph clients until Commit is called.  After
+a call to Commit or Rollback, all methods return ErrTxDone*/
+type Tx interface {
+	UpsertLink(link *Link) error
+	UpsertEdge(edge *Edge) error
+	RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error
+
+	Commit() error
+	Rollback() error
 }
 
 /*Link is a representation of a link object in our graph.  It has a URL and a timestamp for when it was
@@ -27,6 +168,30 @@ type Link struct {
 	ID          uuid.UUID
 	URL         string
 	RetrievedAt time.Time
+
+	/*Feed is set for links that point to an RSS/Atom feed.  Once a link has been
+	flagged as a feed, the flag is retained by subsequent upserts*/
+	Feed bool
+
+	/*RetryNotBefore is set when a server asks us to back off (e.g. via a
+	Retry-After header); the link should not be crawled before this time.
+	Upserts never move this timestamp backwards*/
+	RetryNotBefore time.Time
+
+	/*CrawlPassID is the ID of the last crawl pass that retrieved the link or
+	zero if the link has not been crawled yet.  Upserts never move it
+	backwards*/
+	CrawlPassID uint64
+
+	/*ContentHash is a hash of the content retrieved by the last crawl pass.
+	It allows detecting pages that changed between two passes.  Upserts
+	with an empty hash retain the existing one*/
+	ContentHash string
+
+	/*Priority controls the order in which links are crawled; links with a
+	higher priority are crawled first.  Upserts with a zero priority retain
+	the existing one*/
+	Priority int
 }
 
 /*Edge logically represents the connection of links.  The Src uuid is the uuid of
@@ -36,6 +201,28 @@ type Edge struct {
 	Src       uuid.UUID
 	Dst       uuid.UUID
 	UpdatedAt time.Time
+
+	/*CrawlPassID is the ID of the last crawl pass that upserted the edge.
+	Upserts never move it backwards*/
+	CrawlPassID uint64
+
+	/*Weight is an optional weight for the edge, e.g. the number of page
+	links aggregated into an edge of a host graph (see the hostgraph
+	package).  Upserts with a zero weight retain the existing one*/
+	Weight float64
+}
+
+//edgeIDNamespace is the UUIDv5 namespace used for deriving edge IDs
+var edgeIDNamespace = uuid.MustParse("3f0c7a52-5b1e-4c8e-9d4a-6e2b1f8c0d71")
+
+/*EdgeID returns the ID of the edge from src to dst.  Edge IDs are derived
+deterministically (UUIDv5) from the link IDs so that all stores assign the same
+ID to an edge, and retried or concurrent upserts can never create duplicates*/
+func EdgeID(src, dst uuid.UUID) uuid.UUID {
+	name := make([]byte, 0, 2*len(src))
+	name = append(name, src[:]...)
+	name = append(name, dst[:]...)
+	return uuid.NewSHA1(edgeIDNamespace, name)
 }
 
 /*LinkIterator is implemented by object that can iterate graph links.  Since there
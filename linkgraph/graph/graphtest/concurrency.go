@@ -0,0 +1,242 @@
+package graphtest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+// TestConcurrentLinkUpserts verifies that concurrent upserts of overlapping
+// link sets do not lose updates or assign more than one ID to the same URL.
+func (s *SuiteBase) TestConcurrentLinkUpserts(c *gc.C) {
+	var (
+		wg          sync.WaitGroup
+		numWriters  = 10
+		numShared   = 50
+		numUnique   = 20
+		assignedIDs = make([]map[string]uuid.UUID, numWriters)
+	)
+
+	wg.Add(numWriters)
+	for i := 0; i < numWriters; i++ {
+		go func(id int) {
+			defer wg.Done()
+
+			ids := make(map[string]uuid.UUID)
+			for j := 0; j < numShared; j++ {
+				link := &graph.Link{URL: fmt.Sprintf("shared-%d", j)}
+				c.Assert(s.g.UpsertLink(link), gc.IsNil, gc.Commentf("writer %d", id))
+				ids[link.URL] = link.ID
+			}
+			for j := 0; j < numUnique; j++ {
+				link := &graph.Link{URL: fmt.Sprintf("writer-%d-%d", id, j)}
+				c.Assert(s.g.UpsertLink(link), gc.IsNil, gc.Commentf("writer %d", id))
+				ids[link.URL] = link.ID
+			}
+			assignedIDs[id] = ids
+		}(i)
+	}
+	s.waitForWorkers(c, &wg)
+
+	// All writers must have been handed the same ID for each shared URL.
+	for j := 0; j < numShared; j++ {
+		url := fmt.Sprintf("shared-%d", j)
+		for i := 1; i < numWriters; i++ {
+			c.Assert(assignedIDs[i][url], gc.Equals, assignedIDs[0][url], gc.Commentf("writers 0 and %d got different IDs for %q", i, url))
+		}
+	}
+
+	// Each URL must be stored exactly once under the ID returned by upsert.
+	it, err := s.partitionedLinkIterator(c, 0, 1, time.Now())
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(it.Close(), gc.IsNil) }()
+
+	seenIDs := make(map[uuid.UUID]bool)
+	seenURLs := make(map[string]bool)
+	for it.Next() {
+		link := it.Link()
+		c.Assert(seenIDs[link.ID], gc.Equals, false, gc.Commentf("duplicate link ID %s", link.ID))
+		c.Assert(seenURLs[link.URL], gc.Equals, false, gc.Commentf("duplicate link URL %q", link.URL))
+		seenIDs[link.ID] = true
+		seenURLs[link.URL] = true
+
+		for i := 0; i < numWriters; i++ {
+			if id, found := assignedIDs[i][link.URL]; found {
+				c.Assert(link.ID, gc.Equals, id, gc.Commentf("link %q", link.URL))
+			}
+		}
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(seenURLs, gc.HasLen, numShared+numWriters*numUnique)
+}
+
+// TestConcurrentEdgeUpserts verifies that concurrent upserts of the same edges
+// do not create duplicate edges.
+func (s *SuiteBase) TestConcurrentEdgeUpserts(c *gc.C) {
+	var (
+		wg         sync.WaitGroup
+		numWriters = 10
+		numLinks   = 20
+		linkUUIDs  = make([]uuid.UUID, numLinks)
+	)
+
+	for i := 0; i < numLinks; i++ {
+		link := &graph.Link{URL: fmt.Sprint(i)}
+		c.Assert(s.g.UpsertLink(link), gc.IsNil)
+		linkUUIDs[i] = link.ID
+	}
+
+	// Each writer connects every link to its successor.
+	edgeIDs := make([][]uuid.UUID, numWriters)
+	wg.Add(numWriters)
+	for i := 0; i < numWriters; i++ {
+		go func(id int) {
+			defer wg.Done()
+
+			ids := make([]uuid.UUID, numLinks-1)
+			for j := 0; j < numLinks-1; j++ {
+				edge := &graph.Edge{Src: linkUUIDs[j], Dst: linkUUIDs[j+1]}
+				c.Assert(s.g.UpsertEdge(edge), gc.IsNil, gc.Commentf("writer %d", id))
+				ids[j] = edge.ID
+			}
+			edgeIDs[id] = ids
+		}(i)
+	}
+	s.waitForWorkers(c, &wg)
+
+	for i := 1; i < numWriters; i++ {
+		c.Assert(edgeIDs[i], gc.DeepEquals, edgeIDs[0], gc.Commentf("writers 0 and %d got different edge IDs", i))
+	}
+
+	it, err := s.partitionedEdgeIterator(c, 0, 1, time.Now())
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(it.Close(), gc.IsNil) }()
+
+	seen := make(map[[2]uuid.UUID]bool)
+	for it.Next() {
+		edge := it.Edge()
+		key := [2]uuid.UUID{edge.Src, edge.Dst}
+		c.Assert(seen[key], gc.Equals, false, gc.Commentf("duplicate edge %s -> %s", edge.Src, edge.Dst))
+		seen[key] = true
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(seen, gc.HasLen, numLinks-1)
+}
+
+// TestConcurrentMutationsAndIterators verifies that link and edge iterators
+// can be used while other clients are concurrently mutating the graph and
+// that each iterator yields a consistent view of the graph.
+func (s *SuiteBase) TestConcurrentMutationsAndIterators(c *gc.C) {
+	var (
+		wg         sync.WaitGroup
+		numWriters = 4
+		numReaders = 4
+		numRounds  = 25
+		srcLink    = &graph.Link{URL: "src"}
+	)
+	c.Assert(s.g.UpsertLink(srcLink), gc.IsNil)
+
+	wg.Add(numWriters + numReaders)
+	for i := 0; i < numWriters; i++ {
+		go func(id int) {
+			defer wg.Done()
+
+			for round := 0; round < numRounds; round++ {
+				tag := gc.Commentf("writer %d, round %d", id, round)
+				dst := &graph.Link{URL: fmt.Sprintf("dst-%d-%d", id, round), RetrievedAt: time.Now()}
+				c.Assert(s.g.UpsertLink(dst), gc.IsNil, tag)
+				c.Assert(s.g.UpsertEdge(&graph.Edge{Src: srcLink.ID, Dst: dst.ID}), gc.IsNil, tag)
+				c.Assert(s.g.UpsertEdge(&graph.Edge{Src: dst.ID, Dst: srcLink.ID}), gc.IsNil, tag)
+				if round%5 == 4 {
+					c.Assert(s.g.RemoveStaleEdges(dst.ID, time.Now().Add(-time.Hour)), gc.IsNil, tag)
+				}
+			}
+		}(i)
+	}
+
+	for i := 0; i < numReaders; i++ {
+		go func(id int) {
+			defer wg.Done()
+
+			for round := 0; round < numRounds; round++ {
+				tag := gc.Commentf("reader %d, round %d", id, round)
+
+				linkIt, err := s.partitionedLinkIterator(c, 0, 1, time.Now().Add(time.Minute))
+				c.Assert(err, gc.IsNil, tag)
+				seenLinks := make(map[uuid.UUID]bool)
+				for linkIt.Next() {
+					link := linkIt.Link()
+					c.Assert(seenLinks[link.ID], gc.Equals, false, tag)
+					seenLinks[link.ID] = true
+				}
+				c.Assert(linkIt.Error(), gc.IsNil, tag)
+				c.Assert(linkIt.Close(), gc.IsNil, tag)
+
+				edgeIt, err := s.partitionedEdgeIterator(c, 0, 1, time.Now().Add(time.Minute))
+				c.Assert(err, gc.IsNil, tag)
+				seenEdges := make(map[uuid.UUID]bool)
+				for edgeIt.Next() {
+					edge := edgeIt.Edge()
+					c.Assert(seenEdges[edge.ID], gc.Equals, false, tag)
+					seenEdges[edge.ID] = true
+				}
+				c.Assert(edgeIt.Error(), gc.IsNil, tag)
+				c.Assert(edgeIt.Close(), gc.IsNil, tag)
+			}
+		}(i)
+	}
+	s.waitForWorkers(c, &wg)
+
+	// All links and edges created by the writers must be present once the
+	// writers are done; the RemoveStaleEdges calls only target edges that
+	// were updated more than an hour ago.
+	numDst := numWriters * numRounds
+	c.Assert(s.countLinks(c), gc.Equals, numDst+1)
+	c.Assert(s.countEdges(c), gc.Equals, numDst*2)
+}
+
+func (s *SuiteBase) countLinks(c *gc.C) int {
+	it, err := s.partitionedLinkIterator(c, 0, 1, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(it.Close(), gc.IsNil) }()
+
+	var count int
+	for it.Next() {
+		count++
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	return count
+}
+
+func (s *SuiteBase) countEdges(c *gc.C) int {
+	it, err := s.partitionedEdgeIterator(c, 0, 1, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(it.Close(), gc.IsNil) }()
+
+	var count int
+	for it.Next() {
+		count++
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	return count
+}
+
+// waitForWorkers blocks until wg is done or fails the test if the workers
+// do not complete in a timely fashion.
+func (s *SuiteBase) waitForWorkers(c *gc.C, wg *sync.WaitGroup) {
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+	case <-time.After(10 * time.Second):
+		c.Fatal("timed out waiting for test to complete")
+	}
+}
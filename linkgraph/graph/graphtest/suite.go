@@ -76,6 +76,66 @@ func (s *SuiteBase) TestUpsertLink(c *gc.C) {
 	c.Assert(dup.ID, gc.Not(gc.Equals), uuid.Nil, gc.Commentf("expected a linkID to be assigned to the new link"))
 }
 
+// TestUpsertFeedLink verifies that the feed flag of a link is retained when the
+// link is upserted again without it.
+func (s *SuiteBase) TestUpsertFeedLink(c *gc.C) {
+	feed := &graph.Link{
+		URL:  "https://example.com/feed.xml",
+		Feed: true,
+	}
+	c.Assert(s.g.UpsertLink(feed), gc.IsNil)
+
+	// Upsert the same URL as a plain link (e.g. it also appears in an <a> tag)
+	plain := &graph.Link{URL: feed.URL}
+	c.Assert(s.g.UpsertLink(plain), gc.IsNil)
+	c.Assert(plain.ID, gc.Equals, feed.ID)
+
+	stored, err := s.g.FindLink(feed.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.Feed, gc.Equals, true, gc.Commentf("feed flag was not retained"))
+}
+
+// TestUpsertLinkRetryNotBefore verifies that upserting a link never moves its
+// retry timestamp backwards.
+func (s *SuiteBase) TestUpsertLinkRetryNotBefore(c *gc.C) {
+	retryAt := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	parked := &graph.Link{
+		URL:            "https://example.com/busy",
+		RetryNotBefore: retryAt,
+	}
+	c.Assert(s.g.UpsertLink(parked), gc.IsNil)
+
+	// Upserting the link without a retry timestamp should retain the original one
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: parked.URL}), gc.IsNil)
+	stored, err := s.g.FindLink(parked.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.RetryNotBefore, gc.Equals, retryAt)
+
+	// Upserting the link with a later retry timestamp should update it
+	later := retryAt.Add(time.Hour)
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: parked.URL, RetryNotBefore: later}), gc.IsNil)
+	stored, err = s.g.FindLink(parked.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.RetryNotBefore, gc.Equals, later)
+}
+
+// TestUpsertLinkPriority verifies that link priorities are retained by upserts
+// that do not specify one.
+func (s *SuiteBase) TestUpsertLinkPriority(c *gc.C) {
+	link := &graph.Link{URL: "https://example.com/tag/go", Priority: -10}
+	c.Assert(s.g.UpsertLink(link), gc.IsNil)
+
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: link.URL}), gc.IsNil)
+	stored, err := s.g.FindLink(link.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.Priority, gc.Equals, -10)
+
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: link.URL, Priority: 5}), gc.IsNil)
+	stored, err = s.g.FindLink(link.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.Priority, gc.Equals, 5)
+}
+
 // TestFindLink verifies the link lookup logic.
 func (s *SuiteBase) TestFindLink(c *gc.C) {
 	// Create a new link
@@ -98,6 +158,63 @@ func (s *SuiteBase) TestFindLink(c *gc.C) {
 	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
 }
 
+// TestFindLinkByURL verifies that links can be looked up by their normalized URL.
+func (s *SuiteBase) TestFindLinkByURL(c *gc.C) {
+	link := &graph.Link{URL: "https://Example.com:443/gophers?page=2"}
+	c.Assert(s.g.UpsertLink(link), gc.IsNil)
+
+	for _, u := range []string{
+		"https://Example.com:443/gophers?page=2",
+		"HTTPS://example.com./gophers?page=2#burrows",
+		"https://example.com/gophers?page=2",
+	} {
+		other, err := s.g.FindLinkByURL(u)
+		c.Assert(err, gc.IsNil, gc.Commentf("url %s", u))
+		c.Assert(other.ID, gc.Equals, link.ID, gc.Commentf("url %s", u))
+	}
+
+	// Only the normalized form is matched; paths and queries are compared as-is
+	for _, u := range []string{
+		"https://example.com/Gophers?page=2",
+		"https://example.com/gophers",
+		"http://example.com/gophers?page=2",
+	} {
+		_, err := s.g.FindLinkByURL(u)
+		c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true, gc.Commentf("url %s", u))
+	}
+
+	_, err := s.g.FindLinkByURL("not a url")
+	c.Assert(xerrors.Is(err, graph.ErrInvalidArgument), gc.Equals, true)
+}
+
+// TestFindLinksByURL verifies that links can be looked up by URL in batches.
+func (s *SuiteBase) TestFindLinksByURL(c *gc.C) {
+	gophers := &graph.Link{URL: "https://example.com/gophers"}
+	moles := &graph.Link{URL: "https://example.com/moles"}
+	c.Assert(s.g.UpsertLink(gophers), gc.IsNil)
+	c.Assert(s.g.UpsertLink(moles), gc.IsNil)
+
+	links, err := s.g.FindLinksByURL([]string{
+		"https://example.com/moles",
+		"https://example.com/unknown",
+		"HTTPS://example.com./gophers#burrows",
+		"https://example.com/moles",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(links, gc.HasLen, 4)
+	c.Assert(links[0].ID, gc.Equals, moles.ID)
+	c.Assert(links[1], gc.IsNil)
+	c.Assert(links[2].ID, gc.Equals, gophers.ID)
+	c.Assert(links[3].ID, gc.Equals, moles.ID)
+
+	links, err = s.g.FindLinksByURL(nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(links, gc.HasLen, 0)
+
+	_, err = s.g.FindLinksByURL([]string{"https://example.com/gophers", "not a url"})
+	c.Assert(xerrors.Is(err, graph.ErrInvalidArgument), gc.Equals, true)
+}
+
 // TestConcurrentLinkIterators verifies that multiple clients can concurrently
 // access the store.
 func (s *SuiteBase) TestConcurrentLinkIterators(c *gc.C) {
@@ -264,6 +381,71 @@ func (s *SuiteBase) TestUpsertEdge(c *gc.C) {
 	c.Assert(xerrors.Is(err, graph.ErrUnknownEdgeLinks), gc.Equals, true)
 }
 
+// TestUpsertEdgeWeight verifies that edge weights are retained by upserts that
+// do not specify one.
+func (s *SuiteBase) TestUpsertEdgeWeight(c *gc.C) {
+	src := &graph.Link{URL: "src"}
+	c.Assert(s.g.UpsertLink(src), gc.IsNil)
+	dst := &graph.Link{URL: "dst"}
+	c.Assert(s.g.UpsertLink(dst), gc.IsNil)
+
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID, Weight: 3}), gc.IsNil)
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+	stored, err := s.g.FindEdge(src.ID, dst.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.Weight, gc.Equals, 3.0)
+
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID, Weight: 1.5}), gc.IsNil)
+	stored, err = s.g.FindEdge(src.ID, dst.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.Weight, gc.Equals, 1.5)
+}
+
+// TestFindEdge verifies the edge lookup logic.
+func (s *SuiteBase) TestFindEdge(c *gc.C) {
+	src := &graph.Link{URL: "src"}
+	c.Assert(s.g.UpsertLink(src), gc.IsNil)
+	dst := &graph.Link{URL: "dst"}
+	c.Assert(s.g.UpsertLink(dst), gc.IsNil)
+
+	edge := &graph.Edge{Src: src.ID, Dst: dst.ID}
+	c.Assert(s.g.UpsertEdge(edge), gc.IsNil)
+
+	found, err := s.g.FindEdge(src.ID, dst.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.ID, gc.Equals, edge.ID)
+	c.Assert(found.Src, gc.Equals, src.ID)
+	c.Assert(found.Dst, gc.Equals, dst.ID)
+
+	// Edges are directed
+	_, err = s.g.FindEdge(dst.ID, src.ID)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+}
+
+// TestDeterministicEdgeIDs verifies that edge IDs are derived from the IDs of
+// the links they connect.
+func (s *SuiteBase) TestDeterministicEdgeIDs(c *gc.C) {
+	src := &graph.Link{URL: "src"}
+	c.Assert(s.g.UpsertLink(src), gc.IsNil)
+	dst := &graph.Link{URL: "dst"}
+	c.Assert(s.g.UpsertLink(dst), gc.IsNil)
+
+	// Upserting an edge that specifies a different ID must not create a
+	// duplicate edge
+	edge := &graph.Edge{ID: uuid.New(), Src: src.ID, Dst: dst.ID}
+	c.Assert(s.g.UpsertEdge(edge), gc.IsNil)
+	c.Assert(edge.ID, gc.Equals, graph.EdgeID(src.ID, dst.ID))
+
+	retry := &graph.Edge{Src: src.ID, Dst: dst.ID}
+	c.Assert(s.g.UpsertEdge(retry), gc.IsNil)
+	c.Assert(retry.ID, gc.Equals, edge.ID)
+	c.Assert(s.iteratePartitionedEdges(c, 1), gc.Equals, 1)
+
+	reverse := &graph.Edge{Src: dst.ID, Dst: src.ID}
+	c.Assert(s.g.UpsertEdge(reverse), gc.IsNil)
+	c.Assert(reverse.ID, gc.Not(gc.Equals), edge.ID)
+}
+
 // TestConcurrentEdgeIterators verifies that multiple clients can concurrently
 // access the store.
 func (s *SuiteBase) TestConcurrentEdgeIterators(c *gc.C) {
@@ -478,6 +660,47 @@ func (s *SuiteBase) TestRemoveStaleEdges(c *gc.C) {
 	c.Assert(seen, gc.Equals, numEdges)
 }
 
+// TestRemoveStaleEdgesInRange verifies that stale edges are only removed from
+// links that belong to the specified partition.
+func (s *SuiteBase) TestRemoveStaleEdgesInRange(c *gc.C) {
+	numLinks := 50
+	linkUUIDs := make([]uuid.UUID, numLinks)
+	for i := 0; i < numLinks; i++ {
+		link := &graph.Link{URL: fmt.Sprint(i)}
+		c.Assert(s.g.UpsertLink(link), gc.IsNil)
+		linkUUIDs[i] = link.ID
+	}
+
+	// Connect each link to its successor
+	for i := 0; i < numLinks; i++ {
+		c.Assert(s.g.UpsertEdge(&graph.Edge{
+			Src: linkUUIDs[i],
+			Dst: linkUUIDs[(i+1)%numLinks],
+		}), gc.IsNil)
+	}
+	time.Sleep(100 * time.Millisecond)
+	deleteBefore := time.Now()
+
+	from, to := s.partitionRange(c, 0, 2)
+	c.Assert(s.g.RemoveStaleEdgesInRange(from, to, deleteBefore), gc.IsNil)
+
+	// Edges originating from the first partition should be gone
+	it, err := s.partitionedEdgeIterator(c, 0, 2, time.Now())
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.Next(), gc.Equals, false)
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(it.Close(), gc.IsNil)
+
+	// while the edges of the second partition should be left intact
+	var expCount int
+	for _, id := range linkUUIDs {
+		if id.String() >= to.String() {
+			expCount++
+		}
+	}
+	c.Assert(s.iteratePartitionedEdges(c, 2), gc.Equals, expCount)
+}
+
 func (s *SuiteBase) partitionedLinkIterator(c *gc.C, partition, numPartitions int, accessedBefore time.Time) (graph.LinkIterator, error) {
 	from, to := s.partitionRange(c, partition, numPartitions)
 	return s.g.Links(from, to, accessedBefore)
@@ -526,3 +749,306 @@ func (s *SuiteBase) partitionRange(c *gc.C, partition, numPartitions int) (from,
 
 	return from, to
 }
+
+// TestTransactions verifies that mutations performed within a transaction are
+// only visible after the transaction is committed. The test is skipped for
+// graphs that do not implement graph.Transactor.
+func (s *SuiteBase) TestTransactions(c *gc.C) {
+	txGraph, ok := s.g.(graph.Transactor)
+	if !ok {
+		c.Skip("graph does not support transactions")
+	}
+
+	existing := &graph.Link{URL: "existing"}
+	c.Assert(s.g.UpsertLink(existing), gc.IsNil)
+
+	tx, err := txGraph.Begin()
+	c.Assert(err, gc.IsNil)
+	src := &graph.Link{URL: "src"}
+	c.Assert(tx.UpsertLink(src), gc.IsNil)
+	c.Assert(src.ID, gc.Not(gc.Equals), uuid.Nil, gc.Commentf("expected a linkID to be assigned to the new link"))
+	dup := &graph.Link{URL: "existing"}
+	c.Assert(tx.UpsertLink(dup), gc.IsNil)
+	c.Assert(dup.ID, gc.Equals, existing.ID)
+	edge := &graph.Edge{Src: src.ID, Dst: existing.ID}
+	c.Assert(tx.UpsertEdge(edge), gc.IsNil)
+
+	err = tx.UpsertEdge(&graph.Edge{Src: src.ID, Dst: uuid.New()})
+	c.Assert(xerrors.Is(err, graph.ErrUnknownEdgeLinks), gc.Equals, true)
+
+	// Nothing should be visible before the transaction is committed
+	_, err = s.g.FindLink(src.ID)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+
+	c.Assert(tx.Commit(), gc.IsNil)
+	found, err := s.g.FindLink(src.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.URL, gc.Equals, "src")
+	c.Assert(edge.ID, gc.Not(gc.Equals), uuid.Nil, gc.Commentf("expected an edgeID to be assigned to the new edge"))
+
+	it, err := s.partitionedEdgeIterator(c, 0, 1, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.Next(), gc.Equals, true)
+	c.Assert(it.Edge().ID, gc.Equals, edge.ID)
+	c.Assert(it.Next(), gc.Equals, false)
+	c.Assert(it.Close(), gc.IsNil)
+
+	// Committed transactions cannot be reused
+	err = tx.UpsertLink(&graph.Link{URL: "too-late"})
+	c.Assert(xerrors.Is(err, graph.ErrTxDone), gc.Equals, true)
+	c.Assert(xerrors.Is(tx.Rollback(), graph.ErrTxDone), gc.Equals, true)
+}
+
+// TestTransactionRollback verifies that rolled back transactions leave the
+// graph untouched.
+func (s *SuiteBase) TestTransactionRollback(c *gc.C) {
+	txGraph, ok := s.g.(graph.Transactor)
+	if !ok {
+		c.Skip("graph does not support transactions")
+	}
+
+	src := &graph.Link{URL: "src"}
+	c.Assert(s.g.UpsertLink(src), gc.IsNil)
+	dst := &graph.Link{URL: "dst"}
+	c.Assert(s.g.UpsertLink(dst), gc.IsNil)
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+
+	tx, err := txGraph.Begin()
+	c.Assert(err, gc.IsNil)
+	newLink := &graph.Link{URL: "new"}
+	c.Assert(tx.UpsertLink(newLink), gc.IsNil)
+	c.Assert(tx.UpsertEdge(&graph.Edge{Src: src.ID, Dst: newLink.ID}), gc.IsNil)
+	c.Assert(tx.RemoveStaleEdges(src.ID, time.Now().Add(time.Minute)), gc.IsNil)
+	c.Assert(tx.Rollback(), gc.IsNil)
+
+	_, err = s.g.FindLink(newLink.ID)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+	c.Assert(s.iteratePartitionedEdges(c, 1), gc.Equals, 1)
+	c.Assert(xerrors.Is(tx.Commit(), graph.ErrTxDone), gc.Equals, true)
+}
+
+// TestStats verifies that the graph reports the number of links and edges.
+func (s *SuiteBase) TestStats(c *gc.C) {
+	stats, err := s.g.Stats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(*stats, gc.Equals, graph.Stats{})
+
+	linkUUIDs := make([]uuid.UUID, 5)
+	for i := range linkUUIDs {
+		link := &graph.Link{URL: fmt.Sprint(i)}
+		c.Assert(s.g.UpsertLink(link), gc.IsNil)
+		linkUUIDs[i] = link.ID
+	}
+	for i := 1; i < len(linkUUIDs); i++ {
+		c.Assert(s.g.UpsertEdge(&graph.Edge{Src: linkUUIDs[0], Dst: linkUUIDs[i]}), gc.IsNil)
+	}
+	// Duplicate upserts must not affect the counts
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: "0"}), gc.IsNil)
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: linkUUIDs[0], Dst: linkUUIDs[1]}), gc.IsNil)
+
+	stats, err = s.g.Stats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(*stats, gc.Equals, graph.Stats{Links: 5, Edges: 4})
+}
+
+// TestReadOnlyView verifies that a read-only view exposes the contents of the
+// graph but cannot be converted back to a mutable graph.
+func (s *SuiteBase) TestReadOnlyView(c *gc.C) {
+	src := &graph.Link{URL: "src"}
+	c.Assert(s.g.UpsertLink(src), gc.IsNil)
+	dst := &graph.Link{URL: "dst"}
+	c.Assert(s.g.UpsertLink(dst), gc.IsNil)
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+
+	view := graph.ReadOnly(s.g)
+	_, mutable := view.(graph.Graph)
+	c.Assert(mutable, gc.Equals, false)
+	c.Assert(graph.ReadOnly(view), gc.Equals, view)
+
+	link, err := view.FindLink(src.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(link.URL, gc.Equals, "src")
+
+	_, err = view.FindEdge(src.ID, dst.ID)
+	c.Assert(err, gc.IsNil)
+
+	stats, err := view.Stats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(*stats, gc.Equals, graph.Stats{Links: 2, Edges: 1})
+
+	from, to := s.partitionRange(c, 0, 1)
+	it, err := view.Edges(from, to, time.Now().Add(time.Minute))
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.Next(), gc.Equals, true)
+	c.Assert(it.Close(), gc.IsNil)
+}
+
+// TestCrawlPassAudit verifies that links and edges record the crawl pass that
+// last touched them. The test is skipped for graphs that do not implement
+// graph.CrawlPassTracker.
+func (s *SuiteBase) TestCrawlPassAudit(c *gc.C) {
+	tracker, ok := s.g.(graph.CrawlPassTracker)
+	if !ok {
+		c.Skip("graph does not track crawl passes")
+	}
+
+	pass1, err := tracker.NextCrawlPass()
+	c.Assert(err, gc.IsNil)
+	pass2, err := tracker.NextCrawlPass()
+	c.Assert(err, gc.IsNil)
+	c.Assert(pass2 > pass1, gc.Equals, true, gc.Commentf("expected crawl pass IDs to increase"))
+
+	src := &graph.Link{URL: "src", CrawlPassID: pass1}
+	c.Assert(s.g.UpsertLink(src), gc.IsNil)
+	dst := &graph.Link{URL: "dst", CrawlPassID: pass1}
+	c.Assert(s.g.UpsertLink(dst), gc.IsNil)
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID, CrawlPassID: pass1}), gc.IsNil)
+
+	// Re-crawl src in the second pass; an upsert from an older pass must
+	// not move the pass ID backwards.
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: "src", CrawlPassID: pass2}), gc.IsNil)
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID, CrawlPassID: pass2}), gc.IsNil)
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: "src", CrawlPassID: pass1}), gc.IsNil)
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: "new"}), gc.IsNil)
+
+	found, err := s.g.FindLink(src.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.CrawlPassID, gc.Equals, pass2)
+
+	linkIt, err := tracker.LinksInCrawlPass(pass1)
+	c.Assert(err, gc.IsNil)
+	c.Assert(linkIt.Next(), gc.Equals, true)
+	c.Assert(linkIt.Link().ID, gc.Equals, dst.ID)
+	c.Assert(linkIt.Next(), gc.Equals, false)
+	c.Assert(linkIt.Close(), gc.IsNil)
+
+	linkIt, err = tracker.LinksInCrawlPass(pass2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(linkIt.Next(), gc.Equals, true)
+	c.Assert(linkIt.Link().ID, gc.Equals, src.ID)
+	c.Assert(linkIt.Next(), gc.Equals, false)
+	c.Assert(linkIt.Close(), gc.IsNil)
+
+	edgeIt, err := tracker.EdgesInCrawlPass(pass1)
+	c.Assert(err, gc.IsNil)
+	c.Assert(edgeIt.Next(), gc.Equals, false)
+	c.Assert(edgeIt.Close(), gc.IsNil)
+
+	edgeIt, err = tracker.EdgesInCrawlPass(pass2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(edgeIt.Next(), gc.Equals, true)
+	c.Assert(edgeIt.Edge().Src, gc.Equals, src.ID)
+	c.Assert(edgeIt.Next(), gc.Equals, false)
+	c.Assert(edgeIt.Close(), gc.IsNil)
+
+	// Pass IDs recorded by upserts are never allocated again.
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: "imported", CrawlPassID: pass2 + 10}), gc.IsNil)
+	next, err := tracker.NextCrawlPass()
+	c.Assert(err, gc.IsNil)
+	c.Assert(next > pass2+10, gc.Equals, true)
+}
+
+// TestCrawlPassDiff verifies that the differences between two crawl passes are
+// reported. The test is skipped for graphs that do not implement
+// graph.CrawlPassDiffer.
+func (s *SuiteBase) TestCrawlPassDiff(c *gc.C) {
+	differ, ok := s.g.(graph.CrawlPassDiffer)
+	if !ok {
+		c.Skip("graph does not support crawl pass diffs")
+	}
+	tracker, ok := s.g.(graph.CrawlPassTracker)
+	if !ok {
+		c.Skip("graph does not track crawl passes")
+	}
+
+	crawl := func(pass uint64, url, hash string, dstURLs ...string) {
+		src := &graph.Link{URL: url, CrawlPassID: pass, ContentHash: hash}
+		c.Assert(s.g.UpsertLink(src), gc.IsNil)
+		for _, dstURL := range dstURLs {
+			dst := &graph.Link{URL: dstURL}
+			c.Assert(s.g.UpsertLink(dst), gc.IsNil)
+			c.Assert(s.g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID, CrawlPassID: pass}), gc.IsNil)
+		}
+	}
+
+	pass1, err := tracker.NextCrawlPass()
+	c.Assert(err, gc.IsNil)
+	crawl(pass1, "a", "hash-1", "b", "c")
+	crawl(pass1, "b", "hash-b")
+	crawl(pass1, "c", "hash-c")
+
+	pass2, err := tracker.NextCrawlPass()
+	c.Assert(err, gc.IsNil)
+	crawl(pass2, "a", "hash-2", "b", "d")
+	crawl(pass2, "c", "hash-c")
+	crawl(pass2, "d", "hash-d")
+
+	diff, err := differ.Diff(pass1, pass2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff, gc.DeepEquals, &graph.PassDiff{
+		PassA:            pass1,
+		PassB:            pass2,
+		NewLinks:         []string{"d"},
+		RemovedLinks:     []string{"b"},
+		NewEdges:         []string{"a -> d"},
+		RemovedEdges:     []string{"a -> c"},
+		ChangedDocuments: []string{"a"},
+	})
+
+	diff, err = differ.Diff(pass2, pass2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(diff.Empty(), gc.Equals, true)
+
+	_, err = differ.Diff(pass1, pass2+1)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+}
+
+// TestCrawlPassCheckpoint verifies that crawl pass progress can be saved and
+// restored. The test is skipped for graphs that do not implement
+// graph.CrawlPassCheckpointer.
+func (s *SuiteBase) TestCrawlPassCheckpoint(c *gc.C) {
+	checkpointer, ok := s.g.(graph.CrawlPassCheckpointer)
+	if !ok {
+		c.Skip("graph does not support crawl pass checkpoints")
+	}
+
+	_, err := checkpointer.CrawlPassProgress()
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+
+	midID := uuid.MustParse("80000000-0000-0000-0000-000000000000")
+	progress := &graph.PassProgress{
+		PassID:          42,
+		RetrievedBefore: time.Now().UTC().Truncate(time.Millisecond),
+		Partitions: []graph.PartitionProgress{
+			{FromID: uuid.Nil, ToID: midID, LastLinkID: uuid.New(), Crawled: 10},
+			{FromID: midID, ToID: uuid.Nil, Crawled: 3, Done: true},
+		},
+	}
+	c.Assert(checkpointer.SaveCrawlPassProgress(progress), gc.IsNil)
+
+	// Changes to the saved checkpoint must not leak into the graph
+	exp := *progress
+	exp.Partitions = append([]graph.PartitionProgress(nil), progress.Partitions...)
+	progress.Partitions[0].Crawled = 11
+
+	got, err := checkpointer.CrawlPassProgress()
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.RetrievedBefore.Equal(exp.RetrievedBefore), gc.Equals, true)
+	got.RetrievedBefore = exp.RetrievedBefore
+	c.Assert(got, gc.DeepEquals, &exp)
+	c.Assert(got.Done(), gc.Equals, false)
+
+	// Saving a checkpoint replaces the previous one
+	exp.Partitions[0].Done = true
+	c.Assert(checkpointer.SaveCrawlPassProgress(&exp), gc.IsNil)
+	got, err = checkpointer.CrawlPassProgress()
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Done(), gc.Equals, true)
+
+	// The pass ID of a checkpoint is never allocated again
+	if tracker, ok := s.g.(graph.CrawlPassTracker); ok {
+		next, err := tracker.NextCrawlPass()
+		c.Assert(err, gc.IsNil)
+		c.Assert(next > exp.PassID, gc.Equals, true)
+	}
+}
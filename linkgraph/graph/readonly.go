@@ -0,0 +1,50 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+/*readOnlyGraph wraps a graph and only exposes its read-only methods.  Since the
+wrapped graph is not embedded, callers cannot type-assert their way back to the
+mutating methods*/
+type readOnlyGraph struct {
+	g ReadOnlyGraph
+}
+
+/*ReadOnly returns a view of g that cannot be used to mutate it*/
+func ReadOnly(g ReadOnlyGraph) ReadOnlyGraph {
+	if ro, ok := g.(*readOnlyGraph); ok {
+		return ro
+	}
+	return &readOnlyGraph{g: g}
+}
+
+func (r *readOnlyGraph) FindLink(id uuid.UUID) (*Link, error) {
+	return r.g.FindLink(id)
+}
+
+func (r *readOnlyGraph) FindLinkByURL(rawURL string) (*Link, error) {
+	return r.g.FindLinkByURL(rawURL)
+}
+
+func (r *readOnlyGraph) FindLinksByURL(rawURLs []string) ([]*Link, error) {
+	return r.g.FindLinksByURL(rawURLs)
+}
+
+func (r *readOnlyGraph) FindEdge(src, dst uuid.UUID) (*Edge, error) {
+	return r.g.FindEdge(src, dst)
+}
+
+func (r *readOnlyGraph) Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (LinkIterator, error) {
+	return r.g.Links(fromID, toID, retrievedBefore)
+}
+
+func (r *readOnlyGraph) Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (EdgeIterator, error) {
+	return r.g.Edges(fromID, toID, updatedBefore)
+}
+
+func (r *readOnlyGraph) Stats() (*Stats, error) {
+	return r.g.Stats()
+}
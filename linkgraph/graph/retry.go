@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultRetryAttempts       = 3
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+)
+
+/*RetryPolicy controls how graph operations that fail with a transient error
+are retried.  The backoff between attempts starts at InitialBackoff and doubles
+after each attempt up to MaxBackoff.  Zero values are replaced by defaults of 3
+attempts, 100ms and 5s respectively*/
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) initialBackoff() time.Duration {
+	if p.InitialBackoff <= 0 {
+		return defaultRetryInitialBackoff
+	}
+	return p.InitialBackoff
+}
+
+func (p RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff <= 0 {
+		return defaultRetryMaxBackoff
+	}
+	return p.MaxBackoff
+}
+
+//do invokes op until it succeeds, fails with a non-transient error or the
+//maximum number of attempts is reached
+func (p RetryPolicy) do(op func() error) error {
+	backoff := p.initialBackoff()
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !isTransient(err) || attempt >= p.maxAttempts() {
+			return err
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > p.maxBackoff() {
+			backoff = p.maxBackoff()
+		}
+	}
+}
+
+//isTransient returns true for ErrUnavailable errors and timeouts
+func isTransient(err error) bool {
+	if IsRetryable(err) || xerrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return xerrors.As(err, &netErr) && netErr.Timeout()
+}
+
+/*WithRetry decorates g so that operations failing with a transient error
+(ErrUnavailable or a timeout) are transparently retried according to policy.
+If g implements Transactor, so does the returned graph; the operations
+performed through a transaction are not retried.  The other optional
+interfaces of g can be reached via the As* helpers (see Wrapper); their
+operations are not retried either*/
+func WithRetry(g Graph, policy RetryPolicy) Graph {
+	rg := &retryingGraph{g: g, policy: policy}
+	if txg, ok := g.(Transactor); ok {
+		return &retryingTxGraph{retryingGraph: rg, txg: txg}
+	}
+	return rg
+}
+
+type retryingGraph struct {
+	g      Graph
+	policy RetryPolicy
+}
+
+func (r *retryingGraph) UpsertLink(link *Link) error {
+	return r.policy.do(func() error { return r.g.UpsertLink(link) })
+}
+
+func (r *retryingGraph) UpsertEdge(edge *Edge) error {
+	return r.policy.do(func() error { return r.g.UpsertEdge(edge) })
+}
+
+func (r *retryingGraph) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error {
+	return r.policy.do(func() error { return r.g.RemoveStaleEdges(fromID, updatedBefore) })
+}
+
+func (r *retryingGraph) RemoveStaleEdgesInRange(fromID, toID uuid.UUID, updatedBefore time.Time) error {
+	return r.policy.do(func() error { return r.g.RemoveStaleEdgesInRange(fromID, toID, updatedBefore) })
+}
+
+func (r *retryingGraph) FindLink(id uuid.UUID) (link *Link, err error) {
+	err = r.policy.do(func() error {
+		link, err = r.g.FindLink(id)
+		return err
+	})
+	return link, err
+}
+
+func (r *retryingGraph) FindLinkByURL(rawURL string) (link *Link, err error) {
+	err = r.policy.do(func() error {
+		link, err = r.g.FindLinkByURL(rawURL)
+		return err
+	})
+	return link, err
+}
+
+func (r *retryingGraph) FindLinksByURL(rawURLs []string) (links []*Link, err error) {
+	err = r.policy.do(func() error {
+		links, err = r.g.FindLinksByURL(rawURLs)
+		return err
+	})
+	return links, err
+}
+
+func (r *retryingGraph) FindEdge(src, dst uuid.UUID) (edge *Edge, err error) {
+	err = r.policy.do(func() error {
+		edge, err = r.g.FindEdge(src, dst)
+		return err
+	})
+	return edge, err
+}
+
+func (r *retryingGraph) Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (it LinkIterator, err error) {
+	err = r.policy.do(func() error {
+		it, err = r.g.Links(fromID, toID, retrievedBefore)
+		return err
+	})
+	return it, err
+}
+
+func (r *retryingGraph) Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (it EdgeIterator, err error) {
+	err = r.policy.do(func() error {
+		it, err = r.g.Edges(fromID, toID, updatedBefore)
+		return err
+	})
+	return it, err
+}
+
+func (r *retryingGraph) Stats() (stats *Stats, err error) {
+	err = r.policy.do(func() error {
+		stats, err = r.g.Stats()
+		return err
+	})
+	return stats, err
+}
+
+//Unwrap implements Wrapper
+func (r *retryingGraph) Unwrap() Graph { return r.g }
+
+type retryingTxGraph struct {
+	*retryingGraph
+	txg Transactor
+}
+
+func (r *retryingTxGraph) Begin() (tx Tx, err error) {
+	err = r.policy.do(func() error {
+		tx, err = r.txg.Begin()
+		return err
+	})
+	return tx, err
+}
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RetryTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type RetryTestSuite struct{}
+
+func (s *RetryTestSuite) TestRetryTransientErrors(c *gc.C) {
+	g := &flakyGraph{errs: []error{
+		Unavailable(xerrors.New("connection refused")),
+		&net.DNSError{Err: "i/o timeout", IsTimeout: true},
+	}}
+	rg := WithRetry(g, RetryPolicy{InitialBackoff: time.Millisecond})
+
+	link, err := rg.FindLink(uuid.Nil)
+	c.Assert(err, gc.IsNil)
+	c.Assert(link.URL, gc.Equals, "found")
+	c.Assert(g.calls, gc.Equals, 3)
+}
+
+func (s *RetryTestSuite) TestDoNotRetryPermanentErrors(c *gc.C) {
+	g := &flakyGraph{errs: []error{ErrNotFound}}
+	_, err := WithRetry(g, RetryPolicy{InitialBackoff: time.Millisecond}).FindLink(uuid.Nil)
+	c.Assert(xerrors.Is(err, ErrNotFound), gc.Equals, true)
+	c.Assert(g.calls, gc.Equals, 1)
+}
+
+func (s *RetryTestSuite) TestGiveUpAfterMaxAttempts(c *gc.C) {
+	g := &flakyGraph{errs: []error{ErrUnavailable, ErrUnavailable, ErrUnavailable}}
+	_, err := WithRetry(g, RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}).FindLink(uuid.Nil)
+	c.Assert(xerrors.Is(err, ErrUnavailable), gc.Equals, true)
+	c.Assert(g.calls, gc.Equals, 2)
+}
+
+func (s *RetryTestSuite) TestTransactorIsPreserved(c *gc.C) {
+	_, ok := WithRetry(&flakyGraph{}, RetryPolicy{}).(Transactor)
+	c.Assert(ok, gc.Equals, false)
+
+	_, ok = WithRetry(&flakyTxGraph{}, RetryPolicy{}).(Transactor)
+	c.Assert(ok, gc.Equals, true)
+}
+
+// flakyGraph fails the first len(errs) FindLink calls with the specified
+// errors.
+type flakyGraph struct {
+	Graph
+
+	errs  []error
+	calls int
+}
+
+func (f *flakyGraph) FindLink(uuid.UUID) (*Link, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &Link{URL: "found"}, nil
+}
+
+type flakyTxGraph struct {
+	flakyGraph
+}
+
+func (f *flakyTxGraph) Begin() (Tx, error) { return nil, nil }
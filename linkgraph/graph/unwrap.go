@@ -0,0 +1,66 @@
+package graph
+
+/*Wrapper is implemented by graph decorators such as the ones returned by
+WithCache, WithRetry and DualWriter.  Unwrap returns the decorated graph so
+that the optional interfaces it implements (CrawlPassTracker,
+CrawlPassCheckpointer, CrawlPassDiffer and MemoryReporter) can be discovered
+through any number of decorators via the As* helpers*/
+type Wrapper interface {
+	Unwrap() Graph
+}
+
+/*AsCrawlPassTracker returns the CrawlPassTracker implemented by g or by any of
+the graphs it decorates*/
+func AsCrawlPassTracker(g interface{}) (CrawlPassTracker, bool) {
+	tracker, ok := unwrapUntil(g, func(g interface{}) bool {
+		_, ok := g.(CrawlPassTracker)
+		return ok
+	}).(CrawlPassTracker)
+	return tracker, ok
+}
+
+/*AsCrawlPassCheckpointer returns the CrawlPassCheckpointer implemented by g or
+by any of the graphs it decorates*/
+func AsCrawlPassCheckpointer(g interface{}) (CrawlPassCheckpointer, bool) {
+	checkpointer, ok := unwrapUntil(g, func(g interface{}) bool {
+		_, ok := g.(CrawlPassCheckpointer)
+		return ok
+	}).(CrawlPassCheckpointer)
+	return checkpointer, ok
+}
+
+/*AsCrawlPassDiffer returns the CrawlPassDiffer implemented by g or by any of the
+graphs it decorates*/
+func AsCrawlPassDiffer(g interface{}) (CrawlPassDiffer, bool) {
+	differ, ok := unwrapUntil(g, func(g interface{}) bool {
+		_, ok := g.(CrawlPassDiffer)
+		return ok
+	}).(CrawlPassDiffer)
+	return differ, ok
+}
+
+/*AsMemoryReporter returns the MemoryReporter implemented by g or by any of the
+graphs it decorates*/
+func AsMemoryReporter(g interface{}) (MemoryReporter, bool) {
+	reporter, ok := unwrapUntil(g, func(g interface{}) bool {
+		_, ok := g.(MemoryReporter)
+		return ok
+	}).(MemoryReporter)
+	return reporter, ok
+}
+
+//unwrapUntil unwraps g until match returns true.  It returns nil if none of
+//the graphs in the chain matches
+func unwrapUntil(g interface{}, match func(interface{}) bool) interface{} {
+	for g != nil {
+		if match(g) {
+			return g
+		}
+		w, ok := g.(Wrapper)
+		if !ok {
+			return nil
+		}
+		g = w.Unwrap()
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package graph_test
+
+import (
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(UnwrapTestSuite))
+
+type UnwrapTestSuite struct{}
+
+func (s *UnwrapTestSuite) TestOptionalInterfacesOfDecoratedGraphs(c *gc.C) {
+	mem := memory.NewInMemoryGraph()
+	g := graph.WithRetry(
+		graph.WithCache(graph.DualWriter(mem, memory.NewInMemoryGraph()), graph.CacheConfig{}),
+		graph.RetryPolicy{},
+	)
+
+	tracker, ok := graph.AsCrawlPassTracker(g)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(tracker, gc.Equals, mem)
+
+	checkpointer, ok := graph.AsCrawlPassCheckpointer(g)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(checkpointer, gc.Equals, mem)
+
+	differ, ok := graph.AsCrawlPassDiffer(g)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(differ, gc.Equals, mem)
+
+	reporter, ok := graph.AsMemoryReporter(g)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(reporter, gc.Equals, mem)
+}
+
+func (s *UnwrapTestSuite) TestMissingOptionalInterface(c *gc.C) {
+	_, ok := graph.AsCrawlPassTracker(graph.WithRetry(nil, graph.RetryPolicy{}))
+	c.Assert(ok, gc.Equals, false)
+
+	_, ok = graph.AsMemoryReporter(nil)
+	c.Assert(ok, gc.Equals, false)
+}
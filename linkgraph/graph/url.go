@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+//defaultPorts maps URL schemes to the port that is implied when a URL does not
+//specify one
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+/*NormalizeURL returns the canonical form of rawURL that is used for looking up
+links by URL: the scheme and host are lowercased, trailing dots and default
+ports are stripped from the host, an empty path is replaced by "/" and the
+fragment is dropped.  Two URLs that only differ in these respects refer to the
+same page.  It returns an ErrInvalidArgument error if rawURL is not an absolute
+URL*/
+func NormalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", InvalidArgument(xerrors.Errorf("normalize URL: %w", err))
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", InvalidArgument(xerrors.Errorf("normalize URL: %q is not an absolute URL", rawURL))
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		//IPv6 literals must remain bracketed
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(URLTestSuite))
+
+type URLTestSuite struct{}
+
+func (s *URLTestSuite) TestNormalizeURL(c *gc.C) {
+	for rawURL, exp := range map[string]string{
+		"https://example.com":                   "https://example.com/",
+		"HTTP://Example.COM./a/B?q=1#frag":      "http://example.com/a/B?q=1",
+		"http://example.com:80/":                "http://example.com/",
+		"https://example.com:443/":              "https://example.com/",
+		"http://example.com:8080/":              "http://example.com:8080/",
+		"https://example.com:80/":               "https://example.com:80/",
+		"http://[2001:DB8::1]:80/":              "http://[2001:db8::1]/",
+		"  https://example.com/trimmed  ":       "https://example.com/trimmed",
+		"https://user@example.com/x?b=2&a=1":    "https://user@example.com/x?b=2&a=1",
+		"https://example.com/a%20space?x=%2Fy":  "https://example.com/a%20space?x=%2Fy",
+		"https://example.com/already/canonical": "https://example.com/already/canonical",
+	} {
+		got, err := NormalizeURL(rawURL)
+		c.Assert(err, gc.IsNil, gc.Commentf("url %q", rawURL))
+		c.Assert(got, gc.Equals, exp, gc.Commentf("url %q", rawURL))
+	}
+
+	for _, rawURL := range []string{"", "/relative/path", "example.com/no-scheme", "http://%zz"} {
+		_, err := NormalizeURL(rawURL)
+		c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, true, gc.Commentf("url %q", rawURL))
+	}
+}
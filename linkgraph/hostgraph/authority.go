@@ -0,0 +1,265 @@
+package hostgraph
+
+import (
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultDampingFactor = 0.85
+	defaultMaxIterations = 100
+	defaultTolerance     = 1e-6
+)
+
+// AuthorityConfig encapsulates the settings for computing domain authority
+// scores.
+type AuthorityConfig struct {
+	// Source is the graph that stores the host graph, i.e. the Target of
+	// the Builder that built it.
+	Source Source
+
+	// Namespace is the namespace of the host graph. If not specified,
+	// DefaultNamespace will be used.
+	Namespace string
+
+	// DampingFactor is the probability that a random surfer follows one of
+	// the links of the host it is visiting instead of jumping to a random
+	// host. If not specified, a default value of 0.85 will be used.
+	DampingFactor float64
+
+	// MaxIterations bounds the number of power iterations. If not
+	// specified, a default value of 100 will be used.
+	MaxIterations int
+
+	// Tolerance stops the iterations once the sum of the absolute score
+	// changes drops below it. If not specified, a default value of 1e-6
+	// will be used.
+	Tolerance float64
+}
+
+func (cfg *AuthorityConfig) validate() error {
+	var err error
+	if cfg.Source == nil {
+		err = xerrors.New("source graph not specified")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = DefaultNamespace
+	}
+	cfg.Namespace = strings.ToLower(cfg.Namespace)
+
+	if cfg.DampingFactor == 0 {
+		cfg.DampingFactor = defaultDampingFactor
+	} else if cfg.DampingFactor < 0 || cfg.DampingFactor >= 1 {
+		err = xerrors.Errorf("damping factor must be in the [0, 1) range; got %v", cfg.DampingFactor)
+	}
+	if cfg.MaxIterations <= 0 {
+		cfg.MaxIterations = defaultMaxIterations
+	}
+	if cfg.Tolerance <= 0 {
+		cfg.Tolerance = defaultTolerance
+	}
+	return err
+}
+
+// Authority holds the domain authority score of each host of a host graph.
+// Scores are in the [0, 1] range; the most authoritative host has a score of
+// 1 and hosts that are not part of the host graph have a score of 0.
+type Authority struct {
+	namespace string
+	scores    map[string]float64
+}
+
+// ComputeAuthority runs PageRank over a host graph built by a Builder and
+// returns the resulting domain authority scores. Host edges are followed with
+// a probability proportional to their weight, i.e. to the number of page links
+// between the two hosts, so a host linked from many pages of an authoritative
+// host gains more authority than one linked from a single page.
+func ComputeAuthority(cfg AuthorityConfig) (*Authority, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("domain authority config validation failed: %w", err)
+	}
+
+	hosts, err := hostVertices(cfg.Source, cfg.Namespace)
+	if err != nil {
+		return nil, xerrors.Errorf("domain authority: %w", err)
+	}
+	outEdges, err := weightedHostEdges(cfg.Source, hosts)
+	if err != nil {
+		return nil, xerrors.Errorf("domain authority: %w", err)
+	}
+
+	ranks := pageRank(hosts, outEdges, cfg.DampingFactor, cfg.MaxIterations, cfg.Tolerance)
+	a := &Authority{namespace: cfg.Namespace, scores: make(map[string]float64, len(ranks))}
+	var maxRank float64
+	for _, rank := range ranks {
+		maxRank = math.Max(maxRank, rank)
+	}
+	for id, rank := range ranks {
+		if maxRank > 0 {
+			a.scores[hosts[id]] = rank / maxRank
+		}
+	}
+	return a, nil
+}
+
+// hostVertices maps the ID of each host vertex in g to its host.
+func hostVertices(g Source, namespace string) (map[uuid.UUID]string, error) {
+	linkIt, err := g.Links(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	hosts := make(map[uuid.UUID]string)
+	for linkIt.Next() {
+		if host, ok := Host(namespace, linkIt.Link().URL); ok {
+			hosts[linkIt.Link().ID] = host
+		}
+	}
+	return hosts, linkIt.Error()
+}
+
+// weightedHostEdges returns the weights of the outgoing edges of each host
+// vertex. Edges without a weight count as a single page link.
+func weightedHostEdges(g Source, hosts map[uuid.UUID]string) (map[uuid.UUID]map[uuid.UUID]float64, error) {
+	edgeIt, err := g.Edges(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = edgeIt.Close() }()
+
+	outEdges := make(map[uuid.UUID]map[uuid.UUID]float64)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		if _, ok := hosts[edge.Src]; !ok || edge.Src == edge.Dst {
+			continue
+		} else if _, ok = hosts[edge.Dst]; !ok {
+			continue
+		}
+
+		weight := edge.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		if outEdges[edge.Src] == nil {
+			outEdges[edge.Src] = make(map[uuid.UUID]float64)
+		}
+		outEdges[edge.Src][edge.Dst] += weight
+	}
+	return outEdges, edgeIt.Error()
+}
+
+// pageRank computes the PageRank scores of the vertices by power iteration.
+// The scores of vertices without outgoing edges are distributed evenly among
+// all vertices.
+func pageRank(vertices map[uuid.UUID]string, outEdges map[uuid.UUID]map[uuid.UUID]float64, dampingFactor float64, maxIterations int, tolerance float64) map[uuid.UUID]float64 {
+	numVertices := float64(len(vertices))
+	ranks := make(map[uuid.UUID]float64, len(vertices))
+	for id := range vertices {
+		ranks[id] = 1 / numVertices
+	}
+
+	totalWeights := make(map[uuid.UUID]float64, len(outEdges))
+	for src, dsts := range outEdges {
+		for _, weight := range dsts {
+			totalWeights[src] += weight
+		}
+	}
+
+	for iter := 0; iter < maxIterations; iter++ {
+		var danglingRank float64
+		for id, rank := range ranks {
+			if totalWeights[id] == 0 {
+				danglingRank += rank
+			}
+		}
+
+		base := (1-dampingFactor)/numVertices + dampingFactor*danglingRank/numVertices
+		next := make(map[uuid.UUID]float64, len(vertices))
+		for id := range vertices {
+			next[id] = base
+		}
+		for src, dsts := range outEdges {
+			for dst, weight := range dsts {
+				next[dst] += dampingFactor * ranks[src] * weight / totalWeights[src]
+			}
+		}
+
+		var delta float64
+		for id, rank := range next {
+			delta += math.Abs(rank - ranks[id])
+		}
+		ranks = next
+		if delta < tolerance {
+			break
+		}
+	}
+	return ranks
+}
+
+// Score returns the authority score of host.
+func (a *Authority) Score(host string) float64 {
+	return a.scores[strings.TrimSuffix(strings.ToLower(host), ".")]
+}
+
+// ScoreURL returns the authority score of the host of rawURL.
+func (a *Authority) ScoreURL(rawURL string) float64 {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	return a.Score(u.Hostname())
+}
+
+// Scores returns a copy of the authority score of each host.
+func (a *Authority) Scores() map[string]float64 {
+	scores := make(map[string]float64, len(a.scores))
+	for host, score := range a.scores {
+		scores[host] = score
+	}
+	return scores
+}
+
+// Updater is implemented by types that can store domain authority scores,
+// such as an index.Indexer.
+type Updater interface {
+	UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error
+}
+
+// WriteScores stores the authority score of the host of each page link in
+// pages as the index.FieldDomainAuthority field of its document. Host
+// vertices are skipped so pages and their host graph may share a store.
+// Documents that cannot be updated are reported in the returned error while
+// the remaining updates are still applied.
+func (a *Authority) WriteScores(pages Source, updater Updater) error {
+	linkIt, err := pages.Links(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return xerrors.Errorf("write domain authority: %w", err)
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	for linkIt.Next() {
+		link := linkIt.Link()
+		u, pErr := url.Parse(link.URL)
+		if pErr != nil || u.Scheme == a.namespace || u.Hostname() == "" {
+			continue
+		}
+
+		if uErr := updater.UpdateFields(link.ID, map[string]interface{}{
+			index.FieldDomainAuthority: a.Score(u.Hostname()),
+		}); uErr != nil {
+			err = multierror.Append(err, xerrors.Errorf("update domain authority for %q: %w", link.ID, uErr))
+		}
+	}
+	if itErr := linkIt.Error(); itErr != nil {
+		err = multierror.Append(err, xerrors.Errorf("write domain authority: %w", itErr))
+	}
+	return err
+}
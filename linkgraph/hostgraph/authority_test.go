@@ -0,0 +1,104 @@
+package hostgraph
+
+import (
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(AuthorityTestSuite))
+
+type AuthorityTestSuite struct{}
+
+func (s *AuthorityTestSuite) TestComputeAuthority(c *gc.C) {
+	g := memory.NewInMemoryGraph()
+	hostIDs := make(map[string]uuid.UUID)
+	for _, host := range []string{"a.com", "b.com", "c.com", "d.com"} {
+		link := &graph.Link{URL: HostURL(DefaultNamespace, host)}
+		c.Assert(g.UpsertLink(link), gc.IsNil)
+		hostIDs[host] = link.ID
+	}
+	// Page links are not part of the host graph
+	page := &graph.Link{URL: "http://d.com/"}
+	c.Assert(g.UpsertLink(page), gc.IsNil)
+
+	for _, e := range []struct {
+		src, dst string
+		weight   float64
+	}{
+		{"a.com", "b.com", 3},
+		{"a.com", "c.com", 1},
+		{"d.com", "a.com", 1},
+	} {
+		c.Assert(g.UpsertEdge(&graph.Edge{Src: hostIDs[e.src], Dst: hostIDs[e.dst], Weight: e.weight}), gc.IsNil)
+	}
+	c.Assert(g.UpsertEdge(&graph.Edge{Src: page.ID, Dst: hostIDs["c.com"]}), gc.IsNil)
+
+	a, err := ComputeAuthority(AuthorityConfig{Source: g})
+	c.Assert(err, gc.IsNil)
+	c.Assert(a.Scores(), gc.HasLen, 4)
+
+	// b.com receives three times as many page links from a.com as c.com
+	c.Assert(a.Score("b.com"), gc.Equals, 1.0)
+	c.Assert(a.Score("a.com") < a.Score("b.com"), gc.Equals, true)
+	c.Assert(a.Score("c.com") < a.Score("a.com"), gc.Equals, true)
+	c.Assert(a.Score("d.com") < a.Score("c.com"), gc.Equals, true)
+	c.Assert(a.Score("d.com") > 0, gc.Equals, true)
+
+	c.Assert(a.Score("B.com."), gc.Equals, 1.0)
+	c.Assert(a.ScoreURL("https://b.com/about"), gc.Equals, 1.0)
+	c.Assert(a.Score("unknown.com"), gc.Equals, 0.0)
+}
+
+func (s *AuthorityTestSuite) TestComputeAuthorityOnBuiltGraph(c *gc.C) {
+	g := memory.NewInMemoryGraph()
+	pageIDs := make(map[string]uuid.UUID)
+	for _, u := range []string{"http://a.com/1", "http://a.com/2", "http://b.com/", "http://c.com/"} {
+		link := &graph.Link{URL: u}
+		c.Assert(g.UpsertLink(link), gc.IsNil)
+		pageIDs[u] = link.ID
+	}
+	for _, e := range [][2]string{
+		{"http://a.com/1", "http://b.com/"},
+		{"http://a.com/2", "http://b.com/"},
+		{"http://c.com/", "http://b.com/"},
+	} {
+		c.Assert(g.UpsertEdge(&graph.Edge{Src: pageIDs[e[0]], Dst: pageIDs[e[1]]}), gc.IsNil)
+	}
+
+	b, err := NewBuilder(Config{Source: g, Target: g, Namespace: "hosts"})
+	c.Assert(err, gc.IsNil)
+	_, err = b.Build()
+	c.Assert(err, gc.IsNil)
+
+	a, err := ComputeAuthority(AuthorityConfig{Source: g, Namespace: b.Namespace()})
+	c.Assert(err, gc.IsNil)
+	c.Assert(a.Score("b.com"), gc.Equals, 1.0)
+	c.Assert(a.Score("a.com"), gc.Equals, a.Score("c.com"))
+
+	updater := make(fakeUpdater)
+	c.Assert(a.WriteScores(g, updater), gc.IsNil)
+	c.Assert(updater, gc.HasLen, 4)
+	c.Assert(updater[pageIDs["http://b.com/"]], gc.DeepEquals, map[string]interface{}{index.FieldDomainAuthority: 1.0})
+	c.Assert(updater[pageIDs["http://a.com/1"]], gc.DeepEquals, updater[pageIDs["http://a.com/2"]])
+}
+
+func (s *AuthorityTestSuite) TestAuthorityConfigValidation(c *gc.C) {
+	_, err := ComputeAuthority(AuthorityConfig{})
+	c.Assert(err, gc.ErrorMatches, ".*source graph not specified")
+	_, err = ComputeAuthority(AuthorityConfig{Source: memory.NewInMemoryGraph(), DampingFactor: 1})
+	c.Assert(err, gc.ErrorMatches, `.*damping factor must be in the \[0, 1\) range; got 1`)
+
+	a, err := ComputeAuthority(AuthorityConfig{Source: memory.NewInMemoryGraph()})
+	c.Assert(err, gc.IsNil)
+	c.Assert(a.Scores(), gc.HasLen, 0)
+}
+
+type fakeUpdater map[uuid.UUID]map[string]interface{}
+
+func (u fakeUpdater) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	u[linkID] = fields
+	return nil
+}
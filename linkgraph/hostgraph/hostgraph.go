@@ -0,0 +1,240 @@
+// Package hostgraph derives a host graph from a page-level link graph. Each
+// host (e.g. "blog.example.com") becomes a single vertex and the links between
+// pages of different hosts are collapsed into weighted host-to-host edges. The
+// host graph is a much smaller summary of the web graph that is well suited
+// for computing site-level signals such as domain authority.
+//
+// Host graphs are stored through the graph.Graph interface so they can share
+// a store with the page-level graph: host vertices are links whose URL uses
+// the namespace of the host graph as its scheme (e.g.
+// "hostgraph://blog.example.com/") and therefore never collide with crawled
+// pages.
+package hostgraph
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// DefaultNamespace is the namespace used for host graphs if none is specified.
+const DefaultNamespace = "hostgraph"
+
+var (
+	minUUID = uuid.Nil
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+// Source is implemented by link graphs that can list their links and edges.
+type Source interface {
+	Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+	Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error)
+}
+
+// Config encapsulates the settings for a host graph Builder.
+type Config struct {
+	// Source is the page-level link graph.
+	Source Source
+
+	// Target is the graph that stores the host graph. It may be the same
+	// graph as Source.
+	Target graph.Graph
+
+	// Namespace is used as the URL scheme of the host vertices. It must be
+	// a valid URL scheme that is not used by the crawled pages. If not
+	// specified, DefaultNamespace will be used.
+	Namespace string
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.Source == nil {
+		err = xerrors.New("source graph not specified")
+	}
+	if cfg.Target == nil {
+		err = xerrors.New("target graph not specified")
+	}
+
+	if cfg.Namespace == "" {
+		cfg.Namespace = DefaultNamespace
+	}
+	cfg.Namespace = strings.ToLower(cfg.Namespace)
+	if u, parseErr := url.Parse(cfg.Namespace + "://host/"); parseErr != nil || u.Scheme != cfg.Namespace {
+		err = xerrors.Errorf("invalid namespace %q", cfg.Namespace)
+	} else if cfg.Namespace == "http" || cfg.Namespace == "https" {
+		err = xerrors.Errorf("namespace %q collides with page links", cfg.Namespace)
+	}
+	return err
+}
+
+// Stats summarizes the outcome of a host graph build.
+type Stats struct {
+	// Hosts is the number of hosts with at least one page.
+	Hosts int
+
+	// Edges is the number of host-to-host edges.
+	Edges int
+
+	// PageEdges is the number of page-level edges that were aggregated
+	// into the host-to-host edges.
+	PageEdges int
+}
+
+// Builder collapses a page-level link graph into a host graph.
+type Builder struct {
+	cfg Config
+}
+
+// NewBuilder returns a new host graph Builder using the provided config.
+func NewBuilder(cfg Config) (*Builder, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("host graph builder config validation failed: %w", err)
+	}
+	return &Builder{cfg: cfg}, nil
+}
+
+// Namespace returns the namespace of the host graph.
+func (b *Builder) Namespace() string {
+	return b.cfg.Namespace
+}
+
+// Build (re)computes the host graph. The weight of the edge from host A to
+// host B is the number of page-level edges from pages of A to pages of B.
+// Links between pages of the same host are ignored. Host edges that no longer
+// exist in the page-level graph are removed from the target graph.
+func (b *Builder) Build() (*Stats, error) {
+	start := time.Now()
+
+	linkHosts, err := b.linkHosts(start)
+	if err != nil {
+		return nil, xerrors.Errorf("build host graph: %w", err)
+	}
+
+	weights, pageEdges, err := b.hostEdgeWeights(linkHosts, start)
+	if err != nil {
+		return nil, xerrors.Errorf("build host graph: %w", err)
+	}
+
+	hostIDs := make(map[string]uuid.UUID)
+	for _, host := range linkHosts {
+		if _, found := hostIDs[host]; found {
+			continue
+		}
+		link := &graph.Link{URL: HostURL(b.cfg.Namespace, host)}
+		if err = b.cfg.Target.UpsertLink(link); err != nil {
+			return nil, xerrors.Errorf("build host graph: upsert host %s: %w", host, err)
+		}
+		hostIDs[host] = link.ID
+	}
+
+	for pair, weight := range weights {
+		edge := &graph.Edge{Src: hostIDs[pair[0]], Dst: hostIDs[pair[1]], Weight: weight}
+		if err = b.cfg.Target.UpsertEdge(edge); err != nil {
+			return nil, xerrors.Errorf("build host graph: upsert edge %s -> %s: %w", pair[0], pair[1], err)
+		}
+	}
+
+	if err = b.removeStaleEdges(start); err != nil {
+		return nil, xerrors.Errorf("build host graph: %w", err)
+	}
+	return &Stats{Hosts: len(hostIDs), Edges: len(weights), PageEdges: pageEdges}, nil
+}
+
+// linkHosts maps the ID of each page link in the source graph to its host.
+func (b *Builder) linkHosts(now time.Time) (map[uuid.UUID]string, error) {
+	linkIt, err := b.cfg.Source.Links(minUUID, maxUUID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	linkHosts := make(map[uuid.UUID]string)
+	for linkIt.Next() {
+		u, err := url.Parse(linkIt.Link().URL)
+		if err != nil || u.Scheme == b.cfg.Namespace {
+			continue
+		}
+		if host := strings.ToLower(u.Hostname()); host != "" {
+			linkHosts[linkIt.Link().ID] = host
+		}
+	}
+	return linkHosts, linkIt.Error()
+}
+
+// hostEdgeWeights counts the page-level edges between each pair of hosts.
+func (b *Builder) hostEdgeWeights(linkHosts map[uuid.UUID]string, now time.Time) (map[[2]string]float64, int, error) {
+	edgeIt, err := b.cfg.Source.Edges(minUUID, maxUUID, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = edgeIt.Close() }()
+
+	var (
+		weights   = make(map[[2]string]float64)
+		pageEdges int
+	)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		srcHost, dstHost := linkHosts[edge.Src], linkHosts[edge.Dst]
+		if srcHost == "" || dstHost == "" || srcHost == dstHost {
+			continue
+		}
+		weights[[2]string{srcHost, dstHost}]++
+		pageEdges++
+	}
+	return weights, pageEdges, edgeIt.Error()
+}
+
+// removeStaleEdges removes the outgoing edges of all host vertices that were
+// not upserted by the build that started at buildStart.
+func (b *Builder) removeStaleEdges(buildStart time.Time) error {
+	linkIt, err := b.cfg.Target.Links(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Collect the host vertices before mutating the graph as stores may
+	// not support modifications while an iterator is open
+	var hostIDs []uuid.UUID
+	for linkIt.Next() {
+		if _, ok := Host(b.cfg.Namespace, linkIt.Link().URL); ok {
+			hostIDs = append(hostIDs, linkIt.Link().ID)
+		}
+	}
+	if err = linkIt.Error(); err != nil {
+		_ = linkIt.Close()
+		return err
+	}
+	if err = linkIt.Close(); err != nil {
+		return err
+	}
+
+	for _, hostID := range hostIDs {
+		if err = b.cfg.Target.RemoveStaleEdges(hostID, buildStart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HostURL returns the URL of the vertex for host in the host graph with the
+// specified namespace.
+func HostURL(namespace, host string) string {
+	return namespace + "://" + host + "/"
+}
+
+// Host returns the host represented by a vertex URL of the host graph with the
+// specified namespace. It returns false if hostURL does not belong to the host
+// graph.
+func Host(namespace, hostURL string) (string, bool) {
+	prefix := namespace + "://"
+	if !strings.HasPrefix(hostURL, prefix) {
+		return "", false
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(hostURL, prefix), "/")
+	return host, host != ""
+}
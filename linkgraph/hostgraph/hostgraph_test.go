@@ -0,0 +1,122 @@
+package hostgraph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(HostGraphTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type HostGraphTestSuite struct {
+	g     *memory.InMemoryGraph
+	links map[string]*graph.Link
+}
+
+func (s *HostGraphTestSuite) SetUpTest(c *gc.C) {
+	s.g = memory.NewInMemoryGraph()
+	s.links = make(map[string]*graph.Link)
+	for _, u := range []string{"http://a.com/1", "https://A.com/2", "http://b.com/", "http://c.com/"} {
+		link := &graph.Link{URL: u}
+		c.Assert(s.g.UpsertLink(link), gc.IsNil)
+		s.links[u] = link
+	}
+	for _, e := range [][2]string{
+		{"http://a.com/1", "http://b.com/"},
+		{"https://A.com/2", "http://b.com/"},
+		{"http://a.com/1", "https://A.com/2"},
+		{"http://b.com/", "http://c.com/"},
+		{"http://c.com/", "http://a.com/1"},
+	} {
+		c.Assert(s.g.UpsertEdge(&graph.Edge{Src: s.links[e[0]].ID, Dst: s.links[e[1]].ID}), gc.IsNil)
+	}
+}
+
+func (s *HostGraphTestSuite) TestBuild(c *gc.C) {
+	target := memory.NewInMemoryGraph()
+	b, err := NewBuilder(Config{Source: s.g, Target: target})
+	c.Assert(err, gc.IsNil)
+
+	stats, err := b.Build()
+	c.Assert(err, gc.IsNil)
+	c.Assert(stats, gc.DeepEquals, &Stats{Hosts: 3, Edges: 3, PageEdges: 4})
+	c.Assert(hostEdges(c, target, DefaultNamespace), gc.DeepEquals, map[[2]string]float64{
+		{"a.com", "b.com"}: 2,
+		{"b.com", "c.com"}: 1,
+		{"c.com", "a.com"}: 1,
+	})
+}
+
+func (s *HostGraphTestSuite) TestRebuildInSameGraph(c *gc.C) {
+	b, err := NewBuilder(Config{Source: s.g, Target: s.g, Namespace: "hosts"})
+	c.Assert(err, gc.IsNil)
+	_, err = b.Build()
+	c.Assert(err, gc.IsNil)
+
+	// Drop the b.com -> c.com page link and rebuild; host vertices from
+	// the previous build must not be treated as pages
+	c.Assert(s.g.RemoveStaleEdges(s.links["http://b.com/"].ID, time.Now()), gc.IsNil)
+	stats, err := b.Build()
+	c.Assert(err, gc.IsNil)
+	c.Assert(stats, gc.DeepEquals, &Stats{Hosts: 3, Edges: 2, PageEdges: 3})
+	c.Assert(hostEdges(c, s.g, "hosts"), gc.DeepEquals, map[[2]string]float64{
+		{"a.com", "b.com"}: 2,
+		{"c.com", "a.com"}: 1,
+	})
+
+	graphStats, err := s.g.Stats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(graphStats.Links, gc.Equals, uint64(4+3))
+}
+
+func (s *HostGraphTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewBuilder(Config{Target: s.g})
+	c.Assert(err, gc.ErrorMatches, ".*source graph not specified")
+	_, err = NewBuilder(Config{Source: s.g})
+	c.Assert(err, gc.ErrorMatches, ".*target graph not specified")
+	_, err = NewBuilder(Config{Source: s.g, Target: s.g, Namespace: "https"})
+	c.Assert(err, gc.ErrorMatches, `.*namespace "https" collides with page links`)
+	_, err = NewBuilder(Config{Source: s.g, Target: s.g, Namespace: "host graph"})
+	c.Assert(err, gc.ErrorMatches, `.*invalid namespace "host graph"`)
+}
+
+func (s *HostGraphTestSuite) TestHostURL(c *gc.C) {
+	host, ok := Host("hosts", HostURL("hosts", "blog.example.com"))
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(host, gc.Equals, "blog.example.com")
+
+	_, ok = Host("hosts", "http://blog.example.com/")
+	c.Assert(ok, gc.Equals, false)
+}
+
+// hostEdges returns the weights of the host graph edges in g keyed by the
+// source and destination hosts.
+func hostEdges(c *gc.C, g graph.Graph, namespace string) map[[2]string]float64 {
+	maxUUID := uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	edgeIt, err := g.Edges(uuid.Nil, maxUUID, time.Now())
+	c.Assert(err, gc.IsNil)
+
+	edges := make(map[[2]string]float64)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		src, err := g.FindLink(edge.Src)
+		c.Assert(err, gc.IsNil)
+		dst, err := g.FindLink(edge.Dst)
+		c.Assert(err, gc.IsNil)
+
+		srcHost, srcOK := Host(namespace, src.URL)
+		dstHost, dstOK := Host(namespace, dst.URL)
+		if srcOK && dstOK {
+			edges[[2]string{srcHost, dstHost}] = edge.Weight
+		}
+	}
+	c.Assert(edgeIt.Error(), gc.IsNil)
+	c.Assert(edgeIt.Close(), gc.IsNil)
+	return edges
+}
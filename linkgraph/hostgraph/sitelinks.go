@@ -0,0 +1,195 @@
+package hostgraph
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultMinSitelinkAuthority = 0.5
+	defaultSitelinksPerHost     = 6
+)
+
+// SitelinksConfig encapsulates the settings for computing sitelinks.
+type SitelinksConfig struct {
+	// Source is the page-level link graph. Host vertices stored in the
+	// same graph are ignored.
+	Source Source
+
+	// Authority selects the hosts that get sitelinks.
+	Authority *Authority
+
+	// MinAuthority is the minimum authority score of the hosts that get
+	// sitelinks. If not specified, a default value of 0.5 will be used.
+	MinAuthority float64
+
+	// PerHost is the maximum number of sitelinks per host. If not
+	// specified, a default value of 6 will be used.
+	PerHost int
+}
+
+func (cfg *SitelinksConfig) validate() error {
+	var err error
+	if cfg.Source == nil {
+		err = xerrors.New("source graph not specified")
+	}
+	if cfg.Authority == nil {
+		err = xerrors.New("domain authority scores not specified")
+	}
+	if cfg.MinAuthority <= 0 {
+		cfg.MinAuthority = defaultMinSitelinkAuthority
+	}
+	if cfg.PerHost <= 0 {
+		cfg.PerHost = defaultSitelinksPerHost
+	}
+	return err
+}
+
+// Sitelink is one of the most important pages of a host.
+type Sitelink struct {
+	LinkID uuid.UUID
+	URL    string
+
+	// Score is the PageRank score of the page within the subgraph of the
+	// pages of its host.
+	Score float64
+}
+
+// Sitelinks holds the most important pages of the most authoritative hosts so
+// that search results for those hosts can link to them.
+type Sitelinks struct {
+	byHost map[string][]Sitelink
+}
+
+// ComputeSitelinks selects the hosts whose authority is at least
+// cfg.MinAuthority and, for each one of them, runs PageRank over the subgraph
+// induced by its pages. The pages with the highest internal PageRank, which
+// usually are the ones linked from the navigation of the site, become the
+// sitelinks of the host.
+func ComputeSitelinks(cfg SitelinksConfig) (*Sitelinks, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("sitelinks config validation failed: %w", err)
+	}
+
+	pages, urls, err := sitelinkCandidates(cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("compute sitelinks: %w", err)
+	}
+	outEdges, err := internalEdges(cfg.Source, pages)
+	if err != nil {
+		return nil, xerrors.Errorf("compute sitelinks: %w", err)
+	}
+
+	// Split the pages and their internal edges into one subgraph per host
+	hostPages := make(map[string]map[uuid.UUID]string)
+	for id, host := range pages {
+		if hostPages[host] == nil {
+			hostPages[host] = make(map[uuid.UUID]string)
+		}
+		hostPages[host][id] = host
+	}
+	hostEdges := make(map[string]map[uuid.UUID]map[uuid.UUID]float64)
+	for src, dsts := range outEdges {
+		host := pages[src]
+		if hostEdges[host] == nil {
+			hostEdges[host] = make(map[uuid.UUID]map[uuid.UUID]float64)
+		}
+		hostEdges[host][src] = dsts
+	}
+
+	s := &Sitelinks{byHost: make(map[string][]Sitelink, len(hostPages))}
+	for host, vertices := range hostPages {
+		ranks := pageRank(vertices, hostEdges[host], defaultDampingFactor, defaultMaxIterations, defaultTolerance)
+		links := make([]Sitelink, 0, len(ranks))
+		for id, rank := range ranks {
+			links = append(links, Sitelink{LinkID: id, URL: urls[id], Score: rank})
+		}
+		sort.Slice(links, func(i, j int) bool {
+			if links[i].Score != links[j].Score {
+				return links[i].Score > links[j].Score
+			}
+			return links[i].URL < links[j].URL
+		})
+		if len(links) > cfg.PerHost {
+			links = links[:cfg.PerHost]
+		}
+		s.byHost[host] = links
+	}
+	return s, nil
+}
+
+// sitelinkCandidates returns the host and the URL of each page whose host is
+// authoritative enough to get sitelinks.
+func sitelinkCandidates(cfg SitelinksConfig) (map[uuid.UUID]string, map[uuid.UUID]string, error) {
+	linkIt, err := cfg.Source.Links(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	pages, urls := make(map[uuid.UUID]string), make(map[uuid.UUID]string)
+	for linkIt.Next() {
+		link := linkIt.Link()
+		u, pErr := url.Parse(link.URL)
+		if pErr != nil || u.Scheme == cfg.Authority.namespace {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if host != "" && cfg.Authority.Score(host) >= cfg.MinAuthority {
+			pages[link.ID], urls[link.ID] = host, link.URL
+		}
+	}
+	return pages, urls, linkIt.Error()
+}
+
+// internalEdges returns the outgoing edges between pages of the same host.
+func internalEdges(g Source, pages map[uuid.UUID]string) (map[uuid.UUID]map[uuid.UUID]float64, error) {
+	edgeIt, err := g.Edges(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = edgeIt.Close() }()
+
+	outEdges := make(map[uuid.UUID]map[uuid.UUID]float64)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		srcHost, dstHost := pages[edge.Src], pages[edge.Dst]
+		if srcHost == "" || srcHost != dstHost || edge.Src == edge.Dst {
+			continue
+		}
+		if outEdges[edge.Src] == nil {
+			outEdges[edge.Src] = make(map[uuid.UUID]float64)
+		}
+		outEdges[edge.Src][edge.Dst] = 1
+	}
+	return outEdges, edgeIt.Error()
+}
+
+// ForHost returns the sitelinks of host, most important first.
+func (s *Sitelinks) ForHost(host string) []Sitelink {
+	return s.byHost[strings.TrimSuffix(strings.ToLower(host), ".")]
+}
+
+// ForURL returns the sitelinks of the host of rawURL, most important first.
+func (s *Sitelinks) ForURL(rawURL string) []Sitelink {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	return s.ForHost(u.Hostname())
+}
+
+// Hosts returns the hosts that have sitelinks in alphabetical order.
+func (s *Sitelinks) Hosts() []string {
+	hosts := make([]string, 0, len(s.byHost))
+	for host := range s.byHost {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,78 @@
+package hostgraph
+
+import (
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(SitelinksTestSuite))
+
+type SitelinksTestSuite struct{}
+
+func (s *SitelinksTestSuite) TestComputeSitelinks(c *gc.C) {
+	g := memory.NewInMemoryGraph()
+	ids := make(map[string]uuid.UUID)
+	for _, u := range []string{
+		"http://big.com/", "http://big.com/docs", "http://big.com/blog", "http://big.com/blog/post",
+		"http://small.com/", "http://small.com/about",
+	} {
+		link := &graph.Link{URL: u}
+		c.Assert(g.UpsertLink(link), gc.IsNil)
+		ids[u] = link.ID
+	}
+	// Every page of big.com links to its home page and docs while only the
+	// home page links to the blog. small.com links to big.com.
+	for _, e := range [][2]string{
+		{"http://big.com/", "http://big.com/docs"},
+		{"http://big.com/", "http://big.com/blog"},
+		{"http://big.com/docs", "http://big.com/"},
+		{"http://big.com/blog", "http://big.com/"},
+		{"http://big.com/blog", "http://big.com/docs"},
+		{"http://big.com/blog/post", "http://big.com/"},
+		{"http://big.com/blog/post", "http://big.com/docs"},
+		{"http://small.com/", "http://small.com/about"},
+		{"http://small.com/about", "http://big.com/"},
+	} {
+		c.Assert(g.UpsertEdge(&graph.Edge{Src: ids[e[0]], Dst: ids[e[1]]}), gc.IsNil)
+	}
+
+	b, err := NewBuilder(Config{Source: g, Target: g})
+	c.Assert(err, gc.IsNil)
+	_, err = b.Build()
+	c.Assert(err, gc.IsNil)
+	authority, err := ComputeAuthority(AuthorityConfig{Source: g})
+	c.Assert(err, gc.IsNil)
+	c.Assert(authority.Score("big.com"), gc.Equals, 1.0)
+	c.Assert(authority.Score("small.com") < 0.9, gc.Equals, true)
+
+	sitelinks, err := ComputeSitelinks(SitelinksConfig{Source: g, Authority: authority, MinAuthority: 0.9, PerHost: 3})
+	c.Assert(err, gc.IsNil)
+	c.Assert(sitelinks.Hosts(), gc.DeepEquals, []string{"big.com"})
+	c.Assert(sitelinkURLs(sitelinks.ForURL("https://BIG.com/search?q=gophers")), gc.DeepEquals, []string{
+		"http://big.com/", "http://big.com/docs", "http://big.com/blog",
+	})
+	c.Assert(sitelinks.ForHost("small.com"), gc.HasLen, 0)
+
+	sitelinks, err = ComputeSitelinks(SitelinksConfig{Source: g, Authority: authority, MinAuthority: 0.1})
+	c.Assert(err, gc.IsNil)
+	c.Assert(sitelinks.Hosts(), gc.DeepEquals, []string{"big.com", "small.com"})
+	c.Assert(sitelinks.ForHost("big.com"), gc.HasLen, 4)
+	c.Assert(sitelinkURLs(sitelinks.ForHost("small.com")), gc.DeepEquals, []string{"http://small.com/about", "http://small.com/"})
+}
+
+func (s *SitelinksTestSuite) TestSitelinksConfigValidation(c *gc.C) {
+	_, err := ComputeSitelinks(SitelinksConfig{Authority: &Authority{}})
+	c.Assert(err, gc.ErrorMatches, ".*source graph not specified")
+	_, err = ComputeSitelinks(SitelinksConfig{Source: memory.NewInMemoryGraph()})
+	c.Assert(err, gc.ErrorMatches, ".*domain authority scores not specified")
+}
+
+func sitelinkURLs(links []Sitelink) []string {
+	var urls []string
+	for _, link := range links {
+		urls = append(urls, link.URL)
+	}
+	return urls
+}
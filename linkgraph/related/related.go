@@ -0,0 +1,260 @@
+// Package related finds the pages that are related to a given page. Pages are
+// primarily related by co-citation: two pages are related if they are linked
+// from the same pages, in which case the authors of those pages considered
+// them to be about similar topics. Pages that are rarely linked to can
+// optionally be related by the similarity of their content as captured by the
+// embeddings stored in the text index.
+package related
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// SourceCoCitation and SourceContent identify how a Result was found.
+	SourceCoCitation = "cocitation"
+	SourceContent    = "content"
+
+	defaultRefreshInterval = 10 * time.Minute
+	defaultMaxOutDegree    = 200
+)
+
+var (
+	minUUID = uuid.Nil
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+// Graph is implemented by link graphs that can look up links and list their
+// edges.
+type Graph interface {
+	FindLink(id uuid.UUID) (*graph.Link, error)
+	Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error)
+}
+
+// ContentIndex is implemented by text indexers that can look up documents and
+// execute semantic queries, such as an index.Indexer.
+type ContentIndex interface {
+	FindByID(linkID uuid.UUID) (*index.Document, error)
+	Search(query index.Query) (index.Iterator, error)
+}
+
+// Config encapsulates the settings for a related pages Finder.
+type Config struct {
+	// Graph is the link graph used for finding co-cited pages.
+	Graph Graph
+
+	// Index, if specified, is used for topping up the results of pages
+	// with too few co-cited pages with the pages whose content is most
+	// similar to theirs. Only documents with an embedding (see
+	// index.Document.Embedding) can be related by content.
+	Index ContentIndex
+
+	// RefreshInterval controls how often the snapshot of the link graph
+	// edges used for computing co-citations is rebuilt. If not specified,
+	// a default value of 10 minutes will be used.
+	RefreshInterval time.Duration
+
+	// MaxOutDegree excludes pages with more outgoing links (e.g. site maps
+	// and directories) from co-citation counts as they relate pages with
+	// little in common. If not specified, a default value of 200 will be
+	// used.
+	MaxOutDegree int
+}
+
+func (cfg *Config) validate() error {
+	if cfg.Graph == nil {
+		return xerrors.New("graph not specified")
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultRefreshInterval
+	}
+	if cfg.MaxOutDegree <= 0 {
+		cfg.MaxOutDegree = defaultMaxOutDegree
+	}
+	return nil
+}
+
+// Result is a page related to the page passed to Finder.Related.
+type Result struct {
+	LinkID uuid.UUID
+	URL    string
+
+	// Score is the cosine similarity of the sets of pages linking to the
+	// two pages for co-cited pages or the cosine similarity of the
+	// embeddings of the two pages for pages related by content.
+	Score float64
+
+	// Source is either SourceCoCitation or SourceContent.
+	Source string
+}
+
+// Finder finds related pages.
+type Finder struct {
+	cfg Config
+
+	mu       sync.Mutex
+	builtAt  time.Time
+	inLinks  map[uuid.UUID][]uuid.UUID
+	outLinks map[uuid.UUID][]uuid.UUID
+}
+
+// NewFinder returns a new related pages Finder using the provided config.
+func NewFinder(cfg Config) (*Finder, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("related pages config validation failed: %w", err)
+	}
+	return &Finder{cfg: cfg}, nil
+}
+
+// Related returns up to n pages related to the link with the specified ID,
+// most related first. Co-cited pages are returned ahead of pages related by
+// content. The returned error wraps graph.ErrNotFound if the link does not
+// exist.
+func (f *Finder) Related(linkID uuid.UUID, n int) ([]Result, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if _, err := f.cfg.Graph.FindLink(linkID); err != nil {
+		return nil, xerrors.Errorf("related pages: %w", err)
+	}
+
+	results, err := f.coCited(linkID, n)
+	if err != nil {
+		return nil, xerrors.Errorf("related pages: %w", err)
+	}
+	if len(results) < n && f.cfg.Index != nil {
+		if results, err = f.similarContent(linkID, n, results); err != nil {
+			return nil, xerrors.Errorf("related pages: %w", err)
+		}
+	}
+
+	for i := range results {
+		link, err := f.cfg.Graph.FindLink(results[i].LinkID)
+		if err != nil {
+			return nil, xerrors.Errorf("related pages: lookup %s: %w", results[i].LinkID, err)
+		}
+		results[i].URL = link.URL
+	}
+	return results, nil
+}
+
+// coCited returns up to n pages that are linked from the same pages as
+// linkID, ranked by the cosine similarity of their sets of linking pages.
+func (f *Finder) coCited(linkID uuid.UUID, n int) ([]Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.refresh(); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uuid.UUID]int)
+	for _, src := range f.inLinks[linkID] {
+		if len(f.outLinks[src]) > f.cfg.MaxOutDegree {
+			continue
+		}
+		for _, dst := range f.outLinks[src] {
+			if dst != linkID {
+				counts[dst]++
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(counts))
+	for id, count := range counts {
+		norm := math.Sqrt(float64(len(f.inLinks[linkID]) * len(f.inLinks[id])))
+		results = append(results, Result{LinkID: id, Score: float64(count) / norm, Source: SourceCoCitation})
+	}
+	sortResults(results)
+	if len(results) > n {
+		results = results[:n]
+	}
+	return results, nil
+}
+
+// refresh rebuilds the snapshot of the link graph edges if it is older than
+// the refresh interval.
+func (f *Finder) refresh() error {
+	now := time.Now()
+	if !f.builtAt.IsZero() && now.Sub(f.builtAt) < f.cfg.RefreshInterval {
+		return nil
+	}
+
+	edgeIt, err := f.cfg.Graph.Edges(minUUID, maxUUID, now)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = edgeIt.Close() }()
+
+	inLinks, outLinks := make(map[uuid.UUID][]uuid.UUID), make(map[uuid.UUID][]uuid.UUID)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		if edge.Src == edge.Dst {
+			continue
+		}
+		inLinks[edge.Dst] = append(inLinks[edge.Dst], edge.Src)
+		outLinks[edge.Src] = append(outLinks[edge.Src], edge.Dst)
+	}
+	if err = edgeIt.Error(); err != nil {
+		return err
+	}
+
+	f.inLinks, f.outLinks, f.builtAt = inLinks, outLinks, now
+	return nil
+}
+
+// similarContent appends the pages whose content is most similar to the
+// content of linkID to results until there are n of them. Pages that have not
+// been indexed or have no embedding are not related by content.
+func (f *Finder) similarContent(linkID uuid.UUID, n int, results []Result) ([]Result, error) {
+	doc, err := f.cfg.Index.FindByID(linkID)
+	if xerrors.Is(err, index.ErrNotFound) {
+		return results, nil
+	} else if err != nil {
+		return nil, err
+	} else if len(doc.Embedding) == 0 {
+		return results, nil
+	}
+
+	it, err := f.cfg.Index.Search(index.Query{Type: index.QueryTypeSemantic, Embedding: doc.Embedding})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = it.Close() }()
+
+	seen := map[uuid.UUID]bool{linkID: true}
+	for _, r := range results {
+		seen[r.LinkID] = true
+	}
+	for len(results) < n && it.Next() {
+		similar := it.Document()
+		if seen[similar.LinkID] {
+			continue
+		}
+		seen[similar.LinkID] = true
+		results = append(results, Result{
+			LinkID: similar.LinkID,
+			Score:  index.CosineSimilarity(doc.Embedding, similar.Embedding),
+			Source: SourceContent,
+		})
+	}
+	return results, it.Error()
+}
+
+// sortResults orders results by decreasing score breaking ties by link ID so
+// results are deterministic.
+func sortResults(results []Result) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].LinkID.String() < results[j].LinkID.String()
+	})
+}
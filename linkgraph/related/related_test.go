@@ -0,0 +1,176 @@
+package related
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RelatedTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type RelatedTestSuite struct {
+	g     *memory.InMemoryGraph
+	links map[string]*graph.Link
+}
+
+// SetUpTest creates a graph where s1 links to x and y, s2 links to x, y and z
+// and s3 links to z and w.
+func (s *RelatedTestSuite) SetUpTest(c *gc.C) {
+	s.g = memory.NewInMemoryGraph()
+	s.links = make(map[string]*graph.Link)
+	for _, name := range []string{"s1", "s2", "s3", "x", "y", "z", "w"} {
+		link := &graph.Link{URL: "http://" + name + ".com/"}
+		c.Assert(s.g.UpsertLink(link), gc.IsNil)
+		s.links[name] = link
+	}
+	for _, e := range [][2]string{
+		{"s1", "x"}, {"s1", "y"},
+		{"s2", "x"}, {"s2", "y"}, {"s2", "z"},
+		{"s3", "z"}, {"s3", "w"},
+	} {
+		c.Assert(s.g.UpsertEdge(&graph.Edge{Src: s.links[e[0]].ID, Dst: s.links[e[1]].ID}), gc.IsNil)
+	}
+}
+
+func (s *RelatedTestSuite) TestCoCitation(c *gc.C) {
+	f, err := NewFinder(Config{Graph: s.g})
+	c.Assert(err, gc.IsNil)
+
+	results, err := f.Related(s.links["x"].ID, 10)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.DeepEquals, []Result{
+		{LinkID: s.links["y"].ID, URL: "http://y.com/", Score: 1, Source: SourceCoCitation},
+		{LinkID: s.links["z"].ID, URL: "http://z.com/", Score: 0.5, Source: SourceCoCitation},
+	})
+
+	results, err = f.Related(s.links["x"].ID, 1)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].LinkID, gc.Equals, s.links["y"].ID)
+
+	// Pages without incoming links have no co-cited pages
+	results, err = f.Related(s.links["s1"].ID, 10)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.HasLen, 0)
+}
+
+func (s *RelatedTestSuite) TestMaxOutDegree(c *gc.C) {
+	f, err := NewFinder(Config{Graph: s.g, MaxOutDegree: 2})
+	c.Assert(err, gc.IsNil)
+
+	// s2 links to too many pages to be taken into account
+	results, err := f.Related(s.links["x"].ID, 10)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.DeepEquals, []Result{
+		{LinkID: s.links["y"].ID, URL: "http://y.com/", Score: 0.5, Source: SourceCoCitation},
+	})
+}
+
+func (s *RelatedTestSuite) TestSnapshotRefresh(c *gc.C) {
+	f, err := NewFinder(Config{Graph: s.g, RefreshInterval: time.Hour})
+	c.Assert(err, gc.IsNil)
+	_, err = f.Related(s.links["w"].ID, 10)
+	c.Assert(err, gc.IsNil)
+
+	// Edges added after the snapshot was built are ignored until it expires
+	c.Assert(s.g.UpsertEdge(&graph.Edge{Src: s.links["s1"].ID, Dst: s.links["w"].ID}), gc.IsNil)
+	results, err := f.Related(s.links["w"].ID, 10)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resultIDs(results), gc.DeepEquals, []uuid.UUID{s.links["z"].ID})
+
+	f.builtAt = f.builtAt.Add(-time.Hour)
+	results, err = f.Related(s.links["w"].ID, 10)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.HasLen, 3)
+}
+
+func (s *RelatedTestSuite) TestContentSimilarity(c *gc.C) {
+	idx := fakeContentIndex{
+		s.links["w"].ID:  {LinkID: s.links["w"].ID, Embedding: []float32{1, 0}},
+		s.links["z"].ID:  {LinkID: s.links["z"].ID, Embedding: []float32{1, 0}},
+		s.links["s1"].ID: {LinkID: s.links["s1"].ID, Embedding: []float32{1, 1}},
+		s.links["s2"].ID: {LinkID: s.links["s2"].ID, Embedding: []float32{0, 1}},
+	}
+	f, err := NewFinder(Config{Graph: s.g, Index: idx})
+	c.Assert(err, gc.IsNil)
+
+	// Co-cited pages are not repeated
+	results, err := f.Related(s.links["w"].ID, 3)
+	c.Assert(err, gc.IsNil)
+	c.Assert(resultIDs(results), gc.DeepEquals, []uuid.UUID{s.links["z"].ID, s.links["s1"].ID, s.links["s2"].ID})
+	c.Assert(results[1].URL, gc.Equals, "http://s1.com/")
+	c.Assert(results[1].Source, gc.Equals, SourceContent)
+	c.Assert(results[2].Score, gc.Equals, 0.0)
+
+	// Pages that have not been indexed are only related by co-citation
+	results, err = f.Related(s.links["x"].ID, 3)
+	c.Assert(err, gc.IsNil)
+	c.Assert(results, gc.HasLen, 2)
+}
+
+func (s *RelatedTestSuite) TestErrors(c *gc.C) {
+	_, err := NewFinder(Config{})
+	c.Assert(err, gc.ErrorMatches, ".*graph not specified")
+
+	f, err := NewFinder(Config{Graph: s.g})
+	c.Assert(err, gc.IsNil)
+	_, err = f.Related(uuid.New(), 10)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+}
+
+func resultIDs(results []Result) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, r := range results {
+		ids = append(ids, r.LinkID)
+	}
+	return ids
+}
+
+// fakeContentIndex returns all documents for semantic queries ordered by
+// their similarity to the query embedding.
+type fakeContentIndex map[uuid.UUID]*index.Document
+
+func (idx fakeContentIndex) FindByID(linkID uuid.UUID) (*index.Document, error) {
+	if doc, found := idx[linkID]; found {
+		return doc, nil
+	}
+	return nil, index.ErrNotFound
+}
+
+func (idx fakeContentIndex) Search(q index.Query) (index.Iterator, error) {
+	it := new(docIterator)
+	for _, doc := range idx {
+		it.docs = append(it.docs, doc)
+	}
+	sort.Slice(it.docs, func(i, j int) bool {
+		return index.CosineSimilarity(q.Embedding, it.docs[i].Embedding) > index.CosineSimilarity(q.Embedding, it.docs[j].Embedding)
+	})
+	return it, nil
+}
+
+type docIterator struct {
+	docs []*index.Document
+	cur  int
+}
+
+func (it *docIterator) Next() bool {
+	if it.cur == len(it.docs) {
+		return false
+	}
+	it.cur++
+	return true
+}
+
+func (it *docIterator) Document() *index.Document { return it.docs[it.cur-1] }
+func (it *docIterator) TotalCount() uint64        { return uint64(len(it.docs)) }
+func (it *docIterator) Error() error              { return nil }
+func (it *docIterator) Close() error              { return nil }
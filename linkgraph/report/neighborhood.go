@@ -0,0 +1,227 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// minNodeSize and maxNodeSize bound the size of the nodes in a
+	// neighborhood visualization. Sizes are scaled linearly by PageRank.
+	minNodeSize = 5.0
+	maxNodeSize = 30.0
+
+	// defaultMaxNodes bounds the size of neighborhoods so they can still
+	// be rendered by a browser.
+	defaultMaxNodes = 500
+)
+
+// NeighborhoodGraph is implemented by link graphs that can look up links and
+// list their edges.
+type NeighborhoodGraph interface {
+	Graph
+	FindLink(id uuid.UUID) (*graph.Link, error)
+}
+
+// NeighborhoodNode is a link in a Neighborhood.
+type NeighborhoodNode struct {
+	ID    uuid.UUID `json:"id"`
+	Label string    `json:"label"`
+	Score float64   `json:"score"`
+
+	// Size is the PageRank score of the link scaled to the [5, 30] range.
+	Size float64 `json:"size"`
+
+	// Hops is the distance of the link from the seed, ignoring the
+	// direction of the edges.
+	Hops int `json:"hops"`
+}
+
+// NeighborhoodEdge is an edge between two links of a Neighborhood.
+type NeighborhoodEdge struct {
+	Src uuid.UUID
+	Dst uuid.UUID
+}
+
+// Neighborhood is the subgraph induced by the links within a number of hops
+// from a seed link. It can be exported in the JSON formats expected by the D3
+// and vis.js graph visualization libraries.
+type Neighborhood struct {
+	Seed   uuid.UUID
+	Radius int
+	Nodes  []NeighborhoodNode
+	Edges  []NeighborhoodEdge
+
+	// Truncated is set if the neighborhood contained more than the
+	// requested maximum number of links. Links closest to the seed are
+	// retained.
+	Truncated bool
+}
+
+// BuildNeighborhood extracts the subgraph induced by the links that are at
+// most radius hops away from seed, following edges in either direction. At
+// most maxNodes links are included; a default of 500 is used if maxNodes is not
+// positive. Nodes are sized by their PageRank score as reported by scores,
+// which may be nil. ErrNotFound is returned if the seed link does not exist.
+func BuildNeighborhood(g NeighborhoodGraph, scores ScoreStore, seed uuid.UUID, radius, maxNodes int) (*Neighborhood, error) {
+	if radius < 0 {
+		return nil, xerrors.Errorf("neighborhood: invalid radius %d", radius)
+	}
+	if maxNodes <= 0 {
+		maxNodes = defaultMaxNodes
+	}
+
+	seedLink, err := g.FindLink(seed)
+	if err != nil {
+		return nil, xerrors.Errorf("neighborhood: %w", err)
+	}
+
+	outEdges, inEdges, err := adjacency(g, time.Now())
+	if err != nil {
+		return nil, xerrors.Errorf("neighborhood: %w", err)
+	}
+
+	nh := &Neighborhood{Seed: seed, Radius: radius}
+	hops := map[uuid.UUID]int{seed: 0}
+	order := []uuid.UUID{seed}
+	for frontier := order; len(frontier) != 0 && hops[frontier[0]] < radius && !nh.Truncated; {
+		var next []uuid.UUID
+		for _, id := range frontier {
+			for _, neighbors := range [][]uuid.UUID{outEdges[id], inEdges[id]} {
+				for _, neighbor := range neighbors {
+					if _, seen := hops[neighbor]; seen {
+						continue
+					} else if len(order) == maxNodes {
+						nh.Truncated = true
+						break
+					}
+					hops[neighbor] = hops[id] + 1
+					order = append(order, neighbor)
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	var maxScore float64
+	for _, id := range order {
+		link := seedLink
+		if id != seed {
+			if link, err = g.FindLink(id); err != nil {
+				return nil, xerrors.Errorf("neighborhood: lookup %s: %w", id, err)
+			}
+		}
+
+		node := NeighborhoodNode{ID: id, Label: link.URL, Hops: hops[id]}
+		if scores != nil {
+			doc, err := scores.FindByID(id)
+			if err == nil {
+				node.Score = doc.PageRank
+			} else if !xerrors.Is(err, index.ErrNotFound) {
+				return nil, xerrors.Errorf("neighborhood: score lookup for %s: %w", id, err)
+			}
+		}
+		if node.Score > maxScore {
+			maxScore = node.Score
+		}
+		nh.Nodes = append(nh.Nodes, node)
+	}
+
+	for i := range nh.Nodes {
+		nh.Nodes[i].Size = minNodeSize
+		if maxScore > 0 {
+			nh.Nodes[i].Size += (maxNodeSize - minNodeSize) * nh.Nodes[i].Score / maxScore
+		}
+	}
+	sort.SliceStable(nh.Nodes, func(i, j int) bool {
+		if nh.Nodes[i].Hops != nh.Nodes[j].Hops {
+			return nh.Nodes[i].Hops < nh.Nodes[j].Hops
+		}
+		return nh.Nodes[i].Label < nh.Nodes[j].Label
+	})
+
+	// Emit the induced edges in node order so exports are deterministic
+	for _, node := range nh.Nodes {
+		for _, dst := range outEdges[node.ID] {
+			if _, found := hops[dst]; found {
+				nh.Edges = append(nh.Edges, NeighborhoodEdge{Src: node.ID, Dst: dst})
+			}
+		}
+	}
+	return nh, nil
+}
+
+// adjacency returns the destinations of the outgoing edges and the sources of
+// the incoming edges of each link in g.
+func adjacency(g Graph, now time.Time) (outEdges, inEdges map[uuid.UUID][]uuid.UUID, err error) {
+	edgeIt, err := g.Edges(minUUID, maxUUID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = edgeIt.Close() }()
+
+	outEdges, inEdges = make(map[uuid.UUID][]uuid.UUID), make(map[uuid.UUID][]uuid.UUID)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		outEdges[edge.Src] = append(outEdges[edge.Src], edge.Dst)
+		inEdges[edge.Dst] = append(inEdges[edge.Dst], edge.Src)
+	}
+	return outEdges, inEdges, edgeIt.Error()
+}
+
+// WriteD3 writes the neighborhood to w in the node-link JSON format used by
+// D3 force layouts: {"nodes": [...], "links": [{"source": ..., "target": ...}]}.
+func (nh *Neighborhood) WriteD3(w io.Writer) error {
+	type d3Link struct {
+		Source uuid.UUID `json:"source"`
+		Target uuid.UUID `json:"target"`
+	}
+	out := struct {
+		Nodes []NeighborhoodNode `json:"nodes"`
+		Links []d3Link           `json:"links"`
+	}{Nodes: nh.nodes(), Links: make([]d3Link, 0, len(nh.Edges))}
+	for _, edge := range nh.Edges {
+		out.Links = append(out.Links, d3Link{Source: edge.Src, Target: edge.Dst})
+	}
+	return writeIndentedJSON(w, out)
+}
+
+// WriteVis writes the neighborhood to w in the JSON format of vis.js network
+// datasets: {"nodes": [...], "edges": [{"from": ..., "to": ...}]}.
+func (nh *Neighborhood) WriteVis(w io.Writer) error {
+	type visEdge struct {
+		From   uuid.UUID `json:"from"`
+		To     uuid.UUID `json:"to"`
+		Arrows string    `json:"arrows"`
+	}
+	out := struct {
+		Nodes []NeighborhoodNode `json:"nodes"`
+		Edges []visEdge          `json:"edges"`
+	}{Nodes: nh.nodes(), Edges: make([]visEdge, 0, len(nh.Edges))}
+	for _, edge := range nh.Edges {
+		out.Edges = append(out.Edges, visEdge{From: edge.Src, To: edge.Dst, Arrows: "to"})
+	}
+	return writeIndentedJSON(w, out)
+}
+
+// nodes returns the nodes of nh, never nil so they encode as a JSON array.
+func (nh *Neighborhood) nodes() []NeighborhoodNode {
+	if nh.Nodes == nil {
+		return []NeighborhoodNode{}
+	}
+	return nh.Nodes
+}
+
+func writeIndentedJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
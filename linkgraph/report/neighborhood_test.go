@@ -0,0 +1,116 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(NeighborhoodTestSuite))
+
+type NeighborhoodTestSuite struct {
+	g      *memory.InMemoryGraph
+	scores fakeScores
+	links  map[string]*graph.Link
+}
+
+// SetUpTest creates the graph e -> a -> b -> c -> d plus an isolated link f.
+func (s *NeighborhoodTestSuite) SetUpTest(c *gc.C) {
+	s.g = memory.NewInMemoryGraph()
+	s.scores = make(fakeScores)
+	s.links = make(map[string]*graph.Link)
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		link := &graph.Link{URL: "http://" + name + ".com/"}
+		c.Assert(s.g.UpsertLink(link), gc.IsNil)
+		s.links[name] = link
+	}
+	for _, e := range [][2]string{{"e", "a"}, {"a", "b"}, {"b", "c"}, {"c", "d"}} {
+		c.Assert(s.g.UpsertEdge(&graph.Edge{Src: s.links[e[0]].ID, Dst: s.links[e[1]].ID}), gc.IsNil)
+	}
+	s.scores[s.links["a"].ID] = 0.4
+	s.scores[s.links["b"].ID] = 0.2
+}
+
+func (s *NeighborhoodTestSuite) TestBuildNeighborhood(c *gc.C) {
+	a, b, e := s.links["a"], s.links["b"], s.links["e"]
+	nh, err := BuildNeighborhood(s.g, s.scores, a.ID, 1, 0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(nh.Truncated, gc.Equals, false)
+	c.Assert(nh.Nodes, gc.DeepEquals, []NeighborhoodNode{
+		{ID: a.ID, Label: "http://a.com/", Score: 0.4, Size: 30, Hops: 0},
+		{ID: b.ID, Label: "http://b.com/", Score: 0.2, Size: 17.5, Hops: 1},
+		{ID: e.ID, Label: "http://e.com/", Size: 5, Hops: 1},
+	})
+	c.Assert(nh.Edges, gc.DeepEquals, []NeighborhoodEdge{
+		{Src: a.ID, Dst: b.ID},
+		{Src: e.ID, Dst: a.ID},
+	})
+
+	nh, err = BuildNeighborhood(s.g, nil, a.ID, 2, 0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(nodeLabels(nh), gc.DeepEquals, []string{"http://a.com/", "http://b.com/", "http://e.com/", "http://c.com/"})
+	c.Assert(nh.Edges, gc.HasLen, 3)
+	c.Assert(nh.Nodes[0].Size, gc.Equals, minNodeSize)
+}
+
+func (s *NeighborhoodTestSuite) TestBuildNeighborhoodTruncation(c *gc.C) {
+	nh, err := BuildNeighborhood(s.g, s.scores, s.links["a"].ID, 3, 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(nh.Truncated, gc.Equals, true)
+	c.Assert(nodeLabels(nh), gc.DeepEquals, []string{"http://a.com/", "http://b.com/"})
+	c.Assert(nh.Edges, gc.DeepEquals, []NeighborhoodEdge{{Src: s.links["a"].ID, Dst: s.links["b"].ID}})
+}
+
+func (s *NeighborhoodTestSuite) TestBuildNeighborhoodErrors(c *gc.C) {
+	_, err := BuildNeighborhood(s.g, s.scores, uuid.New(), 1, 0)
+	c.Assert(xerrors.Is(err, graph.ErrNotFound), gc.Equals, true)
+
+	_, err = BuildNeighborhood(s.g, s.scores, s.links["a"].ID, -1, 0)
+	c.Assert(err, gc.ErrorMatches, "neighborhood: invalid radius -1")
+
+	nh, err := BuildNeighborhood(s.g, s.scores, s.links["f"].ID, 2, 0)
+	c.Assert(err, gc.IsNil)
+	c.Assert(nodeLabels(nh), gc.DeepEquals, []string{"http://f.com/"})
+	c.Assert(nh.Edges, gc.HasLen, 0)
+}
+
+func (s *NeighborhoodTestSuite) TestExportFormats(c *gc.C) {
+	a, b := s.links["a"], s.links["b"]
+	nh, err := BuildNeighborhood(s.g, s.scores, a.ID, 1, 0)
+	c.Assert(err, gc.IsNil)
+
+	var (
+		buf bytes.Buffer
+		d3  struct {
+			Nodes []NeighborhoodNode  `json:"nodes"`
+			Links []map[string]string `json:"links"`
+		}
+	)
+	c.Assert(nh.WriteD3(&buf), gc.IsNil)
+	c.Assert(json.Unmarshal(buf.Bytes(), &d3), gc.IsNil)
+	c.Assert(d3.Nodes, gc.DeepEquals, nh.Nodes)
+	c.Assert(d3.Links[0], gc.DeepEquals, map[string]string{"source": a.ID.String(), "target": b.ID.String()})
+
+	var vis struct {
+		Nodes []NeighborhoodNode  `json:"nodes"`
+		Edges []map[string]string `json:"edges"`
+	}
+	buf.Reset()
+	c.Assert(nh.WriteVis(&buf), gc.IsNil)
+	c.Assert(json.Unmarshal(buf.Bytes(), &vis), gc.IsNil)
+	c.Assert(vis.Nodes, gc.DeepEquals, nh.Nodes)
+	c.Assert(vis.Edges[0], gc.DeepEquals, map[string]string{"from": a.ID.String(), "to": b.ID.String(), "arrows": "to"})
+}
+
+func nodeLabels(nh *Neighborhood) []string {
+	var labels []string
+	for _, node := range nh.Nodes {
+		labels = append(labels, node.Label)
+	}
+	return labels
+}
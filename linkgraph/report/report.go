@@ -0,0 +1,229 @@
+package report
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	minUUID = uuid.Nil
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+// Graph is implemented by objects that can list the links and edges of a
+// link graph.
+type Graph interface {
+	Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+	Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error)
+}
+
+// ScoreStore is implemented by objects that can look up the PageRank score
+// of a link, such as an index.Indexer.
+type ScoreStore interface {
+	FindByID(linkID uuid.UUID) (*index.Document, error)
+}
+
+// Entry describes a single link in a PageRank report.
+type Entry struct {
+	LinkID    uuid.UUID `json:"link_id"`
+	URL       string    `json:"url"`
+	Domain    string    `json:"domain"`
+	Score     float64   `json:"score"`
+	InDegree  int       `json:"in_degree"`
+	OutDegree int       `json:"out_degree"`
+}
+
+// Report lists the top-K links by PageRank score. Operators can use it to
+// validate the quality of a crawl, e.g. to spot link farms or domains that
+// dominate the ranking.
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	TotalLinks  int       `json:"total_links"`
+	Entries     []Entry   `json:"entries"`
+}
+
+// TopK builds a report with the k highest scoring links in g. Links that have
+// not been indexed yet are assigned a zero score.
+func TopK(g Graph, scores ScoreStore, k int) (*Report, error) {
+	if k <= 0 {
+		return nil, xerrors.Errorf("top-k report: invalid k %d", k)
+	}
+
+	now := time.Now()
+	inDegree, outDegree, err := degrees(g, now)
+	if err != nil {
+		return nil, xerrors.Errorf("top-k report: %w", err)
+	}
+
+	linkIt, err := g.Links(minUUID, maxUUID, now)
+	if err != nil {
+		return nil, xerrors.Errorf("top-k report: %w", err)
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	rep := &Report{GeneratedAt: now}
+	top := make(entryHeap, 0, k+1)
+	for linkIt.Next() {
+		link := linkIt.Link()
+		rep.TotalLinks++
+
+		var score float64
+		doc, err := scores.FindByID(link.ID)
+		if err == nil {
+			score = doc.PageRank
+		} else if !xerrors.Is(err, index.ErrNotFound) {
+			return nil, xerrors.Errorf("top-k report: score lookup for %s: %w", link.ID, err)
+		}
+
+		heap.Push(&top, Entry{
+			LinkID:    link.ID,
+			URL:       link.URL,
+			Domain:    domainOf(link.URL),
+			Score:     score,
+			InDegree:  inDegree[link.ID],
+			OutDegree: outDegree[link.ID],
+		})
+		if top.Len() > k {
+			heap.Pop(&top)
+		}
+	}
+	if err = linkIt.Error(); err != nil {
+		return nil, xerrors.Errorf("top-k report: %w", err)
+	}
+
+	// Popping from the min-heap yields entries in increasing score order
+	rep.Entries = make([]Entry, top.Len())
+	for i := len(rep.Entries) - 1; i >= 0; i-- {
+		rep.Entries[i] = heap.Pop(&top).(Entry)
+	}
+	return rep, nil
+}
+
+// DomainCount is the number of links that point to a domain.
+type DomainCount struct {
+	Domain string `json:"domain"`
+	Links  int    `json:"links"`
+}
+
+// TopDomains returns the k domains with the most links in g, in descending
+// order of link count. Ties are broken alphabetically.
+func TopDomains(g Graph, k int) ([]DomainCount, error) {
+	if k <= 0 {
+		return nil, xerrors.Errorf("top domains: invalid k %d", k)
+	}
+
+	linkIt, err := g.Links(minUUID, maxUUID, time.Now())
+	if err != nil {
+		return nil, xerrors.Errorf("top domains: %w", err)
+	}
+	defer func() { _ = linkIt.Close() }()
+
+	counts := make(map[string]int)
+	for linkIt.Next() {
+		if domain := domainOf(linkIt.Link().URL); domain != "" {
+			counts[domain]++
+		}
+	}
+	if err = linkIt.Error(); err != nil {
+		return nil, xerrors.Errorf("top domains: %w", err)
+	}
+
+	domains := make([]DomainCount, 0, len(counts))
+	for domain, count := range counts {
+		domains = append(domains, DomainCount{Domain: domain, Links: count})
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].Links != domains[j].Links {
+			return domains[i].Links > domains[j].Links
+		}
+		return domains[i].Domain < domains[j].Domain
+	})
+	if len(domains) > k {
+		domains = domains[:k]
+	}
+	return domains, nil
+}
+
+func degrees(g Graph, now time.Time) (inDegree, outDegree map[uuid.UUID]int, err error) {
+	edgeIt, err := g.Edges(minUUID, maxUUID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = edgeIt.Close() }()
+
+	inDegree, outDegree = make(map[uuid.UUID]int), make(map[uuid.UUID]int)
+	for edgeIt.Next() {
+		edge := edgeIt.Edge()
+		outDegree[edge.Src]++
+		inDegree[edge.Dst]++
+	}
+	return inDegree, outDegree, edgeIt.Error()
+}
+
+func domainOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// WriteJSON writes the report to w in JSON format.
+func (r *Report) WriteJSON(w io.Writer) error {
+	return writeIndentedJSON(w, r)
+}
+
+// WriteCSV writes the report entries to w in CSV format, preceded by a header
+// row.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"rank", "link_id", "url", "domain", "score", "in_degree", "out_degree"}); err != nil {
+		return err
+	}
+	for i, e := range r.Entries {
+		if err := cw.Write([]string{
+			strconv.Itoa(i + 1),
+			e.LinkID.String(),
+			e.URL,
+			e.Domain,
+			strconv.FormatFloat(e.Score, 'g', -1, 64),
+			strconv.Itoa(e.InDegree),
+			strconv.Itoa(e.OutDegree),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// entryHeap is a min-heap of entries ordered by score. Ties are broken by URL
+// so that reports are deterministic.
+type entryHeap []Entry
+
+func (h entryHeap) Len() int { return len(h) }
+func (h entryHeap) Less(i, j int) bool {
+	if h[i].Score != h[j].Score {
+		return h[i].Score < h[j].Score
+	}
+	return h[i].URL > h[j].URL
+}
+func (h entryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x interface{}) { *h = append(*h, x.(Entry)) }
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
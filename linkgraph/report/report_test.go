@@ -0,0 +1,90 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ReportTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type ReportTestSuite struct{}
+
+type fakeScores map[uuid.UUID]float64
+
+func (s fakeScores) FindByID(linkID uuid.UUID) (*index.Document, error) {
+	score, ok := s[linkID]
+	if !ok {
+		return nil, xerrors.Errorf("find by ID: %w", index.ErrNotFound)
+	}
+	return &index.Document{LinkID: linkID, PageRank: score}, nil
+}
+
+func (s *ReportTestSuite) TestTopK(c *gc.C) {
+	g := memory.NewInMemoryGraph()
+	scores := make(fakeScores)
+
+	var links []*graph.Link
+	for i, u := range []string{"http://A.com/", "http://b.com/x", "http://b.com/y", "http://c.com/"} {
+		link := &graph.Link{URL: u}
+		c.Assert(g.UpsertLink(link), gc.IsNil)
+		links = append(links, link)
+		if i < 3 {
+			scores[link.ID] = float64(i+1) / 10
+		}
+	}
+	for _, e := range [][2]int{{0, 1}, {0, 2}, {3, 2}, {1, 2}} {
+		c.Assert(g.UpsertEdge(&graph.Edge{Src: links[e[0]].ID, Dst: links[e[1]].ID}), gc.IsNil)
+	}
+
+	rep, err := TopK(g, scores, 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(rep.TotalLinks, gc.Equals, 4)
+	c.Assert(rep.Entries, gc.DeepEquals, []Entry{
+		{LinkID: links[2].ID, URL: "http://b.com/y", Domain: "b.com", Score: 0.3, InDegree: 3, OutDegree: 0},
+		{LinkID: links[1].ID, URL: "http://b.com/x", Domain: "b.com", Score: 0.2, InDegree: 1, OutDegree: 1},
+	})
+
+	var buf bytes.Buffer
+	c.Assert(rep.WriteCSV(&buf), gc.IsNil)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(lines, gc.HasLen, 3)
+	c.Assert(lines[0], gc.Equals, "rank,link_id,url,domain,score,in_degree,out_degree")
+	c.Assert(lines[1], gc.Equals, "1,"+links[2].ID.String()+",http://b.com/y,b.com,0.3,3,0")
+
+	buf.Reset()
+	c.Assert(rep.WriteJSON(&buf), gc.IsNil)
+	var decoded Report
+	c.Assert(json.Unmarshal(buf.Bytes(), &decoded), gc.IsNil)
+	c.Assert(decoded.Entries, gc.DeepEquals, rep.Entries)
+
+	_, err = TopK(g, scores, 0)
+	c.Assert(err, gc.NotNil)
+}
+
+func (s *ReportTestSuite) TestTopDomains(c *gc.C) {
+	g := memory.NewInMemoryGraph()
+	for _, u := range []string{"http://A.com/", "http://b.com/x", "http://b.com/y", "http://a.com/z", "http://c.com/", "http://b.com/z"} {
+		c.Assert(g.UpsertLink(&graph.Link{URL: u}), gc.IsNil)
+	}
+
+	domains, err := TopDomains(g, 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(domains, gc.DeepEquals, []DomainCount{
+		{Domain: "b.com", Links: 3},
+		{Domain: "a.com", Links: 2},
+	})
+
+	_, err = TopDomains(g, 0)
+	c.Assert(err, gc.NotNil)
+}
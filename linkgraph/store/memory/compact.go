@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"unsafe"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+)
+
+// Compile-time check for ensuring InMemoryGraph implements MemoryReporter.
+var _ graph.MemoryReporter = (*InMemoryGraph)(nil)
+
+// mapEntryOverhead approximates the per-entry bookkeeping cost of a Go map
+// (hash bits, overflow buckets and unused slots due to the load factor).
+const mapEntryOverhead = 16
+
+var (
+	passIDSize     = uint64(unsafe.Sizeof(uint64(0)))
+	uuidSize       = uint64(unsafe.Sizeof(uuid.UUID{}))
+	pointerSize    = uint64(unsafe.Sizeof(uintptr(0)))
+	stringSize     = uint64(unsafe.Sizeof(""))
+	sliceSize      = uint64(unsafe.Sizeof(edgeList(nil)))
+	linkSize       = uint64(unsafe.Sizeof(graph.Link{}))
+	edgeSize       = uint64(unsafe.Sizeof(graph.Edge{}))
+	loggedEdgeSize = uint64(unsafe.Sizeof(loggedEdge{}))
+)
+
+// Compact rebuilds the maps and slices of the graph so that the memory held
+// by removed entries can be reclaimed. Go maps never shrink, so the graph
+// keeps the memory of all the edges it ever stored until it is compacted,
+// e.g. after a cleanup pass pruned a large number of stale edges. Compact
+// blocks all other graph operations while it runs.
+func (s *InMemoryGraph) Compact() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := make(map[uuid.UUID]*graph.Link, len(s.links))
+	for id, link := range s.links {
+		links[id] = link
+	}
+	s.links = links
+
+	linkURLIndex := make(map[string]*graph.Link, len(s.linkURLIndex))
+	for url, link := range s.linkURLIndex {
+		linkURLIndex[url] = link
+	}
+	s.linkURLIndex = linkURLIndex
+
+	normURLIndex := make(map[string]*graph.Link, len(s.normURLIndex))
+	for url, link := range s.normURLIndex {
+		normURLIndex[url] = link
+	}
+	s.normURLIndex = normURLIndex
+
+	edges := make(map[uuid.UUID]*graph.Edge, len(s.edges))
+	for id, edge := range s.edges {
+		edges[id] = edge
+	}
+	s.edges = edges
+
+	// Links whose edges were all removed do not need an entry
+	linkEdgeMap := make(map[uuid.UUID]edgeList, len(s.linkEdgeMap))
+	for id, list := range s.linkEdgeMap {
+		if len(list) == 0 {
+			continue
+		}
+		if cap(list) > len(list) {
+			list = append(edgeList(nil), list...)
+		}
+		linkEdgeMap[id] = list
+	}
+	s.linkEdgeMap = linkEdgeMap
+
+	if cap(s.linkIDs) > len(s.linkIDs) {
+		s.linkIDs = append([]uuid.UUID(nil), s.linkIDs...)
+	}
+}
+
+// MemoryStats estimates the number of bytes used by the graph. The estimate
+// reflects the entries currently stored in the graph; memory that has not
+// been reclaimed by Compact yet is not included.
+func (s *InMemoryGraph) MemoryStats() (*graph.MemoryStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats graph.MemoryStats
+	for _, link := range s.links {
+		stats.Links += linkSize + uint64(len(link.URL)+len(link.ContentHash))
+	}
+	stats.Links += mapSize(len(s.links), uuidSize, pointerSize)
+	stats.Edges = uint64(len(s.edges))*edgeSize + mapSize(len(s.edges), uuidSize, pointerSize)
+
+	// The URL strings are shared with the links but the normalized ones
+	// are not
+	stats.Indices = mapSize(len(s.linkURLIndex), stringSize, pointerSize) +
+		mapSize(len(s.normURLIndex), stringSize, pointerSize) +
+		mapSize(len(s.linkEdgeMap), uuidSize, sliceSize) +
+		uint64(cap(s.linkIDs))*uuidSize
+	for _, list := range s.linkEdgeMap {
+		stats.Indices += uint64(cap(list)) * uuidSize
+	}
+	for normURL := range s.normURLIndex {
+		stats.Indices += uint64(len(normURL))
+	}
+
+	for _, log := range s.passLogs {
+		stats.CrawlPassLogs += mapSize(len(log.links), uuidSize, stringSize) +
+			mapSize(len(log.edges), uuidSize, loggedEdgeSize)
+	}
+	stats.CrawlPassLogs += mapSize(len(s.passLogs), passIDSize, pointerSize)
+	return &stats, nil
+}
+
+// mapSize estimates the number of bytes used by a map with the specified
+// number of entries and key/value sizes.
+func mapSize(entries int, keySize, valueSize uint64) uint64 {
+	return uint64(entries) * (keySize + valueSize + mapEntryOverhead)
+}
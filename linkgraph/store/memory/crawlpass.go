@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// Compile-time checks for ensuring InMemoryGraph implements CrawlPassTracker,
+// CrawlPassDiffer and CrawlPassCheckpointer.
+var (
+	_ graph.CrawlPassTracker      = (*InMemoryGraph)(nil)
+	_ graph.CrawlPassDiffer       = (*InMemoryGraph)(nil)
+	_ graph.CrawlPassCheckpointer = (*InMemoryGraph)(nil)
+)
+
+// NextCrawlPass allocates a new crawl pass ID.
+func (s *InMemoryGraph) NextCrawlPass() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastCrawlPass++
+	return s.lastCrawlPass, nil
+}
+
+// LinksInCrawlPass returns an iterator for the set of links that were last
+// retrieved by the specified crawl pass.
+func (s *InMemoryGraph) LinksInCrawlPass(pass uint64) (graph.LinkIterator, error) {
+	s.mu.RLock()
+	var list []*graph.Link
+	for _, linkID := range s.linkIDs {
+		if link := s.links[linkID]; link.CrawlPassID == pass {
+			list = append(list, link)
+		}
+	}
+	s.mu.RUnlock()
+
+	return &linkIterator{s: s, links: list}, nil
+}
+
+// EdgesInCrawlPass returns an iterator for the set of edges that were last
+// updated by the specified crawl pass.
+func (s *InMemoryGraph) EdgesInCrawlPass(pass uint64) (graph.EdgeIterator, error) {
+	s.mu.RLock()
+	var list []*graph.Edge
+	for _, linkID := range s.linkIDs {
+		for _, edgeID := range s.linkEdgeMap[linkID] {
+			if edge := s.edges[edgeID]; edge.CrawlPassID == pass {
+				list = append(list, edge)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	return &edgeIterator{s: s, edges: list}, nil
+}
+
+// SaveCrawlPassProgress replaces the saved crawl pass checkpoint with a copy
+// of progress.
+func (s *InMemoryGraph) SaveCrawlPassProgress(progress *graph.PassProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.passProgress = copyPassProgress(progress)
+	s.observeCrawlPass(progress.PassID)
+	return nil
+}
+
+// CrawlPassProgress returns a copy of the last saved crawl pass checkpoint.
+func (s *InMemoryGraph) CrawlPassProgress() (*graph.PassProgress, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.passProgress == nil {
+		return nil, xerrors.Errorf("crawl pass progress: %w", graph.ErrNotFound)
+	}
+	return copyPassProgress(s.passProgress), nil
+}
+
+func copyPassProgress(progress *graph.PassProgress) *graph.PassProgress {
+	pCopy := *progress
+	pCopy.Partitions = append([]graph.PartitionProgress(nil), progress.Partitions...)
+	return &pCopy
+}
+
+// observeCrawlPass ensures that NextCrawlPass never returns a pass ID that
+// has already been recorded in the graph. The caller must hold the write lock.
+func (s *InMemoryGraph) observeCrawlPass(pass uint64) {
+	if pass > s.lastCrawlPass {
+		s.lastCrawlPass = pass
+	}
+}
+
+// crawlPassLog records the links retrieved and the edges upserted by a crawl
+// pass so that passes can still be compared after their links have been
+// re-crawled. The in-memory graph keeps the log of every pass.
+type crawlPassLog struct {
+	// links maps the IDs of the retrieved links to their content hash.
+	links map[uuid.UUID]string
+	edges map[uuid.UUID]loggedEdge
+}
+
+// loggedEdge holds the endpoints of an edge, which may have been removed from
+// the graph since it was logged.
+type loggedEdge struct {
+	src, dst uuid.UUID
+}
+
+// Diff reports the differences between two crawl passes.
+func (s *InMemoryGraph) Diff(passA, passB uint64) (*graph.PassDiff, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, pass := range []uint64{passA, passB} {
+		if pass == 0 || pass > s.lastCrawlPass {
+			return nil, xerrors.Errorf("diff crawl passes: unknown pass %d: %w", pass, graph.ErrNotFound)
+		}
+	}
+
+	a, b := s.passLog(passA), s.passLog(passB)
+	diff := &graph.PassDiff{PassA: passA, PassB: passB}
+	for id, hashB := range b.links {
+		hashA, seen := a.links[id]
+		if !seen {
+			diff.NewLinks = append(diff.NewLinks, s.links[id].URL)
+		} else if hashA != "" && hashB != "" && hashA != hashB {
+			diff.ChangedDocuments = append(diff.ChangedDocuments, s.links[id].URL)
+		}
+	}
+	for id := range a.links {
+		if _, seen := b.links[id]; !seen {
+			diff.RemovedLinks = append(diff.RemovedLinks, s.links[id].URL)
+		}
+	}
+	for id, edge := range b.edges {
+		if _, seen := a.edges[id]; !seen {
+			diff.NewEdges = append(diff.NewEdges, s.formatEdge(edge))
+		}
+	}
+	for id, edge := range a.edges {
+		// Edges whose source was not re-crawled by passB are unchanged.
+		if _, recrawled := b.links[edge.src]; !recrawled {
+			continue
+		}
+		if _, seen := b.edges[id]; !seen {
+			diff.RemovedEdges = append(diff.RemovedEdges, s.formatEdge(edge))
+		}
+	}
+
+	for _, list := range [][]string{diff.NewLinks, diff.RemovedLinks, diff.NewEdges, diff.RemovedEdges, diff.ChangedDocuments} {
+		sort.Strings(list)
+	}
+	return diff, nil
+}
+
+// passLog returns the log of the specified pass. The caller must hold the
+// read lock.
+func (s *InMemoryGraph) passLog(pass uint64) *crawlPassLog {
+	if log := s.passLogs[pass]; log != nil {
+		return log
+	}
+	return new(crawlPassLog)
+}
+
+// logLink records a link retrieved by a crawl pass. The caller must hold the
+// write lock.
+func (s *InMemoryGraph) logLink(link *graph.Link) {
+	if log := s.mutablePassLog(link.CrawlPassID); log != nil {
+		log.links[link.ID] = link.ContentHash
+	}
+}
+
+// logEdge records an edge upserted by a crawl pass. The caller must hold the
+// write lock.
+func (s *InMemoryGraph) logEdge(edge *graph.Edge) {
+	if log := s.mutablePassLog(edge.CrawlPassID); log != nil {
+		log.edges[edge.ID] = loggedEdge{src: edge.Src, dst: edge.Dst}
+	}
+}
+
+// mutablePassLog returns the log of the specified pass, creating it if
+// needed, or nil for links and edges that were not touched by a crawl pass.
+func (s *InMemoryGraph) mutablePassLog(pass uint64) *crawlPassLog {
+	if pass == 0 {
+		return nil
+	}
+	log := s.passLogs[pass]
+	if log == nil {
+		log = &crawlPassLog{
+			links: make(map[uuid.UUID]string),
+			edges: make(map[uuid.UUID]loggedEdge),
+		}
+		s.passLogs[pass] = log
+	}
+	return log
+}
+
+// formatEdge formats an edge the same way as graph.Diff.
+func (s *InMemoryGraph) formatEdge(edge loggedEdge) string {
+	return s.links[edge.src].URL + " -> " + s.links[edge.dst].URL
+}
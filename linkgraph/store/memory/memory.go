@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"bytes"
+	"sort"
 	"sync"
 	"time"
 
@@ -25,6 +27,24 @@ type InMemoryGraph struct {
 
 	linkURLIndex map[string]*graph.Link
 	linkEdgeMap  map[uuid.UUID]edgeList
+
+	// normURLIndex maps normalized URLs (see graph.NormalizeURL) to the
+	// first link that was upserted with a URL that normalizes to them.
+	normURLIndex map[string]*graph.Link
+
+	// linkIDs contains the IDs of all links sorted in ascending order so
+	// that Links and Edges can range-scan a partition without visiting
+	// every link in the graph.
+	linkIDs []uuid.UUID
+
+	// lastCrawlPass is the highest crawl pass ID allocated by
+	// NextCrawlPass or recorded on an upserted link or edge while
+	// passLogs keeps track of what each pass touched.
+	lastCrawlPass uint64
+	passLogs      map[uint64]*crawlPassLog
+
+	// passProgress is the last checkpoint saved by the crawler.
+	passProgress *graph.PassProgress
 }
 
 // NewInMemoryGraph creates a new in-memory link graph.
@@ -34,6 +54,8 @@ func NewInMemoryGraph() *InMemoryGraph {
 		edges:        make(map[uuid.UUID]*graph.Edge),
 		linkURLIndex: make(map[string]*graph.Link),
 		linkEdgeMap:  make(map[uuid.UUID]edgeList),
+		normURLIndex: make(map[string]*graph.Link),
+		passLogs:     make(map[uint64]*crawlPassLog),
 	}
 }
 
@@ -42,16 +64,43 @@ func (s *InMemoryGraph) UpsertLink(link *graph.Link) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.upsertLink(link)
+	return nil
+}
+
+// upsertLink implements UpsertLink. The caller must hold the write lock.
+func (s *InMemoryGraph) upsertLink(link *graph.Link) {
+	s.observeCrawlPass(link.CrawlPassID)
+	defer s.logLink(link)
+
 	// Check if a link with the same URL already exists. If so, convert
 	// this into an update and point the link ID to the existing link.
 	if existing := s.linkURLIndex[link.URL]; existing != nil {
 		link.ID = existing.ID
 		origTs := existing.RetrievedAt
+		origFeed := existing.Feed
+		origRetryTs := existing.RetryNotBefore
+		origPass := existing.CrawlPassID
+		origHash := existing.ContentHash
+		origPriority := existing.Priority
 		*existing = *link
 		if origTs.After(existing.RetrievedAt) {
 			existing.RetrievedAt = origTs
 		}
-		return nil
+		if origRetryTs.After(existing.RetryNotBefore) {
+			existing.RetryNotBefore = origRetryTs
+		}
+		if origPass > existing.CrawlPassID {
+			existing.CrawlPassID = origPass
+		}
+		if existing.ContentHash == "" {
+			existing.ContentHash = origHash
+		}
+		if existing.Priority == 0 {
+			existing.Priority = origPriority
+		}
+		existing.Feed = existing.Feed || origFeed
+		return
 	}
 
 	// Assign new ID and insert link
@@ -64,9 +113,18 @@ func (s *InMemoryGraph) UpsertLink(link *graph.Link) error {
 
 	lCopy := new(graph.Link)
 	*lCopy = *link
-	s.linkURLIndex[lCopy.URL] = lCopy
-	s.links[lCopy.ID] = lCopy
-	return nil
+	s.insertLink(lCopy)
+}
+
+// insertLink adds a new link to the graph and its indices. The caller must
+// hold the write lock.
+func (s *InMemoryGraph) insertLink(link *graph.Link) {
+	s.linkURLIndex[link.URL] = link
+	if normURL, err := graph.NormalizeURL(link.URL); err == nil && s.normURLIndex[normURL] == nil {
+		s.normURLIndex[normURL] = link
+	}
+	s.links[link.ID] = link
+	s.insertLinkID(link.ID)
 }
 
 // FindLink looks up a link by its ID.
@@ -84,15 +142,58 @@ func (s *InMemoryGraph) FindLink(id uuid.UUID) (*graph.Link, error) {
 	return lCopy, nil
 }
 
+// FindLinkByURL looks up a link by its normalized URL.
+func (s *InMemoryGraph) FindLinkByURL(rawURL string) (*graph.Link, error) {
+	normURL, err := graph.NormalizeURL(rawURL)
+	if err != nil {
+		return nil, xerrors.Errorf("find link by URL: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link := s.normURLIndex[normURL]
+	if link == nil {
+		return nil, xerrors.Errorf("find link by URL: %w", graph.ErrNotFound)
+	}
+
+	lCopy := new(graph.Link)
+	*lCopy = *link
+	return lCopy, nil
+}
+
+// FindLinksByURL looks up the links for a batch of normalized URLs.
+func (s *InMemoryGraph) FindLinksByURL(rawURLs []string) ([]*graph.Link, error) {
+	normURLs := make([]string, len(rawURLs))
+	for i, rawURL := range rawURLs {
+		normURL, err := graph.NormalizeURL(rawURL)
+		if err != nil {
+			return nil, xerrors.Errorf("find links by URL: %w", err)
+		}
+		normURLs[i] = normURL
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := make([]*graph.Link, len(normURLs))
+	for i, normURL := range normURLs {
+		if link := s.normURLIndex[normURL]; link != nil {
+			lCopy := new(graph.Link)
+			*lCopy = *link
+			links[i] = lCopy
+		}
+	}
+	return links, nil
+}
+
 // Links returns an iterator for the set of links whose IDs belong to the
 // [fromID, toID) range and were retrieved before the provided timestamp.
 func (s *InMemoryGraph) Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error) {
-	from, to := fromID.String(), toID.String()
-
 	s.mu.RLock()
 	var list []*graph.Link
-	for linkID, link := range s.links {
-		if id := linkID.String(); id >= from && id < to && link.RetrievedAt.Before(retrievedBefore) {
+	for _, linkID := range s.linkIDRange(fromID, toID) {
+		if link := s.links[linkID]; link.RetrievedAt.Before(retrievedBefore) {
 			list = append(list, link)
 		}
 	}
@@ -106,30 +207,35 @@ func (s *InMemoryGraph) UpsertEdge(edge *graph.Edge) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.upsertEdge(edge)
+}
+
+// upsertEdge implements UpsertEdge. The caller must hold the write lock.
+func (s *InMemoryGraph) upsertEdge(edge *graph.Edge) error {
 	_, srcExists := s.links[edge.Src]
 	_, dstExists := s.links[edge.Dst]
 	if !srcExists || !dstExists {
 		return xerrors.Errorf("upsert edge: %w", graph.ErrUnknownEdgeLinks)
 	}
-
-	// Scan edge list from source
-	for _, edgeID := range s.linkEdgeMap[edge.Src] {
-		existingEdge := s.edges[edgeID]
-		if existingEdge.Src == edge.Src && existingEdge.Dst == edge.Dst {
-			existingEdge.UpdatedAt = time.Now()
-			*edge = *existingEdge
-			return nil
+	s.observeCrawlPass(edge.CrawlPassID)
+
+	// Edge IDs are derived from the link IDs so an existing edge can be
+	// looked up directly.
+	edge.ID = graph.EdgeID(edge.Src, edge.Dst)
+	s.logEdge(edge)
+	if existingEdge := s.edges[edge.ID]; existingEdge != nil {
+		existingEdge.UpdatedAt = time.Now()
+		if edge.CrawlPassID > existingEdge.CrawlPassID {
+			existingEdge.CrawlPassID = edge.CrawlPassID
 		}
-	}
-
-	// Insert new edge
-	for {
-		edge.ID = uuid.New()
-		if s.edges[edge.ID] == nil {
-			break
+		if edge.Weight != 0 {
+			existingEdge.Weight = edge.Weight
 		}
+		*edge = *existingEdge
+		return nil
 	}
 
+	// Insert new edge
 	edge.UpdatedAt = time.Now()
 	eCopy := new(graph.Edge)
 	*eCopy = *edge
@@ -141,19 +247,39 @@ func (s *InMemoryGraph) UpsertEdge(edge *graph.Edge) error {
 	return nil
 }
 
+// FindEdge looks up the edge from src to dst.
+func (s *InMemoryGraph) FindEdge(src, dst uuid.UUID) (*graph.Edge, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	edge := s.edges[graph.EdgeID(src, dst)]
+	if edge == nil {
+		return nil, xerrors.Errorf("find edge: %w", graph.ErrNotFound)
+	}
+
+	eCopy := new(graph.Edge)
+	*eCopy = *edge
+	return eCopy, nil
+}
+
+// Stats returns the number of links and edges in the graph.
+func (s *InMemoryGraph) Stats() (*graph.Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &graph.Stats{
+		Links: uint64(len(s.links)),
+		Edges: uint64(len(s.edges)),
+	}, nil
+}
+
 // Edges returns an iterator for the set of edges whose source vertex IDs
 // belong to the [fromID, toID) range and were updated before the provided
 // timestamp.
 func (s *InMemoryGraph) Edges(fromID, toID uuid.UUID, updatedBefore time.Time) (graph.EdgeIterator, error) {
-	from, to := fromID.String(), toID.String()
-
 	s.mu.RLock()
 	var list []*graph.Edge
-	for linkID := range s.links {
-		if id := linkID.String(); id < from || id >= to {
-			continue
-		}
-
+	for _, linkID := range s.linkIDRange(fromID, toID) {
 		for _, edgeID := range s.linkEdgeMap[linkID] {
 			if edge := s.edges[edgeID]; edge.UpdatedAt.Before(updatedBefore) {
 				list = append(list, edge)
@@ -171,6 +297,26 @@ func (s *InMemoryGraph) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Ti
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.removeStaleEdges(fromID, updatedBefore)
+	return nil
+}
+
+// RemoveStaleEdgesInRange removes any edge that originates from a link whose
+// ID belongs to the [fromID, toID) range and was updated before the specified
+// timestamp.
+func (s *InMemoryGraph) RemoveStaleEdgesInRange(fromID, toID uuid.UUID, updatedBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, linkID := range s.linkIDRange(fromID, toID) {
+		s.removeStaleEdges(linkID, updatedBefore)
+	}
+	return nil
+}
+
+// removeStaleEdges removes the stale edges originating from fromID. The
+// caller must hold the write lock.
+func (s *InMemoryGraph) removeStaleEdges(fromID uuid.UUID, updatedBefore time.Time) {
 	var newEdgeList edgeList
 	for _, edgeID := range s.linkEdgeMap[fromID] {
 		edge := s.edges[edgeID]
@@ -184,5 +330,32 @@ func (s *InMemoryGraph) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Ti
 
 	// Replace edge list or origin link with the filtered edge list
 	s.linkEdgeMap[fromID] = newEdgeList
-	return nil
+}
+
+// insertLinkID adds id to the sorted list of link IDs. The caller must hold
+// the write lock.
+func (s *InMemoryGraph) insertLinkID(id uuid.UUID) {
+	i := s.searchLinkID(id)
+	s.linkIDs = append(s.linkIDs, uuid.Nil)
+	copy(s.linkIDs[i+1:], s.linkIDs[i:])
+	s.linkIDs[i] = id
+}
+
+// linkIDRange returns the sorted IDs of the links that belong to the
+// [fromID, toID) range. The caller must hold the read lock and must not
+// modify the returned slice.
+func (s *InMemoryGraph) linkIDRange(fromID, toID uuid.UUID) []uuid.UUID {
+	from, to := s.searchLinkID(fromID), s.searchLinkID(toID)
+	if from >= to {
+		return nil
+	}
+	return s.linkIDs[from:to]
+}
+
+// searchLinkID returns the index of the first link ID that is greater than
+// or equal to id.
+func (s *InMemoryGraph) searchLinkID(id uuid.UUID) int {
+	return sort.Search(len(s.linkIDs), func(i int) bool {
+		return bytes.Compare(s.linkIDs[i][:], id[:]) >= 0
+	})
 }
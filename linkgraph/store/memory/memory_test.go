@@ -1,9 +1,13 @@
 package memory
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
 	"github.com/brandonshearin/ask_brandon/linkgraph/graph/graphtest"
+	"github.com/google/uuid"
 	gc "gopkg.in/check.v1"
 )
 
@@ -18,3 +22,96 @@ type InMemoryGraphTestSuite struct {
 func (s *InMemoryGraphTestSuite) SetUpTest(c *gc.C) {
 	s.SetGraph(NewInMemoryGraph())
 }
+
+func (s *InMemoryGraphTestSuite) TestLinkIDRange(c *gc.C) {
+	g := NewInMemoryGraph()
+	for i := 0; i < 50; i++ {
+		c.Assert(g.UpsertLink(&graph.Link{URL: fmt.Sprint(i)}), gc.IsNil)
+	}
+	c.Assert(g.linkIDs, gc.HasLen, 50)
+	for i := 1; i < len(g.linkIDs); i++ {
+		c.Assert(g.linkIDs[i-1].String() < g.linkIDs[i].String(), gc.Equals, true)
+	}
+
+	// Upserting an existing link must not add its ID again.
+	c.Assert(g.UpsertLink(&graph.Link{URL: "0"}), gc.IsNil)
+	c.Assert(g.linkIDs, gc.HasLen, 50)
+
+	from, to := g.linkIDs[10], g.linkIDs[20]
+	c.Assert(g.linkIDRange(from, to), gc.DeepEquals, g.linkIDs[10:20])
+	c.Assert(g.linkIDRange(to, from), gc.HasLen, 0)
+	c.Assert(g.linkIDRange(uuid.Nil, uuid.Nil), gc.HasLen, 0)
+
+	it, err := g.Links(from, to, time.Now())
+	c.Assert(err, gc.IsNil)
+	var got []uuid.UUID
+	for it.Next() {
+		got = append(got, it.Link().ID)
+	}
+	c.Assert(it.Close(), gc.IsNil)
+	c.Assert(got, gc.DeepEquals, g.linkIDs[10:20])
+}
+
+func (s *InMemoryGraphTestSuite) TestTxRemapsConcurrentlyInsertedLinks(c *gc.C) {
+	g := NewInMemoryGraph()
+	src := &graph.Link{URL: "src"}
+	c.Assert(g.UpsertLink(src), gc.IsNil)
+
+	tx, err := g.Begin()
+	c.Assert(err, gc.IsNil)
+	dst := &graph.Link{URL: "dst"}
+	c.Assert(tx.UpsertLink(dst), gc.IsNil)
+	edge := &graph.Edge{Src: src.ID, Dst: dst.ID}
+	c.Assert(tx.UpsertEdge(edge), gc.IsNil)
+
+	// Another client inserts the same link before the transaction commits
+	other := &graph.Link{URL: "dst"}
+	c.Assert(g.UpsertLink(other), gc.IsNil)
+	c.Assert(other.ID, gc.Not(gc.Equals), dst.ID)
+
+	c.Assert(tx.Commit(), gc.IsNil)
+	c.Assert(dst.ID, gc.Equals, other.ID)
+	c.Assert(edge.Dst, gc.Equals, other.ID)
+	c.Assert(g.linkIDs, gc.HasLen, 2)
+}
+
+func (s *InMemoryGraphTestSuite) TestCompact(c *gc.C) {
+	g := NewInMemoryGraph()
+	src := &graph.Link{URL: "src"}
+	c.Assert(g.UpsertLink(src), gc.IsNil)
+	for i := 0; i < 100; i++ {
+		dst := &graph.Link{URL: fmt.Sprint(i)}
+		c.Assert(g.UpsertLink(dst), gc.IsNil)
+		c.Assert(g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+	}
+	before, err := g.MemoryStats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(before.Links > 0 && before.Edges > 0 && before.Indices > 0, gc.Equals, true)
+
+	// Prune all edges
+	c.Assert(g.RemoveStaleEdges(src.ID, time.Now()), gc.IsNil)
+	pruned, err := g.MemoryStats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(pruned.Links, gc.Equals, before.Links)
+	c.Assert(pruned.Edges < before.Edges, gc.Equals, true)
+
+	g.Compact()
+	compacted, err := g.MemoryStats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(compacted.Links, gc.Equals, before.Links)
+	c.Assert(compacted.Edges, gc.Equals, pruned.Edges)
+	c.Assert(compacted.Indices < pruned.Indices, gc.Equals, true)
+	c.Assert(compacted.Total() < before.Total(), gc.Equals, true)
+	c.Assert(g.linkEdgeMap, gc.HasLen, 0)
+
+	// The graph remains usable after compaction
+	found, err := g.FindLink(src.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.URL, gc.Equals, "src")
+	dst := &graph.Link{URL: "0"}
+	c.Assert(g.UpsertLink(dst), gc.IsNil)
+	c.Assert(g.UpsertEdge(&graph.Edge{Src: src.ID, Dst: dst.ID}), gc.IsNil)
+	stats, err := g.Stats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(stats, gc.DeepEquals, &graph.Stats{Links: 101, Edges: 1})
+}
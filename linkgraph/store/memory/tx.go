@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// Compile-time check for ensuring InMemoryGraph implements Transactor.
+var _ graph.Transactor = (*InMemoryGraph)(nil)
+
+// txOp is a mutation buffered by a transaction.
+type txOp struct {
+	// link is a copy of the upserted link; origLink is the caller's link
+	// whose ID is updated if it changes when the transaction is committed.
+	link     *graph.Link
+	origLink *graph.Link
+	edge     *graph.Edge
+
+	staleFromID   uuid.UUID
+	updatedBefore time.Time
+}
+
+// inMemoryTx emulates transactions for the in-memory graph by buffering all
+// mutations and applying them while holding the graph's write lock.
+type inMemoryTx struct {
+	s *InMemoryGraph
+
+	mu   sync.Mutex
+	done bool
+	ops  []txOp
+
+	// pendingLinks tracks the links created by this transaction, keyed by
+	// URL, so they can be referenced by subsequent operations.
+	pendingLinks map[string]uuid.UUID
+	pendingIDs   map[uuid.UUID]bool
+}
+
+// Begin starts a new transaction.
+func (s *InMemoryGraph) Begin() (graph.Tx, error) {
+	return &inMemoryTx{
+		s:            s,
+		pendingLinks: make(map[string]uuid.UUID),
+		pendingIDs:   make(map[uuid.UUID]bool),
+	}, nil
+}
+
+// UpsertLink buffers a link upsert and assigns the link ID.
+func (tx *inMemoryTx) UpsertLink(link *graph.Link) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return xerrors.Errorf("upsert link: %w", graph.ErrTxDone)
+	}
+
+	if id, pending := tx.pendingLinks[link.URL]; pending {
+		link.ID = id
+	} else {
+		tx.s.mu.RLock()
+		existing := tx.s.linkURLIndex[link.URL]
+		tx.s.mu.RUnlock()
+
+		if existing != nil {
+			link.ID = existing.ID
+		} else {
+			link.ID = uuid.New()
+			tx.pendingLinks[link.URL] = link.ID
+			tx.pendingIDs[link.ID] = true
+		}
+	}
+
+	lCopy := new(graph.Link)
+	*lCopy = *link
+	tx.ops = append(tx.ops, txOp{link: lCopy, origLink: link})
+	return nil
+}
+
+// UpsertEdge buffers an edge upsert. The edge ID is populated when the
+// transaction is committed.
+func (tx *inMemoryTx) UpsertEdge(edge *graph.Edge) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return xerrors.Errorf("upsert edge: %w", graph.ErrTxDone)
+	}
+
+	tx.s.mu.RLock()
+	_, srcExists := tx.s.links[edge.Src]
+	_, dstExists := tx.s.links[edge.Dst]
+	tx.s.mu.RUnlock()
+	if !(srcExists || tx.pendingIDs[edge.Src]) || !(dstExists || tx.pendingIDs[edge.Dst]) {
+		return xerrors.Errorf("upsert edge: %w", graph.ErrUnknownEdgeLinks)
+	}
+
+	tx.ops = append(tx.ops, txOp{edge: edge})
+	return nil
+}
+
+// RemoveStaleEdges buffers the removal of stale edges.
+func (tx *inMemoryTx) RemoveStaleEdges(fromID uuid.UUID, updatedBefore time.Time) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return xerrors.Errorf("remove stale edges: %w", graph.ErrTxDone)
+	}
+
+	tx.ops = append(tx.ops, txOp{staleFromID: fromID, updatedBefore: updatedBefore})
+	return nil
+}
+
+// Commit applies all buffered mutations atomically.
+func (tx *inMemoryTx) Commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return xerrors.Errorf("commit: %w", graph.ErrTxDone)
+	}
+	tx.done = true
+
+	tx.s.mu.Lock()
+	defer tx.s.mu.Unlock()
+
+	// Another client may have inserted a link with the same URL after it
+	// was upserted by this transaction; the IDs assigned by the
+	// transaction must then be remapped to the IDs of the existing links.
+	remap := make(map[uuid.UUID]uuid.UUID)
+	for _, op := range tx.ops {
+		if op.link == nil || !tx.pendingIDs[op.link.ID] {
+			continue
+		}
+		if existing := tx.s.linkURLIndex[op.link.URL]; existing != nil {
+			remap[op.link.ID] = existing.ID
+		}
+	}
+	mapID := func(id uuid.UUID) uuid.UUID {
+		if mapped, found := remap[id]; found {
+			return mapped
+		}
+		return id
+	}
+
+	// Validate edges before applying any mutation so a failed commit
+	// leaves the graph untouched.
+	for _, op := range tx.ops {
+		if op.edge == nil {
+			continue
+		}
+		src, dst := mapID(op.edge.Src), mapID(op.edge.Dst)
+		if !tx.linkExistsOrPending(src) || !tx.linkExistsOrPending(dst) {
+			return xerrors.Errorf("commit: %w", graph.ErrUnknownEdgeLinks)
+		}
+	}
+
+	for _, op := range tx.ops {
+		switch {
+		case op.link != nil:
+			if mapped, found := remap[op.link.ID]; found {
+				op.link.ID = mapped
+				op.origLink.ID = mapped
+			}
+			tx.s.upsertTxLink(op.link)
+		case op.edge != nil:
+			op.edge.Src, op.edge.Dst = mapID(op.edge.Src), mapID(op.edge.Dst)
+			if err := tx.s.upsertEdge(op.edge); err != nil {
+				return xerrors.Errorf("commit: %w", err)
+			}
+		default:
+			tx.s.removeStaleEdges(mapID(op.staleFromID), op.updatedBefore)
+		}
+	}
+	return nil
+}
+
+// Rollback discards all buffered mutations.
+func (tx *inMemoryTx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return xerrors.Errorf("rollback: %w", graph.ErrTxDone)
+	}
+	tx.done = true
+	tx.ops = nil
+	return nil
+}
+
+// linkExistsOrPending returns true if id refers to a stored link or to a link
+// created by the transaction. The caller must hold the graph's lock.
+func (tx *inMemoryTx) linkExistsOrPending(id uuid.UUID) bool {
+	_, exists := tx.s.links[id]
+	return exists || tx.pendingIDs[id]
+}
+
+// upsertTxLink upserts a link whose ID has been assigned by a transaction.
+// The caller must hold the write lock.
+func (s *InMemoryGraph) upsertTxLink(link *graph.Link) {
+	if s.linkURLIndex[link.URL] != nil {
+		s.upsertLink(link)
+		return
+	}
+	s.observeCrawlPass(link.CrawlPassID)
+	s.logLink(link)
+
+	lCopy := new(graph.Link)
+	*lCopy = *link
+	s.insertLink(lCopy)
+}
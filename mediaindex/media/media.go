@@ -0,0 +1,59 @@
+package media
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	//ErrNotFound is returned when a media item lookup fails
+	ErrNotFound = xerrors.New("not found")
+
+	//ErrMissingURL is returned when attempting to store a media item without a URL
+	ErrMissingURL = xerrors.New("media item does not provide a URL")
+)
+
+/*Store is implemented by objects that can catalog the images and videos
+discovered while crawling web pages*/
+type Store interface {
+	/*UpsertItem creates a new media item or updates the existing item with
+	the same URL*/
+	UpsertItem(item *Item) error
+
+	/*FindByURL looks up a media item by its URL*/
+	FindByURL(url string) (*Item, error)
+
+	/*FindByPage returns the media items that were embedded in the page
+	with the specified link ID*/
+	FindByPage(pageLinkID uuid.UUID) ([]*Item, error)
+}
+
+/*Type describes the kind of media an item points to*/
+type Type uint8
+
+/*The media types that are recognized by the crawler*/
+const (
+	TypeImage Type = iota
+	TypeVideo
+	TypeAudio
+)
+
+/*Item is a media resource (image, video etc.) referenced by a crawled page*/
+type Item struct {
+	ID  uuid.UUID
+	URL string
+
+	Type Type
+
+	/*AltText holds the alt attribute for images which is the closest thing
+	to a textual description that we can get without fetching the item*/
+	AltText string
+
+	/*the page that the item was discovered in*/
+	PageLinkID uuid.UUID
+	PageURL    string
+
+	DiscoveredAt time.Time
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/brandonshearin/ask_brandon/mediaindex/media"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// Compile-time check for ensuring InMemoryMediaStore implements media.Store.
+var _ media.Store = (*InMemoryMediaStore)(nil)
+
+// InMemoryMediaStore implements an in-memory media catalog that can be
+// concurrently accessed by multiple clients.
+type InMemoryMediaStore struct {
+	mu sync.RWMutex
+
+	items map[string]*media.Item
+
+	// pageIndex maps a page link ID to the URLs of the items it embeds.
+	pageIndex map[uuid.UUID][]string
+}
+
+// NewInMemoryMediaStore creates a new in-memory media store.
+func NewInMemoryMediaStore() *InMemoryMediaStore {
+	return &InMemoryMediaStore{
+		items:     make(map[string]*media.Item),
+		pageIndex: make(map[uuid.UUID][]string),
+	}
+}
+
+// UpsertItem creates a new media item or updates the existing item with the
+// same URL.
+func (s *InMemoryMediaStore) UpsertItem(item *media.Item) error {
+	if item.URL == "" {
+		return xerrors.Errorf("upsert item: %w", media.ErrMissingURL)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing := s.items[item.URL]; existing != nil {
+		item.ID = existing.ID
+		if item.AltText == "" {
+			item.AltText = existing.AltText
+		}
+		s.addToPage(item.PageLinkID, item.URL)
+		*existing = *item
+		return nil
+	}
+
+	item.ID = uuid.New()
+	iCopy := new(media.Item)
+	*iCopy = *item
+	s.items[iCopy.URL] = iCopy
+	s.addToPage(iCopy.PageLinkID, iCopy.URL)
+	return nil
+}
+
+// addToPage associates url with a page unless it is already associated with it.
+// Callers must hold the write lock.
+func (s *InMemoryMediaStore) addToPage(pageLinkID uuid.UUID, url string) {
+	for _, existing := range s.pageIndex[pageLinkID] {
+		if existing == url {
+			return
+		}
+	}
+	s.pageIndex[pageLinkID] = append(s.pageIndex[pageLinkID], url)
+}
+
+// FindByURL looks up a media item by its URL.
+func (s *InMemoryMediaStore) FindByURL(url string) (*media.Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item := s.items[url]
+	if item == nil {
+		return nil, xerrors.Errorf("find by URL: %w", media.ErrNotFound)
+	}
+
+	iCopy := new(media.Item)
+	*iCopy = *item
+	return iCopy, nil
+}
+
+// FindByPage returns the media items that were embedded in the page with
+// the specified link ID.
+func (s *InMemoryMediaStore) FindByPage(pageLinkID uuid.UUID) ([]*media.Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []*media.Item
+	for _, url := range s.pageIndex[pageLinkID] {
+		iCopy := new(media.Item)
+		*iCopy = *s.items[url]
+		list = append(list, iCopy)
+	}
+	return list, nil
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/mediaindex/media"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(InMemoryMediaStoreTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type InMemoryMediaStoreTestSuite struct {
+	s *InMemoryMediaStore
+}
+
+func (s *InMemoryMediaStoreTestSuite) SetUpTest(c *gc.C) {
+	s.s = NewInMemoryMediaStore()
+}
+
+func (s *InMemoryMediaStoreTestSuite) TestUpsertItem(c *gc.C) {
+	pageID := uuid.New()
+	item := &media.Item{
+		URL:        "https://example.com/gopher.png",
+		AltText:    "a gopher",
+		PageLinkID: pageID,
+	}
+	c.Assert(s.s.UpsertItem(item), gc.IsNil)
+	c.Assert(item.ID, gc.Not(gc.Equals), uuid.Nil)
+
+	// Upserting the same URL without alt text keeps the original ID and alt text
+	dup := &media.Item{URL: item.URL, PageLinkID: pageID}
+	c.Assert(s.s.UpsertItem(dup), gc.IsNil)
+	c.Assert(dup.ID, gc.Equals, item.ID)
+
+	got, err := s.s.FindByURL(item.URL)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.AltText, gc.Equals, "a gopher")
+
+	_, err = s.s.FindByURL("https://example.com/missing.png")
+	c.Assert(xerrors.Is(err, media.ErrNotFound), gc.Equals, true)
+
+	err = s.s.UpsertItem(&media.Item{})
+	c.Assert(xerrors.Is(err, media.ErrMissingURL), gc.Equals, true)
+}
+
+func (s *InMemoryMediaStoreTestSuite) TestFindByPage(c *gc.C) {
+	pageID := uuid.New()
+	for _, url := range []string{"https://example.com/a.png", "https://example.com/b.mp4"} {
+		c.Assert(s.s.UpsertItem(&media.Item{URL: url, PageLinkID: pageID}), gc.IsNil)
+	}
+	c.Assert(s.s.UpsertItem(&media.Item{URL: "https://example.com/c.png", PageLinkID: uuid.New()}), gc.IsNil)
+
+	items, err := s.s.FindByPage(pageID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(items, gc.HasLen, 2)
+	c.Assert(items[0].URL, gc.Equals, "https://example.com/a.png")
+	c.Assert(items[1].URL, gc.Equals, "https://example.com/b.mp4")
+}
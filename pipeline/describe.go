@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+//Description is a structural description of a pipeline together with the
+//live statistics of its stages.  It can be serialized to JSON or, using DOT,
+//rendered as a graph
+type Description struct {
+	Stages []StageDescription `json:"stages"`
+}
+
+//StageDescription describes a single pipeline stage
+type StageDescription struct {
+	//Index is the position of the stage in the pipeline
+	Index int `json:"index"`
+
+	//Runner is the kind of StageRunner (e.g. "fifo" or "dynamic_worker_pool").
+	//Runners that do not implement StageDescriber are reported by their Go type
+	Runner string `json:"runner"`
+
+	//Workers is the (maximum) number of payloads that the stage processes
+	//concurrently or zero if unknown
+	Workers int `json:"workers,omitempty"`
+
+	//Processors lists the Go types of the processors used by the stage
+	Processors []string `json:"processors,omitempty"`
+
+	Stats StageStats `json:"stats"`
+}
+
+//StageStats holds the counters of a stage since the pipeline was created.
+//Processed payloads include the ones that were discarded by the processor
+type StageStats struct {
+	Processed uint64 `json:"processed"`
+	Discarded uint64 `json:"discarded"`
+	Errors    uint64 `json:"errors"`
+	InFlight  int64  `json:"in_flight"`
+}
+
+//StageDescriber is implemented by StageRunners that can describe themselves.
+//All runners provided by this package implement it
+type StageDescriber interface {
+	Describe() StageDescription
+}
+
+//Describe returns a description of the stages of the pipeline and their
+//current statistics.  It is safe to call Describe while the pipeline is
+//processing payloads
+func (p *Pipeline) Describe() *Description {
+	d := &Description{Stages: make([]StageDescription, len(p.stages))}
+	for i, stage := range p.stages {
+		if describer, ok := stage.(StageDescriber); ok {
+			d.Stages[i] = describer.Describe()
+		} else {
+			d.Stages[i].Runner = fmt.Sprintf("%T", stage)
+		}
+		d.Stages[i].Index = i
+	}
+	return d
+}
+
+//DOT renders the pipeline as a graph in the Graphviz DOT language with the
+//specified graph name
+func (d *Description) DOT(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", strconv.Quote(name))
+	b.WriteString("\trankdir=LR;\n\tnode [shape=box];\n")
+	b.WriteString("\tsource [shape=oval];\n")
+	for _, stage := range d.Stages {
+		fmt.Fprintf(&b, "\tstage%d [label=%s];\n", stage.Index, strconv.Quote(stage.label()))
+	}
+	b.WriteString("\tsink [shape=oval];\n")
+
+	b.WriteString("\tsource")
+	for _, stage := range d.Stages {
+		fmt.Fprintf(&b, " -> stage%d", stage.Index)
+	}
+	b.WriteString(" -> sink;\n}\n")
+	return b.String()
+}
+
+//label returns the DOT node label of the stage
+func (s StageDescription) label() string {
+	lines := []string{fmt.Sprintf("%d: %s", s.Index, s.Runner)}
+	if s.Workers > 1 {
+		lines[0] += fmt.Sprintf(" (%d workers)", s.Workers)
+	}
+	lines = append(lines, s.Processors...)
+	lines = append(lines, fmt.Sprintf("processed=%d discarded=%d errors=%d in_flight=%d",
+		s.Stats.Processed, s.Stats.Discarded, s.Stats.Errors, s.Stats.InFlight))
+	return strings.Join(lines, "\n")
+}
+
+//stageCounters keeps track of the statistics of a stage.  It is safe for
+//concurrent use
+type stageCounters struct {
+	processed uint64
+	discarded uint64
+	errors    uint64
+	inFlight  int64
+}
+
+//begin records that a payload is being processed
+func (c *stageCounters) begin() { atomic.AddInt64(&c.inFlight, 1) }
+
+//end records the outcome of processing a payload
+func (c *stageCounters) end(out Payload, err error) {
+	atomic.AddInt64(&c.inFlight, -1)
+	switch {
+	case err != nil:
+		atomic.AddUint64(&c.errors, 1)
+	case out == nil:
+		atomic.AddUint64(&c.processed, 1)
+		atomic.AddUint64(&c.discarded, 1)
+	default:
+		atomic.AddUint64(&c.processed, 1)
+	}
+}
+
+//addTo adds the counters to stats
+func (c *stageCounters) addTo(stats *StageStats) {
+	stats.Processed += atomic.LoadUint64(&c.processed)
+	stats.Discarded += atomic.LoadUint64(&c.discarded)
+	stats.Errors += atomic.LoadUint64(&c.errors)
+	stats.InFlight += atomic.LoadInt64(&c.inFlight)
+}
+
+//processorName returns the name under which proc is described
+func processorName(proc Processor) string {
+	return fmt.Sprintf("%T", proc)
+}
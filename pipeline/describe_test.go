@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(DescribeTestSuite))
+
+type DescribeTestSuite struct{}
+
+func (s *DescribeTestSuite) TestDescribe(c *gc.C) {
+	dropFirst := ProcessorFunc(func(_ context.Context, p Payload) (Payload, error) {
+		if p.(*stringPayload).val == "0" {
+			return nil, nil
+		}
+		return p, nil
+	})
+	p := New(
+		FIFO(makePassthroughProcessor()),
+		FixedWorkerPool(makePassthroughProcessor(), 3),
+		DynamicWorkerPool(dropFirst, 2),
+		Broadcast(makePassthroughProcessor(), makePassthroughProcessor()),
+		testStage{c: c},
+	)
+
+	src := &sourceStub{data: stringPayloads(3)}
+	c.Assert(p.Process(context.TODO(), src, new(sinkStub)), gc.IsNil)
+
+	procName := "pipeline.ProcessorFunc"
+	c.Assert(p.Describe(), gc.DeepEquals, &Description{Stages: []StageDescription{
+		{
+			Index:      0,
+			Runner:     "fifo",
+			Workers:    1,
+			Processors: []string{procName},
+			Stats:      StageStats{Processed: 3},
+		},
+		{
+			Index:      1,
+			Runner:     "fixed_worker_pool",
+			Workers:    3,
+			Processors: []string{procName},
+			Stats:      StageStats{Processed: 3},
+		},
+		{
+			Index:      2,
+			Runner:     "dynamic_worker_pool",
+			Workers:    2,
+			Processors: []string{procName},
+			Stats:      StageStats{Processed: 3, Discarded: 1},
+		},
+		{
+			Index:      3,
+			Runner:     "broadcast",
+			Workers:    2,
+			Processors: []string{procName, procName},
+			Stats:      StageStats{Processed: 4},
+		},
+		{
+			Index:  4,
+			Runner: "pipeline.testStage",
+		},
+	}})
+}
+
+func (s *DescribeTestSuite) TestDescriptionFormats(c *gc.C) {
+	d := New(FixedWorkerPool(makePassthroughProcessor(), 2), testStage{c: c}).Describe()
+	d.Stages[0].Stats.Errors = 1
+
+	data, err := json.Marshal(d)
+	c.Assert(err, gc.IsNil)
+	c.Assert(string(data), gc.Equals, `{"stages":[`+
+		`{"index":0,"runner":"fixed_worker_pool","workers":2,"processors":["pipeline.ProcessorFunc"],"stats":{"processed":0,"discarded":0,"errors":1,"in_flight":0}},`+
+		`{"index":1,"runner":"pipeline.testStage","stats":{"processed":0,"discarded":0,"errors":0,"in_flight":0}}]}`)
+
+	dot := d.DOT("crawler")
+	c.Assert(strings.HasPrefix(dot, `digraph "crawler" {`), gc.Equals, true)
+	c.Assert(dot, gc.Matches, `(?s).*stage0 \[label="0: fixed_worker_pool \(2 workers\)\\npipeline.ProcessorFunc\\nprocessed=0 discarded=0 errors=1 in_flight=0"\];.*`)
+	c.Assert(dot, gc.Matches, `(?s).*source -> stage0 -> stage1 -> sink;.*`)
+}
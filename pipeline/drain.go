@@ -0,0 +1,32 @@
+package pipeline
+
+import "context"
+
+/*DrainingSource wraps src so that it stops yielding payloads once drainCtx
+expires.  Payloads that have already been emitted keep flowing through the
+pipeline, so passing a context that outlives drainCtx to Process allows
+in-flight work to complete before Process returns:
+
+	src := pipeline.DrainingSource(shutdownCtx, source)
+	err := p.Process(context.Background(), src, sink)
+*/
+func DrainingSource(drainCtx context.Context, src Source) Source {
+	return &drainingSource{drainCtx: drainCtx, src: src}
+}
+
+type drainingSource struct {
+	drainCtx context.Context
+	src      Source
+}
+
+//Next returns false without consulting the wrapped source once the drain
+//context has expired
+func (s *drainingSource) Next(ctx context.Context) bool {
+	if s.drainCtx.Err() != nil {
+		return false
+	}
+	return s.src.Next(ctx)
+}
+
+func (s *drainingSource) Payload() Payload { return s.src.Payload() }
+func (s *drainingSource) Error() error     { return s.src.Error() }
@@ -4,8 +4,9 @@ import "context"
 
 // Payload is implemented by values that can be sent through a pipeline.
 type Payload interface {
-	// Clone returns a new Payload that is a deep-copy of the original.
-	Clone() Payload
+	// Clone returns a new Payload that is a deep-copy of the original or
+	// an error if the copy cannot be created.
+	Clone() (Payload, error)
 
 	// MarkAsProcessed is invoked by the pipeline when the Payload either
 	// reaches the pipeline sink or it gets discarded by one of the
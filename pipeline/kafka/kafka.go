@@ -0,0 +1,44 @@
+/*Package kafka provides pipeline Source and Sink adapters backed by Kafka
+topics.  They allow the stages of a pipeline to be split across separate
+services (e.g. a fleet of crawlers consuming links published by the graph
+updater) which scale horizontally by joining the same consumer group.
+
+The adapters do not depend on a particular Kafka client.  Instead, they
+operate on the minimal Reader and Writer interfaces below which are trivial to
+implement on top of clients such as segmentio/kafka-go or sarama.*/
+package kafka
+
+import (
+	"context"
+	"time"
+)
+
+//Message is a Kafka record
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string][]byte
+	Time      time.Time
+}
+
+//Reader is implemented by Kafka consumer group members
+type Reader interface {
+	/*FetchMessage blocks until the next message is available or ctx
+	expires.  Fetching a message does not commit its offset.  Readers
+	that have been closed return io.EOF*/
+	FetchMessage(ctx context.Context) (Message, error)
+
+	//CommitMessages commits the offsets of the provided messages for the
+	//consumer group
+	CommitMessages(ctx context.Context, msgs ...Message) error
+}
+
+//Writer is implemented by Kafka producers
+type Writer interface {
+	//WriteMessages publishes the provided messages, blocking until they
+	//have been acknowledged by the brokers
+	WriteMessages(ctx context.Context, msgs ...Message) error
+}
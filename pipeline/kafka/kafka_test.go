@@ -0,0 +1,208 @@
+package kafka
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(KafkaTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type KafkaTestSuite struct{}
+
+func (s *KafkaTestSuite) TestRoundTrip(c *gc.C) {
+	reader := &readerStub{msgs: []Message{
+		{Topic: "links", Partition: 0, Offset: 0, Value: []byte("http://a.com")},
+		{Topic: "links", Partition: 1, Offset: 0, Value: []byte("http://b.com")},
+		{Topic: "links", Partition: 0, Offset: 1, Value: []byte("http://c.com")},
+	}}
+	writer := new(writerStub)
+
+	src, err := NewSource(SourceConfig{Reader: reader, Decode: decodeURL})
+	c.Assert(err, gc.IsNil)
+	sink, err := NewSink(SinkConfig{Writer: writer, Topic: "docs", Encode: encodeURL})
+	c.Assert(err, gc.IsNil)
+
+	p := pipeline.New(pipeline.FIFO(pipeline.ProcessorFunc(func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+		return p, nil
+	})))
+	err = p.Process(context.TODO(), src, sink)
+	c.Assert(err, gc.IsNil)
+	c.Assert(src.Commit(context.TODO()), gc.IsNil)
+
+	var published []string
+	for _, msg := range writer.msgs {
+		c.Assert(msg.Topic, gc.Equals, "docs")
+		published = append(published, string(msg.Value))
+	}
+	c.Assert(published, gc.DeepEquals, []string{"http://a.com", "http://b.com", "http://c.com"})
+	c.Assert(reader.committedOffsets(), gc.DeepEquals, map[int]int64{0: 1, 1: 0})
+}
+
+func (s *KafkaTestSuite) TestCommitWaitsForEarlierOffsets(c *gc.C) {
+	reader := &readerStub{msgs: []Message{
+		{Partition: 0, Offset: 0},
+		{Partition: 0, Offset: 1},
+		{Partition: 0, Offset: 2},
+	}}
+
+	var acks []func()
+	src, err := NewSource(SourceConfig{
+		Reader: reader,
+		Decode: func(msg Message, ack func()) (pipeline.Payload, error) {
+			acks = append(acks, ack)
+			return &urlPayload{ack: ack}, nil
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	for i := 0; i < 3; i++ {
+		c.Assert(src.Next(context.TODO()), gc.Equals, true)
+	}
+
+	//Offsets 1 and 2 cannot be committed until offset 0 is processed
+	acks[2]()
+	acks[1]()
+	c.Assert(src.Commit(context.TODO()), gc.IsNil)
+	c.Assert(reader.committedOffsets(), gc.HasLen, 0)
+
+	acks[0]()
+	c.Assert(src.Commit(context.TODO()), gc.IsNil)
+	c.Assert(reader.committedOffsets(), gc.DeepEquals, map[int]int64{0: 2})
+}
+
+func (s *KafkaTestSuite) TestSkipMessages(c *gc.C) {
+	reader := &readerStub{msgs: []Message{
+		{Partition: 0, Offset: 0},
+		{Partition: 0, Offset: 1, Value: []byte("http://a.com")},
+	}}
+	src, err := NewSource(SourceConfig{Reader: reader, Decode: decodeURL})
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(src.Next(context.TODO()), gc.Equals, true)
+	c.Assert(src.Payload().(*urlPayload).url, gc.Equals, "http://a.com")
+	c.Assert(src.Commit(context.TODO()), gc.IsNil)
+	c.Assert(reader.committedOffsets(), gc.DeepEquals, map[int]int64{0: 0})
+}
+
+func (s *KafkaTestSuite) TestSourceErrors(c *gc.C) {
+	reader := &readerStub{msgs: []Message{{Topic: "links", Partition: 3, Offset: 7}}}
+	src, err := NewSource(SourceConfig{
+		Reader: reader,
+		Decode: func(Message, func()) (pipeline.Payload, error) {
+			return nil, xerrors.New("malformed")
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(src.Next(context.TODO()), gc.Equals, false)
+	c.Assert(src.Error(), gc.ErrorMatches, "decode message at links/3/7: malformed")
+
+	reader = &readerStub{fetchErr: xerrors.New("broker unavailable")}
+	src, err = NewSource(SourceConfig{Reader: reader, Decode: decodeURL})
+	c.Assert(err, gc.IsNil)
+	c.Assert(src.Next(context.TODO()), gc.Equals, false)
+	c.Assert(src.Error(), gc.ErrorMatches, "fetch message: broker unavailable")
+}
+
+func (s *KafkaTestSuite) TestSinkErrors(c *gc.C) {
+	sink, err := NewSink(SinkConfig{
+		Writer: &writerStub{err: xerrors.New("not enough replicas")},
+		Encode: encodeURL,
+	})
+	c.Assert(err, gc.IsNil)
+	err = sink.Consume(context.TODO(), &urlPayload{url: "http://a.com"})
+	c.Assert(err, gc.ErrorMatches, "write messages: not enough replicas")
+
+	//Payloads that encode to no messages are dropped
+	c.Assert(sink.Consume(context.TODO(), &urlPayload{}), gc.IsNil)
+}
+
+func (s *KafkaTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewSource(SourceConfig{Decode: decodeURL})
+	c.Assert(err, gc.ErrorMatches, ".*reader has not been provided")
+	_, err = NewSink(SinkConfig{Writer: new(writerStub)})
+	c.Assert(err, gc.ErrorMatches, ".*encode function has not been provided")
+}
+
+type urlPayload struct {
+	url string
+	ack func()
+}
+
+func (p *urlPayload) Clone() (pipeline.Payload, error) {
+	return &urlPayload{url: p.url, ack: func() {}}, nil
+}
+func (p *urlPayload) MarkAsProcessed()        { p.ack() }
+
+func decodeURL(msg Message, ack func()) (pipeline.Payload, error) {
+	if len(msg.Value) == 0 {
+		return nil, nil
+	}
+	return &urlPayload{url: string(msg.Value), ack: ack}, nil
+}
+
+func encodeURL(p pipeline.Payload) ([]Message, error) {
+	url := p.(*urlPayload).url
+	if url == "" {
+		return nil, nil
+	}
+	return []Message{{Value: []byte(url)}}, nil
+}
+
+//readerStub serves a fixed list of messages and then behaves like a closed
+//reader so that pipelines under test terminate.
+type readerStub struct {
+	mu        sync.Mutex
+	msgs      []Message
+	fetchErr  error
+	committed []Message
+}
+
+func (r *readerStub) FetchMessage(ctx context.Context) (Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fetchErr != nil {
+		return Message{}, r.fetchErr
+	} else if len(r.msgs) == 0 {
+		return Message{}, io.EOF
+	}
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return msg, nil
+}
+
+func (r *readerStub) CommitMessages(_ context.Context, msgs ...Message) error {
+	r.mu.Lock()
+	r.committed = append(r.committed, msgs...)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *readerStub) committedOffsets() map[int]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	offsets := make(map[int]int64)
+	for _, msg := range r.committed {
+		offsets[msg.Partition] = msg.Offset
+	}
+	return offsets
+}
+
+type writerStub struct {
+	msgs []Message
+	err  error
+}
+
+func (w *writerStub) WriteMessages(_ context.Context, msgs ...Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+)
+
+//EncodeFunc converts a pipeline payload into the messages to be published.
+//Returning no messages drops the payload
+type EncodeFunc func(pipeline.Payload) ([]Message, error)
+
+//SinkConfig encapsulates the settings for a Kafka-backed Sink
+type SinkConfig struct {
+	//Writer is used for publishing messages
+	Writer Writer
+
+	//Topic, if specified, is set on messages that do not specify one
+	Topic string
+
+	//Encode converts payloads into messages
+	Encode EncodeFunc
+}
+
+func (cfg *SinkConfig) validate() error {
+	if cfg.Writer == nil {
+		return xerrors.New("writer has not been provided")
+	}
+	if cfg.Encode == nil {
+		return xerrors.New("encode function has not been provided")
+	}
+	return nil
+}
+
+//Sink is a pipeline.Sink that publishes the payloads that reach it to Kafka.
+//Consume blocks until the messages have been acknowledged so that payloads
+//are only marked as processed once they have been durably published
+type Sink struct {
+	cfg SinkConfig
+}
+
+//NewSink creates a new Kafka-backed Sink with the specified config
+func NewSink(cfg SinkConfig) (*Sink, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("kafka sink: config validation failed: %w", err)
+	}
+	return &Sink{cfg: cfg}, nil
+}
+
+//Consume implements pipeline.Sink
+func (s *Sink) Consume(ctx context.Context, p pipeline.Payload) error {
+	msgs, err := s.cfg.Encode(p)
+	if err != nil {
+		return xerrors.Errorf("encode payload: %w", err)
+	} else if len(msgs) == 0 {
+		return nil
+	}
+
+	for i := range msgs {
+		if msgs[i].Topic == "" {
+			msgs[i].Topic = s.cfg.Topic
+		}
+	}
+	if err = s.cfg.Writer.WriteMessages(ctx, msgs...); err != nil {
+		return xerrors.Errorf("write messages: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+)
+
+//DecodeFunc converts a Kafka message into a pipeline payload.  The ack
+//callback must be invoked once the payload has been fully processed,
+//typically from the payload's MarkAsProcessed method.  Messages that cannot be
+//decoded can be skipped by returning a nil payload
+type DecodeFunc func(msg Message, ack func()) (pipeline.Payload, error)
+
+//SourceConfig encapsulates the settings for a Kafka-backed Source
+type SourceConfig struct {
+	//Reader is used for consuming messages
+	Reader Reader
+
+	//Decode converts consumed messages into payloads
+	Decode DecodeFunc
+}
+
+func (cfg *SourceConfig) validate() error {
+	if cfg.Reader == nil {
+		return xerrors.New("reader has not been provided")
+	}
+	if cfg.Decode == nil {
+		return xerrors.New("decode function has not been provided")
+	}
+	return nil
+}
+
+/*Source is a pipeline.Source that emits the payloads decoded from the
+messages of a Kafka topic.
+
+Offsets are only committed once all the payloads decoded from a partition up
+to that offset have been acknowledged.  This provides at-least-once
+semantics: if the service crashes, unacknowledged messages are redelivered to
+another member of the consumer group.  Next returns false once its context
+expires or the reader returns io.EOF (i.e. it has been closed) which makes the
+source suitable for long-running pipelines that are stopped via context
+cancellation*/
+type Source struct {
+	cfg SourceConfig
+
+	mu      sync.Mutex
+	pending map[int]*partitionOffsets
+
+	payload pipeline.Payload
+	err     error
+}
+
+//NewSource creates a new Kafka-backed Source with the specified config
+func NewSource(cfg SourceConfig) (*Source, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("kafka source: config validation failed: %w", err)
+	}
+	return &Source{cfg: cfg, pending: make(map[int]*partitionOffsets)}, nil
+}
+
+//Next implements pipeline.Source
+func (s *Source) Next(ctx context.Context) bool {
+	for {
+		if err := s.Commit(ctx); err != nil {
+			if ctx.Err() == nil {
+				s.err = err
+			}
+			return false
+		}
+
+		msg, err := s.cfg.Reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() == nil && err != io.EOF {
+				s.err = xerrors.Errorf("fetch message: %w", err)
+			}
+			return false
+		}
+
+		ack := s.track(msg)
+		payload, err := s.cfg.Decode(msg, ack)
+		if err != nil {
+			s.err = xerrors.Errorf("decode message at %s/%d/%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+			return false
+		} else if payload == nil {
+			//skipped messages do not hold back the committed offset
+			ack()
+			continue
+		}
+
+		s.payload = payload
+		return true
+	}
+}
+
+//Payload implements pipeline.Source
+func (s *Source) Payload() pipeline.Payload { return s.payload }
+
+//Error implements pipeline.Source
+func (s *Source) Error() error { return s.err }
+
+/*Commit commits the offsets of all the messages whose payloads, along with the
+payloads of all earlier messages in the same partition, have been
+acknowledged.  It is invoked automatically by Next but should also be called
+once the pipeline has returned to commit the offsets of the final payloads*/
+func (s *Source) Commit(ctx context.Context) error {
+	s.mu.Lock()
+	var toCommit []Message
+	for _, p := range s.pending {
+		if msg, ok := p.popAcked(); ok {
+			toCommit = append(toCommit, msg)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(toCommit) == 0 {
+		return nil
+	}
+	sort.Slice(toCommit, func(i, j int) bool { return toCommit[i].Partition < toCommit[j].Partition })
+	if err := s.cfg.Reader.CommitMessages(ctx, toCommit...); err != nil {
+		return xerrors.Errorf("commit messages: %w", err)
+	}
+	return nil
+}
+
+//track registers msg as in-flight and returns a callback for acknowledging it
+func (s *Source) track(msg Message) func() {
+	s.mu.Lock()
+	p := s.pending[msg.Partition]
+	if p == nil {
+		p = new(partitionOffsets)
+		s.pending[msg.Partition] = p
+	}
+	entry := p.push(msg)
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			entry.acked = true
+			s.mu.Unlock()
+		})
+	}
+}
+
+//partitionOffsets keeps track of the in-flight messages of a partition in the
+//order they were fetched
+type partitionOffsets struct {
+	entries []*offsetEntry
+}
+
+type offsetEntry struct {
+	msg   Message
+	acked bool
+}
+
+func (p *partitionOffsets) push(msg Message) *offsetEntry {
+	entry := &offsetEntry{msg: msg}
+	p.entries = append(p.entries, entry)
+	return entry
+}
+
+//popAcked removes the longest prefix of acknowledged messages and returns the
+//last one, which is the message whose offset should be committed
+func (p *partitionOffsets) popAcked() (Message, bool) {
+	var n int
+	for n < len(p.entries) && p.entries[n].acked {
+		n++
+	}
+	if n == 0 {
+		return Message{}, false
+	}
+
+	last := p.entries[n-1].msg
+	p.entries = append(p.entries[:0], p.entries[n:]...)
+	return last, true
+}
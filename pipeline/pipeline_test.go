@@ -77,3 +77,29 @@ func (s *PipelineTestSuite) TestPayloadDiscarding(c *gc.C) {
 	c.Assert(sink.data, gc.HasLen, 0, gc.Commentf("expected all payloads to be discarded by stage processor"))
 	assertAllProcessed(c, src.data)
 }
+
+func (s *PipelineTestSuite) TestDrainingSource(c *gc.C) {
+	drainCtx, drain := context.WithCancel(context.TODO())
+	defer drain()
+
+	src := &sourceStub{data: stringPayloads(100)}
+	sink := &drainingSinkStub{drain: drain}
+
+	p := New(testStage{c: c})
+	err := p.Process(context.TODO(), DrainingSource(drainCtx, src), sink)
+	c.Assert(err, gc.IsNil)
+	c.Assert(src.index < len(src.data), gc.Equals, true, gc.Commentf("expected source to stop emitting payloads once drained"))
+	c.Assert(sink.data, gc.DeepEquals, src.data[:src.index], gc.Commentf("expected in-flight payloads to reach the sink"))
+	assertAllProcessed(c, src.data[:src.index])
+}
+
+//drainingSinkStub initiates a drain when it consumes its first payload
+type drainingSinkStub struct {
+	sinkStub
+	drain func()
+}
+
+func (s *drainingSinkStub) Consume(ctx context.Context, p Payload) error {
+	s.drain()
+	return s.sinkStub.Consume(ctx, p)
+}
@@ -0,0 +1,161 @@
+/*Package redis provides pipeline Source and Sink adapters backed by Redis
+streams.  They offer a lightweight alternative to the kafka adapters for small
+deployments: crawl work published to a stream is distributed among the
+members of a consumer group and acknowledged once processed.
+
+The adapters talk to Redis through the Client interface.  A minimal client
+implementation that speaks the RESP protocol is provided by Dial.*/
+package redis
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+//Client is implemented by types that can execute Redis commands
+type Client interface {
+	/*Do executes a command and returns its reply.  Replies are mapped to
+	string (simple and bulk strings), int64, []interface{} or nil.  Error
+	replies are returned as an Error*/
+	Do(ctx context.Context, args ...string) (interface{}, error)
+}
+
+//Error is an error reply returned by the Redis server
+type Error string
+
+//Error implements error
+func (e Error) Error() string { return string(e) }
+
+//Conn is a Client backed by a single connection to a Redis server.  It is
+//safe for concurrent use; commands are executed one at a time
+type Conn struct {
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+//Dial connects to the Redis server at the specified address
+func Dial(ctx context.Context, addr string) (*Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, xerrors.Errorf("redis: dial %s: %w", addr, err)
+	}
+	return NewConn(conn), nil
+}
+
+//NewConn wraps an existing network connection to a Redis server
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+}
+
+//Close closes the underlying connection
+func (c *Conn) Close() error { return c.conn.Close() }
+
+//Do implements Client
+func (c *Conn) Do(ctx context.Context, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	//interrupt blocked reads and writes if the context expires
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetDeadline(deadline)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	if err := writeCommand(c.w, args); err != nil {
+		return nil, c.fail(ctx, err)
+	}
+	reply, err := readReply(c.r)
+	if err != nil {
+		if _, isReplyErr := err.(Error); isReplyErr {
+			return nil, err
+		}
+		return nil, c.fail(ctx, err)
+	}
+	return reply, nil
+}
+
+//fail reports a connection error.  Once a command has been interrupted, the
+//connection is out of sync with the server and cannot be reused
+func (c *Conn) fail(ctx context.Context, err error) error {
+	_ = c.conn.Close()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return xerrors.Errorf("redis: %w", err)
+}
+
+func writeCommand(w *bufio.Writer, args []string) error {
+	_, _ = w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		_, _ = w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		_, _ = w.WriteString(arg)
+		_, _ = w.WriteString("\r\n")
+	}
+	return w.Flush()
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	} else if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, xerrors.Errorf("malformed reply %q", line)
+	}
+
+	kind, payload := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, Error(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			//nested error replies are returned as values
+			item, err := readReply(r)
+			if replyErr, isReplyErr := err.(Error); isReplyErr {
+				item, err = replyErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, xerrors.Errorf("unsupported reply type %q", kind)
+	}
+}
@@ -0,0 +1,285 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RedisTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type RedisTestSuite struct{}
+
+func (s *RedisTestSuite) TestRoundTrip(c *gc.C) {
+	client := newStreamStub()
+	sink, err := NewSink(SinkConfig{Client: client, Stream: "links", MaxLen: 1000, Encode: encodeURL})
+	c.Assert(err, gc.IsNil)
+	for _, u := range []string{"http://a.com", "http://b.com", "http://c.com"} {
+		c.Assert(sink.Consume(context.TODO(), &urlPayload{url: u}), gc.IsNil)
+	}
+
+	src, err := NewSource(SourceConfig{Client: client, Stream: "links", Group: "crawlers", Consumer: "c1", Decode: decodeURL, BatchSize: 2})
+	c.Assert(err, gc.IsNil)
+
+	var got []string
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	for len(got) < 3 && src.Next(ctx) {
+		p := src.Payload().(*urlPayload)
+		got = append(got, p.url)
+		p.MarkAsProcessed()
+	}
+	c.Assert(src.Error(), gc.IsNil)
+	c.Assert(got, gc.DeepEquals, []string{"http://a.com", "http://b.com", "http://c.com"})
+
+	c.Assert(src.Commit(context.TODO()), gc.IsNil)
+	c.Assert(client.pendingIDs("c1"), gc.HasLen, 0)
+
+	//Once drained, Next blocks until the context expires
+	cancel()
+	c.Assert(src.Next(ctx), gc.Equals, false)
+	c.Assert(src.Error(), gc.IsNil)
+}
+
+func (s *RedisTestSuite) TestReplayPendingEntries(c *gc.C) {
+	client := newStreamStub()
+	client.add("http://a.com")
+	client.add("http://b.com")
+	client.add("http://c.com")
+
+	//The first instance crashes after receiving two entries without
+	//processing them
+	src, err := NewSource(SourceConfig{Client: client, Stream: "links", Group: "crawlers", Consumer: "c1", Decode: decodeURL, BatchSize: 2})
+	c.Assert(err, gc.IsNil)
+	c.Assert(src.Next(context.TODO()), gc.Equals, true)
+	c.Assert(client.pendingIDs("c1"), gc.HasLen, 2)
+
+	//Its replacement replays them before reading new entries
+	src, err = NewSource(SourceConfig{Client: client, Stream: "links", Group: "crawlers", Consumer: "c1", Decode: decodeURL, BatchSize: 1})
+	c.Assert(err, gc.IsNil)
+
+	var got []string
+	for len(got) < 3 && src.Next(context.TODO()) {
+		got = append(got, src.Payload().(*urlPayload).url)
+	}
+	c.Assert(got, gc.DeepEquals, []string{"http://a.com", "http://b.com", "http://c.com"})
+}
+
+func (s *RedisTestSuite) TestSourceErrors(c *gc.C) {
+	client := newStreamStub()
+	client.add("http://a.com")
+	src, err := NewSource(SourceConfig{
+		Client:   client,
+		Stream:   "links",
+		Group:    "crawlers",
+		Consumer: "c1",
+		Decode: func(Entry, func()) (pipeline.Payload, error) {
+			return nil, xerrors.New("malformed")
+		},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(src.Next(context.TODO()), gc.Equals, false)
+	c.Assert(src.Error(), gc.ErrorMatches, "decode entry 1-0: malformed")
+
+	client.err = Error("ERR connection refused")
+	src, err = NewSource(SourceConfig{Client: client, Stream: "links", Group: "g2", Consumer: "c1", Decode: decodeURL})
+	c.Assert(err, gc.IsNil)
+	c.Assert(src.Next(context.TODO()), gc.Equals, false)
+	c.Assert(src.Error(), gc.ErrorMatches, "create consumer group: ERR connection refused")
+}
+
+func (s *RedisTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewSource(SourceConfig{Client: newStreamStub(), Stream: "links", Decode: decodeURL})
+	c.Assert(err, gc.ErrorMatches, ".*stream, group and consumer must be specified")
+	_, err = NewSink(SinkConfig{Client: newStreamStub(), Encode: encodeURL})
+	c.Assert(err, gc.ErrorMatches, ".*stream has not been specified")
+}
+
+func (s *RedisTestSuite) TestConnProtocol(c *gc.C) {
+	client, server := net.Pipe()
+	conn := NewConn(client)
+	defer func() { _ = conn.Close() }()
+
+	go func() {
+		r := bufio.NewReader(server)
+		replies := []string{
+			"+OK\r\n",
+			"-BUSYGROUP Consumer Group name already exists\r\n",
+			"*2\r\n$5\r\nlinks\r\n*2\r\n:42\r\n$-1\r\n",
+		}
+		for _, reply := range replies {
+			if _, err := readReply(r); err != nil {
+				return
+			}
+			_, _ = server.Write([]byte(reply))
+		}
+	}()
+
+	reply, err := conn.Do(context.TODO(), "SET", "k", "v")
+	c.Assert(err, gc.IsNil)
+	c.Assert(reply, gc.Equals, "OK")
+
+	_, err = conn.Do(context.TODO(), "XGROUP", "CREATE", "links", "g", "0")
+	c.Assert(err, gc.FitsTypeOf, Error(""))
+	c.Assert(err, gc.ErrorMatches, "BUSYGROUP.*")
+
+	reply, err = conn.Do(context.TODO(), "XREADGROUP")
+	c.Assert(err, gc.IsNil)
+	c.Assert(reply, gc.DeepEquals, []interface{}{"links", []interface{}{int64(42), nil}})
+}
+
+func (s *RedisTestSuite) TestConnContextCancellation(c *gc.C) {
+	client, server := net.Pipe()
+	defer func() { _ = server.Close() }()
+	conn := NewConn(client)
+
+	//Drain the command but never reply
+	go func() { _, _ = readReply(bufio.NewReader(server)) }()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	_, err := conn.Do(ctx, "XREADGROUP", "BLOCK", "0")
+	c.Assert(err, gc.Equals, context.DeadlineExceeded)
+}
+
+type urlPayload struct {
+	url string
+	ack func()
+}
+
+func (p *urlPayload) Clone() (pipeline.Payload, error) {
+	return &urlPayload{url: p.url, ack: func() {}}, nil
+}
+func (p *urlPayload) MarkAsProcessed()        { p.ack() }
+
+func decodeURL(entry Entry, ack func()) (pipeline.Payload, error) {
+	if entry.Fields["url"] == "" {
+		return nil, nil
+	}
+	return &urlPayload{url: entry.Fields["url"], ack: ack}, nil
+}
+
+func encodeURL(p pipeline.Payload) (map[string]string, error) {
+	return map[string]string{"url": p.(*urlPayload).url}, nil
+}
+
+//streamStub emulates the subset of the Redis stream commands used by the
+//adapters for a single consumer group.
+type streamStub struct {
+	mu        sync.Mutex
+	entries   []Entry
+	delivered int
+	pending   map[string]map[string]bool
+	groups    map[string]bool
+	err       error
+}
+
+func newStreamStub() *streamStub {
+	return &streamStub{pending: make(map[string]map[string]bool), groups: make(map[string]bool)}
+}
+
+func (s *streamStub) add(url string) string {
+	id := strconv.Itoa(len(s.entries)+1) + "-0"
+	s.entries = append(s.entries, Entry{ID: id, Fields: map[string]string{"url": url}})
+	return id
+}
+
+func (s *streamStub) pendingIDs(consumer string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for id := range s.pending[consumer] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *streamStub) Do(ctx context.Context, args ...string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	switch args[0] {
+	case "XADD":
+		fields := args[len(args)-2:]
+		return s.add(fields[1]), nil
+	case "XGROUP":
+		if s.groups[args[3]] {
+			return nil, Error("BUSYGROUP Consumer Group name already exists")
+		}
+		s.groups[args[3]] = true
+		return "OK", nil
+	case "XACK":
+		for _, id := range args[3:] {
+			for _, pending := range s.pending {
+				delete(pending, id)
+			}
+		}
+		return int64(len(args) - 3), nil
+	case "XREADGROUP":
+		return s.read(ctx, args)
+	default:
+		return nil, Error("ERR unknown command " + args[0])
+	}
+}
+
+func (s *streamStub) read(ctx context.Context, args []string) (interface{}, error) {
+	consumer := args[3]
+	count, _ := strconv.Atoi(args[5])
+	startID := args[len(args)-1]
+
+	var out []Entry
+	if startID == ">" {
+		for s.delivered < len(s.entries) && len(out) < count {
+			entry := s.entries[s.delivered]
+			s.delivered++
+			if s.pending[consumer] == nil {
+				s.pending[consumer] = make(map[string]bool)
+			}
+			s.pending[consumer][entry.ID] = true
+			out = append(out, entry)
+		}
+		if len(out) == 0 {
+			//Emulate BLOCK: wait for the context to expire
+			s.mu.Unlock()
+			<-ctx.Done()
+			s.mu.Lock()
+			return nil, ctx.Err()
+		}
+	} else {
+		for _, entry := range s.entries {
+			if s.pending[consumer][entry.ID] && idAfter(entry.ID, startID) && len(out) < count {
+				out = append(out, entry)
+			}
+		}
+	}
+
+	items := make([]interface{}, len(out))
+	for i, entry := range out {
+		items[i] = []interface{}{entry.ID, []interface{}{"url", entry.Fields["url"]}}
+	}
+	return []interface{}{[]interface{}{args[len(args)-2], items}}, nil
+}
+
+func idAfter(id, startID string) bool {
+	seq := func(id string) int {
+		n, _ := strconv.Atoi(strings.SplitN(id, "-", 2)[0])
+		return n
+	}
+	return seq(id) > seq(startID)
+}
@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+)
+
+//EncodeFunc converts a pipeline payload into the fields of a stream entry.
+//Returning no fields drops the payload
+type EncodeFunc func(pipeline.Payload) (map[string]string, error)
+
+//SinkConfig encapsulates the settings for a Redis stream Sink
+type SinkConfig struct {
+	//Client is used for appending to the stream
+	Client Client
+
+	//Stream is the key of the stream to append entries to
+	Stream string
+
+	//MaxLen, if specified, caps the length of the stream.  Older entries
+	//are evicted using approximate trimming
+	MaxLen int
+
+	//Encode converts payloads into stream entries
+	Encode EncodeFunc
+}
+
+func (cfg *SinkConfig) validate() error {
+	if cfg.Client == nil {
+		return xerrors.New("client has not been provided")
+	}
+	if cfg.Stream == "" {
+		return xerrors.New("stream has not been specified")
+	}
+	if cfg.Encode == nil {
+		return xerrors.New("encode function has not been provided")
+	}
+	return nil
+}
+
+//Sink is a pipeline.Sink that appends the payloads that reach it to a Redis
+//stream
+type Sink struct {
+	cfg SinkConfig
+}
+
+//NewSink creates a new Redis stream Sink with the specified config
+func NewSink(cfg SinkConfig) (*Sink, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("redis sink: config validation failed: %w", err)
+	}
+	return &Sink{cfg: cfg}, nil
+}
+
+//Consume implements pipeline.Sink
+func (s *Sink) Consume(ctx context.Context, p pipeline.Payload) error {
+	fields, err := s.cfg.Encode(p)
+	if err != nil {
+		return xerrors.Errorf("encode payload: %w", err)
+	} else if len(fields) == 0 {
+		return nil
+	}
+
+	args := []string{"XADD", s.cfg.Stream}
+	if s.cfg.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.Itoa(s.cfg.MaxLen))
+	}
+	args = append(args, "*")
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, k, fields[k])
+	}
+
+	if _, err = s.cfg.Client.Do(ctx, args...); err != nil {
+		return xerrors.Errorf("append entry: %w", err)
+	}
+	return nil
+}
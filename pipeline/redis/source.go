@@ -0,0 +1,271 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultBatchSize = 16
+	defaultBlockTime = 5 * time.Second
+)
+
+//Entry is an entry of a Redis stream
+type Entry struct {
+	ID     string
+	Fields map[string]string
+}
+
+//DecodeFunc converts a stream entry into a pipeline payload.  The ack callback
+//must be invoked once the payload has been fully processed, typically from
+//the payload's MarkAsProcessed method.  Entries that cannot be decoded can be
+//skipped by returning a nil payload
+type DecodeFunc func(entry Entry, ack func()) (pipeline.Payload, error)
+
+//SourceConfig encapsulates the settings for a Redis stream Source
+type SourceConfig struct {
+	//Client is used for reading from the stream.  As reads block for up
+	//to BlockTime, the client should not be shared with the sink
+	Client Client
+
+	//Stream is the key of the stream to consume
+	Stream string
+
+	//Group is the consumer group shared by all the crawler instances.  It
+	//is created if it does not exist
+	Group string
+
+	//Consumer uniquely identifies this instance within the group
+	Consumer string
+
+	//Decode converts stream entries into payloads
+	Decode DecodeFunc
+
+	//BatchSize is the maximum number of entries fetched at once.  If not
+	//specified, a default value of 16 will be used
+	BatchSize int
+
+	//BlockTime is the maximum time to wait for new entries before
+	//checking whether the source's context has expired.  If not
+	//specified, a default value of 5s will be used
+	BlockTime time.Duration
+}
+
+func (cfg *SourceConfig) validate() error {
+	if cfg.Client == nil {
+		return xerrors.New("client has not been provided")
+	}
+	if cfg.Stream == "" || cfg.Group == "" || cfg.Consumer == "" {
+		return xerrors.New("stream, group and consumer must be specified")
+	}
+	if cfg.Decode == nil {
+		return xerrors.New("decode function has not been provided")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.BlockTime <= 0 {
+		cfg.BlockTime = defaultBlockTime
+	}
+	return nil
+}
+
+/*Source is a pipeline.Source that emits the payloads decoded from the entries
+of a Redis stream.
+
+Entries are read as a member of a consumer group and acknowledged once
+processed.  When the source starts, any entries that were delivered to the
+same consumer but never acknowledged (e.g. because the previous instance
+crashed) are replayed before reading new entries.  Next returns false once
+its context expires*/
+type Source struct {
+	cfg SourceConfig
+
+	groupCreated bool
+	buffered     []Entry
+
+	//replayFrom is the ID after which our own pending entries are
+	//replayed.  It is cleared once all of them have been replayed
+	replayFrom string
+
+	mu    sync.Mutex
+	acked []string
+
+	payload pipeline.Payload
+	err     error
+}
+
+//NewSource creates a new Redis stream Source with the specified config
+func NewSource(cfg SourceConfig) (*Source, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("redis source: config validation failed: %w", err)
+	}
+	return &Source{cfg: cfg, replayFrom: "0"}, nil
+}
+
+//Next implements pipeline.Source
+func (s *Source) Next(ctx context.Context) bool {
+	for {
+		if err := s.Commit(ctx); err != nil {
+			return s.fail(ctx, err)
+		}
+
+		if len(s.buffered) == 0 {
+			if err := s.fetch(ctx); err != nil {
+				return s.fail(ctx, err)
+			} else if len(s.buffered) == 0 {
+				if ctx.Err() != nil {
+					return false
+				}
+				continue
+			}
+		}
+
+		entry := s.buffered[0]
+		s.buffered = s.buffered[1:]
+
+		ack := s.ackFunc(entry.ID)
+		payload, err := s.cfg.Decode(entry, ack)
+		if err != nil {
+			s.err = xerrors.Errorf("decode entry %s: %w", entry.ID, err)
+			return false
+		} else if payload == nil {
+			ack()
+			continue
+		}
+
+		s.payload = payload
+		return true
+	}
+}
+
+//Payload implements pipeline.Source
+func (s *Source) Payload() pipeline.Payload { return s.payload }
+
+//Error implements pipeline.Source
+func (s *Source) Error() error { return s.err }
+
+//Commit acknowledges the entries whose payloads have been processed.  It is
+//invoked automatically by Next but should also be called once the pipeline
+//has returned to acknowledge the final payloads
+func (s *Source) Commit(ctx context.Context) error {
+	s.mu.Lock()
+	ids := s.acked
+	s.acked = nil
+	s.mu.Unlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+	args := append([]string{"XACK", s.cfg.Stream, s.cfg.Group}, ids...)
+	if _, err := s.cfg.Client.Do(ctx, args...); err != nil {
+		//retry on the next commit
+		s.mu.Lock()
+		s.acked = append(ids, s.acked...)
+		s.mu.Unlock()
+		return xerrors.Errorf("ack entries: %w", err)
+	}
+	return nil
+}
+
+func (s *Source) fail(ctx context.Context, err error) bool {
+	if ctx.Err() == nil {
+		s.err = err
+	}
+	return false
+}
+
+func (s *Source) ackFunc(id string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.acked = append(s.acked, id)
+			s.mu.Unlock()
+		})
+	}
+}
+
+//fetch reads the next batch of entries into the buffer
+func (s *Source) fetch(ctx context.Context) error {
+	if !s.groupCreated {
+		_, err := s.cfg.Client.Do(ctx, "XGROUP", "CREATE", s.cfg.Stream, s.cfg.Group, "0", "MKSTREAM")
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return xerrors.Errorf("create consumer group: %w", err)
+		}
+		s.groupCreated = true
+	}
+
+	//replay our own pending entries before asking for new ones
+	args := []string{"XREADGROUP", "GROUP", s.cfg.Group, s.cfg.Consumer, "COUNT", strconv.Itoa(s.cfg.BatchSize)}
+	startID := s.replayFrom
+	if startID == "" {
+		startID = ">"
+		args = append(args, "BLOCK", strconv.FormatInt(int64(s.cfg.BlockTime/time.Millisecond), 10))
+	}
+	args = append(args, "STREAMS", s.cfg.Stream, startID)
+
+	reply, err := s.cfg.Client.Do(ctx, args...)
+	if err != nil {
+		return xerrors.Errorf("read entries: %w", err)
+	}
+	entries, err := parseReadReply(reply)
+	if err != nil {
+		return xerrors.Errorf("read entries: %w", err)
+	}
+
+	if s.replayFrom != "" {
+		if len(entries) == 0 {
+			s.replayFrom = ""
+		} else {
+			s.replayFrom = entries[len(entries)-1].ID
+		}
+	}
+	s.buffered = entries
+	return nil
+}
+
+//parseReadReply extracts the entries from an XREADGROUP reply of the form
+//[[stream, [[id, [field, value, ...]], ...]]]
+func parseReadReply(reply interface{}) ([]Entry, error) {
+	if reply == nil {
+		//BLOCK timeout
+		return nil, nil
+	}
+	streams, ok := reply.([]interface{})
+	if !ok {
+		return nil, xerrors.Errorf("unexpected reply type %T", reply)
+	}
+
+	var entries []Entry
+	for _, stream := range streams {
+		kv, ok := stream.([]interface{})
+		if !ok || len(kv) != 2 {
+			return nil, xerrors.New("malformed stream reply")
+		}
+		items, _ := kv[1].([]interface{})
+		for _, item := range items {
+			pair, ok := item.([]interface{})
+			if !ok || len(pair) != 2 {
+				return nil, xerrors.New("malformed entry reply")
+			}
+			id, _ := pair[0].(string)
+			//entries that were deleted while pending have nil fields
+			fieldList, _ := pair[1].([]interface{})
+			entry := Entry{ID: id, Fields: make(map[string]string, len(fieldList)/2)}
+			for i := 0; i+1 < len(fieldList); i += 2 {
+				k, _ := fieldList[i].(string)
+				v, _ := fieldList[i+1].(string)
+				entry.Fields[k] = v
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -8,19 +8,31 @@ import (
 )
 
 type fifo struct {
-	proc Processor
+	counters stageCounters
+	proc     Processor
 }
 
 /*FIFO returns a StageRunner that processes incoming payloads in
 a fifo fashion.  Each input is passed to the specified processor
 and its output is emitted to the next stage*/
 func FIFO(proc Processor) StageRunner {
-	return fifo{
+	return newFIFO(proc)
+}
+
+func newFIFO(proc Processor) *fifo {
+	return &fifo{
 		proc: proc,
 	}
 }
 
-func (r fifo) Run(ctx context.Context, params StageParams) {
+//Describe implements StageDescriber
+func (r *fifo) Describe() StageDescription {
+	d := StageDescription{Runner: "fifo", Workers: 1, Processors: []string{processorName(r.proc)}}
+	r.counters.addTo(&d.Stats)
+	return d
+}
+
+func (r *fifo) Run(ctx context.Context, params StageParams) {
 	/*Run is designed to be blocking.  It runs this infinite for loop
 	which does one of the following:
 	- Monitors the provided context for cancellation, exiting from main loop if so
@@ -35,7 +47,9 @@ func (r fifo) Run(ctx context.Context, params StageParams) {
 			}
 
 			//Once input payload received, process payload using user-defined processor
+			r.counters.begin()
 			payloadOut, err := r.proc.Process(ctx, payloadIn)
+			r.counters.end(payloadOut, err)
 			if err != nil {
 				wrapperErr := xerrors.Errorf("pipeline stage %d: %w", params.StageIndex(), err)
 				maybeEmitError(wrapperErr, params.Error())
@@ -67,7 +81,7 @@ func maybeEmitError(err error, errCh chan<- error) {
 }
 
 type fixedWorkerPool struct {
-	fifos []StageRunner
+	fifos []*fifo
 }
 
 /*FixedWorkerPool returns a StageRunner that spins up a pool containing
@@ -78,9 +92,9 @@ func FixedWorkerPool(proc Processor, numWorkers int) StageRunner {
 		panic("FixedWorkerPool: numWorkers must be > 0")
 	}
 
-	fifos := make([]StageRunner, numWorkers)
+	fifos := make([]*fifo, numWorkers)
 	for i := 0; i < numWorkers; i++ {
-		fifos[i] = FIFO(proc)
+		fifos[i] = newFIFO(proc)
 	}
 
 	return &fixedWorkerPool{
@@ -88,6 +102,19 @@ func FixedWorkerPool(proc Processor, numWorkers int) StageRunner {
 	}
 }
 
+//Describe implements StageDescriber
+func (p *fixedWorkerPool) Describe() StageDescription {
+	d := StageDescription{
+		Runner:     "fixed_worker_pool",
+		Workers:    len(p.fifos),
+		Processors: []string{processorName(p.fifos[0].proc)},
+	}
+	for _, f := range p.fifos {
+		f.counters.addTo(&d.Stats)
+	}
+	return d
+}
+
 //Run implements stage runner
 func (p *fixedWorkerPool) Run(ctx context.Context, params StageParams) {
 	var wg sync.WaitGroup
@@ -96,15 +123,16 @@ func (p *fixedWorkerPool) Run(ctx context.Context, params StageParams) {
 	for i := 0; i < len(p.fifos); i++ {
 		wg.Add(1)
 		go func(fifoIndex int) {
+			defer wg.Done()
 			p.fifos[fifoIndex].Run(ctx, params)
 		}(i)
-		wg.Done()
 	}
 
 	wg.Wait()
 }
 
 type dynamicWorkerPool struct {
+	counters  stageCounters
 	proc      Processor
 	tokenPool chan struct{}
 }
@@ -129,6 +157,17 @@ func DynamicWorkerPool(proc Processor, maxWorkers int) StageRunner {
 	}
 }
 
+//Describe implements StageDescriber
+func (p *dynamicWorkerPool) Describe() StageDescription {
+	d := StageDescription{
+		Runner:     "dynamic_worker_pool",
+		Workers:    cap(p.tokenPool),
+		Processors: []string{processorName(p.proc)},
+	}
+	p.counters.addTo(&d.Stats)
+	return d
+}
+
 func (p *dynamicWorkerPool) Run(ctx context.Context, params StageParams) {
 stop:
 	for {
@@ -158,7 +197,9 @@ stop:
 			is available for reuse*/
 			go func(payloadIn Payload, token struct{}) {
 				defer func() { p.tokenPool <- token }()
+				p.counters.begin()
 				payloadOut, err := p.proc.Process(ctx, payloadIn)
+				p.counters.end(payloadOut, err)
 				if err != nil {
 					wrappedErr := xerrors.Errorf("pipeline stage %d: %w", params.StageIndex(), err)
 					maybeEmitError(wrappedErr, params.Error())
@@ -186,7 +227,7 @@ stop:
 }
 
 type broadcast struct {
-	fifos []StageRunner
+	fifos []*fifo
 }
 
 //Broadcast receives a list of processor instances and creates a FIFO instance for each one.
@@ -195,9 +236,9 @@ func Broadcast(procs ...Processor) StageRunner {
 		panic("Broadcast: at least one processor must be specified")
 	}
 
-	fifos := make([]StageRunner, len(procs))
+	fifos := make([]*fifo, len(procs))
 	for i, p := range procs {
-		fifos[i] = FIFO(p)
+		fifos[i] = newFIFO(p)
 	}
 
 	return &broadcast{
@@ -205,6 +246,17 @@ func Broadcast(procs ...Processor) StageRunner {
 	}
 }
 
+//Describe implements StageDescriber.  Each payload is processed once by every
+//processor so the counters are summed across processors
+func (b *broadcast) Describe() StageDescription {
+	d := StageDescription{Runner: "broadcast", Workers: len(b.fifos)}
+	for _, f := range b.fifos {
+		d.Processors = append(d.Processors, processorName(f.proc))
+		f.counters.addTo(&d.Stats)
+	}
+	return d
+}
+
 func (b *broadcast) Run(ctx context.Context, params StageParams) {
 	var wg sync.WaitGroup
 	var inCh = make([]chan Payload, len(b.fifos))
@@ -240,17 +292,20 @@ done:
 				break done
 			}
 
-			//Clone payload and dispatch to each FIFO worker
+			//Clone payload and dispatch to each FIFO worker.  If a clone
+			//cannot be created the payload is dropped and the error is
+			//reported just like a processor error
+			fifoPayloads, err := clonePayload(payload, len(b.fifos))
+			if err != nil {
+				wrappedErr := xerrors.Errorf("pipeline stage %d: clone payload: %w", params.StageIndex(), err)
+				maybeEmitError(wrappedErr, params.Error())
+				break done
+			}
 			for i := len(b.fifos) - 1; i >= 0; i-- {
-				var fifoPayload = payload
-				if i != 0 {
-					fifoPayload = payload.Clone()
-				}
-
 				select {
 				case <-ctx.Done():
 					break done
-				case inCh[i] <- fifoPayload:
+				case inCh[i] <- fifoPayloads[i]:
 					//payload sent to the i_th FIFO
 				}
 			}
@@ -263,3 +318,22 @@ done:
 	}
 	wg.Wait()
 }
+
+//clonePayload returns n copies of payload, the first of which is payload
+//itself.  If any of the clones cannot be created, payload and all clones
+//created so far are marked as processed
+func clonePayload(payload Payload, n int) ([]Payload, error) {
+	payloads := make([]Payload, n)
+	payloads[0] = payload
+	for i := 1; i < n; i++ {
+		clone, err := payload.Clone()
+		if err != nil {
+			for _, p := range payloads[:i] {
+				p.MarkAsProcessed()
+			}
+			return nil, err
+		}
+		payloads[i] = clone
+	}
+	return payloads, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"golang.org/x/xerrors"
 	gc "gopkg.in/check.v1"
 )
 
@@ -29,6 +30,55 @@ func (s StageTestSuite) TestFIFO(c *gc.C) {
 	assertAllProcessed(c, src.data)
 }
 
+func (s StageTestSuite) TestFixedWorkerPool(c *gc.C) {
+	const numWorkers = 10
+	syncCh := make(chan struct{})
+	rendezvousCh := make(chan struct{})
+
+	proc := ProcessorFunc(func(_ context.Context, p Payload) (Payload, error) {
+		//signal that we have reached the sync point and wait for the
+		//green light to proceed by the test code
+		syncCh <- struct{}{}
+		<-rendezvousCh
+		return p, nil
+	})
+
+	src := &sourceStub{data: stringPayloads(numWorkers)}
+	sink := new(sinkStub)
+
+	p := New(FixedWorkerPool(proc, numWorkers))
+	doneCh := make(chan struct{})
+	go func() {
+		err := p.Process(context.TODO(), src, sink)
+		c.Check(err, gc.IsNil)
+		close(doneCh)
+	}()
+
+	//wait for all workers to reach the sync point.  This means that each
+	//input from the source is currently handled by a worker in parallel
+	for i := 0; i < numWorkers; i++ {
+		<-syncCh
+	}
+
+	//the workers must still be able to emit their payloads once unblocked
+	close(rendezvousCh)
+	<-doneCh
+	c.Assert(sink.data, gc.HasLen, numWorkers)
+	assertAllProcessed(c, src.data)
+}
+
+func (s StageTestSuite) TestBroadcastCloneError(c *gc.C) {
+	payload := &unclonablePayload{stringPayload: stringPayload{val: "0"}}
+	src := &sourceStub{data: []Payload{payload}}
+	sink := new(sinkStub)
+
+	p := New(Broadcast(makePassthroughProcessor(), makePassthroughProcessor()))
+	err := p.Process(context.TODO(), src, sink)
+	c.Assert(err, gc.ErrorMatches, "(?s).*pipeline stage 0: clone payload: out of disk space.*")
+	c.Assert(sink.data, gc.HasLen, 0)
+	c.Assert(payload.processed, gc.Equals, true)
+}
+
 //passes payload through to next stage
 func makePassthroughProcessor() Processor {
 	return ProcessorFunc(func(_ context.Context, p Payload) (Payload, error) {
@@ -42,3 +92,12 @@ func assertAllProcessed(c *gc.C, payloads []Payload) {
 		c.Assert(payload.processed, gc.Equals, true, gc.Commentf("payload %d not processed", i))
 	}
 }
+
+//unclonablePayload is a payload that cannot be cloned
+type unclonablePayload struct {
+	stringPayload
+}
+
+func (p *unclonablePayload) Clone() (Payload, error) {
+	return nil, xerrors.New("out of disk space")
+}
@@ -48,8 +48,8 @@ type stringPayload struct {
 	val       string
 }
 
-func (s *stringPayload) Clone() Payload {
-	return &stringPayload{val: s.val}
+func (s *stringPayload) Clone() (Payload, error) {
+	return &stringPayload{val: s.val}, nil
 }
 
 func (s *stringPayload) MarkAsProcessed() {
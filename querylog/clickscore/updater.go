@@ -0,0 +1,116 @@
+package clickscore
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// QueryLog is implemented by objects that can report the number of clicks
+// that each search result received.
+type QueryLog interface {
+	ClickCounts(since time.Time) (map[uuid.UUID]uint64, error)
+}
+
+// Indexer is implemented by objects that can apply partial updates to
+// indexed documents.
+type Indexer interface {
+	UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error
+}
+
+// Updater periodically computes a click score for each document based on the
+// clicks recorded in the query log and stores it in the index where it can be
+// used as an additional ranking signal.
+type Updater struct {
+	queryLog QueryLog
+	indexer  Indexer
+	window   time.Duration
+
+	// scored tracks the documents that were assigned a non-zero score by
+	// the previous update so their score can be reset once they stop
+	// receiving clicks.
+	scored map[uuid.UUID]struct{}
+}
+
+// NewUpdater returns a new Updater that computes click scores from the clicks
+// recorded in queryLog within the specified time window and stores them via
+// indexer.
+func NewUpdater(queryLog QueryLog, indexer Indexer, window time.Duration) *Updater {
+	return &Updater{
+		queryLog: queryLog,
+		indexer:  indexer,
+		window:   window,
+		scored:   make(map[uuid.UUID]struct{}),
+	}
+}
+
+// Run updates the click scores every interval until ctx expires or an error
+// occurs.
+func (u *Updater) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		if _, err := u.Update(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Update performs a single click score computation and returns the number of
+// documents whose score was updated.
+//
+// Scores are normalized to the [0, 1] range using a logarithmic scale so a
+// handful of extremely popular results do not dwarf everything else.
+func (u *Updater) Update() (int, error) {
+	counts, err := u.queryLog.ClickCounts(time.Now().Add(-u.window))
+	if err != nil {
+		return 0, xerrors.Errorf("click score update: %w", err)
+	}
+
+	var maxCount uint64
+	for _, count := range counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	var updated int
+	scored := make(map[uuid.UUID]struct{}, len(counts))
+	for linkID, count := range counts {
+		score := math.Log1p(float64(count)) / math.Log1p(float64(maxCount))
+		if err = u.setScore(linkID, score); err != nil {
+			return updated, err
+		}
+		scored[linkID] = struct{}{}
+		updated++
+	}
+
+	// Reset the score of documents that no longer receive any clicks
+	for linkID := range u.scored {
+		if _, stillScored := scored[linkID]; stillScored {
+			continue
+		}
+		if err = u.setScore(linkID, 0); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	u.scored = scored
+	return updated, nil
+}
+
+func (u *Updater) setScore(linkID uuid.UUID, score float64) error {
+	if err := u.indexer.UpdateFields(linkID, map[string]interface{}{index.FieldClickScore: score}); err != nil {
+		return xerrors.Errorf("click score update: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package clickscore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/querylog/query"
+	qlmemory "github.com/brandonshearin/ask_brandon/querylog/store/memory"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/brandonshearin/ask_brandon/textindexer/store/memory"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(UpdaterTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type UpdaterTestSuite struct{}
+
+func (s *UpdaterTestSuite) TestClickBoosting(c *gc.C) {
+	idx, err := memory.NewInMemoryBleveIndexerWithConfig(memory.Config{
+		RankingWeights: memory.RankingWeights{PageRank: 1, ClickScore: 1},
+	})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(idx.Close(), gc.IsNil) }()
+
+	popular := &index.Document{LinkID: uuid.New(), Content: "gophers"}
+	other := &index.Document{LinkID: uuid.New(), Content: "gophers"}
+	c.Assert(idx.Index(popular), gc.IsNil)
+	c.Assert(idx.Index(other), gc.IsNil)
+	c.Assert(idx.UpdateScore(popular.LinkID, 0.2), gc.IsNil)
+	c.Assert(idx.UpdateScore(other.LinkID, 0.5), gc.IsNil)
+
+	q := index.Query{Type: index.QueryTypeMatch, Expression: "gophers"}
+	c.Assert(searchIDs(c, idx, q), gc.DeepEquals, []uuid.UUID{other.LinkID, popular.LinkID})
+
+	queryLog := qlmemory.NewInMemoryQueryLog()
+	for i := 0; i < 3; i++ {
+		c.Assert(queryLog.RecordClick(&query.Click{LinkID: popular.LinkID}), gc.IsNil)
+	}
+
+	u := NewUpdater(queryLog, idx, 24*time.Hour)
+	updated, err := u.Update()
+	c.Assert(err, gc.IsNil)
+	c.Assert(updated, gc.Equals, 1)
+
+	doc, err := idx.FindByID(popular.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(doc.ClickScore, gc.Equals, 1.0)
+	c.Assert(searchIDs(c, idx, q), gc.DeepEquals, []uuid.UUID{popular.LinkID, other.LinkID})
+}
+
+func searchIDs(c *gc.C, idx index.Indexer, q index.Query) []uuid.UUID {
+	it, err := idx.Search(q)
+	c.Assert(err, gc.IsNil)
+
+	var ids []uuid.UUID
+	for it.Next() {
+		ids = append(ids, it.Document().LinkID)
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(it.Close(), gc.IsNil)
+	return ids
+}
@@ -0,0 +1,72 @@
+package query
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	//ErrUnknownQuery is returned when recording a click for a query that has not been logged
+	ErrUnknownQuery = xerrors.New("unknown query")
+
+	//ErrMissingLinkID is returned when recording a click that does not specify the clicked link
+	ErrMissingLinkID = xerrors.New("click does not provide a valid linkID")
+)
+
+/*Store is implemented by objects that can persist the search queries submitted
+through the frontend and the results that users clicked on*/
+type Store interface {
+	/*RecordQuery logs a search query.  If rec.ID is not set, a new ID will
+	be assigned to it*/
+	RecordQuery(rec *Record) error
+
+	/*RecordClick logs a click on a search result*/
+	RecordClick(click *Click) error
+
+	/*PopularQueries returns up to limit of the most frequently searched
+	expressions since the specified time, most popular first*/
+	PopularQueries(since time.Time, limit int) ([]PopularQuery, error)
+
+	/*ClickCounts returns the number of clicks recorded for each link since
+	the specified time*/
+	ClickCounts(since time.Time) (map[uuid.UUID]uint64, error)
+}
+
+/*Record describes a search query submitted by a user*/
+type Record struct {
+	ID uuid.UUID
+
+	Expression  string
+	ResultCount uint64
+
+	Timestamp time.Time
+}
+
+/*Click describes a search result that a user clicked on*/
+type Click struct {
+	//the query whose results were clicked, if known
+	QueryID uuid.UUID
+
+	LinkID uuid.UUID
+	URL    string
+
+	//the zero-based position of the clicked link in the result list
+	Position int
+
+	Timestamp time.Time
+}
+
+/*PopularQuery holds the number of times an expression has been searched for*/
+type PopularQuery struct {
+	Expression string
+	Count      uint64
+}
+
+/*NormalizeExpression returns the canonical form of a search expression which
+is used for aggregating queries*/
+func NormalizeExpression(expr string) string {
+	return strings.ToLower(strings.Join(strings.Fields(expr), " "))
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/querylog/query"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// Compile-time check for ensuring InMemoryQueryLog implements query.Store.
+var _ query.Store = (*InMemoryQueryLog)(nil)
+
+// InMemoryQueryLog implements an in-memory query log that can be concurrently
+// accessed by multiple clients.
+type InMemoryQueryLog struct {
+	mu sync.RWMutex
+
+	queries map[uuid.UUID]*query.Record
+	// queryList holds the logged queries in the order they were recorded.
+	queryList []*query.Record
+	clicks    []*query.Click
+}
+
+// NewInMemoryQueryLog creates a new in-memory query log.
+func NewInMemoryQueryLog() *InMemoryQueryLog {
+	return &InMemoryQueryLog{
+		queries: make(map[uuid.UUID]*query.Record),
+	}
+}
+
+// RecordQuery logs a search query.
+func (l *InMemoryQueryLog) RecordQuery(rec *query.Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rec.ID == uuid.Nil {
+		rec.ID = uuid.New()
+	}
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+
+	rCopy := new(query.Record)
+	*rCopy = *rec
+	l.queries[rCopy.ID] = rCopy
+	l.queryList = append(l.queryList, rCopy)
+	return nil
+}
+
+// RecordClick logs a click on a search result.
+func (l *InMemoryQueryLog) RecordClick(click *query.Click) error {
+	if click.LinkID == uuid.Nil {
+		return xerrors.Errorf("record click: %w", query.ErrMissingLinkID)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if click.QueryID != uuid.Nil {
+		if _, exists := l.queries[click.QueryID]; !exists {
+			return xerrors.Errorf("record click: %w", query.ErrUnknownQuery)
+		}
+	}
+	if click.Timestamp.IsZero() {
+		click.Timestamp = time.Now()
+	}
+
+	cCopy := new(query.Click)
+	*cCopy = *click
+	l.clicks = append(l.clicks, cCopy)
+	return nil
+}
+
+// ClickCounts returns the number of clicks recorded for each link since the
+// specified time.
+func (l *InMemoryQueryLog) ClickCounts(since time.Time) (map[uuid.UUID]uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	counts := make(map[uuid.UUID]uint64)
+	for _, click := range l.clicks {
+		if !click.Timestamp.Before(since) {
+			counts[click.LinkID]++
+		}
+	}
+	return counts, nil
+}
+
+// PopularQueries returns up to limit of the most frequently searched
+// expressions since the specified time.
+func (l *InMemoryQueryLog) PopularQueries(since time.Time, limit int) ([]query.PopularQuery, error) {
+	l.mu.RLock()
+	counts := make(map[string]uint64)
+	for _, rec := range l.queryList {
+		if rec.Timestamp.Before(since) {
+			continue
+		}
+		if expr := query.NormalizeExpression(rec.Expression); expr != "" {
+			counts[expr]++
+		}
+	}
+	l.mu.RUnlock()
+
+	popular := make([]query.PopularQuery, 0, len(counts))
+	for expr, count := range counts {
+		popular = append(popular, query.PopularQuery{Expression: expr, Count: count})
+	}
+	sort.Slice(popular, func(i, j int) bool {
+		if popular[i].Count != popular[j].Count {
+			return popular[i].Count > popular[j].Count
+		}
+		return popular[i].Expression < popular[j].Expression
+	})
+
+	if limit > 0 && len(popular) > limit {
+		popular = popular[:limit]
+	}
+	return popular, nil
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/querylog/query"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(InMemoryQueryLogTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type InMemoryQueryLogTestSuite struct {
+	log *InMemoryQueryLog
+}
+
+func (s *InMemoryQueryLogTestSuite) SetUpTest(c *gc.C) {
+	s.log = NewInMemoryQueryLog()
+}
+
+func (s *InMemoryQueryLogTestSuite) TestRecordClick(c *gc.C) {
+	rec := &query.Record{Expression: "gophers", ResultCount: 3}
+	c.Assert(s.log.RecordQuery(rec), gc.IsNil)
+	c.Assert(rec.ID, gc.Not(gc.Equals), uuid.Nil)
+	c.Assert(rec.Timestamp.IsZero(), gc.Equals, false)
+
+	err := s.log.RecordClick(&query.Click{QueryID: rec.ID, LinkID: uuid.New(), URL: "http://example.com"})
+	c.Assert(err, gc.IsNil)
+
+	err = s.log.RecordClick(&query.Click{QueryID: uuid.New(), LinkID: uuid.New()})
+	c.Assert(xerrors.Is(err, query.ErrUnknownQuery), gc.Equals, true)
+
+	err = s.log.RecordClick(&query.Click{QueryID: rec.ID})
+	c.Assert(xerrors.Is(err, query.ErrMissingLinkID), gc.Equals, true)
+}
+
+func (s *InMemoryQueryLogTestSuite) TestPopularQueries(c *gc.C) {
+	now := time.Now()
+	for _, rec := range []*query.Record{
+		{Expression: "old query", Timestamp: now.Add(-48 * time.Hour)},
+		{Expression: "gophers"},
+		{Expression: "  Gophers "},
+		{Expression: "golang"},
+		{Expression: "gophers"},
+		{Expression: "golang"},
+		{Expression: "bleve"},
+	} {
+		c.Assert(s.log.RecordQuery(rec), gc.IsNil)
+	}
+
+	popular, err := s.log.PopularQueries(now.Add(-time.Hour), 2)
+	c.Assert(err, gc.IsNil)
+	c.Assert(popular, gc.DeepEquals, []query.PopularQuery{
+		{Expression: "gophers", Count: 3},
+		{Expression: "golang", Count: 2},
+	})
+}
+
+func (s *InMemoryQueryLogTestSuite) TestClickCounts(c *gc.C) {
+	now := time.Now()
+	linkA, linkB := uuid.New(), uuid.New()
+	for _, click := range []*query.Click{
+		{LinkID: linkA, Timestamp: now.Add(-48 * time.Hour)},
+		{LinkID: linkA},
+		{LinkID: linkB},
+		{LinkID: linkB},
+	} {
+		c.Assert(s.log.RecordClick(click), gc.IsNil)
+	}
+
+	counts, err := s.log.ClickCounts(now.Add(-time.Hour))
+	c.Assert(err, gc.IsNil)
+	c.Assert(counts, gc.DeepEquals, map[uuid.UUID]uint64{linkA: 1, linkB: 2})
+}
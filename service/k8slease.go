@@ -0,0 +1,377 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewInterval = 5 * time.Second
+
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// microTimeLayout is the format of the timestamps in Lease objects.
+	microTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// errLeaseHeld is returned when a lease is held by another replica.
+var errLeaseHeld = xerrors.New("lease held by another holder")
+
+// KubernetesLeaseConfig encapsulates the configuration options for a
+// KubernetesLease.
+type KubernetesLeaseConfig struct {
+	// Namespace and Name identify the coordination.k8s.io/v1 Lease
+	// object used as the lock. If Namespace is not specified, the
+	// namespace of the pod's service account will be used.
+	Namespace string
+	Name      string
+
+	// Identity uniquely identifies this replica. If not specified, the
+	// hostname (i.e. the pod name) will be used.
+	Identity string
+
+	// APIServer is the URL of the Kubernetes API server. If not
+	// specified, the in-cluster configuration will be used.
+	APIServer string
+
+	// Token is the bearer token used for authenticating with the API
+	// server. If not specified, the pod's service account token will be
+	// used.
+	Token string
+
+	// HTTPClient is used for talking to the API server. If not specified,
+	// a client that trusts the cluster CA will be used.
+	HTTPClient *http.Client
+
+	// LeaseDuration is the time after which a lease that has not been
+	// renewed can be taken over by another replica. If not specified, a
+	// default value of 15s will be used.
+	LeaseDuration time.Duration
+
+	// RenewInterval controls how often the lease is renewed while held
+	// and how often acquisition is retried. It must be shorter than
+	// LeaseDuration. If not specified, a default value of 5s will be used.
+	RenewInterval time.Duration
+
+	// Clock returns the current time. If not specified, time.Now will be
+	// used.
+	Clock func() time.Time
+}
+
+func (cfg *KubernetesLeaseConfig) validate() error {
+	var err error
+	if cfg.Name == "" {
+		err = xerrors.New("lease name not specified")
+	}
+
+	if cfg.Namespace == "" {
+		if ns, nsErr := ioutil.ReadFile(serviceAccountDir + "/namespace"); nsErr == nil {
+			cfg.Namespace = strings.TrimSpace(string(ns))
+		} else {
+			err = xerrors.New("lease namespace not specified")
+		}
+	}
+	if cfg.Identity == "" {
+		if cfg.Identity, _ = os.Hostname(); cfg.Identity == "" {
+			err = xerrors.New("identity not specified")
+		}
+	}
+	if cfg.APIServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			err = xerrors.New("API server not specified and not running in a cluster")
+		}
+		cfg.APIServer = "https://" + net.JoinHostPort(host, port)
+	}
+	cfg.APIServer = strings.TrimSuffix(cfg.APIServer, "/")
+	if cfg.Token == "" {
+		if token, tokenErr := ioutil.ReadFile(serviceAccountDir + "/token"); tokenErr == nil {
+			cfg.Token = strings.TrimSpace(string(token))
+		}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = inClusterHTTPClient()
+	}
+
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = defaultLeaseDuration
+	}
+	if cfg.RenewInterval <= 0 {
+		cfg.RenewInterval = defaultRenewInterval
+	}
+	if cfg.RenewInterval >= cfg.LeaseDuration {
+		err = xerrors.New("renew interval must be shorter than the lease duration")
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return err
+}
+
+// inClusterHTTPClient returns an HTTP client that trusts the cluster CA, or
+// the default client if the CA certificate is not available.
+func inClusterHTTPClient() *http.Client {
+	caCert, err := ioutil.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return http.DefaultClient
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caCert)
+	return &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+}
+
+// KubernetesLease is a LeaderLock backed by a Kubernetes Lease object. It
+// follows the same protocol as the leader election helpers of client-go so
+// replicas coordinate through optimistic concurrency on the Lease.
+type KubernetesLease struct {
+	cfg KubernetesLeaseConfig
+
+	mu        sync.Mutex
+	stopRenew chan struct{}
+	renewDone chan struct{}
+}
+
+// NewKubernetesLease creates a new KubernetesLease using the provided config.
+func NewKubernetesLease(cfg KubernetesLeaseConfig) (*KubernetesLease, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("kubernetes lease config validation failed: %w", err)
+	}
+	return &KubernetesLease{cfg: cfg}, nil
+}
+
+// Acquire implements LeaderLock.
+func (l *KubernetesLease) Acquire(ctx context.Context) (<-chan struct{}, error) {
+	for {
+		// Errors other than errLeaseHeld are treated as transient
+		// (e.g. API server restarts) and acquisition is retried
+		if err := l.tryAcquireOrRenew(ctx); err == nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.cfg.RenewInterval):
+		}
+	}
+
+	lost := make(chan struct{})
+	l.mu.Lock()
+	l.stopRenew, l.renewDone = make(chan struct{}), make(chan struct{})
+	go l.renew(lost, l.stopRenew, l.renewDone)
+	l.mu.Unlock()
+	return lost, nil
+}
+
+// renew keeps the lease alive until stop is closed. If the lease cannot be
+// renewed before it expires, lost is closed.
+func (l *KubernetesLease) renew(lost, stop, done chan struct{}) {
+	defer close(done)
+
+	lastRenew := l.cfg.Clock()
+	ticker := time.NewTicker(l.cfg.RenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.cfg.RenewInterval)
+		err := l.tryAcquireOrRenew(ctx)
+		cancel()
+		switch {
+		case err == nil:
+			lastRenew = l.cfg.Clock()
+		case xerrors.Is(err, errLeaseHeld) || l.cfg.Clock().Sub(lastRenew) >= l.cfg.LeaseDuration:
+			close(lost)
+			return
+		}
+	}
+}
+
+// Release implements LeaderLock.
+func (l *KubernetesLease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	if l.stopRenew != nil {
+		close(l.stopRenew)
+		<-l.renewDone
+		l.stopRenew, l.renewDone = nil, nil
+	}
+	l.mu.Unlock()
+
+	lease, err := l.get(ctx)
+	if err != nil {
+		return err
+	} else if lease == nil || lease.Spec.HolderIdentity != l.cfg.Identity {
+		return nil
+	}
+
+	// Mirror client-go: clear the holder and shorten the lease so that
+	// other replicas can take over immediately
+	lease.Spec.HolderIdentity = ""
+	lease.Spec.LeaseDurationSeconds = 1
+	lease.Spec.RenewTime = l.now()
+	if err = l.update(ctx, lease); xerrors.Is(err, errLeaseHeld) {
+		err = nil
+	}
+	return err
+}
+
+// tryAcquireOrRenew creates or updates the lease so that it is held by this
+// replica. It returns errLeaseHeld if another replica holds a valid lease.
+func (l *KubernetesLease) tryAcquireOrRenew(ctx context.Context) error {
+	lease, err := l.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := l.now()
+	if lease == nil {
+		return l.create(ctx, &leaseObject{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMetadata{Name: l.cfg.Name, Namespace: l.cfg.Namespace},
+			Spec: leaseSpec{
+				HolderIdentity:       l.cfg.Identity,
+				LeaseDurationSeconds: int(l.cfg.LeaseDuration / time.Second),
+				AcquireTime:          now,
+				RenewTime:            now,
+			},
+		})
+	}
+
+	if holder := lease.Spec.HolderIdentity; holder != l.cfg.Identity {
+		if holder != "" && !lease.expired(l.cfg.Clock()) {
+			return errLeaseHeld
+		}
+		lease.Spec.HolderIdentity = l.cfg.Identity
+		lease.Spec.AcquireTime = now
+		lease.Spec.LeaseTransitions++
+	}
+	lease.Spec.LeaseDurationSeconds = int(l.cfg.LeaseDuration / time.Second)
+	lease.Spec.RenewTime = now
+	return l.update(ctx, lease)
+}
+
+func (l *KubernetesLease) now() string {
+	return l.cfg.Clock().UTC().Format(microTimeLayout)
+}
+
+func (l *KubernetesLease) leasesURL() string {
+	return l.cfg.APIServer + "/apis/coordination.k8s.io/v1/namespaces/" + l.cfg.Namespace + "/leases"
+}
+
+// get returns the lease object or nil if it does not exist.
+func (l *KubernetesLease) get(ctx context.Context) (*leaseObject, error) {
+	var lease leaseObject
+	status, err := l.do(ctx, http.MethodGet, l.leasesURL()+"/"+l.cfg.Name, nil, &lease)
+	if status == http.StatusNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (l *KubernetesLease) create(ctx context.Context, lease *leaseObject) error {
+	_, err := l.do(ctx, http.MethodPost, l.leasesURL(), lease, nil)
+	return err
+}
+
+// update replaces the lease object. Updates are conditional on the resource
+// version of lease; errLeaseHeld is returned if the lease was modified
+// concurrently.
+func (l *KubernetesLease) update(ctx context.Context, lease *leaseObject) error {
+	_, err := l.do(ctx, http.MethodPut, l.leasesURL()+"/"+l.cfg.Name, lease, nil)
+	return err
+}
+
+// do sends a request to the API server and decodes the response into out.
+func (l *KubernetesLease) do(ctx context.Context, method, url string, in, out interface{}) (int, error) {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return 0, err
+		}
+	}
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if l.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.cfg.Token)
+	}
+
+	res, err := l.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return 0, xerrors.Errorf("%s lease: %w", strings.ToLower(method), err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	switch {
+	case res.StatusCode == http.StatusConflict:
+		// Another replica created or updated the lease first
+		return res.StatusCode, errLeaseHeld
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		msg, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, xerrors.Errorf("%s lease: unexpected status %d: %s", strings.ToLower(method), res.StatusCode, strings.TrimSpace(string(msg)))
+	case out != nil:
+		if err = json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res.StatusCode, xerrors.Errorf("%s lease: %w", strings.ToLower(method), err)
+		}
+	}
+	return res.StatusCode, nil
+}
+
+// leaseObject is the subset of the coordination.k8s.io/v1 Lease resource used
+// for leader election.
+type leaseObject struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions,omitempty"`
+}
+
+// expired returns true if the lease has not been renewed within its duration.
+func (l *leaseObject) expired(now time.Time) bool {
+	renewed, err := time.Parse(microTimeLayout, l.Spec.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(l.Spec.LeaseDurationSeconds) * time.Second))
+}
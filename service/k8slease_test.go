@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(KubernetesLeaseTestSuite))
+
+type KubernetesLeaseTestSuite struct {
+	api *fakeLeaseAPI
+	srv *httptest.Server
+	now time.Time
+	mu  sync.Mutex
+}
+
+func (s *KubernetesLeaseTestSuite) SetUpTest(c *gc.C) {
+	s.api = &fakeLeaseAPI{}
+	s.srv = httptest.NewServer(s.api)
+	s.now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *KubernetesLeaseTestSuite) TearDownTest(c *gc.C) {
+	s.srv.Close()
+}
+
+func (s *KubernetesLeaseTestSuite) TestAcquireAndRelease(c *gc.C) {
+	lock := s.newLease(c, "replica-1")
+
+	lost, err := lock.Acquire(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(lost, gc.NotNil)
+	c.Assert(s.api.holder(), gc.Equals, "replica-1")
+	c.Assert(s.api.token, gc.Equals, "Bearer secret")
+
+	c.Assert(lock.Release(context.TODO()), gc.IsNil)
+	c.Assert(s.api.holder(), gc.Equals, "")
+
+	// Another replica can take over immediately after a release
+	other := s.newLease(c, "replica-2")
+	_, err = other.Acquire(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.api.holder(), gc.Equals, "replica-2")
+	c.Assert(other.Release(context.TODO()), gc.IsNil)
+}
+
+func (s *KubernetesLeaseTestSuite) TestAcquireBlocksWhileHeld(c *gc.C) {
+	leader := s.newLease(c, "replica-1")
+	_, err := leader.Acquire(context.TODO())
+	c.Assert(err, gc.IsNil)
+	defer func() { _ = leader.Release(context.TODO()) }()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	_, err = s.newLease(c, "replica-2").Acquire(ctx)
+	c.Assert(err, gc.Equals, context.DeadlineExceeded)
+	c.Assert(s.api.holder(), gc.Equals, "replica-1")
+}
+
+func (s *KubernetesLeaseTestSuite) TestTakeOverExpiredLease(c *gc.C) {
+	stale := s.newLease(c, "replica-1")
+	c.Assert(stale.tryAcquireOrRenew(context.TODO()), gc.IsNil)
+
+	// replica-1 stops renewing the lease
+	s.advance(time.Minute)
+	other := s.newLease(c, "replica-2")
+	_, err := other.Acquire(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.api.holder(), gc.Equals, "replica-2")
+	c.Assert(other.Release(context.TODO()), gc.IsNil)
+	c.Assert(s.api.lease.Spec.LeaseTransitions, gc.Equals, 1)
+}
+
+func (s *KubernetesLeaseTestSuite) TestLostLease(c *gc.C) {
+	lock := s.newLease(c, "replica-1")
+	lost, err := lock.Acquire(context.TODO())
+	c.Assert(err, gc.IsNil)
+
+	// Simulate another replica forcefully taking over the lease
+	s.api.mu.Lock()
+	s.api.lease.Spec.HolderIdentity = "replica-2"
+	s.api.lease.Spec.RenewTime = s.clock().Format(microTimeLayout)
+	s.api.mu.Unlock()
+
+	select {
+	case <-lost:
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for the lease to be lost")
+	}
+	c.Assert(lock.Release(context.TODO()), gc.IsNil)
+	c.Assert(s.api.holder(), gc.Equals, "replica-2", gc.Commentf("release must not clear a lease held by another replica"))
+}
+
+func (s *KubernetesLeaseTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewKubernetesLease(KubernetesLeaseConfig{
+		Namespace:     "default",
+		Name:          "pagerank",
+		Identity:      "replica-1",
+		APIServer:     s.srv.URL,
+		LeaseDuration: time.Second,
+		RenewInterval: time.Second,
+	})
+	c.Assert(err, gc.ErrorMatches, ".*renew interval must be shorter than the lease duration")
+}
+
+func (s *KubernetesLeaseTestSuite) newLease(c *gc.C, identity string) *KubernetesLease {
+	lock, err := NewKubernetesLease(KubernetesLeaseConfig{
+		Namespace:     "default",
+		Name:          "pagerank",
+		Identity:      identity,
+		APIServer:     s.srv.URL + "/",
+		Token:         "secret",
+		HTTPClient:    s.srv.Client(),
+		LeaseDuration: 15 * time.Second,
+		RenewInterval: 20 * time.Millisecond,
+		Clock:         s.clock,
+	})
+	c.Assert(err, gc.IsNil)
+	return lock
+}
+
+func (s *KubernetesLeaseTestSuite) clock() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+func (s *KubernetesLeaseTestSuite) advance(d time.Duration) {
+	s.mu.Lock()
+	s.now = s.now.Add(d)
+	s.mu.Unlock()
+}
+
+// fakeLeaseAPI emulates the subset of the Kubernetes API used for managing
+// a single Lease object, including optimistic concurrency checks.
+type fakeLeaseAPI struct {
+	mu      sync.Mutex
+	lease   *leaseObject
+	version int
+	token   string
+}
+
+func (a *fakeLeaseAPI) holder() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.lease == nil {
+		return ""
+	}
+	return a.lease.Spec.HolderIdentity
+}
+
+func (a *fakeLeaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = r.Header.Get("Authorization")
+
+	const leasesPath = "/apis/coordination.k8s.io/v1/namespaces/default/leases"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == leasesPath+"/pagerank":
+		if a.lease == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(a.lease)
+	case r.Method == http.MethodPost && r.URL.Path == leasesPath:
+		if a.lease != nil {
+			http.Error(w, "already exists", http.StatusConflict)
+			return
+		}
+		a.store(w, r)
+	case r.Method == http.MethodPut && r.URL.Path == leasesPath+"/pagerank":
+		var lease leaseObject
+		if err := json.NewDecoder(r.Body).Decode(&lease); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if a.lease == nil || lease.Metadata.ResourceVersion != a.lease.Metadata.ResourceVersion {
+			http.Error(w, "conflict", http.StatusConflict)
+			return
+		}
+		a.lease = &lease
+		a.bump(w)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (a *fakeLeaseAPI) store(w http.ResponseWriter, r *http.Request) {
+	var lease leaseObject
+	if err := json.NewDecoder(r.Body).Decode(&lease); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.lease = &lease
+	a.bump(w)
+}
+
+func (a *fakeLeaseAPI) bump(w http.ResponseWriter) {
+	a.version++
+	a.lease.Metadata.ResourceVersion = strconv.Itoa(a.version)
+	_ = json.NewEncoder(w).Encode(a.lease)
+}
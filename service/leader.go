@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// releaseTimeout bounds the time spent releasing a leader lock when a
+// service exits.
+const releaseTimeout = 5 * time.Second
+
+// ErrLeadershipLost is returned by services wrapped with RunAsLeader if the
+// leader lock is lost while they are running.
+var ErrLeadershipLost = xerrors.New("leadership lost")
+
+// LeaderLock is implemented by distributed locks that can be used for electing
+// a leader among the replicas of a service.
+type LeaderLock interface {
+	// Acquire blocks until the lock is acquired or ctx expires. While the
+	// lock is held, it is kept alive in the background; the returned
+	// channel is closed if the lock is lost.
+	Acquire(ctx context.Context) (lost <-chan struct{}, err error)
+
+	// Release gives up the lock so that another replica can acquire it
+	// without having to wait for it to expire.
+	Release(ctx context.Context) error
+}
+
+// RunAsLeader wraps svc so that it only runs while lock is held. Replicas that
+// do not hold the lock block until they acquire it. If the lock is lost, svc
+// is stopped and ErrLeadershipLost is returned so that the replica can be
+// restarted; the lock is always released when svc returns.
+func RunAsLeader(lock LeaderLock, svc Service) Service {
+	return &leaderService{lock: lock, svc: svc}
+}
+
+type leaderService struct {
+	lock LeaderLock
+	svc  Service
+}
+
+func (s *leaderService) Name() string { return s.svc.Name() }
+
+func (s *leaderService) Run(ctx context.Context) error {
+	lost, err := s.lock.Acquire(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Shut down before becoming the leader
+			return nil
+		}
+		return xerrors.Errorf("acquire leader lock: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lost:
+			cancel()
+		case <-runCtx.Done():
+		}
+	}()
+
+	err = s.svc.Run(runCtx)
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancelRelease()
+	if relErr := s.lock.Release(releaseCtx); relErr != nil && err == nil {
+		err = xerrors.Errorf("release leader lock: %w", relErr)
+	}
+
+	select {
+	case <-lost:
+		if ctx.Err() == nil && err == nil {
+			err = ErrLeadershipLost
+		}
+	default:
+	}
+	return err
+}
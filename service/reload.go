@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// defaultWatchInterval is the default interval for polling the watched
+// configuration file for changes.
+const defaultWatchInterval = 10 * time.Second
+
+// ReloaderConfig encapsulates the configuration options for a Reloader.
+type ReloaderConfig struct {
+	// Name of the reloader service. If not specified, "config-reloader"
+	// will be used.
+	Name string
+
+	// Reload re-reads the configuration and applies it to the running
+	// components (e.g. via crawler.Crawler.Reconfigure).
+	Reload func(ctx context.Context) error
+
+	// Signals that trigger a reload. If not specified, SIGHUP will be used.
+	Signals []os.Signal
+
+	// WatchFile, if specified, is polled for changes to its modification
+	// time or size and a reload is triggered whenever it changes.
+	WatchFile string
+
+	// WatchInterval controls how often WatchFile is polled. If not
+	// specified, a default value of 10s will be used.
+	WatchInterval time.Duration
+
+	// OnError is invoked with the errors returned by Reload. Failed
+	// reloads do not stop the reloader so the components keep running
+	// with their previous configuration. If not specified, errors are
+	// discarded.
+	OnError func(error)
+}
+
+func (cfg *ReloaderConfig) validate() error {
+	var err error
+	if cfg.Reload == nil {
+		err = xerrors.New("reload function not specified")
+	}
+
+	if cfg.Name == "" {
+		cfg.Name = "config-reloader"
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{syscall.SIGHUP}
+	}
+	if cfg.WatchInterval <= 0 {
+		cfg.WatchInterval = defaultWatchInterval
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(error) {}
+	}
+	return err
+}
+
+// Reloader is a Service that reloads the configuration of the running
+// components when one of the configured signals is received or when the
+// watched configuration file changes.
+type Reloader struct {
+	cfg ReloaderConfig
+}
+
+// NewReloader creates a new Reloader using the provided config.
+func NewReloader(cfg ReloaderConfig) (*Reloader, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("reloader config validation failed: %w", err)
+	}
+	return &Reloader{cfg: cfg}, nil
+}
+
+// Name implements Service.
+func (r *Reloader) Name() string { return r.cfg.Name }
+
+// Run implements Service.
+func (r *Reloader) Run(ctx context.Context) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, r.cfg.Signals...)
+	defer signal.Stop(sigCh)
+
+	var (
+		tickCh   <-chan time.Time
+		lastStat fileStamp
+	)
+	if r.cfg.WatchFile != "" {
+		lastStat = statFile(r.cfg.WatchFile)
+		ticker := time.NewTicker(r.cfg.WatchInterval)
+		defer ticker.Stop()
+		tickCh = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sigCh:
+		case <-tickCh:
+			stat := statFile(r.cfg.WatchFile)
+			if stat == lastStat {
+				continue
+			}
+			lastStat = stat
+		}
+
+		if err := r.cfg.Reload(ctx); err != nil {
+			r.cfg.OnError(xerrors.Errorf("reload configuration: %w", err))
+		}
+	}
+}
+
+// fileStamp captures the attributes of a file that are compared to detect
+// changes. The zero value is used for files that cannot be accessed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
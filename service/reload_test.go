@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ReloaderTestSuite))
+
+type ReloaderTestSuite struct{}
+
+func (s *ReloaderTestSuite) TestReloadOnSignal(c *gc.C) {
+	// Keep SIGUSR2 from terminating the process before the reloader
+	// registers for it
+	guardCh := make(chan os.Signal, 1)
+	signal.Notify(guardCh, syscall.SIGUSR2)
+	defer signal.Stop(guardCh)
+
+	reloadCh := make(chan struct{}, 10)
+	errCh := make(chan error, 10)
+	var calls int32
+	r, err := NewReloader(ReloaderConfig{
+		Reload: func(context.Context) error {
+			reloadCh <- struct{}{}
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return xerrors.New("bad config")
+			}
+			return nil
+		},
+		Signals: []os.Signal{syscall.SIGUSR2},
+		OnError: func(err error) { errCh <- err },
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(r.Name(), gc.Equals, "config-reloader")
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	doneCh := make(chan error, 1)
+	go func() { doneCh <- r.Run(ctx) }()
+
+	// A failed reload is reported but does not stop the reloader
+	s.signalUntilReloaded(c, reloadCh)
+	c.Assert(<-errCh, gc.ErrorMatches, "reload configuration: bad config")
+	s.signalUntilReloaded(c, reloadCh)
+
+	cancel()
+	c.Assert(<-doneCh, gc.IsNil)
+}
+
+func (s *ReloaderTestSuite) TestReloadOnFileChange(c *gc.C) {
+	dir, err := ioutil.TempDir("", "reloader")
+	c.Assert(err, gc.IsNil)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "crawler.json")
+	c.Assert(ioutil.WriteFile(path, []byte(`{}`), 0600), gc.IsNil)
+
+	reloadCh := make(chan struct{}, 1)
+	r, err := NewReloader(ReloaderConfig{
+		Reload: func(context.Context) error {
+			reloadCh <- struct{}{}
+			return nil
+		},
+		WatchFile:     path,
+		WatchInterval: 10 * time.Millisecond,
+	})
+	c.Assert(err, gc.IsNil)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = r.Run(ctx) }()
+
+	// Unchanged files do not trigger a reload
+	select {
+	case <-reloadCh:
+		c.Fatal("unexpected reload")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.Assert(ioutil.WriteFile(path, []byte(`{"fetch_workers": 4}`), 0600), gc.IsNil)
+	select {
+	case <-reloadCh:
+	case <-time.After(time.Second):
+		c.Fatal("timed out waiting for reload")
+	}
+}
+
+func (s *ReloaderTestSuite) TestMissingReloadFunc(c *gc.C) {
+	_, err := NewReloader(ReloaderConfig{})
+	c.Assert(err, gc.ErrorMatches, "reloader config validation failed: reload function not specified")
+}
+
+// signalUntilReloaded sends SIGUSR2 to the process until the reloader, which
+// may not have registered for the signal yet, picks it up.
+func (s *ReloaderTestSuite) signalUntilReloaded(c *gc.C, reloadCh <-chan struct{}) {
+	deadline := time.After(time.Second)
+	for {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+		select {
+		case <-reloadCh:
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			c.Fatal("timed out waiting for reload")
+		}
+	}
+}
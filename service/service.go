@@ -0,0 +1,155 @@
+// Package service provides the building blocks for running the long-lived
+// components of the search engine (crawlers, updaters, the frontend etc.) in
+// environments such as Kubernetes: graceful shutdown on SIGTERM within a
+// configurable grace period, optional leader election so that only a
+// single replica of a service is active at any time and configuration reloads
+// triggered by SIGHUP or changes to a configuration file.
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/xerrors"
+)
+
+// defaultGracePeriod matches the default termination grace period of
+// Kubernetes pods.
+const defaultGracePeriod = 30 * time.Second
+
+// ErrGracePeriodExceeded is returned by Run if some services did not shut
+// down within the configured grace period.
+var ErrGracePeriodExceeded = xerrors.New("grace period exceeded")
+
+// Service is implemented by long-lived components.
+type Service interface {
+	// Name returns a human-readable name for the service.
+	Name() string
+
+	// Run executes the service until ctx expires or an error occurs.
+	// Once ctx expires, services should stop starting new work (e.g.
+	// crawl passes), finish any in-flight work and return.
+	Run(ctx context.Context) error
+}
+
+// Func adapts a function to the Service interface.
+func Func(name string, run func(ctx context.Context) error) Service {
+	return funcService{name: name, run: run}
+}
+
+type funcService struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func (s funcService) Name() string                  { return s.name }
+func (s funcService) Run(ctx context.Context) error { return s.run(ctx) }
+
+// Config encapsulates the configuration options for Run.
+type Config struct {
+	// Services to run.
+	Services []Service
+
+	// GracePeriod is the time that services are given to drain their
+	// in-flight work once a shutdown has been initiated. If not
+	// specified, a default value of 30s will be used.
+	GracePeriod time.Duration
+
+	// Signals that initiate a shutdown. If not specified, SIGINT and
+	// SIGTERM will be used.
+	Signals []os.Signal
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if len(cfg.Services) == 0 {
+		err = xerrors.New("no services specified")
+	}
+	for i, svc := range cfg.Services {
+		if svc == nil {
+			err = xerrors.Errorf("service %d is nil", i)
+		}
+	}
+
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = defaultGracePeriod
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	return err
+}
+
+type result struct {
+	name string
+	err  error
+}
+
+// Run executes the provided services concurrently and blocks until all of
+// them have returned. A shutdown is initiated when one of the configured
+// signals is received, ctx expires or any of the services fails: the context
+// passed to the services is cancelled and they are given GracePeriod to
+// return. If some of them are still running after the grace period,
+// Run returns ErrGracePeriodExceeded without waiting for them.
+func Run(ctx context.Context, cfg Config) error {
+	if err := cfg.validate(); err != nil {
+		return xerrors.Errorf("service config validation failed: %w", err)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, cfg.Signals...)
+	defer signal.Stop(sigCh)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resCh := make(chan result, len(cfg.Services))
+	running := make(map[string]int)
+	for _, svc := range cfg.Services {
+		running[svc.Name()]++
+		go func(svc Service) {
+			resCh <- result{name: svc.Name(), err: svc.Run(runCtx)}
+		}(svc)
+	}
+
+	var (
+		err      error
+		graceCh  <-chan time.Time
+		shutdown = func() {
+			if graceCh == nil {
+				cancel()
+				graceCh = time.After(cfg.GracePeriod)
+			}
+		}
+	)
+	for len(running) != 0 {
+		select {
+		case res := <-resCh:
+			if running[res.name]--; running[res.name] == 0 {
+				delete(running, res.name)
+			}
+			if res.err != nil {
+				err = multierror.Append(err, xerrors.Errorf("%s: %w", res.name, res.err))
+				shutdown()
+			}
+		case <-sigCh:
+			shutdown()
+		case <-runCtx.Done():
+			shutdown()
+		case <-graceCh:
+			names := make([]string, 0, len(running))
+			for name := range running {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return multierror.Append(err, xerrors.Errorf("%s still running: %w", strings.Join(names, ", "), ErrGracePeriodExceeded))
+		}
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ServiceTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type ServiceTestSuite struct{}
+
+func (s *ServiceTestSuite) TestShutdownOnSignal(c *gc.C) {
+	started := make(chan struct{})
+	var drained bool
+	svc := Func("crawler", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		drained = true
+		return nil
+	})
+
+	go func() {
+		<-started
+		_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	}()
+
+	err := Run(context.TODO(), Config{
+		Services: []Service{svc},
+		Signals:  []os.Signal{syscall.SIGUSR1},
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(drained, gc.Equals, true)
+}
+
+func (s *ServiceTestSuite) TestShutdownOnServiceError(c *gc.C) {
+	var stopped bool
+	err := Run(context.TODO(), Config{
+		Services: []Service{
+			Func("frontend", func(ctx context.Context) error {
+				<-ctx.Done()
+				stopped = true
+				return nil
+			}),
+			Func("crawler", func(context.Context) error {
+				return xerrors.New("boom")
+			}),
+		},
+	})
+	c.Assert(err, gc.ErrorMatches, "(?s).*crawler: boom.*")
+	c.Assert(stopped, gc.Equals, true, gc.Commentf("expected remaining services to be stopped"))
+}
+
+func (s *ServiceTestSuite) TestGracePeriodExceeded(c *gc.C) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	block := make(chan struct{})
+	defer close(block)
+	err := Run(ctx, Config{
+		Services: []Service{
+			Func("stuck", func(context.Context) error {
+				<-block
+				return nil
+			}),
+			Func("well-behaved", func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			}),
+		},
+		GracePeriod: 50 * time.Millisecond,
+	})
+	c.Assert(xerrors.Is(err, ErrGracePeriodExceeded), gc.Equals, true)
+	c.Assert(err, gc.ErrorMatches, "(?s).*stuck still running.*")
+}
+
+func (s *ServiceTestSuite) TestConfigValidation(c *gc.C) {
+	err := Run(context.TODO(), Config{})
+	c.Assert(err, gc.ErrorMatches, ".*no services specified")
+}
+
+func (s *ServiceTestSuite) TestRunAsLeader(c *gc.C) {
+	lock := newFakeLock()
+	var ran bool
+	svc := RunAsLeader(lock, Func("pagerank", func(context.Context) error {
+		ran = true
+		return nil
+	}))
+
+	c.Assert(svc.Name(), gc.Equals, "pagerank")
+	c.Assert(svc.Run(context.TODO()), gc.IsNil)
+	c.Assert(ran, gc.Equals, true)
+	c.Assert(lock.released, gc.Equals, true)
+}
+
+func (s *ServiceTestSuite) TestRunAsLeaderLostLock(c *gc.C) {
+	lock := newFakeLock()
+	svc := RunAsLeader(lock, Func("pagerank", func(ctx context.Context) error {
+		lock.lose()
+		<-ctx.Done()
+		return nil
+	}))
+
+	err := svc.Run(context.TODO())
+	c.Assert(xerrors.Is(err, ErrLeadershipLost), gc.Equals, true)
+	c.Assert(lock.released, gc.Equals, true)
+}
+
+func (s *ServiceTestSuite) TestRunAsLeaderShutdownBeforeAcquire(c *gc.C) {
+	lock := newFakeLock()
+	lock.held = true
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	var ran bool
+	svc := RunAsLeader(lock, Func("pagerank", func(context.Context) error {
+		ran = true
+		return nil
+	}))
+	c.Assert(svc.Run(ctx), gc.IsNil)
+	c.Assert(ran, gc.Equals, false)
+}
+
+// fakeLock is a LeaderLock whose loss can be triggered by tests.
+type fakeLock struct {
+	mu       sync.Mutex
+	held     bool
+	released bool
+	lostCh   chan struct{}
+}
+
+func newFakeLock() *fakeLock {
+	return &fakeLock{lostCh: make(chan struct{})}
+}
+
+func (l *fakeLock) Acquire(ctx context.Context) (<-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return l.lostCh, nil
+}
+
+func (l *fakeLock) Release(context.Context) error {
+	l.mu.Lock()
+	l.released = true
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *fakeLock) lose() { close(l.lostCh) }
@@ -0,0 +1,57 @@
+package shortestpath
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ShortestPathTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type ShortestPathTestSuite struct{}
+
+func (s *ShortestPathTestSuite) TestShortestPathTo(c *gc.C) {
+	calc, err := NewCalculator(2)
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(calc.Close(), gc.IsNil) }()
+
+	for _, id := range []string{"a", "b", "c", "d", "island"} {
+		calc.AddVertex(id)
+	}
+	edges := []struct {
+		src, dst string
+		cost     int
+	}{
+		{"a", "b", 1},
+		{"a", "c", 5},
+		{"b", "c", 1},
+		{"c", "d", 2},
+		{"b", "d", 7},
+	}
+	for _, e := range edges {
+		c.Assert(calc.AddEdge(e.src, e.dst, e.cost), gc.IsNil)
+	}
+	c.Assert(calc.AddEdge("a", "b", -1), gc.NotNil)
+
+	c.Assert(calc.CalculateShortestPaths(context.TODO(), "a"), gc.IsNil)
+
+	path, cost, err := calc.ShortestPathTo("d")
+	c.Assert(err, gc.IsNil)
+	c.Assert(path, gc.DeepEquals, []string{"a", "b", "c", "d"})
+	c.Assert(cost, gc.Equals, 4)
+
+	path, cost, err = calc.ShortestPathTo("a")
+	c.Assert(err, gc.IsNil)
+	c.Assert(path, gc.DeepEquals, []string{"a"})
+	c.Assert(cost, gc.Equals, 0)
+
+	_, _, err = calc.ShortestPathTo("island")
+	c.Assert(xerrors.Is(err, ErrUnreachable), gc.Equals, true)
+
+	_, _, err = calc.ShortestPathTo("missing")
+	c.Assert(xerrors.Is(err, ErrUnknownVertex), gc.Equals, true)
+}
@@ -0,0 +1,179 @@
+// Package embedding integrates the text indexer with embedding services for
+// semantic search. It provides an index.Embedder that talks to an HTTP
+// embedding service, an index.Enricher that stores the embedding of each
+// document before it is indexed and a helper for embedding search queries.
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	// maxInputRunes bounds the length of the document text that is sent to
+	// the embedding service as models only consider a limited context.
+	maxInputRunes = 8192
+)
+
+// HTTPEmbedderConfig encapsulates the configuration options for an
+// HTTPEmbedder.
+type HTTPEmbedderConfig struct {
+	// Endpoint is the URL of the embeddings API of the service.
+	Endpoint string
+
+	// Model, if specified, is sent along with each request to select the
+	// embedding model.
+	Model string
+
+	// HTTPClient is used for talking to the service. If not specified, a
+	// client with a 30s timeout will be used.
+	HTTPClient *http.Client
+}
+
+func (cfg *HTTPEmbedderConfig) validate() error {
+	var err error
+	if u, parseErr := url.Parse(cfg.Endpoint); parseErr != nil {
+		err = xerrors.Errorf("invalid endpoint: %w", parseErr)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		err = xerrors.Errorf("invalid endpoint: unsupported scheme %q", u.Scheme)
+	}
+
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return err
+}
+
+// HTTPEmbedder is an index.Embedder backed by an embedding service that
+// implements the widely supported OpenAI-style embeddings API: texts are
+// POSTed as `{"model": ..., "input": [...]}` and the service responds with
+// `{"data": [{"index": ..., "embedding": [...]}, ...]}`.
+type HTTPEmbedder struct {
+	cfg HTTPEmbedderConfig
+}
+
+// NewHTTPEmbedder returns a new HTTPEmbedder instance using the provided
+// config.
+func NewHTTPEmbedder(cfg HTTPEmbedderConfig) (*HTTPEmbedder, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("embedder config validation failed: %w", err)
+	}
+	return &HTTPEmbedder{cfg: cfg}, nil
+}
+
+type embedRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed implements index.Embedder. Errors caused by the service being
+// unreachable, overloaded or failing match index.ErrUnavailable.
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embedRequest{Model: e.cfg.Model, Input: texts})
+	if err != nil {
+		return nil, xerrors.Errorf("embed: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, xerrors.Errorf("embed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.cfg.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("embed: %w", index.Unavailable(err))
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(res.Body)
+		err = xerrors.Errorf("embedding service responded with %d: %s", res.StatusCode, bytes.TrimSpace(msg))
+		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+			err = index.Unavailable(err)
+		}
+		return nil, xerrors.Errorf("embed: %w", err)
+	}
+
+	var embedRes embedResponse
+	if err = json.NewDecoder(res.Body).Decode(&embedRes); err != nil {
+		return nil, xerrors.Errorf("embed: decode response: %w", err)
+	}
+	embeddings := make([][]float32, len(texts))
+	for _, item := range embedRes.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, xerrors.Errorf("embed: response references unknown input %d", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	for i, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return nil, xerrors.Errorf("embed: response is missing the embedding of input %d", i)
+		}
+	}
+	return embeddings, nil
+}
+
+// Enricher is an index.Enricher that populates the Embedding field of
+// documents so they can be matched by semantic queries.
+type Enricher struct {
+	embedder index.Embedder
+}
+
+// NewEnricher returns a new Enricher that computes embeddings using embedder.
+func NewEnricher(embedder index.Embedder) *Enricher {
+	return &Enricher{embedder: embedder}
+}
+
+// Enrich implements index.Enricher. The embedding is computed from the title
+// and the main text of the document if available or its content otherwise.
+// Documents that already have an embedding or no text are left untouched.
+func (e *Enricher) Enrich(ctx context.Context, doc *index.Document) error {
+	if len(doc.Embedding) != 0 {
+		return nil
+	}
+
+	text := doc.MainText
+	if text == "" {
+		text = doc.Content
+	}
+	if doc.Title == "" && text == "" {
+		return nil
+	}
+	if runes := []rune(text); len(runes) > maxInputRunes {
+		text = string(runes[:maxInputRunes])
+	}
+
+	embeddings, err := e.embedder.Embed(ctx, []string{doc.Title + "\n" + text})
+	if err != nil {
+		return err
+	}
+	doc.Embedding = embeddings[0]
+	return nil
+}
+
+// SemanticQuery returns a QueryTypeSemantic query for expression whose
+// embedding is computed using embedder.
+func SemanticQuery(ctx context.Context, embedder index.Embedder, expression string) (index.Query, error) {
+	embeddings, err := embedder.Embed(ctx, []string{expression})
+	if err != nil {
+		return index.Query{}, xerrors.Errorf("semantic query: %w", err)
+	}
+	return index.Query{Type: index.QueryTypeSemantic, Expression: expression, Embedding: embeddings[0]}, nil
+}
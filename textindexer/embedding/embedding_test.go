@@ -0,0 +1,120 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(EmbeddingTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type EmbeddingTestSuite struct{}
+
+// fakeService embeds each text as a vector holding its length and the number
+// of spaces it contains. Responses list the embeddings in reverse order to
+// verify that the index of each item is honored.
+func fakeService(c *gc.C, requests *[]embedRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, gc.Equals, http.MethodPost)
+		c.Check(r.Header.Get("Content-Type"), gc.Equals, "application/json")
+
+		var req embedRequest
+		c.Assert(json.NewDecoder(r.Body).Decode(&req), gc.IsNil)
+		if requests != nil {
+			*requests = append(*requests, req)
+		}
+
+		var res embedResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			res.Data = append(res.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{
+				Index:     i,
+				Embedding: []float32{float32(len(req.Input[i])), float32(strings.Count(req.Input[i], " "))},
+			})
+		}
+		c.Assert(json.NewEncoder(w).Encode(res), gc.IsNil)
+	}))
+}
+
+func (s *EmbeddingTestSuite) TestEmbed(c *gc.C) {
+	var requests []embedRequest
+	srv := fakeService(c, &requests)
+	defer srv.Close()
+
+	e, err := NewHTTPEmbedder(HTTPEmbedderConfig{Endpoint: srv.URL + "/v1/embeddings", Model: "mini"})
+	c.Assert(err, gc.IsNil)
+
+	embeddings, err := e.Embed(context.TODO(), []string{"go", "gopher tunnels"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(embeddings, gc.DeepEquals, [][]float32{{2, 0}, {14, 1}})
+	c.Assert(requests, gc.DeepEquals, []embedRequest{{Model: "mini", Input: []string{"go", "gopher tunnels"}}})
+}
+
+func (s *EmbeddingTestSuite) TestEmbedErrors(c *gc.C) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "model is loading", status)
+	}))
+	defer srv.Close()
+
+	e, err := NewHTTPEmbedder(HTTPEmbedderConfig{Endpoint: srv.URL})
+	c.Assert(err, gc.IsNil)
+
+	_, err = e.Embed(context.TODO(), []string{"go"})
+	c.Assert(err, gc.ErrorMatches, "embed: embedding service responded with 503: model is loading")
+	c.Assert(xerrors.Is(err, index.ErrUnavailable), gc.Equals, true)
+
+	status = http.StatusBadRequest
+	_, err = e.Embed(context.TODO(), []string{"go"})
+	c.Assert(err, gc.ErrorMatches, "embed: embedding service responded with 400: .*")
+	c.Assert(xerrors.Is(err, index.ErrUnavailable), gc.Equals, false)
+
+	_, err = NewHTTPEmbedder(HTTPEmbedderConfig{Endpoint: "ftp://embed.local"})
+	c.Assert(err, gc.ErrorMatches, ".*unsupported scheme.*")
+}
+
+func (s *EmbeddingTestSuite) TestEnricher(c *gc.C) {
+	var requests []embedRequest
+	srv := fakeService(c, &requests)
+	defer srv.Close()
+	e, err := NewHTTPEmbedder(HTTPEmbedderConfig{Endpoint: srv.URL})
+	c.Assert(err, gc.IsNil)
+	enricher := NewEnricher(e)
+
+	doc := &index.Document{Title: "Gophers", Content: "Menu Gophers dig", MainText: "Gophers dig"}
+	c.Assert(enricher.Enrich(context.TODO(), doc), gc.IsNil)
+	c.Assert(doc.Embedding, gc.DeepEquals, []float32{19, 1})
+	c.Assert(requests[0].Input, gc.DeepEquals, []string{"Gophers\nGophers dig"})
+
+	// Documents with an embedding or without any text are skipped
+	doc = &index.Document{Title: "Gophers", Embedding: []float32{1}}
+	c.Assert(enricher.Enrich(context.TODO(), doc), gc.IsNil)
+	c.Assert(enricher.Enrich(context.TODO(), new(index.Document)), gc.IsNil)
+	c.Assert(requests, gc.HasLen, 1)
+}
+
+func (s *EmbeddingTestSuite) TestSemanticQuery(c *gc.C) {
+	srv := fakeService(c, nil)
+	defer srv.Close()
+	e, err := NewHTTPEmbedder(HTTPEmbedderConfig{Endpoint: srv.URL})
+	c.Assert(err, gc.IsNil)
+
+	q, err := SemanticQuery(context.TODO(), e, "burrowing rodents")
+	c.Assert(err, gc.IsNil)
+	c.Assert(q, gc.DeepEquals, index.Query{
+		Type:       index.QueryTypeSemantic,
+		Expression: "burrowing rodents",
+		Embedding:  []float32{17, 1},
+	})
+}
@@ -0,0 +1,131 @@
+// Package expiry implements an index maintenance job that removes the
+// documents of links that the crawler has not successfully retrieved for a
+// while, so that search results do not point to pages that have disappeared.
+package expiry
+
+import (
+	"context"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	minUUID = uuid.Nil
+	maxUUID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+)
+
+// Graph is implemented by objects that can list the links of a link graph.
+type Graph interface {
+	Links(fromID, toID uuid.UUID, retrievedBefore time.Time) (graph.LinkIterator, error)
+}
+
+// Indexer is implemented by objects that can look up and delete indexed
+// documents.
+type Indexer interface {
+	FindByID(linkID uuid.UUID) (*index.Document, error)
+	Delete(linkID uuid.UUID) error
+}
+
+// Expirer periodically deletes the indexed documents whose links have not
+// been retrieved within a configurable time-to-live. The crawler only bumps
+// the retrieval time of a link when it successfully fetches it, so links that
+// keep failing (or that are no longer crawled) eventually expire, just like
+// their stale edges are pruned from the graph.
+type Expirer struct {
+	graph   Graph
+	indexer Indexer
+	ttl     time.Duration
+}
+
+// NewExpirer returns a new Expirer that deletes documents from indexer whose
+// links in g have not been retrieved within ttl.
+func NewExpirer(g Graph, indexer Indexer, ttl time.Duration) *Expirer {
+	return &Expirer{
+		graph:   g,
+		indexer: indexer,
+		ttl:     ttl,
+	}
+}
+
+// Run expires stale documents every interval until ctx expires or an error
+// occurs.
+func (e *Expirer) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		if _, err := e.Expire(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Expire performs a single pass over the links that were last retrieved
+// before the TTL cutoff and returns the number of documents that were
+// deleted. Links that were never retrieved are skipped as are documents that
+// were fetched after the cutoff (e.g. when the graph update of a crawl pass
+// failed but the page was still indexed).
+func (e *Expirer) Expire(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-e.ttl)
+	staleIDs, err := e.staleLinkIDs(cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int
+	for _, linkID := range staleIDs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		doc, err := e.indexer.FindByID(linkID)
+		if xerrors.Is(err, index.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return deleted, xerrors.Errorf("expire documents: %w", err)
+		} else if doc.FetchedAt.After(cutoff) {
+			continue
+		}
+
+		// The document may have been deleted concurrently
+		if err = e.indexer.Delete(linkID); xerrors.Is(err, index.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return deleted, xerrors.Errorf("expire documents: %w", err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// staleLinkIDs returns the IDs of the links that were last retrieved before
+// cutoff.
+func (e *Expirer) staleLinkIDs(cutoff time.Time) ([]uuid.UUID, error) {
+	it, err := e.graph.Links(minUUID, maxUUID, cutoff)
+	if err != nil {
+		return nil, xerrors.Errorf("list stale links: %w", err)
+	}
+
+	var ids []uuid.UUID
+	for it.Next() {
+		if link := it.Link(); !link.RetrievedAt.IsZero() {
+			ids = append(ids, link.ID)
+		}
+	}
+	if err = it.Error(); err != nil {
+		_ = it.Close()
+		return nil, xerrors.Errorf("list stale links: %w", err)
+	}
+	if err = it.Close(); err != nil {
+		return nil, xerrors.Errorf("list stale links: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,105 @@
+package expiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ExpiryTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type ExpiryTestSuite struct{}
+
+func (s *ExpiryTestSuite) TestExpireStaleDocuments(c *gc.C) {
+	var (
+		g       = memory.NewInMemoryGraph()
+		idx     = newFakeIndexer()
+		now     = time.Now()
+		ttl     = 30 * 24 * time.Hour
+		longAgo = now.Add(-2 * ttl)
+	)
+
+	fresh := s.addLink(c, g, idx, "https://example.com/fresh", now.Add(-time.Hour), now.Add(-time.Hour))
+	stale := s.addLink(c, g, idx, "https://example.com/stale", longAgo, longAgo)
+	// The graph update failed but the page was indexed recently
+	reindexed := s.addLink(c, g, idx, "https://example.com/reindexed", longAgo, now.Add(-time.Hour))
+	// Links that were never retrieved or indexed are skipped
+	c.Assert(g.UpsertLink(&graph.Link{URL: "https://example.com/new"}), gc.IsNil)
+	unindexed := &graph.Link{URL: "https://example.com/unindexed", RetrievedAt: longAgo}
+	c.Assert(g.UpsertLink(unindexed), gc.IsNil)
+
+	e := NewExpirer(g, idx, ttl)
+	deleted, err := e.Expire(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(deleted, gc.Equals, 1)
+
+	_, err = idx.FindByID(stale)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+	for _, id := range []uuid.UUID{fresh, reindexed} {
+		_, err = idx.FindByID(id)
+		c.Assert(err, gc.IsNil)
+	}
+
+	// Expiring again is a no-op
+	deleted, err = e.Expire(context.TODO())
+	c.Assert(err, gc.IsNil)
+	c.Assert(deleted, gc.Equals, 0)
+}
+
+func (s *ExpiryTestSuite) TestRunStopsWhenContextExpires(c *gc.C) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	e := NewExpirer(memory.NewInMemoryGraph(), newFakeIndexer(), time.Hour)
+	c.Assert(e.Run(ctx, time.Minute), gc.IsNil)
+}
+
+func (s *ExpiryTestSuite) addLink(c *gc.C, g graph.Graph, idx *fakeIndexer, url string, retrievedAt, fetchedAt time.Time) uuid.UUID {
+	link := &graph.Link{URL: url, RetrievedAt: retrievedAt}
+	c.Assert(g.UpsertLink(link), gc.IsNil)
+	c.Assert(idx.Index(&index.Document{LinkID: link.ID, URL: url, FetchedAt: fetchedAt}), gc.IsNil)
+	return link.ID
+}
+
+// fakeIndexer is a map-backed index.Indexer.
+type fakeIndexer struct {
+	index.Indexer
+
+	docs map[uuid.UUID]*index.Document
+}
+
+func newFakeIndexer() *fakeIndexer {
+	return &fakeIndexer{docs: make(map[uuid.UUID]*index.Document)}
+}
+
+func (f *fakeIndexer) Index(doc *index.Document) error {
+	dCopy := *doc
+	f.docs[doc.LinkID] = &dCopy
+	return nil
+}
+
+func (f *fakeIndexer) FindByID(linkID uuid.UUID) (*index.Document, error) {
+	doc := f.docs[linkID]
+	if doc == nil {
+		return nil, index.ErrNotFound
+	}
+	dCopy := *doc
+	return &dCopy, nil
+}
+
+func (f *fakeIndexer) Delete(linkID uuid.UUID) error {
+	if f.docs[linkID] == nil {
+		return index.ErrNotFound
+	}
+	delete(f.docs, linkID)
+	return nil
+}
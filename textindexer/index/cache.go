@@ -0,0 +1,214 @@
+package index
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultCacheSize = 10000
+	defaultCacheTTL  = 5 * time.Minute
+)
+
+/*
+CacheConfig controls the size of the LRU cache used by WithCache and how long
+cached documents remain valid.  If not specified, up to 10000 documents are
+cached for 5 minutes.
+*/
+type CacheConfig struct {
+	Size int
+	TTL  time.Duration
+}
+
+func (cfg CacheConfig) size() int {
+	if cfg.Size <= 0 {
+		return defaultCacheSize
+	}
+	return cfg.Size
+}
+
+func (cfg CacheConfig) ttl() time.Duration {
+	if cfg.TTL <= 0 {
+		return defaultCacheTTL
+	}
+	return cfg.TTL
+}
+
+/*
+WithCache decorates idx with an LRU cache for FindByID lookups.  Cached
+documents are invalidated when they are modified through the returned indexer
+and the whole cache is purged when a reindex is committed; changes applied to
+idx by other clients become visible once the cached entries expire.
+*/
+func WithCache(idx Indexer, cfg CacheConfig) Indexer {
+	return &cachingIndexer{Indexer: idx, cache: newDocCache(cfg.size(), cfg.ttl())}
+}
+
+type cachingIndexer struct {
+	Indexer
+	cache *docCache
+}
+
+func (c *cachingIndexer) Index(doc *Document) error {
+	err := c.Indexer.Index(doc)
+	c.cache.invalidate(doc.LinkID)
+	return err
+}
+
+func (c *cachingIndexer) FindByID(linkID uuid.UUID) (*Document, error) {
+	if doc := c.cache.get(linkID); doc != nil {
+		return doc, nil
+	}
+
+	gen := c.cache.generation()
+	doc, err := c.Indexer.FindByID(linkID)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(gen, doc)
+	return copyDoc(doc), nil
+}
+
+func (c *cachingIndexer) UpdateScore(linkID uuid.UUID, score float64) error {
+	err := c.Indexer.UpdateScore(linkID, score)
+	c.cache.invalidate(linkID)
+	return err
+}
+
+func (c *cachingIndexer) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	err := c.Indexer.UpdateFields(linkID, fields)
+	c.cache.invalidate(linkID)
+	return err
+}
+
+func (c *cachingIndexer) UpdateFieldsIfVersion(linkID uuid.UUID, version uint64, fields map[string]interface{}) error {
+	err := c.Indexer.UpdateFieldsIfVersion(linkID, version, fields)
+	c.cache.invalidate(linkID)
+	return err
+}
+
+func (c *cachingIndexer) Delete(linkID uuid.UUID) error {
+	err := c.Indexer.Delete(linkID)
+	c.cache.invalidate(linkID)
+	return err
+}
+
+func (c *cachingIndexer) CommitReindex() error {
+	err := c.Indexer.CommitReindex()
+	c.cache.purge()
+	return err
+}
+
+//docCache is an LRU cache of documents with a per-entry expiry time
+type docCache struct {
+	mu      sync.Mutex
+	maxSize int
+	ttl     time.Duration
+	now     func() time.Time
+	lru     *list.List
+	entries map[uuid.UUID]*list.Element
+
+	//gen is incremented on every invalidation so that documents fetched
+	//before a concurrent update completed are not cached
+	gen uint64
+}
+
+type docCacheEntry struct {
+	doc       *Document
+	expiresAt time.Time
+}
+
+func newDocCache(maxSize int, ttl time.Duration) *docCache {
+	return &docCache{
+		maxSize: maxSize,
+		ttl:     ttl,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+//get returns a copy of the cached document with the specified ID or nil if
+//the document is not cached or its entry has expired
+func (c *docCache) get(linkID uuid.UUID) *Document {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[linkID]
+	if !found {
+		return nil
+	}
+	entry := elem.Value.(*docCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return copyDoc(entry.doc)
+}
+
+func (c *docCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+//put caches a copy of doc unless the cache was invalidated after gen was
+//obtained
+func (c *docCache) put(gen uint64, doc *Document) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	entry := &docCacheEntry{doc: copyDoc(doc), expiresAt: c.now().Add(c.ttl)}
+	if elem, found := c.entries[doc.LinkID]; found {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[doc.LinkID] = c.lru.PushFront(entry)
+	if c.lru.Len() > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *docCache) invalidate(linkID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	if elem, found := c.entries[linkID]; found {
+		c.remove(elem)
+	}
+}
+
+func (c *docCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	c.lru.Init()
+	c.entries = make(map[uuid.UUID]*list.Element)
+}
+
+func (c *docCache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*docCacheEntry).doc.LinkID)
+	c.lru.Remove(elem)
+}
+
+func copyDoc(d *Document) *Document {
+	dCopy := new(Document)
+	*dCopy = *d
+	dCopy.Keywords = append([]string(nil), d.Keywords...)
+	dCopy.Keyphrases = append([]string(nil), d.Keyphrases...)
+	dCopy.Embedding = append([]float32(nil), d.Embedding...)
+	dCopy.Entities = append([]Entity(nil), d.Entities...)
+	dCopy.AnchorText = append([]string(nil), d.AnchorText...)
+	return dCopy
+}
@@ -0,0 +1,111 @@
+package index
+
+import (
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CacheTestSuite))
+
+type CacheTestSuite struct{}
+
+func (s *CacheTestSuite) TestFindByIDIsCached(c *gc.C) {
+	idx := newCountingIndexer()
+	ci := WithCache(idx, CacheConfig{})
+
+	doc := &Document{LinkID: uuid.New(), Title: "cached", Keywords: []string{"go"}}
+	c.Assert(ci.Index(doc), gc.IsNil)
+
+	for i := 0; i < 3; i++ {
+		found, err := ci.FindByID(doc.LinkID)
+		c.Assert(err, gc.IsNil)
+		c.Assert(found.Title, gc.Equals, "cached")
+		c.Assert(found.Keywords, gc.DeepEquals, []string{"go"})
+
+		// Mutating the returned document must not affect the cached copy
+		found.Keywords[0] = "mutated"
+	}
+	c.Assert(idx.findCalls, gc.Equals, 1)
+}
+
+func (s *CacheTestSuite) TestUpdatesInvalidateCachedDocument(c *gc.C) {
+	idx := newCountingIndexer()
+	ci := WithCache(idx, CacheConfig{})
+
+	doc := &Document{LinkID: uuid.New()}
+	c.Assert(ci.Index(doc), gc.IsNil)
+	_, err := ci.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+
+	c.Assert(ci.UpdateScore(doc.LinkID, 0.5), gc.IsNil)
+	found, err := ci.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.PageRank, gc.Equals, 0.5)
+
+	c.Assert(ci.CommitReindex(), gc.IsNil)
+	_, err = ci.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(idx.findCalls, gc.Equals, 3)
+
+	c.Assert(ci.Delete(doc.LinkID), gc.IsNil)
+	_, err = ci.FindByID(doc.LinkID)
+	c.Assert(err, gc.Equals, ErrNotFound)
+}
+
+func (s *CacheTestSuite) TestEviction(c *gc.C) {
+	ci := WithCache(newCountingIndexer(), CacheConfig{Size: 1}).(*cachingIndexer)
+
+	first, second := &Document{LinkID: uuid.New()}, &Document{LinkID: uuid.New()}
+	gen := ci.cache.generation()
+	ci.cache.put(gen, first)
+	ci.cache.put(gen, second)
+	c.Assert(ci.cache.get(first.LinkID), gc.IsNil)
+	c.Assert(ci.cache.get(second.LinkID), gc.NotNil)
+
+	// Documents fetched before an invalidation are not cached
+	ci.cache.invalidate(uuid.New())
+	ci.cache.put(gen, first)
+	c.Assert(ci.cache.get(first.LinkID), gc.IsNil)
+}
+
+// countingIndexer is a map-backed Indexer that counts FindByID calls.
+type countingIndexer struct {
+	Indexer
+
+	docs      map[uuid.UUID]*Document
+	findCalls int
+}
+
+func newCountingIndexer() *countingIndexer {
+	return &countingIndexer{docs: make(map[uuid.UUID]*Document)}
+}
+
+func (f *countingIndexer) Index(doc *Document) error {
+	f.docs[doc.LinkID] = copyDoc(doc)
+	return nil
+}
+
+func (f *countingIndexer) FindByID(linkID uuid.UUID) (*Document, error) {
+	f.findCalls++
+	doc, found := f.docs[linkID]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return copyDoc(doc), nil
+}
+
+func (f *countingIndexer) UpdateScore(linkID uuid.UUID, score float64) error {
+	doc, found := f.docs[linkID]
+	if !found {
+		return ErrNotFound
+	}
+	doc.PageRank = score
+	return nil
+}
+
+func (f *countingIndexer) Delete(linkID uuid.UUID) error {
+	delete(f.docs, linkID)
+	return nil
+}
+
+func (f *countingIndexer) CommitReindex() error { return nil }
@@ -14,10 +14,101 @@ type Document struct {
 	/*correspond to the value of the <title> element if the
 	link points to an HTML page*/
 	Title string
-	/*stores the block of text extracted by the crawler*/
+	/*stores the block of text extracted by the crawler that gets indexed*/
 	Content string
+	/*the full text of the page and, if boilerplate removal is enabled, the
+	main text of the page without navigation menus, footers etc.  Content
+	is populated from one of the two*/
+	RawText  string
+	MainText string
+	/*ISO 639-1 code for the language of Content, if known*/
+	Language string
+
+	/*page metadata extracted from the <meta> tags of an HTML page; the
+	description is also searchable and gets a higher weight than Content*/
+	Description string
+	Keywords    []string
+	/*Keyphrases are the most significant phrases of the page as computed
+	by a keyphrase extractor (see the keyphrase package).  They are
+	searchable and matches get a higher weight than Content matches*/
+	Keyphrases []string
+	/*OpenGraph title, description and preview image URL*/
+	OGTitle       string
+	OGDescription string
+	OGImage       string
+
+	/*Summary is a short extractive summary of the page (see the summarizer
+	package) that is used as the snippet of search results when the content
+	does not match the query*/
+	Summary string
+
+	/*Embedding is an optional vector representation of the page (see
+	Embedder) that is used by QueryTypeSemantic queries*/
+	Embedding []float32
+
+	/*ParentLinkID is set for passages, i.e. child documents that hold a
+	chunk of the content of the page with this LinkID (see SplitPassages).
+	PassageIndex is the position of the passage within the page*/
+	ParentLinkID uuid.UUID
+	PassageIndex int
+
+	/*structured schema.org entities embedded in the page as JSON-LD*/
+	Entities []Entity
 
 	IndexedAt time.Time
 
+	/*crawl provenance: when and by which crawl pass the page was fetched,
+	the HTTP status code of the response and a hash of the raw content.
+	Crawl pass IDs increase monotonically (see graph.CrawlPassTracker)*/
+	FetchedAt   time.Time
+	CrawlPassID uint64
+	HTTPStatus  int
+	ContentHash string
+
 	PageRank float64
+
+	/*enrichments computed from the link graph after the page has been
+	indexed: the number of incoming links and the anchor text they use*/
+	InDegree   int
+	AnchorText []string
+
+	/*ClickScore is a [0, 1] score derived from the number of times the
+	document was clicked in search results*/
+	ClickScore float64
+
+	/*Betweenness and Closeness are link graph centrality scores computed
+	offline (see the centrality package) for ranking experiments*/
+	Betweenness float64
+	Closeness   float64
+
+	/*CommunityID identifies the cluster of closely linked pages the
+	document belongs to (see the community package)*/
+	CommunityID string
+
+	/*DomainAuthority is a [0, 1] score computed offline by running PageRank
+	over the host graph (see the linkgraph/hostgraph package); all pages of
+	a host share the same score*/
+	DomainAuthority float64
+
+	/*Filtered is set for documents that a content classifier flagged (e.g.
+	as adult content or spam) for the reason in FilterReason.  Filtered
+	documents are excluded from the results of queries that set
+	ExcludeFiltered*/
+	Filtered     bool
+	FilterReason string
+
+	/*Version is assigned by the indexer and incremented each time the
+	document is modified.  It allows callers to detect concurrent
+	modifications (see Indexer.Index and Indexer.UpdateFieldsIfVersion)*/
+	Version uint64
+}
+
+/*Entity describes a schema.org entity (e.g. an Article) that was embedded in a
+page using JSON-LD*/
+type Entity struct {
+	/*schema.org type of the entity, e.g. "Article" or "Product"*/
+	Type          string
+	Name          string
+	Author        string
+	DatePublished time.Time
 }
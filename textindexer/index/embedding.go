@@ -0,0 +1,38 @@
+package index
+
+import (
+	"context"
+	"math"
+)
+
+/*
+Embedder is implemented by objects that map texts to vectors (embeddings)
+such that semantically similar texts have a high cosine similarity.  The
+returned slice holds one embedding per input text.  See the embedding package
+for an implementation backed by an HTTP embedding service
+*/
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+/*
+CosineSimilarity returns the cosine of the angle between a and b.  It returns
+zero if the vectors have different dimensions or either of them is a zero
+vector
+*/
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
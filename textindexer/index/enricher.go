@@ -0,0 +1,41 @@
+package index
+
+import "context"
+
+/*
+Enricher is implemented by plugins that augment documents right before they
+are indexed (e.g. keyword extraction, entity tagging or summarization).
+Enrich mutates doc in place; an error prevents the document from being
+indexed
+*/
+type Enricher interface {
+	Enrich(ctx context.Context, doc *Document) error
+}
+
+/*EnricherFunc adapts a function to the Enricher interface */
+type EnricherFunc func(ctx context.Context, doc *Document) error
+
+/*Enrich calls f(ctx, doc) */
+func (f EnricherFunc) Enrich(ctx context.Context, doc *Document) error {
+	return f(ctx, doc)
+}
+
+/*
+ChainEnrichers returns an Enricher that invokes enrichers in order so that
+each one sees the changes made by the previous ones.  The chain stops at the
+first error
+*/
+func ChainEnrichers(enrichers ...Enricher) Enricher {
+	return enricherChain(append([]Enricher(nil), enrichers...))
+}
+
+type enricherChain []Enricher
+
+func (chain enricherChain) Enrich(ctx context.Context, doc *Document) error {
+	for _, enricher := range chain {
+		if err := enricher.Enrich(ctx, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
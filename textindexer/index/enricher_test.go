@@ -0,0 +1,42 @@
+package index
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(EnricherTestSuite))
+
+type EnricherTestSuite struct{}
+
+func (s *EnricherTestSuite) TestChainEnrichers(c *gc.C) {
+	var calls []string
+	tagger := EnricherFunc(func(_ context.Context, doc *Document) error {
+		calls = append(calls, "tagger")
+		doc.Keywords = append(doc.Keywords, "go")
+		return nil
+	})
+	summarizer := EnricherFunc(func(_ context.Context, doc *Document) error {
+		calls = append(calls, "summarizer")
+		doc.Description = doc.Title + " about " + doc.Keywords[0]
+		return nil
+	})
+
+	doc := &Document{Title: "A post"}
+	c.Assert(ChainEnrichers(tagger, summarizer).Enrich(context.TODO(), doc), gc.IsNil)
+	c.Assert(calls, gc.DeepEquals, []string{"tagger", "summarizer"})
+	c.Assert(doc.Keywords, gc.DeepEquals, []string{"go"})
+	c.Assert(doc.Description, gc.Equals, "A post about go")
+
+	// The chain stops at the first error
+	calls = nil
+	failing := EnricherFunc(func(context.Context, *Document) error {
+		calls = append(calls, "failing")
+		return xerrors.New("model unavailable")
+	})
+	err := ChainEnrichers(tagger, failing, summarizer).Enrich(context.TODO(), new(Document))
+	c.Assert(err, gc.ErrorMatches, "model unavailable")
+	c.Assert(calls, gc.DeepEquals, []string{"tagger", "failing"})
+}
@@ -2,9 +2,60 @@ package index
 
 import "golang.org/x/xerrors"
 
+var (
+	//ErrConflict is the class of errors caused by a conflicting concurrent update
+	ErrConflict = xerrors.New("conflict")
+	//ErrUnavailable is the class of transient errors caused by an index backend that cannot be reached
+	ErrUnavailable = xerrors.New("unavailable")
+	//ErrInvalidArgument is the class of errors caused by invalid documents or queries
+	ErrInvalidArgument = xerrors.New("invalid argument")
+)
+
 var (
 	//ErrNotFound is returned by the indexer when attempting to look up a doc that doesn't exist
 	ErrNotFound = xerrors.New("not found")
 	//ErrMissingLinkID is returned when attempting to index a doc that does not specify a valid link ID
-	ErrMissingLinkID = xerrors.New("document does not provide a valid linkID")
+	ErrMissingLinkID = InvalidArgument(xerrors.New("document does not provide a valid linkID"))
+	//ErrUnknownField is returned when attempting a partial update of a field that does not exist or cannot be updated
+	ErrUnknownField = InvalidArgument(xerrors.New("unknown field"))
+	//ErrInvalidFieldValue is returned when a partial update specifies a value with the wrong type for its field
+	ErrInvalidFieldValue = InvalidArgument(xerrors.New("invalid field value"))
+	//ErrVersionConflict is returned when a conditional write is attempted against a document whose version has changed
+	ErrVersionConflict = Conflict(xerrors.New("document version conflict"))
+	//ErrReindexInProgress is returned when attempting to begin a reindex while another one is still in progress
+	ErrReindexInProgress = Conflict(xerrors.New("reindex already in progress"))
+	//ErrNoReindexInProgress is returned when attempting to commit a reindex that has not been started
+	ErrNoReindexInProgress = xerrors.New("no reindex in progress")
 )
+
+//classifiedError tags an error with one of the error classes above while
+//preserving its message and its identity for xerrors.Is
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string        { return e.err.Error() }
+func (e *classifiedError) Unwrap() error        { return e.err }
+func (e *classifiedError) Is(target error) bool { return target == e.class }
+
+//Conflict wraps err so that it matches ErrConflict
+func Conflict(err error) error { return classify(ErrConflict, err) }
+
+//Unavailable wraps err so that it matches ErrUnavailable
+func Unavailable(err error) error { return classify(ErrUnavailable, err) }
+
+//InvalidArgument wraps err so that it matches ErrInvalidArgument
+func InvalidArgument(err error) error { return classify(ErrInvalidArgument, err) }
+
+func classify(class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+//IsRetryable returns true if the operation that returned err may succeed if retried
+func IsRetryable(err error) bool {
+	return xerrors.Is(err, ErrUnavailable)
+}
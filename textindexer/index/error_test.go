@@ -0,0 +1,34 @@
+package index
+
+import (
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ErrorTestSuite))
+
+type ErrorTestSuite struct{}
+
+func (s *ErrorTestSuite) TestErrorClasses(c *gc.C) {
+	err := xerrors.Errorf("index: %w", ErrVersionConflict)
+	c.Assert(xerrors.Is(err, ErrVersionConflict), gc.Equals, true)
+	c.Assert(xerrors.Is(err, ErrConflict), gc.Equals, true)
+	c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, false)
+	c.Assert(err, gc.ErrorMatches, "index: document version conflict")
+
+	c.Assert(xerrors.Is(ErrMissingLinkID, ErrInvalidArgument), gc.Equals, true)
+	c.Assert(xerrors.Is(ErrUnknownField, ErrInvalidArgument), gc.Equals, true)
+	c.Assert(xerrors.Is(ErrNotFound, ErrInvalidArgument), gc.Equals, false)
+}
+
+func (s *ErrorTestSuite) TestIsRetryable(c *gc.C) {
+	connErr := xerrors.New("connection refused")
+	err := xerrors.Errorf("search: %w", Unavailable(connErr))
+	c.Assert(IsRetryable(err), gc.Equals, true)
+	c.Assert(xerrors.Is(err, connErr), gc.Equals, true)
+	c.Assert(err, gc.ErrorMatches, "search: connection refused")
+
+	c.Assert(IsRetryable(ErrVersionConflict), gc.Equals, false)
+	c.Assert(IsRetryable(nil), gc.Equals, false)
+	c.Assert(Unavailable(nil), gc.IsNil)
+}
@@ -0,0 +1,103 @@
+package index
+
+import (
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+/*
+Names of the document fields that can be modified via partial updates
+(see Indexer.UpdateFields) together with the type of value they expect
+*/
+const (
+	FieldTitle       = "Title"       // string
+	FieldDescription = "Description" // string
+	FieldLanguage    = "Language"    // string
+	FieldKeywords    = "Keywords"    // []string
+	FieldPageRank    = "PageRank"    // float64
+	FieldInDegree    = "InDegree"    // int
+	FieldAnchorText  = "AnchorText"  // []string
+	FieldClickScore  = "ClickScore"  // float64
+	FieldBetweenness = "Betweenness" // float64
+	FieldCloseness   = "Closeness"   // float64
+	FieldCommunityID = "CommunityID" // string
+
+	FieldDomainAuthority = "DomainAuthority" // float64
+)
+
+/*
+ApplyFields applies a set of partial updates to doc.  Either all updates are
+applied or, if any of the field names is unknown or any of the values has the
+wrong type, doc is left untouched and an error is returned
+*/
+func ApplyFields(doc *Document, fields map[string]interface{}) error {
+	//validate everything before touching doc so the update is all-or-nothing
+	updated := *doc
+	for name, value := range fields {
+		var ok bool
+		switch name {
+		case FieldTitle:
+			updated.Title, ok = value.(string)
+		case FieldDescription:
+			updated.Description, ok = value.(string)
+		case FieldLanguage:
+			updated.Language, ok = value.(string)
+		case FieldKeywords:
+			var keywords []string
+			if keywords, ok = value.([]string); ok {
+				updated.Keywords = append([]string(nil), keywords...)
+			}
+		case FieldPageRank:
+			updated.PageRank, ok = value.(float64)
+		case FieldInDegree:
+			updated.InDegree, ok = value.(int)
+		case FieldClickScore:
+			updated.ClickScore, ok = value.(float64)
+		case FieldBetweenness:
+			updated.Betweenness, ok = value.(float64)
+		case FieldCloseness:
+			updated.Closeness, ok = value.(float64)
+		case FieldCommunityID:
+			updated.CommunityID, ok = value.(string)
+		case FieldDomainAuthority:
+			updated.DomainAuthority, ok = value.(float64)
+		case FieldAnchorText:
+			var anchorText []string
+			if anchorText, ok = value.([]string); ok {
+				updated.AnchorText = append([]string(nil), anchorText...)
+			}
+		default:
+			return xerrors.Errorf("field %q: %w", name, ErrUnknownField)
+		}
+
+		if !ok {
+			return xerrors.Errorf("field %q: %w", name, ErrInvalidFieldValue)
+		}
+	}
+
+	*doc = updated
+	return nil
+}
+
+/*
+UpdateFieldsWithRetry performs a read-modify-write cycle on the document with
+linkID using optimistic concurrency control.  The document is looked up,
+passed to updateFn which returns the fields to update and then written back
+conditionally on its version.  Version conflicts cause the cycle to be retried
+up to maxAttempts times
+*/
+func UpdateFieldsWithRetry(idx Indexer, linkID uuid.UUID, maxAttempts int, updateFn func(doc *Document) map[string]interface{}) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var doc *Document
+		if doc, err = idx.FindByID(linkID); err != nil {
+			return xerrors.Errorf("update fields with retry: %w", err)
+		}
+
+		err = idx.UpdateFieldsIfVersion(linkID, doc.Version, updateFn(doc))
+		if !xerrors.Is(err, ErrVersionConflict) {
+			return err
+		}
+	}
+	return xerrors.Errorf("update fields with retry: giving up after %d attempts: %w", maxAttempts, err)
+}
@@ -0,0 +1,97 @@
+package index
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+/*
+DocumentVersion is a snapshot of a document as it was indexed before being
+replaced by a newer version of its content.  The document's IndexedAt and
+FetchedAt fields tell when the snapshot was indexed and crawled while
+ArchivedAt records when it was replaced
+*/
+type DocumentVersion struct {
+	Document   *Document
+	ArchivedAt time.Time
+}
+
+/*
+ContentStore is implemented by objects that can persist document snapshots.
+PutVersion must be idempotent for snapshots of the same document version so
+that failed writes can be retried.  Versions returns the snapshots of a
+document sorted from oldest to newest, or an empty list if none have been
+stored
+*/
+type ContentStore interface {
+	PutVersion(linkID uuid.UUID, version DocumentVersion) error
+	Versions(linkID uuid.UUID) ([]DocumentVersion, error)
+}
+
+/*
+HistoryIndexer is an Indexer that retains the previous versions of the
+documents it reindexes
+*/
+type HistoryIndexer interface {
+	Indexer
+
+	/*
+		History returns the prior versions of a document, oldest first.
+		The current version is not included; use FindByID to retrieve it
+	*/
+	History(linkID uuid.UUID) ([]DocumentVersion, error)
+}
+
+/*
+WithHistory decorates idx so that each reindex that changes the content of a
+document first stores a snapshot of the previous version in store.  Partial
+updates (e.g. UpdateScore) do not create new versions.  The snapshot is
+stored before the document is reindexed so that a failed write never loses a
+version; a retried write stores the same snapshot again
+*/
+func WithHistory(idx Indexer, store ContentStore) HistoryIndexer {
+	return &historyIndexer{Indexer: idx, store: store}
+}
+
+type historyIndexer struct {
+	Indexer
+	store ContentStore
+}
+
+func (h *historyIndexer) Index(doc *Document) error {
+	prev, err := h.Indexer.FindByID(doc.LinkID)
+	if err != nil && !xerrors.Is(err, ErrNotFound) {
+		return xerrors.Errorf("archive previous version: %w", err)
+	}
+
+	if prev != nil && contentChanged(prev, doc) {
+		version := DocumentVersion{Document: prev, ArchivedAt: time.Now()}
+		if err = h.store.PutVersion(doc.LinkID, version); err != nil {
+			return xerrors.Errorf("archive previous version: %w", err)
+		}
+	}
+
+	return h.Indexer.Index(doc)
+}
+
+func (h *historyIndexer) History(linkID uuid.UUID) ([]DocumentVersion, error) {
+	versions, err := h.store.Versions(linkID)
+	if err != nil {
+		return nil, xerrors.Errorf("history: %w", err)
+	}
+	return versions, nil
+}
+
+/*
+contentChanged returns true if the content of doc differs from prev.  Content
+hashes are compared if both documents have one; otherwise the indexed text is
+compared
+*/
+func contentChanged(prev, doc *Document) bool {
+	if prev.ContentHash != "" && doc.ContentHash != "" {
+		return prev.ContentHash != doc.ContentHash
+	}
+	return prev.Title != doc.Title || prev.Content != doc.Content || prev.Description != doc.Description
+}
@@ -0,0 +1,93 @@
+package index
+
+import (
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(HistoryTestSuite))
+
+type HistoryTestSuite struct{}
+
+func (s *HistoryTestSuite) TestReindexArchivesPreviousVersion(c *gc.C) {
+	store := new(fakeContentStore)
+	hi := WithHistory(newCountingIndexer(), store)
+
+	linkID := uuid.New()
+	for _, content := range []string{"v1", "v1", "v2", "v3"} {
+		c.Assert(hi.Index(&Document{LinkID: linkID, Content: content}), gc.IsNil)
+	}
+	c.Assert(hi.UpdateScore(linkID, 0.5), gc.IsNil)
+
+	// Reindexing unchanged content does not create a new version.
+	versions, err := hi.History(linkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(versions, gc.HasLen, 2)
+	c.Assert(versions[0].Document.Content, gc.Equals, "v1")
+	c.Assert(versions[1].Document.Content, gc.Equals, "v2")
+	c.Assert(versions[0].ArchivedAt.After(versions[1].ArchivedAt), gc.Equals, false)
+
+	current, err := hi.FindByID(linkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(current.Content, gc.Equals, "v3")
+
+	versions, err = hi.History(uuid.New())
+	c.Assert(err, gc.IsNil)
+	c.Assert(versions, gc.HasLen, 0)
+}
+
+func (s *HistoryTestSuite) TestContentHashesAreCompared(c *gc.C) {
+	store := new(fakeContentStore)
+	hi := WithHistory(newCountingIndexer(), store)
+
+	linkID := uuid.New()
+	c.Assert(hi.Index(&Document{LinkID: linkID, Content: "gophers", ContentHash: "abc"}), gc.IsNil)
+	c.Assert(hi.Index(&Document{LinkID: linkID, Content: "gophers!", ContentHash: "abc"}), gc.IsNil)
+	c.Assert(store.versions, gc.HasLen, 0)
+	c.Assert(hi.Index(&Document{LinkID: linkID, Content: "gophers!", ContentHash: "def"}), gc.IsNil)
+	c.Assert(store.versions, gc.HasLen, 1)
+}
+
+func (s *HistoryTestSuite) TestStoreErrorAbortsReindex(c *gc.C) {
+	store := &fakeContentStore{err: xerrors.New("store unavailable")}
+	hi := WithHistory(newCountingIndexer(), store)
+
+	linkID := uuid.New()
+	c.Assert(hi.Index(&Document{LinkID: linkID, Content: "v1"}), gc.IsNil)
+	err := hi.Index(&Document{LinkID: linkID, Content: "v2"})
+	c.Assert(err, gc.ErrorMatches, "archive previous version: store unavailable")
+
+	current, err := hi.FindByID(linkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(current.Content, gc.Equals, "v1")
+
+	_, err = hi.History(linkID)
+	c.Assert(err, gc.ErrorMatches, "history: store unavailable")
+}
+
+type fakeContentStore struct {
+	versions []DocumentVersion
+	err      error
+}
+
+func (f *fakeContentStore) PutVersion(_ uuid.UUID, version DocumentVersion) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.versions = append(f.versions, version)
+	return nil
+}
+
+func (f *fakeContentStore) Versions(linkID uuid.UUID) ([]DocumentVersion, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var list []DocumentVersion
+	for _, version := range f.versions {
+		if version.Document.LinkID == linkID {
+			list = append(list, version)
+		}
+	}
+	return list, nil
+}
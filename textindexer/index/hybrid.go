@@ -0,0 +1,116 @@
+package index
+
+import (
+	"sort"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	/*
+		RRFConstant dampens the contribution of the top ranked results of
+		each retriever when fusing them with reciprocal rank fusion.  60 is
+		the value suggested by the paper that introduced the method
+	*/
+	RRFConstant = 60
+
+	/*
+		MaxHybridCandidates is the maximum number of results of each
+		retriever that are considered when fusing the results of a
+		QueryTypeHybrid query
+	*/
+	MaxHybridCandidates = 1000
+)
+
+/*
+HybridWeights returns the weights applied to the keyword and semantic ranks of
+a QueryTypeHybrid query.  Both retrievers are weighted equally if q does not
+specify any weights
+*/
+func (q Query) HybridWeights() (keyword, semantic float64) {
+	if q.KeywordWeight == 0 && q.SemanticWeight == 0 {
+		return 1, 1
+	}
+	return q.KeywordWeight, q.SemanticWeight
+}
+
+/*
+FuseRanks merges the results of the keyword and semantic retrievers of the
+QueryTypeHybrid query q using weighted reciprocal rank fusion: each document
+scores the sum of weight/(RRFConstant+rank) over the result lists it appears
+in.  Up to MaxHybridCandidates results are read from each iterator before both
+of them are closed; q.Offset is applied to the fused result set
+*/
+func FuseRanks(q Query, keyword, semantic Iterator) (Iterator, error) {
+	defer func() {
+		_ = keyword.Close()
+		_ = semantic.Close()
+	}()
+
+	keywordWeight, semanticWeight := q.HybridWeights()
+	if keywordWeight < 0 || semanticWeight < 0 {
+		return nil, xerrors.Errorf("fuse ranks: %w", InvalidArgument(xerrors.New("hybrid weights must not be negative")))
+	}
+
+	var (
+		fused = make(map[string]*fusedResult)
+		order []*fusedResult
+	)
+	for _, retriever := range []struct {
+		it     Iterator
+		weight float64
+	}{{keyword, keywordWeight}, {semantic, semanticWeight}} {
+		for rank := 1; rank <= MaxHybridCandidates && retriever.it.Next(); rank++ {
+			doc := retriever.it.Document()
+			res, found := fused[doc.LinkID.String()]
+			if !found {
+				res = &fusedResult{doc: doc}
+				fused[doc.LinkID.String()] = res
+				order = append(order, res)
+			}
+			res.score += retriever.weight / float64(RRFConstant+rank)
+		}
+		if err := retriever.it.Error(); err != nil {
+			return nil, xerrors.Errorf("fuse ranks: %w", err)
+		}
+	}
+
+	//documents with the same score keep the order in which they were first
+	//retrieved, i.e. keyword matches come first
+	sort.SliceStable(order, func(a, b int) bool { return order[a].score > order[b].score })
+
+	it := &fusedIterator{total: uint64(len(order))}
+	if q.Offset < len(order) {
+		it.results = order[q.Offset:]
+	}
+	return it, nil
+}
+
+type fusedResult struct {
+	doc   *Document
+	score float64
+}
+
+//fusedIterator iterates the results of FuseRanks
+type fusedIterator struct {
+	results []*fusedResult
+	total   uint64
+	curDoc  *Document
+}
+
+func (it *fusedIterator) Close() error {
+	it.results = nil
+	return nil
+}
+
+func (it *fusedIterator) Next() bool {
+	if len(it.results) == 0 {
+		return false
+	}
+	it.curDoc, it.results = it.results[0].doc, it.results[1:]
+	return true
+}
+
+func (it *fusedIterator) Error() error        { return nil }
+func (it *fusedIterator) Document() *Document { return it.curDoc }
+func (it *fusedIterator) TotalCount() uint64  { return it.total }
@@ -0,0 +1,80 @@
+package index
+
+import (
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(HybridTestSuite))
+
+type HybridTestSuite struct {
+	docs []*Document
+}
+
+func (s *HybridTestSuite) SetUpTest(c *gc.C) {
+	s.docs = nil
+	for i := 0; i < 4; i++ {
+		s.docs = append(s.docs, &Document{LinkID: uuid.New()})
+	}
+}
+
+func (s *HybridTestSuite) TestFuseRanks(c *gc.C) {
+	a, b, cc, d := s.docs[0], s.docs[1], s.docs[2], s.docs[3]
+	specs := []struct {
+		descr string
+		q     Query
+		exp   []*Document
+	}{
+		{
+			// a and c tie; a was retrieved first by the keyword search
+			descr: "equal weights",
+			q:     Query{Type: QueryTypeHybrid},
+			exp:   []*Document{a, cc, b, d},
+		},
+		{
+			descr: "semantic ranks weigh twice as much",
+			q:     Query{Type: QueryTypeHybrid, KeywordWeight: 1, SemanticWeight: 2},
+			exp:   []*Document{cc, a, d, b},
+		},
+		{
+			descr: "keyword ranks only",
+			q:     Query{Type: QueryTypeHybrid, KeywordWeight: 1},
+			exp:   []*Document{a, b, cc, d},
+		},
+		{
+			descr: "offset",
+			q:     Query{Type: QueryTypeHybrid, KeywordWeight: 1, SemanticWeight: 2, Offset: 2},
+			exp:   []*Document{d, b},
+		},
+	}
+
+	for specIndex, spec := range specs {
+		c.Logf("[spec %d] %s", specIndex, spec.descr)
+		it, err := FuseRanks(spec.q, docIterator(a, b, cc), docIterator(cc, d, a))
+		c.Assert(err, gc.IsNil)
+		c.Assert(it.TotalCount(), gc.Equals, uint64(4))
+
+		var got []*Document
+		for it.Next() {
+			got = append(got, it.Document())
+		}
+		c.Assert(it.Error(), gc.IsNil)
+		c.Assert(it.Close(), gc.IsNil)
+		c.Assert(got, gc.DeepEquals, spec.exp)
+	}
+}
+
+func (s *HybridTestSuite) TestFuseRanksWithNegativeWeight(c *gc.C) {
+	q := Query{Type: QueryTypeHybrid, KeywordWeight: -1, SemanticWeight: 1}
+	_, err := FuseRanks(q, docIterator(s.docs...), docIterator())
+	c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, true)
+}
+
+func docIterator(docs ...*Document) Iterator {
+	it := &fusedIterator{total: uint64(len(docs))}
+	for _, doc := range docs {
+		it.results = append(it.results, &fusedResult{doc: doc})
+	}
+	return it
+}
@@ -1,6 +1,10 @@
 package index
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 /*
 Indexer exposes an interface that can index and search documents
@@ -8,23 +12,121 @@ Indexer exposes an interface that can index and search documents
 type Indexer interface {
 	/*
 		Index adds a document to the index, or reindexes an existing document
-		when its content changes.
+		when its content changes.  If doc.Version is non-zero, the write only
+		succeeds if it matches the version of the stored document; otherwise
+		ErrVersionConflict is returned.  On success, doc.Version is set to
+		the new version of the document.
 	*/
 	Index(doc *Document) error
 	/*
 		FindByID performs a lookup for a document by its ID
 	*/
 	FindByID(linkID uuid.UUID) (*Document, error)
+	/*
+		FindByURL performs a lookup for a document by its URL.  Both
+		rawURL and the URLs of the indexed documents are compared in
+		their normalized form (see graph.NormalizeURL).  Passages are
+		never returned
+	*/
+	FindByURL(rawURL string) (*Document, error)
+	/*
+		FindByURLs looks up the documents for a batch of URLs.  The
+		returned slice has an entry for each URL in rawURLs; the entries
+		for URLs without a matching document are nil
+	*/
+	FindByURLs(rawURLs []string) ([]*Document, error)
 	/*
 		Search expects a Query type as opposed to a string argument.
 		Offers us flexibility to expand the indexer's query capabilities
 		further down the road without having to modify the Search() signature
 	*/
 	Search(query Query) (Iterator, error)
+	/*
+		SearchAll works like Search but is optimized for bulk consumers
+		(e.g. reindexers and analytics jobs) that stream through the entire
+		result set: results are fetched from the backend in large batches
+	*/
+	SearchAll(query Query) (Iterator, error)
+	/*
+		Count returns the number of documents that match query without
+		materializing any of them
+	*/
+	Count(query Query) (uint64, error)
+	/*
+		Suggestions returns alternative, correctly spelled, expressions for
+		query (best first) that the frontend can offer when a search yields
+		few results.  An empty list is returned if no correction is needed.
+	*/
+	Suggestions(query Query) ([]string, error)
 	/*
 		UpdateScore updates the PageRank score for a document.
 	*/
 	UpdateScore(linkID uuid.UUID, score float64) error
+	/*
+		UpdateFields atomically applies a set of partial updates, keyed by
+		field name (see the Field* constants), to a document without
+		requiring its full content to be reindexed.
+	*/
+	UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error
+	/*
+		UpdateFieldsIfVersion behaves like UpdateFields but only applies
+		the updates if the document's current version equals version.  It
+		returns ErrVersionConflict if the document was modified in the
+		meantime, in which case callers should re-read it and retry.
+	*/
+	UpdateFieldsIfVersion(linkID uuid.UUID, version uint64, fields map[string]interface{}) error
+	/*
+		Delete removes a document from the index, e.g. once its link has
+		not been retrieved for a long time.  It returns ErrNotFound if the
+		document does not exist.
+	*/
+	Delete(linkID uuid.UUID) error
+	/*
+		BeginReindex starts building a new index (e.g. with updated analyzers
+		or mappings) in the background.  Searches keep being served by the
+		current index while writes are applied to both of them.
+	*/
+	BeginReindex() error
+	/*
+		CommitReindex waits for the index started by BeginReindex to be
+		fully built and atomically swaps it in place of the current one.
+	*/
+	CommitReindex() error
+	/*
+		StorageStats reports the number of documents and an estimate of
+		the resources used by the index so operators can provision
+		capacity
+	*/
+	StorageStats() (*StorageStats, error)
+}
+
+/*
+StorageStats describes the storage footprint of an index.  Sizes are estimates
+that only account for the stored documents and index structures known to the
+backend
+*/
+type StorageStats struct {
+	Documents uint64
+	/*
+		MemoryBytes and DiskBytes estimate the memory and disk space used
+		by the index; DiskBytes is zero for in-memory indices
+	*/
+	MemoryBytes uint64
+	DiskBytes   uint64
+	/*
+		Segments is the number of segments (e.g. bleve indices or Lucene
+		segments) that make up the index.  It grows temporarily while a
+		reindex is in progress
+	*/
+	Segments int
+}
+
+//Add accumulates the stats of other into s, e.g. to report the total footprint of a sharded index
+func (s *StorageStats) Add(other *StorageStats) {
+	s.Documents += other.Documents
+	s.MemoryBytes += other.MemoryBytes
+	s.DiskBytes += other.DiskBytes
+	s.Segments += other.Segments
 }
 
 //Query is an object that represents what our users search
@@ -43,19 +145,88 @@ type Query struct {
 	Expression string
 	// The number of serach results to skip
 	Offset int
+	/*
+		Language, if set, restricts results to documents whose content
+		is written in the language with this ISO 639-1 code
+	*/
+	Language string
+	/*
+		EntityType and PublishedAfter, if set, restrict results to documents
+		that embed a structured entity of that type and/or were published
+		after the specified time
+	*/
+	EntityType     string
+	PublishedAfter time.Time
+	/*
+		FetchedAfter, if set, restricts results to documents whose page was
+		fetched by the crawler after the specified time
+	*/
+	FetchedAfter time.Time
+	/*
+		Site, if set, restricts results to documents whose URL host is
+		Site or one of its subdomains (e.g. "example.com" also matches
+		"blog.example.com")
+	*/
+	Site string
+	/*
+		CrawlPassID, if non-zero, restricts results to documents that were
+		indexed by the specified crawl pass
+	*/
+	CrawlPassID uint64
+	/*
+		ExcludeFiltered, if set, omits documents that were flagged by a
+		content classifier (see Document.Filtered) from the results, e.g.
+		to implement safe-search
+	*/
+	ExcludeFiltered bool
+	/*
+		IncludeDomains, if not empty, restricts results to documents whose
+		URL host is one of the listed domains or one of their subdomains.
+		ExcludeDomains omits documents whose URL host is one of the listed
+		domains or one of their subdomains.  Both compose with Site
+	*/
+	IncludeDomains []string
+	ExcludeDomains []string
+	/*
+		Passages, if set, matches the passages of documents (see
+		SplitPassages) instead of whole documents, e.g. for retrieving the
+		parts of pages that answer a question.  Passages are omitted from
+		the results of all other queries
+	*/
+	Passages bool
+	/*
+		Embedding is the vector representation of Expression (see
+		Embedder) that QueryTypeSemantic and QueryTypeHybrid queries are
+		matched against
+	*/
+	Embedding []float32
+	/*
+		KeywordWeight and SemanticWeight control how much the keyword and
+		semantic ranks of a document contribute to its score in a
+		QueryTypeHybrid query.  If neither is set, both retrievers are
+		weighted equally
+	*/
+	KeywordWeight  float64
+	SemanticWeight float64
 }
 
 // QueryType describes the types of queries supported by the indexer implementations
 type QueryType uint8
 
 /*
-These are the two types of search queries,
+These are the types of search queries,
 can be extended in the future to perform boolean-,
-date-, or domain-based queries
+date-, or domain-based queries.  QueryTypeSemantic performs
+a k-nearest-neighbour search that ranks the documents with an
+embedding by their cosine similarity to Query.Embedding.
+QueryTypeHybrid runs both a keyword (match) and a semantic
+search and merges their results (see FuseRanks)
 */
 const (
 	QueryTypeMatch QueryType = iota
 	QueryTypePhrase
+	QueryTypeSemantic
+	QueryTypeHybrid
 )
 
 /*
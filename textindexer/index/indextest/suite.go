@@ -76,6 +76,61 @@ func (s *SuiteBase) TestFindByID(c *gc.C) {
 	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
 }
 
+//TestFindByURL verifies that documents can be looked up by their normalized URL
+func (s *SuiteBase) TestFindByURL(c *gc.C) {
+	doc := &index.Document{
+		LinkID:  uuid.New(),
+		URL:     "http://Example.com:80/gophers",
+		Content: "gophers",
+	}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+
+	got, err := s.idx.FindByURL("http://example.com/gophers#burrows")
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.LinkID, gc.Equals, doc.LinkID)
+
+	_, err = s.idx.FindByURL("http://example.com/moles")
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+	_, err = s.idx.FindByURL("example.com/gophers")
+	c.Assert(xerrors.Is(err, index.ErrInvalidArgument), gc.Equals, true)
+
+	// Documents are no longer found under their previous URL once they are
+	// reindexed with a new one or deleted
+	doc.URL, doc.Version = "http://example.com/moles", 0
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+	_, err = s.idx.FindByURL("http://example.com/gophers")
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+	got, err = s.idx.FindByURL("http://example.com/moles")
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.LinkID, gc.Equals, doc.LinkID)
+
+	c.Assert(s.idx.Delete(doc.LinkID), gc.IsNil)
+	_, err = s.idx.FindByURL("http://example.com/moles")
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+}
+
+//TestFindByURLs verifies that documents can be looked up by URL in batches
+func (s *SuiteBase) TestFindByURLs(c *gc.C) {
+	gophers := &index.Document{LinkID: uuid.New(), URL: "http://example.com/gophers", Content: "gophers"}
+	moles := &index.Document{LinkID: uuid.New(), URL: "http://example.com/moles", Content: "moles"}
+	c.Assert(s.idx.Index(gophers), gc.IsNil)
+	c.Assert(s.idx.Index(moles), gc.IsNil)
+
+	docs, err := s.idx.FindByURLs([]string{
+		"http://example.com/moles",
+		"http://example.com/unknown",
+		"http://Example.com:80/gophers#burrows",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(docs, gc.HasLen, 3)
+	c.Assert(docs[0].LinkID, gc.Equals, moles.LinkID)
+	c.Assert(docs[1], gc.IsNil)
+	c.Assert(docs[2].LinkID, gc.Equals, gophers.LinkID)
+
+	_, err = s.idx.FindByURLs([]string{"example.com/gophers"})
+	c.Assert(xerrors.Is(err, index.ErrInvalidArgument), gc.Equals, true)
+}
+
 //TestUpdateScore1 verifies that a document's pagerank is changed correctly
 func (s *SuiteBase) TestUpdateScore1(c *gc.C) {
 	doc := &index.Document{
@@ -237,3 +292,687 @@ func (s *SuiteBase) TestMatchSearch(c *gc.C) {
 	c.Assert(err, gc.IsNil)
 	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, expectedIDs)
 }
+
+//TestLanguageFilter verifies that search results can be restricted to a particular language
+func (s *SuiteBase) TestLanguageFilter(c *gc.C) {
+	var (
+		numDocs     = 20
+		expectedIDs []uuid.UUID
+	)
+
+	for i := 0; i < numDocs; i++ {
+		id := uuid.New()
+		doc := &index.Document{
+			LinkID:   id,
+			Title:    fmt.Sprintf("Document with id %s", id.String()),
+			Content:  "a document about gophers",
+			Language: "en",
+		}
+
+		if i%4 == 0 {
+			doc.Language = "fr"
+			expectedIDs = append(expectedIDs, id)
+		}
+
+		err := s.idx.Index(doc)
+		c.Assert(err, gc.IsNil)
+		err = s.idx.UpdateScore(id, float64(numDocs-i))
+		c.Assert(err, gc.IsNil)
+	}
+
+	it, err := s.idx.Search(index.Query{
+		Type:       index.QueryTypeMatch,
+		Expression: "gophers",
+		Language:   "fr",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, expectedIDs)
+}
+
+//TestDescriptionSearch verifies that documents can be matched by their description
+func (s *SuiteBase) TestDescriptionSearch(c *gc.C) {
+	withDesc := &index.Document{
+		LinkID:      uuid.New(),
+		Title:       "Title",
+		Content:     "nothing to see here",
+		Description: "a page about gophers",
+	}
+	withoutDesc := &index.Document{
+		LinkID:  uuid.New(),
+		Title:   "Title",
+		Content: "nothing to see here either",
+	}
+	c.Assert(s.idx.Index(withDesc), gc.IsNil)
+	c.Assert(s.idx.Index(withoutDesc), gc.IsNil)
+
+	it, err := s.idx.Search(index.Query{
+		Type:       index.QueryTypeMatch,
+		Expression: "gophers",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{withDesc.LinkID})
+
+	got, err := s.idx.FindByID(withDesc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Description, gc.Equals, withDesc.Description)
+}
+
+//TestKeyphraseSearch verifies that documents can be matched by their keyphrases
+func (s *SuiteBase) TestKeyphraseSearch(c *gc.C) {
+	withPhrases := &index.Document{
+		LinkID:     uuid.New(),
+		Title:      "Title",
+		Content:    "nothing to see here",
+		Keyphrases: []string{"gopher tunnels", "burrowing rodents"},
+	}
+	withoutPhrases := &index.Document{
+		LinkID:  uuid.New(),
+		Title:   "Title",
+		Content: "nothing to see here either",
+	}
+	c.Assert(s.idx.Index(withPhrases), gc.IsNil)
+	c.Assert(s.idx.Index(withoutPhrases), gc.IsNil)
+
+	for _, q := range []index.Query{
+		{Type: index.QueryTypeMatch, Expression: "rodents"},
+		{Type: index.QueryTypePhrase, Expression: "gopher tunnels"},
+	} {
+		it, err := s.idx.Search(q)
+		c.Assert(err, gc.IsNil)
+		c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{withPhrases.LinkID})
+	}
+
+	got, err := s.idx.FindByID(withPhrases.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Keyphrases, gc.DeepEquals, withPhrases.Keyphrases)
+}
+
+//TestHybridSearch verifies that hybrid queries fuse the results of a keyword and a semantic search
+func (s *SuiteBase) TestHybridSearch(c *gc.C) {
+	keywordOnly := &index.Document{LinkID: uuid.New(), Content: "gophers dig tunnels"}
+	semanticOnly := &index.Document{LinkID: uuid.New(), Content: "burrowing rodents", Embedding: []float32{1, 0}}
+	both := &index.Document{LinkID: uuid.New(), Content: "gophers", Embedding: []float32{0.9, 0.1}}
+	unrelated := &index.Document{LinkID: uuid.New(), Content: "cooking pasta", Embedding: []float32{0, 1}}
+	for _, doc := range []*index.Document{keywordOnly, semanticOnly, both, unrelated} {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+	}
+
+	q := index.Query{
+		Type:           index.QueryTypeHybrid,
+		Expression:     "gophers",
+		Embedding:      []float32{1, 0},
+		KeywordWeight:  1,
+		SemanticWeight: 2,
+	}
+	it, err := s.idx.Search(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.TotalCount(), gc.Equals, uint64(4))
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{both.LinkID, semanticOnly.LinkID, unrelated.LinkID, keywordOnly.LinkID})
+
+	q.Offset = 2
+	it, err = s.idx.Search(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{unrelated.LinkID, keywordOnly.LinkID})
+
+	count, err := s.idx.Count(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, uint64(4))
+
+	_, err = s.idx.Search(index.Query{Type: index.QueryTypeHybrid, Expression: "gophers"})
+	c.Assert(xerrors.Is(err, index.ErrInvalidArgument), gc.Equals, true)
+}
+
+//TestPassageSearch verifies that passages are only matched by queries that ask for them
+func (s *SuiteBase) TestPassageSearch(c *gc.C) {
+	page := &index.Document{
+		LinkID:  uuid.New(),
+		URL:     "http://example.com/gophers",
+		Content: "gophers are burrowing rodents that dig extensive tunnel systems",
+	}
+	c.Assert(s.idx.Index(page), gc.IsNil)
+	passages := index.SplitPassages(page, index.PassageConfig{Words: 5, Overlap: 2})
+	for _, passage := range passages {
+		c.Assert(s.idx.Index(passage), gc.IsNil)
+	}
+
+	c.Assert(passages, gc.HasLen, 3)
+
+	it, err := s.idx.Search(index.Query{Expression: "systems"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{page.LinkID})
+
+	it, err = s.idx.Search(index.Query{Expression: "systems", Passages: true})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{passages[2].LinkID})
+
+	count, err := s.idx.Count(index.Query{Expression: "rodents", Passages: true})
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, uint64(2))
+
+	got, err := s.idx.FindByID(passages[1].LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.ParentLinkID, gc.Equals, page.LinkID)
+	c.Assert(got.PassageIndex, gc.Equals, 1)
+}
+
+//TestStructuredDataFilters verifies that search results can be filtered by entity type and publication date
+func (s *SuiteBase) TestStructuredDataFilters(c *gc.C) {
+	now := time.Now().UTC().Truncate(time.Second)
+	oldArticle := &index.Document{
+		LinkID:   uuid.New(),
+		Content:  "gophers",
+		Entities: []index.Entity{{Type: "Article", DatePublished: now.Add(-48 * time.Hour)}},
+	}
+	newArticle := &index.Document{
+		LinkID:   uuid.New(),
+		Content:  "gophers",
+		Entities: []index.Entity{{Type: "Article", DatePublished: now}},
+	}
+	product := &index.Document{
+		LinkID:   uuid.New(),
+		Content:  "gophers",
+		Entities: []index.Entity{{Type: "Product", DatePublished: now}},
+	}
+	for i, doc := range []*index.Document{oldArticle, newArticle, product} {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(10-i)), gc.IsNil)
+	}
+
+	it, err := s.idx.Search(index.Query{
+		Type:       index.QueryTypeMatch,
+		Expression: "gophers",
+		EntityType: "article",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{oldArticle.LinkID, newArticle.LinkID})
+
+	it, err = s.idx.Search(index.Query{
+		Type:           index.QueryTypeMatch,
+		Expression:     "gophers",
+		EntityType:     "Article",
+		PublishedAfter: now.Add(-time.Hour),
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{newArticle.LinkID})
+}
+
+//TestCrawlProvenance verifies that provenance fields are stored and can be used to filter by fetch time
+//and crawl pass
+func (s *SuiteBase) TestCrawlProvenance(c *gc.C) {
+	now := time.Now().UTC().Truncate(time.Second)
+	stale := &index.Document{
+		LinkID:      uuid.New(),
+		Content:     "gophers",
+		FetchedAt:   now.Add(-14 * 24 * time.Hour),
+		CrawlPassID: 1,
+		HTTPStatus:  200,
+		ContentHash: "abc",
+	}
+	fresh := &index.Document{
+		LinkID:      uuid.New(),
+		Content:     "gophers",
+		FetchedAt:   now.Add(-time.Hour),
+		CrawlPassID: 2,
+		HTTPStatus:  200,
+		ContentHash: "def",
+	}
+	for i, doc := range []*index.Document{stale, fresh} {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(10-i)), gc.IsNil)
+	}
+
+	got, err := s.idx.FindByID(fresh.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.FetchedAt.Equal(fresh.FetchedAt), gc.Equals, true)
+	c.Assert(got.CrawlPassID, gc.Equals, fresh.CrawlPassID)
+	c.Assert(got.HTTPStatus, gc.Equals, fresh.HTTPStatus)
+	c.Assert(got.ContentHash, gc.Equals, fresh.ContentHash)
+
+	it, err := s.idx.Search(index.Query{
+		Type:         index.QueryTypeMatch,
+		Expression:   "gophers",
+		FetchedAfter: now.Add(-7 * 24 * time.Hour),
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{fresh.LinkID})
+
+	it, err = s.idx.Search(index.Query{
+		Type:        index.QueryTypeMatch,
+		Expression:  "gophers",
+		CrawlPassID: stale.CrawlPassID,
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{stale.LinkID})
+}
+
+//TestSiteFilter verifies that results can be restricted to a domain and its subdomains
+func (s *SuiteBase) TestSiteFilter(c *gc.C) {
+	urls := []string{
+		"https://example.com/gophers",
+		"https://blog.example.com/gophers",
+		"https://notexample.com/gophers",
+		"https://example.org/gophers",
+	}
+	ids := make([]uuid.UUID, len(urls))
+	for i, u := range urls {
+		ids[i] = uuid.New()
+		c.Assert(s.idx.Index(&index.Document{LinkID: ids[i], URL: u, Content: "gophers"}), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(ids[i], float64(len(urls)-i)), gc.IsNil)
+	}
+
+	specs := []struct {
+		site string
+		exp  []uuid.UUID
+	}{
+		{site: "example.com", exp: ids[:2]},
+		{site: "Blog.Example.com", exp: ids[1:2]},
+		{site: "example.org", exp: ids[3:]},
+		{site: "org", exp: nil},
+	}
+	for _, spec := range specs {
+		it, err := s.idx.Search(index.Query{
+			Type:       index.QueryTypeMatch,
+			Expression: "gophers",
+			Site:       spec.site,
+		})
+		c.Assert(err, gc.IsNil)
+		c.Assert(s.iterateDocs(c, it), gc.DeepEquals, spec.exp, gc.Commentf("site %q", spec.site))
+	}
+}
+
+//TestFilteredDocuments verifies that documents flagged by a content classifier
+//can be omitted from the results
+func (s *SuiteBase) TestFilteredDocuments(c *gc.C) {
+	clean := &index.Document{LinkID: uuid.New(), Content: "gophers"}
+	spam := &index.Document{LinkID: uuid.New(), Content: "gophers", Filtered: true, FilterReason: "spam"}
+	for i, doc := range []*index.Document{clean, spam} {
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(10-i)), gc.IsNil)
+	}
+
+	got, err := s.idx.FindByID(spam.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Filtered, gc.Equals, true)
+	c.Assert(got.FilterReason, gc.Equals, "spam")
+
+	q := index.Query{Type: index.QueryTypeMatch, Expression: "gophers"}
+	it, err := s.idx.Search(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{clean.LinkID, spam.LinkID})
+
+	q.ExcludeFiltered = true
+	it, err = s.idx.Search(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{clean.LinkID})
+
+	count, err := s.idx.Count(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, uint64(1))
+}
+
+//TestDomainFilters verifies that results can be restricted to, or omit, a set
+//of domains and their subdomains
+func (s *SuiteBase) TestDomainFilters(c *gc.C) {
+	urls := []string{
+		"https://example.com/gophers",
+		"https://blog.example.com/gophers",
+		"https://example.org/gophers",
+		"https://example.net/gophers",
+	}
+	ids := make([]uuid.UUID, len(urls))
+	for i, u := range urls {
+		ids[i] = uuid.New()
+		c.Assert(s.idx.Index(&index.Document{LinkID: ids[i], URL: u, Content: "gophers"}), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(ids[i], float64(len(urls)-i)), gc.IsNil)
+	}
+
+	specs := []struct {
+		include, exclude []string
+		site             string
+		exp              []uuid.UUID
+	}{
+		{include: []string{"example.com", "Example.NET"}, exp: []uuid.UUID{ids[0], ids[1], ids[3]}},
+		{exclude: []string{"blog.example.com", "example.org"}, exp: []uuid.UUID{ids[0], ids[3]}},
+		{include: []string{"example.com", "example.org"}, exclude: []string{"blog.example.com"}, exp: []uuid.UUID{ids[0], ids[2]}},
+		{include: []string{"example.org", "example.net"}, site: "example.net", exp: []uuid.UUID{ids[3]}},
+		{exclude: []string{"example.com"}, site: "example.com", exp: nil},
+	}
+	for i, spec := range specs {
+		it, err := s.idx.Search(index.Query{
+			Type:           index.QueryTypeMatch,
+			Expression:     "gophers",
+			Site:           spec.site,
+			IncludeDomains: spec.include,
+			ExcludeDomains: spec.exclude,
+		})
+		c.Assert(err, gc.IsNil)
+		c.Assert(s.iterateDocs(c, it), gc.DeepEquals, spec.exp, gc.Commentf("spec %d", i))
+	}
+}
+
+//TestUpdateFields verifies that partial updates are applied to indexed documents
+func (s *SuiteBase) TestUpdateFields(c *gc.C) {
+	doc := &index.Document{
+		LinkID:  uuid.New(),
+		Title:   "Title",
+		Content: "nothing to see here",
+	}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+
+	err := s.idx.UpdateFields(doc.LinkID, map[string]interface{}{
+		index.FieldInDegree:   3,
+		index.FieldAnchorText: []string{"gopher facts"},
+		index.FieldPageRank:   0.5,
+	})
+	c.Assert(err, gc.IsNil)
+
+	got, err := s.idx.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Content, gc.Equals, doc.Content)
+	c.Assert(got.InDegree, gc.Equals, 3)
+	c.Assert(got.AnchorText, gc.DeepEquals, []string{"gopher facts"})
+	c.Assert(got.PageRank, gc.Equals, 0.5)
+
+	//anchor text should be searchable
+	it, err := s.idx.Search(index.Query{
+		Type:       index.QueryTypeMatch,
+		Expression: "gopher",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{doc.LinkID})
+}
+
+//TestUpdateFieldsIsAtomic verifies that invalid partial updates leave the document untouched
+func (s *SuiteBase) TestUpdateFieldsIsAtomic(c *gc.C) {
+	doc := &index.Document{
+		LinkID: uuid.New(),
+		Title:  "Title",
+	}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+
+	err := s.idx.UpdateFields(doc.LinkID, map[string]interface{}{
+		index.FieldTitle:    "New title",
+		index.FieldInDegree: "not a number",
+	})
+	c.Assert(xerrors.Is(err, index.ErrInvalidFieldValue), gc.Equals, true)
+
+	err = s.idx.UpdateFields(doc.LinkID, map[string]interface{}{
+		index.FieldTitle: "New title",
+		"Bogus":          1,
+	})
+	c.Assert(xerrors.Is(err, index.ErrUnknownField), gc.Equals, true)
+
+	got, err := s.idx.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Title, gc.Equals, "Title")
+}
+
+//TestVersionConflicts verifies that conditional writes detect concurrent modifications
+func (s *SuiteBase) TestVersionConflicts(c *gc.C) {
+	doc := &index.Document{
+		LinkID: uuid.New(),
+		Title:  "Title",
+	}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+	readVersion := doc.Version
+	c.Assert(readVersion, gc.Not(gc.Equals), uint64(0))
+
+	//a concurrent writer bumps the document version
+	c.Assert(s.idx.UpdateScore(doc.LinkID, 0.5), gc.IsNil)
+
+	err := s.idx.UpdateFieldsIfVersion(doc.LinkID, readVersion, map[string]interface{}{
+		index.FieldTitle: "Stale title",
+	})
+	c.Assert(xerrors.Is(err, index.ErrVersionConflict), gc.Equals, true)
+
+	stale := &index.Document{LinkID: doc.LinkID, Title: "Stale title", Version: readVersion}
+	c.Assert(xerrors.Is(s.idx.Index(stale), index.ErrVersionConflict), gc.Equals, true)
+
+	//retrying with the latest version should succeed without losing the concurrent write
+	err = index.UpdateFieldsWithRetry(s.idx, doc.LinkID, 3, func(*index.Document) map[string]interface{} {
+		return map[string]interface{}{index.FieldTitle: "New title"}
+	})
+	c.Assert(err, gc.IsNil)
+
+	got, err := s.idx.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(got.Title, gc.Equals, "New title")
+	c.Assert(got.PageRank, gc.Equals, 0.5)
+	c.Assert(got.Version > readVersion, gc.Equals, true)
+}
+
+//TestCount verifies that the number of matching documents can be retrieved without searching
+func (s *SuiteBase) TestCount(c *gc.C) {
+	numDocs := 25
+	for i := 0; i < numDocs; i++ {
+		doc := &index.Document{
+			LinkID:  uuid.New(),
+			Content: "this is the text of a document",
+		}
+		if i%5 == 0 {
+			doc.Content = "this content is interesting"
+		}
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+	}
+
+	q := index.Query{
+		Type:       index.QueryTypeMatch,
+		Expression: "interesting",
+	}
+	count, err := s.idx.Count(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, uint64(5))
+
+	it, err := s.idx.Search(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.TotalCount(), gc.Equals, count)
+	c.Assert(it.Close(), gc.IsNil)
+
+	count, err = s.idx.Count(index.Query{Type: index.QueryTypeMatch, Expression: "unicorns"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, uint64(0))
+}
+
+//TestSearchAll verifies that bulk searches stream through the entire result set
+func (s *SuiteBase) TestSearchAll(c *gc.C) {
+	var (
+		numDocs     = 150
+		expectedIDs []uuid.UUID
+	)
+	for i := 0; i < numDocs; i++ {
+		id := uuid.New()
+		doc := &index.Document{
+			LinkID:  id,
+			Content: "this is the text of a document",
+		}
+		if i%2 == 0 {
+			doc.Content = "this content is interesting"
+			expectedIDs = append(expectedIDs, id)
+		}
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(id, float64(numDocs-i)), gc.IsNil)
+	}
+
+	it, err := s.idx.SearchAll(index.Query{
+		Type:       index.QueryTypeMatch,
+		Expression: "interesting",
+	})
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.TotalCount(), gc.Equals, uint64(len(expectedIDs)))
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, expectedIDs)
+}
+
+//TestDelete verifies that deleted documents can no longer be looked up or searched
+func (s *SuiteBase) TestDelete(c *gc.C) {
+	keepID, deleteID := uuid.New(), uuid.New()
+	c.Assert(s.idx.Index(&index.Document{LinkID: keepID, Content: "gophers"}), gc.IsNil)
+	c.Assert(s.idx.Index(&index.Document{LinkID: deleteID, Content: "gophers"}), gc.IsNil)
+
+	c.Assert(s.idx.Delete(deleteID), gc.IsNil)
+
+	_, err := s.idx.FindByID(deleteID)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+
+	it, err := s.idx.Search(index.Query{Type: index.QueryTypeMatch, Expression: "gophers"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, []uuid.UUID{keepID})
+
+	err = s.idx.Delete(deleteID)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+
+	//a deleted document can be indexed again
+	c.Assert(s.idx.Index(&index.Document{LinkID: deleteID, Content: "gophers"}), gc.IsNil)
+	count, err := s.idx.Count(index.Query{Type: index.QueryTypeMatch, Expression: "gophers"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, uint64(2))
+}
+
+//TestStorageStats verifies that the reported storage footprint grows as documents are indexed
+func (s *SuiteBase) TestStorageStats(c *gc.C) {
+	before, err := s.idx.StorageStats()
+	c.Assert(err, gc.IsNil)
+
+	for i := 0; i < 10; i++ {
+		doc := &index.Document{
+			LinkID:  uuid.New(),
+			Title:   fmt.Sprintf("doc %d", i),
+			Content: "this is the text of a document about gophers",
+		}
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+	}
+
+	after, err := s.idx.StorageStats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(after.Documents, gc.Equals, before.Documents+10)
+	c.Assert(after.MemoryBytes+after.DiskBytes > before.MemoryBytes+before.DiskBytes, gc.Equals, true)
+	c.Assert(after.Segments > 0, gc.Equals, true)
+}
+
+//TestReindex verifies that documents remain searchable across a reindex and that writes made while reindexing are retained
+func (s *SuiteBase) TestReindex(c *gc.C) {
+	var expectedIDs []uuid.UUID
+	for i := 0; i < 20; i++ {
+		id := uuid.New()
+		expectedIDs = append(expectedIDs, id)
+		c.Assert(s.idx.Index(&index.Document{LinkID: id, Content: "gophers"}), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(id, float64(100-i)), gc.IsNil)
+	}
+
+	err := s.idx.CommitReindex()
+	c.Assert(xerrors.Is(err, index.ErrNoReindexInProgress), gc.Equals, true)
+
+	c.Assert(s.idx.BeginReindex(), gc.IsNil)
+	err = s.idx.BeginReindex()
+	c.Assert(xerrors.Is(err, index.ErrReindexInProgress), gc.Equals, true)
+
+	//writes performed while reindexing
+	lateID := uuid.New()
+	c.Assert(s.idx.Index(&index.Document{LinkID: lateID, Content: "gophers"}), gc.IsNil)
+	c.Assert(s.idx.UpdateScore(lateID, 1000), gc.IsNil)
+	expectedIDs = append([]uuid.UUID{lateID}, expectedIDs...)
+
+	q := index.Query{Type: index.QueryTypeMatch, Expression: "gophers"}
+	it, err := s.idx.SearchAll(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, expectedIDs)
+
+	c.Assert(s.idx.CommitReindex(), gc.IsNil)
+
+	it, err = s.idx.SearchAll(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, expectedIDs)
+}
+
+//TestSuggestions verifies that misspelled words in search expressions are corrected
+func (s *SuiteBase) TestSuggestions(c *gc.C) {
+	for _, content := range []string{
+		"gophers live in a tunnel",
+		"a tunnel dug by gophers",
+		"the gopher sleeps",
+	} {
+		c.Assert(s.idx.Index(&index.Document{LinkID: uuid.New(), Content: content}), gc.IsNil)
+	}
+
+	suggestions, err := s.idx.Suggestions(index.Query{Type: index.QueryTypeMatch, Expression: "gophers tunnel"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(suggestions, gc.HasLen, 0)
+
+	suggestions, err = s.idx.Suggestions(index.Query{Type: index.QueryTypeMatch, Expression: "the gopehrs tunel"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(len(suggestions) > 0, gc.Equals, true)
+	c.Assert(suggestions[0], gc.Equals, "the gophers tunnel")
+}
+
+//TestPagination verifies that search results spanning multiple result pages are iterated in order
+func (s *SuiteBase) TestPagination(c *gc.C) {
+	expectedIDs := s.indexRankedDocs(c, 25)
+
+	it, err := s.idx.Search(index.Query{Type: index.QueryTypeMatch, Expression: "paginated"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.TotalCount(), gc.Equals, uint64(25))
+	c.Assert(s.iterateDocs(c, it), gc.DeepEquals, expectedIDs)
+}
+
+//TestPaginationWithOffset verifies that Query.Offset skips results across page boundaries while TotalCount reports the full result set
+func (s *SuiteBase) TestPaginationWithOffset(c *gc.C) {
+	expectedIDs := s.indexRankedDocs(c, 30)
+
+	specs := []struct {
+		offset int
+		exp    []uuid.UUID
+	}{
+		{offset: 0, exp: expectedIDs},
+		{offset: 3, exp: expectedIDs[3:]},
+		{offset: 10, exp: expectedIDs[10:]},
+		{offset: 15, exp: expectedIDs[15:]},
+		{offset: 29, exp: expectedIDs[29:]},
+		{offset: 30, exp: nil},
+		{offset: 45, exp: nil},
+	}
+
+	for _, spec := range specs {
+		comment := gc.Commentf("offset %d", spec.offset)
+		it, err := s.idx.Search(index.Query{
+			Type:       index.QueryTypeMatch,
+			Expression: "paginated",
+			Offset:     spec.offset,
+		})
+		c.Assert(err, gc.IsNil, comment)
+		c.Assert(it.TotalCount(), gc.Equals, uint64(30), comment)
+		c.Assert(s.iterateDocs(c, it), gc.DeepEquals, spec.exp, comment)
+	}
+}
+
+//TestEmptyResultIteration verifies that iterating a search without matches yields no documents and no errors
+func (s *SuiteBase) TestEmptyResultIteration(c *gc.C) {
+	s.indexRankedDocs(c, 5)
+
+	it, err := s.idx.Search(index.Query{Type: index.QueryTypeMatch, Expression: "unicorns"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.TotalCount(), gc.Equals, uint64(0))
+	c.Assert(it.Next(), gc.Equals, false)
+	c.Assert(it.Next(), gc.Equals, false)
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(it.Close(), gc.IsNil)
+
+	it, err = s.idx.SearchAll(index.Query{Type: index.QueryTypeMatch, Expression: "unicorns"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.TotalCount(), gc.Equals, uint64(0))
+	c.Assert(s.iterateDocs(c, it), gc.HasLen, 0)
+}
+
+//indexRankedDocs indexes numDocs documents containing the term "paginated" and returns their IDs in descending rank order
+func (s *SuiteBase) indexRankedDocs(c *gc.C, numDocs int) []uuid.UUID {
+	expectedIDs := make([]uuid.UUID, numDocs)
+	for i := 0; i < numDocs; i++ {
+		id := uuid.New()
+		expectedIDs[i] = id
+		c.Assert(s.idx.Index(&index.Document{
+			LinkID:  id,
+			Title:   fmt.Sprintf("page %d", i),
+			Content: "a paginated document",
+		}), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(id, float64(numDocs-i)), gc.IsNil)
+	}
+	return expectedIDs
+}
@@ -0,0 +1,82 @@
+package index
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const defaultPassageWords = 100
+
+/*
+PassageConfig controls how the content of a document is split into passages.
+Passages are runs of Words words and consecutive passages share Overlap words
+so that answers spanning a passage boundary are still retrievable
+*/
+type PassageConfig struct {
+	//Words defaults to 100 if not specified
+	Words int
+	//Overlap defaults to a fifth of Words if not specified or not smaller than Words
+	Overlap int
+}
+
+func (cfg PassageConfig) withDefaults() PassageConfig {
+	if cfg.Words <= 0 {
+		cfg.Words = defaultPassageWords
+	}
+	if cfg.Overlap <= 0 || cfg.Overlap >= cfg.Words {
+		cfg.Overlap = cfg.Words / 5
+	}
+	return cfg
+}
+
+/*
+PassageID returns the LinkID of the n-th passage of the document with the
+specified LinkID.  IDs are derived deterministically so reindexing a page
+overwrites its existing passages
+*/
+func PassageID(parentID uuid.UUID, n int) uuid.UUID {
+	return uuid.NewSHA1(parentID, []byte("passage:"+strconv.Itoa(n)))
+}
+
+/*
+SplitPassages splits the content of doc into overlapping passages that can be
+indexed as child documents of doc for retrieving the parts of a page that
+answer a question.  Passages inherit the URL, title and the filterable
+attributes of doc and are only matched by queries that set Query.Passages
+*/
+func SplitPassages(doc *Document, cfg PassageConfig) []*Document {
+	cfg = cfg.withDefaults()
+	words := strings.Fields(doc.Content)
+
+	var passages []*Document
+	for start, step := 0, cfg.Words-cfg.Overlap; start < len(words); start += step {
+		end := start + cfg.Words
+		if end > len(words) {
+			end = len(words)
+		}
+
+		n := len(passages)
+		passages = append(passages, &Document{
+			LinkID:       PassageID(doc.LinkID, n),
+			ParentLinkID: doc.LinkID,
+			PassageIndex: n,
+			URL:          doc.URL,
+			Title:        doc.Title,
+			Content:      strings.Join(words[start:end], " "),
+			Language:     doc.Language,
+			Entities:     append([]Entity(nil), doc.Entities...),
+			IndexedAt:    doc.IndexedAt,
+			FetchedAt:    doc.FetchedAt,
+			CrawlPassID:  doc.CrawlPassID,
+			HTTPStatus:   doc.HTTPStatus,
+			Filtered:     doc.Filtered,
+			FilterReason: doc.FilterReason,
+		})
+		if end == len(words) {
+			break
+		}
+	}
+	return passages
+}
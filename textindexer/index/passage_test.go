@@ -0,0 +1,46 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(PassageTestSuite))
+
+type PassageTestSuite struct{}
+
+func (s *PassageTestSuite) TestSplitPassages(c *gc.C) {
+	doc := &Document{
+		LinkID:   uuid.New(),
+		URL:      "http://example.com/",
+		Title:    "Gophers",
+		Content:  strings.Repeat("gopher ", 250),
+		Language: "en",
+		Filtered: true,
+	}
+
+	passages := SplitPassages(doc, PassageConfig{})
+	c.Assert(passages, gc.HasLen, 3)
+	for n, passage := range passages {
+		c.Assert(passage.LinkID, gc.Equals, PassageID(doc.LinkID, n))
+		c.Assert(passage.ParentLinkID, gc.Equals, doc.LinkID)
+		c.Assert(passage.PassageIndex, gc.Equals, n)
+		c.Assert(passage.URL, gc.Equals, doc.URL)
+		c.Assert(passage.Language, gc.Equals, doc.Language)
+		c.Assert(passage.Filtered, gc.Equals, true)
+	}
+	// 100 word passages starting every 80 words
+	c.Assert(len(strings.Fields(passages[0].Content)), gc.Equals, 100)
+	c.Assert(len(strings.Fields(passages[2].Content)), gc.Equals, 90)
+
+	c.Assert(SplitPassages(&Document{LinkID: doc.LinkID}, PassageConfig{}), gc.HasLen, 0)
+}
+
+func (s *PassageTestSuite) TestPassageConfigDefaults(c *gc.C) {
+	c.Assert(PassageConfig{}.withDefaults(), gc.Equals, PassageConfig{Words: 100, Overlap: 20})
+	c.Assert(PassageConfig{Words: 50}.withDefaults(), gc.Equals, PassageConfig{Words: 50, Overlap: 10})
+	c.Assert(PassageConfig{Words: 50, Overlap: 60}.withDefaults(), gc.Equals, PassageConfig{Words: 50, Overlap: 10})
+	c.Assert(PassageConfig{Words: 50, Overlap: 5}.withDefaults(), gc.Equals, PassageConfig{Words: 50, Overlap: 5})
+}
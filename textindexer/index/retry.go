@@ -0,0 +1,178 @@
+package index
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultRetryAttempts       = 3
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+)
+
+/*
+RetryPolicy controls how indexer operations that fail with a transient error
+are retried.  The delay between attempts grows exponentially from
+InitialBackoff up to MaxBackoff.  If not specified, 3 attempts are made with a
+backoff between 100ms and 5s.
+*/
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return defaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) initialBackoff() time.Duration {
+	if p.InitialBackoff <= 0 {
+		return defaultRetryInitialBackoff
+	}
+	return p.InitialBackoff
+}
+
+func (p RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff <= 0 {
+		return defaultRetryMaxBackoff
+	}
+	return p.MaxBackoff
+}
+
+func (p RetryPolicy) do(op func() error) error {
+	backoff := p.initialBackoff()
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !isTransient(err) || attempt >= p.maxAttempts() {
+			return err
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > p.maxBackoff() {
+			backoff = p.maxBackoff()
+		}
+	}
+}
+
+func isTransient(err error) bool {
+	if IsRetryable(err) || xerrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return xerrors.As(err, &netErr) && netErr.Timeout()
+}
+
+/*
+WithRetry decorates idx so that operations failing with ErrUnavailable or a
+timeout are transparently retried according to policy.  Errors from the
+iterators returned by Search and SearchAll are not retried.
+*/
+func WithRetry(idx Indexer, policy RetryPolicy) Indexer {
+	return &retryingIndexer{idx: idx, policy: policy}
+}
+
+type retryingIndexer struct {
+	idx    Indexer
+	policy RetryPolicy
+}
+
+func (r *retryingIndexer) Index(doc *Document) error {
+	return r.policy.do(func() error { return r.idx.Index(doc) })
+}
+
+func (r *retryingIndexer) FindByID(linkID uuid.UUID) (doc *Document, err error) {
+	err = r.policy.do(func() error {
+		doc, err = r.idx.FindByID(linkID)
+		return err
+	})
+	return doc, err
+}
+
+func (r *retryingIndexer) FindByURL(rawURL string) (doc *Document, err error) {
+	err = r.policy.do(func() error {
+		doc, err = r.idx.FindByURL(rawURL)
+		return err
+	})
+	return doc, err
+}
+
+func (r *retryingIndexer) FindByURLs(rawURLs []string) (docs []*Document, err error) {
+	err = r.policy.do(func() error {
+		docs, err = r.idx.FindByURLs(rawURLs)
+		return err
+	})
+	return docs, err
+}
+
+func (r *retryingIndexer) Search(query Query) (it Iterator, err error) {
+	err = r.policy.do(func() error {
+		it, err = r.idx.Search(query)
+		return err
+	})
+	return it, err
+}
+
+func (r *retryingIndexer) SearchAll(query Query) (it Iterator, err error) {
+	err = r.policy.do(func() error {
+		it, err = r.idx.SearchAll(query)
+		return err
+	})
+	return it, err
+}
+
+func (r *retryingIndexer) Count(query Query) (count uint64, err error) {
+	err = r.policy.do(func() error {
+		count, err = r.idx.Count(query)
+		return err
+	})
+	return count, err
+}
+
+func (r *retryingIndexer) Suggestions(query Query) (suggestions []string, err error) {
+	err = r.policy.do(func() error {
+		suggestions, err = r.idx.Suggestions(query)
+		return err
+	})
+	return suggestions, err
+}
+
+func (r *retryingIndexer) UpdateScore(linkID uuid.UUID, score float64) error {
+	return r.policy.do(func() error { return r.idx.UpdateScore(linkID, score) })
+}
+
+func (r *retryingIndexer) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	return r.policy.do(func() error { return r.idx.UpdateFields(linkID, fields) })
+}
+
+func (r *retryingIndexer) UpdateFieldsIfVersion(linkID uuid.UUID, version uint64, fields map[string]interface{}) error {
+	return r.policy.do(func() error { return r.idx.UpdateFieldsIfVersion(linkID, version, fields) })
+}
+
+func (r *retryingIndexer) Delete(linkID uuid.UUID) error {
+	return r.policy.do(func() error { return r.idx.Delete(linkID) })
+}
+
+func (r *retryingIndexer) StorageStats() (stats *StorageStats, err error) {
+	err = r.policy.do(func() error {
+		stats, err = r.idx.StorageStats()
+		return err
+	})
+	return stats, err
+}
+
+func (r *retryingIndexer) BeginReindex() error {
+	return r.policy.do(r.idx.BeginReindex)
+}
+
+func (r *retryingIndexer) CommitReindex() error {
+	return r.policy.do(r.idx.CommitReindex)
+}
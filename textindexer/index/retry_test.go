@@ -0,0 +1,62 @@
+package index
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(RetryTestSuite))
+
+type RetryTestSuite struct{}
+
+func (s *RetryTestSuite) TestRetryTransientErrors(c *gc.C) {
+	specs := []struct {
+		descr   string
+		errs    []error
+		expErr  error
+		expCall int
+	}{
+		{"success", nil, nil, 1},
+		{"recovers", []error{Unavailable(xerrors.New("down")), context.DeadlineExceeded}, nil, 3},
+		{"gives up", []error{Unavailable(xerrors.New("down")), Unavailable(xerrors.New("down")), ErrUnavailable}, ErrUnavailable, 3},
+		{"permanent", []error{ErrVersionConflict}, ErrVersionConflict, 1},
+	}
+
+	for _, spec := range specs {
+		idx := &flakyIndexer{errs: spec.errs}
+		err := WithRetry(idx, RetryPolicy{InitialBackoff: time.Millisecond}).Index(&Document{})
+		if spec.expErr == nil {
+			c.Assert(err, gc.IsNil, gc.Commentf(spec.descr))
+		} else {
+			c.Assert(xerrors.Is(err, spec.expErr), gc.Equals, true, gc.Commentf(spec.descr))
+		}
+		c.Assert(idx.calls, gc.Equals, spec.expCall, gc.Commentf(spec.descr))
+	}
+}
+
+func (s *RetryTestSuite) TestRetryPolicyDefaults(c *gc.C) {
+	var p RetryPolicy
+	c.Assert(p.maxAttempts(), gc.Equals, defaultRetryAttempts)
+	c.Assert(p.initialBackoff(), gc.Equals, defaultRetryInitialBackoff)
+	c.Assert(p.maxBackoff(), gc.Equals, defaultRetryMaxBackoff)
+}
+
+// flakyIndexer fails the first len(errs) Index calls with the specified
+// errors.
+type flakyIndexer struct {
+	Indexer
+
+	errs  []error
+	calls int
+}
+
+func (f *flakyIndexer) Index(*Document) error {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return f.errs[f.calls-1]
+	}
+	return nil
+}
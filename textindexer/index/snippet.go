@@ -0,0 +1,177 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const snippetEllipsis = "..."
+
+/*
+Snippet computes a query-aware snippet of at most maxLen characters (not counting
+ellipses) for doc.  The snippet is the window of the document content that
+contains the most query terms (or phrase occurrences for phrase queries).  If
+the content does not match the query, the document summary (see
+Document.Summary) or, if there is none, the beginning of the content is
+returned.
+
+Snippets are computed client-side so they are consistent across backends,
+regardless of whether they support highlighting
+*/
+func Snippet(doc *Document, q Query, maxLen int) string {
+	text := doc.Content
+	if text == "" {
+		text = doc.Description
+	}
+	words := strings.Fields(text)
+	if len(words) == 0 || maxLen <= 0 {
+		return ""
+	}
+
+	normWords := make([]string, len(words))
+	for i, word := range words {
+		normWords[i] = normalizeTerm(word)
+	}
+	terms := queryTerms(q)
+
+	var bestStart, bestEnd, bestScore, bestSlack int
+	for start := range words {
+		end := windowEnd(words, start, maxLen)
+		score, firstHit, lastHit := snippetScore(normWords[start:end], terms, q.Type == QueryTypePhrase)
+
+		//among windows with the same score, prefer the one where the
+		//matches are surrounded by the most context
+		slack := firstHit
+		if after := end - start - 1 - lastHit; after < slack {
+			slack = after
+		}
+		if start == 0 || score > bestScore || (score > 0 && score == bestScore && slack > bestSlack) {
+			bestStart, bestEnd, bestScore, bestSlack = start, end, score, slack
+		}
+		//no point in scanning further if we can already see the end of the text
+		if end == len(words) {
+			break
+		}
+	}
+
+	//a fragment without any matches says little about the document; the
+	//summary is a better fit if one is available
+	if bestScore == 0 {
+		if summaryWords := strings.Fields(doc.Summary); len(summaryWords) != 0 {
+			return windowSnippet(summaryWords, 0, windowEnd(summaryWords, 0, maxLen), maxLen)
+		}
+	}
+	return windowSnippet(words, bestStart, bestEnd, maxLen)
+}
+
+//windowSnippet joins the words in [start, end) adding ellipses where the text was cut
+func windowSnippet(words []string, start, end, maxLen int) string {
+	snippet := strings.Join(words[start:end], " ")
+	if end == start {
+		//the first word alone is longer than maxLen
+		snippet = truncateRunes(words[start], maxLen)
+		end++
+	}
+	if start > 0 {
+		snippet = snippetEllipsis + snippet
+	}
+	if end < len(words) {
+		snippet = strings.TrimRight(snippet, ".,;:!?") + snippetEllipsis
+	}
+	return snippet
+}
+
+//windowEnd returns the index past the last word that fits in a window of maxLen characters starting at start
+func windowEnd(words []string, start, maxLen int) int {
+	var length int
+	end := start
+	for ; end < len(words); end++ {
+		wordLen := utf8.RuneCountInString(words[end])
+		if end > start {
+			wordLen++ //separating space
+		}
+		if length+wordLen > maxLen {
+			break
+		}
+		length += wordLen
+	}
+	return end
+}
+
+/*
+snippetScore returns the number of distinct query terms that appear in window or,
+for phrase queries, the number of occurrences of the phrase.  It also returns
+the positions of the first and last matching words in window
+*/
+func snippetScore(window, terms []string, phrase bool) (score, firstHit, lastHit int) {
+	firstHit, lastHit = -1, -1
+	addHit := func(from, to int) {
+		if firstHit == -1 || from < firstHit {
+			firstHit = from
+		}
+		if to > lastHit {
+			lastHit = to
+		}
+	}
+
+	if len(terms) == 0 {
+		return 0, -1, -1
+	}
+
+	if phrase {
+		for i := 0; i+len(terms) <= len(window); i++ {
+			if equalTerms(window[i:i+len(terms)], terms) {
+				score++
+				addHit(i, i+len(terms)-1)
+			}
+		}
+		return score, firstHit, lastHit
+	}
+
+	for _, term := range terms {
+		for i, word := range window {
+			if word == term {
+				score++
+				addHit(i, i)
+				break
+			}
+		}
+	}
+	return score, firstHit, lastHit
+}
+
+func equalTerms(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//queryTerms returns the normalized terms of the query expression
+func queryTerms(q Query) []string {
+	var terms []string
+	for _, field := range strings.Fields(q.Expression) {
+		if term := normalizeTerm(field); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+//normalizeTerm lowercases term and strips any leading or trailing punctuation
+func normalizeTerm(term string) string {
+	return strings.ToLower(strings.TrimFunc(term, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}))
+}
+
+func truncateRunes(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
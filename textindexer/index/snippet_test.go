@@ -0,0 +1,72 @@
+package index
+
+import (
+	"testing"
+
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(SnippetTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type SnippetTestSuite struct{}
+
+func (s *SnippetTestSuite) TestSnippet(c *gc.C) {
+	doc := &Document{
+		Content: "Once upon a time there was a small town. The town was home to many animals. " +
+			"Among them lived a family of gophers who dug tunnels under the old oak tree. " +
+			"Nobody knew how deep the gopher tunnels went.",
+	}
+
+	specs := []struct {
+		descr string
+		q     Query
+		exp   string
+	}{
+		{
+			descr: "match query",
+			q:     Query{Type: QueryTypeMatch, Expression: "Gophers tunnels"},
+			exp:   "...family of gophers who dug tunnels under...",
+		},
+		{
+			descr: "phrase query",
+			q:     Query{Type: QueryTypePhrase, Expression: "gopher tunnels"},
+			exp:   "...knew how deep the gopher tunnels went.",
+		},
+		{
+			descr: "no match",
+			q:     Query{Type: QueryTypeMatch, Expression: "unicorns"},
+			exp:   "Once upon a time there was a small town...",
+		},
+	}
+
+	for specIndex, spec := range specs {
+		c.Logf("[spec %d] %s", specIndex, spec.descr)
+		c.Assert(Snippet(doc, spec.q, 40), gc.Equals, spec.exp)
+	}
+}
+
+func (s *SnippetTestSuite) TestSnippetShortContent(c *gc.C) {
+	doc := &Document{Content: "hello gophers"}
+	c.Assert(Snippet(doc, Query{Expression: "gophers"}, 40), gc.Equals, "hello gophers")
+
+	doc = &Document{Description: "supercalifragilistic"}
+	c.Assert(Snippet(doc, Query{Expression: "gophers"}, 5), gc.Equals, "super")
+
+	c.Assert(Snippet(&Document{}, Query{Expression: "gophers"}, 40), gc.Equals, "")
+}
+
+func (s *SnippetTestSuite) TestSnippetSummaryFallback(c *gc.C) {
+	doc := &Document{
+		Content: "Home About Contact. Gophers dig tunnels under the old oak tree.",
+		Summary: "Gophers dig tunnels under the old oak tree. They rarely come out during the day.",
+	}
+
+	// Matching fragments are preferred over the summary
+	c.Assert(Snippet(doc, Query{Expression: "tunnels"}, 40), gc.Equals, "...Contact. Gophers dig tunnels under the...")
+
+	// The summary replaces fragments that do not match the query
+	c.Assert(Snippet(doc, Query{Expression: "unicorns"}, 40), gc.Equals, "Gophers dig tunnels under the old oak...")
+	c.Assert(Snippet(doc, Query{Expression: "unicorns"}, 200), gc.Equals, doc.Summary)
+}
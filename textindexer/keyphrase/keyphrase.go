@@ -0,0 +1,185 @@
+// Package keyphrase provides an index.Enricher that extracts the keyphrases
+// of documents using RAKE (Rapid Automatic Keyword Extraction): the text is
+// split into candidate phrases at stop words and punctuation, each word is
+// scored by the ratio of its degree (the total length of the candidates it
+// appears in) to its frequency and each candidate by the sum of the scores of
+// its words.
+package keyphrase
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+)
+
+const (
+	defaultMaxPhrases     = 5
+	defaultMaxPhraseWords = 3
+)
+
+// stopwords delimit candidate phrases.
+var stopwords = toSet(
+	"a", "about", "above", "after", "again", "all", "also", "am", "an", "and",
+	"any", "are", "as", "at", "be", "because", "been", "before", "being",
+	"between", "both", "but", "by", "can", "could", "did", "do", "does",
+	"doing", "down", "during", "each", "few", "for", "from", "further", "had",
+	"has", "have", "having", "he", "her", "here", "hers", "him", "his", "how",
+	"i", "if", "in", "into", "is", "it", "its", "just", "me", "more", "most",
+	"my", "no", "nor", "not", "now", "of", "off", "on", "once", "only", "or",
+	"other", "our", "out", "over", "own", "same", "she", "should", "so",
+	"some", "such", "than", "that", "the", "their", "them", "then", "there",
+	"these", "they", "this", "those", "through", "to", "too", "under",
+	"until", "up", "very", "was", "we", "were", "what", "when", "where",
+	"which", "while", "who", "whom", "why", "will", "with", "would", "you",
+	"your",
+)
+
+// Config encapsulates the configuration options for an Extractor.
+type Config struct {
+	// MaxPhrases is the maximum number of keyphrases extracted from a
+	// document. If not specified, up to 5 keyphrases are extracted.
+	MaxPhrases int
+
+	// MaxPhraseWords is the maximum number of words in a keyphrase. Longer
+	// candidates are discarded. If not specified, a default value of 3 will
+	// be used.
+	MaxPhraseWords int
+}
+
+func (cfg Config) maxPhrases() int {
+	if cfg.MaxPhrases <= 0 {
+		return defaultMaxPhrases
+	}
+	return cfg.MaxPhrases
+}
+
+func (cfg Config) maxPhraseWords() int {
+	if cfg.MaxPhraseWords <= 0 {
+		return defaultMaxPhraseWords
+	}
+	return cfg.MaxPhraseWords
+}
+
+// Extractor is an index.Enricher that populates the Keyphrases field of
+// documents.
+type Extractor struct {
+	cfg Config
+}
+
+// New returns a new Extractor instance using the provided config.
+func New(cfg Config) *Extractor {
+	return &Extractor{cfg: cfg}
+}
+
+// Enrich implements index.Enricher. Keyphrases are extracted from the title
+// and the main text of the document if available or its content otherwise.
+// Documents that already have keyphrases are left untouched.
+func (e *Extractor) Enrich(_ context.Context, doc *index.Document) error {
+	if len(doc.Keyphrases) != 0 {
+		return nil
+	}
+
+	text := doc.MainText
+	if text == "" {
+		text = doc.Content
+	}
+	doc.Keyphrases = e.Extract(doc.Title + ".\n" + text)
+	return nil
+}
+
+// Extract returns the lowercased keyphrases of text ordered by decreasing
+// score.
+func (e *Extractor) Extract(text string) []string {
+	var (
+		candidates [][]string
+		freq       = make(map[string]int)
+		degree     = make(map[string]int)
+	)
+	for _, candidate := range candidatePhrases(text) {
+		if len(candidate) > e.cfg.maxPhraseWords() {
+			continue
+		}
+		candidates = append(candidates, candidate)
+		for _, word := range candidate {
+			freq[word]++
+			degree[word] += len(candidate)
+		}
+	}
+
+	scores := make(map[string]float64)
+	var phrases []string
+	for _, candidate := range candidates {
+		phrase := strings.Join(candidate, " ")
+		if _, seen := scores[phrase]; seen {
+			continue
+		}
+		var score float64
+		for _, word := range candidate {
+			score += float64(degree[word]) / float64(freq[word])
+		}
+		scores[phrase] = score
+		phrases = append(phrases, phrase)
+	}
+
+	sort.SliceStable(phrases, func(i, j int) bool { return scores[phrases[i]] > scores[phrases[j]] })
+	if len(phrases) > e.cfg.maxPhrases() {
+		phrases = phrases[:e.cfg.maxPhrases()]
+	}
+	return phrases
+}
+
+// candidatePhrases splits text into runs of lowercased words that are
+// delimited by stop words and punctuation. Words without any letters (e.g.
+// numbers) also act as delimiters.
+func candidatePhrases(text string) [][]string {
+	var (
+		candidates [][]string
+		current    []string
+		word       []rune
+	)
+	endWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := strings.Trim(strings.ToLower(string(word)), "'-")
+		word = word[:0]
+		if _, stop := stopwords[w]; stop || strings.IndexFunc(w, unicode.IsLetter) == -1 {
+			endPhrase(&candidates, &current)
+			return
+		}
+		current = append(current, w)
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '-':
+			word = append(word, r)
+		case unicode.IsSpace(r):
+			endWord()
+		default:
+			endWord()
+			endPhrase(&candidates, &current)
+		}
+	}
+	endWord()
+	endPhrase(&candidates, &current)
+	return candidates
+}
+
+func endPhrase(candidates *[][]string, current *[]string) {
+	if len(*current) != 0 {
+		*candidates = append(*candidates, *current)
+		*current = nil
+	}
+}
+
+func toSet(words ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		set[w] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,55 @@
+package keyphrase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(KeyphraseTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type KeyphraseTestSuite struct{}
+
+func (s *KeyphraseTestSuite) TestExtract(c *gc.C) {
+	text := "Pocket gophers dig extensive tunnel systems. The tunnel systems of pocket " +
+		"gophers can damage vegetable gardens, so gardeners use humane traps in 2020."
+
+	// Candidates with more than 3 words are dropped and ties keep the order
+	// in which the phrases appear in the text
+	phrases := New(Config{MaxPhrases: 2}).Extract(text)
+	c.Assert(phrases, gc.DeepEquals, []string{
+		"damage vegetable gardens",
+		"tunnel systems",
+	})
+}
+
+func (s *KeyphraseTestSuite) TestCandidatePhrases(c *gc.C) {
+	c.Assert(candidatePhrases("The 'Go' programming-language: fast, and fun!"), gc.DeepEquals, [][]string{
+		{"go", "programming-language"},
+		{"fast"},
+		{"fun"},
+	})
+	c.Assert(candidatePhrases("and the of 42"), gc.HasLen, 0)
+}
+
+func (s *KeyphraseTestSuite) TestMaxPhraseWords(c *gc.C) {
+	phrases := New(Config{MaxPhraseWords: 2}).Extract("very long candidate keyphrase here. short phrase")
+	c.Assert(phrases, gc.DeepEquals, []string{"short phrase"})
+}
+
+func (s *KeyphraseTestSuite) TestEnrich(c *gc.C) {
+	var enricher index.Enricher = New(Config{})
+
+	doc := &index.Document{Title: "Gopher facts", Content: "Menu", MainText: "Gophers dig tunnels."}
+	c.Assert(enricher.Enrich(context.TODO(), doc), gc.IsNil)
+	c.Assert(doc.Keyphrases, gc.DeepEquals, []string{"gophers dig tunnels", "gopher facts"})
+
+	// Existing keyphrases are kept
+	doc = &index.Document{Content: "Gophers dig tunnels.", Keyphrases: []string{"rodents"}}
+	c.Assert(enricher.Enrich(context.TODO(), doc), gc.IsNil)
+	c.Assert(doc.Keyphrases, gc.DeepEquals, []string{"rodents"})
+}
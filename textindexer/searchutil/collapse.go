@@ -0,0 +1,153 @@
+// Package searchutil provides helpers for post-processing search results
+// before they are presented to users.
+package searchutil
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+)
+
+const (
+	defaultTitleSimilarity = 0.8
+	defaultMaxPerSite      = 2
+)
+
+// CollapseConfig controls which search results are collapsed by Collapse.
+type CollapseConfig struct {
+	// TitleSimilarity is the minimum Jaccard similarity between the sets of
+	// words of two titles for the results to be considered near-duplicates.
+	// If not specified, a default value of 0.8 will be used.
+	TitleSimilarity float64
+
+	// MaxPerSite is the maximum number of results from the same site that
+	// are not collapsed. If not specified, a default value of 2 will be
+	// used. A negative value disables per-site collapsing.
+	MaxPerSite int
+}
+
+func (cfg CollapseConfig) withDefaults() CollapseConfig {
+	if cfg.TitleSimilarity <= 0 || cfg.TitleSimilarity > 1 {
+		cfg.TitleSimilarity = defaultTitleSimilarity
+	}
+	if cfg.MaxPerSite == 0 {
+		cfg.MaxPerSite = defaultMaxPerSite
+	}
+	return cfg
+}
+
+// Cluster is a search result together with the lower ranked results that were
+// collapsed under it.
+type Cluster struct {
+	// Doc is the highest ranked result of the cluster.
+	Doc *index.Document
+
+	// Site is the site of Doc (see Site).
+	Site string
+
+	// Collapsed lists, in rank order, the results whose title is a
+	// near-duplicate of the title of Doc and, if Doc is the highest ranked
+	// result of its site, the results from the same site that exceeded
+	// the per-site limit.
+	Collapsed []*index.Document
+
+	titleWords map[string]struct{}
+}
+
+// CollapsedFromSite returns true if any of the collapsed results is from the
+// site of the cluster, in which case users may want to see all results from
+// that site.
+func (c Cluster) CollapsedFromSite() bool {
+	for _, doc := range c.Collapsed {
+		if Site(doc.URL) == c.Site {
+			return true
+		}
+	}
+	return false
+}
+
+// Collapse groups docs, which must be ordered by rank, into clusters so that
+// near-duplicate results and results from sites that already have enough
+// results are hidden under a higher ranked result. Clusters are returned in
+// the order of their highest ranked result.
+func Collapse(docs []*index.Document, cfg CollapseConfig) []Cluster {
+	cfg = cfg.withDefaults()
+
+	var (
+		clusters []Cluster
+		siteHits = make(map[string]int)
+		siteHead = make(map[string]int)
+	)
+	for _, doc := range docs {
+		site, titleWords := Site(doc.URL), wordSet(doc.Title)
+		if i := nearDuplicate(clusters, titleWords, cfg.TitleSimilarity); i != -1 {
+			clusters[i].Collapsed = append(clusters[i].Collapsed, doc)
+			continue
+		}
+		if head, found := siteHead[site]; found && cfg.MaxPerSite > 0 && siteHits[site] >= cfg.MaxPerSite {
+			clusters[head].Collapsed = append(clusters[head].Collapsed, doc)
+			continue
+		}
+
+		if _, found := siteHead[site]; !found && site != "" {
+			siteHead[site] = len(clusters)
+		}
+		siteHits[site]++
+		clusters = append(clusters, Cluster{Doc: doc, Site: site, titleWords: titleWords})
+	}
+	return clusters
+}
+
+// nearDuplicate returns the index of the cluster whose title is similar to
+// titleWords or -1 if there is none. Results without a title are never
+// near-duplicates.
+func nearDuplicate(clusters []Cluster, titleWords map[string]struct{}, minSimilarity float64) int {
+	if len(titleWords) == 0 {
+		return -1
+	}
+	for i, cluster := range clusters {
+		if jaccard(cluster.titleWords, titleWords) >= minSimilarity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Site returns the site of rawURL, i.e. its lowercased host without any "www."
+// prefix, or an empty string if rawURL cannot be parsed.
+func Site(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	return strings.TrimPrefix(host, "www.")
+}
+
+// wordSet returns the set of lowercased words in text.
+func wordSet(text string) map[string]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// jaccard returns the Jaccard similarity of two sets.
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	var common int
+	for word := range a {
+		if _, found := b[word]; found {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
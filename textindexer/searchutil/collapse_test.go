@@ -0,0 +1,76 @@
+package searchutil
+
+import (
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CollapseTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type CollapseTestSuite struct{}
+
+func (s *CollapseTestSuite) TestCollapseNearDuplicateTitles(c *gc.C) {
+	docs := []*index.Document{
+		{URL: "http://a.com/gophers", Title: "All about Gophers"},
+		{URL: "http://b.com/moles", Title: "Moles"},
+		{URL: "http://mirror.org/gophers", Title: "All about gophers!"},
+		{URL: "http://c.com/", Title: ""},
+		{URL: "http://d.com/", Title: ""},
+	}
+	clusters := Collapse(docs, CollapseConfig{})
+	c.Assert(clusterURLs(clusters), gc.DeepEquals, [][]string{
+		{"http://a.com/gophers", "http://mirror.org/gophers"},
+		{"http://b.com/moles"},
+		{"http://c.com/"},
+		{"http://d.com/"},
+	})
+	c.Assert(clusters[0].CollapsedFromSite(), gc.Equals, false)
+}
+
+func (s *CollapseTestSuite) TestCollapsePerSite(c *gc.C) {
+	docs := []*index.Document{
+		{URL: "http://www.a.com/1", Title: "Gopher facts"},
+		{URL: "http://b.com/1", Title: "Burrows"},
+		{URL: "http://a.com/2", Title: "Gopher diets"},
+		{URL: "http://A.com/3", Title: "Gopher predators"},
+		{URL: "http://a.com/4", Title: "Gopher habitats"},
+		{URL: "http://blog.a.com/1", Title: "Gopher news"},
+	}
+	clusters := Collapse(docs, CollapseConfig{})
+	c.Assert(clusterURLs(clusters), gc.DeepEquals, [][]string{
+		{"http://www.a.com/1", "http://A.com/3", "http://a.com/4"},
+		{"http://b.com/1"},
+		{"http://a.com/2"},
+		{"http://blog.a.com/1"},
+	})
+	c.Assert(clusters[0].Site, gc.Equals, "a.com")
+	c.Assert(clusters[0].CollapsedFromSite(), gc.Equals, true)
+
+	clusters = Collapse(docs, CollapseConfig{MaxPerSite: -1})
+	c.Assert(clusters, gc.HasLen, len(docs))
+}
+
+func (s *CollapseTestSuite) TestTitleSimilarity(c *gc.C) {
+	docs := []*index.Document{
+		{URL: "http://a.com/", Title: "Gophers - the complete guide"},
+		{URL: "http://b.com/", Title: "Gophers | The complete guide (2nd edition)"},
+	}
+	c.Assert(Collapse(docs, CollapseConfig{}), gc.HasLen, 2)
+	c.Assert(Collapse(docs, CollapseConfig{TitleSimilarity: 0.6}), gc.HasLen, 1)
+}
+
+func clusterURLs(clusters []Cluster) [][]string {
+	var urls [][]string
+	for _, cluster := range clusters {
+		group := []string{cluster.Doc.URL}
+		for _, doc := range cluster.Collapsed {
+			group = append(group, doc.URL)
+		}
+		urls = append(urls, group)
+	}
+	return urls
+}
@@ -0,0 +1,85 @@
+package fallback
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState uint8
+
+const (
+	// breakerClosed lets all requests through to the primary indexer.
+	breakerClosed breakerState = iota
+	// breakerOpen diverts all requests to the fallback indexer.
+	breakerOpen
+	// breakerHalfOpen lets a single probe request through to the primary
+	// indexer to check whether it has recovered.
+	breakerHalfOpen
+)
+
+// circuitBreaker keeps track of the health of the primary indexer. It opens
+// after a number of consecutive failures and allows a probe request once the
+// cooldown period has elapsed.
+type circuitBreaker struct {
+	mu sync.Mutex
+
+	failureThreshold int
+	cooldown         time.Duration
+	now              func() time.Time
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow returns true if a request should be sent to the primary indexer.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		// Only one probe request at a time
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// success records a successful request to the primary indexer. It returns
+// true if the request closed a previously open breaker.
+func (b *circuitBreaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recovered := b.state != breakerClosed
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+	return recovered
+}
+
+// failure records a failed request to the primary indexer.
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.failureThreshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+	b.probing = false
+}
@@ -0,0 +1,404 @@
+package fallback
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// Compile-time check for ensuring Indexer implements index.Indexer.
+var _ index.Indexer = (*Indexer)(nil)
+
+// ErrPrimaryUnavailable is returned for operations that can only be served by
+// the primary indexer while it is unavailable.
+var ErrPrimaryUnavailable = index.Unavailable(xerrors.New("primary indexer unavailable"))
+
+const (
+	defaultFailureThreshold = 5
+	defaultCooldownPeriod   = 30 * time.Second
+)
+
+// Config encapsulates the configuration options for a fallback Indexer.
+type Config struct {
+	// Primary is the indexer that normally serves all requests (e.g.
+	// Elasticsearch).
+	Primary index.Indexer
+
+	// Fallback is a local indexer (e.g. an in-memory bleve index) that
+	// buffers documents while the primary indexer is unavailable.
+	Fallback index.Indexer
+
+	// FailureThreshold is the number of consecutive transient failures
+	// after which all writes are diverted to the fallback indexer. If not
+	// specified, a default value of 5 will be used.
+	FailureThreshold int
+
+	// CooldownPeriod is the time to wait before probing whether the primary
+	// indexer has recovered. If not specified, a default value of 30s
+	// will be used.
+	CooldownPeriod time.Duration
+
+	// Clock returns the current time. If not specified, time.Now will be
+	// used.
+	Clock func() time.Time
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.Primary == nil {
+		err = xerrors.New("primary indexer not specified")
+	} else if cfg.Fallback == nil {
+		err = xerrors.New("fallback indexer not specified")
+	}
+
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.CooldownPeriod <= 0 {
+		cfg.CooldownPeriod = defaultCooldownPeriod
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return err
+}
+
+// Indexer is a composite index.Indexer that writes to a primary indexer and
+// falls back to buffering documents in a local indexer when the primary one
+// is down, so that crawl passes do not fail outright during index outages.
+// A circuit breaker stops sending requests to the primary indexer after
+// repeated transient failures; once the primary indexer recovers, the
+// buffered documents are replayed into it.
+//
+// Reads are served by the primary indexer, except for documents that are
+// still buffered and for queries issued while the breaker is open which are
+// served by the fallback indexer. Operations that update or delete existing
+// documents (UpdateScore, UpdateFields, UpdateFieldsIfVersion, Delete) and
+// reindexing require the primary indexer and fail with ErrPrimaryUnavailable
+// during an outage.
+type Indexer struct {
+	primary  index.Indexer
+	fallback index.Indexer
+	breaker  *circuitBreaker
+
+	mu sync.Mutex
+	// pending contains the IDs of the documents buffered in the fallback
+	// indexer that have not been replayed yet.
+	pending map[uuid.UUID]struct{}
+
+	// replayMu ensures that only one replay runs at a time.
+	replayMu sync.Mutex
+}
+
+// NewIndexer creates a new fallback Indexer using the provided config.
+func NewIndexer(cfg Config) (*Indexer, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("fallback indexer config validation failed: %w", err)
+	}
+
+	return &Indexer{
+		primary:  cfg.Primary,
+		fallback: cfg.Fallback,
+		breaker: &circuitBreaker{
+			failureThreshold: cfg.FailureThreshold,
+			cooldown:         cfg.CooldownPeriod,
+			now:              cfg.Clock,
+		},
+		pending: make(map[uuid.UUID]struct{}),
+	}, nil
+}
+
+// Index writes doc to the primary indexer. If the primary indexer is
+// unavailable, doc is buffered in the fallback indexer instead.
+func (i *Indexer) Index(doc *index.Document) error {
+	if i.breaker.allow() {
+		err := i.primary.Index(doc)
+		if !isOutage(err) {
+			if err == nil {
+				i.removePending(doc.LinkID)
+			}
+			i.recordSuccess()
+			return err
+		}
+		i.breaker.failure()
+	}
+
+	// Versions are tracked by the primary indexer; buffered documents are
+	// written unconditionally.
+	buffered := *doc
+	buffered.Version = 0
+	if err := i.fallback.Index(&buffered); err != nil {
+		return xerrors.Errorf("fallback index: %w", err)
+	}
+	i.addPending(doc.LinkID)
+	return nil
+}
+
+// FindByID looks up a document by its ID. Buffered documents are served by
+// the fallback indexer.
+func (i *Indexer) FindByID(linkID uuid.UUID) (*index.Document, error) {
+	if i.isPending(linkID) {
+		return i.fallback.FindByID(linkID)
+	}
+
+	var doc *index.Document
+	err := i.read(func(idx index.Indexer) (err error) {
+		doc, err = idx.FindByID(linkID)
+		return err
+	})
+	return doc, err
+}
+
+// FindByURL looks up a document by its URL. Buffered documents are served by
+// the fallback indexer.
+func (i *Indexer) FindByURL(rawURL string) (*index.Document, error) {
+	if doc, err := i.fallback.FindByURL(rawURL); err == nil && i.isPending(doc.LinkID) {
+		return doc, nil
+	}
+
+	var doc *index.Document
+	err := i.read(func(idx index.Indexer) (err error) {
+		doc, err = idx.FindByURL(rawURL)
+		return err
+	})
+	return doc, err
+}
+
+// FindByURLs looks up the documents for a batch of URLs. Buffered documents
+// are served by the fallback indexer.
+func (i *Indexer) FindByURLs(rawURLs []string) ([]*index.Document, error) {
+	var docs []*index.Document
+	err := i.read(func(idx index.Indexer) (err error) {
+		docs, err = idx.FindByURLs(rawURLs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if buffered, err := i.fallback.FindByURLs(rawURLs); err == nil {
+		for n, doc := range buffered {
+			if doc != nil && i.isPending(doc.LinkID) {
+				docs[n] = doc
+			}
+		}
+	}
+	return docs, nil
+}
+
+// Search performs a search using the primary indexer, or the fallback indexer
+// while the primary indexer is unavailable.
+func (i *Indexer) Search(q index.Query) (index.Iterator, error) {
+	var it index.Iterator
+	err := i.read(func(idx index.Indexer) (err error) {
+		it, err = idx.Search(q)
+		return err
+	})
+	return it, err
+}
+
+// SearchAll works like Search but is optimized for bulk consumers.
+func (i *Indexer) SearchAll(q index.Query) (index.Iterator, error) {
+	var it index.Iterator
+	err := i.read(func(idx index.Indexer) (err error) {
+		it, err = idx.SearchAll(q)
+		return err
+	})
+	return it, err
+}
+
+// Count returns the number of documents matching q.
+func (i *Indexer) Count(q index.Query) (uint64, error) {
+	var count uint64
+	err := i.read(func(idx index.Indexer) (err error) {
+		count, err = idx.Count(q)
+		return err
+	})
+	return count, err
+}
+
+// Suggestions returns spelling suggestions for q.
+func (i *Indexer) Suggestions(q index.Query) ([]string, error) {
+	var suggestions []string
+	err := i.read(func(idx index.Indexer) (err error) {
+		suggestions, err = idx.Suggestions(q)
+		return err
+	})
+	return suggestions, err
+}
+
+// UpdateScore updates the PageRank score of a document in the primary indexer.
+func (i *Indexer) UpdateScore(linkID uuid.UUID, score float64) error {
+	return i.write(func() error { return i.primary.UpdateScore(linkID, score) })
+}
+
+// UpdateFields applies a set of partial updates to a document in the primary
+// indexer.
+func (i *Indexer) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	return i.write(func() error { return i.primary.UpdateFields(linkID, fields) })
+}
+
+// UpdateFieldsIfVersion behaves like UpdateFields but only applies the updates
+// if the document's current version equals version.
+func (i *Indexer) UpdateFieldsIfVersion(linkID uuid.UUID, version uint64, fields map[string]interface{}) error {
+	return i.write(func() error { return i.primary.UpdateFieldsIfVersion(linkID, version, fields) })
+}
+
+// Delete removes a document from the primary indexer. A buffered copy of the
+// document is discarded so that it does not get replayed.
+func (i *Indexer) Delete(linkID uuid.UUID) error {
+	var buffered bool
+	if i.isPending(linkID) {
+		if err := i.fallback.Delete(linkID); err != nil && !xerrors.Is(err, index.ErrNotFound) {
+			return xerrors.Errorf("fallback delete: %w", err)
+		}
+		i.removePending(linkID)
+		buffered = true
+	}
+
+	err := i.write(func() error { return i.primary.Delete(linkID) })
+	if buffered && xerrors.Is(err, index.ErrNotFound) {
+		// The document was never replayed into the primary indexer
+		return nil
+	}
+	return err
+}
+
+// StorageStats returns the storage footprint of the primary indexer.
+func (i *Indexer) StorageStats() (*index.StorageStats, error) {
+	var stats *index.StorageStats
+	err := i.write(func() (err error) {
+		stats, err = i.primary.StorageStats()
+		return err
+	})
+	return stats, err
+}
+
+// BeginReindex starts a reindex of the primary indexer.
+func (i *Indexer) BeginReindex() error {
+	return i.write(i.primary.BeginReindex)
+}
+
+// CommitReindex completes a reindex of the primary indexer.
+func (i *Indexer) CommitReindex() error {
+	return i.write(i.primary.CommitReindex)
+}
+
+// Pending returns the number of buffered documents that have not been
+// replayed into the primary indexer yet.
+func (i *Indexer) Pending() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.pending)
+}
+
+// Replay copies the buffered documents from the fallback indexer into the
+// primary indexer. It is invoked automatically when the primary indexer
+// recovers but may also be called explicitly (e.g. before shutting down).
+func (i *Indexer) Replay() error {
+	i.replayMu.Lock()
+	defer i.replayMu.Unlock()
+
+	for _, linkID := range i.pendingIDs() {
+		doc, err := i.fallback.FindByID(linkID)
+		if err != nil {
+			return xerrors.Errorf("replay: %w", err)
+		}
+
+		doc.Version = 0
+		if err = i.primary.Index(doc); err != nil {
+			if isOutage(err) {
+				i.breaker.failure()
+			}
+			return xerrors.Errorf("replay: %w", err)
+		}
+		i.removePending(linkID)
+	}
+	return nil
+}
+
+// read invokes fn with the primary indexer, or with the fallback indexer if
+// the primary one is unavailable.
+func (i *Indexer) read(fn func(index.Indexer) error) error {
+	if i.breaker.allow() {
+		err := fn(i.primary)
+		if !isOutage(err) {
+			i.recordSuccess()
+			return err
+		}
+		i.breaker.failure()
+	}
+	return fn(i.fallback)
+}
+
+// write invokes fn which can only be served by the primary indexer.
+func (i *Indexer) write(fn func() error) error {
+	if !i.breaker.allow() {
+		return ErrPrimaryUnavailable
+	}
+
+	err := fn()
+	if isOutage(err) {
+		i.breaker.failure()
+		return err
+	}
+	i.recordSuccess()
+	return err
+}
+
+// recordSuccess notifies the breaker of a successful request and replays the
+// buffered documents if the primary indexer just recovered.
+func (i *Indexer) recordSuccess() {
+	if i.breaker.success() {
+		// A failed replay will be retried after the next recovery
+		_ = i.Replay()
+	}
+}
+
+func (i *Indexer) addPending(linkID uuid.UUID) {
+	i.mu.Lock()
+	i.pending[linkID] = struct{}{}
+	i.mu.Unlock()
+}
+
+func (i *Indexer) removePending(linkID uuid.UUID) {
+	i.mu.Lock()
+	delete(i.pending, linkID)
+	i.mu.Unlock()
+}
+
+func (i *Indexer) isPending(linkID uuid.UUID) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	_, found := i.pending[linkID]
+	return found
+}
+
+func (i *Indexer) pendingIDs() []uuid.UUID {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	ids := make([]uuid.UUID, 0, len(i.pending))
+	for id := range i.pending {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// isOutage returns true if err indicates that the primary indexer is
+// unavailable rather than that the request itself was invalid.
+func isOutage(err error) bool {
+	if err == nil {
+		return false
+	}
+	if index.IsRetryable(err) || xerrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return xerrors.As(err, &netErr)
+}
@@ -0,0 +1,215 @@
+package fallback
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(FallbackIndexerTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type FallbackIndexerTestSuite struct {
+	primary  *fakeIndexer
+	fallback *fakeIndexer
+	now      time.Time
+	idx      *Indexer
+}
+
+func (s *FallbackIndexerTestSuite) SetUpTest(c *gc.C) {
+	s.primary = newFakeIndexer()
+	s.fallback = newFakeIndexer()
+	s.now = time.Now()
+
+	var err error
+	s.idx, err = NewIndexer(Config{
+		Primary:          s.primary,
+		Fallback:         s.fallback,
+		FailureThreshold: 2,
+		CooldownPeriod:   time.Minute,
+		Clock:            func() time.Time { return s.now },
+	})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *FallbackIndexerTestSuite) TestWritesGoToPrimary(c *gc.C) {
+	doc := &index.Document{LinkID: uuid.New(), Title: "hello"}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+	c.Assert(s.primary.docs, gc.HasLen, 1)
+	c.Assert(s.fallback.docs, gc.HasLen, 0)
+
+	found, err := s.idx.FindByID(doc.LinkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.Title, gc.Equals, "hello")
+}
+
+func (s *FallbackIndexerTestSuite) TestBufferAndReplayDuringOutage(c *gc.C) {
+	s.primary.setDown(true)
+
+	// Writes are buffered while the primary is down
+	ids := make([]uuid.UUID, 4)
+	for i := range ids {
+		ids[i] = uuid.New()
+		c.Assert(s.idx.Index(&index.Document{LinkID: ids[i], Title: "buffered", Version: 7}), gc.IsNil)
+	}
+	c.Assert(s.idx.Pending(), gc.Equals, 4)
+	c.Assert(s.fallback.docs, gc.HasLen, 4)
+
+	// Once the breaker opens, the primary is no longer contacted
+	c.Assert(s.primary.indexCalls, gc.Equals, 2)
+
+	// Buffered documents are served by the fallback
+	found, err := s.idx.FindByID(ids[0])
+	c.Assert(err, gc.IsNil)
+	c.Assert(found.Title, gc.Equals, "buffered")
+
+	// Updates require the primary
+	err = s.idx.UpdateScore(ids[0], 0.5)
+	c.Assert(xerrors.Is(err, ErrPrimaryUnavailable), gc.Equals, true)
+	c.Assert(index.IsRetryable(err), gc.Equals, true)
+
+	// After the cooldown, a successful probe replays the buffered documents
+	s.primary.setDown(false)
+	s.now = s.now.Add(2 * time.Minute)
+	c.Assert(s.idx.Index(&index.Document{LinkID: uuid.New(), Title: "probe"}), gc.IsNil)
+	c.Assert(s.idx.Pending(), gc.Equals, 0)
+	c.Assert(s.primary.docs, gc.HasLen, 5)
+	for _, id := range ids {
+		doc := s.primary.docs[id]
+		c.Assert(doc, gc.NotNil)
+		c.Assert(doc.Title, gc.Equals, "buffered")
+	}
+}
+
+func (s *FallbackIndexerTestSuite) TestDeleteBufferedDocuments(c *gc.C) {
+	s.primary.setDown(true)
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	for _, id := range ids {
+		c.Assert(s.idx.Index(&index.Document{LinkID: id}), gc.IsNil)
+	}
+
+	// Buffered documents are discarded even if the primary is down
+	err := s.idx.Delete(ids[0])
+	c.Assert(xerrors.Is(err, ErrPrimaryUnavailable), gc.Equals, true)
+	c.Assert(s.idx.Pending(), gc.Equals, 1)
+	c.Assert(s.fallback.docs, gc.HasLen, 1)
+
+	// Buffered documents that never reached the primary are not reported
+	// as missing
+	s.primary.setDown(false)
+	s.now = s.now.Add(2 * time.Minute)
+	c.Assert(s.idx.Delete(ids[1]), gc.IsNil)
+	c.Assert(s.idx.Pending(), gc.Equals, 0)
+	c.Assert(s.fallback.docs, gc.HasLen, 0)
+	c.Assert(s.primary.docs, gc.HasLen, 0)
+}
+
+func (s *FallbackIndexerTestSuite) TestFailedProbeReopensBreaker(c *gc.C) {
+	s.primary.setDown(true)
+	for i := 0; i < 2; i++ {
+		c.Assert(s.idx.Index(&index.Document{LinkID: uuid.New()}), gc.IsNil)
+	}
+	c.Assert(s.primary.indexCalls, gc.Equals, 2)
+
+	s.now = s.now.Add(2 * time.Minute)
+	c.Assert(s.idx.Index(&index.Document{LinkID: uuid.New()}), gc.IsNil)
+	c.Assert(s.primary.indexCalls, gc.Equals, 3)
+
+	// The breaker opened again so the next write is buffered straight away
+	c.Assert(s.idx.Index(&index.Document{LinkID: uuid.New()}), gc.IsNil)
+	c.Assert(s.primary.indexCalls, gc.Equals, 3)
+	c.Assert(s.idx.Pending(), gc.Equals, 4)
+}
+
+func (s *FallbackIndexerTestSuite) TestNonTransientErrorsAreReturned(c *gc.C) {
+	err := s.idx.Index(&index.Document{})
+	c.Assert(xerrors.Is(err, index.ErrMissingLinkID), gc.Equals, true)
+	c.Assert(s.fallback.docs, gc.HasLen, 0)
+}
+
+func (s *FallbackIndexerTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewIndexer(Config{Fallback: s.fallback})
+	c.Assert(err, gc.ErrorMatches, ".*primary indexer not specified")
+
+	_, err = NewIndexer(Config{Primary: s.primary})
+	c.Assert(err, gc.ErrorMatches, ".*fallback indexer not specified")
+}
+
+// fakeIndexer is a map-backed index.Indexer that can simulate an outage.
+type fakeIndexer struct {
+	index.Indexer
+
+	mu         sync.Mutex
+	down       bool
+	indexCalls int
+	docs       map[uuid.UUID]*index.Document
+}
+
+func newFakeIndexer() *fakeIndexer {
+	return &fakeIndexer{docs: make(map[uuid.UUID]*index.Document)}
+}
+
+func (f *fakeIndexer) setDown(down bool) {
+	f.mu.Lock()
+	f.down = down
+	f.mu.Unlock()
+}
+
+func (f *fakeIndexer) Index(doc *index.Document) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.indexCalls++
+	if f.down {
+		return index.Unavailable(xerrors.New("connection refused"))
+	} else if doc.LinkID == uuid.Nil {
+		return index.ErrMissingLinkID
+	}
+	dCopy := *doc
+	f.docs[doc.LinkID] = &dCopy
+	return nil
+}
+
+func (f *fakeIndexer) FindByID(linkID uuid.UUID) (*index.Document, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down {
+		return nil, index.Unavailable(xerrors.New("connection refused"))
+	}
+	doc := f.docs[linkID]
+	if doc == nil {
+		return nil, index.ErrNotFound
+	}
+	dCopy := *doc
+	return &dCopy, nil
+}
+
+func (f *fakeIndexer) UpdateScore(uuid.UUID, float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down {
+		return index.Unavailable(xerrors.New("connection refused"))
+	}
+	return nil
+}
+
+func (f *fakeIndexer) Delete(linkID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.down {
+		return index.Unavailable(xerrors.New("connection refused"))
+	} else if f.docs[linkID] == nil {
+		return index.ErrNotFound
+	}
+	delete(f.docs, linkID)
+	return nil
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"unicode"
+
+	"github.com/blevesearch/bleve/analysis"
+	"github.com/blevesearch/bleve/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/analysis/tokenizer/whitespace"
+	"github.com/blevesearch/bleve/mapping"
+	"github.com/blevesearch/bleve/registry"
+)
+
+const (
+	//CodeAnalyzer is the name of an analyzer that makes code identifiers
+	//such as "http.Client" or "bspgraph.Executor" searchable.  Each token is
+	//indexed as-is and also split into its parts on punctuation and
+	//camelCase boundaries
+	CodeAnalyzer = "code"
+
+	codeTokenFilterName = "code_identifier"
+)
+
+func init() {
+	registry.RegisterTokenFilter(codeTokenFilterName, func(map[string]interface{}, *registry.Cache) (analysis.TokenFilter, error) {
+		return codeTokenFilter{}, nil
+	})
+}
+
+//addCodeAnalyzer registers the code analyzer with indexMapping
+func addCodeAnalyzer(indexMapping *mapping.IndexMappingImpl) error {
+	//the whitespace tokenizer keeps identifiers like "http.Client" in one
+	//piece so the token filter gets to see them
+	return indexMapping.AddCustomAnalyzer(CodeAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     whitespace.Name,
+		"token_filters": []string{codeTokenFilterName, lowercase.Name, en.StopName},
+	})
+}
+
+/*
+codeTokenFilter strips leading and trailing punctuation from each token and, for
+tokens that look like code identifiers, emits the identifier parts in addition
+to the original token.  The parts share the position of the original token so
+phrase queries keep working
+*/
+type codeTokenFilter struct{}
+
+func (codeTokenFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	output := make(analysis.TokenStream, 0, len(input))
+	for _, tok := range input {
+		term := trimPunct([]rune(string(tok.Term)))
+		if len(term) == 0 {
+			continue
+		}
+
+		output = append(output, &analysis.Token{
+			Term:     []byte(string(term)),
+			Start:    tok.Start,
+			End:      tok.End,
+			Position: tok.Position,
+			Type:     tok.Type,
+		})
+
+		if parts := splitIdentifier(term); len(parts) > 1 {
+			for _, part := range parts {
+				output = append(output, &analysis.Token{
+					Term:     []byte(part),
+					Start:    tok.Start,
+					End:      tok.End,
+					Position: tok.Position,
+					Type:     tok.Type,
+				})
+			}
+		}
+	}
+	return output
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+//trimPunct removes any non-letter and non-digit runes from both ends of term
+func trimPunct(term []rune) []rune {
+	start, end := 0, len(term)
+	for start < end && !isWordRune(term[start]) {
+		start++
+	}
+	for end > start && !isWordRune(term[end-1]) {
+		end--
+	}
+	return term[start:end]
+}
+
+/*
+splitIdentifier splits an identifier on punctuation (e.g. "http.Client" or
+"snake_case") and on camelCase boundaries (e.g. "NewHTTPServer" becomes "New",
+"HTTP" and "Server")
+*/
+func splitIdentifier(term []rune) []string {
+	var (
+		parts []string
+		start = -1
+	)
+	for i, r := range term {
+		if !isWordRune(r) {
+			if start != -1 {
+				parts = append(parts, string(term[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start == -1 {
+			start = i
+			continue
+		}
+
+		//a new part begins at a lower-to-upper transition ("inMemory") or
+		//at the last upper-case rune of an acronym ("HTTPServer")
+		prev := term[i-1]
+		acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(term) && unicode.IsLower(term[i+1])
+		if (unicode.IsLower(prev) && unicode.IsUpper(r)) || acronymEnd {
+			parts = append(parts, string(term[start:i]))
+			start = i
+		}
+	}
+	if start != -1 {
+		parts = append(parts, string(term[start:]))
+	}
+	return parts
+}
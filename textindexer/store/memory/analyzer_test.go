@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(CodeAnalyzerTestSuite))
+
+type CodeAnalyzerTestSuite struct{}
+
+func (s *CodeAnalyzerTestSuite) TestSplitIdentifier(c *gc.C) {
+	specs := []struct {
+		in  string
+		exp []string
+	}{
+		{in: "gopher", exp: []string{"gopher"}},
+		{in: "http.Client", exp: []string{"http", "Client"}},
+		{in: "bspgraph.Executor", exp: []string{"bspgraph", "Executor"}},
+		{in: "NewInMemoryGraph", exp: []string{"New", "In", "Memory", "Graph"}},
+		{in: "NewHTTPServer", exp: []string{"New", "HTTP", "Server"}},
+		{in: "snake_case", exp: []string{"snake", "case"}},
+	}
+
+	for specIndex, spec := range specs {
+		c.Logf("[spec %d] %s", specIndex, spec.in)
+		c.Assert(splitIdentifier([]rune(spec.in)), gc.DeepEquals, spec.exp)
+	}
+}
+
+func (s *CodeAnalyzerTestSuite) TestSearchCodeIdentifiers(c *gc.C) {
+	idx, err := NewInMemoryBleveIndexerWithConfig(Config{Analyzer: CodeAnalyzer})
+	c.Assert(err, gc.IsNil)
+	defer func() { c.Assert(idx.Close(), gc.IsNil) }()
+
+	doc := &index.Document{
+		LinkID:  uuid.New(),
+		Content: "Use bspgraph.Executor (or NewInMemoryGraph), then call Run.",
+	}
+	other := &index.Document{
+		LinkID:  uuid.New(),
+		Content: "An article about gophers",
+	}
+	c.Assert(idx.Index(doc), gc.IsNil)
+	c.Assert(idx.Index(other), gc.IsNil)
+
+	for _, expr := range []string{"bspgraph.Executor", "executor", "memory", "NewInMemoryGraph"} {
+		count, err := idx.Count(index.Query{Type: index.QueryTypeMatch, Expression: expr})
+		c.Assert(err, gc.IsNil)
+		c.Assert(count, gc.Equals, uint64(1), gc.Commentf("expression %q", expr))
+	}
+}
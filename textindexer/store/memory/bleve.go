@@ -1,11 +1,17 @@
 package memory
 
 import (
+	"net/url"
+	"strings"
 	"sync"
 	"time"
+	"unsafe"
 
 	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/mapping"
 	"github.com/blevesearch/bleve/search/query"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
 	"github.com/brandonshearin/ask_brandon/textindexer/index"
 	"github.com/google/uuid"
 	"golang.org/x/xerrors"
@@ -20,23 +26,113 @@ type InMemoryBleveIndexer struct {
 	//mu ensures that the in-memory indexer is safe for concurrent use
 	mu   sync.RWMutex
 	docs map[string]*index.Document
+	//urls maps the normalized URLs of the indexed documents to their keys
+	urls map[string]string
 	//idx stores a reference to the bleve index
 	idx bleve.Index
+	//reindex is non-nil while a replacement bleve index is being built
+	reindex *pendingReindex
+	//cfg is retained so that replacement indices use the same mapping
+	cfg Config
+}
+
+//Config encapsulates the configuration options for creating a new in-memory bleve indexer
+type Config struct {
+	//Analyzer, if specified, is the name of the bleve analyzer used for the
+	//text fields of indexed documents and for search expressions.  Besides
+	//the analyzers built into bleve, CodeAnalyzer can be used to make code
+	//identifiers searchable.  If not specified, bleve's standard analyzer
+	//will be used.
+	Analyzer string
+
+	//RankingWeights controls how the static ranking signals of documents
+	//are combined for ordering search results; ties are broken by the
+	//relevance score computed by bleve.  If not specified, results are
+	//ordered by PageRank.
+	RankingWeights RankingWeights
+}
+
+//RankingWeights specifies the weight of each static ranking signal
+type RankingWeights struct {
+	PageRank        float64
+	ClickScore      float64
+	DomainAuthority float64
+}
+
+func (cfg Config) rankingWeights() RankingWeights {
+	if cfg.RankingWeights == (RankingWeights{}) {
+		return RankingWeights{PageRank: 1}
+	}
+	return cfg.RankingWeights
 }
 
 /*
 bleveDoc is the object bleve indexes for us for full-text searching
 */
 type bleveDoc struct {
-	Title    string
-	Content  string
-	PageRank float64
+	Title       string
+	Content     string
+	Description string
+	Keyphrases  []string
+	Language    string
+	AnchorText  []string
+	PageRank    float64
+
+	//Rank combines the static ranking signals of the document (see
+	//RankingWeights) and is used for ordering search results
+	Rank float64
+
+	//EntityTypes lists the schema.org types of the entities embedded in
+	//the document while PublishedAt holds the earliest publication date
+	//among them (nil if unknown)
+	EntityTypes []string
+	PublishedAt *time.Time
+
+	//FetchedAt is nil if the document was not populated by the crawler
+	FetchedAt *time.Time
+
+	//Sites lists the host of the document URL and all of its parent
+	//domains so that site filters also match subdomains
+	Sites []string
+
+	//CrawlPassID is the ID of the crawl pass that indexed the document
+	CrawlPassID float64
+
+	//Filtered is set if a content classifier flagged the document
+	Filtered bool
+
+	//Passage is set for documents that hold a passage of a page
+	Passage bool
 }
 
+const (
+	//searchBatchSize is the number of results fetched per page for
+	//interactive searches while scrollBatchSize is used by SearchAll
+	searchBatchSize = 10
+	scrollBatchSize = 1000
+)
+
+//descriptionBoost and keyphraseBoost are applied to matches against the page
+//description and keyphrases so they rank above matches that only appear in the
+//page content
+const (
+	descriptionBoost = 1.5
+	keyphraseBoost   = 2.0
+)
+
 //NewInMemoryBleveIndexer creates a text indexer that uses an in-memory bleve instance for indexing docs
 func NewInMemoryBleveIndexer() (*InMemoryBleveIndexer, error) {
-	mapping := bleve.NewIndexMapping()
-	idx, err := bleve.NewMemOnly(mapping)
+	return NewInMemoryBleveIndexerWithConfig(Config{})
+}
+
+//NewInMemoryBleveIndexerWithConfig creates an in-memory bleve text indexer using the options in cfg
+func NewInMemoryBleveIndexerWithConfig(cfg Config) (*InMemoryBleveIndexer, error) {
+	indexMapping, err := newIndexMapping(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := bleve.NewMemOnly(indexMapping)
 	if err != nil {
 		return nil, err
 	}
@@ -44,11 +140,35 @@ func NewInMemoryBleveIndexer() (*InMemoryBleveIndexer, error) {
 	return &InMemoryBleveIndexer{
 		idx:  idx,
 		docs: make(map[string]*index.Document),
+		urls: make(map[string]string),
+		cfg:  cfg,
 	}, nil
 }
 
+//newIndexMapping returns the mapping used for the bleve index
+func newIndexMapping(cfg Config) (*mapping.IndexMappingImpl, error) {
+	indexMapping := bleve.NewIndexMapping()
+
+	if cfg.Analyzer == CodeAnalyzer {
+		if err := addCodeAnalyzer(indexMapping); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Analyzer != "" {
+		indexMapping.DefaultAnalyzer = cfg.Analyzer
+	}
+
+	//keyword fields are matched as-is, so they should not go through the text analyzer
+	indexMapping.DefaultMapping.AddFieldMappingsAt("Language", keywordFieldMapping())
+	indexMapping.DefaultMapping.AddFieldMappingsAt("EntityTypes", keywordFieldMapping())
+	indexMapping.DefaultMapping.AddFieldMappingsAt("Sites", keywordFieldMapping())
+	return indexMapping, nil
+}
+
 // Close the indexer and release any allocated resources.
 func (i *InMemoryBleveIndexer) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	return i.idx.Close()
 }
 
@@ -64,16 +184,37 @@ func (i *InMemoryBleveIndexer) Index(doc *index.Document) error {
 	key := dcopy.LinkID.String()
 	//acquire write lock when making changes to data structure
 	i.mu.Lock()
-	/*if doc has already been indexed, copy over its PageRank value*/
-	if orig, exists := i.docs[key]; exists {
+	defer i.mu.Unlock()
+
+	var curVersion uint64
+	/*if doc has already been indexed, copy over its PageRank value and
+	any other enrichments that are not populated by the crawler*/
+	orig, exists := i.docs[key]
+	if exists {
 		dcopy.PageRank = orig.PageRank
+		dcopy.ClickScore = orig.ClickScore
+		dcopy.Betweenness = orig.Betweenness
+		dcopy.Closeness = orig.Closeness
+		dcopy.CommunityID = orig.CommunityID
+		dcopy.DomainAuthority = orig.DomainAuthority
+		dcopy.InDegree = orig.InDegree
+		dcopy.AnchorText = orig.AnchorText
+		curVersion = orig.Version
+	}
+	if doc.Version != 0 && doc.Version != curVersion {
+		return xerrors.Errorf("index: %w", index.ErrVersionConflict)
 	}
+	dcopy.Version = curVersion + 1
 
-	if err := i.idx.Index(key, makeBleveDoc(dcopy)); err != nil {
+	if err := i.indexDoc(key, dcopy); err != nil {
 		return xerrors.Errorf("index: %w", err)
 	}
+	if exists {
+		i.unindexURL(key, orig)
+	}
+	i.indexURL(key, dcopy)
 	i.docs[key] = dcopy
-	i.mu.Unlock()
+	doc.Version = dcopy.Version
 	return nil
 }
 
@@ -87,28 +228,126 @@ func (i *InMemoryBleveIndexer) FindByID(linkID uuid.UUID) (*index.Document, erro
 	return i.findByID(linkID.String())
 }
 
+//FindByURL looks up the document whose normalized URL matches rawURL
+func (i *InMemoryBleveIndexer) FindByURL(rawURL string) (*index.Document, error) {
+	normURL, err := graph.NormalizeURL(rawURL)
+	if err != nil {
+		return nil, xerrors.Errorf("find by URL: %w", index.InvalidArgument(err))
+	}
+
+	i.mu.RLock()
+	key, found := i.urls[normURL]
+	i.mu.RUnlock()
+	if !found {
+		return nil, xerrors.Errorf("find by URL: %w", index.ErrNotFound)
+	}
+	return i.findByID(key)
+}
+
+//FindByURLs looks up the documents whose normalized URLs match rawURLs
+func (i *InMemoryBleveIndexer) FindByURLs(rawURLs []string) ([]*index.Document, error) {
+	normURLs := make([]string, len(rawURLs))
+	for n, rawURL := range rawURLs {
+		normURL, err := graph.NormalizeURL(rawURL)
+		if err != nil {
+			return nil, xerrors.Errorf("find by URLs: %w", index.InvalidArgument(err))
+		}
+		normURLs[n] = normURL
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	docs := make([]*index.Document, len(normURLs))
+	for n, normURL := range normURLs {
+		if key, found := i.urls[normURL]; found {
+			if doc, found := i.docs[key]; found {
+				docs[n] = copyDoc(doc)
+			}
+		}
+	}
+	return docs, nil
+}
+
 //Search is called by clients of the text indexer to submit queries
 func (i *InMemoryBleveIndexer) Search(q index.Query) (index.Iterator, error) {
-	//Determine what type of query the caller asked us to perform,
-	//invoking the appropriate bleve helper
-	var bq query.Query
+	return i.search(q, searchBatchSize)
+}
+
+//SearchAll streams through all documents matching q, fetching them in large batches
+func (i *InMemoryBleveIndexer) SearchAll(q index.Query) (index.Iterator, error) {
+	return i.search(q, scrollBatchSize)
+}
+
+func (i *InMemoryBleveIndexer) search(q index.Query, batchSize int) (index.Iterator, error) {
 	switch q.Type {
-	case index.QueryTypePhrase:
-		bq = bleve.NewMatchPhraseQuery(q.Expression)
-	case index.QueryTypeMatch:
-		bq = bleve.NewMatchQuery(q.Expression)
+	case index.QueryTypeSemantic:
+		return i.semanticSearch(q)
+	case index.QueryTypeHybrid:
+		return i.hybridSearch(q)
 	}
 
-	searchReq := bleve.NewSearchRequest(bq)
-	searchReq.SortBy([]string{"-PageRank", "-_score"})
-	searchReq.Size = 10
+	searchReq := bleve.NewSearchRequest(bleveQuery(q))
+	searchReq.SortBy([]string{"-Rank", "-_score"})
+	searchReq.Size = batchSize
 	searchReq.From = q.Offset
-	rs, err := i.idx.Search(searchReq)
+	bleveIdx := i.bleveIndex()
+	rs, err := bleveIdx.Search(searchReq)
 	if err != nil {
 		return nil, xerrors.Errorf("search: %w", err)
 	}
 	//if the search returns a result, present an iterator to the caller for them to consume the matched documents
-	return &bleveIterator{idx: i, searchReq: searchReq, rs: rs, cumIdx: uint64(q.Offset)}, nil
+	return &bleveIterator{idx: i, bleveIdx: bleveIdx, searchReq: searchReq, rs: rs, cumIdx: uint64(q.Offset)}, nil
+}
+
+/*
+Count returns the number of documents matching q.  It performs a search that
+requests no hits so bleve only has to compute the total.
+*/
+func (i *InMemoryBleveIndexer) Count(q index.Query) (uint64, error) {
+	switch q.Type {
+	case index.QueryTypeSemantic, index.QueryTypeHybrid:
+		it, err := i.search(q, searchBatchSize)
+		if err != nil {
+			return 0, xerrors.Errorf("count: %w", err)
+		}
+		_ = it.Close()
+		return it.TotalCount(), nil
+	}
+
+	searchReq := bleve.NewSearchRequest(bleveQuery(q))
+	searchReq.Size = 0
+	rs, err := i.bleveIndex().Search(searchReq)
+	if err != nil {
+		return 0, xerrors.Errorf("count: %w", err)
+	}
+	return rs.Total, nil
+}
+
+//DocumentCount returns the number of indexed documents
+func (i *InMemoryBleveIndexer) DocumentCount() (uint64, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return uint64(len(i.docs)), nil
+}
+
+/*
+StorageStats reports the number of stored documents and an estimate of the memory
+they use.  The bleve index is held in memory too; each bleve index (including a
+replacement index that is being built) counts as a segment.
+*/
+func (i *InMemoryBleveIndexer) StorageStats() (*index.StorageStats, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	stats := &index.StorageStats{Documents: uint64(len(i.docs)), Segments: 1}
+	for _, doc := range i.docs {
+		stats.MemoryBytes += docSize(doc)
+	}
+	if i.reindex != nil {
+		stats.Segments++
+	}
+	return stats, nil
 }
 
 /*
@@ -119,21 +358,288 @@ func (i *InMemoryBleveIndexer) UpdateScore(linkID uuid.UUID, score float64) erro
 	defer i.mu.Unlock()
 
 	key := linkID.String()
-	if doc, found := i.docs[key]; found {
+	if orig, found := i.docs[key]; found {
 		//any updates to a searchable attribute requires a reindex operation.
-		//PageRank of document is updated in-place since we have acquired a write lock
+		//The score is applied to a copy so the stored document is only
+		//replaced once the bleve index has been successfully updated
+		doc := copyDoc(orig)
 		doc.PageRank = score
-		if err := i.idx.Index(key, makeBleveDoc(doc)); err != nil {
+		doc.Version++
+		if err := i.indexDoc(key, doc); err != nil {
 			return xerrors.Errorf("update score: %w", err)
 		}
+		i.docs[key] = doc
 	} else {
 		//if document not found, don't index it but still store it
-		doc := &index.Document{LinkID: linkID, PageRank: score}
+		doc := &index.Document{LinkID: linkID, PageRank: score, Version: 1}
 		i.docs[key] = doc
 	}
 	return nil
 }
 
+/*
+UpdateFields applies a set of partial updates to the document with linkID.  Like
+UpdateScore, updates to documents that have not been indexed yet are stored but
+not indexed.
+*/
+func (i *InMemoryBleveIndexer) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.updateFields(linkID, fields)
+}
+
+/*
+UpdateFieldsIfVersion applies a set of partial updates to the document with linkID
+provided that its version has not changed since it was read by the caller.
+*/
+func (i *InMemoryBleveIndexer) UpdateFieldsIfVersion(linkID uuid.UUID, version uint64, fields map[string]interface{}) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var curVersion uint64
+	if doc, found := i.docs[linkID.String()]; found {
+		curVersion = doc.Version
+	}
+	if curVersion != version {
+		return xerrors.Errorf("update fields: %w", index.ErrVersionConflict)
+	}
+	return i.updateFields(linkID, fields)
+}
+
+//updateFields implements UpdateFields; callers must hold the write lock
+func (i *InMemoryBleveIndexer) updateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	key := linkID.String()
+	orig, found := i.docs[key]
+	if !found {
+		orig = &index.Document{LinkID: linkID}
+	}
+
+	//apply the updates to a copy so the stored document is only replaced
+	//once the bleve index has been successfully updated
+	updated := copyDoc(orig)
+	if err := index.ApplyFields(updated, fields); err != nil {
+		return xerrors.Errorf("update fields: %w", err)
+	}
+	updated.Version++
+
+	if found {
+		if err := i.indexDoc(key, updated); err != nil {
+			return xerrors.Errorf("update fields: %w", err)
+		}
+	}
+	i.docs[key] = updated
+	return nil
+}
+
+/*
+Delete removes the document with linkID from the index.  Documents that were
+only created by score or field updates are removed as well.
+*/
+func (i *InMemoryBleveIndexer) Delete(linkID uuid.UUID) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	key := linkID.String()
+	doc, found := i.docs[key]
+	if !found {
+		return xerrors.Errorf("delete: %w", index.ErrNotFound)
+	}
+
+	if err := i.idx.Delete(key); err != nil {
+		return xerrors.Errorf("delete: %w", err)
+	}
+	if i.reindex != nil {
+		if err := i.reindex.idx.Delete(key); err != nil {
+			return xerrors.Errorf("delete: %w", err)
+		}
+	}
+	i.unindexURL(key, doc)
+	delete(i.docs, key)
+	return nil
+}
+
+/*
+bleveQuery builds the bleve query for q by AND-ing its text query with any filters
+and excluding the documents that match any of its exclusions
+*/
+func bleveQuery(q index.Query) query.Query {
+	//Determine what type of query the caller asked us to perform,
+	//invoking the appropriate bleve helper
+	bq := textQuery(q)
+
+	//apply any filters by AND-ing them with the text query
+	if filters := queryFilters(q); len(filters) != 0 {
+		bq = bleve.NewConjunctionQuery(append([]query.Query{bq}, filters...)...)
+	}
+	if exclusions := queryExclusions(q); len(exclusions) != 0 {
+		boolQ := bleve.NewBooleanQuery()
+		boolQ.AddMust(bq)
+		boolQ.AddMustNot(exclusions...)
+		bq = boolQ
+	}
+	return bq
+}
+
+/*
+textQuery builds a bleve query for the expression in q.  Description and
+keyphrase matches are OR-ed in with a boost so they contribute more to the
+score of a document.  Semantic queries are not matched by bleve so all
+documents are returned
+*/
+func textQuery(q index.Query) query.Query {
+	switch q.Type {
+	case index.QueryTypeSemantic:
+		return bleve.NewMatchAllQuery()
+	case index.QueryTypePhrase:
+		descQ := bleve.NewMatchPhraseQuery(q.Expression)
+		descQ.SetField("Description")
+		descQ.SetBoost(descriptionBoost)
+		keyphraseQ := bleve.NewMatchPhraseQuery(q.Expression)
+		keyphraseQ.SetField("Keyphrases")
+		keyphraseQ.SetBoost(keyphraseBoost)
+		return bleve.NewDisjunctionQuery(bleve.NewMatchPhraseQuery(q.Expression), descQ, keyphraseQ)
+	default:
+		descQ := bleve.NewMatchQuery(q.Expression)
+		descQ.SetField("Description")
+		descQ.SetBoost(descriptionBoost)
+		keyphraseQ := bleve.NewMatchQuery(q.Expression)
+		keyphraseQ.SetField("Keyphrases")
+		keyphraseQ.SetBoost(keyphraseBoost)
+		return bleve.NewDisjunctionQuery(bleve.NewMatchQuery(q.Expression), descQ, keyphraseQ)
+	}
+}
+
+//queryFilters returns the list of non-scoring restrictions specified by q
+func queryFilters(q index.Query) []query.Query {
+	var filters []query.Query
+
+	//restrict results to a particular language with an exact match on
+	//the Language field
+	if q.Language != "" {
+		lq := bleve.NewTermQuery(strings.ToLower(q.Language))
+		lq.SetField("Language")
+		filters = append(filters, lq)
+	}
+
+	if q.EntityType != "" {
+		eq := bleve.NewTermQuery(strings.ToLower(q.EntityType))
+		eq.SetField("EntityTypes")
+		filters = append(filters, eq)
+	}
+
+	if !q.PublishedAfter.IsZero() {
+		exclusive := false
+		dq := bleve.NewDateRangeInclusiveQuery(q.PublishedAfter, time.Time{}, &exclusive, nil)
+		dq.SetField("PublishedAt")
+		filters = append(filters, dq)
+	}
+
+	if !q.FetchedAfter.IsZero() {
+		exclusive := false
+		fq := bleve.NewDateRangeInclusiveQuery(q.FetchedAfter, time.Time{}, &exclusive, nil)
+		fq.SetField("FetchedAt")
+		filters = append(filters, fq)
+	}
+
+	if site := normalizeHost(q.Site); site != "" {
+		sq := bleve.NewTermQuery(site)
+		sq.SetField("Sites")
+		filters = append(filters, sq)
+	}
+
+	if q.CrawlPassID != 0 {
+		pass, inclusive := float64(q.CrawlPassID), true
+		pq := bleve.NewNumericRangeInclusiveQuery(&pass, &pass, &inclusive, &inclusive)
+		pq.SetField("CrawlPassID")
+		filters = append(filters, pq)
+	}
+
+	if q.Passages {
+		pq := bleve.NewBoolFieldQuery(true)
+		pq.SetField("Passage")
+		filters = append(filters, pq)
+	}
+
+	//documents must be hosted on any of the included domains
+	if sites := siteQueries(q.IncludeDomains); len(sites) != 0 {
+		filters = append(filters, bleve.NewDisjunctionQuery(sites...))
+	}
+
+	return filters
+}
+
+//queryExclusions returns the list of queries matching documents that q omits
+func queryExclusions(q index.Query) []query.Query {
+	exclusions := siteQueries(q.ExcludeDomains)
+	if q.ExcludeFiltered {
+		fq := bleve.NewBoolFieldQuery(true)
+		fq.SetField("Filtered")
+		exclusions = append(exclusions, fq)
+	}
+	//passages are only returned when explicitly requested
+	if !q.Passages {
+		pq := bleve.NewBoolFieldQuery(true)
+		pq.SetField("Passage")
+		exclusions = append(exclusions, pq)
+	}
+	return exclusions
+}
+
+//siteQueries returns a query for each domain that matches the documents hosted
+//on the domain or any of its subdomains
+func siteQueries(domains []string) []query.Query {
+	var queries []query.Query
+	for _, domain := range domains {
+		if site := normalizeHost(domain); site != "" {
+			sq := bleve.NewTermQuery(site)
+			sq.SetField("Sites")
+			queries = append(queries, sq)
+		}
+	}
+	return queries
+}
+
+/*
+indexDoc (re)indexes doc in the bleve index as well as in the replacement index
+if a reindex is in progress.  Callers must hold the write lock
+*/
+func (i *InMemoryBleveIndexer) indexDoc(key string, doc *index.Document) error {
+	bdoc := makeBleveDoc(doc, i.cfg.rankingWeights())
+	if err := i.idx.Index(key, bdoc); err != nil {
+		return err
+	}
+	if i.reindex != nil {
+		return i.reindex.idx.Index(key, bdoc)
+	}
+	return nil
+}
+
+//indexURL maps the normalized URL of doc to key unless doc is a passage.
+//Callers must hold the write lock
+func (i *InMemoryBleveIndexer) indexURL(key string, doc *index.Document) {
+	if doc.ParentLinkID != uuid.Nil {
+		return
+	}
+	if normURL, err := graph.NormalizeURL(doc.URL); err == nil {
+		i.urls[normURL] = key
+	}
+}
+
+//unindexURL removes the mapping of the normalized URL of doc if it still
+//points to key.  Callers must hold the write lock
+func (i *InMemoryBleveIndexer) unindexURL(key string, doc *index.Document) {
+	if normURL, err := graph.NormalizeURL(doc.URL); err == nil && i.urls[normURL] == key {
+		delete(i.urls, normURL)
+	}
+}
+
+//bleveIndex returns the bleve index that is currently used for searching
+func (i *InMemoryBleveIndexer) bleveIndex() bleve.Index {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.idx
+}
+
 func (i *InMemoryBleveIndexer) findByID(linkID string) (*index.Document, error) {
 	i.mu.RLock()
 	defer i.mu.RUnlock()
@@ -146,17 +652,106 @@ func (i *InMemoryBleveIndexer) findByID(linkID string) (*index.Document, error)
 func copyDoc(d *index.Document) *index.Document {
 	dCopy := new(index.Document)
 	*dCopy = *d
+	dCopy.Keywords = append([]string(nil), d.Keywords...)
+	dCopy.Keyphrases = append([]string(nil), d.Keyphrases...)
+	dCopy.Embedding = append([]float32(nil), d.Embedding...)
+	dCopy.Entities = append([]index.Entity(nil), d.Entities...)
+	dCopy.AnchorText = append([]string(nil), d.AnchorText...)
 	return dCopy
 }
 
+//docSize estimates the number of bytes used by doc, including the contents of its strings and slices
+func docSize(d *index.Document) uint64 {
+	size := int(unsafe.Sizeof(*d)) + len(d.URL) + len(d.Title) + len(d.Content) + len(d.RawText) + len(d.MainText) +
+		len(d.Language) + len(d.Description) + len(d.OGTitle) + len(d.OGDescription) + len(d.OGImage) +
+		len(d.Summary) + len(d.ContentHash) + len(d.CommunityID) + len(d.FilterReason) +
+		len(d.Embedding)*int(unsafe.Sizeof(float32(0)))
+	for _, keyword := range d.Keywords {
+		size += int(unsafe.Sizeof(keyword)) + len(keyword)
+	}
+	for _, phrase := range d.Keyphrases {
+		size += int(unsafe.Sizeof(phrase)) + len(phrase)
+	}
+	for _, anchor := range d.AnchorText {
+		size += int(unsafe.Sizeof(anchor)) + len(anchor)
+	}
+	for _, entity := range d.Entities {
+		size += int(unsafe.Sizeof(entity)) + len(entity.Type) + len(entity.Name) + len(entity.Author)
+	}
+	return uint64(size)
+}
+
+func keywordFieldMapping() *mapping.FieldMapping {
+	fm := bleve.NewTextFieldMapping()
+	fm.Analyzer = keyword.Name
+	return fm
+}
+
 /*
 makeBleveDoc helper returns a partial, light weight view of the original document
 that contains only the fields we want to use as part of our search queries
 */
-func makeBleveDoc(d *index.Document) bleveDoc {
+func makeBleveDoc(d *index.Document, weights RankingWeights) bleveDoc {
+	var (
+		entityTypes []string
+		publishedAt *time.Time
+		fetchedAt   *time.Time
+	)
+	for _, entity := range d.Entities {
+		entityTypes = append(entityTypes, strings.ToLower(entity.Type))
+		if date := entity.DatePublished; !date.IsZero() && (publishedAt == nil || date.Before(*publishedAt)) {
+			publishedAt = &date
+		}
+	}
+
+	if !d.FetchedAt.IsZero() {
+		ts := d.FetchedAt
+		fetchedAt = &ts
+	}
+
 	return bleveDoc{
-		Title:    d.Title,
-		Content:  d.Content,
-		PageRank: d.PageRank,
+		Title:       d.Title,
+		Content:     d.Content,
+		Description: d.Description,
+		Keyphrases:  d.Keyphrases,
+		Language:    strings.ToLower(d.Language),
+		AnchorText:  d.AnchorText,
+		PageRank:    d.PageRank,
+		Rank:        weights.PageRank*d.PageRank + weights.ClickScore*d.ClickScore + weights.DomainAuthority*d.DomainAuthority,
+		EntityTypes: entityTypes,
+		PublishedAt: publishedAt,
+		FetchedAt:   fetchedAt,
+		Sites:       siteDomains(d.URL),
+		CrawlPassID: float64(d.CrawlPassID),
+		Filtered:    d.Filtered,
+		Passage:     d.ParentLinkID != uuid.Nil,
+	}
+}
+
+//siteDomains returns the host of rawURL followed by each of its parent domains,
+//e.g. "blog.example.com" and "example.com".  Top-level domains are omitted
+func siteDomains(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
 	}
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return nil
+	}
+
+	domains := []string{host}
+	for {
+		dot := strings.IndexByte(host, '.')
+		if dot == -1 || strings.IndexByte(host[dot+1:], '.') == -1 {
+			return domains
+		}
+		host = host[dot+1:]
+		domains = append(domains, host)
+	}
+}
+
+//normalizeHost lowercases host and strips any trailing dot
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
 }
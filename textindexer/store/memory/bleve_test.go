@@ -3,7 +3,10 @@ package memory
 import (
 	"testing"
 
+	"github.com/blevesearch/bleve"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
 	"github.com/brandonshearin/ask_brandon/textindexer/index/indextest"
+	"github.com/google/uuid"
 	gc "gopkg.in/check.v1"
 )
 
@@ -26,3 +29,60 @@ func (s *InMemoryBleveTestSuite) SetUpTest(c *gc.C) {
 func (s *InMemoryBleveTestSuite) TearDownTest(c *gc.C) {
 	c.Assert(s.idx.Close(), gc.IsNil)
 }
+
+//TestDeleteWhileIterating verifies that documents deleted (e.g. by the expirer)
+//while a result set is being consumed are skipped instead of aborting the
+//iteration
+func (s *InMemoryBleveTestSuite) TestDeleteWhileIterating(c *gc.C) {
+	numDocs := 2 * searchBatchSize
+	ids := make([]uuid.UUID, numDocs)
+	for i := range ids {
+		ids[i] = uuid.New()
+		c.Assert(s.idx.Index(&index.Document{LinkID: ids[i], Content: "expiring gophers"}), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(ids[i], float64(numDocs-i)), gc.IsNil)
+	}
+
+	it, err := s.idx.Search(index.Query{Type: index.QueryTypeMatch, Expression: "gophers"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.Next(), gc.Equals, true)
+	c.Assert(it.Document().LinkID, gc.Equals, ids[0])
+
+	//delete a document from the current page and all documents of the next
+	//page so the next batch comes back empty
+	for _, id := range append([]uuid.UUID{ids[1]}, ids[searchBatchSize:]...) {
+		c.Assert(s.idx.Delete(id), gc.IsNil)
+	}
+
+	var seen []uuid.UUID
+	for it.Next() {
+		seen = append(seen, it.Document().LinkID)
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(it.Close(), gc.IsNil)
+	c.Assert(seen, gc.DeepEquals, ids[2:searchBatchSize])
+}
+
+//TestUpdateScoreIsAtomic verifies that the stored document is left untouched
+//if the updated document cannot be indexed
+func (s *InMemoryBleveTestSuite) TestUpdateScoreIsAtomic(c *gc.C) {
+	id := uuid.New()
+	c.Assert(s.idx.Index(&index.Document{LinkID: id, Content: "gophers"}), gc.IsNil)
+	c.Assert(s.idx.UpdateScore(id, 0.5), gc.IsNil)
+	before, err := s.idx.FindByID(id)
+	c.Assert(err, gc.IsNil)
+
+	//swap in a closed bleve index so that indexing fails
+	closedIdx, err := bleve.NewMemOnly(bleve.NewIndexMapping())
+	c.Assert(err, gc.IsNil)
+	c.Assert(closedIdx.Close(), gc.IsNil)
+	origIdx := s.idx.idx
+	s.idx.idx = closedIdx
+	err = s.idx.UpdateScore(id, 0.9)
+	s.idx.idx = origIdx
+	c.Assert(err, gc.NotNil)
+
+	after, err := s.idx.FindByID(id)
+	c.Assert(err, gc.IsNil)
+	c.Assert(after.PageRank, gc.Equals, before.PageRank)
+	c.Assert(after.Version, gc.Equals, before.Version)
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+)
+
+//Compile-time check for ensuring InMemoryContentStore implements index.ContentStore
+var _ index.ContentStore = (*InMemoryContentStore)(nil)
+
+//InMemoryContentStore keeps the document snapshots archived by index.WithHistory
+//in memory.  It is meant for tests and single-node deployments
+type InMemoryContentStore struct {
+	mu       sync.RWMutex
+	versions map[uuid.UUID][]index.DocumentVersion
+}
+
+//NewInMemoryContentStore creates a new in-memory content store
+func NewInMemoryContentStore() *InMemoryContentStore {
+	return &InMemoryContentStore{versions: make(map[uuid.UUID][]index.DocumentVersion)}
+}
+
+//PutVersion stores a document snapshot.  A snapshot of a document version that
+//has already been stored replaces the existing one
+func (s *InMemoryContentStore) PutVersion(linkID uuid.UUID, version index.DocumentVersion) error {
+	version.Document = copyDoc(version.Document)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.versions[linkID]
+	if n := len(list); n != 0 && version.Document.Version != 0 && list[n-1].Document.Version == version.Document.Version {
+		list[n-1] = version
+		return nil
+	}
+	s.versions[linkID] = append(list, version)
+	return nil
+}
+
+//Versions returns the snapshots of a document, oldest first
+func (s *InMemoryContentStore) Versions(linkID uuid.UUID) ([]index.DocumentVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]index.DocumentVersion, len(s.versions[linkID]))
+	for i, version := range s.versions[linkID] {
+		list[i] = index.DocumentVersion{Document: copyDoc(version.Document), ArchivedAt: version.ArchivedAt}
+	}
+	return list, nil
+}
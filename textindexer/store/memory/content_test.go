@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(InMemoryContentStoreTestSuite))
+
+type InMemoryContentStoreTestSuite struct{}
+
+func (s *InMemoryContentStoreTestSuite) TestVersions(c *gc.C) {
+	store := NewInMemoryContentStore()
+	linkID := uuid.New()
+	now := time.Now()
+
+	for i, content := range []string{"v1", "v2"} {
+		doc := &index.Document{LinkID: linkID, Content: content, Version: uint64(i + 1)}
+		c.Assert(store.PutVersion(linkID, index.DocumentVersion{Document: doc, ArchivedAt: now}), gc.IsNil)
+	}
+
+	// Storing the same version again replaces the existing snapshot.
+	retried := &index.Document{LinkID: linkID, Content: "v2", Keywords: []string{"go"}, Version: 2}
+	c.Assert(store.PutVersion(linkID, index.DocumentVersion{Document: retried, ArchivedAt: now}), gc.IsNil)
+	retried.Keywords[0] = "mutated"
+
+	versions, err := store.Versions(linkID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(versions, gc.HasLen, 2)
+	c.Assert(versions[0].Document.Content, gc.Equals, "v1")
+	c.Assert(versions[1].Document.Keywords, gc.DeepEquals, []string{"go"})
+
+	versions, err = store.Versions(uuid.New())
+	c.Assert(err, gc.IsNil)
+	c.Assert(versions, gc.HasLen, 0)
+}
@@ -3,11 +3,15 @@ package memory
 import (
 	"github.com/blevesearch/bleve"
 	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
 )
 
 type bleveIterator struct {
 	//allows iterator to access the stored docs when the iterator is advaned
 	idx *InMemoryBleveIndexer
+	//the bleve index the search was performed against; it stays the same
+	//for the lifetime of the iterator even if the indexer swaps indices
+	bleveIdx bleve.Index
 	//iterator needs a pointer to the sasrch request, to trigger new bleve searches once
 	//the current page of results has been consumed
 	searchReq *bleve.SearchRequest
@@ -26,6 +30,7 @@ type bleveIterator struct {
 // Close the iterator and release any allocated resources.
 func (it *bleveIterator) Close() error {
 	it.idx = nil
+	it.bleveIdx = nil
 	it.searchReq = nil
 	if it.rs != nil {
 		it.cumIdx = it.rs.Total
@@ -34,30 +39,47 @@ func (it *bleveIterator) Close() error {
 }
 
 // Next loads the next document matching the search query.
-// It returns false if no more documents are available.
+// It returns false if no more documents are available.  Hits whose documents
+// have been deleted (e.g. by the expirer) since the search was performed are
+// skipped.
 func (it *bleveIterator) Next() bool {
-	if it.lastErr != nil || it.rs == nil || it.cumIdx >= it.rs.Total {
-		return false
-	}
-
-	// Do we need to fetch the next batch?
-	if it.rsIdx >= it.rs.Hits.Len() {
-		it.searchReq.From += it.searchReq.Size
-		if it.rs, it.lastErr = it.idx.idx.Search(it.searchReq); it.lastErr != nil {
+	for {
+		if it.lastErr != nil || it.rs == nil || it.cumIdx >= it.rs.Total {
 			return false
 		}
 
-		it.rsIdx = 0
-	}
+		// Do we need to fetch the next batch?
+		if it.rsIdx >= it.rs.Hits.Len() {
+			it.searchReq.From += it.searchReq.Size
+			if it.rs, it.lastErr = it.bleveIdx.Search(it.searchReq); it.lastErr != nil {
+				return false
+			}
 
-	nextID := it.rs.Hits[it.rsIdx].ID
-	if it.latchedDoc, it.lastErr = it.idx.findByID(nextID); it.lastErr != nil {
-		return false
-	}
+			it.rsIdx = 0
+
+			// Concurrent deletes may have shrunk the result set so
+			// that there is nothing left to fetch
+			if it.rsIdx >= it.rs.Hits.Len() {
+				it.cumIdx = it.rs.Total
+				return false
+			}
+		}
 
-	it.cumIdx++
-	it.rsIdx++
-	return true
+		nextID := it.rs.Hits[it.rsIdx].ID
+		it.cumIdx++
+		it.rsIdx++
+
+		doc, err := it.idx.findByID(nextID)
+		if xerrors.Is(err, index.ErrNotFound) {
+			continue
+		} else if err != nil {
+			it.lastErr = err
+			return false
+		}
+
+		it.latchedDoc = doc
+		return true
+	}
 }
 
 // Error returns the last error encountered by the iterator.
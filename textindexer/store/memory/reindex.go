@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"github.com/blevesearch/bleve"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+//pendingReindex tracks a replacement bleve index that is being built in the background
+type pendingReindex struct {
+	idx bleve.Index
+
+	//doneCh is closed once the background build completes; err holds
+	//the build error, if any, and must only be read after that
+	doneCh chan struct{}
+	err    error
+}
+
+/*
+BeginReindex creates a new bleve index using the latest index mapping and starts
+populating it with the stored documents in the background.  Until CommitReindex
+is called, searches are served by the current index while writes are applied
+to both indices.
+*/
+func (i *InMemoryBleveIndexer) BeginReindex() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.reindex != nil {
+		return xerrors.Errorf("begin reindex: %w", index.ErrReindexInProgress)
+	}
+
+	indexMapping, err := newIndexMapping(i.cfg)
+	if err != nil {
+		return xerrors.Errorf("begin reindex: %w", err)
+	}
+
+	newIdx, err := bleve.NewMemOnly(indexMapping)
+	if err != nil {
+		return xerrors.Errorf("begin reindex: %w", err)
+	}
+
+	//only documents that have been indexed need to be copied over; the
+	//ones created by score updates for unknown links are not searchable
+	keys := make([]string, 0, len(i.docs))
+	for key, doc := range i.docs {
+		if !doc.IndexedAt.IsZero() {
+			keys = append(keys, key)
+		}
+	}
+
+	i.reindex = &pendingReindex{idx: newIdx, doneCh: make(chan struct{})}
+	go i.buildReindex(i.reindex, keys)
+	return nil
+}
+
+/*
+buildReindex copies the documents with the specified keys into the replacement
+index.  Each document is read under the read lock so that the latest version
+is copied even if a concurrent write already updated the replacement index
+*/
+func (i *InMemoryBleveIndexer) buildReindex(r *pendingReindex, keys []string) {
+	defer close(r.doneCh)
+
+	for _, key := range keys {
+		i.mu.RLock()
+		doc, found := i.docs[key]
+		if !found {
+			//the document was deleted after the reindex started
+			i.mu.RUnlock()
+			continue
+		}
+		err := r.idx.Index(key, makeBleveDoc(doc, i.cfg.rankingWeights()))
+		i.mu.RUnlock()
+
+		if err != nil {
+			r.err = err
+			return
+		}
+	}
+}
+
+/*
+CommitReindex blocks until the background build started by BeginReindex completes
+and then atomically swaps the replacement index in.  The previous index is not
+closed so that iterators created before the swap can still be consumed.
+*/
+func (i *InMemoryBleveIndexer) CommitReindex() error {
+	i.mu.RLock()
+	r := i.reindex
+	i.mu.RUnlock()
+	if r == nil {
+		return xerrors.Errorf("commit reindex: %w", index.ErrNoReindexInProgress)
+	}
+
+	<-r.doneCh
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.reindex = nil
+	if r.err != nil {
+		_ = r.idx.Close()
+		return xerrors.Errorf("commit reindex: %w", r.err)
+	}
+
+	i.idx = r.idx
+	return nil
+}
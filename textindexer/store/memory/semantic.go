@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/blevesearch/bleve"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+//semanticHit is a document matched by a semantic query together with its
+//similarity to the query embedding
+type semanticHit struct {
+	id    string
+	score float64
+}
+
+/*
+semanticSearch ranks the documents that match the filters of q and have an
+embedding with the same dimensions as q.Embedding by their cosine similarity to
+it.  Similarities are computed exhaustively, which yields exact nearest
+neighbours and is adequate for the document counts an in-memory index is meant
+for
+*/
+func (i *InMemoryBleveIndexer) semanticSearch(q index.Query) (*semanticIterator, error) {
+	if len(q.Embedding) == 0 {
+		return nil, xerrors.Errorf("semantic search: %w", index.InvalidArgument(xerrors.New("query embedding not specified")))
+	}
+
+	ids, err := i.matchingIDs(q)
+	if err != nil {
+		return nil, xerrors.Errorf("semantic search: %w", err)
+	}
+
+	i.mu.RLock()
+	hits := make([]semanticHit, 0, len(ids))
+	for _, id := range ids {
+		if doc := i.docs[id]; doc != nil && len(doc.Embedding) == len(q.Embedding) {
+			hits = append(hits, semanticHit{id: id, score: index.CosineSimilarity(q.Embedding, doc.Embedding)})
+		}
+	}
+	i.mu.RUnlock()
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].score != hits[b].score {
+			return hits[a].score > hits[b].score
+		}
+		return hits[a].id < hits[b].id
+	})
+
+	it := &semanticIterator{idx: i, total: uint64(len(hits))}
+	if q.Offset < len(hits) {
+		it.hits = hits[q.Offset:]
+	}
+	return it, nil
+}
+
+/*
+hybridSearch runs a keyword search ranked by BM25 relevance and a semantic
+search for q and fuses their results with index.FuseRanks
+*/
+func (i *InMemoryBleveIndexer) hybridSearch(q index.Query) (index.Iterator, error) {
+	semanticQ := q
+	semanticQ.Type, semanticQ.Offset = index.QueryTypeSemantic, 0
+	semanticIt, err := i.semanticSearch(semanticQ)
+	if err != nil {
+		return nil, xerrors.Errorf("hybrid search: %w", err)
+	}
+
+	keywordQ := q
+	keywordQ.Type = index.QueryTypeMatch
+	searchReq := bleve.NewSearchRequest(bleveQuery(keywordQ))
+	searchReq.SortBy([]string{"-_score"})
+	searchReq.Size = searchBatchSize
+	bleveIdx := i.bleveIndex()
+	rs, err := bleveIdx.Search(searchReq)
+	if err != nil {
+		_ = semanticIt.Close()
+		return nil, xerrors.Errorf("hybrid search: %w", err)
+	}
+	keywordIt := &bleveIterator{idx: i, bleveIdx: bleveIdx, searchReq: searchReq, rs: rs}
+
+	it, err := index.FuseRanks(q, keywordIt, semanticIt)
+	if err != nil {
+		return nil, xerrors.Errorf("hybrid search: %w", err)
+	}
+	return it, nil
+}
+
+//matchingIDs returns the IDs of all documents that match the filters and
+//exclusions of q
+func (i *InMemoryBleveIndexer) matchingIDs(q index.Query) ([]string, error) {
+	searchReq := bleve.NewSearchRequest(bleveQuery(q))
+	searchReq.Size = scrollBatchSize
+	bleveIdx := i.bleveIndex()
+
+	var ids []string
+	for {
+		rs, err := bleveIdx.Search(searchReq)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range rs.Hits {
+			ids = append(ids, hit.ID)
+		}
+		if searchReq.From += searchReq.Size; len(rs.Hits) == 0 || uint64(searchReq.From) >= rs.Total {
+			return ids, nil
+		}
+	}
+}
+
+//semanticIterator iterates the results of a semantic query
+type semanticIterator struct {
+	idx   *InMemoryBleveIndexer
+	hits  []semanticHit
+	total uint64
+
+	latchedDoc *index.Document
+	lastErr    error
+}
+
+// Close the iterator and release any allocated resources.
+func (it *semanticIterator) Close() error {
+	it.idx = nil
+	it.hits = nil
+	return nil
+}
+
+// Next loads the next document matching the search query.
+// It returns false if no more documents are available.
+func (it *semanticIterator) Next() bool {
+	if it.lastErr != nil || len(it.hits) == 0 {
+		return false
+	}
+
+	if it.latchedDoc, it.lastErr = it.idx.findByID(it.hits[0].id); it.lastErr != nil {
+		return false
+	}
+	it.hits = it.hits[1:]
+	return true
+}
+
+// Error returns the last error encountered by the iterator.
+func (it *semanticIterator) Error() error {
+	return it.lastErr
+}
+
+// Document returns the current document from the result set.
+func (it *semanticIterator) Document() *index.Document {
+	return it.latchedDoc
+}
+
+// TotalCount returns the number of search results.
+func (it *semanticIterator) TotalCount() uint64 {
+	return it.total
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"golang.org/x/xerrors"
+)
+
+const (
+	//maxSuggestions is the maximum number of alternative queries returned by Suggestions
+	maxSuggestions = 3
+
+	//maxEditDistance is the maximum Levenshtein distance between a
+	//misspelled word and its corrections
+	maxEditDistance = 2
+)
+
+//suggestionFields are the fields whose term dictionaries are used for spelling corrections
+var suggestionFields = []string{"Title", "Content", "Description"}
+
+//suggestionCandidate is a dictionary term that could replace a misspelled word
+type suggestionCandidate struct {
+	term     string
+	distance int
+	count    uint64
+}
+
+/*
+Suggestions returns up to maxSuggestions alternative expressions for q, best
+first, where each word of the expression that does not appear in the index
+has been replaced by a similarly spelled term from the bleve term dictionaries.
+An empty list is returned if all words of the expression are known.
+*/
+func (i *InMemoryBleveIndexer) Suggestions(q index.Query) ([]string, error) {
+	bleveIdx := i.bleveIndex()
+	dict, err := termDictionary(bleveIdx)
+	if err != nil {
+		return nil, xerrors.Errorf("suggestions: %w", err)
+	}
+	analyzer := bleveIdx.Mapping().AnalyzerNamed(bleveIdx.Mapping().AnalyzerNameForPath("Content"))
+
+	words := strings.Fields(q.Expression)
+	candidates := make([][]suggestionCandidate, len(words))
+	var misspelled bool
+	for wordIndex, word := range words {
+		var unknown bool
+		for _, tok := range analyzer.Analyze([]byte(word)) {
+			if dict[string(tok.Term)] == 0 {
+				unknown = true
+				break
+			}
+		}
+		if !unknown {
+			continue
+		}
+
+		if candidates[wordIndex] = spellingCandidates(strings.ToLower(word), dict); len(candidates[wordIndex]) != 0 {
+			misspelled = true
+		}
+	}
+	if !misspelled {
+		return nil, nil
+	}
+
+	//the n-th suggestion uses the n-th best candidate for each misspelled
+	//word, falling back to the best one if there are not enough candidates
+	var suggestions []string
+	for n := 0; n < maxSuggestions; n++ {
+		var (
+			corrected = make([]string, len(words))
+			exhausted = true
+		)
+		for wordIndex, word := range words {
+			switch wordCandidates := candidates[wordIndex]; {
+			case len(wordCandidates) == 0:
+				corrected[wordIndex] = word
+			case n < len(wordCandidates):
+				corrected[wordIndex] = wordCandidates[n].term
+				exhausted = false
+			default:
+				corrected[wordIndex] = wordCandidates[0].term
+			}
+		}
+		if exhausted {
+			break
+		}
+		suggestions = append(suggestions, strings.Join(corrected, " "))
+	}
+	return suggestions, nil
+}
+
+//termDictionary returns the terms of the suggestion fields along with the number of documents containing them
+func termDictionary(bleveIdx bleve.Index) (map[string]uint64, error) {
+	dict := make(map[string]uint64)
+	for _, field := range suggestionFields {
+		fieldDict, err := bleveIdx.FieldDict(field)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := fieldDict.Next()
+		for ; err == nil && entry != nil; entry, err = fieldDict.Next() {
+			dict[entry.Term] += entry.Count
+		}
+		if closeErr := fieldDict.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dict, nil
+}
+
+//spellingCandidates returns the dictionary terms within maxEditDistance of word,
+//closest and most frequent first
+func spellingCandidates(word string, dict map[string]uint64) []suggestionCandidate {
+	//short words are only allowed a single edit
+	maxDist := maxEditDistance
+	if len([]rune(word)) < 5 {
+		maxDist = 1
+	}
+
+	var candidates []suggestionCandidate
+	for term, count := range dict {
+		if dist := levenshtein(word, term); dist <= maxDist {
+			candidates = append(candidates, suggestionCandidate{term: term, distance: dist, count: count})
+		}
+	}
+
+	sort.Slice(candidates, func(l, r int) bool {
+		if candidates[l].distance != candidates[r].distance {
+			return candidates[l].distance < candidates[r].distance
+		}
+		if candidates[l].count != candidates[r].count {
+			return candidates[l].count > candidates[r].count
+		}
+		return candidates[l].term < candidates[r].term
+	})
+	return candidates
+}
+
+//levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,115 @@
+package sharded
+
+import (
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+)
+
+// mergeIterator merges the rank-ordered results of several shard iterators
+// into a single rank-ordered result set.
+type mergeIterator struct {
+	its      []index.Iterator
+	rankFunc func(*index.Document) float64
+
+	// heads holds the next unconsumed document of each shard iterator or
+	// nil if the iterator has not been advanced yet or is exhausted.
+	heads   []*index.Document
+	started bool
+	total   uint64
+	lastErr error
+	curDoc  *index.Document
+}
+
+func newMergeIterator(its []index.Iterator, rankFunc func(*index.Document) float64) *mergeIterator {
+	var total uint64
+	for _, it := range its {
+		total += it.TotalCount()
+	}
+
+	return &mergeIterator{
+		its:      its,
+		rankFunc: rankFunc,
+		heads:    make([]*index.Document, len(its)),
+		total:    total,
+	}
+}
+
+// Next loads the highest ranked document among the shard results that have
+// not been consumed yet. It returns false if no more documents are available.
+func (it *mergeIterator) Next() bool {
+	if it.lastErr != nil {
+		return false
+	}
+
+	if !it.started {
+		it.started = true
+		for shardIndex := range it.its {
+			if !it.advance(shardIndex) {
+				return false
+			}
+		}
+	}
+
+	best := -1
+	for shardIndex, doc := range it.heads {
+		if doc == nil {
+			continue
+		}
+		// Ties are resolved in favor of the lowest shard index so that
+		// the merged order is deterministic.
+		if best == -1 || it.rankFunc(doc) > it.rankFunc(it.heads[best]) {
+			best = shardIndex
+		}
+	}
+	if best == -1 {
+		it.curDoc = nil
+		return false
+	}
+
+	it.curDoc = it.heads[best]
+	return it.advance(best)
+}
+
+// advance loads the next document of the specified shard iterator into heads.
+// It returns false if the shard iterator reported an error.
+func (it *mergeIterator) advance(shardIndex int) bool {
+	shardIt := it.its[shardIndex]
+	if shardIt.Next() {
+		it.heads[shardIndex] = shardIt.Document()
+		return true
+	}
+
+	it.heads[shardIndex] = nil
+	if err := shardIt.Error(); err != nil {
+		it.lastErr = err
+		it.curDoc = nil
+		return false
+	}
+	return true
+}
+
+// Error returns the last error encountered by any of the shard iterators.
+func (it *mergeIterator) Error() error {
+	return it.lastErr
+}
+
+// Document returns the current document from the merged result set.
+func (it *mergeIterator) Document() *index.Document {
+	return it.curDoc
+}
+
+// TotalCount returns the approximate number of search results across all
+// shards.
+func (it *mergeIterator) TotalCount() uint64 {
+	return it.total
+}
+
+// Close closes all shard iterators.
+func (it *mergeIterator) Close() error {
+	var err error
+	for _, shardIt := range it.its {
+		if closeErr := shardIt.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
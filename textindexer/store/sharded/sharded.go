@@ -0,0 +1,350 @@
+package sharded
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// Compile-time check for ensuring Indexer implements index.Indexer.
+var _ index.Indexer = (*Indexer)(nil)
+
+// maxSuggestions is the maximum number of merged suggestions returned by
+// Suggestions.
+const maxSuggestions = 3
+
+// Config encapsulates the configuration options for a sharded Indexer.
+type Config struct {
+	// Shards are the underlying indexers. Documents are assigned to a
+	// shard by hashing their LinkID, so the number and order of shards
+	// must not change once documents have been indexed.
+	Shards []index.Indexer
+
+	// RankFunc returns the value used for ordering the merged search
+	// results of all shards (highest first). It should match the ordering
+	// applied by the shards themselves. If not specified, results are
+	// ordered by PageRank.
+	RankFunc func(*index.Document) float64
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if len(cfg.Shards) == 0 {
+		err = xerrors.New("no shards specified")
+	}
+	for i, shard := range cfg.Shards {
+		if shard == nil {
+			err = xerrors.Errorf("shard %d is nil", i)
+		}
+	}
+
+	if cfg.RankFunc == nil {
+		cfg.RankFunc = func(doc *index.Document) float64 { return doc.PageRank }
+	}
+	return err
+}
+
+// Indexer is a composite index.Indexer that partitions documents across a
+// set of underlying indexers so that no single index has to hold the entire
+// corpus. Each document is stored in the shard selected by the hash of its
+// LinkID; searches are fanned out to all shards and their results are merged
+// into a single, globally ranked, result set.
+type Indexer struct {
+	shards   []index.Indexer
+	rankFunc func(*index.Document) float64
+}
+
+// NewIndexer creates a new sharded Indexer using the provided config.
+func NewIndexer(cfg Config) (*Indexer, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("sharded indexer config validation failed: %w", err)
+	}
+
+	return &Indexer{
+		shards:   append([]index.Indexer(nil), cfg.Shards...),
+		rankFunc: cfg.RankFunc,
+	}, nil
+}
+
+// Index adds doc to the shard it is assigned to.
+func (i *Indexer) Index(doc *index.Document) error {
+	return i.shardFor(doc.LinkID).Index(doc)
+}
+
+// FindByID looks up a document in the shard it is assigned to.
+func (i *Indexer) FindByID(linkID uuid.UUID) (*index.Document, error) {
+	return i.shardFor(linkID).FindByID(linkID)
+}
+
+// FindByURL looks up a document by its URL. As documents are assigned to shards
+// by their LinkID, all shards are queried.
+func (i *Indexer) FindByURL(rawURL string) (*index.Document, error) {
+	perShard := make([]*index.Document, len(i.shards))
+	err := i.eachShard(func(shardIndex int, shard index.Indexer) error {
+		doc, err := shard.FindByURL(rawURL)
+		if xerrors.Is(err, index.ErrNotFound) {
+			return nil
+		}
+		perShard[shardIndex] = doc
+		return err
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("find by URL: %w", err)
+	}
+
+	for _, doc := range perShard {
+		if doc != nil {
+			return doc, nil
+		}
+	}
+	return nil, xerrors.Errorf("find by URL: %w", index.ErrNotFound)
+}
+
+// FindByURLs looks up the documents for a batch of URLs. All shards are
+// queried with the entire batch.
+func (i *Indexer) FindByURLs(rawURLs []string) ([]*index.Document, error) {
+	perShard := make([][]*index.Document, len(i.shards))
+	err := i.eachShard(func(shardIndex int, shard index.Indexer) (err error) {
+		perShard[shardIndex], err = shard.FindByURLs(rawURLs)
+		return err
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("find by URLs: %w", err)
+	}
+
+	docs := make([]*index.Document, len(rawURLs))
+	for _, shardDocs := range perShard {
+		for n, doc := range shardDocs {
+			if docs[n] == nil {
+				docs[n] = doc
+			}
+		}
+	}
+	return docs, nil
+}
+
+// Search queries all shards and merges their results by rank or, for
+// semantic queries, by their similarity to the query embedding. Hybrid
+// queries are split into a keyword and a semantic query whose merged results
+// are fused with index.FuseRanks so documents are ranked by their position in
+// the global result lists rather than in the results of their shard.
+func (i *Indexer) Search(q index.Query) (index.Iterator, error) {
+	return i.search(q, index.Indexer.Search)
+}
+
+// SearchAll works like Search but uses the bulk search API of the shards.
+func (i *Indexer) SearchAll(q index.Query) (index.Iterator, error) {
+	return i.search(q, index.Indexer.SearchAll)
+}
+
+func (i *Indexer) search(q index.Query, searchFn func(index.Indexer, index.Query) (index.Iterator, error)) (index.Iterator, error) {
+	if q.Type == index.QueryTypeHybrid {
+		return i.hybridSearch(q, searchFn)
+	}
+
+	// The offset refers to the merged result set so each shard needs to
+	// return its results from the start.
+	offset := q.Offset
+	q.Offset = 0
+
+	its := make([]index.Iterator, len(i.shards))
+	err := i.eachShard(func(shardIndex int, shard index.Indexer) (err error) {
+		its[shardIndex], err = searchFn(shard, q)
+		return err
+	})
+	if err != nil {
+		for _, it := range its {
+			if it != nil {
+				_ = it.Close()
+			}
+		}
+		return nil, xerrors.Errorf("search: %w", err)
+	}
+
+	// Semantic results are ranked by their similarity to the query rather
+	// than by the static rank of the documents.
+	rankFunc := i.rankFunc
+	if q.Type == index.QueryTypeSemantic {
+		rankFunc = func(doc *index.Document) float64 { return index.CosineSimilarity(q.Embedding, doc.Embedding) }
+	}
+	it := newMergeIterator(its, rankFunc)
+	for n := 0; n < offset && it.Next(); n++ {
+	}
+	return it, nil
+}
+
+func (i *Indexer) hybridSearch(q index.Query, searchFn func(index.Indexer, index.Query) (index.Iterator, error)) (index.Iterator, error) {
+	keywordQ, semanticQ := q, q
+	keywordQ.Type, keywordQ.Offset = index.QueryTypeMatch, 0
+	semanticQ.Type, semanticQ.Offset = index.QueryTypeSemantic, 0
+
+	keywordIt, err := i.search(keywordQ, searchFn)
+	if err != nil {
+		return nil, err
+	}
+	semanticIt, err := i.search(semanticQ, searchFn)
+	if err != nil {
+		_ = keywordIt.Close()
+		return nil, err
+	}
+
+	it, err := index.FuseRanks(q, keywordIt, semanticIt)
+	if err != nil {
+		return nil, xerrors.Errorf("search: %w", err)
+	}
+	return it, nil
+}
+
+// Count returns the total number of documents matching q across all shards.
+func (i *Indexer) Count(q index.Query) (uint64, error) {
+	counts := make([]uint64, len(i.shards))
+	err := i.eachShard(func(shardIndex int, shard index.Indexer) (err error) {
+		counts[shardIndex], err = shard.Count(q)
+		return err
+	})
+	if err != nil {
+		return 0, xerrors.Errorf("count: %w", err)
+	}
+
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
+
+// Suggestions merges the spelling suggestions of all shards. As each shard
+// only knows about the terms of its own documents, suggestions proposed by
+// more shards are ranked first; ties are broken by the best position at
+// which a shard ranked them.
+func (i *Indexer) Suggestions(q index.Query) ([]string, error) {
+	perShard := make([][]string, len(i.shards))
+	err := i.eachShard(func(shardIndex int, shard index.Indexer) (err error) {
+		perShard[shardIndex], err = shard.Suggestions(q)
+		return err
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("suggestions: %w", err)
+	}
+
+	type candidate struct {
+		votes   int
+		bestPos int
+	}
+	var (
+		candidates = make(map[string]*candidate)
+		merged     []string
+	)
+	for _, suggestions := range perShard {
+		for pos, suggestion := range suggestions {
+			cand, found := candidates[suggestion]
+			if !found {
+				cand = &candidate{bestPos: pos}
+				candidates[suggestion] = cand
+				merged = append(merged, suggestion)
+			}
+			cand.votes++
+			if pos < cand.bestPos {
+				cand.bestPos = pos
+			}
+		}
+	}
+
+	sort.SliceStable(merged, func(a, b int) bool {
+		ca, cb := candidates[merged[a]], candidates[merged[b]]
+		if ca.votes != cb.votes {
+			return ca.votes > cb.votes
+		}
+		return ca.bestPos < cb.bestPos
+	})
+	if len(merged) > maxSuggestions {
+		merged = merged[:maxSuggestions]
+	}
+	return merged, nil
+}
+
+// UpdateScore updates the PageRank score of a document in its shard.
+func (i *Indexer) UpdateScore(linkID uuid.UUID, score float64) error {
+	return i.shardFor(linkID).UpdateScore(linkID, score)
+}
+
+// UpdateFields applies a set of partial updates to a document in its shard.
+func (i *Indexer) UpdateFields(linkID uuid.UUID, fields map[string]interface{}) error {
+	return i.shardFor(linkID).UpdateFields(linkID, fields)
+}
+
+// UpdateFieldsIfVersion behaves like UpdateFields but only applies the updates
+// if the document's current version equals version.
+func (i *Indexer) UpdateFieldsIfVersion(linkID uuid.UUID, version uint64, fields map[string]interface{}) error {
+	return i.shardFor(linkID).UpdateFieldsIfVersion(linkID, version, fields)
+}
+
+// Delete removes a document from its shard.
+func (i *Indexer) Delete(linkID uuid.UUID) error {
+	return i.shardFor(linkID).Delete(linkID)
+}
+
+// BeginReindex starts a reindex on all shards.
+func (i *Indexer) BeginReindex() error {
+	return i.eachShard(func(_ int, shard index.Indexer) error { return shard.BeginReindex() })
+}
+
+// CommitReindex completes the reindex of all shards.
+func (i *Indexer) CommitReindex() error {
+	return i.eachShard(func(_ int, shard index.Indexer) error { return shard.CommitReindex() })
+}
+
+// StorageStats returns the combined storage footprint of all shards.
+func (i *Indexer) StorageStats() (*index.StorageStats, error) {
+	perShard := make([]*index.StorageStats, len(i.shards))
+	err := i.eachShard(func(shardIndex int, shard index.Indexer) (err error) {
+		perShard[shardIndex], err = shard.StorageStats()
+		return err
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("storage stats: %w", err)
+	}
+
+	total := new(index.StorageStats)
+	for _, stats := range perShard {
+		total.Add(stats)
+	}
+	return total, nil
+}
+
+// shardFor returns the shard that linkID is assigned to.
+func (i *Indexer) shardFor(linkID uuid.UUID) index.Indexer {
+	h := fnv.New32a()
+	_, _ = h.Write(linkID[:])
+	return i.shards[h.Sum32()%uint32(len(i.shards))]
+}
+
+// eachShard invokes fn concurrently for every shard and returns the first
+// error encountered.
+func (i *Indexer) eachShard(fn func(shardIndex int, shard index.Indexer) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(i.shards))
+	)
+	for shardIndex, shard := range i.shards {
+		wg.Add(1)
+		go func(shardIndex int, shard index.Indexer) {
+			defer wg.Done()
+			if err := fn(shardIndex, shard); err != nil {
+				errs[shardIndex] = xerrors.Errorf("shard %d: %w", shardIndex, err)
+			}
+		}(shardIndex, shard)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
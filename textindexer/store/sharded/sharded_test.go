@@ -0,0 +1,424 @@
+package sharded
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(ShardedIndexerTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type ShardedIndexerTestSuite struct {
+	shards []*fakeIndexer
+	idx    *Indexer
+}
+
+func (s *ShardedIndexerTestSuite) SetUpTest(c *gc.C) {
+	s.shards = make([]*fakeIndexer, 4)
+	shards := make([]index.Indexer, len(s.shards))
+	for i := range s.shards {
+		s.shards[i] = newFakeIndexer()
+		shards[i] = s.shards[i]
+	}
+
+	var err error
+	s.idx, err = NewIndexer(Config{Shards: shards})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *ShardedIndexerTestSuite) TestDocumentsAreDistributedAcrossShards(c *gc.C) {
+	ids := s.indexRankedDocs(c, 100)
+
+	var total int
+	for i, shard := range s.shards {
+		c.Assert(len(shard.docs) > 0, gc.Equals, true, gc.Commentf("shard %d is empty", i))
+		total += len(shard.docs)
+	}
+	c.Assert(total, gc.Equals, len(ids))
+
+	for _, id := range ids {
+		doc, err := s.idx.FindByID(id)
+		c.Assert(err, gc.IsNil)
+		c.Assert(doc.LinkID, gc.Equals, id)
+	}
+
+	_, err := s.idx.FindByID(uuid.New())
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+}
+
+func (s *ShardedIndexerTestSuite) TestFindByURLQueriesAllShards(c *gc.C) {
+	ids := s.indexRankedDocs(c, 20)
+	for i, id := range ids {
+		c.Assert(s.idx.Index(&index.Document{LinkID: id, URL: fmt.Sprintf("http://example.com/%d", i)}), gc.IsNil)
+	}
+
+	for i, id := range ids {
+		doc, err := s.idx.FindByURL(fmt.Sprintf("http://example.com/%d", i))
+		c.Assert(err, gc.IsNil)
+		c.Assert(doc.LinkID, gc.Equals, id)
+	}
+
+	_, err := s.idx.FindByURL("http://example.com/unknown")
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+
+	s.shards[2].err = index.ErrUnavailable
+	_, err = s.idx.FindByURL("http://example.com/unknown")
+	c.Assert(xerrors.Is(err, index.ErrUnavailable), gc.Equals, true)
+}
+
+func (s *ShardedIndexerTestSuite) TestFindByURLsQueriesAllShards(c *gc.C) {
+	ids := s.indexRankedDocs(c, 20)
+	urls := make([]string, len(ids)+1)
+	for i, id := range ids {
+		urls[i] = fmt.Sprintf("http://example.com/%d", i)
+		c.Assert(s.idx.Index(&index.Document{LinkID: id, URL: urls[i]}), gc.IsNil)
+	}
+	urls[len(ids)] = "http://example.com/unknown"
+
+	docs, err := s.idx.FindByURLs(urls)
+	c.Assert(err, gc.IsNil)
+	c.Assert(docs, gc.HasLen, len(urls))
+	for i, id := range ids {
+		c.Assert(docs[i].LinkID, gc.Equals, id)
+	}
+	c.Assert(docs[len(ids)], gc.IsNil)
+
+	s.shards[2].err = index.ErrUnavailable
+	_, err = s.idx.FindByURLs(urls)
+	c.Assert(xerrors.Is(err, index.ErrUnavailable), gc.Equals, true)
+}
+
+func (s *ShardedIndexerTestSuite) TestSearchMergesResultsByRank(c *gc.C) {
+	expIDs := s.indexRankedDocs(c, 50)
+
+	for _, offset := range []int{0, 7, 49, 50, 60} {
+		comment := gc.Commentf("offset %d", offset)
+		it, err := s.idx.Search(index.Query{Expression: "sharded", Offset: offset})
+		c.Assert(err, gc.IsNil, comment)
+		c.Assert(it.TotalCount(), gc.Equals, uint64(50), comment)
+
+		var exp []uuid.UUID
+		if offset < len(expIDs) {
+			exp = expIDs[offset:]
+		}
+		c.Assert(iterateDocs(c, it), gc.DeepEquals, exp, comment)
+	}
+
+	count, err := s.idx.Count(index.Query{Expression: "sharded"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(count, gc.Equals, uint64(50))
+}
+
+func (s *ShardedIndexerTestSuite) TestStorageStatsAreSummed(c *gc.C) {
+	s.indexRankedDocs(c, 20)
+
+	stats, err := s.idx.StorageStats()
+	c.Assert(err, gc.IsNil)
+	c.Assert(stats, gc.DeepEquals, &index.StorageStats{
+		Documents:   20,
+		MemoryBytes: 20 * 100,
+		Segments:    len(s.shards),
+	})
+}
+
+func (s *ShardedIndexerTestSuite) TestCustomRankFunc(c *gc.C) {
+	var shards []index.Indexer
+	for _, shard := range s.shards {
+		shards = append(shards, shard)
+	}
+	idx, err := NewIndexer(Config{
+		Shards:   shards,
+		RankFunc: func(doc *index.Document) float64 { return -doc.PageRank },
+	})
+	c.Assert(err, gc.IsNil)
+	for _, shard := range s.shards {
+		shard.ascending = true
+	}
+
+	expIDs := s.indexRankedDocs(c, 20)
+	it, err := idx.SearchAll(index.Query{Expression: "sharded"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(iterateDocs(c, it), gc.DeepEquals, reverse(expIDs))
+}
+
+func (s *ShardedIndexerTestSuite) TestSemanticSearchMergesResultsBySimilarity(c *gc.C) {
+	expIDs := s.indexRankedDocs(c, 20)
+
+	// The embeddings point further away from the query vector as the
+	// PageRank of the documents decreases
+	for i := len(expIDs) - 1; i >= 0; i-- {
+		doc, err := s.idx.FindByID(expIDs[i])
+		c.Assert(err, gc.IsNil)
+		doc.Embedding = []float32{1, float32(i)}
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(i)), gc.IsNil)
+	}
+
+	it, err := s.idx.Search(index.Query{Type: index.QueryTypeSemantic, Embedding: []float32{1, 0}})
+	c.Assert(err, gc.IsNil)
+	c.Assert(iterateDocs(c, it), gc.DeepEquals, expIDs)
+}
+
+func (s *ShardedIndexerTestSuite) TestHybridSearchFusesGlobalRanks(c *gc.C) {
+	expIDs := s.indexRankedDocs(c, 20)
+
+	// Keyword results are ordered by PageRank and semantic results by
+	// similarity so the two retrievers rank the documents in reverse order
+	for i, id := range expIDs {
+		doc, err := s.idx.FindByID(id)
+		c.Assert(err, gc.IsNil)
+		doc.Embedding = []float32{1, float32(i)}
+		c.Assert(s.idx.Index(doc), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(doc.LinkID, float64(i)), gc.IsNil)
+	}
+
+	q := index.Query{Type: index.QueryTypeHybrid, Expression: "sharded", Embedding: []float32{1, 0}, KeywordWeight: 1}
+	it, err := s.idx.Search(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(it.TotalCount(), gc.Equals, uint64(20))
+	c.Assert(iterateDocs(c, it), gc.DeepEquals, reverse(append([]uuid.UUID(nil), expIDs...)))
+
+	q.KeywordWeight, q.SemanticWeight, q.Offset = 1, 3, 15
+	it, err = s.idx.Search(q)
+	c.Assert(err, gc.IsNil)
+	c.Assert(iterateDocs(c, it), gc.DeepEquals, expIDs[15:])
+}
+
+func (s *ShardedIndexerTestSuite) TestShardErrors(c *gc.C) {
+	s.indexRankedDocs(c, 10)
+	s.shards[2].err = index.Unavailable(xerrors.New("connection refused"))
+
+	_, err := s.idx.Search(index.Query{Expression: "sharded"})
+	c.Assert(xerrors.Is(err, index.ErrUnavailable), gc.Equals, true)
+
+	_, err = s.idx.Count(index.Query{Expression: "sharded"})
+	c.Assert(xerrors.Is(err, index.ErrUnavailable), gc.Equals, true)
+}
+
+func (s *ShardedIndexerTestSuite) TestSuggestions(c *gc.C) {
+	s.shards[0].suggestions = []string{"the gopher tunel", "the gophers tunnel"}
+	s.shards[1].suggestions = []string{"the gophers tunnel"}
+	s.shards[3].suggestions = []string{"the gophers tunnel", "the gophers funnel"}
+
+	suggestions, err := s.idx.Suggestions(index.Query{Expression: "the gopehrs tunel"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(suggestions, gc.DeepEquals, []string{
+		"the gophers tunnel",
+		"the gopher tunel",
+		"the gophers funnel",
+	})
+}
+
+func (s *ShardedIndexerTestSuite) TestConfigValidation(c *gc.C) {
+	_, err := NewIndexer(Config{})
+	c.Assert(err, gc.ErrorMatches, ".*no shards specified")
+
+	_, err = NewIndexer(Config{Shards: []index.Indexer{s.shards[0], nil}})
+	c.Assert(err, gc.ErrorMatches, ".*shard 1 is nil")
+}
+
+// indexRankedDocs indexes numDocs documents containing the term "sharded" and
+// returns their IDs in descending PageRank order.
+func (s *ShardedIndexerTestSuite) indexRankedDocs(c *gc.C, numDocs int) []uuid.UUID {
+	ids := make([]uuid.UUID, numDocs)
+	for i := range ids {
+		ids[i] = uuid.New()
+		c.Assert(s.idx.Index(&index.Document{
+			LinkID:  ids[i],
+			Title:   fmt.Sprintf("page %d", i),
+			Content: "a sharded document",
+		}), gc.IsNil)
+		c.Assert(s.idx.UpdateScore(ids[i], float64(numDocs-i)), gc.IsNil)
+	}
+	return ids
+}
+
+func iterateDocs(c *gc.C, it index.Iterator) []uuid.UUID {
+	var seen []uuid.UUID
+	for it.Next() {
+		seen = append(seen, it.Document().LinkID)
+	}
+	c.Assert(it.Error(), gc.IsNil)
+	c.Assert(it.Close(), gc.IsNil)
+	return seen
+}
+
+func reverse(ids []uuid.UUID) []uuid.UUID {
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+	return ids
+}
+
+// fakeIndexer is a map-backed index.Indexer that matches documents whose
+// content contains the query expression and orders them by PageRank.
+type fakeIndexer struct {
+	index.Indexer
+
+	mu          sync.Mutex
+	err         error
+	ascending   bool
+	suggestions []string
+	docs        map[uuid.UUID]*index.Document
+}
+
+func newFakeIndexer() *fakeIndexer {
+	return &fakeIndexer{docs: make(map[uuid.UUID]*index.Document)}
+}
+
+func (f *fakeIndexer) Index(doc *index.Document) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dCopy := *doc
+	f.docs[doc.LinkID] = &dCopy
+	return nil
+}
+
+func (f *fakeIndexer) FindByID(linkID uuid.UUID) (*index.Document, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	doc := f.docs[linkID]
+	if doc == nil {
+		return nil, index.ErrNotFound
+	}
+	dCopy := *doc
+	return &dCopy, nil
+}
+
+func (f *fakeIndexer) FindByURL(rawURL string) (*index.Document, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, doc := range f.docs {
+		if doc.URL == rawURL {
+			dCopy := *doc
+			return &dCopy, nil
+		}
+	}
+	return nil, index.ErrNotFound
+}
+
+func (f *fakeIndexer) FindByURLs(rawURLs []string) ([]*index.Document, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	docs := make([]*index.Document, len(rawURLs))
+	for i, rawURL := range rawURLs {
+		for _, doc := range f.docs {
+			if doc.URL == rawURL {
+				dCopy := *doc
+				docs[i] = &dCopy
+			}
+		}
+	}
+	return docs, nil
+}
+
+func (f *fakeIndexer) UpdateScore(linkID uuid.UUID, score float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	doc := f.docs[linkID]
+	if doc == nil {
+		return index.ErrNotFound
+	}
+	doc.PageRank = score
+	return nil
+}
+
+func (f *fakeIndexer) Search(q index.Query) (index.Iterator, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var docs []*index.Document
+	for _, doc := range f.docs {
+		if q.Type == index.QueryTypeSemantic && len(doc.Embedding) != 0 ||
+			q.Type != index.QueryTypeSemantic && strings.Contains(doc.Content, q.Expression) {
+			dCopy := *doc
+			docs = append(docs, &dCopy)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if q.Type == index.QueryTypeSemantic {
+			return index.CosineSimilarity(q.Embedding, docs[i].Embedding) > index.CosineSimilarity(q.Embedding, docs[j].Embedding)
+		}
+		if f.ascending {
+			return docs[i].PageRank < docs[j].PageRank
+		}
+		return docs[i].PageRank > docs[j].PageRank
+	})
+
+	it := &sliceIterator{total: uint64(len(docs))}
+	if q.Offset < len(docs) {
+		it.docs = docs[q.Offset:]
+	}
+	return it, nil
+}
+
+func (f *fakeIndexer) SearchAll(q index.Query) (index.Iterator, error) {
+	return f.Search(q)
+}
+
+func (f *fakeIndexer) Count(q index.Query) (uint64, error) {
+	it, err := f.Search(q)
+	if err != nil {
+		return 0, err
+	}
+	return it.TotalCount(), nil
+}
+
+func (f *fakeIndexer) Suggestions(index.Query) ([]string, error) {
+	return f.suggestions, nil
+}
+
+func (f *fakeIndexer) StorageStats() (*index.StorageStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &index.StorageStats{
+		Documents:   uint64(len(f.docs)),
+		MemoryBytes: uint64(100 * len(f.docs)),
+		Segments:    1,
+	}, nil
+}
+
+type sliceIterator struct {
+	docs  []*index.Document
+	cur   *index.Document
+	total uint64
+}
+
+func (it *sliceIterator) Close() error { return nil }
+func (it *sliceIterator) Error() error { return nil }
+
+func (it *sliceIterator) Next() bool {
+	if len(it.docs) == 0 {
+		return false
+	}
+	it.cur, it.docs = it.docs[0], it.docs[1:]
+	return true
+}
+
+func (it *sliceIterator) Document() *index.Document { return it.cur }
+func (it *sliceIterator) TotalCount() uint64        { return it.total }
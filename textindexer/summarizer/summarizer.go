@@ -0,0 +1,213 @@
+// Package summarizer provides an index.Enricher that computes extractive
+// summaries of documents using TextRank: the sentences of a document are
+// ranked by running PageRank over a graph whose edges are weighted by the
+// word overlap between sentences, and the top ranked sentences make up the
+// summary.
+package summarizer
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+)
+
+const (
+	defaultMaxSentences      = 3
+	defaultMaxInputSentences = 200
+
+	dampingFactor = 0.85
+	maxIterations = 50
+	minDelta      = 1e-6
+)
+
+// stopwords are very frequent English words that are ignored when comparing
+// sentences.
+var stopwords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {},
+	"for": {}, "from": {}, "has": {}, "have": {}, "in": {}, "is": {}, "it": {},
+	"its": {}, "of": {}, "on": {}, "or": {}, "that": {}, "the": {}, "this": {},
+	"to": {}, "was": {}, "were": {}, "will": {}, "with": {},
+}
+
+// Config encapsulates the configuration options for a Summarizer.
+type Config struct {
+	// MaxSentences is the maximum number of sentences in a summary. If
+	// not specified, summaries consist of up to 3 sentences.
+	MaxSentences int
+
+	// MaxInputSentences bounds the number of sentences, counted from the
+	// beginning of a document, that are considered for the summary. Ranking
+	// is quadratic in the number of sentences. If not specified, a default
+	// value of 200 will be used.
+	MaxInputSentences int
+}
+
+func (cfg Config) maxSentences() int {
+	if cfg.MaxSentences <= 0 {
+		return defaultMaxSentences
+	}
+	return cfg.MaxSentences
+}
+
+func (cfg Config) maxInputSentences() int {
+	if cfg.MaxInputSentences <= 0 {
+		return defaultMaxInputSentences
+	}
+	return cfg.MaxInputSentences
+}
+
+// Summarizer is an index.Enricher that populates the Summary field of
+// documents.
+type Summarizer struct {
+	cfg Config
+}
+
+// New returns a new Summarizer instance using the provided config.
+func New(cfg Config) *Summarizer {
+	return &Summarizer{cfg: cfg}
+}
+
+// Enrich implements index.Enricher. The summary is computed from the main
+// text of the document if available or from its content otherwise. Documents
+// that already have a summary are left untouched.
+func (s *Summarizer) Enrich(_ context.Context, doc *index.Document) error {
+	if doc.Summary != "" {
+		return nil
+	}
+
+	text := doc.MainText
+	if text == "" {
+		text = doc.Content
+	}
+	doc.Summary = s.Summarize(text)
+	return nil
+}
+
+// Summarize returns the highest ranked sentences of text in the order they
+// appear in the text.
+func (s *Summarizer) Summarize(text string) string {
+	sentences := splitSentences(text)
+	if limit := s.cfg.maxInputSentences(); len(sentences) > limit {
+		sentences = sentences[:limit]
+	}
+	if len(sentences) <= s.cfg.maxSentences() {
+		return strings.Join(sentences, " ")
+	}
+
+	scores := rankSentences(sentences)
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	top := order[:s.cfg.maxSentences()]
+	sort.Ints(top)
+	summary := make([]string, len(top))
+	for i, sentenceIndex := range top {
+		summary[i] = sentences[sentenceIndex]
+	}
+	return strings.Join(summary, " ")
+}
+
+// rankSentences returns the TextRank score of each sentence.
+func rankSentences(sentences []string) []float64 {
+	words := make([]map[string]struct{}, len(sentences))
+	for i, sentence := range sentences {
+		words[i] = sentenceWords(sentence)
+	}
+
+	n := len(sentences)
+	weights := make([][]float64, n)
+	outWeight := make([]float64, n)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			w := similarity(words[i], words[j])
+			weights[i][j], weights[j][i] = w, w
+			outWeight[i] += w
+			outWeight[j] += w
+		}
+	}
+
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1.0 / float64(n)
+	}
+	next := make([]float64, n)
+	for iter := 0; iter < maxIterations; iter++ {
+		var delta float64
+		for i := 0; i < n; i++ {
+			var sum float64
+			for j := 0; j < n; j++ {
+				if weights[j][i] != 0 {
+					sum += weights[j][i] / outWeight[j] * scores[j]
+				}
+			}
+			next[i] = (1-dampingFactor)/float64(n) + dampingFactor*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+		scores, next = next, scores
+		if delta < minDelta {
+			break
+		}
+	}
+	return scores
+}
+
+// similarity returns the word overlap of two sentences normalized by their
+// lengths as proposed in the TextRank paper.
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+
+	var common int
+	for word := range a {
+		if _, found := b[word]; found {
+			common++
+		}
+	}
+	return float64(common) / (math.Log(float64(len(a))) + math.Log(float64(len(b))))
+}
+
+// sentenceWords returns the set of lowercased words of sentence excluding
+// stop words.
+func sentenceWords(sentence string) map[string]struct{} {
+	words := make(map[string]struct{})
+	for _, field := range strings.FieldsFunc(sentence, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		word := strings.ToLower(field)
+		if _, stop := stopwords[word]; !stop {
+			words[word] = struct{}{}
+		}
+	}
+	return words
+}
+
+// splitSentences splits text into sentences that end with a period, an
+// exclamation or a question mark followed by whitespace.
+func splitSentences(text string) []string {
+	var (
+		sentences []string
+		current   []string
+	)
+	for _, field := range strings.Fields(text) {
+		current = append(current, field)
+		if strings.ContainsAny(field[len(field)-1:], ".!?") {
+			sentences = append(sentences, strings.Join(current, " "))
+			current = current[:0]
+		}
+	}
+	if len(current) != 0 {
+		sentences = append(sentences, strings.Join(current, " "))
+	}
+	return sentences
+}
@@ -0,0 +1,56 @@
+package summarizer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(SummarizerTestSuite))
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type SummarizerTestSuite struct{}
+
+const article = "Gophers are burrowing rodents that live in North America. " +
+	"Cookie banners are annoying. " +
+	"Gophers dig extensive tunnel systems under farms and gardens. " +
+	"Subscribe to our newsletter! " +
+	"The tunnel systems of gophers can damage farms and gardens. " +
+	"Farmers often try to keep gophers away from their gardens. " +
+	"Copyright 2020."
+
+func (s *SummarizerTestSuite) TestSummarize(c *gc.C) {
+	summary := New(Config{MaxSentences: 2}).Summarize(article)
+	c.Assert(summary, gc.Equals, "Gophers dig extensive tunnel systems under farms and gardens. "+
+		"The tunnel systems of gophers can damage farms and gardens.")
+}
+
+func (s *SummarizerTestSuite) TestShortText(c *gc.C) {
+	sum := New(Config{})
+	c.Assert(sum.Summarize(""), gc.Equals, "")
+	c.Assert(sum.Summarize("Just one sentence without a period"), gc.Equals, "Just one sentence without a period")
+	c.Assert(sum.Summarize("One.  Two!\nThree?"), gc.Equals, "One. Two! Three?")
+}
+
+func (s *SummarizerTestSuite) TestMaxInputSentences(c *gc.C) {
+	text := article + " " + strings.Repeat("Gophers love tunnels and farms and gardens. ", 10)
+	summary := New(Config{MaxSentences: 1, MaxInputSentences: 7}).Summarize(text)
+	c.Assert(strings.Contains(article, summary), gc.Equals, true, gc.Commentf("summary %q", summary))
+}
+
+func (s *SummarizerTestSuite) TestEnrich(c *gc.C) {
+	var enricher index.Enricher = New(Config{MaxSentences: 2})
+
+	doc := &index.Document{Content: "Menu. " + article, MainText: article}
+	c.Assert(enricher.Enrich(context.TODO(), doc), gc.IsNil)
+	c.Assert(doc.Summary, gc.Equals, New(Config{MaxSentences: 2}).Summarize(article))
+
+	// Existing summaries are kept
+	doc = &index.Document{Content: article, Summary: "Provided by the publisher."}
+	c.Assert(enricher.Enrich(context.TODO(), doc), gc.IsNil)
+	c.Assert(doc.Summary, gc.Equals, "Provided by the publisher.")
+}
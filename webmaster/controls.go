@@ -0,0 +1,128 @@
+package webmaster
+
+import (
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+// RequestRecrawl schedules a recrawl of the page at rawURL on behalf of owner
+// who must have verified its site. The link is added to the graph if it is not
+// known yet and its priority is raised to RecrawlPriority. The link is then
+// queued until it is handed out by Recrawls.
+func (m *Manager) RequestRecrawl(owner, rawURL string) (*graph.Link, error) {
+	normURL, err := m.requireVerified(owner, rawURL)
+	if err != nil {
+		return nil, xerrors.Errorf("request recrawl: %w", err)
+	}
+
+	link, err := m.cfg.Graph.FindLinkByURL(normURL)
+	if xerrors.Is(err, graph.ErrNotFound) {
+		link = &graph.Link{URL: normURL}
+	} else if err != nil {
+		return nil, xerrors.Errorf("request recrawl: %w", err)
+	}
+	link.Priority = RecrawlPriority
+	if err = m.cfg.Graph.UpsertLink(link); err != nil {
+		return nil, xerrors.Errorf("request recrawl: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, queued := m.queued[link.ID]; !queued {
+		linkCopy := *link
+		m.recrawls = append(m.recrawls, &linkCopy)
+		m.queued[link.ID] = struct{}{}
+	}
+	return link, nil
+}
+
+// PendingRecrawls returns the number of queued recrawl requests.
+func (m *Manager) PendingRecrawls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.recrawls)
+}
+
+// Recrawls dequeues the pending recrawl requests and returns an iterator over
+// their links in request order that can be passed to crawler.Crawl. Unlike
+// the regular crawl passes, the links are crawled regardless of when they
+// were last retrieved.
+func (m *Manager) Recrawls() graph.LinkIterator {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	links := m.recrawls
+	m.recrawls, m.queued = nil, make(map[uuid.UUID]struct{})
+	return &linkIterator{links: links}
+}
+
+// SetCrawlDelay sets the crawl rate preference of the site with the specified
+// host on behalf of owner who must have verified it. The crawler waits at
+// least delay between two requests to the site; a zero delay removes the
+// preference.
+func (m *Manager) SetCrawlDelay(owner, host string, delay time.Duration) error {
+	host = normalizeHost(host)
+	if delay < 0 || delay > m.cfg.MaxCrawlDelay {
+		return xerrors.Errorf("set crawl delay: delay must be between 0 and %s: %w", m.cfg.MaxCrawlDelay, ErrInvalidArgument)
+	}
+	if !m.IsVerified(owner, host) {
+		return xerrors.Errorf("set crawl delay: %q: %w", host, ErrNotVerified)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if delay == 0 {
+		delete(m.crawlDelays, host)
+		return nil
+	}
+	m.crawlDelays[host] = delay
+	return nil
+}
+
+// CrawlDelay returns the crawl rate preference of host or zero if no
+// preference has been set. It implements crawler.SiteCrawlDelays.
+func (m *Manager) CrawlDelay(host string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.crawlDelays[normalizeHost(host)]
+}
+
+// RemoveURL removes the page at rawURL from the index on behalf of owner who
+// must have verified its site, without going through moderation. Like approved
+// removal requests, the URL is blocklisted for Config.RemovalBlockPeriod. It
+// returns index.ErrNotFound if the page has not been indexed.
+func (m *Manager) RemoveURL(owner, rawURL string) error {
+	normURL, err := m.requireVerified(owner, rawURL)
+	if err != nil {
+		return xerrors.Errorf("remove URL: %w", err)
+	}
+
+	if _, err = m.cfg.Index.FindByURL(normURL); err != nil {
+		return xerrors.Errorf("remove URL: %w", err)
+	}
+	if _, err = m.removeAndBlock(normURL); err != nil {
+		return xerrors.Errorf("remove URL: %w", err)
+	}
+	return nil
+}
+
+// linkIterator is a graph.LinkIterator over a slice of links.
+type linkIterator struct {
+	links []*graph.Link
+	cur   *graph.Link
+}
+
+func (it *linkIterator) Next() bool {
+	if len(it.links) == 0 {
+		return false
+	}
+	it.cur, it.links = it.links[0], it.links[1:]
+	return true
+}
+
+func (it *linkIterator) Link() *graph.Link { return it.cur }
+func (it *linkIterator) Error() error      { return nil }
+func (it *linkIterator) Close() error      { return nil }
@@ -0,0 +1,238 @@
+package webmaster
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/crawler"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/pipeline"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrUnknownRemoval is returned when reviewing a removal request that
+	// does not exist.
+	ErrUnknownRemoval = xerrors.New("unknown removal request")
+
+	// ErrAlreadyReviewed is returned when reviewing a removal request that
+	// has already been approved or rejected.
+	ErrAlreadyReviewed = xerrors.New("removal request already reviewed")
+
+	// ErrRemovalQueueFull is returned when submitting a removal request
+	// while the maximum number of requests is pending review.
+	ErrRemovalQueueFull = xerrors.New("removal queue full")
+)
+
+const (
+	defaultRemovalBlockPeriod = 90 * 24 * time.Hour
+	defaultMaxPendingRemovals = 10000
+)
+
+// RemovalStatus describes the moderation state of a removal request.
+type RemovalStatus string
+
+const (
+	RemovalPending  RemovalStatus = "pending"
+	RemovalApproved RemovalStatus = "approved"
+	RemovalRejected RemovalStatus = "rejected"
+)
+
+// RemovalRequest asks for a URL to be removed from the index.
+type RemovalRequest struct {
+	ID uuid.UUID
+
+	// URL is the normalized URL of the page to remove.
+	URL string
+
+	// Requester and Reason are provided by the submitter for the
+	// moderators.
+	Requester string
+	Reason    string
+
+	Status      RemovalStatus
+	SubmittedAt time.Time
+	ReviewedAt  time.Time
+
+	// BlockedUntil is set for approved requests; the URL is not crawled
+	// or indexed again before this time.
+	BlockedUntil time.Time
+}
+
+// SubmitRemoval queues a request for removing the page at rawURL from the index
+// until it is reviewed by a moderator. Submitting a URL that is already
+// pending review returns the existing request.
+func (m *Manager) SubmitRemoval(requester, rawURL, reason string) (*RemovalRequest, error) {
+	normURL, err := graph.NormalizeURL(rawURL)
+	if err != nil {
+		return nil, xerrors.Errorf("submit removal: invalid URL %q: %w", rawURL, ErrInvalidArgument)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := 0
+	for _, req := range m.removals {
+		if req.Status != RemovalPending {
+			continue
+		} else if req.URL == normURL {
+			reqCopy := *req
+			return &reqCopy, nil
+		}
+		pending++
+	}
+	if pending >= m.cfg.MaxPendingRemovals {
+		return nil, xerrors.Errorf("submit removal: %w", ErrRemovalQueueFull)
+	}
+
+	req := &RemovalRequest{
+		ID:          uuid.New(),
+		URL:         normURL,
+		Requester:   requester,
+		Reason:      reason,
+		Status:      RemovalPending,
+		SubmittedAt: m.cfg.Clock(),
+	}
+	m.removals[req.ID] = req
+	reqCopy := *req
+	return &reqCopy, nil
+}
+
+// RemovalRequests returns up to limit removal requests with the specified
+// status, oldest first. An empty status matches all requests.
+func (m *Manager) RemovalRequests(status RemovalStatus, limit int) []*RemovalRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var reqs []*RemovalRequest
+	for _, req := range m.removals {
+		if status == "" || req.Status == status {
+			reqCopy := *req
+			reqs = append(reqs, &reqCopy)
+		}
+	}
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].SubmittedAt.Before(reqs[j].SubmittedAt) })
+	if limit > 0 && len(reqs) > limit {
+		reqs = reqs[:limit]
+	}
+	return reqs
+}
+
+// ApproveRemoval approves the pending removal request with the specified ID:
+// the link is tombstoned, the indexed document is deleted and the URL is
+// blocklisted for Config.RemovalBlockPeriod.
+func (m *Manager) ApproveRemoval(id uuid.UUID) (*RemovalRequest, error) {
+	m.mu.Lock()
+	req := m.removals[id]
+	var normURL string
+	if req != nil {
+		normURL = req.URL
+	}
+	m.mu.Unlock()
+	if req == nil {
+		return nil, xerrors.Errorf("approve removal %s: %w", id, ErrUnknownRemoval)
+	}
+
+	// The stores are updated without holding the lock; removing a URL
+	// twice is harmless if the request is approved concurrently.
+	blockedUntil, err := m.removeAndBlock(normURL)
+	if err != nil {
+		return nil, xerrors.Errorf("approve removal %s: %w", id, err)
+	}
+	return m.review(id, RemovalApproved, blockedUntil)
+}
+
+// RejectRemoval rejects the pending removal request with the specified ID.
+func (m *Manager) RejectRemoval(id uuid.UUID) (*RemovalRequest, error) {
+	return m.review(id, RemovalRejected, time.Time{})
+}
+
+// review records the outcome of reviewing the removal request with the
+// specified ID.
+func (m *Manager) review(id uuid.UUID, status RemovalStatus, blockedUntil time.Time) (*RemovalRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req := m.removals[id]
+	if req == nil {
+		return nil, xerrors.Errorf("review removal %s: %w", id, ErrUnknownRemoval)
+	} else if req.Status != RemovalPending {
+		return nil, xerrors.Errorf("review removal %s: %w", id, ErrAlreadyReviewed)
+	}
+	req.Status, req.ReviewedAt, req.BlockedUntil = status, m.cfg.Clock(), blockedUntil
+	reqCopy := *req
+	return &reqCopy, nil
+}
+
+// removeAndBlock blocklists normURL, tombstones its link and deletes its
+// indexed document. It returns the time until which the URL is blocked.
+//
+// A tombstoned link is parked (see graph.Link.RetryNotBefore) until the block
+// expires so that regular crawl passes skip it, even if it is discovered
+// again in the meantime.
+func (m *Manager) removeAndBlock(normURL string) (time.Time, error) {
+	blockedUntil := m.cfg.Clock().Add(m.cfg.RemovalBlockPeriod)
+
+	// The URL is blocklisted first so that a crawl pass in progress cannot
+	// index it again after its document has been deleted.
+	m.mu.Lock()
+	if blockedUntil.After(m.blocked[normURL]) {
+		m.blocked[normURL] = blockedUntil
+	}
+	m.mu.Unlock()
+
+	link, err := m.cfg.Graph.FindLinkByURL(normURL)
+	if xerrors.Is(err, graph.ErrNotFound) {
+		link = &graph.Link{URL: normURL}
+	} else if err != nil {
+		return time.Time{}, xerrors.Errorf("tombstone link: %w", err)
+	}
+	link.RetryNotBefore = blockedUntil
+	if err = m.cfg.Graph.UpsertLink(link); err != nil {
+		return time.Time{}, xerrors.Errorf("tombstone link: %w", err)
+	}
+
+	doc, err := m.cfg.Index.FindByURL(normURL)
+	if err == nil {
+		err = m.cfg.Index.Delete(doc.LinkID)
+	}
+	if err != nil && !xerrors.Is(err, index.ErrNotFound) {
+		return time.Time{}, xerrors.Errorf("delete document: %w", err)
+	}
+	return blockedUntil, nil
+}
+
+// Blocklisted returns true if rawURL has been removed and may not be crawled or
+// indexed yet.
+func (m *Manager) Blocklisted(rawURL string) bool {
+	normURL, err := graph.NormalizeURL(rawURL)
+	if err != nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	blockedUntil, found := m.blocked[normURL]
+	if found && !blockedUntil.After(m.cfg.Clock()) {
+		delete(m.blocked, normURL)
+		return false
+	}
+	return found
+}
+
+// BlocklistFilter returns a processor that discards the crawler payloads of
+// blocklisted URLs. It should be installed both as a crawler.Config.PreFetch
+// hook, so that blocklisted links are not fetched when crawled explicitly, and
+// as a crawler.Config.PreIndex hook so that pages ingested from WARC files are
+// kept out of the index too.
+func (m *Manager) BlocklistFilter() pipeline.Processor {
+	return pipeline.ProcessorFunc(func(_ context.Context, p pipeline.Payload) (pipeline.Payload, error) {
+		if payload := p.(*crawler.Payload); m.Blocklisted(payload.URL) {
+			return nil, nil
+		}
+		return p, nil
+	})
+}
@@ -0,0 +1,82 @@
+package webmaster
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
+
+var (
+	metaTagRegex  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaAttrRegex = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// verifyMetaTag checks whether the home page of the site at origin contains a
+// verification meta tag with token.
+func (m *Manager) verifyMetaTag(origin, token string) error {
+	res, err := m.cfg.URLGetter.Get(origin + "/")
+	if err != nil {
+		return xerrors.Errorf("fetch home page: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return xerrors.Errorf("fetch home page: unexpected status %d: %w", res.StatusCode, ErrVerificationFailed)
+	}
+
+	page, err := ioutil.ReadAll(io.LimitReader(res.Body, maxVerificationPageBytes))
+	if err != nil {
+		return xerrors.Errorf("fetch home page: %w", err)
+	}
+	for _, content := range metaContents(string(page), VerificationName) {
+		if content == token {
+			return nil
+		}
+	}
+	return xerrors.Errorf("meta tag not found: %w", ErrVerificationFailed)
+}
+
+// verifyTXTRecord checks whether host has a verification TXT record with
+// token.
+func (m *Manager) verifyTXTRecord(ctx context.Context, host, token string) error {
+	records, err := m.cfg.Resolver.LookupTXT(ctx, host)
+	if err != nil {
+		return xerrors.Errorf("lookup TXT records: %v: %w", err, ErrVerificationFailed)
+	}
+	expRecord := VerificationName + "=" + token
+	for _, record := range records {
+		if strings.TrimSpace(record) == expRecord {
+			return nil
+		}
+	}
+	return xerrors.Errorf("TXT record not found: %w", ErrVerificationFailed)
+}
+
+// metaContents returns the content attributes of the meta tags in page whose
+// name attribute equals name.
+func metaContents(page, name string) []string {
+	var contents []string
+	for _, tag := range metaTagRegex.FindAllString(page, -1) {
+		var tagName, content string
+		for _, attr := range metaAttrRegex.FindAllStringSubmatch(tag, -1) {
+			value := attr[2] + attr[3] + attr[4]
+			switch strings.ToLower(attr[1]) {
+			case "name":
+				tagName = value
+			case "content":
+				content = value
+			}
+		}
+		if strings.EqualFold(tagName, name) {
+			contents = append(contents, strings.TrimSpace(content))
+		}
+	}
+	return contents
+}
@@ -0,0 +1,396 @@
+// Package webmaster allows site owners to prove that they control a site and,
+// once verified, to manage how the site is crawled and indexed: owners can
+// request a recrawl of specific URLs, set a crawl rate preference for their
+// site and remove URLs from the index.
+//
+// Ownership is proven by publishing a per-owner token, either in a meta tag
+// on the home page of the site or in a DNS TXT record of the site's host.
+package webmaster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// ErrUnknownSite is returned when verifying a site for which no
+	// verification token has been issued to the owner.
+	ErrUnknownSite = xerrors.New("no verification token issued for site")
+
+	// ErrVerificationFailed is returned when the verification token could
+	// not be found on the site.
+	ErrVerificationFailed = xerrors.New("site verification failed")
+
+	// ErrNotVerified is returned when an owner attempts to manage a site
+	// that they have not verified.
+	ErrNotVerified = xerrors.New("site not verified")
+
+	// ErrInvalidArgument is returned for invalid sites, URLs, verification
+	// methods or crawl delays.
+	ErrInvalidArgument = xerrors.New("invalid argument")
+)
+
+const (
+	// VerificationName is the name of the meta tag and the prefix of the
+	// DNS TXT record that carry verification tokens.
+	VerificationName = "ask-brandon-site-verification"
+
+	// RecrawlPriority is assigned to links whose recrawl has been requested
+	// so that they are crawled ahead of the links discovered by the crawler.
+	RecrawlPriority = 1000
+
+	defaultMaxCrawlDelay = time.Minute
+	defaultFetchTimeout  = 10 * time.Second
+
+	// maxVerificationPageBytes bounds the part of the home page that is
+	// searched for the verification meta tag.
+	maxVerificationPageBytes = 1 << 20
+)
+
+// Method selects how site ownership is verified.
+type Method string
+
+const (
+	// MetaTag verification looks for the token in a meta tag of the site's
+	// home page.
+	MetaTag Method = "meta"
+
+	// DNSRecord verification looks for the token in a TXT record of the
+	// site's host.
+	DNSRecord Method = "dns"
+)
+
+// LinkStore is implemented by link graphs that can look up and upsert links
+// (see graph.Graph).
+type LinkStore interface {
+	FindLinkByURL(rawURL string) (*graph.Link, error)
+	UpsertLink(link *graph.Link) error
+}
+
+// DocumentStore is implemented by text indexers that can look up documents by
+// URL and delete them (see index.Indexer).
+type DocumentStore interface {
+	FindByURL(rawURL string) (*index.Document, error)
+	Delete(linkID uuid.UUID) error
+}
+
+// URLGetter is implemented by objects that can perform HTTP GET requests.
+type URLGetter interface {
+	Get(url string) (*http.Response, error)
+}
+
+// TXTResolver is implemented by objects that can look up DNS TXT records
+// (e.g. net.Resolver).
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Config encapsulates the configuration options for a Manager.
+type Config struct {
+	// Graph is used for scheduling recrawls.
+	Graph LinkStore
+
+	// Index is used for removing URLs from the index.
+	Index DocumentStore
+
+	// URLGetter fetches the home pages of sites that are verified via
+	// meta tags. If not specified, an http.Client with a 10s timeout will
+	// be used.
+	URLGetter URLGetter
+
+	// Resolver looks up the TXT records of sites that are verified via
+	// DNS. If not specified, net.DefaultResolver will be used.
+	Resolver TXTResolver
+
+	// MaxCrawlDelay is the largest crawl delay that owners may ask for. If
+	// not specified, a default value of 1m will be used.
+	MaxCrawlDelay time.Duration
+
+	// RemovalBlockPeriod is the time for which removed URLs are kept out of
+	// the crawl and the index. If not specified, a default value of 90
+	// days will be used.
+	RemovalBlockPeriod time.Duration
+
+	// MaxPendingRemovals caps the number of removal requests that can be
+	// pending review. If not specified, a default value of 10000 will be
+	// used.
+	MaxPendingRemovals int
+
+	// Clock returns the current time. If not specified, time.Now will be
+	// used.
+	Clock func() time.Time
+}
+
+func (cfg *Config) validate() error {
+	var err error
+	if cfg.Graph == nil {
+		err = xerrors.New("link graph not specified")
+	} else if cfg.Index == nil {
+		err = xerrors.New("text indexer not specified")
+	}
+
+	if cfg.URLGetter == nil {
+		cfg.URLGetter = &http.Client{Timeout: defaultFetchTimeout}
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+	if cfg.MaxCrawlDelay <= 0 {
+		cfg.MaxCrawlDelay = defaultMaxCrawlDelay
+	}
+	if cfg.RemovalBlockPeriod <= 0 {
+		cfg.RemovalBlockPeriod = defaultRemovalBlockPeriod
+	}
+	if cfg.MaxPendingRemovals <= 0 {
+		cfg.MaxPendingRemovals = defaultMaxPendingRemovals
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+	return err
+}
+
+// Site describes the verification state of a site claimed by an owner.
+type Site struct {
+	Owner string
+	Host  string
+
+	// Origin is the scheme and host of the site's home page.
+	Origin string
+
+	// Token is the verification token that the owner must publish.
+	Token string
+
+	// VerifiedAt and Method are set once the site has been verified.
+	VerifiedAt time.Time
+	Method     Method
+
+	// CrawlDelay is the crawl rate preference of the site, if any.
+	CrawlDelay time.Duration
+}
+
+// Verified returns true if the site has been verified.
+func (s *Site) Verified() bool { return !s.VerifiedAt.IsZero() }
+
+// MetaTag returns the meta tag that verifies the site when added to the home
+// page of the site.
+func (s *Site) MetaTag() string {
+	return `<meta name="` + VerificationName + `" content="` + s.Token + `">`
+}
+
+// TXTRecord returns the contents of the DNS TXT record that verifies the site
+// when added to its host.
+func (s *Site) TXTRecord() string {
+	return VerificationName + "=" + s.Token
+}
+
+// siteKey identifies a site claimed by an owner. Several owners may claim and
+// verify the same site.
+type siteKey struct {
+	owner string
+	host  string
+}
+
+// Manager keeps track of the sites claimed by their owners and applies the
+// per-site controls of verified owners. It also moderates the requests for
+// removing URLs from the index that anyone can submit. Manager implements
+// crawler.SiteCrawlDelays so that crawl rate preferences can be enforced by
+// the crawler. All methods are safe for concurrent use.
+type Manager struct {
+	cfg Config
+
+	mu    sync.Mutex
+	sites map[siteKey]*Site
+
+	// crawlDelays holds the crawl rate preference of each host. Several
+	// owners of a host share its preference.
+	crawlDelays map[string]time.Duration
+
+	// recrawls holds the links whose recrawl has been requested in request
+	// order; queued is used for skipping duplicate requests.
+	recrawls []*graph.Link
+	queued   map[uuid.UUID]struct{}
+
+	// removals holds the submitted removal requests while blocked maps
+	// the normalized URLs of removed pages to the time until which they
+	// are blocklisted.
+	removals map[uuid.UUID]*RemovalRequest
+	blocked  map[string]time.Time
+}
+
+// NewManager creates a new Manager using the provided config.
+func NewManager(cfg Config) (*Manager, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, xerrors.Errorf("webmaster config validation failed: %w", err)
+	}
+
+	return &Manager{
+		cfg:         cfg,
+		sites:       make(map[siteKey]*Site),
+		crawlDelays: make(map[string]time.Duration),
+		queued:      make(map[uuid.UUID]struct{}),
+		removals:    make(map[uuid.UUID]*RemovalRequest),
+		blocked:     make(map[string]time.Time),
+	}, nil
+}
+
+// Claim registers siteURL (e.g. "https://example.com") as a site of owner and
+// returns the site including the token that owner must publish to verify it.
+// Claiming an already claimed site returns its existing token.
+func (m *Manager) Claim(owner, siteURL string) (*Site, error) {
+	if owner == "" {
+		return nil, xerrors.Errorf("claim site: owner not specified: %w", ErrInvalidArgument)
+	}
+	origin, host, err := parseSite(siteURL)
+	if err != nil {
+		return nil, xerrors.Errorf("claim site: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := siteKey{owner: owner, host: host}
+	if site := m.sites[key]; site != nil {
+		return m.copySite(site), nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, xerrors.Errorf("claim site: %w", err)
+	}
+	site := &Site{Owner: owner, Host: host, Origin: origin, Token: token}
+	m.sites[key] = site
+	return m.copySite(site), nil
+}
+
+// Verify checks whether owner has published the verification token of the
+// site with the specified host using method. It returns ErrVerificationFailed
+// if the token could not be found.
+func (m *Manager) Verify(ctx context.Context, owner, host string, method Method) (*Site, error) {
+	host = normalizeHost(host)
+
+	m.mu.Lock()
+	site := m.sites[siteKey{owner: owner, host: host}]
+	var origin, token string
+	if site != nil {
+		origin, token = site.Origin, site.Token
+	}
+	m.mu.Unlock()
+	if site == nil {
+		return nil, xerrors.Errorf("verify site %q: %w", host, ErrUnknownSite)
+	}
+
+	// Tokens are looked up without holding the lock as remote sites may
+	// be slow to respond.
+	var err error
+	switch method {
+	case MetaTag:
+		err = m.verifyMetaTag(origin, token)
+	case DNSRecord:
+		err = m.verifyTXTRecord(ctx, host, token)
+	default:
+		err = xerrors.Errorf("unknown verification method %q: %w", method, ErrInvalidArgument)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("verify site %q: %w", host, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	site.VerifiedAt, site.Method = m.cfg.Clock(), method
+	return m.copySite(site), nil
+}
+
+// IsVerified returns true if owner has verified the site with the specified
+// host.
+func (m *Manager) IsVerified(owner, host string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	site := m.sites[siteKey{owner: owner, host: normalizeHost(host)}]
+	return site != nil && site.Verified()
+}
+
+// Sites returns the sites claimed by owner sorted by host.
+func (m *Manager) Sites(owner string) []*Site {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sites []*Site
+	for key, site := range m.sites {
+		if key.owner == owner {
+			sites = append(sites, m.copySite(site))
+		}
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Host < sites[j].Host })
+	return sites
+}
+
+// copySite returns a copy of site that includes the crawl rate preference of
+// its host. Callers must hold the lock.
+func (m *Manager) copySite(site *Site) *Site {
+	siteCopy := *site
+	siteCopy.CrawlDelay = m.crawlDelays[site.Host]
+	return &siteCopy
+}
+
+// requireVerified returns the normalized form of rawURL or an ErrNotVerified
+// error if owner has not verified its host. Callers must not hold the lock.
+func (m *Manager) requireVerified(owner, rawURL string) (string, error) {
+	normURL, err := graph.NormalizeURL(rawURL)
+	if err != nil {
+		return "", xerrors.Errorf("invalid URL %q: %w", rawURL, ErrInvalidArgument)
+	}
+	u, err := url.Parse(normURL)
+	if err != nil {
+		return "", xerrors.Errorf("invalid URL %q: %w", rawURL, ErrInvalidArgument)
+	}
+	if host := normalizeHost(u.Hostname()); !m.IsVerified(owner, host) {
+		return "", xerrors.Errorf("%q: %w", host, ErrNotVerified)
+	}
+	return normURL, nil
+}
+
+// parseSite returns the origin and host of siteURL. Bare host names are
+// assumed to be served over https.
+func parseSite(siteURL string) (origin, host string, err error) {
+	if !strings.Contains(siteURL, "://") {
+		siteURL = "https://" + siteURL
+	}
+	normURL, err := graph.NormalizeURL(siteURL)
+	if err != nil {
+		return "", "", xerrors.Errorf("invalid site %q: %w", siteURL, ErrInvalidArgument)
+	}
+	u, err := url.Parse(normURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", "", xerrors.Errorf("invalid site %q: %w", siteURL, ErrInvalidArgument)
+	}
+	return u.Scheme + "://" + u.Host, normalizeHost(u.Hostname()), nil
+}
+
+// normalizeHost lowercases host and strips its trailing dot so that it matches
+// the host names used by the crawler.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// newToken returns a random verification token.
+func newToken() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", xerrors.Errorf("generate verification token: %w", err)
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
@@ -0,0 +1,290 @@
+package webmaster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brandonshearin/ask_brandon/crawler"
+	"github.com/brandonshearin/ask_brandon/linkgraph/graph"
+	memgraph "github.com/brandonshearin/ask_brandon/linkgraph/store/memory"
+	"github.com/brandonshearin/ask_brandon/textindexer/index"
+	memindex "github.com/brandonshearin/ask_brandon/textindexer/store/memory"
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+	gc "gopkg.in/check.v1"
+)
+
+var _ = gc.Suite(new(WebmasterTestSuite))
+
+// Compile-time check for ensuring Manager implements crawler.SiteCrawlDelays.
+var _ crawler.SiteCrawlDelays = (*Manager)(nil)
+
+func Test(t *testing.T) { gc.TestingT(t) }
+
+type WebmasterTestSuite struct {
+	g       *memgraph.InMemoryGraph
+	idx     *memindex.InMemoryBleveIndexer
+	records map[string][]string
+	m       *Manager
+}
+
+func (s *WebmasterTestSuite) SetUpTest(c *gc.C) {
+	var err error
+	s.g = memgraph.NewInMemoryGraph()
+	s.idx, err = memindex.NewInMemoryBleveIndexer()
+	c.Assert(err, gc.IsNil)
+	s.records = make(map[string][]string)
+
+	s.m, err = NewManager(Config{
+		Graph:    s.g,
+		Index:    s.idx,
+		Resolver: fakeResolver(s.records),
+	})
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *WebmasterTestSuite) TearDownTest(c *gc.C) {
+	c.Assert(s.idx.Close(), gc.IsNil)
+}
+
+func (s *WebmasterTestSuite) TestVerifyWithMetaTag(c *gc.C) {
+	var homePage string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, gc.Equals, "/")
+		fmt.Fprint(w, homePage)
+	}))
+	defer srv.Close()
+
+	site, err := s.m.Claim("alice", srv.URL)
+	c.Assert(err, gc.IsNil)
+	c.Assert(site.Verified(), gc.Equals, false)
+	c.Assert(site.Token, gc.Not(gc.Equals), "")
+
+	// Claiming a site again returns the same token
+	again, err := s.m.Claim("alice", srv.URL+"/some/page")
+	c.Assert(err, gc.IsNil)
+	c.Assert(again.Token, gc.Equals, site.Token)
+
+	homePage = `<html><head><meta name="` + VerificationName + `" content="not-the-token"></head></html>`
+	_, err = s.m.Verify(context.TODO(), "alice", site.Host, MetaTag)
+	c.Assert(xerrors.Is(err, ErrVerificationFailed), gc.Equals, true, gc.Commentf("err: %v", err))
+	c.Assert(s.m.IsVerified("alice", site.Host), gc.Equals, false)
+
+	homePage = `<html><head><META content='` + site.Token + `' Name="` + VerificationName + `"/></head></html>`
+	verified, err := s.m.Verify(context.TODO(), "alice", site.Host, MetaTag)
+	c.Assert(err, gc.IsNil)
+	c.Assert(verified.Verified(), gc.Equals, true)
+	c.Assert(verified.Method, gc.Equals, MetaTag)
+	c.Assert(s.m.IsVerified("alice", site.Host), gc.Equals, true)
+
+	// Verification does not carry over to other owners
+	c.Assert(s.m.IsVerified("bob", site.Host), gc.Equals, false)
+	_, err = s.m.Verify(context.TODO(), "bob", site.Host, MetaTag)
+	c.Assert(xerrors.Is(err, ErrUnknownSite), gc.Equals, true)
+}
+
+func (s *WebmasterTestSuite) TestVerifyWithTXTRecord(c *gc.C) {
+	site, err := s.m.Claim("alice", "Example.COM")
+	c.Assert(err, gc.IsNil)
+	c.Assert(site.Host, gc.Equals, "example.com")
+	c.Assert(site.Origin, gc.Equals, "https://example.com")
+
+	_, err = s.m.Verify(context.TODO(), "alice", "example.com", DNSRecord)
+	c.Assert(xerrors.Is(err, ErrVerificationFailed), gc.Equals, true)
+
+	s.records["example.com"] = []string{"v=spf1 -all", site.TXTRecord()}
+	verified, err := s.m.Verify(context.TODO(), "alice", "example.com", DNSRecord)
+	c.Assert(err, gc.IsNil)
+	c.Assert(verified.Method, gc.Equals, DNSRecord)
+
+	_, err = s.m.Verify(context.TODO(), "alice", "example.com", Method("carrier-pigeon"))
+	c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, true)
+
+	sites := s.m.Sites("alice")
+	c.Assert(sites, gc.HasLen, 1)
+	c.Assert(sites[0].Verified(), gc.Equals, true)
+}
+
+func (s *WebmasterTestSuite) TestRequestRecrawl(c *gc.C) {
+	s.verify(c, "alice", "example.com")
+
+	existing := &graph.Link{URL: "https://example.com/old", RetrievedAt: time.Now()}
+	c.Assert(s.g.UpsertLink(existing), gc.IsNil)
+
+	_, err := s.m.RequestRecrawl("bob", "https://example.com/old")
+	c.Assert(xerrors.Is(err, ErrNotVerified), gc.Equals, true)
+	_, err = s.m.RequestRecrawl("alice", "https://other.com/")
+	c.Assert(xerrors.Is(err, ErrNotVerified), gc.Equals, true)
+
+	link, err := s.m.RequestRecrawl("alice", "HTTPS://example.com/old#top")
+	c.Assert(err, gc.IsNil)
+	c.Assert(link.ID, gc.Equals, existing.ID)
+	_, err = s.m.RequestRecrawl("alice", "https://example.com/old")
+	c.Assert(err, gc.IsNil)
+
+	// Unknown URLs are added to the graph
+	newLink, err := s.m.RequestRecrawl("alice", "https://example.com/new")
+	c.Assert(err, gc.IsNil)
+	stored, err := s.g.FindLinkByURL("https://example.com/new")
+	c.Assert(err, gc.IsNil)
+	c.Assert(stored.ID, gc.Equals, newLink.ID)
+	c.Assert(stored.Priority, gc.Equals, RecrawlPriority)
+
+	c.Assert(s.m.PendingRecrawls(), gc.Equals, 2)
+	var urls []string
+	for it := s.m.Recrawls(); it.Next(); {
+		urls = append(urls, it.Link().URL)
+	}
+	c.Assert(urls, gc.DeepEquals, []string{"https://example.com/old", "https://example.com/new"})
+	c.Assert(s.m.PendingRecrawls(), gc.Equals, 0)
+}
+
+func (s *WebmasterTestSuite) TestSetCrawlDelay(c *gc.C) {
+	c.Assert(xerrors.Is(s.m.SetCrawlDelay("alice", "example.com", time.Second), ErrNotVerified), gc.Equals, true)
+
+	s.verify(c, "alice", "example.com")
+	c.Assert(s.m.SetCrawlDelay("alice", "example.com", 5*time.Second), gc.IsNil)
+	c.Assert(s.m.CrawlDelay("example.com"), gc.Equals, 5*time.Second)
+	c.Assert(s.m.CrawlDelay("other.com"), gc.Equals, time.Duration(0))
+	c.Assert(s.m.Sites("alice")[0].CrawlDelay, gc.Equals, 5*time.Second)
+
+	err := s.m.SetCrawlDelay("alice", "example.com", time.Hour)
+	c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, true)
+
+	c.Assert(s.m.SetCrawlDelay("alice", "example.com", 0), gc.IsNil)
+	c.Assert(s.m.CrawlDelay("example.com"), gc.Equals, time.Duration(0))
+}
+
+func (s *WebmasterTestSuite) TestRemoveURL(c *gc.C) {
+	doc := &index.Document{LinkID: uuid.New(), URL: "https://example.com/secret", Title: "secret"}
+	c.Assert(s.idx.Index(doc), gc.IsNil)
+
+	c.Assert(xerrors.Is(s.m.RemoveURL("alice", doc.URL), ErrNotVerified), gc.Equals, true)
+
+	s.verify(c, "alice", "example.com")
+	c.Assert(s.m.RemoveURL("alice", doc.URL), gc.IsNil)
+	_, err := s.idx.FindByID(doc.LinkID)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+
+	err = s.m.RemoveURL("alice", doc.URL)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+}
+
+func (s *WebmasterTestSuite) TestParseSite(c *gc.C) {
+	for _, siteURL := range []string{"", "ftp://example.com", "https:///path"} {
+		_, err := s.m.Claim("alice", siteURL)
+		c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, true, gc.Commentf("site %q", siteURL))
+	}
+	_, err := s.m.Claim("", "example.com")
+	c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, true)
+}
+
+func (s *WebmasterTestSuite) TestRemovalWorkflow(c *gc.C) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m, err := NewManager(Config{
+		Graph:              s.g,
+		Index:              s.idx,
+		RemovalBlockPeriod: 24 * time.Hour,
+		Clock:              func() time.Time { return now },
+	})
+	c.Assert(err, gc.IsNil)
+
+	link := &graph.Link{URL: "https://example.com/leak"}
+	c.Assert(s.g.UpsertLink(link), gc.IsNil)
+	c.Assert(s.idx.Index(&index.Document{LinkID: link.ID, URL: link.URL, Title: "leak"}), gc.IsNil)
+
+	_, err = m.SubmitRemoval("someone@example.com", "not a url", "")
+	c.Assert(xerrors.Is(err, ErrInvalidArgument), gc.Equals, true)
+
+	req, err := m.SubmitRemoval("someone@example.com", "HTTPS://example.com/leak#top", "personal data")
+	c.Assert(err, gc.IsNil)
+	c.Assert(req.Status, gc.Equals, RemovalPending)
+	c.Assert(req.URL, gc.Equals, link.URL)
+
+	// Submitting a pending URL again returns the existing request
+	dup, err := m.SubmitRemoval("other@example.com", link.URL, "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(dup.ID, gc.Equals, req.ID)
+
+	rejected, err := m.SubmitRemoval("troll@example.com", "https://example.com/fine", "")
+	c.Assert(err, gc.IsNil)
+	c.Assert(m.RemovalRequests(RemovalPending, 0), gc.HasLen, 2)
+
+	// Nothing is removed until the request is approved
+	_, err = s.idx.FindByID(link.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(m.Blocklisted(link.URL), gc.Equals, false)
+
+	approved, err := m.ApproveRemoval(req.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(approved.Status, gc.Equals, RemovalApproved)
+	c.Assert(approved.BlockedUntil, gc.Equals, now.Add(24*time.Hour))
+	_, err = m.ApproveRemoval(req.ID)
+	c.Assert(xerrors.Is(err, ErrAlreadyReviewed), gc.Equals, true)
+	_, err = m.ApproveRemoval(uuid.New())
+	c.Assert(xerrors.Is(err, ErrUnknownRemoval), gc.Equals, true)
+
+	_, err = s.idx.FindByID(link.ID)
+	c.Assert(xerrors.Is(err, index.ErrNotFound), gc.Equals, true)
+	tombstoned, err := s.g.FindLink(link.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(tombstoned.RetryNotBefore, gc.Equals, approved.BlockedUntil)
+
+	// Rediscovering the link does not lift the tombstone
+	c.Assert(s.g.UpsertLink(&graph.Link{URL: link.URL}), gc.IsNil)
+	tombstoned, err = s.g.FindLink(link.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(tombstoned.RetryNotBefore, gc.Equals, approved.BlockedUntil)
+
+	_, err = m.RejectRemoval(rejected.ID)
+	c.Assert(err, gc.IsNil)
+	c.Assert(m.RemovalRequests(RemovalPending, 0), gc.HasLen, 0)
+	c.Assert(m.RemovalRequests("", 0), gc.HasLen, 2)
+	c.Assert(m.Blocklisted("https://example.com/fine"), gc.Equals, false)
+
+	// Blocklisted payloads are discarded by the crawler hook
+	filter := m.BlocklistFilter()
+	out, err := filter.Process(context.TODO(), &crawler.Payload{URL: "https://example.com/leak"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.IsNil)
+	out, err = filter.Process(context.TODO(), &crawler.Payload{URL: "https://example.com/fine"})
+	c.Assert(err, gc.IsNil)
+	c.Assert(out, gc.NotNil)
+
+	// The block expires after the configured period
+	now = now.Add(25 * time.Hour)
+	c.Assert(m.Blocklisted(link.URL), gc.Equals, false)
+}
+
+func (s *WebmasterTestSuite) TestRemovalQueueLimit(c *gc.C) {
+	m, err := NewManager(Config{Graph: s.g, Index: s.idx, MaxPendingRemovals: 1})
+	c.Assert(err, gc.IsNil)
+
+	_, err = m.SubmitRemoval("", "https://example.com/a", "")
+	c.Assert(err, gc.IsNil)
+	_, err = m.SubmitRemoval("", "https://example.com/b", "")
+	c.Assert(xerrors.Is(err, ErrRemovalQueueFull), gc.Equals, true)
+}
+
+// verify claims and verifies the site with the specified host via DNS.
+func (s *WebmasterTestSuite) verify(c *gc.C, owner, host string) {
+	site, err := s.m.Claim(owner, host)
+	c.Assert(err, gc.IsNil)
+	s.records[host] = append(s.records[host], site.TXTRecord())
+	_, err = s.m.Verify(context.TODO(), owner, host, DNSRecord)
+	c.Assert(err, gc.IsNil)
+}
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, found := r[name]
+	if !found {
+		return nil, xerrors.New("no such host")
+	}
+	return records, nil
+}